@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Distributed mode: `agent --listen :7070` serves a small gRPC service on each load-generator pod, and
+// `coordinate --agents a:7070,b:7070 -- <run flags>` hands every agent a share of the rate and its patient ordinals
+// (benchmarkgo.AgentAssignment), starts them at the same wall-clock time and merges their results. Messages are JSON
+// (jsonCodec), so the service needs no generated protobuf code.
+//
+// Every call carries the shared secret from LOADRUNNER_AGENT_TOKEN (set on the agents and the coordinator), and an
+// agent forwards only the run flags in agentRunFlags: nothing that runs a command, reads or writes a local path,
+// opens a listener or an outbound metrics connection, or points the child at another host reaches it. The child
+// inherits the agent's environment, database passwords included, so it connects only to the targets that environment
+// configures. The token travels in plaintext, so agents
+// belong on a private network; --listen defaults to localhost.
+
+// agentTokenEnv names the environment variable holding the shared agent token.
+const agentTokenEnv = "LOADRUNNER_AGENT_TOKEN"
+
+// agentRunFlags are the run flags an agent accepts from the coordinator: the workload and tuning settings.
+// Flags that run commands (--failover-hook), touch local files (--repro-dir, --output-csv, --report-md,
+// --results-json, --scenario, --baseline, --live-config, --annotations-file, --db-password-file, --snapshot-*),
+// read agent secrets (--db-password-env), listen or push (--control-addr, --metrics-addr, --pprof-addr, --push-*,
+// --otel-endpoint), pick the hosts the child connects to with the agent's credentials (--db-host, --db-port,
+// --query-host, --postgres-replica-host, --httpsink-url) and the per-agent flags set by the assignment are not in it.
+var agentRunFlags = map[string]bool{
+	"database": true, "duration": true, "total-rows": true, "mode": true, "concurrency": true, "workload": true,
+	"load-steps": true, "find-max": true, "find-max-p99-ms": true, "find-max-step-sec": true, "arrival": true,
+	"pattern": true, "burst": true, "ramp-up-sec": true, "ramp-down-sec": true, "warmup-sec": true,
+	"duty-on": true, "duty-idle": true, "key-dist": true, "read-ratio": true,
+	"batch-size": true, "workers": true, "producers": true, "insert-queue-size": true, "query-queue-size": true,
+	"queries-per-record": true, "query-delay": true, "ignore-select-errors": true, "duplicate-ratio": true,
+	"duplicate-lag-sec": true, "query-type": true, "query-types": true, "query-select": true, "verify": true,
+	"page-size": true, "page-depth": true, "result-set-sizes": true, "range-window-sec": true,
+	"session-think-ms": true, "query-think-time-ms": true, "query-think-jitter-ms": true,
+	"retention-at": true, "retention-keep": true, "preload-rows": true, "payload-transform": true, "durability": true,
+	"gomaxprocs": true, "cpus": true, "gogc": true, "gomemlimit": true,
+	"conflict-writers": true, "conflict-keys": true, "conflict-isolation": true, "failover-watch": true,
+	"failover-at": true, "dedup-watch": true, "dedup-poll-sec": true, "dedup-wait-sec": true, "reconcile": true,
+	"verify-goodput": true, "cost-per-gb-month": true, "cost-per-vcpu-hour": true, "cost-vcpus": true,
+	"breaker-failures": true, "breaker-latency-ms": true, "breaker-slow-batches": true, "breaker-pause-sec": true,
+	"max-total-rows": true, "max-storage-gb": true, "repro-after": true, "dual-write-timeout": true,
+	"latency-sample-rate": true, "otel-sample-rate": true, "server-metrics-interval": true, "fail-on-regression": true,
+	"db-name": true, "db-user": true, "clickhouse-cluster": true,
+	"table-prefix": true, "pool-size": true, "insert-pool-size": true, "query-pool-size": true,
+	"pool-max-idle": true, "pool-max-lifetime": true,
+	"pgbouncer-enabled": true, "postgres-timescale": true, "postgres-distribution": true,
+	"postgres-replica-timeout": true, "clickhouse-name-index": true, "clickhouse-topology": true,
+	"clickhouse-protocol": true, "clickhouse-visibility-timeout": true, "clickhouse-partition-minutes": true,
+	"mysql-engine": true, "kafka-partitions": true, "kafka-compression": true, "httpsink-timeout": true, "dynamodb-billing": true, "dynamodb-rcu": true,
+	"dynamodb-wcu": true, "singlestore-table-type": true, "tidb-auto-random": true,
+	"yb-load-balance": true, "yb-read-from-followers": true, "yb-follower-staleness-ms": true,
+}
+
+// agentPerRunFlags are set by each assignment (AgentAssignment.Flags) and so cannot come in the run flags.
+var agentPerRunFlags = map[string]bool{
+	"rows-per-second": true, "seed": true, "results-json": true, "runner-id": true, "runner-count": true,
+	"patient-counter": true,
+}
+
+// checkAgentArgs rejects run flags an agent does not forward. The first argument must be a flag, so the child cannot
+// be turned into a subcommand (dashboards, compare, ...); other non-flag arguments are flag values.
+func checkAgentArgs(args []string) error {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("run flags must start with a flag, got %q", args[0])
+	}
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") || a == "--" {
+			continue
+		}
+		name := strings.SplitN(strings.TrimLeft(a, "-"), "=", 2)[0]
+		if name != "" && name[0] >= '0' && name[0] <= '9' {
+			continue // a negative value, e.g. --gogc -1
+		}
+		if agentPerRunFlags[name] {
+			return fmt.Errorf("--%s is set per agent by the coordinator; pass the total rate and seed as coordinate flags", name)
+		}
+		if !agentRunFlags[name] {
+			return fmt.Errorf("--%s is not accepted by agents (only workload and tuning flags are forwarded; targets come from the agent's environment)", name)
+		}
+	}
+	return nil
+}
+
+// agentToken returns the shared token, or an error naming the variable when it is unset.
+func agentToken() (string, error) {
+	token := os.Getenv(agentTokenEnv)
+	if token == "" {
+		return "", fmt.Errorf("%s must be set to the shared agent token", agentTokenEnv)
+	}
+	return token, nil
+}
+
+// tokenCreds sends the shared token on every coordinator call.
+type tokenCreds string
+
+func (t tokenCreds) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (tokenCreds) RequireTransportSecurity() bool { return false }
+
+// checkToken is the agent's interceptor: calls without the shared token are refused.
+func checkToken(token string) grpc.UnaryServerInterceptor {
+	want := []byte("Bearer " + token)
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if got := md.Get("authorization"); len(got) != 1 || subtle.ConstantTimeCompare([]byte(got[0]), want) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "missing or wrong agent token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// jsonCodec is the gRPC codec for the agent service, selected with the "json" content subtype.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return "json" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// agentStop is the (empty) request and response of Agent/Stop.
+type agentStop struct{}
+
+// agentService is the loadrunner.Agent gRPC service: Run executes an assignment and returns its results once the
+// run ends; Stop interrupts the current run, which then still returns its (partial) results.
+type agentService interface {
+	Run(ctx context.Context, a *benchmarkgo.AgentAssignment) (*benchmarkgo.AgentRun, error)
+	Stop(ctx context.Context, _ *agentStop) (*agentStop, error)
+}
+
+var agentServiceDesc = grpc.ServiceDesc{
+	ServiceName: "loadrunner.Agent",
+	HandlerType: (*agentService)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Run", Handler: func(srv any, ctx context.Context, dec func(any) error, icpt grpc.UnaryServerInterceptor) (any, error) {
+			a := new(benchmarkgo.AgentAssignment)
+			if err := dec(a); err != nil {
+				return nil, err
+			}
+			if icpt == nil {
+				return srv.(agentService).Run(ctx, a)
+			}
+			return icpt(ctx, a, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/loadrunner.Agent/Run"}, func(ctx context.Context, req any) (any, error) {
+				return srv.(agentService).Run(ctx, req.(*benchmarkgo.AgentAssignment))
+			})
+		}},
+		{MethodName: "Stop", Handler: func(srv any, ctx context.Context, dec func(any) error, icpt grpc.UnaryServerInterceptor) (any, error) {
+			in := new(agentStop)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if icpt == nil {
+				return srv.(agentService).Stop(ctx, in)
+			}
+			return icpt(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/loadrunner.Agent/Stop"}, func(ctx context.Context, req any) (any, error) {
+				return srv.(agentService).Stop(ctx, req.(*agentStop))
+			})
+		}},
+	},
+	Metadata: "agent.go",
+}
+
+// agent runs one assignment at a time as a child loadrunner process.
+type agent struct {
+	mu  sync.Mutex
+	cmd *exec.Cmd // the running child, nil when idle
+}
+
+// Run waits for the assignment's start time, runs it and returns the child's results.
+func (a *agent) Run(ctx context.Context, as *benchmarkgo.AgentAssignment) (*benchmarkgo.AgentRun, error) {
+	if err := checkAgentArgs(as.Args); err != nil {
+		return &benchmarkgo.AgentRun{Error: err.Error()}, nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "loadrunner-agent-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "results.json")
+	args := append(append(append([]string(nil), as.Args...), as.Flags()...), "--results-json="+path)
+	cmd := exec.Command(exe, args...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	if a.cmd != nil {
+		a.mu.Unlock()
+		return &benchmarkgo.AgentRun{Error: "agent is already running an assignment"}, nil
+	}
+	a.cmd = cmd
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.cmd = nil
+		a.mu.Unlock()
+	}()
+
+	log.Printf("Agent: assignment %d/%d at %d rows/sec, starting at %s", as.Index+1, as.Count, as.RowsPerSec, as.StartAt.Format(time.RFC3339Nano))
+	select {
+	case <-time.After(time.Until(as.StartAt)):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := cmd.Start(); err != nil {
+		return &benchmarkgo.AgentRun{Error: err.Error()}, nil
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// The coordinator went away: stop the child the way Ctrl-C would.
+		select {
+		case <-ctx.Done():
+			cmd.Process.Signal(os.Interrupt)
+		case <-done:
+		}
+	}()
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		fmt.Fprintf(os.Stdout, "[agent %d] %s\n", as.Index, sc.Text())
+	}
+	if err := cmd.Wait(); err != nil {
+		return &benchmarkgo.AgentRun{Error: err.Error()}, nil
+	}
+	res, err := benchmarkgo.ReadResults(path)
+	if err != nil {
+		return &benchmarkgo.AgentRun{Error: err.Error()}, nil
+	}
+	log.Printf("Agent: assignment %d/%d done, %d rows inserted", as.Index+1, as.Count, int64(res.Inserted.Total))
+	return &benchmarkgo.AgentRun{Results: res}, nil
+}
+
+// Stop interrupts the running child, if any; its Run call still returns the results it writes on the way out.
+func (a *agent) Stop(context.Context, *agentStop) (*agentStop, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cmd != nil && a.cmd.Process != nil {
+		a.cmd.Process.Signal(os.Interrupt)
+	}
+	return &agentStop{}, nil
+}
+
+// runAgent serves the agent service until the process is killed.
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	listen := fs.String("listen", "localhost:7070", "Address for the coordinator's gRPC connections (e.g. :7070 to accept them from other hosts)")
+	fs.Parse(args)
+	token, err := agentToken()
+	if err != nil {
+		log.Fatalf("agent: %v", err)
+	}
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalf("agent: %v", err)
+	}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(checkToken(token)))
+	srv.RegisterService(&agentServiceDesc, &agent{})
+	// Ctrl-C is for the child run (it reaches it through the process group); the agent keeps serving. Catching it
+	// rather than ignoring it keeps the child's default handling, since ignored signals stay ignored across exec.
+	signal.Notify(make(chan os.Signal, 1), os.Interrupt)
+	log.Printf("Agent listening on %s", lis.Addr())
+	if err := srv.Serve(lis); err != nil {
+		log.Fatalf("agent: %v", err)
+	}
+}
+
+// runCoordinate splits a run over the agents, starts them together and merges their results.
+func runCoordinate(args []string) {
+	fs := flag.NewFlagSet("coordinate", flag.ExitOnError)
+	agents := fs.String("agents", "", "Comma-separated agent addresses (host:port) (required)")
+	rowsPerSecond := fs.Int("rows-per-second", 1000, "Total target insert rate, split evenly over the agents")
+	seed := fs.Int64("seed", 0, "Base seed; agent i runs with seed+i (0 = random)")
+	startDelay := fs.Duration("start-delay", 5*time.Second, "How far ahead the shared start time is set, so every agent has its assignment before it")
+	resultsJSON := fs.String("results-json", "", "Write the merged results as JSON to this file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: coordinate --agents host:port,... [--rows-per-second N] [--seed N] [--results-json file] -- <run flags>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	var addrs []string
+	for _, a := range strings.Split(*agents, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	if len(addrs) == 0 {
+		log.Fatal("coordinate: --agents is required")
+	}
+	if *rowsPerSecond < len(addrs) {
+		log.Fatalf("coordinate: --rows-per-second %d is less than one row/sec per agent", *rowsPerSecond)
+	}
+	runArgs := fs.Args()
+	if err := checkAgentArgs(runArgs); err != nil {
+		log.Fatalf("coordinate: %v", err)
+	}
+	token, err := agentToken()
+	if err != nil {
+		log.Fatalf("coordinate: %v", err)
+	}
+	if *seed == 0 {
+		*seed = rand.Int63()
+	}
+
+	conns := make([]*grpc.ClientConn, len(addrs))
+	for i, addr := range addrs {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithPerRPCCredentials(tokenCreds(token)), grpc.WithDefaultCallOptions(grpc.CallContentSubtype(jsonCodec{}.Name())))
+		if err != nil {
+			log.Fatalf("coordinate %s: %v", addr, err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+
+	// Ctrl-C stops every agent's run; their partial results still come back and are merged.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		for range sig {
+			log.Printf("Coordinator: stopping agents ...")
+			for _, conn := range conns {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				conn.Invoke(ctx, "/loadrunner.Agent/Stop", &agentStop{}, &agentStop{})
+				cancel()
+			}
+		}
+	}()
+
+	shares := benchmarkgo.SplitRate(*rowsPerSecond, len(addrs))
+	startAt := time.Now().Add(*startDelay)
+	log.Printf("Distributed run: %d agents, %d rows/sec total, seed %d, starting at %s", len(addrs), *rowsPerSecond, *seed, startAt.Format(time.RFC3339Nano))
+	results := make([]*benchmarkgo.Results, len(addrs))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	for i, addr := range addrs {
+		as := &benchmarkgo.AgentAssignment{
+			Index:      i,
+			Count:      len(addrs),
+			RowsPerSec: shares[i],
+			Seed:       *seed + int64(i),
+			StartAt:    startAt,
+			Args:       runArgs,
+		}
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			out := new(benchmarkgo.AgentRun)
+			err := conns[i].Invoke(context.Background(), "/loadrunner.Agent/Run", as, out)
+			if err == nil && out.Error != "" {
+				err = errors.New(out.Error)
+			}
+			if err != nil {
+				log.Printf("Agent %s: %v", addr, err)
+				mu.Lock()
+				failed = append(failed, addr)
+				mu.Unlock()
+				return
+			}
+			results[i] = out.Results
+		}(i, addr)
+	}
+	wg.Wait()
+
+	merged := benchmarkgo.MergeResults(results)
+	benchmarkgo.LogAgentComparison(addrs, results, merged)
+	if merged != nil && *resultsJSON != "" {
+		merged.Metadata["agent_addresses"] = addrs
+		merged.Metadata["seed"] = *seed
+		if err := benchmarkgo.WriteResults(*resultsJSON, merged); err != nil {
+			log.Printf("coordinate --results-json: %v", err)
+		}
+	}
+	if len(failed) > 0 {
+		log.Printf("%d agent(s) failed: %s", len(failed), strings.Join(failed, ", "))
+		os.Exit(1)
+	}
+}
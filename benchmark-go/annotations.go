@@ -0,0 +1,71 @@
+package benchmarkgo
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Annotate records an external event (e.g. "failover started", "node drained") in the run timeline so
+// latency excursions can be correlated with it afterwards.
+func (r *LoadRunner) Annotate(label string) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return
+	}
+	r.events.add(r.runStart, "annotation", label)
+}
+
+// watchAnnotations tails path (polled like the live config) and records every line appended during the run as
+// an annotation. Lines already in the file when the run starts are skipped, so one file can serve several runs.
+// The file may be created after the run starts.
+func (r *LoadRunner) watchAnnotations(ctx context.Context, path string) {
+	var offset int64
+	if st, err := os.Stat(path); err == nil {
+		offset = st.Size()
+	}
+	var partial string // trailing text not yet terminated by a newline
+	ticker := time.NewTicker(liveConfigPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		st, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if st.Size() < offset { // truncated or replaced: start over
+			offset, partial = 0, ""
+		}
+		if st.Size() == offset {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			log.Printf("Annotations %s: %v", path, err)
+			continue
+		}
+		_, err = f.Seek(offset, io.SeekStart)
+		if err == nil {
+			rd := bufio.NewReader(f)
+			for {
+				line, err := rd.ReadString('\n')
+				offset += int64(len(line))
+				if err != nil {
+					partial += line
+					break
+				}
+				r.Annotate(partial + line)
+				partial = ""
+			}
+		}
+		f.Close()
+	}
+}
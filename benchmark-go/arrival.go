@@ -0,0 +1,59 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Arrival processes: how the gaps between batches are spread around the mean set by the target rate.
+const (
+	ArrivalFixed   = "fixed"   // token bucket at the target rate (default)
+	ArrivalUniform = "uniform" // gaps drawn uniformly from 0 to twice the mean
+	ArrivalPoisson = "poisson" // exponential gaps: independent arrivals, bursty like a real HL7 feed
+)
+
+// ValidateArrival returns an error if kind is not a known arrival process.
+func ValidateArrival(kind string) error {
+	switch kind {
+	case ArrivalFixed, ArrivalUniform, ArrivalPoisson:
+		return nil
+	}
+	return fmt.Errorf("unknown arrival process %q (want %s, %s or %s)", kind, ArrivalFixed, ArrivalUniform, ArrivalPoisson)
+}
+
+// arrivals draws the gaps of a random arrival process.
+type arrivals struct {
+	kind string
+	rng  *rand.Rand
+}
+
+func newArrivals(kind string, seed int64) *arrivals {
+	return &arrivals{kind: kind, rng: rand.New(rand.NewSource(seed))}
+}
+
+// gap scales the mean gap between batches by a draw with mean 1.
+func (a *arrivals) gap(mean time.Duration) time.Duration {
+	if a.kind == ArrivalPoisson {
+		return time.Duration(a.rng.ExpFloat64() * float64(mean))
+	}
+	return time.Duration(a.rng.Float64() * 2 * float64(mean))
+}
+
+// waitArrival sleeps until a batch's drawn arrival time. The process is open loop: arrivals are drawn on their
+// own schedule, so batches that fell behind a slow backend are sent at once rather than pushing later ones back.
+func waitArrival(ctx context.Context, due time.Time) error {
+	wait := time.Until(due)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,157 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Target attainment: for each second of the load, the rows the rate target asked for vs the rows the router
+// enqueued to workers and the rows workers committed. Enqueued falling short means producers or pacing lag;
+// enqueued on target but committed short means the database is falling behind.
+
+// attainmentWorst is how many of the worst seconds the report lists.
+const attainmentWorst = 10
+
+// AttainmentSecond is one second of the load.
+type AttainmentSecond struct {
+	Second    int   `json:"second"`
+	Target    int64 `json:"target"`
+	Enqueued  int64 `json:"enqueued"`
+	Committed int64 `json:"committed"`
+}
+
+// AttainmentReport summarizes how closely each second of the load met the rate target.
+type AttainmentReport struct {
+	Seconds       int   `json:"seconds"`
+	TargetRows    int64 `json:"target_rows"`
+	EnqueuedRows  int64 `json:"enqueued_rows"`
+	CommittedRows int64 `json:"committed_rows"`
+	// EnqueuedPct and CommittedPct are the share of the target met, per second capped at the target so that a
+	// catch-up burst does not hide the seconds that fell short.
+	EnqueuedPct  float64            `json:"enqueued_pct"`
+	CommittedPct float64            `json:"committed_pct"`
+	Worst        []AttainmentSecond `json:"worst"` // lowest committed/target first
+}
+
+// attainment accumulates per-second counters from start; guarded by mu.
+type attainment struct {
+	mu        sync.Mutex
+	start     time.Time
+	target    []int64
+	enqueued  []int64
+	committed []int64
+}
+
+var attainmentRec attainment
+
+// startAttainment resets the counters; second 0 begins at start.
+func startAttainment(start time.Time) {
+	a := &attainmentRec
+	a.mu.Lock()
+	a.start = start
+	a.target, a.enqueued, a.committed = nil, nil, nil
+	a.mu.Unlock()
+}
+
+// add adds rows to the current second of counts, growing it as needed. No-op before startAttainment.
+func (a *attainment) add(counts *[]int64, rows int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.start.IsZero() {
+		return
+	}
+	sec := int(time.Since(a.start) / time.Second)
+	for len(*counts) <= sec {
+		*counts = append(*counts, 0)
+	}
+	(*counts)[sec] += int64(rows)
+}
+
+// noteEnqueued records rows the router handed to a worker.
+func noteEnqueued(rows int) {
+	attainmentRec.add(&attainmentRec.enqueued, rows)
+}
+
+// noteCommitted records rows a worker inserted successfully.
+func noteCommitted(rows int) {
+	attainmentRec.add(&attainmentRec.committed, rows)
+}
+
+// runAttainment records each second's target: the limiter's current rate, or 0 during duty-cycle idle phases.
+func (r *LoadRunner) runAttainment(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			target := int64(r.rateLimiter.Limit())
+			if r.dutyGate != nil && r.dutyGate.paused() {
+				target = 0
+			}
+			a := &attainmentRec
+			a.mu.Lock()
+			a.target = append(a.target, target)
+			a.mu.Unlock()
+		}
+	}
+}
+
+// loadAttainment summarizes the seconds that have a recorded target; nil before the first full second.
+func loadAttainment() *AttainmentReport {
+	a := &attainmentRec
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.target) == 0 {
+		return nil
+	}
+	at := func(counts []int64, sec int) int64 {
+		if sec < len(counts) {
+			return counts[sec]
+		}
+		return 0
+	}
+	rep := &AttainmentReport{Seconds: len(a.target)}
+	var enqueuedMet, committedMet int64
+	var active []AttainmentSecond
+	for sec, target := range a.target {
+		s := AttainmentSecond{Second: sec, Target: target, Enqueued: at(a.enqueued, sec), Committed: at(a.committed, sec)}
+		rep.TargetRows += s.Target
+		rep.EnqueuedRows += s.Enqueued
+		rep.CommittedRows += s.Committed
+		enqueuedMet += min(s.Enqueued, s.Target)
+		committedMet += min(s.Committed, s.Target)
+		if s.Target > 0 {
+			active = append(active, s)
+		}
+	}
+	if rep.TargetRows > 0 {
+		rep.EnqueuedPct = float64(enqueuedMet) / float64(rep.TargetRows) * 100
+		rep.CommittedPct = float64(committedMet) / float64(rep.TargetRows) * 100
+	}
+	ratio := func(s AttainmentSecond) float64 { return float64(s.Committed) / float64(s.Target) }
+	sort.SliceStable(active, func(i, j int) bool { return ratio(active[i]) < ratio(active[j]) })
+	rep.Worst = active[:min(len(active), attainmentWorst)]
+	return rep
+}
+
+// logAttainment logs the overall target attainment and the worst seconds.
+func logAttainment(rep *AttainmentReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Target attainment over %d s: enqueued %.1f%%, committed %.1f%% of %d target rows",
+		rep.Seconds, rep.EnqueuedPct, rep.CommittedPct, rep.TargetRows)
+	if len(rep.Worst) == 0 {
+		return
+	}
+	log.Printf("  Worst seconds:  second     target   enqueued  committed  attained")
+	for _, s := range rep.Worst {
+		log.Printf("                  %6d %10d %10d %10d  %7.1f%%",
+			s.Second, s.Target, s.Enqueued, s.Committed, float64(s.Committed)/float64(s.Target)*100)
+	}
+}
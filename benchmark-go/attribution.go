@@ -0,0 +1,106 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ServerTimedQuerier is implemented by Queriers that can report the server-side execution time of a
+// primary-key lookup (e.g. ClickHouse progress packets, Postgres statement timestamps).
+type ServerTimedQuerier interface {
+	// QueryByPrimaryKeyTimed is QueryByPrimaryKey plus the server-reported duration (0 if unavailable).
+	QueryByPrimaryKeyTimed(ctx context.Context, mrn string) (n int, server time.Duration, err error)
+}
+
+// maxAttributionSamples bounds the reservoir of per-sample gaps kept for percentiles.
+const maxAttributionSamples = 10000
+
+// LatencyAttribution splits sampled client-observed latency into server time and the remaining
+// network/queueing/driver gap.
+type LatencyAttribution struct {
+	Samples      int64   `json:"samples"`
+	ClientSec    float64 `json:"client_sec"` // sums over samples
+	ServerSec    float64 `json:"server_sec"`
+	GapP50Ms     float64 `json:"gap_p50_ms"`
+	GapP99Ms     float64 `json:"gap_p99_ms"`
+	GapMaxMs     float64 `json:"gap_max_ms"`
+	ServerShare  float64 `json:"server_share"` // ServerSec / ClientSec
+	ServerSource string  `json:"server_source,omitempty"`
+}
+
+// attribution collects samples; guarded by attributionMu.
+var (
+	attributionMu   sync.Mutex
+	attributionN    int64
+	attributionCli  time.Duration
+	attributionSrv  time.Duration
+	attributionGaps []time.Duration
+	attributionMax  time.Duration
+)
+
+// AddLatencySample records one sampled operation's client-observed and server-reported durations.
+func AddLatencySample(client, server time.Duration) {
+	gap := client - server
+	if gap < 0 {
+		gap = 0
+	}
+	attributionMu.Lock()
+	attributionN++
+	attributionCli += client
+	attributionSrv += server
+	if gap > attributionMax {
+		attributionMax = gap
+	}
+	if len(attributionGaps) < maxAttributionSamples {
+		attributionGaps = append(attributionGaps, gap)
+	} else if i := rand.Int63n(attributionN); i < maxAttributionSamples {
+		attributionGaps[i] = gap
+	}
+	attributionMu.Unlock()
+}
+
+// loadAttribution summarizes the samples; nil when none were taken.
+func loadAttribution() *LatencyAttribution {
+	attributionMu.Lock()
+	defer attributionMu.Unlock()
+	if attributionN == 0 {
+		return nil
+	}
+	gaps := append([]time.Duration(nil), attributionGaps...)
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	pct := func(p float64) float64 {
+		return float64(gaps[int(p*float64(len(gaps)-1))].Microseconds()) / 1000
+	}
+	a := &LatencyAttribution{
+		Samples:   attributionN,
+		ClientSec: attributionCli.Seconds(),
+		ServerSec: attributionSrv.Seconds(),
+		GapP50Ms:  pct(0.50),
+		GapP99Ms:  pct(0.99),
+		GapMaxMs:  float64(attributionMax.Microseconds()) / 1000,
+	}
+	if a.ClientSec > 0 {
+		a.ServerShare = a.ServerSec / a.ClientSec
+	}
+	return a
+}
+
+// sampleLatency reports whether this operation should be attributed (probability rate).
+func sampleLatency(rate float64) bool {
+	return rate > 0 && (rate >= 1 || rand.Float64() < rate)
+}
+
+// logAttribution logs the client/server/gap split (only when samples were taken).
+func logAttribution(a *LatencyAttribution) {
+	if a == nil {
+		return
+	}
+	n := float64(a.Samples)
+	log.Printf("Latency attribution (%d sampled pk lookups): client avg %.2f ms | server avg %.2f ms (%.0f%%) | network/queueing gap avg %.2f ms, p50 %.2f ms, p99 %.2f ms, max %.2f ms",
+		a.Samples, a.ClientSec/n*1000, a.ServerSec/n*1000, a.ServerShare*100,
+		(a.ClientSec-a.ServerSec)/n*1000, a.GapP50Ms, a.GapP99Ms, a.GapMaxMs)
+}
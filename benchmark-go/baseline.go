@@ -0,0 +1,62 @@
+package benchmarkgo
+
+import (
+	"log"
+	"slices"
+	"strings"
+)
+
+// BaselineOptions gates the run against a stored results file (disabled when Path is "").
+type BaselineOptions struct {
+	Path         string  // Results JSON of the baseline run
+	ThresholdPct float64 // fail when a gated metric is worse by more than this percentage
+}
+
+// gatedMetrics are the comparison metrics the baseline gate fails on: throughput drops and p99 rises.
+var gatedMetrics = []string{"insert rows/s", "insert p99 ms", "query p99 ms"}
+
+// BaselineReport is the outcome of the baseline gate.
+type BaselineReport struct {
+	Path         string             `json:"path"`
+	ThresholdPct float64            `json:"threshold_pct"`
+	Metrics      []ComparisonMetric `json:"metrics"`
+	Regressions  []string           `json:"regressions,omitempty"`
+	Passed       bool               `json:"passed"`
+}
+
+// checkBaseline compares res with the baseline file on the gated metrics.
+func checkBaseline(opts BaselineOptions, res *Results) *BaselineReport {
+	rep := &BaselineReport{Path: opts.Path, ThresholdPct: opts.ThresholdPct}
+	baseline, err := ReadResults(opts.Path)
+	if err != nil {
+		log.Printf("Baseline: %v", err)
+		rep.Regressions = []string{"baseline unreadable"}
+		return rep
+	}
+	for _, m := range CompareResults(baseline, res, opts.ThresholdPct) {
+		if slices.Contains(gatedMetrics, m.Name) {
+			rep.Metrics = append(rep.Metrics, m)
+		}
+	}
+	rep.Regressions = Regressions(rep.Metrics)
+	rep.Passed = len(rep.Regressions) == 0
+	return rep
+}
+
+// logBaseline logs the gated metrics and the verdict.
+func logBaseline(rep *BaselineReport) {
+	if rep == nil {
+		return
+	}
+	LogComparison(rep.Path, "this run", rep.Metrics, rep.ThresholdPct)
+	if rep.Passed {
+		log.Printf("Baseline gate: PASSED (no gated metric worse than %g%%)", rep.ThresholdPct)
+	} else {
+		log.Printf("Baseline gate: FAILED: %s", strings.Join(rep.Regressions, ", "))
+	}
+}
+
+// BaselineFailed reports whether the run regressed against its baseline (false when no baseline was set).
+func (r *LoadRunner) BaselineFailed() bool {
+	return r.baseline != nil && !r.baseline.Passed
+}
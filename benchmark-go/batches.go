@@ -0,0 +1,124 @@
+package benchmarkgo
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Insert latency is reported per row (batch latency / rows) and per batch; the per-row figure moves with batch
+// size alone, so the sizes of the statements actually sent are tracked separately. Producers always enqueue
+// full --batch-size batches (there is no timeout flush), but a worker sends a pair's originals and its
+// deduplicated duplicates as separate statements, so statement sizes depend on the duplicate ratio.
+
+// Batch kinds: how a statement's rows were cut from the producer's batch.
+const (
+	BatchWhole      = "whole"      // the entire batch in one statement (no duplicates)
+	BatchOriginals  = "originals"  // the batch's originals, duplicates sent separately
+	BatchDuplicates = "duplicates" // the batch's deduplicated duplicates
+)
+
+var batchKinds = []string{BatchWhole, BatchOriginals, BatchDuplicates}
+
+// maxBatchRows bounds the batch-size histogram; larger statements are clamped.
+const maxBatchRows = 1_000_000
+
+// BatchKindStats is the statements of one kind.
+type BatchKindStats struct {
+	Kind         string  `json:"kind"`
+	Batches      int64   `json:"batches"`
+	Rows         int64   `json:"rows"`
+	AvgRows      float64 `json:"avg_rows"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"` // successful statements only
+}
+
+// BatchReport is the distribution of statement sizes next to the configured batch size.
+type BatchReport struct {
+	Configured int              `json:"configured_batch_size"`
+	Batches    int64            `json:"batches"`
+	SizeMin    int64            `json:"size_min"`
+	SizeP50    int64            `json:"size_p50"`
+	SizeP90    int64            `json:"size_p90"`
+	SizeP99    int64            `json:"size_p99"`
+	SizeMax    int64            `json:"size_max"`
+	Kinds      []BatchKindStats `json:"kinds"`
+}
+
+// batchKindTotals accumulates one kind; guarded by batchMu.
+type batchKindTotals struct {
+	batches   int64
+	rows      int64
+	succeeded int64
+	latency   time.Duration
+}
+
+var (
+	batchMu     sync.Mutex
+	batchSizes  = hdrhistogram.New(1, maxBatchRows, 3)
+	batchTotals = map[string]*batchKindTotals{}
+)
+
+// recordBatch records one statement of kind with rows rows; latency counts only when ok.
+func recordBatch(kind string, rows int, latency time.Duration, ok bool) {
+	if rows <= 0 {
+		return
+	}
+	batchMu.Lock()
+	defer batchMu.Unlock()
+	batchSizes.RecordValue(min(int64(rows), maxBatchRows))
+	t := batchTotals[kind]
+	if t == nil {
+		t = &batchKindTotals{}
+		batchTotals[kind] = t
+	}
+	t.batches++
+	t.rows += int64(rows)
+	if ok {
+		t.succeeded++
+		t.latency += latency
+	}
+}
+
+// loadBatchReport summarizes the statements sent; nil before the first.
+func loadBatchReport(configured int) *BatchReport {
+	batchMu.Lock()
+	defer batchMu.Unlock()
+	if batchSizes.TotalCount() == 0 {
+		return nil
+	}
+	rep := &BatchReport{
+		Configured: configured,
+		Batches:    batchSizes.TotalCount(),
+		SizeMin:    batchSizes.Min(),
+		SizeP50:    batchSizes.ValueAtQuantile(50),
+		SizeP90:    batchSizes.ValueAtQuantile(90),
+		SizeP99:    batchSizes.ValueAtQuantile(99),
+		SizeMax:    batchSizes.Max(),
+	}
+	for _, kind := range batchKinds {
+		t := batchTotals[kind]
+		if t == nil {
+			continue
+		}
+		ks := BatchKindStats{Kind: kind, Batches: t.batches, Rows: t.rows, AvgRows: float64(t.rows) / float64(t.batches)}
+		if t.succeeded > 0 {
+			ks.AvgLatencyMs = float64(t.latency.Microseconds()) / 1000 / float64(t.succeeded)
+		}
+		rep.Kinds = append(rep.Kinds, ks)
+	}
+	return rep
+}
+
+// logBatches logs the statement-size distribution and per-kind sizes and latencies.
+func logBatches(rep *BatchReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Insert statements: %d, rows per statement min %d | p50 %d | p90 %d | p99 %d | max %d (--batch-size %d)",
+		rep.Batches, rep.SizeMin, rep.SizeP50, rep.SizeP90, rep.SizeP99, rep.SizeMax, rep.Configured)
+	for _, k := range rep.Kinds {
+		log.Printf("  %-10s %8d statements, avg %.1f rows, avg latency %.2f ms", k.Kind, k.Batches, k.AvgRows, k.AvgLatencyMs)
+	}
+}
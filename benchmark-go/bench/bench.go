@@ -0,0 +1,80 @@
+// Package bench runs the benchmark from Go code: start from NewConfig with a backend context (e.g.
+// &clickhouse.Context{} or sqlite.NewContext()), adjust the settings, and Run returns the Results that loadrunner
+// --results-json would write, as a struct.
+// Progress and the summary still go to the standard logger; point it elsewhere (log.SetOutput) to silence them.
+//
+// The run's counters are process-wide, so a process runs one benchmark: once a Run gets past the backend's Setup, a
+// later Run returns ErrAlreadyRan. A Run that fails before then (invalid settings, an unreachable database) can be
+// retried.
+// Harnesses that run several benchmarks start one process per run, as loadrunner's multi-target mode does.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// Results is a finished run's stats, reports and effective settings.
+type Results = benchmarkgo.Results
+
+// Config is one benchmark run: the load settings and the backend to run them against.
+type Config struct {
+	benchmarkgo.Config
+	Backend  benchmarkgo.WorkerCtx // required; owns the connections (Setup and Teardown run inside Run)
+	Metadata map[string]any        // recorded in Results.Metadata next to the runner's own entries
+}
+
+// NewConfig returns a Config with loadrunner's default settings (benchmarkgo.DefaultConfig) against backend.
+func NewConfig(database string, backend benchmarkgo.WorkerCtx) Config {
+	return Config{Config: benchmarkgo.DefaultConfig(database), Backend: backend}
+}
+
+// ErrAlreadyRan is returned by a Run after one that started, or while another Run is in progress.
+var ErrAlreadyRan = errors.New("bench: a benchmark already ran in this process (its counters are process-wide)")
+
+var (
+	runMu sync.Mutex // held for the length of a Run
+	ran   bool       // a Run got past Setup; guarded by runMu
+)
+
+// Run executes the benchmark and returns its results. Cancelling ctx ends the load early, like Ctrl-C: the run
+// drains and still returns results. Errors are for runs that could not start (invalid settings, setup failures),
+// which return empty Results, and for runs stopped by a failure, which return the Results up to that point.
+func Run(ctx context.Context, cfg Config) (Results, error) {
+	if cfg.Backend == nil {
+		return Results{}, errors.New("bench: Config.Backend is required")
+	}
+	if err := cfg.Validate(); err != nil {
+		return Results{}, fmt.Errorf("bench: %w", err)
+	}
+	if !runMu.TryLock() {
+		return Results{}, ErrAlreadyRan
+	}
+	defer runMu.Unlock()
+	if ran {
+		return Results{}, ErrAlreadyRan
+	}
+	r := benchmarkgo.NewLoadRunner(cfg.Config, cfg.Backend)
+	r.SetMetadata("table_prefix", benchmarkgo.TablePrefix())
+	r.SetMetadata("table", benchmarkgo.Table())
+	for k, v := range cfg.Metadata {
+		r.SetMetadata(k, v)
+	}
+	err := r.Run(ctx)
+	ran = r.Started()
+	if err != nil {
+		if res := r.Results(); res != nil {
+			return *res, err
+		}
+		return Results{}, err
+	}
+	res := r.Results()
+	if res == nil {
+		return Results{}, errors.New("bench: the run finished without results")
+	}
+	return *res, nil
+}
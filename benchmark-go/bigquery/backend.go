@@ -0,0 +1,238 @@
+//go:build bigquery
+
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	bq "cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Schema is hl7_messages as a BigQuery table: STRING columns, TIMESTAMP created/updated and a required MRN.
+func Schema() bq.Schema {
+	schema := make(bq.Schema, len(sqldb.Columns))
+	for i, c := range sqldb.Columns {
+		f := &bq.FieldSchema{Name: c, Type: bq.StringFieldType}
+		switch c {
+		case "created_at", "updated_at":
+			f.Type, f.Required = bq.TimestampFieldType, true
+		case "medical_record_number":
+			f.Required = true
+		}
+		schema[i] = f
+	}
+	return schema
+}
+
+// InitSchema creates the dataset (in location) and the table, clustered by medical_record_number then
+// patient_id so point lookups prune blocks; there are no unique keys, so duplicates append.
+func InitSchema(ctx context.Context, client *bq.Client, dataset, location string) error {
+	ds := client.Dataset(dataset)
+	if err := ds.Create(ctx, &bq.DatasetMetadata{Location: location}); err != nil && !isAlreadyExists(err) {
+		return err
+	}
+	err := ds.Table(benchmarkgo.Table()).Create(ctx, &bq.TableMetadata{
+		Schema:     Schema(),
+		Clustering: &bq.Clustering{Fields: []string{"medical_record_number", "patient_id"}},
+	})
+	if err != nil && !isAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// RowDescriptor returns the proto descriptor of a table row for the Storage Write API, and the normalized form
+// the stream is opened with.
+func RowDescriptor() (protoreflect.MessageDescriptor, *descriptorpb.DescriptorProto, error) {
+	tableSchema, err := adapt.BQSchemaToStorageTableSchema(Schema())
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := adapt.StorageSchemaToProto2Descriptor(tableSchema, "root")
+	if err != nil {
+		return nil, nil, err
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, nil, fmt.Errorf("bigquery: row descriptor is %T, not a message", d)
+	}
+	dp, err := adapt.NormalizeDescriptor(md)
+	if err != nil {
+		return nil, nil, err
+	}
+	return md, dp, nil
+}
+
+// OpenDefaultStream opens a managed writer on the table's default stream: appends are committed on success
+// and visible to queries at once, with no stream lifecycle to manage.
+func OpenDefaultStream(ctx context.Context, mw *managedwriter.Client, project, dataset string, dp *descriptorpb.DescriptorProto) (*managedwriter.ManagedStream, error) {
+	return mw.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(managedwriter.TableParentFromParts(project, dataset, benchmarkgo.Table())),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(dp))
+}
+
+// EncodeRows serializes rows as proto messages of md; TIMESTAMP columns are microseconds since the epoch.
+// Returns the encoded rows and their total size.
+func EncodeRows(md protoreflect.MessageDescriptor, rows []benchmarkgo.RowForDB) ([][]byte, int64, error) {
+	now := time.Now().UTC()
+	out := make([][]byte, 0, len(rows))
+	var size int64
+	for _, r := range rows {
+		values, err := sqldb.RowFromJSON(r.JSONMessage, now)
+		if err != nil {
+			return nil, 0, err
+		}
+		msg := dynamicpb.NewMessage(md)
+		for i, c := range sqldb.Columns {
+			fd := md.Fields().ByName(protoreflect.Name(c))
+			switch v := values[i].(type) {
+			case nil:
+			case time.Time:
+				msg.Set(fd, protoreflect.ValueOfInt64(v.UnixMicro()))
+			case string:
+				if fd.Kind() == protoreflect.Int64Kind {
+					t, err := time.Parse(time.RFC3339Nano, v)
+					if err != nil {
+						t = now
+					}
+					msg.Set(fd, protoreflect.ValueOfInt64(t.UnixMicro()))
+				} else {
+					msg.Set(fd, protoreflect.ValueOfString(v))
+				}
+			default:
+				msg.Set(fd, protoreflect.ValueOfString(fmt.Sprint(v)))
+			}
+		}
+		b, err := proto.Marshal(msg)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, b)
+		size += int64(len(b))
+	}
+	return out, size, nil
+}
+
+// AppendRows appends one batch to the stream and waits for the server's acknowledgment.
+func AppendRows(ctx context.Context, stream *managedwriter.ManagedStream, data [][]byte) error {
+	res, err := stream.AppendRows(ctx, data)
+	if err != nil {
+		return err
+	}
+	_, err = res.GetResult(ctx)
+	return err
+}
+
+// tableRef returns the fully qualified `project.dataset.table` for SQL.
+func tableRef(project, dataset string) string {
+	return "`" + project + "." + dataset + "." + benchmarkgo.Table() + "`"
+}
+
+// runQuery runs sql with named parameters (@name) and returns the row count and the raw value bytes read.
+func runQuery(ctx context.Context, client *bq.Client, sql string, params map[string]interface{}) (int, int64, error) {
+	q := client.Query(sql)
+	for name, v := range params {
+		q.Parameters = append(q.Parameters, bq.QueryParameter{Name: name, Value: v})
+	}
+	it, err := q.Read(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	var n int
+	var size int64
+	for {
+		var row []bq.Value
+		err := it.Next(&row)
+		if err == iterator.Done {
+			return n, size, nil
+		}
+		if err != nil {
+			return n, size, err
+		}
+		n++
+		for _, v := range row {
+			if s, ok := v.(string); ok {
+				size += int64(len(s))
+			}
+		}
+	}
+}
+
+// scalar runs sql with named parameters and returns the first column of its first row.
+func scalar(ctx context.Context, client *bq.Client, sql string, params map[string]interface{}) (bq.Value, error) {
+	q := client.Query(sql)
+	for name, v := range params {
+		q.Parameters = append(q.Parameters, bq.QueryParameter{Name: name, Value: v})
+	}
+	it, err := q.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var row []bq.Value
+	if err := it.Next(&row); err != nil {
+		return nil, err
+	}
+	return row[0], nil
+}
+
+// QueryByPrimaryKey returns 1 when mrn exists (duplicates append, so the lookup stops at the first row), else 0.
+func QueryByPrimaryKey(ctx context.Context, client *bq.Client, project, dataset, mrn string) (int, error) {
+	v, err := scalar(ctx, client, "SELECT COUNT(*) FROM (SELECT 1 FROM "+tableRef(project, dataset)+
+		" WHERE medical_record_number = @mrn LIMIT 1)", map[string]interface{}{"mrn": mrn})
+	if err != nil {
+		return 0, err
+	}
+	n, _ := v.(int64)
+	return int(n), nil
+}
+
+// QueryResultSet fetches full rows for the MRN range ending at mrn (descending, LIMIT limit).
+func QueryResultSet(ctx context.Context, client *bq.Client, project, dataset, mrn string, limit int) (int, int64, error) {
+	return runQuery(ctx, client, "SELECT * FROM "+tableRef(project, dataset)+
+		" WHERE medical_record_number <= @mrn ORDER BY medical_record_number DESC LIMIT @lim",
+		map[string]interface{}{"mrn": mrn, "lim": limit})
+}
+
+// QueryByPatientID fetches full rows for patient_id and returns how many were read.
+func QueryByPatientID(ctx context.Context, client *bq.Client, project, dataset, patientID string) (int, error) {
+	n, _, err := runQuery(ctx, client, "SELECT * FROM "+tableRef(project, dataset)+" WHERE patient_id = @pid",
+		map[string]interface{}{"pid": patientID})
+	return n, err
+}
+
+// AggregateByPatientID summarizes the patient's rows (count, latest update, payload size) and returns the count.
+func AggregateByPatientID(ctx context.Context, client *bq.Client, project, dataset, patientID string) (int, error) {
+	v, err := scalar(ctx, client, "SELECT COUNT(*), MAX(updated_at), SUM(BYTE_LENGTH(source)) FROM "+
+		tableRef(project, dataset)+" WHERE patient_id = @pid", map[string]interface{}{"pid": patientID})
+	if err != nil {
+		return 0, err
+	}
+	n, _ := v.(int64)
+	return int(n), nil
+}
+
+// GetMaxPatientCounter returns the max patient ordinal in the table, or -1.
+func GetMaxPatientCounter(ctx context.Context, client *bq.Client, project, dataset string) (int, error) {
+	v, err := scalar(ctx, client, "SELECT MAX(SAFE_CAST(SUBSTR(patient_id, 9) AS INT64)) FROM "+
+		tableRef(project, dataset)+" WHERE STARTS_WITH(patient_id, 'patient-')", nil)
+	if err != nil {
+		return -1, err
+	}
+	n, ok := v.(int64)
+	if !ok {
+		return -1, nil
+	}
+	return int(n), nil
+}
@@ -0,0 +1,39 @@
+//go:build bigquery
+
+package bigquery
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// classifyError maps REST (query) errors to "bigquery:<reason>", e.g. bigquery:rateLimitExceeded, and Storage
+// Write API gRPC errors to "bigquery:<code>", e.g. bigquery:ResourceExhausted.
+func classifyError(err error) (string, bool) {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		if len(apiErr.Errors) > 0 && apiErr.Errors[0].Reason != "" {
+			return "bigquery:" + apiErr.Errors[0].Reason, true
+		}
+		return "bigquery:http_" + strconv.Itoa(apiErr.Code), true
+	}
+	if s, ok := status.FromError(err); ok && s.Code() != codes.OK {
+		return "bigquery:" + s.Code().String(), true
+	}
+	return "", false
+}
+
+// isAlreadyExists reports a 409 from dataset or table creation.
+func isAlreadyExists(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == 409
+}
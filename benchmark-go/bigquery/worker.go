@@ -0,0 +1,184 @@
+//go:build bigquery
+
+// Package bigquery runs the benchmark against Google BigQuery: batches are appended through the Storage Write
+// API's default stream (committed on acknowledgment) and query workers run parameterized point lookups, so
+// cloud warehouse ingestion can be compared with ClickHouse. Build with -tags bigquery.
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	bq "cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"github.com/db-benchmarking/benchmark-go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	defaultDataset  = "db_benchmark"
+	defaultLocation = "US"
+)
+
+// Backend implements benchmarkgo.InsertBackend on one managed default stream, which multiplexes concurrent
+// appends, so the stream is the conn.
+type Backend struct {
+	stream *managedwriter.ManagedStream
+	md     protoreflect.MessageDescriptor
+}
+
+// GetConn returns the shared stream.
+func (b *Backend) GetConn() interface{} {
+	return b.stream
+}
+
+// ReleaseConn is a no-op; the stream is shared.
+func (b *Backend) ReleaseConn(c interface{}) {}
+
+// InsertBatch appends rows as one AppendRows request; Bytes is the encoded proto size.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	stream, ok := conn.(*managedwriter.ManagedStream)
+	if !ok || len(rows) == 0 {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // pgbouncer routing hint; unused for BigQuery
+	data, size, err := EncodeRows(b.md, rows)
+	if err != nil {
+		return benchmarkgo.InsertResult{}, err
+	}
+	if err := AppendRows(context.Background(), stream, data); err != nil {
+		return benchmarkgo.InsertResult{}, err
+	}
+	return benchmarkgo.InsertResult{Rows: len(rows), Statements: 1, Bytes: size}, nil
+}
+
+// Context handles setup/teardown and query workers for BigQuery.
+type Context struct {
+	Project  string
+	Dataset  string
+	Location string
+	client   *bq.Client
+	writer   *managedwriter.Client
+	stream   *managedwriter.ManagedStream
+}
+
+// Setup creates the clients (credentials from Application Default Credentials), the dataset and table if
+// missing, and opens the default write stream.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if c.client != nil {
+		log.Fatal("bigquery Setup already called")
+	}
+	if c.Project == "" {
+		return nil, fmt.Errorf("bigquery: set BIGQUERY_PROJECT (or GOOGLE_CLOUD_PROJECT)")
+	}
+	ctx := context.Background()
+	client, err := bq.NewClient(ctx, c.Project)
+	if err != nil {
+		return nil, err
+	}
+	if err := InitSchema(ctx, client, c.Dataset, c.Location); err != nil {
+		client.Close()
+		return nil, err
+	}
+	log.Printf("Table %s.%s.%s ready (BigQuery, %s)", c.Project, c.Dataset, benchmarkgo.Table(), c.Location)
+	md, dp, err := RowDescriptor()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	writer, err := managedwriter.NewClient(ctx, c.Project)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	stream, err := OpenDefaultStream(ctx, writer, c.Project, c.Dataset, dp)
+	if err != nil {
+		writer.Close()
+		client.Close()
+		return nil, err
+	}
+	c.client, c.writer, c.stream = client, writer, stream
+	log.Printf("Starting insertions via the Storage Write API default stream (target %d rows/sec) ...", targetRPS)
+	return &Backend{stream: stream, md: md}, nil
+}
+
+// Teardown closes the stream and clients.
+func (c *Context) Teardown() {
+	if c.client == nil {
+		return
+	}
+	c.stream.Close()
+	c.writer.Close()
+	c.client.Close()
+	c.client, c.writer, c.stream = nil, nil, nil
+}
+
+// GetMaxPatientCounter returns the max patient ordinal in the table, or -1.
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	return GetMaxPatientCounter(context.Background(), c.client, c.Project, c.Dataset)
+}
+
+// querier binds the query client to benchmarkgo.Querier.
+type querier struct {
+	c *Context
+}
+
+func (q querier) QueryByPrimaryKey(ctx context.Context, mrn string) (int, error) {
+	return QueryByPrimaryKey(ctx, q.c.client, q.c.Project, q.c.Dataset, mrn)
+}
+
+func (q querier) QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error) {
+	return QueryResultSet(ctx, q.c.client, q.c.Project, q.c.Dataset, mrn, limit)
+}
+
+func (q querier) QueryByPatientID(ctx context.Context, patientID string) (int, error) {
+	return QueryByPatientID(ctx, q.c.client, q.c.Project, q.c.Dataset, patientID)
+}
+
+func (q querier) AggregateByPatientID(ctx context.Context, patientID string) (int, error) {
+	return AggregateByPatientID(ctx, q.c.client, q.c.Project, q.c.Dataset, patientID)
+}
+
+// RunQueryWorker consumes from queryQueue, runs the configured queries per MRN, reports via benchmarkgo.AddQuery.
+// workerIndex is the 0-based index of this query worker.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex // reserved for logging/tracing
+	runner := benchmarkgo.NewQueryRunner(opts)
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
+			if time.Now().Before(deadline) {
+				time.Sleep(time.Until(deadline))
+			}
+		}
+		count, failed, latency := runner.Run(context.Background(), querier{c}, job)
+		benchmarkgo.AddQuery(int64(count), latency.Microseconds(), int64(failed))
+	}
+}
+
+// NewContext returns a worker context for BIGQUERY_PROJECT (or GOOGLE_CLOUD_PROJECT), dataset BIGQUERY_DATASET
+// (default db_benchmark) created in BIGQUERY_LOCATION (default US).
+func NewContext() *Context {
+	project := os.Getenv("BIGQUERY_PROJECT")
+	if project == "" {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	c := &Context{Project: project, Dataset: os.Getenv("BIGQUERY_DATASET"), Location: os.Getenv("BIGQUERY_LOCATION")}
+	if c.Dataset == "" {
+		c.Dataset = defaultDataset
+	}
+	if c.Location == "" {
+		c.Location = defaultLocation
+	}
+	return c
+}
@@ -0,0 +1,116 @@
+package benchmarkgo
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Circuit breaker: with Config.Breaker enabled, each insert worker stops sending batches after consecutive failed
+// or slow ones and sheds the records it dequeues for BreakerOptions.PauseSec, then lets one batch through to probe
+// the database. Shed records are reported separately instead of piling errors and timeouts onto a dying database.
+
+// BreakerOptions configures the per-worker circuit breaker; the zero value disables it.
+type BreakerOptions struct {
+	Failures    int     // consecutive failed batches that open the breaker (0 = no failure trigger)
+	SlowSec     float64 // a batch taking longer than this counts as slow (0 = no latency trigger)
+	SlowBatches int     // consecutive slow batches that open the breaker
+	PauseSec    float64 // how long an open breaker sheds records before probing with one batch
+}
+
+// Enabled reports whether either trigger is set.
+func (o BreakerOptions) Enabled() bool {
+	return o.Failures > 0 || o.SlowSec > 0
+}
+
+// BreakerReport is what the insert workers' circuit breakers did during the run.
+type BreakerReport struct {
+	Trips        int64 `json:"trips"`
+	FailureTrips int64 `json:"failure_trips"` // opened after consecutive failed batches
+	SlowTrips    int64 `json:"slow_trips"`    // opened after consecutive slow batches
+	ShedBatches  int64 `json:"shed_batches"`
+	ShedRows     int64 `json:"shed_rows"` // records dropped while a breaker was open, never sent
+}
+
+var breakerFailureTrips, breakerSlowTrips, breakerShedBatches, breakerShedRows atomic.Int64
+
+// breaker is one insert worker's circuit breaker; only that worker's goroutine uses it.
+type breaker struct {
+	opts      BreakerOptions
+	failures  int
+	slow      int
+	openUntil time.Time
+}
+
+// newBreaker returns a breaker for opts, or nil when it is disabled.
+func newBreaker(opts BreakerOptions) *breaker {
+	if !opts.Enabled() {
+		return nil
+	}
+	return &breaker{opts: opts}
+}
+
+// allow reports whether a batch may go to the backend: false while the breaker is open. A nil breaker allows all.
+func (b *breaker) allow() bool {
+	return b == nil || !time.Now().Before(b.openUntil)
+}
+
+// record notes a batch's outcome, opening the breaker when a trigger's threshold is reached. A failed probe after
+// a pause reopens it at once, since its counters still hold the streak that opened it.
+func (b *breaker) record(worker int, ok bool, latency time.Duration) {
+	if b == nil {
+		return
+	}
+	if ok {
+		b.failures = 0
+	} else {
+		b.failures++
+	}
+	if ok && b.opts.SlowSec > 0 && latency.Seconds() > b.opts.SlowSec {
+		b.slow++
+	} else {
+		b.slow = 0
+	}
+	pause := time.Duration(b.opts.PauseSec * float64(time.Second))
+	switch {
+	case b.opts.Failures > 0 && b.failures >= b.opts.Failures:
+		breakerFailureTrips.Add(1)
+		log.Printf("Circuit breaker: insert worker %d open for %.1fs after %d consecutive failed batches", worker, b.opts.PauseSec, b.failures)
+	case b.opts.SlowSec > 0 && b.slow >= max(b.opts.SlowBatches, 1):
+		breakerSlowTrips.Add(1)
+		log.Printf("Circuit breaker: insert worker %d open for %.1fs after %d consecutive batches over %gms", worker, b.opts.PauseSec, b.slow, b.opts.SlowSec*1000)
+	default:
+		return
+	}
+	b.openUntil = time.Now().Add(pause)
+}
+
+// shed counts a batch dropped while the breaker was open.
+func shed(rows int) {
+	breakerShedBatches.Add(1)
+	breakerShedRows.Add(int64(rows))
+}
+
+// loadBreaker returns the breaker report, or nil when the breaker is disabled.
+func loadBreaker(opts BreakerOptions) *BreakerReport {
+	if !opts.Enabled() {
+		return nil
+	}
+	f, s := breakerFailureTrips.Load(), breakerSlowTrips.Load()
+	return &BreakerReport{
+		Trips:        f + s,
+		FailureTrips: f,
+		SlowTrips:    s,
+		ShedBatches:  breakerShedBatches.Load(),
+		ShedRows:     breakerShedRows.Load(),
+	}
+}
+
+// logBreaker logs the breaker report (only when the breaker is enabled).
+func logBreaker(rep *BreakerReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Circuit breaker: %d trips (%d on failures, %d on latency) | shed %d rows in %d batches",
+		rep.Trips, rep.FailureTrips, rep.SlowTrips, rep.ShedRows, rep.ShedBatches)
+}
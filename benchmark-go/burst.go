@@ -0,0 +1,170 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Burst injection: every EverySec the rate rises by RPS for DurationSec on top of the steady target, and latency
+// is reported separately for burst and non-burst windows.
+
+// BurstOptions superimposes periodic bursts on the target rate.
+type BurstOptions struct {
+	RPS         int     // rows/sec added during a burst (0 = no bursts)
+	DurationSec float64 // length of each burst
+	EverySec    float64 // time from one burst's start to the next; the first starts EverySec into the run
+}
+
+// Enabled reports whether bursts are configured.
+func (o BurstOptions) Enabled() bool {
+	return o.RPS > 0
+}
+
+// ParseBurst parses "rate=ROWS_PER_SEC,duration=D,every=D" where D is a Go duration or plain seconds, e.g.
+// "rate=10000,duration=5s,every=60s".
+func ParseBurst(s string) (BurstOptions, error) {
+	var o BurstOptions
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return o, fmt.Errorf("%q: want key=value", kv)
+		}
+		var err error
+		switch k {
+		case "rate":
+			o.RPS, err = strconv.Atoi(v)
+		case "duration":
+			o.DurationSec, err = parseSeconds(v)
+		case "every":
+			o.EverySec, err = parseSeconds(v)
+		default:
+			return o, fmt.Errorf("unknown key %q (want rate, duration or every)", k)
+		}
+		if err != nil {
+			return o, fmt.Errorf("%s: %v", k, err)
+		}
+	}
+	if o.RPS <= 0 || !(o.DurationSec > 0) || !(o.EverySec > o.DurationSec) { // negated so NaN fails too
+		return o, fmt.Errorf("want rate > 0, duration > 0 and every > duration")
+	}
+	return o, nil
+}
+
+// parseSeconds parses a Go duration ("5s", "1m") or a plain number of seconds.
+func parseSeconds(s string) (float64, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d.Seconds(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// burstActive is set during a burst: the router adds the burst rate and latencies are recorded under it.
+var burstActive atomic.Bool
+
+// burstWindow is the latencyRecorder.windows index for samples recorded now.
+func burstWindow() int {
+	if burstActive.Load() {
+		return 1
+	}
+	return 0
+}
+
+// BurstWindowStats is the latency of one kind of window.
+type BurstWindowStats struct {
+	Insert   *LatencyPercentiles `json:"insert_latency,omitempty"` // per batch
+	EndToEnd *LatencyPercentiles `json:"end_to_end_latency,omitempty"`
+	Query    *LatencyPercentiles `json:"query_latency,omitempty"`
+}
+
+// BurstReport compares latency during bursts with the rest of the run.
+type BurstReport struct {
+	RPS         int              `json:"rps"`
+	DurationSec float64          `json:"duration_sec"`
+	EverySec    float64          `json:"every_sec"`
+	Bursts      int              `json:"bursts"`
+	Burst       BurstWindowStats `json:"burst"`
+	Steady      BurstWindowStats `json:"steady"`
+}
+
+// runBursts toggles burstActive on the configured schedule until ctx ends.
+func (r *LoadRunner) runBursts(ctx context.Context) {
+	opts := r.Config.Burst
+	defer burstActive.Store(false)
+	every := time.Duration(opts.EverySec * float64(time.Second))
+	length := time.Duration(opts.DurationSec * float64(time.Second))
+	for next := r.runStart.Add(every); ; next = next.Add(every) {
+		if !sleepCtx(ctx, time.Until(next)) {
+			return
+		}
+		burstActive.Store(true)
+		r.bursts.Add(1)
+		r.events.add(r.runStart, "burst", fmt.Sprintf("+%d rows/sec for %gs", opts.RPS, opts.DurationSec))
+		ok := sleepCtx(ctx, length)
+		burstActive.Store(false)
+		if !ok {
+			return
+		}
+	}
+}
+
+// sleepCtx sleeps for d; false if ctx ended first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// loadBurst reads the burst and non-burst latencies; nil unless bursts are configured.
+func (r *LoadRunner) loadBurst() *BurstReport {
+	opts := r.Config.Burst
+	if !opts.Enabled() {
+		return nil
+	}
+	stats := func(burst bool) BurstWindowStats {
+		return BurstWindowStats{
+			Insert:   insertLatencies.window(burst),
+			EndToEnd: endToEndLatencies.window(burst),
+			Query:    queryLatencies.window(burst),
+		}
+	}
+	return &BurstReport{
+		RPS:         opts.RPS,
+		DurationSec: opts.DurationSec,
+		EverySec:    opts.EverySec,
+		Bursts:      int(r.bursts.Load()),
+		Burst:       stats(true),
+		Steady:      stats(false),
+	}
+}
+
+// logBurst logs latency during bursts next to the rest of the run.
+func logBurst(rep *BurstReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Bursts: %d × +%d rows/sec for %gs every %gs", rep.Bursts, rep.RPS, rep.DurationSec, rep.EverySec)
+	for _, w := range []struct {
+		name string
+		s    BurstWindowStats
+	}{{"burst", rep.Burst}, {"non-burst", rep.Steady}} {
+		if w.s.Insert != nil {
+			log.Printf("  %-9s insert/batch %s", w.name, w.s.Insert)
+		}
+		if w.s.EndToEnd != nil {
+			log.Printf("  %-9s insert/e2e   %s", w.name, w.s.EndToEnd)
+		}
+		if w.s.Query != nil {
+			log.Printf("  %-9s query        %s", w.name, w.s.Query)
+		}
+	}
+}
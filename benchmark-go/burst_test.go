@@ -0,0 +1,40 @@
+package benchmarkgo
+
+import "testing"
+
+func TestParseBurst(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    BurstOptions
+		wantErr bool
+	}{
+		{in: "rate=10000,duration=5s,every=60s", want: BurstOptions{RPS: 10000, DurationSec: 5, EverySec: 60}},
+		{in: "every=1m, rate=500, duration=1.5", want: BurstOptions{RPS: 500, DurationSec: 1.5, EverySec: 60}},
+		{in: "rate=1,duration=500ms,every=2", want: BurstOptions{RPS: 1, DurationSec: 0.5, EverySec: 2}},
+		{in: "", wantErr: true},
+		{in: "rate=10000", wantErr: true},
+		{in: "rate=10000,duration=5s", wantErr: true},
+		{in: "rate,duration=5s,every=60s", wantErr: true},
+		{in: "rate=1e4,duration=5s,every=60s", wantErr: true},
+		{in: "rate=10000,duration=5x,every=60s", wantErr: true},
+		{in: "rate=10000,duration=5s,every=1h1", wantErr: true},
+		{in: "rate=10000,duration=5s,every=60s,jitter=1s", wantErr: true},
+		{in: "rate=0,duration=5s,every=60s", wantErr: true},
+		{in: "rate=-10,duration=5s,every=60s", wantErr: true},
+		{in: "rate=10000,duration=0,every=60s", wantErr: true},
+		{in: "rate=10000,duration=-5s,every=60s", wantErr: true},
+		{in: "rate=10000,duration=NaN,every=60s", wantErr: true},
+		{in: "rate=10000,duration=60s,every=60s", wantErr: true},
+		{in: "rate=10000,duration=5s,every=NaN", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBurst(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBurst(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseBurst(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
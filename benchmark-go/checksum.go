@@ -0,0 +1,143 @@
+package benchmarkgo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// Checksums: every generated record carries CHECKSUM, a SHA-256 over its identifying columns and its SOURCE.
+// QueryTypeChecksum reads a row back, recomputes the checksum from what it got and compares, so corruption between
+// the generator and the reader (encoding, truncation, a mangled transform) is caught under load.
+
+// ChecksumRow is what a checksum query reads back; NULL reads as "".
+type ChecksumRow struct {
+	PatientID   string
+	MRN         string
+	LastName    string
+	FirstName   string
+	DateOfBirth string
+	Checksum    string
+	Source      string // as stored, i.e. still encoded when payload transforms are on
+}
+
+// ChecksumFetcher is implemented by Queriers that can run QueryTypeChecksum; nil means no row for mrn.
+type ChecksumFetcher interface {
+	FetchChecksumRow(ctx context.Context, mrn string) (*ChecksumRow, error)
+}
+
+// sourceSum is the hex SHA-256 of a SOURCE value.
+func sourceSum(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordChecksum is the CHECKSUM of a record: the hex SHA-256 of its identifying columns and the SHA-256 of its
+// SOURCE (sourceSum), separated by unit separators.
+func recordChecksum(patientID, mrn, lastName, firstName, dateOfBirth, sourceHash string) string {
+	h := sha256.New()
+	for _, s := range []string{patientID, mrn, lastName, firstName, dateOfBirth, sourceHash} {
+		h.Write([]byte(s))
+		h.Write([]byte{0x1f})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChecksumReport summarizes the checksum queries.
+type ChecksumReport struct {
+	Checked    int64    `json:"checked"`
+	Missing    int64    `json:"missing"`           // no row for the MRN
+	Mismatched int64    `json:"mismatched"`        // recomputed checksum differs from CHECKSUM
+	Samples    []string `json:"samples,omitempty"` // first mismatches
+}
+
+// checksumStats accumulates checksum queries from all query workers, process-wide like the other query stats.
+var checksumStats struct {
+	mu  sync.Mutex
+	rep ChecksumReport
+}
+
+// recordChecksumResult adds one checksum query: row is nil when it was missing, sample non-empty on a mismatch.
+func recordChecksumResult(row *ChecksumRow, sample string) {
+	checksumStats.mu.Lock()
+	defer checksumStats.mu.Unlock()
+	rep := &checksumStats.rep
+	rep.Checked++
+	switch {
+	case row == nil:
+		rep.Missing++
+	case sample != "":
+		rep.Mismatched++
+		if len(rep.Samples) < maxVerifySamples {
+			rep.Samples = append(rep.Samples, sample)
+		}
+	}
+}
+
+// runChecksum fetches the row for job's MRN, decodes SOURCE if payload transforms are on, recomputes the checksum
+// and fails the query when the row is missing or the checksum differs.
+func (qr *QueryRunner) runChecksum(ctx context.Context, q Querier, job *QueryJob) (count int, failed int, latency time.Duration) {
+	var row *ChecksumRow
+	err := errChecksumUnsupported
+	if cf, ok := q.(ChecksumFetcher); ok {
+		t0 := time.Now()
+		row, err = cf.FetchChecksumRow(ctx, job.MRN)
+		latency = time.Since(t0)
+	}
+	source := ""
+	if err == nil && row != nil {
+		source = row.Source
+		if len(qr.Opts.Payload) > 0 {
+			source, err = qr.Opts.Payload.Decode(row.Source)
+		}
+	}
+	AddError(ErrOpQuery, err)
+	if err != nil {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("Checksum query failed for MEDICAL_RECORD_NUMBER=%s: %v", job.MRN, err)
+		}
+		return 1, failed, latency
+	}
+	sample := ""
+	if row != nil {
+		got := recordChecksum(row.PatientID, row.MRN, row.LastName, row.FirstName, row.DateOfBirth, sourceSum(source))
+		if got != row.Checksum {
+			sample = job.MRN + ": CHECKSUM " + row.Checksum + ", recomputed " + got
+		}
+	}
+	recordChecksumResult(row, sample)
+	if row == nil || sample != "" {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("Checksum mismatch for MEDICAL_RECORD_NUMBER=%s: row found %v, SOURCE %d bytes", job.MRN, row != nil, len(source))
+		}
+	}
+	return 1, failed, latency
+}
+
+// loadChecksum returns the checksum report, or nil when no checksum query ran.
+func loadChecksum() *ChecksumReport {
+	checksumStats.mu.Lock()
+	defer checksumStats.mu.Unlock()
+	if checksumStats.rep.Checked == 0 {
+		return nil
+	}
+	rep := checksumStats.rep
+	rep.Samples = append([]string(nil), checksumStats.rep.Samples...)
+	return &rep
+}
+
+// logChecksum logs the checksum results (only when checksum queries ran).
+func logChecksum(rep *ChecksumReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Checksum: %d rows checked, %d missing, %d mismatched", rep.Checked, rep.Missing, rep.Mismatched)
+	for _, s := range rep.Samples {
+		log.Printf("  e.g. %s", s)
+	}
+}
@@ -3,8 +3,12 @@ package clickhouse
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"os"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
@@ -12,17 +16,39 @@ import (
 	"github.com/db-benchmarking/benchmark-go"
 )
 
-// CreatePool creates a channel of ClickHouse connections (each is a separate conn).
-func CreatePool(ctx context.Context, host string, port int, size int) (chan driver.Conn, []driver.Conn, error) {
-	opts := &clickhouse.Options{
-		Addr: []string{host + ":" + fmtPort(port)},
-		Auth: clickhouse.Auth{
-			Database: benchmarkgo.DBName,
-			Username: benchmarkgo.User,
-			Password: benchmarkgo.Password,
-		},
-		DialTimeout: 10 * time.Second,
+// Topologies for --clickhouse-topology.
+const (
+	TopologyCluster = "cluster" // ReplicatedReplacingMergeTree per shard behind a Distributed table, DDL ON CLUSTER
+	TopologySingle  = "single"  // one ReplacingMergeTree table, no ON CLUSTER (laptop ClickHouse, ClickHouse Cloud)
+)
+
+// ValidateTopology checks a --clickhouse-topology value.
+func ValidateTopology(topology string) error {
+	switch topology {
+	case TopologyCluster, TopologySingle:
+		return nil
+	}
+	return fmt.Errorf("unknown topology %q (want %s or %s)", topology, TopologyCluster, TopologySingle)
+}
+
+// Wire protocols for --clickhouse-protocol.
+const (
+	ProtocolNative = "native" // native TCP protocol (port 9000)
+	ProtocolHTTP   = "http"   // HTTP interface (port 8123), Native format bodies
+)
+
+// ValidateProtocol checks a --clickhouse-protocol value.
+func ValidateProtocol(protocol string) error {
+	switch protocol {
+	case ProtocolNative, ProtocolHTTP:
+		return nil
 	}
+	return fmt.Errorf("unknown protocol %q (want %s or %s)", protocol, ProtocolNative, ProtocolHTTP)
+}
+
+// CreatePool creates a channel of ClickHouse connections (each is a separate conn) speaking protocol.
+func CreatePool(ctx context.Context, host string, port int, size int, protocol string) (chan driver.Conn, []driver.Conn, error) {
+	opts := poolOptions(host, port, protocol)
 	ch := make(chan driver.Conn, size)
 	var conns []driver.Conn
 	for i := 0; i < size; i++ {
@@ -43,10 +69,32 @@ func CreatePool(ctx context.Context, host string, port int, size int) (chan driv
 		conns = append(conns, conn)
 		ch <- conn
 	}
-	log.Printf("Prewarmed ClickHouse connection pool (%d clients)", size)
+	log.Printf("Prewarmed ClickHouse connection pool (%d clients, %s protocol)", size, protocol)
 	return ch, conns, nil
 }
 
+// poolOptions returns the options each pooled connection is opened with.
+func poolOptions(host string, port int, protocol string) *clickhouse.Options {
+	opts := &clickhouse.Options{
+		Addr:     []string{host + ":" + fmtPort(port)},
+		Protocol: clickhouse.Native,
+		Auth: clickhouse.Auth{
+			Database: benchmarkgo.DBName(),
+			Username: benchmarkgo.DBUser(),
+			Password: benchmarkgo.DBPassword(),
+		},
+		DialTimeout: 10 * time.Second,
+	}
+	if d := benchmarkgo.Pool().MaxLifetime; d > 0 {
+		opts.ConnMaxLifetime = d
+	}
+	if protocol == ProtocolHTTP {
+		opts.Protocol = clickhouse.HTTP
+		opts.MaxOpenConns = 1 // one HTTP client per pooled conn, like the native pool
+	}
+	return opts
+}
+
 func fmtPort(p int) string {
 	if p <= 0 {
 		return "9000"
@@ -54,15 +102,8 @@ func fmtPort(p int) string {
 	return strconv.Itoa(p)
 }
 
-// InitSchema creates database and hl7_messages_local + hl7_messages on cluster.
-func InitSchema(ctx context.Context, conn driver.Conn) error {
-	cluster := benchmarkgo.ClickHouseCluster
-	db := benchmarkgo.DBName
-	policy := benchmarkgo.ClickHouseStoragePolicy()
-	if err := conn.Exec(ctx, "CREATE DATABASE IF NOT EXISTS "+db+" ON CLUSTER '"+cluster+"'"); err != nil {
-		return err
-	}
-	localSQL := `CREATE TABLE IF NOT EXISTS ` + db + `.hl7_messages_local ON CLUSTER '` + cluster + `' (
+// columnsDDL is the hl7_messages column list shared by the local, distributed and single-node tables.
+const columnsDDL = `
 		FHIR_ID Nullable(String), RX_PATIENT_ID Nullable(String), SOURCE Nullable(String), CDC Nullable(String),
 		CREATED_AT DateTime64(3), CREATED_BY Nullable(String), UPDATED_AT DateTime64(3), UPDATED_BY Nullable(String),
 		LOAD_DATE Nullable(String), CHECKSUM Nullable(String), PATIENT_ID Nullable(String), MEDICAL_RECORD_NUMBER String,
@@ -71,28 +112,121 @@ func InitSchema(ctx context.Context, conn driver.Conn) error {
 		GENDER_IDENTITY Nullable(String), FHIR_GENDER_IDENTITY Nullable(String), MARITAL_STATUS Nullable(String), FHIR_MARITAL_STATUS Nullable(String),
 		RACE_DISPLAY Nullable(String), FHIR_RACE_DISPLAY Nullable(String), ETHNICITY_DISPLAY Nullable(String), FHIR_ETHNICITY_DISPLAY Nullable(String),
 		SEX_AT_BIRTH Nullable(String), IS_PREGNANT Nullable(String)
-	) ENGINE = ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/hl7_messages_local', '{replica}', UPDATED_AT)
-	ORDER BY MEDICAL_RECORD_NUMBER SETTINGS storage_policy = '` + policy + `'`
+	`
+
+// InitSchema creates the database and tables: hl7_messages_local + a Distributed hl7_messages on the cluster,
+// or a single ReplacingMergeTree hl7_messages for TopologySingle. partitionMinutes > 0 partitions the MergeTree
+// table by CREATED_AT (see partitionBy); 0 leaves it unpartitioned.
+func InitSchema(ctx context.Context, conn driver.Conn, topology string, partitionMinutes int) error {
+	cluster := benchmarkgo.ClickHouseCluster()
+	db := benchmarkgo.DBName()
+	table := benchmarkgo.Table()
+	local := localTable()
+	if err := conn.Exec(ctx, "CREATE DATABASE IF NOT EXISTS "+db+onCluster(topology)); err != nil {
+		return err
+	}
+	if topology == TopologySingle {
+		sql := `CREATE TABLE IF NOT EXISTS ` + db + `.` + table + ` (` + columnsDDL + `) ENGINE = ` +
+			replacingEngine(topology, table) + partitionBy(partitionMinutes) + ` ORDER BY MEDICAL_RECORD_NUMBER` + storageSettings(topology)
+		if err := conn.Exec(ctx, sql); err != nil {
+			return err
+		}
+		log.Printf("Table %s created (ClickHouse, single node)", table)
+		return nil
+	}
+	localSQL := `CREATE TABLE IF NOT EXISTS ` + db + `.` + local + onCluster(topology) + ` (` + columnsDDL + `) ENGINE = ` +
+		replacingEngine(topology, local) + partitionBy(partitionMinutes) + `
+	ORDER BY MEDICAL_RECORD_NUMBER` + storageSettings(topology)
 	if err := conn.Exec(ctx, localSQL); err != nil {
 		return err
 	}
-	distSQL := `CREATE TABLE IF NOT EXISTS ` + db + `.hl7_messages ON CLUSTER '` + cluster + `' (
-		FHIR_ID Nullable(String), RX_PATIENT_ID Nullable(String), SOURCE Nullable(String), CDC Nullable(String),
-		CREATED_AT DateTime64(3), CREATED_BY Nullable(String), UPDATED_AT DateTime64(3), UPDATED_BY Nullable(String),
-		LOAD_DATE Nullable(String), CHECKSUM Nullable(String), PATIENT_ID Nullable(String), MEDICAL_RECORD_NUMBER String,
-		NAME_PREFIX Nullable(String), LAST_NAME Nullable(String), FIRST_NAME Nullable(String), NAME_SUFFIX Nullable(String),
-		DATE_OF_BIRTH Nullable(String), GENDER_ADMINISTRATIVE Nullable(String), FHIR_GENDER_ADMINISTRATIVE Nullable(String),
-		GENDER_IDENTITY Nullable(String), FHIR_GENDER_IDENTITY Nullable(String), MARITAL_STATUS Nullable(String), FHIR_MARITAL_STATUS Nullable(String),
-		RACE_DISPLAY Nullable(String), FHIR_RACE_DISPLAY Nullable(String), ETHNICITY_DISPLAY Nullable(String), FHIR_ETHNICITY_DISPLAY Nullable(String),
-		SEX_AT_BIRTH Nullable(String), IS_PREGNANT Nullable(String)
-	) ENGINE = Distributed('` + cluster + `', '` + db + `', hl7_messages_local, sipHash64(MEDICAL_RECORD_NUMBER))`
+	distSQL := `CREATE TABLE IF NOT EXISTS ` + db + `.` + table + onCluster(topology) + ` (` + columnsDDL +
+		`) ENGINE = Distributed('` + cluster + `', '` + db + `', ` + local + `, sipHash64(MEDICAL_RECORD_NUMBER))`
 	if err := conn.Exec(ctx, distSQL); err != nil {
 		return err
 	}
-	log.Println("Cluster tables hl7_messages created (ClickHouse)")
+	log.Printf("Cluster tables %s created (ClickHouse)", table)
 	return nil
 }
 
+// AddNameIndex adds a bloom_filter skipping index on (LAST_NAME, FIRST_NAME, DATE_OF_BIRTH) to the MergeTree table,
+// so name-dob lookups skip granules instead of scanning the table. Parts written before it existed stay unindexed
+// until merged.
+func AddNameIndex(ctx context.Context, conn driver.Conn, topology string) error {
+	table := benchmarkgo.DBName() + "." + localTable()
+	if topology == TopologySingle {
+		table = benchmarkgo.DBName() + "." + benchmarkgo.Table()
+	}
+	if err := conn.Exec(ctx, "ALTER TABLE "+table+onCluster(topology)+
+		" ADD INDEX IF NOT EXISTS idx_name_dob (LAST_NAME, FIRST_NAME, DATE_OF_BIRTH) TYPE bloom_filter GRANULARITY 4"); err != nil {
+		return err
+	}
+	log.Printf("Skipping index idx_name_dob (bloom_filter on LAST_NAME, FIRST_NAME, DATE_OF_BIRTH) ready on %s", table)
+	return nil
+}
+
+// onCluster returns the ON CLUSTER clause for DDL, or "" on a single node.
+func onCluster(topology string) string {
+	if topology == TopologySingle {
+		return ""
+	}
+	return " ON CLUSTER '" + benchmarkgo.ClickHouseCluster() + "'"
+}
+
+// partitionBy returns the PARTITION BY clause for minutes-wide CREATED_AT partitions, numbered by
+// intDiv(unix time, minutes*60) so a partition's time range follows from its value, or "" when minutes is 0.
+// ReplacingMergeTree only collapses duplicates within a partition, so this is opt-in (--clickhouse-partition-minutes).
+func partitionBy(minutes int) string {
+	if minutes <= 0 {
+		return ""
+	}
+	return " PARTITION BY intDiv(toUnixTimestamp(CREATED_AT), " + strconv.Itoa(minutes*60) + ")"
+}
+
+// replacingEngine returns the ReplacingMergeTree engine for table: replicated per shard on the cluster, plain on a
+// single node (ClickHouse Cloud turns it into SharedReplacingMergeTree itself).
+func replacingEngine(topology, table string) string {
+	if topology == TopologySingle {
+		return "ReplacingMergeTree(UPDATED_AT)"
+	}
+	return "ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/" + table + "', '{replica}', UPDATED_AT)"
+}
+
+// storageSettings returns the storage_policy SETTINGS clause. Single nodes (laptops, ClickHouse Cloud) only get one
+// when CLICKHOUSE_STORAGE_POLICY is set, since the tiered policy is defined by the cluster deployment.
+func storageSettings(topology string) string {
+	if topology == TopologySingle && os.Getenv("CLICKHOUSE_STORAGE_POLICY") == "" {
+		return ""
+	}
+	return " SETTINGS storage_policy = '" + benchmarkgo.ClickHouseStoragePolicy() + "'"
+}
+
+// dataTable returns the MergeTree table holding the parts: hl7_messages_local on the cluster, hl7_messages on a single node.
+func dataTable(topology string) string {
+	if topology == TopologySingle {
+		return benchmarkgo.Table()
+	}
+	return localTable()
+}
+
+// systemTable returns system.<name> across all replicas of the cluster, or the local one on a single node.
+func systemTable(topology, name string) string {
+	if topology == TopologySingle {
+		return "system." + name
+	}
+	return "clusterAllReplicas('" + benchmarkgo.ClickHouseCluster() + "', system." + name + ")"
+}
+
+// qualifiedTable returns db.table for the (prefixed) table queries and inserts go to (distributed on the cluster).
+func qualifiedTable() string {
+	return benchmarkgo.DBName() + "." + benchmarkgo.Table()
+}
+
+// localTable returns the (prefixed) per-shard ReplicatedReplacingMergeTree table name.
+func localTable() string {
+	return benchmarkgo.Table() + "_local"
+}
+
 func get(m map[string]interface{}, k string) interface{} {
 	if v, ok := m[k]; ok {
 		return v
@@ -126,39 +260,91 @@ func rowFromJSON(jsonStr string, now time.Time) ([]interface{}, error) {
 	}, nil
 }
 
-// InsertBatch inserts rows into default.hl7_messages using PrepareBatch.
-func InsertBatch(ctx context.Context, conn driver.Conn, rows []benchmarkgo.RowForDB) (int, error) {
+// InsertQuorum maps a benchmarkgo durability level to insert_quorum / insert_quorum_parallel values.
+// Default keeps the historical quorum of 2 (2 replicas per shard); remote_apply disables parallel quorum
+// so acknowledged inserts are linearizable and visible to select_sequential_consistency reads.
+func InsertQuorum(level string) (quorum string, parallel string) {
+	switch level {
+	case benchmarkgo.DurabilityOff, benchmarkgo.DurabilityLocal:
+		return "0", "1"
+	case benchmarkgo.DurabilityOn:
+		return "auto", "1"
+	case benchmarkgo.DurabilityRemoteApply:
+		return "majority", "0"
+	}
+	return "2", "1"
+}
+
+// InsertBatch inserts rows into default.hl7_messages using PrepareBatch. durability selects insert_quorum (see InsertQuorum).
+// Phases split JSON decode, column appends and the PrepareBatch + Send round trips.
+// The result carries the server's progress (bytes written, elapsed) and a warning when the DelayedInserts profile
+// event shows the insert was throttled for too many parts.
+func InsertBatch(ctx context.Context, conn driver.Conn, rows []benchmarkgo.RowForDB, durability string) (benchmarkgo.InsertResult, error) {
+	var res benchmarkgo.InsertResult
 	if len(rows) == 0 {
-		return 0, nil
+		return res, nil
 	}
+	var delayed, delayedMs int64
 	now := time.Now().UTC()
 	// PrepareBatch expects "INSERT INTO table"; Append() adds rows in table column order.
-	insertSQL := `INSERT INTO ` + benchmarkgo.DBName + `.hl7_messages`
+	insertSQL := `INSERT INTO ` + qualifiedTable()
+	quorum, parallel := InsertQuorum(durability)
 	insertCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
-		"insert_quorum":                   "2", // 2 replicas per shard → quorum 2
-		"insert_quorum_parallel":          "1", // wait for quorum on each replica sequentially
-		"distributed_foreground_insert":   "1", // insert to distributed table in foreground
-		"async_insert":                    "0", // sync insert: wait for write to complete
+		"insert_quorum":                 quorum,   // replicas per shard that must ack
+		"insert_quorum_parallel":        parallel, // 1 = parallel quorum inserts, 0 = linearizable
+		"distributed_foreground_insert": "1",      // insert to distributed table in foreground
+		"async_insert":                  "0",      // sync insert: wait for write to complete
+	}), clickhouse.WithProgress(func(p *clickhouse.Progress) {
+		res.Bytes += int64(p.WroteBytes)
+		if p.Elapsed > res.ServerTime {
+			res.ServerTime = p.Elapsed
+		}
+	}), clickhouse.WithProfileEvents(func(events []clickhouse.ProfileEvent) {
+		for _, e := range events {
+			switch e.Name {
+			case "DelayedInserts":
+				delayed += e.Value
+			case "DelayedInsertsMilliseconds":
+				delayedMs += e.Value
+			}
+		}
 	}))
+	t0 := time.Now()
 	batch, err := conn.PrepareBatch(insertCtx, insertSQL)
+	res.Phases.Call = time.Since(t0)
 	if err != nil {
-		return 0, err
+		return res, err
 	}
 	for _, r := range rows {
+		t0 = time.Now()
 		row, err := rowFromJSON(r.JSONMessage, now)
+		t1 := time.Now()
+		res.Phases.Decode += t1.Sub(t0)
 		if err != nil {
 			batch.Abort()
-			return 0, err
+			return res, err
 		}
-		if err := batch.Append(row...); err != nil {
+		err = batch.Append(row...)
+		res.Phases.Bind += time.Since(t1)
+		if err != nil {
 			batch.Abort()
-			return 0, err
+			return res, err
 		}
 	}
-	if err := batch.Send(); err != nil {
-		return 0, err
+	t0 = time.Now()
+	err = batch.Send()
+	res.Phases.Call += time.Since(t0)
+	if err != nil {
+		return res, err
+	}
+	res.Rows = len(rows)
+	if delayed > 0 {
+		res.Warnings = append(res.Warnings, benchmarkgo.InsertWarning{
+			Code:    "clickhouse:delayed_insert",
+			Message: fmt.Sprintf("insert delayed %d ms due to too many parts (parts_to_delay_insert)", delayedMs),
+		})
 	}
-	return len(rows), nil
+	return res, nil
 }
 
 // QueryByPrimaryKey returns row count for the given MRN (FINAL).
@@ -167,7 +353,7 @@ func QueryByPrimaryKey(ctx context.Context, conn driver.Conn, mrn string) (int,
 		"select_sequential_consistency": "1",
 		"prefer_localhost_replica":      "0",
 	}))
-	row := conn.QueryRow(queryCtx, "SELECT count() FROM "+benchmarkgo.DBName+".hl7_messages FINAL WHERE MEDICAL_RECORD_NUMBER = $1", mrn)
+	row := conn.QueryRow(queryCtx, "SELECT count() FROM "+qualifiedTable()+" FINAL WHERE MEDICAL_RECORD_NUMBER = $1", mrn)
 	var n uint64
 	if err := row.Scan(&n); err != nil {
 		return 0, err
@@ -175,13 +361,538 @@ func QueryByPrimaryKey(ctx context.Context, conn driver.Conn, mrn string) (int,
 	return int(n), nil
 }
 
+// FetchSource returns SOURCE of the row for mrn after merge (implements benchmarkgo.SourceFetcher via querier).
+func FetchSource(ctx context.Context, conn driver.Conn, mrn string) (string, error) {
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}))
+	var source string
+	err := conn.QueryRow(queryCtx, "SELECT assumeNotNull(SOURCE) FROM "+qualifiedTable()+
+		" FINAL WHERE MEDICAL_RECORD_NUMBER = $1 LIMIT 1", mrn).Scan(&source)
+	return source, err
+}
+
+// QueryByPrimaryKeyTimed is QueryByPrimaryKey that also returns the server-reported elapsed time from the query's
+// progress packets (0 when the server's protocol revision does not send it).
+func QueryByPrimaryKeyTimed(ctx context.Context, conn driver.Conn, mrn string) (int, time.Duration, error) {
+	var server time.Duration
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}), clickhouse.WithProgress(func(p *clickhouse.Progress) {
+		if p.Elapsed > server {
+			server = p.Elapsed
+		}
+	}))
+	row := conn.QueryRow(queryCtx, "SELECT count() FROM "+qualifiedTable()+" FINAL WHERE MEDICAL_RECORD_NUMBER = $1", mrn)
+	var n uint64
+	if err := row.Scan(&n); err != nil {
+		return 0, server, err
+	}
+	return int(n), server, nil
+}
+
+// QueryResultSet fetches full rows (FINAL) for the MRN range ending at mrn (descending, LIMIT limit).
+// Returns rows and approximate bytes scanned, so latency can be related to result-set size.
+func QueryResultSet(ctx context.Context, conn driver.Conn, mrn string, limit int) (int, int64, error) {
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}))
+	rows, err := conn.Query(queryCtx, "SELECT * FROM "+qualifiedTable()+" FINAL WHERE MEDICAL_RECORD_NUMBER <= $1 ORDER BY MEDICAL_RECORD_NUMBER DESC LIMIT $2", mrn, limit)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	return scanRows(rows)
+}
+
+// scanRows scans every row, returning how many there were and the approximate size of their values.
+func scanRows(rows driver.Rows) (int, int64, error) {
+	colTypes := rows.ColumnTypes()
+	dest := make([]interface{}, len(colTypes))
+	for i, ct := range colTypes {
+		dest[i] = reflect.New(ct.ScanType()).Interface()
+	}
+	var n int
+	var bytes int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, bytes, err
+		}
+		n++
+		for _, d := range dest {
+			bytes += valueSize(reflect.ValueOf(d))
+		}
+	}
+	return n, bytes, rows.Err()
+}
+
+// FetchByPrimaryKey fetches and scans the row (FINAL) for mrn, with or without SOURCE, and returns rows and
+// bytes read.
+func FetchByPrimaryKey(ctx context.Context, conn driver.Conn, mrn string, withPayload bool) (int, int64, error) {
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}))
+	cols := "*"
+	if !withPayload {
+		cols = "* EXCEPT (SOURCE)"
+	}
+	rows, err := conn.Query(queryCtx, "SELECT "+cols+" FROM "+qualifiedTable()+" FINAL WHERE MEDICAL_RECORD_NUMBER = $1", mrn)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	return scanRows(rows)
+}
+
+// FetchVerifyColumns reads the columns --verify compares from the row (FINAL) for mrn; nil when there is none.
+func FetchVerifyColumns(ctx context.Context, conn driver.Conn, mrn string) (*benchmarkgo.VerifyColumns, error) {
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}))
+	rows, err := conn.Query(queryCtx, "SELECT ifNull(PATIENT_ID, ''), ifNull(LAST_NAME, ''), ifNull(FIRST_NAME, ''),"+
+		" ifNull(DATE_OF_BIRTH, ''), ifNull(CHECKSUM, '') FROM "+qualifiedTable()+" FINAL WHERE MEDICAL_RECORD_NUMBER = $1 LIMIT 1", mrn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var c benchmarkgo.VerifyColumns
+	if err := rows.Scan(&c.PatientID, &c.LastName, &c.FirstName, &c.DateOfBirth, &c.Checksum); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FetchChecksumRow reads the columns CHECKSUM covers, CHECKSUM and SOURCE from the row (FINAL) for mrn; nil when
+// there is none.
+func FetchChecksumRow(ctx context.Context, conn driver.Conn, mrn string) (*benchmarkgo.ChecksumRow, error) {
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}))
+	rows, err := conn.Query(queryCtx, "SELECT ifNull(PATIENT_ID, ''), MEDICAL_RECORD_NUMBER, ifNull(LAST_NAME, ''), ifNull(FIRST_NAME, ''),"+
+		" ifNull(DATE_OF_BIRTH, ''), ifNull(CHECKSUM, ''), ifNull(SOURCE, '') FROM "+qualifiedTable()+" FINAL WHERE MEDICAL_RECORD_NUMBER = $1 LIMIT 1", mrn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var c benchmarkgo.ChecksumRow
+	if err := rows.Scan(&c.PatientID, &c.MRN, &c.LastName, &c.FirstName, &c.DateOfBirth, &c.Checksum, &c.Source); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// valueSize approximates the in-memory size of a scanned value (string length, else fixed type size).
+func valueSize(v reflect.Value) int64 {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return 0
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return int64(v.Len())
+	}
+	return int64(v.Type().Size())
+}
+
+// QueryPatientRange fetches full rows (FINAL) for PATIENT_ID created between from and to and returns how many
+// were read.
+func QueryPatientRange(ctx context.Context, conn driver.Conn, patientID string, from, to time.Time) (int, error) {
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}))
+	rows, err := conn.Query(queryCtx, "SELECT * FROM "+qualifiedTable()+" FINAL WHERE PATIENT_ID = $1 AND CREATED_AT BETWEEN $2 AND $3", patientID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// pageOrder is the patient list's sort order for the pagination queries.
+const pageOrder = " ORDER BY LAST_NAME, FIRST_NAME, PATIENT_ID"
+
+// QueryPageOffset fetches limit rows (FINAL) of the name-ordered patient list from offset on and returns how many
+// were read.
+func QueryPageOffset(ctx context.Context, conn driver.Conn, offset, limit int) (int, error) {
+	rows, err := conn.Query(ctx, "SELECT * FROM "+qualifiedTable()+" FINAL"+pageOrder+" LIMIT $1 OFFSET $2", limit, offset)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// QueryPageAfter fetches the limit rows (FINAL) of the name-ordered patient list after the given key and returns
+// how many were read.
+func QueryPageAfter(ctx context.Context, conn driver.Conn, lastName, firstName, patientID string, limit int) (int, error) {
+	rows, err := conn.Query(ctx, "SELECT * FROM "+qualifiedTable()+" FINAL WHERE (LAST_NAME, FIRST_NAME, PATIENT_ID) > ($1, $2, $3)"+
+		pageOrder+" LIMIT $4", lastName, firstName, patientID, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// QueryByNameDOB fetches full rows (FINAL) matching last name, first name and date of birth and returns how many
+// were read.
+func QueryByNameDOB(ctx context.Context, conn driver.Conn, lastName, firstName, dateOfBirth string) (int, error) {
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}))
+	rows, err := conn.Query(queryCtx, "SELECT * FROM "+qualifiedTable()+" FINAL WHERE LAST_NAME = $1 AND FIRST_NAME = $2 AND DATE_OF_BIRTH = $3",
+		lastName, firstName, dateOfBirth)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// QueryByPatientID fetches full rows (FINAL) for PATIENT_ID and returns how many were read.
+func QueryByPatientID(ctx context.Context, conn driver.Conn, patientID string) (int, error) {
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}))
+	rows, err := conn.Query(queryCtx, "SELECT * FROM "+qualifiedTable()+" FINAL WHERE PATIENT_ID = $1", patientID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// AggregateByPatientID summarizes the patient's rows (count, latest update, payload size) and returns the count.
+func AggregateByPatientID(ctx context.Context, conn driver.Conn, patientID string) (int, error) {
+	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"select_sequential_consistency": "1",
+		"prefer_localhost_replica":      "0",
+	}))
+	var n uint64
+	var lastUpdated time.Time
+	var payloadBytes uint64
+	err := conn.QueryRow(queryCtx, "SELECT count(), max(UPDATED_AT), sum(length(assumeNotNull(SOURCE))) FROM "+qualifiedTable()+
+		" FINAL WHERE PATIENT_ID = $1", patientID).Scan(&n, &lastUpdated, &payloadBytes)
+	return int(n), err
+}
+
+// CountRows returns count() of the distributed hl7_messages table (without FINAL).
+func CountRows(ctx context.Context, conn driver.Conn) (int64, error) {
+	var n uint64
+	if err := conn.QueryRow(ctx, "SELECT count() FROM "+qualifiedTable()).Scan(&n); err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// CountRowsFinal returns count() of the table with FINAL, i.e. with not-yet-merged duplicates collapsed.
+func CountRowsFinal(ctx context.Context, conn driver.Conn) (int64, error) {
+	var n uint64
+	if err := conn.QueryRow(ctx, "SELECT count() FROM "+qualifiedTable()+" FINAL").Scan(&n); err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// OptimizeFinal merges all parts of the MergeTree table (on every shard for the cluster), collapsing duplicates,
+// and waits for the replicas to finish.
+func OptimizeFinal(ctx context.Context, conn driver.Conn, topology string) error {
+	optimizeCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"alter_sync": "2",
+	}))
+	return conn.Exec(optimizeCtx, "OPTIMIZE TABLE "+benchmarkgo.DBName()+"."+dataTable(topology)+onCluster(topology)+" FINAL")
+}
+
+// CountKeys returns the exact number of distinct MRNs of generated patients, for failover reconciliation
+// (duplicates stay separate rows until ReplacingMergeTree merges them).
+func CountKeys(ctx context.Context, conn driver.Conn) (int64, error) {
+	var n uint64
+	if err := conn.QueryRow(ctx, "SELECT uniqExact(MEDICAL_RECORD_NUMBER) FROM "+qualifiedTable()+
+		" WHERE startsWith(PATIENT_ID, 'patient-')").Scan(&n); err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// SampleKeys returns up to limit distinct patient IDs of generated patients, chosen at random.
+func SampleKeys(ctx context.Context, conn driver.Conn, limit int) ([]string, error) {
+	rows, err := conn.Query(ctx, "SELECT PATIENT_ID FROM "+qualifiedTable()+
+		" WHERE startsWith(PATIENT_ID, 'patient-') GROUP BY PATIENT_ID ORDER BY rand() LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// TableBytes returns bytes_on_disk of the table's active parts, across all replicas of the cluster.
+func TableBytes(ctx context.Context, conn driver.Conn, topology string) (int64, error) {
+	var n uint64
+	err := conn.QueryRow(ctx, "SELECT sum(bytes_on_disk) FROM "+systemTable(topology, "parts")+
+		" WHERE database = '"+benchmarkgo.DBName()+"' AND table = '"+dataTable(topology)+"' AND active").Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// Server metrics sampled during the run: the merge and insert-pressure signals, not the full system tables.
+var (
+	serverMetricNames = []string{"Query", "Merge", "PartMutation", "ReplicatedFetch", "ReplicatedSend",
+		"BackgroundMergesAndMutationsPoolTask", "DelayedInserts", "TCPConnection", "HTTPConnection", "MemoryTracking"}
+	serverEventNames = []string{"InsertQuery", "SelectQuery", "InsertedRows", "InsertedBytes", "DelayedInserts",
+		"RejectedInserts", "MergedRows", "MergedUncompressedBytes", "Merge"}
+	serverAsyncMetricNames = []string{"MaxPartCountForPartition", "TotalPartsOfMergeTreeTables", "ReplicasMaxAbsoluteDelay",
+		"ReplicasMaxQueueSize", "LoadAverage1", "MemoryResident", "OSMemoryAvailable"}
+)
+
+// ServerMetrics samples system.metrics and system.events (summed over replicas), system.asynchronous_metrics
+// (worst replica), running merges and the table's active parts.
+func ServerMetrics(ctx context.Context, conn driver.Conn, topology string) (map[string]float64, error) {
+	in := func(names []string) string {
+		return "'" + strings.Join(names, "', '") + "'"
+	}
+	tableFilter := " WHERE database = '" + benchmarkgo.DBName() + "' AND table = '" + dataTable(topology) + "'"
+	queries := []struct{ prefix, query string }{
+		{"metrics", "SELECT metric, toFloat64(sum(value)) FROM " + systemTable(topology, "metrics") +
+			" WHERE metric IN (" + in(serverMetricNames) + ") GROUP BY metric"},
+		{"events", "SELECT event, toFloat64(sum(value)) FROM " + systemTable(topology, "events") +
+			" WHERE event IN (" + in(serverEventNames) + ") GROUP BY event"},
+		{"asynchronous_metrics", "SELECT metric, toFloat64(max(value)) FROM " + systemTable(topology, "asynchronous_metrics") +
+			" WHERE metric IN (" + in(serverAsyncMetricNames) + ") GROUP BY metric"},
+		{"merges", "SELECT 'running', toFloat64(count()) FROM " + systemTable(topology, "merges") + tableFilter},
+		{"parts", "SELECT 'active', toFloat64(count()) FROM " + systemTable(topology, "parts") + tableFilter + " AND active"},
+	}
+	out := make(map[string]float64)
+	for _, q := range queries {
+		rows, err := conn.Query(ctx, q.query)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", q.prefix, err)
+		}
+		for rows.Next() {
+			var name string
+			var v float64
+			if err := rows.Scan(&name, &v); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("%s: %w", q.prefix, err)
+			}
+			out[q.prefix+"."+name] = v
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", q.prefix, err)
+		}
+	}
+	return out, nil
+}
+
+// MaxActiveParts returns the highest active part count of any partition of the table on any replica, the number
+// parts_to_delay_insert and parts_to_throw_insert are compared against.
+func MaxActiveParts(ctx context.Context, conn driver.Conn, topology string) (int64, error) {
+	var n uint64
+	err := conn.QueryRow(ctx, "SELECT max(parts) FROM (SELECT hostName() AS host, partition_id, count() AS parts FROM "+
+		systemTable(topology, "parts")+" WHERE database = '"+benchmarkgo.DBName()+"' AND table = '"+dataTable(topology)+
+		"' AND active GROUP BY host, partition_id)").Scan(&n)
+	return int64(n), err
+}
+
+// DescribeSchema returns SHOW CREATE TABLE of hl7_messages_local and the distributed hl7_messages table
+// (only hl7_messages on a single node).
+func DescribeSchema(ctx context.Context, conn driver.Conn, topology string) (string, error) {
+	tables := []string{localTable(), benchmarkgo.Table()}
+	if topology == TopologySingle {
+		tables = tables[1:]
+	}
+	var stmts []string
+	for _, t := range tables {
+		var stmt string
+		if err := conn.QueryRow(ctx, "SHOW CREATE TABLE "+benchmarkgo.DBName()+"."+t).Scan(&stmt); err != nil {
+			return "", err
+		}
+		stmts = append(stmts, stmt+";")
+	}
+	return strings.Join(stmts, "\n\n"), nil
+}
+
+// CacheCounters returns cumulative mark and uncompressed cache hits and misses summed over all replicas.
+func CacheCounters(ctx context.Context, conn driver.Conn, topology string) (int64, int64, error) {
+	var hits, misses uint64
+	err := conn.QueryRow(ctx, "SELECT sumIf(value, event IN ('MarkCacheHits', 'UncompressedCacheHits')), "+
+		"sumIf(value, event IN ('MarkCacheMisses', 'UncompressedCacheMisses')) "+
+		"FROM "+systemTable(topology, "events")).Scan(&hits, &misses)
+	return int64(hits), int64(misses), err
+}
+
+// ServerVersion returns version() of the server behind conn.
+func ServerVersion(ctx context.Context, conn driver.Conn) (string, error) {
+	var v string
+	err := conn.QueryRow(ctx, "SELECT version()").Scan(&v)
+	return v, err
+}
+
+// DeleteOlderThan removes rows with CREATED_AT < cutoff. With partitionMinutes > 0 it drops every partition that
+// lies entirely before the cutoff (DROP PARTITION, the production retention path; rows in the partition holding the
+// cutoff stay until a later step). An unpartitioned table falls back to an ALTER TABLE ... DELETE mutation on every
+// shard, waited for with mutations_sync=2; a mutation rewrites parts, so the result carries a Note that its timings
+// are not comparable to DROP PARTITION.
+func DeleteOlderThan(ctx context.Context, conn driver.Conn, cutoff time.Time, topology string, partitionMinutes int) (benchmarkgo.RetentionResult, error) {
+	res := benchmarkgo.RetentionResult{BytesBefore: -1, BytesAfter: -1}
+	var err error
+	if res.RowsBefore, err = CountRows(ctx, conn); err != nil {
+		return res, err
+	}
+	if b, err := TableBytes(ctx, conn, topology); err == nil {
+		res.BytesBefore = b
+	}
+	t0 := time.Now()
+	if partitionMinutes > 0 {
+		err = dropPartitionsBefore(ctx, conn, cutoff, topology, partitionMinutes, &res)
+	} else {
+		res.Method = "ALTER DELETE (mutations_sync=2)"
+		res.Note = "table is unpartitioned, so this ran a mutation that rewrites parts instead of DROP PARTITION; " +
+			"timings and impact are not comparable to partition-based retention (set --clickhouse-partition-minutes)"
+		mutationCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+			"mutations_sync": "2",
+		}))
+		err = conn.Exec(mutationCtx, "ALTER TABLE "+benchmarkgo.DBName()+"."+dataTable(topology)+onCluster(topology)+
+			" DELETE WHERE CREATED_AT < $1", cutoff)
+	}
+	if err != nil {
+		return res, err
+	}
+	res.DeleteSec = time.Since(t0).Seconds()
+	if res.RowsAfter, err = CountRows(ctx, conn); err != nil {
+		return res, err
+	}
+	if b, err := TableBytes(ctx, conn, topology); err == nil {
+		res.BytesAfter = b
+	}
+	return res, nil
+}
+
+// dropPartitionsBefore drops the partitions (numbered as in partitionBy) that end at or before cutoff, on every
+// replica, and records the method and partition counts in res.
+func dropPartitionsBefore(ctx context.Context, conn driver.Conn, cutoff time.Time, topology string, minutes int, res *benchmarkgo.RetentionResult) error {
+	rows, err := conn.Query(ctx, "SELECT DISTINCT partition FROM "+systemTable(topology, "parts")+
+		" WHERE database = $1 AND table = $2 AND active", benchmarkgo.DBName(), dataTable(topology))
+	if err != nil {
+		return err
+	}
+	width := int64(minutes * 60)
+	var total int
+	var old []int64
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return err
+		}
+		total++
+		if n, err := strconv.ParseInt(p, 10, 64); err == nil && (n+1)*width <= cutoff.Unix() {
+			old = append(old, n)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, n := range old {
+		if err := conn.Exec(ctx, "ALTER TABLE "+benchmarkgo.DBName()+"."+dataTable(topology)+onCluster(topology)+
+			" DROP PARTITION "+strconv.FormatInt(n, 10)); err != nil {
+			return err
+		}
+	}
+	res.Method = fmt.Sprintf("DROP PARTITION (%d of %d %d-minute partitions)", len(old), total, minutes)
+	return nil
+}
+
+// SaveSnapshot recreates snapshot name as a replicated copy of hl7_messages_local on every shard (of hl7_messages on
+// a single node) and fills it with REPLACE PARTITION, which hardlinks the source parts instead of copying rows.
+// The table is unpartitioned, so tuple() is its only partition.
+func SaveSnapshot(ctx context.Context, conn driver.Conn, name, topology string) (benchmarkgo.SnapshotResult, error) {
+	res := benchmarkgo.SnapshotResult{Method: "REPLACE PARTITION (hardlinked parts)", Rows: -1}
+	snap := benchmarkgo.SnapshotTable(name)
+	qualifiedSnap := benchmarkgo.DBName() + "." + snap
+	if err := conn.Exec(ctx, "DROP TABLE IF EXISTS "+qualifiedSnap+onCluster(topology)+" SYNC"); err != nil {
+		return res, err
+	}
+	err := conn.Exec(ctx, "CREATE TABLE "+qualifiedSnap+onCluster(topology)+" AS "+benchmarkgo.DBName()+"."+dataTable(topology)+
+		" ENGINE = "+replacingEngine(topology, snap)+" ORDER BY MEDICAL_RECORD_NUMBER"+storageSettings(topology))
+	if err != nil {
+		return res, err
+	}
+	if err := conn.Exec(ctx, "ALTER TABLE "+qualifiedSnap+onCluster(topology)+" REPLACE PARTITION tuple() FROM "+
+		benchmarkgo.DBName()+"."+dataTable(topology)); err != nil {
+		return res, err
+	}
+	res.Rows, err = CountRows(ctx, conn)
+	return res, err
+}
+
+// RestoreSnapshot atomically swaps the parts of hl7_messages_local on every shard (hl7_messages on a single node)
+// for those of snapshot name (REPLACE PARTITION), leaving the snapshot intact for the next phase.
+func RestoreSnapshot(ctx context.Context, conn driver.Conn, name, topology string) (benchmarkgo.SnapshotResult, error) {
+	res := benchmarkgo.SnapshotResult{Method: "REPLACE PARTITION (hardlinked parts)", Rows: -1}
+	err := conn.Exec(ctx, "ALTER TABLE "+benchmarkgo.DBName()+"."+dataTable(topology)+onCluster(topology)+
+		" REPLACE PARTITION tuple() FROM "+benchmarkgo.DBName()+"."+benchmarkgo.SnapshotTable(name))
+	if err != nil {
+		return res, err
+	}
+	res.Rows, err = CountRows(ctx, conn)
+	return res, err
+}
+
 // GetMaxPatientCounter returns max patient ordinal from PATIENT_ID, or -1.
 func GetMaxPatientCounter(ctx context.Context, conn driver.Conn) (int, error) {
 	queryCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
 		"select_sequential_consistency": "1",
 		"prefer_localhost_replica":      "0",
 	}))
-	row := conn.QueryRow(queryCtx, "SELECT COALESCE(MAX(toInt64OrZero(substring(PATIENT_ID, 10))), -1) FROM "+benchmarkgo.DBName+".hl7_messages WHERE PATIENT_ID != ''")
+	row := conn.QueryRow(queryCtx, "SELECT COALESCE(MAX(toInt64OrZero(substring(PATIENT_ID, 10))), -1) FROM "+qualifiedTable()+" WHERE PATIENT_ID != ''")
 	var n int64
 	if err := row.Scan(&n); err != nil {
 		return -1, err
@@ -0,0 +1,26 @@
+package clickhouse
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// classifyError maps server exceptions to "clickhouse:<code> <NAME>", e.g. clickhouse:252 TOO_MANY_PARTS.
+func classifyError(err error) (string, bool) {
+	var ex *clickhouse.Exception
+	if errors.As(err, &ex) {
+		code := "clickhouse:" + strconv.Itoa(int(ex.Code))
+		if ex.Name != "" {
+			code += " " + ex.Name
+		}
+		return code, true
+	}
+	return "", false
+}
@@ -0,0 +1,19 @@
+package clickhouse
+
+import (
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+func init() {
+	benchmarkgo.RegisterMicrobench(benchmarkgo.Microbench{Name: "clickhouse/rowFromJSON", Setup: func(batchSize int) func() {
+		rows := benchmarkgo.MicrobenchRows(batchSize)
+		return func() {
+			now := time.Now()
+			for _, r := range rows {
+				_, _ = rowFromJSON(r.JSONMessage, now)
+			}
+		}
+	}})
+}
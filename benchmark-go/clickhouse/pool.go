@@ -0,0 +1,85 @@
+package clickhouse
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// healthCheckTimeout bounds the ping that decides whether a suspect connection is dead.
+const healthCheckTimeout = 2 * time.Second
+
+// connPool tracks the connections of the channel pool so that dead ones can be replaced: a connection that hit a
+// transport error is marked suspect, pinged when released, and swapped for a fresh one if the ping fails. Without
+// this a server restart leaves every pooled connection broken for the rest of the run.
+type connPool struct {
+	ch      chan driver.Conn
+	opts    *clickhouse.Options
+	mu      sync.Mutex
+	conns   []driver.Conn // every open connection, for Close
+	suspect map[driver.Conn]bool
+}
+
+// suspect marks conn for a health check when it is released.
+func (p *connPool) markSuspect(conn driver.Conn) {
+	p.mu.Lock()
+	p.suspect[conn] = true
+	p.mu.Unlock()
+}
+
+// release returns conn to the channel, replacing it first if it is suspect and fails its health check.
+func (p *connPool) release(conn driver.Conn) {
+	p.mu.Lock()
+	suspect := p.suspect[conn]
+	delete(p.suspect, conn)
+	p.mu.Unlock()
+	if suspect && !healthy(conn) {
+		conn = p.replace(conn)
+	}
+	p.ch <- conn
+}
+
+// healthy reports whether conn answers a ping within healthCheckTimeout.
+func healthy(conn driver.Conn) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	return conn.Ping(ctx) == nil
+}
+
+// replace closes the dead connection old and opens a new one in its place. The new connection goes into the pool
+// even when the server is still down; it dials again on its next use.
+func (p *connPool) replace(old driver.Conn) driver.Conn {
+	fresh, err := clickhouse.Open(p.opts)
+	if err != nil {
+		log.Printf("ClickHouse reconnect failed, keeping the dead connection: %v", err)
+		return old
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	err = fresh.Ping(ctx)
+	cancel()
+	benchmarkgo.AddReconnect(err)
+	old.Close()
+	p.mu.Lock()
+	for i, c := range p.conns {
+		if c == old {
+			p.conns[i] = fresh
+		}
+	}
+	p.mu.Unlock()
+	return fresh
+}
+
+// close closes every connection.
+func (p *connPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+}
@@ -2,6 +2,7 @@ package clickhouse
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"time"
@@ -12,45 +13,65 @@ import (
 
 const defaultHost = "clickhouse"
 const defaultPort = 9000
+const defaultHTTPPort = 8123
 
 // Backend implements benchmarkgo.InsertBackend using a channel of ClickHouse connections.
 type Backend struct {
-	ch chan driver.Conn
+	pool       *connPool
+	durability string
 }
 
 // GetConn acquires a connection from the pool.
 func (b *Backend) GetConn() interface{} {
-	return <-b.ch
+	return <-b.pool.ch
 }
 
-// ReleaseConn returns the connection to the pool.
+// ReleaseConn returns the connection to the pool, replacing it if a transport error left it dead.
 func (b *Backend) ReleaseConn(c interface{}) {
 	if conn, ok := c.(driver.Conn); ok {
-		b.ch <- conn
+		b.pool.release(conn)
 	}
 }
 
-// InsertBatch inserts rows using the given connection (must be driver.Conn). Returns (rowsInserted, statementCount, error).
-func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (int, int, error) {
+// InsertBatch inserts rows using the given connection (must be driver.Conn) in one batch, with the server's
+// written bytes, elapsed time and insert delays (native protocol only).
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
 	c, ok := conn.(driver.Conn)
 	if !ok {
-		return 0, 0, nil
+		return benchmarkgo.InsertResult{}, nil
 	}
 	_ = queryHint // unused for ClickHouse
-	n, err := InsertBatch(context.Background(), c, rows)
+	res, err := InsertBatch(context.Background(), c, rows, b.durability)
 	if err != nil {
-		return n, 0, err
+		if benchmarkgo.IsConnectionError(err) {
+			b.pool.markSuspect(c)
+		}
+		return res, err
 	}
-	return n, 1, nil
+	res.Statements = 1
+	return res, nil
 }
 
 // Context holds the connection pool for setup/teardown and query workers.
 type Context struct {
-	ch     chan driver.Conn
-	conns  []driver.Conn
+	ch         chan driver.Conn
+	pool       *connPool // inserts and the admin methods below (c.ch)
+	queryPool  *connPool // query workers; the same pool as pool when the run has none
+	Durability string    // benchmarkgo durability level, applied as insert_quorum
+	Protocol   string    // ProtocolNative (default) or ProtocolHTTP, to compare wire-protocol overhead
+	Topology   string    // TopologyCluster (default) or TopologySingle
+	// PartitionMinutes, when > 0, partitions the table into CREATED_AT ranges this many minutes wide so the retention
+	// step drops whole partitions (not with snapshots, which copy the single unpartitioned partition).
+	PartitionMinutes int
+	// NameIndex adds the idx_name_dob skipping index for name-dob lookups (see AddNameIndex).
+	NameIndex bool
+	// VisibilityTimeout, when > 0, makes query workers poll each MRN with FINAL from insert completion until it
+	// is visible (up to this long) and record the lag via benchmarkgo.AddVisibilityLag.
+	VisibilityTimeout time.Duration
 }
 
-// Setup creates the pool (insert-only size when queriesPerRecord is 0, else insert+query size), prewarms, and inits schema.
+// Setup creates the insert pool and, when queriesPerRecord > 0, the query pool (sized by benchmarkgo.Pool), prewarms
+// them, and inits schema.
 func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
 	if c.ch != nil {
 		log.Fatal("clickhouse Setup already called")
@@ -59,43 +80,77 @@ func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchm
 	if host == "" {
 		host = defaultHost
 	}
+	if c.Protocol == "" {
+		c.Protocol = ProtocolNative
+	}
+	if c.Topology == "" {
+		c.Topology = TopologyCluster
+	}
 	port := defaultPort
-	poolSize := numWorkers
-	if queriesPerRecord > 0 {
-		poolSize = numWorkers * 2
+	if c.Protocol == ProtocolHTTP {
+		port = defaultHTTPPort
+	}
+	if h := benchmarkgo.DBHost(); h != "" {
+		host = h
 	}
+	if p := benchmarkgo.DBPort(); p > 0 {
+		port = p
+	}
+	insertSize, querySize := benchmarkgo.Pool().Sizes(numWorkers, queriesPerRecord > 0)
 	ctx := context.Background()
-	log.Printf("Creating ClickHouse connection pool at %s:%d (%d clients)",
-		host, port, poolSize)
-	if queriesPerRecord > 0 {
-		log.Printf("  for %d insert + %d query workers", numWorkers, numWorkers)
+	log.Printf("Creating ClickHouse connection pool at %s:%d (%d clients, %s protocol)",
+		host, port, insertSize, c.Protocol)
+	qhost, qport := benchmarkgo.QueryAddr(host, port)
+	if querySize > 0 && (qhost != host || qport != port) {
+		log.Printf("  + %d clients for query workers at %s:%d", querySize, qhost, qport)
+	} else if querySize > 0 {
+		log.Printf("  + %d clients for query workers", querySize)
 	}
-	ch, conns, err := CreatePool(ctx, host, port, poolSize)
+	ch, conns, err := CreatePool(ctx, host, port, insertSize, c.Protocol)
 	if err != nil {
 		return nil, err
 	}
 	c.ch = ch
-	c.conns = conns
+	c.pool = &connPool{ch: ch, opts: poolOptions(host, port, c.Protocol), conns: conns, suspect: make(map[driver.Conn]bool)}
+	c.queryPool = c.pool
+	if querySize > 0 {
+		qch, qconns, err := CreatePool(ctx, qhost, qport, querySize, c.Protocol)
+		if err != nil {
+			c.Teardown()
+			return nil, err
+		}
+		c.queryPool = &connPool{ch: qch, opts: poolOptions(qhost, qport, c.Protocol), conns: qconns, suspect: make(map[driver.Conn]bool)}
+	}
 	conn := <-ch
-	if err := InitSchema(ctx, conn); err != nil {
+	err = InitSchema(ctx, conn, c.Topology, c.PartitionMinutes)
+	if err == nil && c.NameIndex {
+		err = AddNameIndex(ctx, conn, c.Topology)
+	}
+	if err != nil {
 		ch <- conn
-		for _, co := range conns {
-			co.Close()
-		}
+		c.Teardown()
 		return nil, err
 	}
 	ch <- conn
 	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
-	return &Backend{ch: ch}, nil
+	return &Backend{pool: c.pool, durability: c.Durability}, nil
+}
+
+// DurabilitySetting reports the insert_quorum settings used for inserts (implements benchmarkgo.DurabilityReporter).
+func (c *Context) DurabilitySetting() string {
+	quorum, parallel := InsertQuorum(c.Durability)
+	return "insert_quorum=" + quorum + " insert_quorum_parallel=" + parallel
 }
 
 // Teardown closes all connections.
 func (c *Context) Teardown() {
-	if c.conns != nil {
-		for _, conn := range c.conns {
-			conn.Close()
-		}
-		c.conns = nil
+	if c.queryPool != nil && c.queryPool != c.pool {
+		c.queryPool.close()
+	}
+	c.queryPool = nil
+	if c.pool != nil {
+		c.pool.close()
+		c.pool = nil
 		c.ch = nil
 	}
 }
@@ -107,40 +162,247 @@ func (c *Context) GetMaxPatientCounter() (int, error) {
 	return GetMaxPatientCounter(context.Background(), conn)
 }
 
+// DeleteOlderThan runs the retention step on a pooled connection (implements benchmarkgo.RetentionBackend).
+func (c *Context) DeleteOlderThan(ctx context.Context, cutoff time.Time) (benchmarkgo.RetentionResult, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return DeleteOlderThan(ctx, conn, cutoff, c.Topology, c.PartitionMinutes)
+}
+
+// CountKeys counts generated patients' MRNs on a pooled connection (implements benchmarkgo.KeyCounter).
+func (c *Context) CountKeys(ctx context.Context) (int64, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return CountKeys(ctx, conn)
+}
+
+// BulkLoad inserts rows as one large native batch on a pooled connection (implements benchmarkgo.BulkLoader).
+func (c *Context) BulkLoad(ctx context.Context, rows []benchmarkgo.RowForDB) (int64, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	res, err := InsertBatch(ctx, conn, rows, c.Durability)
+	return int64(res.Rows), err
+}
+
+// SampleKeys samples generated patient IDs on a pooled connection (implements benchmarkgo.KeySampler).
+func (c *Context) SampleKeys(ctx context.Context, limit int) ([]string, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return SampleKeys(ctx, conn, limit)
+}
+
+// CountRows counts the table's rows on a pooled connection (implements benchmarkgo.RowCounter).
+func (c *Context) CountRows(ctx context.Context) (int64, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return CountRows(ctx, conn)
+}
+
+// CountRowsFinal counts the table's rows with FINAL on a pooled connection (implements benchmarkgo.FinalCounter).
+func (c *Context) CountRowsFinal(ctx context.Context) (int64, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return CountRowsFinal(ctx, conn)
+}
+
+// OptimizeFinal runs OPTIMIZE ... FINAL on a pooled connection (implements benchmarkgo.DedupBackend).
+func (c *Context) OptimizeFinal(ctx context.Context) error {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return OptimizeFinal(ctx, conn, c.Topology)
+}
+
+// TableBytes measures the table's on-disk size on a pooled connection (implements benchmarkgo.StorageReporter).
+func (c *Context) TableBytes(ctx context.Context) (int64, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return TableBytes(ctx, conn, c.Topology)
+}
+
+// DescribeSchema renders the tables' DDL on a pooled connection (implements benchmarkgo.SchemaDescriber).
+func (c *Context) DescribeSchema(ctx context.Context) (string, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return DescribeSchema(ctx, conn, c.Topology)
+}
+
+// CacheCounters reads mark/uncompressed cache hits and misses on a pooled connection (implements benchmarkgo.CacheReporter).
+func (c *Context) CacheCounters(ctx context.Context) (int64, int64, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return CacheCounters(ctx, conn, c.Topology)
+}
+
+// ServerVersion reports the ClickHouse version on a pooled connection (implements benchmarkgo.VersionReporter).
+func (c *Context) ServerVersion(ctx context.Context) (string, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return ServerVersion(ctx, conn)
+}
+
+// MaxActiveParts returns the most active parts in any partition on any replica (implements benchmarkgo.PartsReporter).
+func (c *Context) MaxActiveParts(ctx context.Context) (int64, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return MaxActiveParts(ctx, conn, c.Topology)
+}
+
+// ServerMetrics samples server statistics on a pooled connection (implements benchmarkgo.ServerMetricsReporter).
+func (c *Context) ServerMetrics(ctx context.Context) (map[string]float64, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return ServerMetrics(ctx, conn, c.Topology)
+}
+
+// errPartitionedSnapshot is returned by the snapshot methods when PartitionMinutes is set.
+var errPartitionedSnapshot = errors.New("snapshots need the unpartitioned table (PartitionMinutes 0)")
+
+// SaveSnapshot copies the table into snapshot name on a pooled connection (implements benchmarkgo.SnapshotBackend).
+func (c *Context) SaveSnapshot(ctx context.Context, name string) (benchmarkgo.SnapshotResult, error) {
+	if c.PartitionMinutes > 0 {
+		return benchmarkgo.SnapshotResult{}, errPartitionedSnapshot
+	}
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return SaveSnapshot(ctx, conn, name, c.Topology)
+}
+
+// RestoreSnapshot replaces the table's contents with snapshot name on a pooled connection.
+func (c *Context) RestoreSnapshot(ctx context.Context, name string) (benchmarkgo.SnapshotResult, error) {
+	if c.PartitionMinutes > 0 {
+		return benchmarkgo.SnapshotResult{}, errPartitionedSnapshot
+	}
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return RestoreSnapshot(ctx, conn, name, c.Topology)
+}
+
+// CountMRN counts rows with the MRN on a pooled connection (read-after-write checks of a dual-write run).
+func (c *Context) CountMRN(ctx context.Context, mrn string) (int, error) {
+	conn := <-c.ch
+	defer func() { c.ch <- conn }()
+	return QueryByPrimaryKey(ctx, conn, mrn)
+}
+
+// visibilityPollInterval spaces the FINAL lookups of a record that is not visible yet.
+const visibilityPollInterval = 5 * time.Millisecond
+
+// waitVisible polls mrn until a FINAL lookup returns it or the visibility deadline passes, and records the lag
+// from insertTime. Failed lookups are counted as query errors and retried.
+func (c *Context) waitVisible(ctx context.Context, conn driver.Conn, mrn string, insertTime time.Time) {
+	deadline := insertTime.Add(c.VisibilityTimeout)
+	for {
+		n, err := QueryByPrimaryKey(ctx, conn, mrn)
+		if err != nil {
+			benchmarkgo.AddError(benchmarkgo.ErrOpQuery, err)
+		} else if n >= 1 {
+			benchmarkgo.AddVisibilityLag(time.Since(insertTime), true)
+			return
+		}
+		if time.Now().After(deadline) {
+			benchmarkgo.AddVisibilityLag(0, false)
+			return
+		}
+		time.Sleep(visibilityPollInterval)
+	}
+}
+
+// querier binds a pooled connection to benchmarkgo.Querier.
+type querier struct {
+	conn driver.Conn
+}
+
+func (q querier) QueryByPrimaryKey(ctx context.Context, mrn string) (int, error) {
+	return QueryByPrimaryKey(ctx, q.conn, mrn)
+}
+
+// timedQuerier adds server-side timing, which relies on progress packets only the native protocol sends.
+type timedQuerier struct {
+	querier
+}
+
+func (q timedQuerier) QueryByPrimaryKeyTimed(ctx context.Context, mrn string) (int, time.Duration, error) {
+	return QueryByPrimaryKeyTimed(ctx, q.conn, mrn)
+}
+
+func (q querier) FetchByPrimaryKey(ctx context.Context, mrn string, withPayload bool) (int, int64, error) {
+	return FetchByPrimaryKey(ctx, q.conn, mrn, withPayload)
+}
+
+func (q querier) FetchVerifyColumns(ctx context.Context, mrn string) (*benchmarkgo.VerifyColumns, error) {
+	return FetchVerifyColumns(ctx, q.conn, mrn)
+}
+
+func (q querier) FetchChecksumRow(ctx context.Context, mrn string) (*benchmarkgo.ChecksumRow, error) {
+	return FetchChecksumRow(ctx, q.conn, mrn)
+}
+
+func (q querier) FetchSource(ctx context.Context, mrn string) (string, error) {
+	return FetchSource(ctx, q.conn, mrn)
+}
+
+func (q querier) QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error) {
+	return QueryResultSet(ctx, q.conn, mrn, limit)
+}
+
+func (q querier) QueryPageOffset(ctx context.Context, offset, limit int) (int, error) {
+	return QueryPageOffset(ctx, q.conn, offset, limit)
+}
+
+func (q querier) QueryPageAfter(ctx context.Context, lastName, firstName, patientID string, limit int) (int, error) {
+	return QueryPageAfter(ctx, q.conn, lastName, firstName, patientID, limit)
+}
+
+func (q querier) QueryByNameDOB(ctx context.Context, lastName, firstName, dateOfBirth string) (int, error) {
+	return QueryByNameDOB(ctx, q.conn, lastName, firstName, dateOfBirth)
+}
+
+func (q querier) QueryByPatientID(ctx context.Context, patientID string) (int, error) {
+	return QueryByPatientID(ctx, q.conn, patientID)
+}
+
+func (q querier) AggregateByPatientID(ctx context.Context, patientID string) (int, error) {
+	return AggregateByPatientID(ctx, q.conn, patientID)
+}
+
+func (q querier) QueryPatientRange(ctx context.Context, patientID string, from, to time.Time) (int, error) {
+	return QueryPatientRange(ctx, q.conn, patientID, from, to)
+}
+
 // RunQueryWorker consumes from queryQueue and runs queries, reports via benchmarkgo.AddQuery.
 // workerIndex is the 0-based index of this query worker.
 func (c *Context) RunQueryWorker(
 	workerIndex int,
 	queryQueue <-chan *benchmarkgo.QueryJob,
-	queriesPerRecord int,
-	queryDelaySec float64,
-	ignoreSelectErrors bool,
+	opts benchmarkgo.QueryOptions,
 ) {
 	_ = workerIndex // reserved for logging/tracing
+	runner := benchmarkgo.NewQueryRunner(opts)
 	for job := range queryQueue {
 		if job == nil {
 			return
 		}
-		if queryDelaySec > 0 {
-			deadline := job.InsertTime.Add(time.Duration(queryDelaySec * float64(time.Second)))
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
 			if time.Now().Before(deadline) {
 				time.Sleep(time.Until(deadline))
 			}
 		}
-		conn := <-c.ch
 		t0 := time.Now()
-		var failed int
-		for i := 0; i < queriesPerRecord; i++ {
-			n, _ := QueryByPrimaryKey(context.Background(), conn, job.MRN)
-			if n != 1 {
-				failed++
-				if !ignoreSelectErrors {
-					log.Printf("Query by primary key returned %d rows for MEDICAL_RECORD_NUMBER=%s (expected 1)", n, job.MRN)
-				}
-			}
+		conn := <-c.queryPool.ch
+		benchmarkgo.AddQueryPoolWait(time.Since(t0))
+		if c.VisibilityTimeout > 0 && !job.InsertTime.IsZero() {
+			c.waitVisible(context.Background(), conn, job.MRN, job.InsertTime)
+		}
+		var q benchmarkgo.Querier = querier{conn}
+		if c.Protocol == ProtocolNative {
+			q = timedQuerier{querier{conn}}
+		}
+		count, failed, latency := runner.Run(context.Background(), q, job)
+		if failed > 0 {
+			c.queryPool.markSuspect(conn) // errors are not surfaced per query; a ping tells a dead connection apart
 		}
-		latencyMicros := time.Since(t0).Microseconds()
-		c.ch <- conn
-		benchmarkgo.AddQuery(int64(queriesPerRecord), latencyMicros, int64(failed))
+		c.queryPool.release(conn)
+		benchmarkgo.AddQuery(int64(count), latency.Microseconds(), int64(failed))
 	}
 }
@@ -0,0 +1,170 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Client resources: the load generator's own CPU, memory, GC and goroutines, sampled every progress interval.
+// A generator that is CPU-bound or stalled in GC under-reports what the database can do, so it must show.
+
+// clientCPUBoundPct is the share of the usable CPUs above which an interval counts as client-bound.
+const clientCPUBoundPct = 85
+
+// ClientSample is the load generator's resource use over one progress interval.
+type ClientSample struct {
+	AtSec        float64 `json:"at_sec"`
+	IntervalSec  float64 `json:"interval_sec"`
+	CPUCores     float64 `json:"cpu_cores"` // CPU seconds per wall second
+	CPUPct       float64 `json:"cpu_pct"`   // of the usable CPUs
+	RSSBytes     int64   `json:"rss_bytes"` // 0 where the OS does not report it
+	HeapBytes    int64   `json:"heap_bytes"`
+	GCs          uint32  `json:"gcs"`
+	GCPauseMs    float64 `json:"gc_pause_ms"` // stop-the-world pause summed over the interval's GCs
+	MaxGCPauseMs float64 `json:"max_gc_pause_ms"`
+	Goroutines   int     `json:"goroutines"`
+}
+
+// ClientReport summarizes the load generator's resource use over the run.
+type ClientReport struct {
+	CPUs              float64        `json:"cpus"` // GOMAXPROCS, capped by the cgroup CPU limit
+	AvgCPUPct         float64        `json:"avg_cpu_pct"`
+	PeakCPUPct        float64        `json:"peak_cpu_pct"`
+	CPUBoundIntervals int            `json:"cpu_bound_intervals"` // intervals above clientCPUBoundPct
+	PeakRSSBytes      int64          `json:"peak_rss_bytes"`
+	PeakHeapBytes     int64          `json:"peak_heap_bytes"`
+	GCs               uint32         `json:"gcs"`
+	GCPauseMs         float64        `json:"gc_pause_ms"`
+	MaxGCPauseMs      float64        `json:"max_gc_pause_ms"`
+	PeakGoroutines    int            `json:"peak_goroutines"`
+	Samples           []ClientSample `json:"samples"`
+}
+
+// clientSampler keeps the previous interval's counters; guarded by mu.
+type clientSampler struct {
+	mu       sync.Mutex
+	start    time.Time
+	prevAt   time.Time
+	prevCPU  float64
+	prevGC   uint32
+	prevTime uint64 // MemStats.PauseTotalNs
+	samples  []ClientSample
+}
+
+var clientRes = &clientSampler{}
+
+// usableCPUs is GOMAXPROCS, capped by the cgroup CPU limit.
+func usableCPUs() float64 {
+	procs := float64(runtime.GOMAXPROCS(0))
+	if limit := CgroupCPULimit(); limit > 0 && limit < procs {
+		procs = limit
+	}
+	return procs
+}
+
+// startClientSampling resets the sampler; the first interval begins now.
+func startClientSampling() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	c := clientRes
+	c.mu.Lock()
+	c.start = time.Now()
+	c.prevAt = c.start
+	c.prevCPU = processCPUSeconds()
+	c.prevGC = ms.NumGC
+	c.prevTime = ms.PauseTotalNs
+	c.samples = nil
+	c.mu.Unlock()
+}
+
+// sampleClient measures the interval since the previous sample and records it.
+func sampleClient() ClientSample {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	now := time.Now()
+	cpu := processCPUSeconds()
+	c := clientRes
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := ClientSample{
+		AtSec:       now.Sub(c.start).Seconds(),
+		IntervalSec: now.Sub(c.prevAt).Seconds(),
+		RSSBytes:    processRSSBytes(),
+		HeapBytes:   int64(ms.HeapAlloc),
+		GCs:         ms.NumGC - c.prevGC,
+		GCPauseMs:   float64(ms.PauseTotalNs-c.prevTime) / 1e6,
+		Goroutines:  runtime.NumGoroutine(),
+	}
+	if s.IntervalSec > 0 {
+		s.CPUCores = (cpu - c.prevCPU) / s.IntervalSec
+		s.CPUPct = s.CPUCores / usableCPUs() * 100
+	}
+	// PauseNs is a ring of the last 256 pauses; the newest is at (NumGC+255)%256.
+	for i := uint32(0); i < min(s.GCs, uint32(len(ms.PauseNs))); i++ {
+		p := float64(ms.PauseNs[(ms.NumGC-i+255)%256]) / 1e6
+		s.MaxGCPauseMs = max(s.MaxGCPauseMs, p)
+	}
+	c.prevAt, c.prevCPU, c.prevGC, c.prevTime = now, cpu, ms.NumGC, ms.PauseTotalNs
+	c.samples = append(c.samples, s)
+	return s
+}
+
+// String renders the sample for the progress log.
+func (s ClientSample) String() string {
+	rss := "n/a"
+	if s.RSSBytes > 0 {
+		rss = FormatBytes(s.RSSBytes)
+	}
+	return fmt.Sprintf("CPU %.2f cores (%.0f%%) | RSS %s | heap %s | GC %d, pause %.2f ms (max %.2f) | goroutines %d",
+		s.CPUCores, s.CPUPct, rss, FormatBytes(s.HeapBytes), s.GCs, s.GCPauseMs, s.MaxGCPauseMs, s.Goroutines)
+}
+
+// loadClient summarizes the samples so far; nil before the first interval.
+func loadClient() *ClientReport {
+	c := clientRes
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.samples) == 0 {
+		return nil
+	}
+	rep := &ClientReport{CPUs: usableCPUs(), Samples: append([]ClientSample(nil), c.samples...)}
+	var cpuSec, wallSec float64
+	for _, s := range c.samples {
+		cpuSec += s.CPUCores * s.IntervalSec
+		wallSec += s.IntervalSec
+		rep.PeakCPUPct = max(rep.PeakCPUPct, s.CPUPct)
+		if s.CPUPct > clientCPUBoundPct {
+			rep.CPUBoundIntervals++
+		}
+		rep.PeakRSSBytes = max(rep.PeakRSSBytes, s.RSSBytes)
+		rep.PeakHeapBytes = max(rep.PeakHeapBytes, s.HeapBytes)
+		rep.GCs += s.GCs
+		rep.GCPauseMs += s.GCPauseMs
+		rep.MaxGCPauseMs = max(rep.MaxGCPauseMs, s.MaxGCPauseMs)
+		rep.PeakGoroutines = max(rep.PeakGoroutines, s.Goroutines)
+	}
+	if wallSec > 0 {
+		rep.AvgCPUPct = cpuSec / wallSec / rep.CPUs * 100
+	}
+	return rep
+}
+
+// logClient logs the generator's resource use and warns when intervals were client-bound.
+func logClient(rep *ClientReport) {
+	if rep == nil {
+		return
+	}
+	rss := "n/a"
+	if rep.PeakRSSBytes > 0 {
+		rss = FormatBytes(rep.PeakRSSBytes)
+	}
+	log.Printf("Client resources: CPU avg %.0f%%, peak %.0f%% of %g CPUs | peak RSS %s, heap %s | %d GCs, %.1f ms paused (max %.2f ms) | peak %d goroutines",
+		rep.AvgCPUPct, rep.PeakCPUPct, rep.CPUs, rss, FormatBytes(rep.PeakHeapBytes), rep.GCs, rep.GCPauseMs, rep.MaxGCPauseMs, rep.PeakGoroutines)
+	if rep.CPUBoundIntervals > 0 {
+		log.Printf("  %s%d of %d intervals above %d%% CPU: the generator itself was saturated, so throughput and latency there understate the database%s",
+			_colorYellow, rep.CPUBoundIntervals, len(rep.Samples), clientCPUBoundPct, _colorReset)
+	}
+}
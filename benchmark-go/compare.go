@@ -0,0 +1,141 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ComparisonMetric is one metric of two runs and its relative change from the baseline.
+type ComparisonMetric struct {
+	Name           string  `json:"name"`
+	Baseline       float64 `json:"baseline"`
+	Candidate      float64 `json:"candidate"`
+	DeltaPct       float64 `json:"delta_pct"` // (candidate - baseline) / baseline × 100; NaN when the baseline is 0
+	HigherIsBetter bool    `json:"higher_is_better"`
+	Regression     bool    `json:"regression"` // worse than the baseline by more than the threshold
+}
+
+// comparedMetric extracts one metric from a run; ok is false when the run has no data for it.
+type comparedMetric struct {
+	name           string
+	higherIsBetter bool
+	value          func(res *Results) (float64, bool)
+}
+
+func latencyMetric(name string, pick func(*Results) *LatencyPercentiles, p func(*LatencyPercentiles) float64) comparedMetric {
+	return comparedMetric{name, false, func(res *Results) (float64, bool) {
+		if lp := pick(res); lp != nil {
+			return p(lp), true
+		}
+		return 0, false
+	}}
+}
+
+func insertPercentiles(res *Results) *LatencyPercentiles { return res.Inserted.Latency }
+func queryPercentiles(res *Results) *LatencyPercentiles  { return res.Queries.Latency }
+
+var comparedMetrics = []comparedMetric{
+	{"insert rows/s", true, func(res *Results) (float64, bool) { return res.ActualRPS, true }},
+	{"goodput rows/s", true, func(res *Results) (float64, bool) {
+		if res.Goodput == nil {
+			return 0, false
+		}
+		return res.Goodput.GoodputRPS, true
+	}},
+	{"steady rows/s", true, func(res *Results) (float64, bool) {
+		if res.Summary != nil {
+			for _, p := range res.Summary.Phases {
+				if p.Name == phaseNames[PhaseSteady] {
+					return p.RowsPerSec, true
+				}
+			}
+		}
+		return 0, false
+	}},
+	latencyMetric("insert p50 ms", insertPercentiles, func(p *LatencyPercentiles) float64 { return p.P50Ms }),
+	latencyMetric("insert p90 ms", insertPercentiles, func(p *LatencyPercentiles) float64 { return p.P90Ms }),
+	latencyMetric("insert p95 ms", insertPercentiles, func(p *LatencyPercentiles) float64 { return p.P95Ms }),
+	latencyMetric("insert p99 ms", insertPercentiles, func(p *LatencyPercentiles) float64 { return p.P99Ms }),
+	latencyMetric("insert p99.9 ms", insertPercentiles, func(p *LatencyPercentiles) float64 { return p.P999Ms }),
+	latencyMetric("insert max ms", insertPercentiles, func(p *LatencyPercentiles) float64 { return p.MaxMs }),
+	{"queries/s", true, func(res *Results) (float64, bool) {
+		if res.ElapsedSec == 0 || res.Queries.Count == 0 {
+			return 0, false
+		}
+		return res.Queries.Count / res.ElapsedSec, true
+	}},
+	latencyMetric("query p50 ms", queryPercentiles, func(p *LatencyPercentiles) float64 { return p.P50Ms }),
+	latencyMetric("query p90 ms", queryPercentiles, func(p *LatencyPercentiles) float64 { return p.P90Ms }),
+	latencyMetric("query p95 ms", queryPercentiles, func(p *LatencyPercentiles) float64 { return p.P95Ms }),
+	latencyMetric("query p99 ms", queryPercentiles, func(p *LatencyPercentiles) float64 { return p.P99Ms }),
+	latencyMetric("query p99.9 ms", queryPercentiles, func(p *LatencyPercentiles) float64 { return p.P999Ms }),
+	latencyMetric("query max ms", queryPercentiles, func(p *LatencyPercentiles) float64 { return p.MaxMs }),
+}
+
+// CompareResults compares candidate with baseline on the throughput and latency metrics both runs report,
+// flagging metrics that got worse by more than thresholdPct percent.
+func CompareResults(baseline, candidate *Results, thresholdPct float64) []ComparisonMetric {
+	var out []ComparisonMetric
+	for _, m := range comparedMetrics {
+		b, okB := m.value(baseline)
+		c, okC := m.value(candidate)
+		if !okB || !okC {
+			continue
+		}
+		cm := ComparisonMetric{Name: m.name, Baseline: b, Candidate: c, HigherIsBetter: m.higherIsBetter, DeltaPct: math.NaN()}
+		if b != 0 {
+			cm.DeltaPct = (c - b) / b * 100
+			worse := cm.DeltaPct
+			if m.higherIsBetter {
+				worse = -worse
+			}
+			cm.Regression = worse > thresholdPct
+		}
+		out = append(out, cm)
+	}
+	return out
+}
+
+// Regressions returns the names of the regressed metrics.
+func Regressions(metrics []ComparisonMetric) []string {
+	var names []string
+	for _, m := range metrics {
+		if m.Regression {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
+
+// LogComparison prints the metrics as a baseline/candidate/delta table, marking regressions.
+func LogComparison(baselineName, candidateName string, metrics []ComparisonMetric, thresholdPct float64) {
+	width := max(14, len(baselineName)+2, len(candidateName)+2)
+	log.Printf("Comparing %s (baseline) with %s (regression threshold %g%%):", baselineName, candidateName, thresholdPct)
+	log.Printf("  %-18s%*s%*s%10s", "metric", width, "baseline", width, "candidate", "delta")
+	for _, m := range metrics {
+		delta := "n/a"
+		if !math.IsNaN(m.DeltaPct) {
+			delta = fmt.Sprintf("%+.1f%%", m.DeltaPct)
+		}
+		flag := ""
+		if m.Regression {
+			flag = "  REGRESSION"
+		}
+		log.Printf("  %-18s%*.2f%*.2f%10s%s", m.Name, width, m.Baseline, width, m.Candidate, delta, flag)
+	}
+}
+
+// ParsePercent parses a threshold such as "10%" or "10" into 10.
+func ParsePercent(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("%g%% is negative", v)
+	}
+	return v, nil
+}
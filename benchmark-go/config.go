@@ -1,15 +1,169 @@
 package benchmarkgo
 
-import "os"
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Connection is where the postgres and clickhouse backends connect. Host and Port override the backend's own
+// <NAME>_HOST/<NAME>_PORT environment variables and defaults when set.
+type Connection struct {
+	Host     string
+	Port     int
+	Database string
+	User     string
+	Password string
+	Cluster  string // ClickHouse cluster for ON CLUSTER and the Distributed table (must match the deployment)
+	// QueryHost (host or host:port), when set, is where the query workers' pool connects, e.g. a read replica,
+	// while inserts go to Host. Its port defaults to the insert port.
+	QueryHost string
+}
+
+// connection is the process-wide setting, changed by SetConnection.
+var connection = DefaultConnection()
+
+// DefaultConnection returns the settings of the docker-compose deployment.
+func DefaultConnection() Connection {
+	return Connection{Database: "postgres", User: "default", Password: "strongpassword", Cluster: "dev-cluster"}
+}
+
+// FromEnv returns c overridden by <prefix>_HOST, _PORT, _DB, _USER, _PASSWORD and _CLUSTER where they are set
+// (an empty _USER or _PASSWORD counts as set).
+func (c Connection) FromEnv(prefix string) (Connection, error) {
+	if v := os.Getenv(prefix + "_HOST"); v != "" {
+		c.Host = v
+	}
+	if v := os.Getenv(prefix + "_PORT"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p <= 0 {
+			return c, fmt.Errorf("%s_PORT=%q is not a port number", prefix, v)
+		}
+		c.Port = p
+	}
+	if v := os.Getenv(prefix + "_DB"); v != "" {
+		c.Database = v
+	}
+	if v, ok := os.LookupEnv(prefix + "_USER"); ok {
+		c.User = v
+	}
+	if v, ok := os.LookupEnv(prefix + "_PASSWORD"); ok {
+		c.Password = v
+	}
+	if v := os.Getenv(prefix + "_CLUSTER"); v != "" {
+		c.Cluster = v
+	}
+	if v := os.Getenv(prefix + "_QUERY_HOST"); v != "" {
+		c.QueryHost = v
+	}
+	return c, nil
+}
 
-const (
-	DBName   = "postgres"
-	User     = "default"
-	Password = "strongpassword"
+// SetConnection replaces the connection settings (call once at startup, before Setup). Database and Cluster are
+// spliced into ClickHouse statements (the database unquoted, the cluster in single quotes), so they are checked.
+func SetConnection(c Connection) error {
+	if !databaseNameRe.MatchString(c.Database) {
+		return fmt.Errorf("database name %q must match %s", c.Database, databaseNameRe)
+	}
+	if !clusterNameRe.MatchString(c.Cluster) {
+		return fmt.Errorf("cluster name %q must match %s", c.Cluster, clusterNameRe)
+	}
+	connection = c
+	return nil
+}
+
+var (
+	databaseNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	clusterNameRe  = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
 )
 
-// ClickHouse cluster name (must match deployment; used on ON CLUSTER and Distributed table).
-const ClickHouseCluster = "dev-cluster"
+// DBHost returns the host override ("" = the backend's default).
+func DBHost() string { return connection.Host }
+
+// DBPort returns the port override (0 = the backend's default).
+func DBPort() int { return connection.Port }
+
+// DBName returns the database the postgres and clickhouse backends use.
+func DBName() string { return connection.Database }
+
+// DBUser returns the user the postgres and clickhouse backends log in as.
+func DBUser() string { return connection.User }
+
+// DBPassword returns that user's password.
+func DBPassword() string { return connection.Password }
+
+// ClickHouseCluster returns the ClickHouse cluster name used on ON CLUSTER and the Distributed table.
+func ClickHouseCluster() string { return connection.Cluster }
+
+// DBQueryHost returns Connection.QueryHost, empty when query workers connect where inserts do.
+func DBQueryHost() string { return connection.QueryHost }
+
+// QueryAddr returns the address the query workers' pool connects to: Connection.QueryHost when set (its port
+// defaulting to port), else host and port, the insert pool's address.
+func QueryAddr(host string, port int) (string, int) {
+	if connection.QueryHost == "" {
+		return host, port
+	}
+	h, p, err := net.SplitHostPort(connection.QueryHost)
+	if err != nil {
+		return connection.QueryHost, port
+	}
+	if n, err := strconv.Atoi(p); err == nil {
+		port = n
+	}
+	return h, port
+}
+
+// PoolOptions size and tune the connection pools of the postgres, clickhouse and database/sql backends. The zero
+// value is the default: one connection per insert worker and one per query worker, all kept open for the run.
+type PoolOptions struct {
+	InsertSize  int           // connections for insert workers (0 = one per worker)
+	QuerySize   int           // connections for query workers (0 = one per worker)
+	MaxIdle     int           // connections kept open while idle (0 = all; not clickhouse, whose pool is fixed)
+	MaxLifetime time.Duration // close and reopen connections older than this (0 = never)
+}
+
+// pool is the process-wide setting, changed by SetPoolOptions.
+var pool PoolOptions
+
+// SetPoolOptions sets the pool options (call once at startup, before Setup).
+func SetPoolOptions(o PoolOptions) error {
+	if o.InsertSize < 0 || o.QuerySize < 0 || o.MaxIdle < 0 || o.MaxLifetime < 0 {
+		return fmt.Errorf("pool sizes, max idle and max lifetime must be >= 0")
+	}
+	pool = o
+	return nil
+}
+
+// Pool returns the pool options set by SetPoolOptions.
+func Pool() PoolOptions { return pool }
+
+// Sizes returns the insert and query pool sizes for numWorkers workers of each kind; query is 0 when no query
+// workers run.
+func (o PoolOptions) Sizes(numWorkers int, queries bool) (insert, query int) {
+	insert, query = numWorkers, numWorkers
+	if o.InsertSize > 0 {
+		insert = o.InsertSize
+	}
+	if o.QuerySize > 0 {
+		query = o.QuerySize
+	}
+	if !queries {
+		query = 0
+	}
+	return insert, query
+}
+
+// Idle returns how many of size connections to keep open while idle.
+func (o PoolOptions) Idle(size int) int {
+	if o.MaxIdle > 0 {
+		return min(o.MaxIdle, size)
+	}
+	return size
+}
 
 // ClickHouseStoragePolicy for hl7_messages_local (must exist on ClickHouse server).
 func ClickHouseStoragePolicy() string {
@@ -18,3 +172,36 @@ func ClickHouseStoragePolicy() string {
 	}
 	return "hl7_tiered"
 }
+
+// baseTable is the unprefixed name of the benchmark table.
+const baseTable = "hl7_messages"
+
+// tablePrefix is prepended to every table, index, partition and key name so concurrent runs can share one database.
+var tablePrefix string
+
+var tablePrefixRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// SetTablePrefix sets the prefix used by Table (call once at startup, before Setup).
+// The prefix is spliced into DDL and queries unquoted, so it must be a lowercase SQL identifier.
+func SetTablePrefix(prefix string) error {
+	if prefix != "" && !tablePrefixRe.MatchString(prefix) {
+		return fmt.Errorf("table prefix %q must match %s", prefix, tablePrefixRe)
+	}
+	tablePrefix = prefix
+	return nil
+}
+
+// TablePrefix returns the prefix set by SetTablePrefix ("" by default).
+func TablePrefix() string {
+	return tablePrefix
+}
+
+// Table returns the (prefixed) benchmark table name, e.g. "run2_hl7_messages".
+func Table() string {
+	return tablePrefix + baseTable
+}
+
+// Prefixed applies the table prefix to a secondary object name (index, partition, local table).
+func Prefixed(name string) string {
+	return tablePrefix + name
+}
@@ -0,0 +1,157 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Isolation levels for the conflicting-writer scenario.
+const (
+	IsolationReadCommitted  = "read_committed"
+	IsolationRepeatableRead = "repeatable_read"
+	IsolationSerializable   = "serializable"
+)
+
+// Conflict kinds a ConflictBackend reports for a retried transaction.
+const (
+	ConflictDeadlock      = "deadlock"
+	ConflictSerialization = "serialization"
+)
+
+// ValidateIsolation checks a --conflict-isolation value.
+func ValidateIsolation(level string) error {
+	switch level {
+	case IsolationReadCommitted, IsolationRepeatableRead, IsolationSerializable:
+		return nil
+	}
+	return fmt.Errorf("unknown isolation level %q (want %s, %s or %s)", level,
+		IsolationReadCommitted, IsolationRepeatableRead, IsolationSerializable)
+}
+
+// ConflictOptions configures the conflicting-writer scenario: Writers goroutines repeatedly upserting the same
+// Keys MRNs, each transaction in its own random order, under Isolation.
+type ConflictOptions struct {
+	Writers   int
+	Keys      int
+	Isolation string
+}
+
+// ConflictBackend is implemented by WorkerCtx backends that can run the conflicting-writer scenario until ctx is
+// done, reporting every transaction outcome to rec.
+type ConflictBackend interface {
+	RunConflictWriters(ctx context.Context, opts ConflictOptions, rec *ConflictRecorder) error
+}
+
+// ConflictReport summarizes the conflicting-writer scenario.
+type ConflictReport struct {
+	Isolation             string  `json:"isolation"`
+	Writers               int     `json:"writers"`
+	Keys                  int     `json:"keys"`
+	Committed             int64   `json:"committed"`
+	Aborted               int64   `json:"aborted"` // gave up: non-retryable error or retries exhausted
+	Deadlocks             int64   `json:"deadlocks"`
+	SerializationFailures int64   `json:"serialization_failures"`
+	RetriedTransactions   int64   `json:"retried_transactions"` // committed after at least one retry
+	FirstTryAvgMs         float64 `json:"first_try_avg_ms"`     // commit latency of transactions that needed no retry
+	RetryAvgMs            float64 `json:"retry_avg_ms"`         // first attempt to commit, for retried transactions
+	RetryMaxMs            float64 `json:"retry_max_ms"`
+	Error                 string  `json:"error,omitempty"`
+}
+
+// ConflictRecorder accumulates transaction outcomes from concurrent conflict writers.
+type ConflictRecorder struct {
+	mu          sync.Mutex
+	rep         ConflictReport
+	firstTrySec float64
+	retrySec    float64
+}
+
+// Retry records a transaction attempt that failed with a retryable conflict of kind.
+func (c *ConflictRecorder) Retry(kind string, err error) {
+	AddError(ErrOpConflict, err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch kind {
+	case ConflictDeadlock:
+		c.rep.Deadlocks++
+	case ConflictSerialization:
+		c.rep.SerializationFailures++
+	}
+}
+
+// Commit records a committed transaction that took attempts tries and latency from first attempt to commit.
+func (c *ConflictRecorder) Commit(attempts int, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rep.Committed++
+	sec := latency.Seconds()
+	if attempts <= 1 {
+		c.firstTrySec += sec
+		return
+	}
+	c.rep.RetriedTransactions++
+	c.retrySec += sec
+	if ms := sec * 1000; ms > c.rep.RetryMaxMs {
+		c.rep.RetryMaxMs = ms
+	}
+}
+
+// Abort records a transaction that was given up on.
+func (c *ConflictRecorder) Abort(err error) {
+	AddError(ErrOpConflict, err)
+	c.mu.Lock()
+	c.rep.Aborted++
+	c.mu.Unlock()
+}
+
+// Report returns the accumulated report with averages filled in.
+func (c *ConflictRecorder) Report() ConflictReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rep := c.rep
+	if first := rep.Committed - rep.RetriedTransactions; first > 0 {
+		rep.FirstTryAvgMs = c.firstTrySec / float64(first) * 1000
+	}
+	if rep.RetriedTransactions > 0 {
+		rep.RetryAvgMs = c.retrySec / float64(rep.RetriedTransactions) * 1000
+	}
+	return rep
+}
+
+// runConflicts runs the conflicting-writer scenario alongside the load until ctx is done.
+func (r *LoadRunner) runConflicts(ctx context.Context) {
+	cfg := &r.Config
+	cb, ok := r.WorkerCtx.(ConflictBackend)
+	if !ok {
+		log.Printf("Conflict writers: %s backend does not support the conflicting-writer scenario, skipping", cfg.Database)
+		return
+	}
+	opts := ConflictOptions{Writers: cfg.ConflictWriters, Keys: cfg.ConflictKeys, Isolation: cfg.ConflictIsolation}
+	log.Printf("Conflict writers: %d writers upserting the same %d MRNs under %s", opts.Writers, opts.Keys, opts.Isolation)
+	rec := &ConflictRecorder{rep: ConflictReport{Isolation: opts.Isolation, Writers: opts.Writers, Keys: opts.Keys}}
+	err := cb.RunConflictWriters(ctx, opts, rec)
+	rep := rec.Report()
+	if err != nil {
+		rep.Error = err.Error()
+		log.Printf("Conflict writers: %v", err)
+	}
+	r.conflicts = &rep
+}
+
+// logConflicts logs the conflicting-writer scenario outcome (only when it ran).
+func logConflicts(rep *ConflictReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Conflict writers (%d writers x %d MRNs, %s): %d committed, %d aborted",
+		rep.Writers, rep.Keys, rep.Isolation, rep.Committed, rep.Aborted)
+	log.Printf("  deadlocks %d | serialization failures %d | %d transactions committed after retry",
+		rep.Deadlocks, rep.SerializationFailures, rep.RetriedTransactions)
+	log.Printf("  latency: first try avg %.2f ms | retried avg %.2f ms, max %.2f ms", rep.FirstTryAvgMs, rep.RetryAvgMs, rep.RetryMaxMs)
+	if rep.Error != "" {
+		log.Printf("  error: %s", rep.Error)
+	}
+}
@@ -0,0 +1,155 @@
+package benchmarkgo
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Control API: with Config.ControlAddr, a small HTTP server runs for the length of the load so a long soak test can
+// be watched and adjusted without a restart:
+//
+//	GET  /stats     live counters (ControlStats)
+//	POST /settings  apply LiveSettings (same JSON as the --live-config file), e.g. {"target_rps": 5000}
+//	POST /pause     hold insert batches back (the duty-cycle gate); producers block once the queues fill
+//	POST /resume    let them through again
+//	POST /stop      end the run now, as if the duration had passed; the summary and reports still run
+//
+// Every change is recorded in the run timeline like a live config change. The POST routes need
+// "Authorization: Bearer <Config.ControlToken>"; without a token the API only listens on a loopback address.
+
+// ControlStats is the body of GET /stats.
+type ControlStats struct {
+	ElapsedSec float64       `json:"elapsed_sec"`
+	TargetRPS  int64         `json:"target_rps"`
+	Paused     bool          `json:"paused"`
+	InsertRate float64       `json:"insert_rate"` // rows/sec since the run started
+	Inserted   InsertedStats `json:"inserted"`
+	Queries    QueryStats    `json:"queries"`
+	Errors     []ErrorCount  `json:"errors,omitempty"`
+}
+
+// serveControl serves the control API on addr until ctx ends.
+func (r *LoadRunner) serveControl(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	token := r.Config.ControlToken
+	if tcp, ok := lis.Addr().(*net.TCPAddr); ok && token == "" && !tcp.IP.IsLoopback() {
+		lis.Close()
+		return fmt.Errorf("%s is not a loopback address; set a control token to serve the API beyond localhost", addr)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stats", r.controlStats)
+	mux.HandleFunc("POST /settings", checkControlToken(token, r.controlSettings))
+	mux.HandleFunc("POST /pause", checkControlToken(token, r.controlPause(true)))
+	mux.HandleFunc("POST /resume", checkControlToken(token, r.controlPause(false)))
+	mux.HandleFunc("POST /stop", checkControlToken(token, r.controlStop))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Control API on %s: %v", addr, err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	log.Printf("Control API on http://%s (GET /stats; POST /settings, /pause, /resume, /stop)", lis.Addr())
+	return nil
+}
+
+// checkControlToken wraps a route that changes the run: requests without the bearer token are refused. An empty
+// token lets every request through (serveControl then listens on loopback only).
+func checkControlToken(token string, h http.HandlerFunc) http.HandlerFunc {
+	if token == "" {
+		return h
+	}
+	want := []byte("Bearer " + token)
+	return func(w http.ResponseWriter, req *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), want) != 1 {
+			controlError(w, http.StatusUnauthorized, fmt.Errorf("missing or wrong control token"))
+			return
+		}
+		h(w, req)
+	}
+}
+
+func (r *LoadRunner) controlStats(w http.ResponseWriter, _ *http.Request) {
+	snap := loadSnapshot()
+	elapsed := time.Since(r.runStart).Seconds()
+	st := ControlStats{
+		ElapsedSec: elapsed,
+		TargetRPS:  targetRPS.Load(),
+		Paused:     r.dutyGate != nil && r.dutyGate.paused(),
+		Inserted:   snap.Inserted,
+		Queries:    snap.Queries,
+		Errors:     snap.Errors,
+	}
+	if elapsed > 0 {
+		st.InsertRate = snap.Inserted.Total / elapsed
+	}
+	writeControlJSON(w, http.StatusOK, st)
+}
+
+func (r *LoadRunner) controlSettings(w http.ResponseWriter, req *http.Request) {
+	var s LiveSettings
+	if err := json.NewDecoder(req.Body).Decode(&s); err != nil {
+		controlError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := r.ApplyLiveSettings(s, "control API"); err != nil {
+		controlError(w, http.StatusBadRequest, err)
+		return
+	}
+	r.controlStats(w, req)
+}
+
+// controlPause returns the handler for /pause (pause true) or /resume.
+func (r *LoadRunner) controlPause(pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if r.Config.DutyCycle.Enabled() {
+			controlError(w, http.StatusConflict, fmt.Errorf("the duty cycle is pausing and resuming this run"))
+			return
+		}
+		r.controlMu.Lock()
+		changed := r.dutyGate.paused() != pause
+		if changed && pause {
+			r.dutyGate.pause()
+		} else if changed {
+			r.dutyGate.resume()
+		}
+		r.controlMu.Unlock()
+		if changed {
+			state := "resumed"
+			if pause {
+				state = "paused"
+			}
+			r.events.add(r.runStart, "control", "control API: inserts "+state)
+		}
+		r.controlStats(w, req)
+	}
+}
+
+func (r *LoadRunner) controlStop(w http.ResponseWriter, req *http.Request) {
+	r.events.add(r.runStart, "control", "control API: stop")
+	r.cancelRun()
+	r.controlStats(w, req)
+}
+
+func writeControlJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func controlError(w http.ResponseWriter, status int, err error) {
+	writeControlJSON(w, status, map[string]string{"error": err.Error()})
+}
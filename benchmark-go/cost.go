@@ -0,0 +1,88 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StorageReporter is implemented by WorkerCtx backends that can measure the table's on-disk size.
+type StorageReporter interface {
+	TableBytes(ctx context.Context) (int64, error)
+}
+
+// CostOptions prices a run. Compute is the database's vCPUs for the run's wall time; storage is the table's
+// growth kept for a month. Zero prices leave that component out.
+type CostOptions struct {
+	PerGBMonth   float64
+	PerVCPUHour  float64
+	VCPUs        float64 // database vCPUs billed for the run's duration
+	storageStart int64   // table bytes before the load; -1 when not measurable
+}
+
+// Enabled reports whether any price is set.
+func (o CostOptions) Enabled() bool {
+	return o.PerGBMonth > 0 || o.PerVCPUHour > 0
+}
+
+// CostReport is the estimated cost of a run, normalized per million messages.
+type CostReport struct {
+	Rows           int64   `json:"rows"`
+	VCPUHours      float64 `json:"vcpu_hours"`
+	ComputeCost    float64 `json:"compute_cost"`
+	StorageBytes   int64   `json:"storage_growth_bytes"`
+	StorageSource  string  `json:"storage_source"` // "measured" (table size delta) or "logical" (bytes written)
+	StorageCostMon float64 `json:"storage_cost_per_month"`
+	TotalCost      float64 `json:"total_cost"`
+	PerMillion     float64 `json:"cost_per_million_messages"`
+}
+
+// startCost records the table size before the load when the backend can measure it.
+func (r *LoadRunner) startCost() {
+	r.cost.storageStart = -1
+	if sr, ok := r.WorkerCtx.(StorageReporter); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if b, err := sr.TableBytes(ctx); err == nil {
+			r.cost.storageStart = b
+		} else {
+			log.Printf("Cost: table size before load: %v (using logical bytes)", err)
+		}
+	}
+}
+
+// finishCost prices the run from elapsed time, rows and storage growth (measured when possible, else bytes sent).
+func (r *LoadRunner) finishCost(snapshot Snapshot) *CostReport {
+	rep := &CostReport{
+		Rows:          int64(snapshot.Inserted.Total),
+		StorageBytes:  int64(snapshot.Inserted.Bytes),
+		StorageSource: "logical",
+	}
+	if sr, ok := r.WorkerCtx.(StorageReporter); ok && r.cost.storageStart >= 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if b, err := sr.TableBytes(ctx); err == nil {
+			rep.StorageBytes, rep.StorageSource = max(0, b-r.cost.storageStart), "measured"
+		} else {
+			log.Printf("Cost: table size after load: %v (using logical bytes)", err)
+		}
+	}
+	rep.VCPUHours = r.cost.VCPUs * r.runEnd.Sub(r.runStart).Hours()
+	rep.ComputeCost = rep.VCPUHours * r.cost.PerVCPUHour
+	rep.StorageCostMon = float64(rep.StorageBytes) / (1 << 30) * r.cost.PerGBMonth
+	rep.TotalCost = rep.ComputeCost + rep.StorageCostMon
+	if rep.Rows > 0 {
+		rep.PerMillion = rep.TotalCost / float64(rep.Rows) * 1e6
+	}
+	return rep
+}
+
+// logCost logs the cost estimate (only when prices were configured).
+func logCost(c *CostReport) {
+	if c == nil {
+		return
+	}
+	log.Printf("Estimated cost: $%.4f per million messages ($%.4f total for %d messages)", c.PerMillion, c.TotalCost, c.Rows)
+	log.Printf("  compute: %.3f vCPU-hours = $%.4f | storage: %s growth (%s) = $%.4f per month retained",
+		c.VCPUHours, c.ComputeCost, FormatBytes(c.StorageBytes), c.StorageSource, c.StorageCostMon)
+}
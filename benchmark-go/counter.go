@@ -0,0 +1,114 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Patient counter modes: how producers map their sequence of new patients to globally unique ordinals.
+const (
+	// PatientCounterMax starts after the max ordinal in the DB (single runner only; concurrent runners collide).
+	PatientCounterMax = "max"
+	// PatientCounterReserve leases blocks of ordinals from an atomic counter in the database (see CounterReserver).
+	PatientCounterReserve = "reserve"
+	// PatientCounterStatic interleaves ordinals by runner: ordinal ≡ RunnerID (mod RunnerCount). No coordination needed.
+	PatientCounterStatic = "static"
+)
+
+// reserveBlockBatches is how many batches' worth of ordinals one reservation leases.
+const reserveBlockBatches = 64
+
+// CounterReserver is implemented by backends that can hand out disjoint ordinal ranges to concurrent runners.
+type CounterReserver interface {
+	// ReservePatientRange atomically reserves n ordinals and returns the first. The counter is raised to at least
+	// floor first, so ordinals already in the table (written before the counter existed) are never reissued.
+	ReservePatientRange(ctx context.Context, floor, n int) (int, error)
+}
+
+// ValidatePatientCounter checks mode and the runner id/count used by PatientCounterStatic.
+func ValidatePatientCounter(mode string, runnerID, runnerCount int) error {
+	switch mode {
+	case PatientCounterMax, PatientCounterReserve:
+		return nil
+	case PatientCounterStatic:
+		if runnerCount < 1 || runnerID < 0 || runnerID >= runnerCount {
+			return fmt.Errorf("static mode needs 0 <= runner id < runner count (got id %d, count %d)", runnerID, runnerCount)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown patient counter mode %q (want %s, %s or %s)", mode, PatientCounterMax, PatientCounterReserve, PatientCounterStatic)
+}
+
+// PatientAllocator maps a runner's logical patient sequence (batchIndex*batchSize + i) to unique ordinals.
+// Producers share one allocator; duplicates pick logical indexes below the current batch so they hit this runner's own patients.
+type PatientAllocator struct {
+	mode   string
+	start  int // first ordinal (max, static)
+	stride int // static: runner count
+	offset int // static: runner id
+
+	// reserve mode: blocks[i] is the first ordinal of logical block i.
+	reserver  CounterReserver
+	floor     int
+	blockSize int
+	mu        sync.RWMutex
+	blocks    []int
+}
+
+// newPatientAllocator builds the allocator for cfg.PatientCounter. maxInDB is the max ordinal already stored (-1 if none).
+func newPatientAllocator(cfg *Config, wc WorkerCtx, maxInDB int) (*PatientAllocator, error) {
+	next := max(0, maxInDB+1)
+	a := &PatientAllocator{mode: cfg.PatientCounter, start: next, stride: 1}
+	switch cfg.PatientCounter {
+	case PatientCounterStatic:
+		a.stride, a.offset = cfg.RunnerCount, cfg.RunnerID
+		// Align so every runner's residue class starts above maxInDB regardless of when it read the max.
+		a.start = (next + cfg.RunnerCount - 1) / cfg.RunnerCount * cfg.RunnerCount
+	case PatientCounterReserve:
+		cr, ok := wc.(CounterReserver)
+		if !ok {
+			return nil, fmt.Errorf("--patient-counter=%s is not supported by %s", PatientCounterReserve, cfg.Database)
+		}
+		a.reserver, a.floor = cr, next
+		a.blockSize = max(1, cfg.BatchSize) * reserveBlockBatches
+	}
+	return a, nil
+}
+
+// Ordinal returns the patient ordinal for logical index k. It fails only in reserve mode, when a new block cannot
+// be leased from the database.
+func (a *PatientAllocator) Ordinal(k int) (int, error) {
+	if a.mode != PatientCounterReserve {
+		return a.start + k*a.stride + a.offset, nil
+	}
+	block := k / a.blockSize
+	a.mu.RLock()
+	if block < len(a.blocks) {
+		first := a.blocks[block]
+		a.mu.RUnlock()
+		return first + k%a.blockSize, nil
+	}
+	a.mu.RUnlock()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for len(a.blocks) <= block {
+		first, err := a.reserver.ReservePatientRange(context.Background(), a.floor, a.blockSize)
+		if err != nil {
+			return 0, fmt.Errorf("reserve patient range: %w", err)
+		}
+		a.blocks = append(a.blocks, first)
+	}
+	return a.blocks[block] + k%a.blockSize, nil
+}
+
+// Describe summarizes the allocation scheme for the startup log.
+func (a *PatientAllocator) Describe() string {
+	switch a.mode {
+	case PatientCounterStatic:
+		return fmt.Sprintf("static: ordinals ≡ %d (mod %d) starting at %d", a.offset, a.stride, a.start+a.offset)
+	case PatientCounterReserve:
+		return fmt.Sprintf("reserve: blocks of %d ordinals leased from the database counter (floor %d)", a.blockSize, a.floor)
+	}
+	return fmt.Sprintf("max: ordinals starting at %d", a.start)
+}
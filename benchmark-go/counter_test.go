@@ -0,0 +1,162 @@
+package benchmarkgo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fakeReserver is a WorkerCtx whose database counter lives in memory.
+type fakeReserver struct {
+	WorkerCtx // unused; satisfies the interface
+	mu        sync.Mutex
+	next      int
+	calls     int
+	err       error
+}
+
+func (f *fakeReserver) ReservePatientRange(_ context.Context, floor, n int) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return 0, f.err
+	}
+	first := max(f.next, floor)
+	f.next = first + n
+	return first, nil
+}
+
+func TestPatientAllocatorStatic(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		maxInDB []int // what each runner read at startup, by RunnerID
+		first   []int // each runner's ordinal for k = 0
+	}{
+		{name: "empty table", count: 3, maxInDB: []int{-1, -1, -1}, first: []int{0, 1, 2}},
+		{name: "same max", count: 3, maxInDB: []int{9, 9, 9}, first: []int{12, 13, 14}},
+		{name: "max on a boundary", count: 4, maxInDB: []int{7, 7, 7, 7}, first: []int{8, 9, 10, 11}},
+		{name: "later reads see other runners' rows", count: 3, maxInDB: []int{9, 14, 40}, first: []int{12, 16, 44}},
+		{name: "one runner", count: 1, maxInDB: []int{5}, first: []int{6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := make(map[int]int)
+			for id := 0; id < tt.count; id++ {
+				cfg := &Config{PatientCounter: PatientCounterStatic, RunnerID: id, RunnerCount: tt.count}
+				a, err := newPatientAllocator(cfg, nil, tt.maxInDB[id])
+				if err != nil {
+					t.Fatal(err)
+				}
+				for k := 0; k < 1000; k++ {
+					ord, err := a.Ordinal(k)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if k == 0 && ord != tt.first[id] {
+						t.Errorf("runner %d: first ordinal %d, want %d", id, ord, tt.first[id])
+					}
+					if ord <= tt.maxInDB[id] {
+						t.Errorf("runner %d: ordinal %d is not above the max it read (%d)", id, ord, tt.maxInDB[id])
+					}
+					if prev, ok := owner[ord]; ok {
+						t.Fatalf("ordinal %d issued to runners %d and %d", ord, prev, id)
+					}
+					owner[ord] = id
+				}
+			}
+		})
+	}
+}
+
+func TestPatientAllocatorReserve(t *testing.T) {
+	const batchSize = 10
+	block := batchSize * reserveBlockBatches
+	tests := []struct {
+		name    string
+		maxInDB []int // what each runner read at startup
+		ks      []int // logical indexes each runner asks for, in turn
+		leases  int   // ReservePatientRange calls
+		first   int   // lowest ordinal issued
+	}{
+		{name: "one runner, one block", maxInDB: []int{-1}, ks: []int{0, 1, block - 1}, leases: 1, first: 0},
+		{name: "block boundary", maxInDB: []int{-1}, ks: []int{block - 1, block, block + 1}, leases: 2, first: block - 1},
+		{name: "skipped blocks are leased", maxInDB: []int{99}, ks: []int{3*block + 5}, leases: 4, first: 100 + 3*block + 5},
+		{name: "two runners interleaved", maxInDB: []int{-1, -1}, ks: []int{0, block - 1, block, 2 * block}, leases: 6, first: 0},
+		{name: "later read raises the floor", maxInDB: []int{4, 5000}, ks: []int{0, block}, leases: 4, first: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeReserver{}
+			runners := make([]*PatientAllocator, len(tt.maxInDB)) // built on first use, after earlier runners leased
+			owner := make(map[int]int)
+			lowest := -1
+			for _, k := range tt.ks {
+				for id := range runners {
+					if runners[id] == nil {
+						cfg := &Config{Database: "fake", PatientCounter: PatientCounterReserve, BatchSize: batchSize}
+						a, err := newPatientAllocator(cfg, db, tt.maxInDB[id])
+						if err != nil {
+							t.Fatal(err)
+						}
+						runners[id] = a
+					}
+					ord, err := runners[id].Ordinal(k)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if ord <= tt.maxInDB[id] {
+						t.Errorf("runner %d, k %d: ordinal %d is not above the max it read (%d)", id, k, ord, tt.maxInDB[id])
+					}
+					if again, _ := runners[id].Ordinal(k); again != ord {
+						t.Errorf("runner %d, k %d: ordinal %d then %d", id, k, ord, again)
+					}
+					if prev, ok := owner[ord]; ok && prev != id {
+						t.Fatalf("ordinal %d issued to runners %d and %d", ord, prev, id)
+					}
+					owner[ord] = id
+					if lowest < 0 || ord < lowest {
+						lowest = ord
+					}
+				}
+			}
+			if db.calls != tt.leases {
+				t.Errorf("%d reservations, want %d", db.calls, tt.leases)
+			}
+			if lowest != tt.first {
+				t.Errorf("lowest ordinal %d, want %d", lowest, tt.first)
+			}
+		})
+	}
+}
+
+func TestPatientAllocatorReserveErrors(t *testing.T) {
+	cfg := &Config{Database: "fake", PatientCounter: PatientCounterReserve, BatchSize: 10}
+	if _, err := newPatientAllocator(cfg, struct{ WorkerCtx }{}, -1); err == nil {
+		t.Error("reserve mode on a backend without CounterReserver: no error")
+	}
+	db := &fakeReserver{err: errors.New("connection refused")}
+	a, err := newPatientAllocator(cfg, db, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Ordinal(0); !errors.Is(err, db.err) {
+		t.Errorf("Ordinal with a failing reserver: error = %v, want it to wrap %v", err, db.err)
+	}
+}
+
+func TestPatientAllocatorMax(t *testing.T) {
+	for _, maxInDB := range []int{-1, 0, 41} {
+		a, err := newPatientAllocator(&Config{PatientCounter: PatientCounterMax}, nil, maxInDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range []int{0, 1, 500} {
+			if ord, _ := a.Ordinal(k); ord != max(0, maxInDB+1)+k {
+				t.Errorf("max %d, k %d: ordinal %d, want %d", maxInDB, k, ord, max(0, maxInDB+1)+k)
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package benchmarkgo
+
+import "errors"
+
+var errAffinityUnsupported = errors.New("CPU affinity is not supported on this OS")
+
+// SetCPUAffinity is not supported outside Linux.
+func SetCPUAffinity(cpus []int) error {
+	return errAffinityUnsupported
+}
+
+// CPUAffinity is not supported outside Linux.
+func CPUAffinity() ([]int, error) {
+	return nil, errAffinityUnsupported
+}
+
+// processCPUSeconds is not measured outside Linux.
+func processCPUSeconds() float64 {
+	return 0
+}
+
+// processRSSBytes is not measured outside Linux.
+func processRSSBytes() int64 {
+	return 0
+}
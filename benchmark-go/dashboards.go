@@ -0,0 +1,140 @@
+package benchmarkgo
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Grafana dashboard JSON (the subset we emit). Datasource is the ${datasource} template variable so the
+// dashboards import into any Grafana with a Prometheus datasource.
+type grafanaDashboard struct {
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Time          map[string]string `json:"time"`
+	Refresh       string            `json:"refresh"`
+	Templating    grafanaTemplating `json:"templating"`
+	Panels        []grafanaPanel    `json:"panels"`
+}
+
+type grafanaTemplating struct {
+	List []map[string]interface{} `json:"list"`
+}
+
+type grafanaPanel struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	Datasource  map[string]string      `json:"datasource"`
+	GridPos     map[string]int         `json:"gridPos"`
+	FieldConfig map[string]interface{} `json:"fieldConfig"`
+	Targets     []grafanaTarget        `json:"targets"`
+}
+
+type grafanaTarget struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+}
+
+var promDatasource = map[string]string{"type": "prometheus", "uid": "${datasource}"}
+
+// metricExpr is the PromQL for a metric's panel: per-second rate for counters, the value for gauges, summed by its labels.
+func metricExpr(m Metric) string {
+	by := ""
+	if len(m.Labels) > 0 {
+		by = " by (" + strings.Join(m.Labels, ", ") + ") "
+	}
+	if m.Kind == MetricCounter {
+		return "sum" + by + "(rate(" + m.Name + "[$__rate_interval]))"
+	}
+	return "sum" + by + "(" + m.Name + ")"
+}
+
+// legendFormat renders the series legend from the metric's labels.
+func legendFormat(m Metric) string {
+	if len(m.Labels) == 0 {
+		return strings.TrimPrefix(m.Name, "loadrunner_")
+	}
+	parts := make([]string, len(m.Labels))
+	for i, l := range m.Labels {
+		parts[i] = "{{" + l + "}}"
+	}
+	return strings.Join(parts, " ")
+}
+
+func newPanel(id int, title, desc, unit string, targets ...grafanaTarget) grafanaPanel {
+	const width, height = 12, 8
+	return grafanaPanel{
+		ID:          id,
+		Type:        "timeseries",
+		Title:       title,
+		Description: desc,
+		Datasource:  promDatasource,
+		GridPos:     map[string]int{"h": height, "w": width, "x": ((id - 1) % 2) * width, "y": ((id - 1) / 2) * height},
+		FieldConfig: map[string]interface{}{"defaults": map[string]string{"unit": unit}, "overrides": []interface{}{}},
+		Targets:     targets,
+	}
+}
+
+// buildDashboard returns the dashboard for one group of Metrics.
+func buildDashboard(group string) grafanaDashboard {
+	d := grafanaDashboard{
+		UID:           "loadrunner-" + strings.ToLower(group),
+		Title:         "Load runner: " + group,
+		Tags:          []string{"loadrunner", "generated"},
+		SchemaVersion: 39,
+		Time:          map[string]string{"from": "now-30m", "to": "now"},
+		Refresh:       "5s",
+		Templating: grafanaTemplating{List: []map[string]interface{}{{
+			"name": "datasource", "type": "datasource", "query": "prometheus", "label": "Datasource",
+		}}},
+	}
+	for _, m := range Metrics {
+		if m.Group != group {
+			continue
+		}
+		title := strings.TrimPrefix(m.Name, "loadrunner_")
+		d.Panels = append(d.Panels, newPanel(len(d.Panels)+1, title, m.Help, m.Unit,
+			grafanaTarget{RefID: "A", Expr: metricExpr(m), LegendFormat: legendFormat(m)}))
+		if m.PerUnitOf != "" {
+			expr := "sum(rate(" + m.Name + "[$__rate_interval])) / sum(rate(" + m.PerUnitOf + "[$__rate_interval]))"
+			d.Panels = append(d.Panels, newPanel(len(d.Panels)+1, title+" per "+strings.TrimPrefix(m.PerUnitOf, "loadrunner_"),
+				"Average: "+m.Name+" / "+m.PerUnitOf+".", m.Unit,
+				grafanaTarget{RefID: "A", Expr: expr, LegendFormat: "avg"}))
+		}
+	}
+	return d
+}
+
+// WriteDashboards writes one Grafana dashboard JSON file per metric group into dir and returns the paths.
+func WriteDashboards(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var groups []string
+	seen := make(map[string]bool)
+	for _, m := range Metrics {
+		if !seen[m.Group] {
+			seen[m.Group] = true
+			groups = append(groups, m.Group)
+		}
+	}
+	var paths []string
+	for _, g := range groups {
+		b, err := json.MarshalIndent(buildDashboard(g), "", "  ")
+		if err != nil {
+			return paths, err
+		}
+		path := filepath.Join(dir, "loadrunner-"+strings.ToLower(g)+".json")
+		if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
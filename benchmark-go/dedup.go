@@ -0,0 +1,118 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Dedup convergence: with Config.DedupWatch, after the run the table's rows are counted with and without FINAL
+// every DedupPollSec until background merges have collapsed every duplicate (or DedupWaitSec passes), then
+// OPTIMIZE ... FINAL is timed. How long a ReplacingMergeTree table serves duplicates to non-FINAL reads is what
+// this measures.
+
+// DedupBackend is implemented by WorkerCtx backends that collapse duplicates in background merges (ClickHouse).
+type DedupBackend interface {
+	RowCounter
+	FinalCounter
+	// OptimizeFinal forces the merge of all parts (OPTIMIZE TABLE ... FINAL) and returns once it is done.
+	OptimizeFinal(ctx context.Context) error
+}
+
+// DedupSample is one convergence poll: rows without and with FINAL.
+type DedupSample struct {
+	Sec       float64 `json:"sec"` // seconds since the run ended
+	Rows      int64   `json:"rows"`
+	RowsFinal int64   `json:"rows_final"`
+}
+
+// DedupReport is how duplicates converged after the run.
+type DedupReport struct {
+	Samples        []DedupSample `json:"samples"`
+	UnmergedAtEnd  int64         `json:"unmerged_at_end"` // rows - rows_final at the first poll
+	ConvergedSec   float64       `json:"converged_sec"`   // seconds until background merges caught up; -1 if not within the wait
+	OptimizeSec    float64       `json:"optimize_sec"`    // OPTIMIZE ... FINAL duration; -1 if it failed
+	UnmergedAfter  int64         `json:"unmerged_after"`  // rows - rows_final after OPTIMIZE
+	RowsAfterMerge int64         `json:"rows_after_merge"`
+	Error          string        `json:"error,omitempty"`
+}
+
+// runDedupWatch polls row counts until they converge or Config.DedupWaitSec passes, then times OPTIMIZE FINAL.
+// Ctrl-C (ctx) ends the wait early; OPTIMIZE still runs so the table is left merged.
+func (r *LoadRunner) runDedupWatch(ctx context.Context) {
+	cfg := &r.Config
+	db, ok := r.WorkerCtx.(DedupBackend)
+	if !ok {
+		log.Printf("Dedup watch: %s backend does not merge duplicates in the background, skipping", cfg.Database)
+		return
+	}
+	rep := &DedupReport{ConvergedSec: -1, OptimizeSec: -1}
+	r.dedup = rep
+	poll := time.Duration(cfg.DedupPollSec * float64(time.Second))
+	log.Printf("Dedup watch: counting rows with and without FINAL every %.0fs for up to %.0fs ...", cfg.DedupPollSec, cfg.DedupWaitSec)
+	t0 := time.Now()
+	for {
+		s, err := dedupSample(ctx, db, time.Since(t0).Seconds())
+		if err != nil {
+			rep.Error = err.Error()
+			return
+		}
+		rep.Samples = append(rep.Samples, s)
+		if len(rep.Samples) == 1 {
+			rep.UnmergedAtEnd = s.Rows - s.RowsFinal
+		}
+		if s.Rows == s.RowsFinal {
+			rep.ConvergedSec = s.Sec
+			break
+		}
+		log.Printf("Dedup watch: %.0fs: %d rows, %d with FINAL (%d unmerged)", s.Sec, s.Rows, s.RowsFinal, s.Rows-s.RowsFinal)
+		if s.Sec+cfg.DedupPollSec > cfg.DedupWaitSec || !sleepCtx(ctx, poll) {
+			break
+		}
+	}
+	// The wait may have ended on Ctrl-C; OPTIMIZE and the last count run regardless.
+	ctx = context.WithoutCancel(ctx)
+	t1 := time.Now()
+	if err := db.OptimizeFinal(ctx); err != nil {
+		rep.Error = err.Error()
+		return
+	}
+	rep.OptimizeSec = time.Since(t1).Seconds()
+	s, err := dedupSample(ctx, db, time.Since(t0).Seconds())
+	if err != nil {
+		rep.Error = err.Error()
+		return
+	}
+	rep.RowsAfterMerge = s.Rows
+	rep.UnmergedAfter = s.Rows - s.RowsFinal
+}
+
+// dedupSample counts rows without and with FINAL.
+func dedupSample(ctx context.Context, db DedupBackend, sec float64) (DedupSample, error) {
+	s := DedupSample{Sec: sec}
+	var err error
+	if s.Rows, err = db.CountRows(ctx); err != nil {
+		return s, err
+	}
+	s.RowsFinal, err = db.CountRowsFinal(ctx)
+	return s, err
+}
+
+// logDedup logs the dedup convergence (only when it ran).
+func logDedup(rep *DedupReport) {
+	if rep == nil {
+		return
+	}
+	if rep.Error != "" {
+		log.Printf("Dedup watch failed: %s", rep.Error)
+		return
+	}
+	log.Printf("Dedup convergence: %d unmerged duplicate rows at run end", rep.UnmergedAtEnd)
+	if rep.ConvergedSec >= 0 {
+		log.Printf("  background merges converged %.1fs after the run", rep.ConvergedSec)
+	} else {
+		last := rep.Samples[len(rep.Samples)-1]
+		log.Printf("  background merges not converged after %.1fs (%d unmerged)", last.Sec, last.Rows-last.RowsFinal)
+	}
+	log.Printf("  OPTIMIZE FINAL: %.2fs, %d rows after, %d unmerged", rep.OptimizeSec, rep.RowsAfterMerge, rep.UnmergedAfter)
+}
@@ -0,0 +1,168 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// Distributed runs: one pod cannot generate enough load for a large cluster, so a coordinator splits a run across
+// agents on several hosts. Each agent gets a share of the target rate, its own patient ordinals (static counter,
+// interleaved by agent index) and a shared wall-clock start, runs it as a normal child run, and sends its Results
+// back; the coordinator merges them into one report and shows the agents side by side.
+
+// AgentAssignment is one agent's part of a distributed run.
+type AgentAssignment struct {
+	Index      int       `json:"index"` // runner ID for the static patient counter
+	Count      int       `json:"count"` // number of agents
+	RowsPerSec int       `json:"rows_per_sec"`
+	Seed       int64     `json:"seed"`
+	StartAt    time.Time `json:"start_at"` // agents sleep until this wall-clock time, so hosts need synced clocks
+	Args       []string  `json:"args"`     // the coordinator's run flags; the assignment's own flags follow them
+}
+
+// AgentRun is an agent's answer to an assignment.
+type AgentRun struct {
+	Results *Results `json:"results,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// SplitRate divides a target rate over n agents, spreading the remainder over the first ones; nil if n < 1.
+func SplitRate(total, n int) []int {
+	if n < 1 {
+		return nil
+	}
+	shares := make([]int, n)
+	for i := range shares {
+		shares[i] = total / n
+		if i < total%n {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// Flags renders the assignment as loadrunner flags, appended after Args so they win.
+func (a AgentAssignment) Flags() []string {
+	return []string{
+		fmt.Sprintf("--rows-per-second=%d", a.RowsPerSec),
+		"--patient-counter=" + PatientCounterStatic,
+		fmt.Sprintf("--runner-id=%d", a.Index),
+		fmt.Sprintf("--runner-count=%d", a.Count),
+		fmt.Sprintf("--seed=%d", a.Seed),
+	}
+}
+
+// MergeResults combines the agents' Results into one: counts, rates and summed latencies add up, the run spans
+// the earliest start to the longest elapsed time, and errors are merged per (op, code). Percentiles cannot be
+// merged from summaries, so each is the worst agent's, an upper bound for the combined distribution. Nil parts
+// are agents that returned no results; Metadata counts them as agents_failed, apart from the agents merged.
+func MergeResults(parts []*Results) *Results {
+	var out *Results
+	errs := make(map[errorKey]*ErrorCount)
+	merged := 0
+	for _, p := range parts {
+		if p == nil {
+			continue
+		}
+		merged++
+		if out == nil {
+			out = &Results{Database: p.Database, StartedAt: p.StartedAt, Metadata: map[string]interface{}{}}
+		}
+		if p.StartedAt.Before(out.StartedAt) {
+			out.StartedAt = p.StartedAt
+		}
+		out.ElapsedSec = max(out.ElapsedSec, p.ElapsedSec)
+		out.WarmupSec = max(out.WarmupSec, p.WarmupSec)
+		out.TargetRPS += p.TargetRPS
+		out.Concurrency += p.Concurrency
+		out.ActualRPS += p.ActualRPS
+		addInserted(&out.Inserted, p.Inserted)
+		out.Queries.Count += p.Queries.Count
+		out.Queries.TotalLatencySec += p.Queries.TotalLatencySec
+		out.Queries.FailedCount += p.Queries.FailedCount
+		out.Queries.Latency = worstPercentiles(out.Queries.Latency, p.Queries.Latency)
+		for _, e := range p.Errors {
+			k := errorKey{e.Op, e.Code}
+			if m := errs[k]; m != nil {
+				m.Count += e.Count
+			} else {
+				e := e
+				errs[k] = &e
+			}
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	for _, e := range errs {
+		out.Errors = append(out.Errors, *e)
+	}
+	sort.Slice(out.Errors, func(i, j int) bool {
+		a, b := out.Errors[i], out.Errors[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Op != b.Op { // ties in a fixed order, not the map's
+			return a.Op < b.Op
+		}
+		return a.Code < b.Code
+	})
+	out.Metadata["agents"] = merged
+	if failed := len(parts) - merged; failed > 0 {
+		out.Metadata["agents_failed"] = failed
+	}
+	return out
+}
+
+// addInserted adds b's insert counters to a.
+func addInserted(a *InsertedStats, b InsertedStats) {
+	a.Total += b.Total
+	a.Attempted += b.Attempted
+	a.Originals += b.Originals
+	a.Duplicates += b.Duplicates
+	a.TotalInsertLatencySec += b.TotalInsertLatencySec
+	a.InsertStatements += b.InsertStatements
+	a.Bytes += b.Bytes
+	a.ServerSec += b.ServerSec
+	a.ServerTimedBatches += b.ServerTimedBatches
+	a.Postgres1 += b.Postgres1
+	a.Postgres2 += b.Postgres2
+	a.Latency = worstPercentiles(a.Latency, b.Latency)
+	a.EndToEnd = worstPercentiles(a.EndToEnd, b.EndToEnd)
+}
+
+// worstPercentiles returns the per-percentile maximum of a and b with their counts summed; nil if both are nil.
+func worstPercentiles(a, b *LatencyPercentiles) *LatencyPercentiles {
+	if a == nil || b == nil {
+		if a == nil {
+			a = b
+		}
+		if a == nil {
+			return nil
+		}
+		c := *a
+		return &c
+	}
+	return &LatencyPercentiles{
+		Count:  a.Count + b.Count,
+		P50Ms:  max(a.P50Ms, b.P50Ms),
+		P90Ms:  max(a.P90Ms, b.P90Ms),
+		P95Ms:  max(a.P95Ms, b.P95Ms),
+		P99Ms:  max(a.P99Ms, b.P99Ms),
+		P999Ms: max(a.P999Ms, b.P999Ms),
+		MaxMs:  max(a.MaxMs, b.MaxMs),
+	}
+}
+
+// LogAgentComparison prints the agents' results and their merge side by side, like LogTargetComparison, followed
+// by the merged totals.
+func LogAgentComparison(names []string, results []*Results, merged *Results) {
+	logComparison("Agent comparison", append(append([]string(nil), names...), "total"), append(append([]*Results(nil), results...), merged))
+	if merged == nil {
+		return
+	}
+	log.Printf("Distributed run: %d rows inserted at %.1f rows/sec (target %d), %d queries, %d failed; percentiles are the worst agent's",
+		int64(merged.Inserted.Total), merged.ActualRPS, merged.TargetRPS, int64(merged.Queries.Count), int64(merged.Queries.FailedCount))
+}
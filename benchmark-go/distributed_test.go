@@ -0,0 +1,120 @@
+package benchmarkgo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitRate(t *testing.T) {
+	tests := []struct {
+		total, n int
+		want     []int
+	}{
+		{total: 9000, n: 3, want: []int{3000, 3000, 3000}},
+		{total: 10, n: 3, want: []int{4, 3, 3}},
+		{total: 11, n: 3, want: []int{4, 4, 3}},
+		{total: 3, n: 3, want: []int{1, 1, 1}},
+		{total: 2, n: 3, want: []int{1, 1, 0}},
+		{total: 0, n: 2, want: []int{0, 0}},
+		{total: 7, n: 1, want: []int{7}},
+		{total: 7, n: 0, want: nil},
+		{total: 7, n: -1, want: nil},
+	}
+	for _, tt := range tests {
+		got := SplitRate(tt.total, tt.n)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitRate(%d, %d) = %v, want %v", tt.total, tt.n, got, tt.want)
+		}
+		sum := 0
+		for _, s := range got {
+			sum += s
+		}
+		if tt.n > 0 && sum != tt.total {
+			t.Errorf("SplitRate(%d, %d) shares add up to %d", tt.total, tt.n, sum)
+		}
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := &Results{
+		Database: "postgres", StartedAt: t0.Add(time.Second), ElapsedSec: 60, TargetRPS: 500, ActualRPS: 490,
+		Inserted: InsertedStats{Total: 29400, Originals: 22000, Duplicates: 7400, TotalInsertLatencySec: 12,
+			Latency: &LatencyPercentiles{Count: 100, P50Ms: 5, P99Ms: 40, MaxMs: 90}},
+		Queries: QueryStats{Count: 1000, TotalLatencySec: 2, FailedCount: 3},
+		Errors:  []ErrorCount{{Op: "insert", Code: "timeout", Count: 2}, {Op: "query", Code: "57014", Count: 3}},
+	}
+	b := &Results{
+		Database: "postgres", StartedAt: t0, ElapsedSec: 61, TargetRPS: 500, ActualRPS: 480,
+		Inserted: InsertedStats{Total: 28800, Originals: 21600, Duplicates: 7200, TotalInsertLatencySec: 14,
+			Latency: &LatencyPercentiles{Count: 120, P50Ms: 7, P99Ms: 30, MaxMs: 120}},
+		Queries: QueryStats{Count: 900, TotalLatencySec: 3},
+		Errors:  []ErrorCount{{Op: "insert", Code: "timeout", Count: 4}, {Op: "insert", Code: "40001", Count: 3}},
+	}
+
+	tests := []struct {
+		name  string
+		parts []*Results
+		check func(t *testing.T, got *Results)
+	}{
+		{name: "no parts", parts: nil, check: func(t *testing.T, got *Results) {
+			if got != nil {
+				t.Errorf("got %+v, want nil", got)
+			}
+		}},
+		{name: "only failed agents", parts: []*Results{nil, nil}, check: func(t *testing.T, got *Results) {
+			if got != nil {
+				t.Errorf("got %+v, want nil", got)
+			}
+		}},
+		{name: "one agent", parts: []*Results{a}, check: func(t *testing.T, got *Results) {
+			if got.Inserted.Total != a.Inserted.Total || got.TargetRPS != a.TargetRPS || len(got.Errors) != 2 {
+				t.Errorf("got %+v, want the agent's own totals", got)
+			}
+			if got.Inserted.Latency == a.Inserted.Latency {
+				t.Errorf("merged latency aliases the agent's")
+			}
+			if _, ok := got.Metadata["agents_failed"]; got.Metadata["agents"] != 1 || ok {
+				t.Errorf("metadata = %v, want 1 agent and no agents_failed", got.Metadata)
+			}
+		}},
+		{name: "two agents and a failed one", parts: []*Results{a, nil, b}, check: func(t *testing.T, got *Results) {
+			if !got.StartedAt.Equal(t0) || got.ElapsedSec != 61 {
+				t.Errorf("span = %s + %gs, want %s + 61s", got.StartedAt, got.ElapsedSec, t0)
+			}
+			if got.TargetRPS != 1000 || got.ActualRPS != 970 {
+				t.Errorf("rates = %d target, %g actual; want 1000, 970", got.TargetRPS, got.ActualRPS)
+			}
+			if got.Inserted.Total != 58200 || got.Inserted.Originals != 43600 || got.Inserted.TotalInsertLatencySec != 26 {
+				t.Errorf("inserted = %+v", got.Inserted)
+			}
+			if got.Queries.Count != 1900 || got.Queries.TotalLatencySec != 5 || got.Queries.FailedCount != 3 {
+				t.Errorf("queries = %+v", got.Queries)
+			}
+			wantLat := &LatencyPercentiles{Count: 220, P50Ms: 7, P99Ms: 40, MaxMs: 120}
+			if !reflect.DeepEqual(got.Inserted.Latency, wantLat) {
+				t.Errorf("insert latency = %+v, want the worst of each percentile %+v", got.Inserted.Latency, wantLat)
+			}
+			wantErrs := []ErrorCount{
+				{Op: "insert", Code: "timeout", Count: 6},
+				{Op: "insert", Code: "40001", Count: 3},
+				{Op: "query", Code: "57014", Count: 3},
+			}
+			if !reflect.DeepEqual(got.Errors, wantErrs) {
+				t.Errorf("errors = %+v, want %+v", got.Errors, wantErrs)
+			}
+			if got.Metadata["agents"] != 2 || got.Metadata["agents_failed"] != 1 {
+				t.Errorf("agents = %v, agents_failed = %v; want 2 and 1", got.Metadata["agents"], got.Metadata["agents_failed"])
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, MergeResults(tt.parts))
+		})
+	}
+	if a.Errors[0].Count != 2 || b.Errors[0].Count != 4 {
+		t.Errorf("MergeResults changed its inputs' error counts")
+	}
+}
@@ -0,0 +1,244 @@
+package druid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+)
+
+const (
+	// taskPollInterval is how often a submitted ingestion task's status is checked.
+	taskPollInterval = 250 * time.Millisecond
+	// taskTimeout bounds how long one batch waits for its task to finish (tasks queue when task slots are full).
+	taskTimeout = 10 * time.Minute
+)
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// APIError is a non-2xx response from the router: the HTTP status plus Druid's error code and message.
+type APIError struct {
+	Status       int
+	Code         string `json:"errorCode"`
+	Kind         string `json:"error"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("druid: HTTP %d %s: %s", e.Status, e.code(), e.ErrorMessage)
+}
+
+func (e *APIError) code() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	if e.Kind != "" {
+		return e.Kind
+	}
+	return fmt.Sprintf("http_%d", e.Status)
+}
+
+// TaskError is an ingestion task that ended in FAILED.
+type TaskError struct {
+	TaskID string
+	Msg    string
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("druid task %s failed: %s", e.TaskID, e.Msg)
+}
+
+// classifyError maps API errors to "druid:<errorCode>" and failed tasks to "druid:task_failed".
+func classifyError(err error) (string, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return "druid:" + apiErr.code(), true
+	}
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) {
+		return "druid:task_failed", true
+	}
+	return "", false
+}
+
+// Client talks to the Druid router, which proxies both the Overlord task API and the Broker SQL endpoint.
+type Client struct {
+	URL      string // e.g. http://druid-router:8888
+	User     string // optional basic auth
+	Password string
+	HTTP     *http.Client
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, out interface{}) (int64, error) {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+path, rd)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Password)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return int64(len(b)), err
+	}
+	if resp.StatusCode/100 != 2 {
+		apiErr := &APIError{Status: resp.StatusCode}
+		if json.Unmarshal(b, apiErr) != nil {
+			apiErr.ErrorMessage = strings.TrimSpace(string(b))
+		}
+		return int64(len(b)), apiErr
+	}
+	if out != nil {
+		return int64(len(b)), json.Unmarshal(b, out)
+	}
+	return int64(len(b)), nil
+}
+
+// sqlParam is a Druid SQL dynamic parameter.
+type sqlParam struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// SQL runs query with positional ? parameters and returns the rows as arrays plus the response size in bytes.
+func (c *Client) SQL(ctx context.Context, query string, args ...interface{}) ([][]interface{}, int64, error) {
+	params := make([]sqlParam, len(args))
+	for i, a := range args {
+		switch a.(type) {
+		case int, int64:
+			params[i] = sqlParam{Type: "BIGINT", Value: a}
+		default:
+			params[i] = sqlParam{Type: "VARCHAR", Value: a}
+		}
+	}
+	var rows [][]interface{}
+	n, err := c.do(ctx, http.MethodPost, "/druid/v2/sql", map[string]interface{}{
+		"query":        query,
+		"parameters":   params,
+		"resultFormat": "array",
+	}, &rows)
+	return rows, n, err
+}
+
+// ingestionSpec is a native batch (index_parallel) task appending rows from an inline NDJSON source.
+// created_at becomes __time; every other column is a string dimension and rollup is off, so each row is kept.
+func ingestionSpec(data string) map[string]interface{} {
+	dims := make([]string, 0, len(sqldb.Columns))
+	for _, c := range sqldb.Columns {
+		if c != "created_at" {
+			dims = append(dims, c)
+		}
+	}
+	return map[string]interface{}{
+		"type": "index_parallel",
+		"spec": map[string]interface{}{
+			"dataSchema": map[string]interface{}{
+				"dataSource":      benchmarkgo.Table(),
+				"timestampSpec":   map[string]interface{}{"column": "created_at", "format": "iso"},
+				"dimensionsSpec":  map[string]interface{}{"dimensions": dims},
+				"granularitySpec": map[string]interface{}{"segmentGranularity": "hour", "queryGranularity": "none", "rollup": false},
+			},
+			"ioConfig": map[string]interface{}{
+				"type":             "index_parallel",
+				"inputSource":      map[string]interface{}{"type": "inline", "data": data},
+				"inputFormat":      map[string]interface{}{"type": "json"},
+				"appendToExisting": true,
+			},
+			"tuningConfig": map[string]interface{}{"type": "index_parallel", "maxNumConcurrentSubTasks": 1},
+		},
+		// Concurrent append locks let every insert worker's task write the same hour interval at once.
+		"context": map[string]interface{}{"useConcurrentLocks": true},
+	}
+}
+
+// encodeRows renders rows as NDJSON with created_at/updated_at as ISO-8601 timestamps.
+func encodeRows(rows []benchmarkgo.RowForDB) (string, error) {
+	now := time.Now().UTC()
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	for _, r := range rows {
+		vals, err := sqldb.RowFromJSON(r.JSONMessage, now)
+		if err != nil {
+			return "", err
+		}
+		obj := make(map[string]interface{}, len(sqldb.Columns))
+		for i, c := range sqldb.Columns {
+			if t, ok := vals[i].(time.Time); ok {
+				obj[c] = t.Format(time.RFC3339Nano)
+			} else if vals[i] != nil {
+				obj[c] = vals[i]
+			}
+		}
+		if err := enc.Encode(obj); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// Ingest submits one append task for rows and waits until it succeeds or fails, so insert latency covers
+// the rows becoming queryable (segments published), not just the task being accepted. Returns the task's
+// run duration as reported by the Overlord.
+func (c *Client) Ingest(ctx context.Context, rows []benchmarkgo.RowForDB) (time.Duration, error) {
+	data, err := encodeRows(rows)
+	if err != nil {
+		return 0, err
+	}
+	var submitted struct {
+		Task string `json:"task"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/druid/indexer/v1/task", ingestionSpec(data), &submitted); err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, taskTimeout)
+	defer cancel()
+	for {
+		var st struct {
+			Status struct {
+				Status   string `json:"status"`
+				ErrorMsg string `json:"errorMsg"`
+				Duration int64  `json:"duration"` // milliseconds
+			} `json:"status"`
+		}
+		if _, err := c.do(ctx, http.MethodGet, "/druid/indexer/v1/task/"+submitted.Task+"/status", nil, &st); err != nil {
+			return 0, err
+		}
+		switch st.Status.Status {
+		case "SUCCESS":
+			return time.Duration(st.Status.Duration) * time.Millisecond, nil
+		case "FAILED":
+			return 0, &TaskError{TaskID: submitted.Task, Msg: st.Status.ErrorMsg}
+		}
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("druid task %s: %w", submitted.Task, ctx.Err())
+		case <-time.After(taskPollInterval):
+		}
+	}
+}
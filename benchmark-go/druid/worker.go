@@ -0,0 +1,174 @@
+// Package druid runs the benchmark against Apache Druid: each insert batch is a native batch append task with an
+// inline source (no Kafka needed), and query workers use the SQL endpoint, so Druid can be compared with ClickHouse
+// on the same HL7 dataset.
+package druid
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+const defaultRouterURL = "http://druid-router:8888"
+
+// Backend implements benchmarkgo.InsertBackend; the HTTP client pools connections, so the client is the conn.
+type Backend struct {
+	client *Client
+}
+
+// GetConn returns the shared client.
+func (b *Backend) GetConn() interface{} {
+	return b.client
+}
+
+// ReleaseConn is a no-op; the HTTP client manages its pool.
+func (b *Backend) ReleaseConn(c interface{}) {}
+
+// InsertBatch ingests rows as one append task (one statement), reporting the task duration as server time.
+// Druid has no upsert: a duplicate MRN is appended as another row.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	client, ok := conn.(*Client)
+	if !ok || len(rows) == 0 {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // pgbouncer routing hint; unused for Druid
+	taskTime, err := client.Ingest(context.Background(), rows)
+	if err != nil {
+		return benchmarkgo.InsertResult{Statements: 1}, err
+	}
+	return benchmarkgo.InsertResult{Rows: len(rows), Statements: 1, ServerTime: taskTime}, nil
+}
+
+// Context handles setup/teardown and query workers for Druid.
+type Context struct {
+	client *Client
+}
+
+// Setup builds the router client from DRUID_ROUTER_URL (and optional DRUID_USER/DRUID_PASSWORD). The datasource
+// is created by the first ingestion task, so there is no schema step.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if c.client != nil {
+		log.Fatal("druid Setup already called")
+	}
+	url := os.Getenv("DRUID_ROUTER_URL")
+	if url == "" {
+		url = defaultRouterURL
+	}
+	conns := max(1, numWorkers) + max(1, numWorkers*queriesPerRecord)
+	c.client = &Client{
+		URL:      strings.TrimRight(url, "/"),
+		User:     os.Getenv("DRUID_USER"),
+		Password: os.Getenv("DRUID_PASSWORD"),
+		HTTP: &http.Client{
+			Timeout:   taskTimeout,
+			Transport: &http.Transport{MaxIdleConnsPerHost: conns},
+		},
+	}
+	if _, _, err := c.client.SQL(context.Background(), "SELECT 1"); err != nil {
+		c.client = nil
+		return nil, err
+	}
+	log.Printf("Using Druid router at %s; datasource %s (one append task per batch)", url, benchmarkgo.Table())
+	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
+	return &Backend{client: c.client}, nil
+}
+
+// Teardown closes idle HTTP connections.
+func (c *Context) Teardown() {
+	if c.client != nil {
+		c.client.HTTP.CloseIdleConnections()
+		c.client = nil
+	}
+}
+
+// GetMaxPatientCounter returns the max patient ordinal in the datasource, or -1 if it does not exist yet.
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	rows, _, err := c.client.SQL(context.Background(),
+		`SELECT MAX(CAST(REPLACE(patient_id, 'patient-', '') AS BIGINT)) FROM "`+benchmarkgo.Table()+`" WHERE patient_id LIKE 'patient-%'`)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorMessage, "not found") {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return intAt(rows, -1), nil
+}
+
+// intAt returns the first column of the first row as an int, or def when absent or null.
+func intAt(rows [][]interface{}, def int) int {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return def
+	}
+	switch v := rows[0][0].(type) {
+	case float64:
+		return int(v)
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// querier binds the shared client to benchmarkgo.Querier.
+type querier struct {
+	client *Client
+}
+
+// QueryByPrimaryKey counts distinct rows keyed by mrn (appended duplicates of one MRN collapse to one).
+func (q querier) QueryByPrimaryKey(ctx context.Context, mrn string) (int, error) {
+	rows, _, err := q.client.SQL(ctx, `SELECT COUNT(*) FROM (SELECT medical_record_number FROM "`+benchmarkgo.Table()+
+		`" WHERE medical_record_number = ? GROUP BY medical_record_number)`, mrn)
+	return intAt(rows, 0), err
+}
+
+// QueryResultSet reads up to limit rows at or below mrn. Druid scan queries can only order by __time, so the rows
+// are not sorted by MRN.
+func (q querier) QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error) {
+	rows, n, err := q.client.SQL(ctx, `SELECT * FROM "`+benchmarkgo.Table()+`" WHERE medical_record_number <= ? LIMIT ?`, mrn, limit)
+	return len(rows), n, err
+}
+
+func (q querier) QueryByPatientID(ctx context.Context, patientID string) (int, error) {
+	rows, _, err := q.client.SQL(ctx, `SELECT * FROM "`+benchmarkgo.Table()+`" WHERE patient_id = ?`, patientID)
+	return len(rows), err
+}
+
+func (q querier) AggregateByPatientID(ctx context.Context, patientID string) (int, error) {
+	rows, _, err := q.client.SQL(ctx, `SELECT COUNT(*), MAX(updated_at), SUM(CHAR_LENGTH(source)) FROM "`+
+		benchmarkgo.Table()+`" WHERE patient_id = ?`, patientID)
+	return intAt(rows, 0), err
+}
+
+// RunQueryWorker consumes from queryQueue, runs the configured queries per MRN via Druid SQL, reports via benchmarkgo.AddQuery.
+// workerIndex is the 0-based index of this query worker.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex // reserved for logging/tracing
+	runner := benchmarkgo.NewQueryRunner(opts)
+	q := querier{c.client}
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
+			if time.Now().Before(deadline) {
+				time.Sleep(time.Until(deadline))
+			}
+		}
+		count, failed, latency := runner.Run(context.Background(), q, job)
+		benchmarkgo.AddQuery(int64(count), latency.Microseconds(), int64(failed))
+	}
+}
@@ -0,0 +1,188 @@
+package benchmarkgo
+
+import (
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Dual-write runs write every batch to a primary and a secondary database (e.g. Postgres then ClickHouse, as
+// the production dual-write does) and poll both for each record until it is visible. The per-database
+// read-after-write lag and its divergence between the two show how far the secondary trails the primary.
+
+// maxDualWriteSamples bounds the reservoirs of lag samples kept for percentiles.
+const maxDualWriteSamples = 10000
+
+// DualWriteSide is one database's share of a dual-write run.
+type DualWriteSide struct {
+	Name           string  `json:"name"`
+	InsertBatches  int64   `json:"insert_batches"`
+	InsertFailures int64   `json:"insert_failures"`
+	InsertAvgMs    float64 `json:"insert_avg_ms"` // per successful batch
+	Visible        int64   `json:"visible"`       // records found before the visibility timeout
+	Missing        int64   `json:"missing"`       // records still not found at the timeout
+	LagP50Ms       float64 `json:"lag_p50_ms"`    // insert acknowledgment → first successful read
+	LagP99Ms       float64 `json:"lag_p99_ms"`
+	LagMaxMs       float64 `json:"lag_max_ms"`
+}
+
+// DualWriteReport compares read-after-write visibility of the two databases of a dual-write run.
+type DualWriteReport struct {
+	Primary   DualWriteSide `json:"primary"`
+	Secondary DualWriteSide `json:"secondary"`
+	// Divergence is |secondary lag - primary lag| over records visible in both.
+	Both            int64   `json:"both"`
+	DivergenceP50Ms float64 `json:"divergence_p50_ms"`
+	DivergenceP99Ms float64 `json:"divergence_p99_ms"`
+	DivergenceMaxMs float64 `json:"divergence_max_ms"`
+	SecondaryLater  float64 `json:"secondary_later_share"` // share of records the secondary exposed after the primary
+}
+
+// dualWriteSide accumulates one database's samples; guarded by dualWriteMu.
+type dualWriteSide struct {
+	name     string
+	batches  int64
+	failures int64
+	insert   time.Duration
+	visible  int64
+	missing  int64
+	lags     []time.Duration
+	maxLag   time.Duration
+}
+
+var (
+	dualWriteMu     sync.Mutex
+	dualWriteOn     bool
+	dualWriteSides  [2]dualWriteSide
+	dualWriteBoth   int64
+	dualWriteLater  int64
+	dualWriteDivs   []time.Duration
+	dualWriteMaxDiv time.Duration
+)
+
+// SetDualWriteTargets enables the dual-write report with the names of the primary and secondary databases.
+func SetDualWriteTargets(primary, secondary string) {
+	dualWriteMu.Lock()
+	dualWriteOn = true
+	dualWriteSides[0].name = primary
+	dualWriteSides[1].name = secondary
+	dualWriteMu.Unlock()
+}
+
+// AddDualWriteInsert records one batch written to side (0 = primary, 1 = secondary).
+func AddDualWriteInsert(side int, d time.Duration, err error) {
+	dualWriteMu.Lock()
+	s := &dualWriteSides[side]
+	s.batches++
+	if err != nil {
+		s.failures++
+	} else {
+		s.insert += d
+	}
+	dualWriteMu.Unlock()
+}
+
+// AddDualWriteVisibility records one record's read-after-write lag on each side; ok is false for a side
+// that did not return the record before the visibility timeout.
+func AddDualWriteVisibility(lag [2]time.Duration, ok [2]bool) {
+	dualWriteMu.Lock()
+	defer dualWriteMu.Unlock()
+	for i := range dualWriteSides {
+		s := &dualWriteSides[i]
+		if !ok[i] {
+			s.missing++
+			continue
+		}
+		s.visible++
+		s.maxLag = max(s.maxLag, lag[i])
+		s.lags = addReservoir(s.lags, lag[i], s.visible)
+	}
+	if !ok[0] || !ok[1] {
+		return
+	}
+	dualWriteBoth++
+	div := lag[1] - lag[0]
+	if div > 0 {
+		dualWriteLater++
+	} else {
+		div = -div
+	}
+	dualWriteMaxDiv = max(dualWriteMaxDiv, div)
+	dualWriteDivs = addReservoir(dualWriteDivs, div, dualWriteBoth)
+}
+
+// addReservoir keeps a uniform sample of at most maxDualWriteSamples of the n values seen so far.
+func addReservoir(samples []time.Duration, d time.Duration, n int64) []time.Duration {
+	if len(samples) < maxDualWriteSamples {
+		return append(samples, d)
+	}
+	if i := rand.Int63n(n); i < maxDualWriteSamples {
+		samples[i] = d
+	}
+	return samples
+}
+
+// durationPercentiles returns the p50 and p99 of ds in ms (sorts ds).
+func durationPercentiles(ds []time.Duration) (p50, p99 float64) {
+	if len(ds) == 0 {
+		return 0, 0
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	pct := func(p float64) float64 {
+		return float64(ds[int(p*float64(len(ds)-1))].Microseconds()) / 1000
+	}
+	return pct(0.50), pct(0.99)
+}
+
+// loadDualWrite summarizes the dual-write samples; nil unless a dual-write backend ran.
+func loadDualWrite() *DualWriteReport {
+	dualWriteMu.Lock()
+	defer dualWriteMu.Unlock()
+	if !dualWriteOn {
+		return nil
+	}
+	side := func(s *dualWriteSide) DualWriteSide {
+		out := DualWriteSide{
+			Name:           s.name,
+			InsertBatches:  s.batches,
+			InsertFailures: s.failures,
+			Visible:        s.visible,
+			Missing:        s.missing,
+			LagMaxMs:       float64(s.maxLag.Microseconds()) / 1000,
+		}
+		if ok := s.batches - s.failures; ok > 0 {
+			out.InsertAvgMs = float64(s.insert.Microseconds()) / 1000 / float64(ok)
+		}
+		out.LagP50Ms, out.LagP99Ms = durationPercentiles(append([]time.Duration(nil), s.lags...))
+		return out
+	}
+	rep := &DualWriteReport{
+		Primary:         side(&dualWriteSides[0]),
+		Secondary:       side(&dualWriteSides[1]),
+		Both:            dualWriteBoth,
+		DivergenceMaxMs: float64(dualWriteMaxDiv.Microseconds()) / 1000,
+	}
+	rep.DivergenceP50Ms, rep.DivergenceP99Ms = durationPercentiles(append([]time.Duration(nil), dualWriteDivs...))
+	if dualWriteBoth > 0 {
+		rep.SecondaryLater = float64(dualWriteLater) / float64(dualWriteBoth)
+	}
+	return rep
+}
+
+// logDualWrite logs per-database insert and visibility stats and their lag divergence (only for dual-write runs).
+func logDualWrite(rep *DualWriteReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Dual-write:")
+	for _, s := range []DualWriteSide{rep.Primary, rep.Secondary} {
+		log.Printf("  %-12s inserts: %d batches, %d failed, avg %.2f ms | read-after-write: %d visible, %d missing | lag p50 %.2f ms, p99 %.2f ms, max %.2f ms",
+			s.Name, s.InsertBatches, s.InsertFailures, s.InsertAvgMs, s.Visible, s.Missing, s.LagP50Ms, s.LagP99Ms, s.LagMaxMs)
+	}
+	if rep.Both > 0 {
+		log.Printf("  Lag divergence over %d records visible in both: p50 %.2f ms, p99 %.2f ms, max %.2f ms; %s exposed %.0f%% of them later than %s",
+			rep.Both, rep.DivergenceP50Ms, rep.DivergenceP99Ms, rep.DivergenceMaxMs, rep.Secondary.Name, rep.SecondaryLater*100, rep.Primary.Name)
+	}
+}
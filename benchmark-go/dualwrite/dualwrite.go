@@ -0,0 +1,186 @@
+// Package dualwrite writes every batch to a primary and a secondary database, mirroring an application-level
+// dual write, and measures how long each takes to expose a written record to readers.
+package dualwrite
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// pollInterval is the pause between visibility checks of a record not yet readable on both sides.
+const pollInterval = 5 * time.Millisecond
+
+// DefaultVisibilityTimeout is how long query workers poll for a record before counting it missing.
+const DefaultVisibilityTimeout = 10 * time.Second
+
+// Reader is implemented by backend Contexts that can look a record up by MRN outside the query workers.
+type Reader interface {
+	CountMRN(ctx context.Context, mrn string) (int, error)
+}
+
+// Target is one side of the dual write: a backend Context that also implements Reader.
+type Target struct {
+	Name string
+	Ctx  benchmarkgo.WorkerCtx
+}
+
+// conns is a pair of connections checked out together for one batch.
+type conns [2]interface{}
+
+// Backend writes each batch to the primary, then the secondary (implements benchmarkgo.InsertBackend).
+type Backend struct {
+	backends [2]benchmarkgo.InsertBackend
+	names    [2]string
+}
+
+// GetConn checks out a connection from each side.
+func (b *Backend) GetConn() interface{} {
+	return conns{b.backends[0].GetConn(), b.backends[1].GetConn()}
+}
+
+// ReleaseConn returns both connections.
+func (b *Backend) ReleaseConn(c interface{}) {
+	if cs, ok := c.(conns); ok {
+		b.backends[0].ReleaseConn(cs[0])
+		b.backends[1].ReleaseConn(cs[1])
+	}
+}
+
+// InsertBatch writes rows to the primary and, if that succeeded, to the secondary, like an application that
+// commits to its system of record first. Rows are the primary's, so throughput counts each record once.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	cs, ok := conn.(conns)
+	if !ok {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	var res benchmarkgo.InsertResult
+	for i, be := range b.backends {
+		t0 := time.Now()
+		r, err := be.InsertBatch(cs[i], rows, queryHint)
+		benchmarkgo.AddDualWriteInsert(i, time.Since(t0), err)
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", b.names[i], err)
+		}
+		if i == 0 {
+			res.Rows = r.Rows
+		}
+		res.Statements += r.Statements
+		res.Bytes += r.Bytes
+		res.ServerTime += r.ServerTime
+		res.Warnings = append(res.Warnings, r.Warnings...)
+	}
+	return res, nil
+}
+
+// Context sets up both sides and runs the read-after-write checks in the query workers.
+type Context struct {
+	Primary           Target
+	Secondary         Target
+	VisibilityTimeout time.Duration // 0 = DefaultVisibilityTimeout
+}
+
+func (c *Context) targets() [2]Target {
+	return [2]Target{c.Primary, c.Secondary}
+}
+
+// Setup sets up the primary, then the secondary; both must implement Reader.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	b := &Backend{}
+	for i, t := range c.targets() {
+		if _, ok := t.Ctx.(Reader); !ok {
+			return nil, fmt.Errorf("dual-write: %s cannot look up records by MRN", t.Name)
+		}
+		be, err := t.Ctx.Setup(numWorkers, targetRPS, queriesPerRecord)
+		if err != nil {
+			if i == 1 {
+				c.Primary.Ctx.Teardown()
+			}
+			return nil, fmt.Errorf("%s: %w", t.Name, err)
+		}
+		b.backends[i] = be
+		b.names[i] = t.Name
+	}
+	if c.VisibilityTimeout <= 0 {
+		c.VisibilityTimeout = DefaultVisibilityTimeout
+	}
+	benchmarkgo.SetDualWriteTargets(c.Primary.Name, c.Secondary.Name)
+	log.Printf("Dual-write: %s (primary) then %s (secondary); query workers poll both for each record (timeout %s)",
+		c.Primary.Name, c.Secondary.Name, c.VisibilityTimeout)
+	return b, nil
+}
+
+// Teardown tears down both sides.
+func (c *Context) Teardown() {
+	c.Secondary.Ctx.Teardown()
+	c.Primary.Ctx.Teardown()
+}
+
+// GetMaxPatientCounter returns the higher of the two sides' max ordinals, so new ordinals are new on both.
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	best := -1
+	for _, t := range c.targets() {
+		n, err := t.Ctx.GetMaxPatientCounter()
+		if err != nil {
+			return -1, fmt.Errorf("%s: %w", t.Name, err)
+		}
+		best = max(best, n)
+	}
+	return best, nil
+}
+
+// RunQueryWorker polls both sides for each job's MRN until it is readable on both or the visibility timeout
+// passes, then records each side's read-after-write lag. Every lookup is reported via benchmarkgo.AddQuery.
+// The configured query type and queries per record do not apply: each record gets one visibility check.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex // reserved for logging/tracing
+	readers := [2]Reader{c.Primary.Ctx.(Reader), c.Secondary.Ctx.(Reader)}
+	ctx := context.Background()
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
+			if time.Now().Before(deadline) {
+				time.Sleep(time.Until(deadline))
+			}
+		}
+		var lag [2]time.Duration
+		var visible [2]bool
+		deadline := job.InsertTime.Add(c.VisibilityTimeout)
+		for {
+			for i, rd := range readers {
+				if visible[i] {
+					continue
+				}
+				t0 := time.Now()
+				n, err := rd.CountMRN(ctx, job.MRN)
+				failed := int64(0)
+				if err != nil {
+					benchmarkgo.AddError(benchmarkgo.ErrOpQuery, err)
+					failed = 1
+				}
+				d := time.Since(t0)
+				benchmarkgo.AddQuery(1, d.Microseconds(), failed)
+				benchmarkgo.AddQueryLatency(d)
+				if n >= 1 {
+					visible[i] = true
+					lag[i] = time.Since(job.InsertTime)
+				}
+			}
+			if (visible[0] && visible[1]) || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(pollInterval)
+		}
+		benchmarkgo.AddDualWriteVisibility(lag, visible)
+	}
+}
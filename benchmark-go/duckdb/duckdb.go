@@ -0,0 +1,44 @@
+//go:build duckdb
+
+// Package duckdb runs the benchmark against an embedded DuckDB database file: the same workload with no server
+// and no network, as a local baseline. Build with -tags duckdb (cgo; the driver bundles prebuilt DuckDB
+// libraries).
+package duckdb
+
+import (
+	"os"
+
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+	_ "github.com/duckdb/duckdb-go/v2"
+)
+
+const defaultPath = "benchmark.duckdb"
+
+// Dialect is DuckDB's SQL flavour. There is no patient_id index: DuckDB rejects ON CONFLICT DO UPDATE that assigns
+// an indexed column, and its zone maps make the patient_id scan cheap enough for a baseline.
+var Dialect = &sqldb.Dialect{
+	Name:        "duckdb",
+	Driver:      "duckdb",
+	Placeholder: sqldb.QuestionPlaceholder,
+	Schema: func(table string) []string {
+		return []string{
+			"CREATE TABLE IF NOT EXISTS " + table + " (" + sqldb.ColumnsDDL("VARCHAR", "TIMESTAMPTZ", "VARCHAR") +
+				", PRIMARY KEY (medical_record_number))",
+		}
+	},
+	Upsert:         sqldb.OnConflictUpsert,
+	PatientOrdinal: "TRY_CAST(substr(patient_id, 9) AS BIGINT)",
+	PayloadLength:  "strlen",
+}
+
+// NewContext returns a worker context for the database file at DUCKDB_PATH (default benchmark.duckdb; ":memory:" for RAM only).
+func NewContext() *sqldb.Context {
+	path := os.Getenv("DUCKDB_PATH")
+	if path == "" {
+		path = defaultPath
+	}
+	if path == ":memory:" {
+		path = ""
+	}
+	return &sqldb.Context{Dialect: Dialect, DSN: path}
+}
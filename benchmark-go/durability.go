@@ -0,0 +1,27 @@
+package benchmarkgo
+
+import "fmt"
+
+// Durability levels selectable via Config.Durability. Names follow Postgres synchronous_commit; each backend maps
+// them to its own acknowledgment setting (ClickHouse: insert_quorum) so durability-vs-throughput curves line up across engines.
+const (
+	DurabilityDefault     = ""             // backend's built-in behavior (Postgres off, ClickHouse insert_quorum=2)
+	DurabilityOff         = "off"          // ack before local flush / single replica
+	DurabilityLocal       = "local"        // ack after local durable write, no replica wait
+	DurabilityOn          = "on"           // ack after replicas confirm the write
+	DurabilityRemoteApply = "remote_apply" // ack after replicas have applied and will serve the write
+)
+
+// ValidateDurability returns an error if level is not a known durability level.
+func ValidateDurability(level string) error {
+	switch level {
+	case DurabilityDefault, DurabilityOff, DurabilityLocal, DurabilityOn, DurabilityRemoteApply:
+		return nil
+	}
+	return fmt.Errorf("unknown durability level %q (want off, local, on, or remote_apply)", level)
+}
+
+// DurabilityReporter is implemented by WorkerCtx backends that can describe the native setting their durability level maps to.
+type DurabilityReporter interface {
+	DurabilitySetting() string
+}
@@ -0,0 +1,246 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dutyWarmWindow is the start of each active phase whose latency and cache hit ratio are reported separately
+// from the rest of the phase, to show cold-start cost after idling.
+const dutyWarmWindow = 30 * time.Second
+
+// CacheReporter is implemented by WorkerCtx backends that expose cumulative cache hit/miss counters
+// (Postgres shared buffers, ClickHouse mark and uncompressed caches).
+type CacheReporter interface {
+	CacheCounters(ctx context.Context) (hits, misses int64, err error)
+}
+
+// DutyCycleOptions alternates active and idle phases: OnSec of load, then IdleSec with no inserts or queries,
+// repeated until the run ends.
+type DutyCycleOptions struct {
+	OnSec   float64
+	IdleSec float64
+}
+
+// Enabled reports whether a duty cycle is configured.
+func (o DutyCycleOptions) Enabled() bool {
+	return o.OnSec > 0 && o.IdleSec > 0
+}
+
+// DutyPhase is one active phase. Phase 0 starts cold from setup; later phases follow an idle period.
+type DutyPhase struct {
+	Index          int      `json:"index"`
+	StartSec       float64  `json:"start_sec"`
+	FirstInsertMs  float64  `json:"first_insert_ms"` // -1 if no insert completed in the phase
+	FirstQueryMs   float64  `json:"first_query_ms"`  // -1 if no query completed in the phase
+	WarmQueryMs    float64  `json:"warm_query_ms"`   // avg per query in the first dutyWarmWindow
+	SteadyQueryMs  float64  `json:"steady_query_ms"` // avg per query in the rest of the phase
+	WarmInsertMs   float64  `json:"warm_insert_ms"`  // avg per row in the first dutyWarmWindow
+	SteadyInsertMs float64  `json:"steady_insert_ms"`
+	WarmCacheHit   *float64 `json:"warm_cache_hit_ratio,omitempty"`
+	SteadyCacheHit *float64 `json:"steady_cache_hit_ratio,omitempty"`
+}
+
+// DutyCycleReport lists the active phases of a duty-cycle run.
+type DutyCycleReport struct {
+	OnSec   float64     `json:"on_sec"`
+	IdleSec float64     `json:"idle_sec"`
+	Phases  []DutyPhase `json:"phases"`
+}
+
+// dutyGate blocks the router while the run is idle.
+type dutyGate struct {
+	mu   sync.Mutex
+	open chan struct{} // closed while active
+}
+
+func newDutyGate() *dutyGate {
+	g := &dutyGate{open: make(chan struct{})}
+	close(g.open)
+	return g
+}
+
+// wait returns once the gate is open, or ctx's error.
+func (g *dutyGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	open := g.open
+	g.mu.Unlock()
+	select {
+	case <-open:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *dutyGate) pause() {
+	g.mu.Lock()
+	g.open = make(chan struct{})
+	g.mu.Unlock()
+}
+
+func (g *dutyGate) resume() {
+	g.mu.Lock()
+	close(g.open)
+	g.mu.Unlock()
+}
+
+// paused reports whether the gate is holding batches back (an idle phase).
+func (g *dutyGate) paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	select {
+	case <-g.open:
+		return false
+	default:
+		return true
+	}
+}
+
+// First insert/query after a phase starts: armed at the start, stored (micros) by the first AddInsert/AddQuery.
+var (
+	dutyArmInsert, dutyArmQuery     atomic.Bool
+	dutyFirstInsert, dutyFirstQuery atomic.Int64
+)
+
+// noteDutyInsert records the first insert flush of an active phase.
+func noteDutyInsert(latencyMicros int64) {
+	if dutyArmInsert.CompareAndSwap(true, false) {
+		dutyFirstInsert.Store(latencyMicros)
+	}
+}
+
+// noteDutyQuery records the first query of an active phase (per-query latency of the first job).
+func noteDutyQuery(count, latencyMicros int64) {
+	if count > 0 && dutyArmQuery.CompareAndSwap(true, false) {
+		dutyFirstQuery.Store(latencyMicros / count)
+	}
+}
+
+// dutyCounters is a point-in-time read of the counters a phase is measured from.
+type dutyCounters struct {
+	rows, insertMicros     int64
+	queries, queryMicros   int64
+	cacheHits, cacheMisses int64
+	cacheOK                bool
+}
+
+func (r *LoadRunner) readDutyCounters(ctx context.Context) dutyCounters {
+	c := dutyCounters{
+		rows: insertTotal.Load(), insertMicros: insertLatencyMicros.Load(),
+		queries: queryCount.Load(), queryMicros: queryLatencyMicros.Load(),
+	}
+	if cr, ok := r.WorkerCtx.(CacheReporter); ok {
+		var err error
+		if c.cacheHits, c.cacheMisses, err = cr.CacheCounters(ctx); err == nil {
+			c.cacheOK = true
+		} else if ctx.Err() == nil {
+			log.Printf("Duty cycle: cache counters: %v", err)
+		}
+	}
+	return c
+}
+
+// avgMs returns the average latency in ms per unit between two counter reads, or 0.
+func avgMs(micros, n int64) float64 {
+	if n <= 0 {
+		return 0
+	}
+	return float64(micros) / float64(n) / 1000
+}
+
+// hitRatio returns the cache hit ratio between a and b, or nil when either read failed or nothing was read.
+func hitRatio(a, b dutyCounters) *float64 {
+	if !a.cacheOK || !b.cacheOK {
+		return nil
+	}
+	hits, misses := b.cacheHits-a.cacheHits, b.cacheMisses-a.cacheMisses
+	if hits+misses <= 0 {
+		return nil
+	}
+	ratio := float64(hits) / float64(hits+misses)
+	return &ratio
+}
+
+// runDutyCycle alternates active and idle phases until ctx ends, measuring each active phase.
+func (r *LoadRunner) runDutyCycle(ctx context.Context) {
+	opts := r.Config.DutyCycle
+	on := time.Duration(opts.OnSec * float64(time.Second))
+	idle := time.Duration(opts.IdleSec * float64(time.Second))
+	warm := min(dutyWarmWindow, on)
+	sleep := func(d time.Duration) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(d):
+			return true
+		}
+	}
+	for i := 0; ; i++ {
+		phase := DutyPhase{Index: i, StartSec: time.Since(r.runStart).Seconds(), FirstInsertMs: -1, FirstQueryMs: -1}
+		dutyFirstInsert.Store(-1)
+		dutyFirstQuery.Store(-1)
+		dutyArmInsert.Store(true)
+		dutyArmQuery.Store(true)
+		start := r.readDutyCounters(ctx)
+		done := !sleep(warm)
+		mid := r.readDutyCounters(ctx)
+		if !done {
+			done = !sleep(on - warm)
+		}
+		end := r.readDutyCounters(context.Background())
+		dutyArmInsert.Store(false)
+		dutyArmQuery.Store(false)
+		if v := dutyFirstInsert.Load(); v >= 0 {
+			phase.FirstInsertMs = float64(v) / 1000
+		}
+		if v := dutyFirstQuery.Load(); v >= 0 {
+			phase.FirstQueryMs = float64(v) / 1000
+		}
+		phase.WarmQueryMs = avgMs(mid.queryMicros-start.queryMicros, mid.queries-start.queries)
+		phase.SteadyQueryMs = avgMs(end.queryMicros-mid.queryMicros, end.queries-mid.queries)
+		phase.WarmInsertMs = avgMs(mid.insertMicros-start.insertMicros, mid.rows-start.rows)
+		phase.SteadyInsertMs = avgMs(end.insertMicros-mid.insertMicros, end.rows-mid.rows)
+		phase.WarmCacheHit = hitRatio(start, mid)
+		phase.SteadyCacheHit = hitRatio(mid, end)
+		r.duty.Phases = append(r.duty.Phases, phase)
+		if done {
+			return
+		}
+		r.dutyGate.pause()
+		r.events.add(r.runStart, "duty", fmt.Sprintf("idle for %.0fs after active phase %d", opts.IdleSec, i))
+		ok := sleep(idle)
+		r.dutyGate.resume()
+		if !ok {
+			return
+		}
+		r.events.add(r.runStart, "duty", fmt.Sprintf("active phase %d for %.0fs", i+1, opts.OnSec))
+	}
+}
+
+// logDutyCycle logs each active phase's cold-start and steady-state latency (only when a duty cycle ran).
+func logDutyCycle(rep *DutyCycleReport) {
+	if rep == nil || len(rep.Phases) == 0 {
+		return
+	}
+	log.Printf("Duty cycle (%.0fs on / %.0fs idle), first %.0fs of each phase vs the rest:", rep.OnSec, rep.IdleSec, dutyWarmWindow.Seconds())
+	ratio := func(p *float64) string {
+		if p == nil {
+			return "n/a"
+		}
+		return fmt.Sprintf("%.1f%%", *p*100)
+	}
+	for _, p := range rep.Phases {
+		label := "after idle"
+		if p.Index == 0 {
+			label = "from setup"
+		}
+		log.Printf("  phase %d at %.0fs (%s): first insert %.2f ms, first query %.2f ms | query avg %.2f → %.2f ms | insert avg %.3f → %.3f ms/row | cache hit %s → %s",
+			p.Index, p.StartSec, label, p.FirstInsertMs, p.FirstQueryMs, p.WarmQueryMs, p.SteadyQueryMs,
+			p.WarmInsertMs, p.SteadyInsertMs, ratio(p.WarmCacheHit), ratio(p.SteadyCacheHit))
+	}
+}
@@ -0,0 +1,314 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ddb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+)
+
+const (
+	keyAttr       = "medical_record_number"
+	patientAttr   = "patient_id"
+	patientIndex  = "patient_id-index"
+	batchWriteMax = 25  // BatchWriteItem item limit
+	batchGetMax   = 100 // BatchGetItem key limit
+	maxRetries    = 10
+	// sourceChunkBytes splits SOURCE (~2 MiB) across chunk items under the 400 KB item limit.
+	sourceChunkBytes = 350 << 10
+	// maxCounterKey is the item holding the highest patient ordinal written, so reruns continue after existing data.
+	maxCounterKey = "__max_patient_counter"
+	mrnPrefix     = "MRN-"
+	patientPrefix = "patient-"
+)
+
+// consumedWCU / consumedRCU total the capacity units DynamoDB reported as consumed (ReturnConsumedCapacity).
+var consumedWCU, consumedRCU atomic.Uint64
+
+// addConsumed accumulates consumed capacity in hundredths of a unit.
+func addConsumed(counter *atomic.Uint64, cc []types.ConsumedCapacity) {
+	for _, c := range cc {
+		if c.CapacityUnits != nil {
+			counter.Add(uint64(*c.CapacityUnits * 100))
+		}
+	}
+}
+
+func retryBackoff(attempt int) time.Duration {
+	d := 25 * time.Millisecond << min(attempt, 6)
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// chunkKey is the partition key of SOURCE chunk i of mrn.
+func chunkKey(mrn string, i int) string {
+	return mrn + "#source#" + strconv.Itoa(i)
+}
+
+// buildItems maps one row to its head item (all columns except SOURCE, plus source_chunks) and the SOURCE chunk items.
+func buildItems(jsonMsg string, now time.Time) (string, []map[string]types.AttributeValue, error) {
+	vals, err := sqldb.RowFromJSON(jsonMsg, now)
+	if err != nil {
+		return "", nil, err
+	}
+	head := make(map[string]types.AttributeValue, len(sqldb.Columns))
+	var mrn, source string
+	for i, c := range sqldb.Columns {
+		var s string
+		switch v := vals[i].(type) {
+		case nil:
+			continue
+		case time.Time:
+			s = v.Format(time.RFC3339Nano)
+		case string:
+			s = v
+		default:
+			s = fmt.Sprint(v)
+		}
+		switch c {
+		case "source":
+			source = s
+			continue
+		case keyAttr:
+			mrn = s
+		}
+		if s != "" {
+			head[c] = &types.AttributeValueMemberS{Value: s}
+		}
+	}
+	if mrn == "" {
+		return "", nil, errors.New("dynamodb: row without medical_record_number")
+	}
+	items := []map[string]types.AttributeValue{head}
+	chunks := 0
+	for off := 0; off < len(source); off += sourceChunkBytes {
+		end := min(off+sourceChunkBytes, len(source))
+		items = append(items, map[string]types.AttributeValue{
+			keyAttr: &types.AttributeValueMemberS{Value: chunkKey(mrn, chunks)},
+			"data":  &types.AttributeValueMemberS{Value: source[off:end]},
+		})
+		chunks++
+	}
+	head["source_chunks"] = &types.AttributeValueMemberN{Value: strconv.Itoa(chunks)}
+	return mrn, items, nil
+}
+
+// batchWrite sends reqs (≤25) and resubmits unprocessed items with backoff, counting each retry as
+// benchmarkgo.ErrOpInsertRetry. Returns the number of BatchWriteItem calls.
+func batchWrite(ctx context.Context, client *ddb.Client, reqs []types.WriteRequest) (int, error) {
+	table := benchmarkgo.Table()
+	calls := 0
+	for attempt := 0; ; attempt++ {
+		calls++
+		out, err := client.BatchWriteItem(ctx, &ddb.BatchWriteItemInput{
+			RequestItems:           map[string][]types.WriteRequest{table: reqs},
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+		if err == nil {
+			addConsumed(&consumedWCU, out.ConsumedCapacity)
+			reqs = out.UnprocessedItems[table]
+			if len(reqs) == 0 {
+				return calls, nil
+			}
+			err = errUnprocessed
+		}
+		if !isThrottle(err) || attempt >= maxRetries {
+			return calls, err
+		}
+		benchmarkgo.AddError(benchmarkgo.ErrOpInsertRetry, err)
+		time.Sleep(retryBackoff(attempt))
+	}
+}
+
+// InsertBatch writes rows (head + SOURCE chunk items each) with BatchWriteItem in 25-item chunks; rewriting an
+// MRN overwrites its items, so duplicates are upserts. Returns (rowsInserted, batchWriteCalls, error).
+func InsertBatch(ctx context.Context, client *ddb.Client, rows []benchmarkgo.RowForDB) (int, int, error) {
+	now := time.Now().UTC()
+	var reqs []types.WriteRequest
+	maxOrdinal := -1
+	for _, r := range rows {
+		_, items, err := buildItems(r.JSONMessage, now)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, it := range items {
+			reqs = append(reqs, types.WriteRequest{PutRequest: &types.PutRequest{Item: it}})
+		}
+		if o := ordinal(r.PatientID, patientPrefix); o > maxOrdinal {
+			maxOrdinal = o
+		}
+	}
+	calls := 0
+	for off := 0; off < len(reqs); off += batchWriteMax {
+		n, err := batchWrite(ctx, client, reqs[off:min(off+batchWriteMax, len(reqs))])
+		calls += n
+		if err != nil {
+			return 0, calls, err
+		}
+	}
+	if maxOrdinal >= 0 {
+		if err := raiseMaxCounter(ctx, client, maxOrdinal); err != nil {
+			return len(rows), calls, err
+		}
+	}
+	return len(rows), calls, nil
+}
+
+// raiseMaxCounter sets the max-counter item to n unless it already holds a higher ordinal.
+func raiseMaxCounter(ctx context.Context, client *ddb.Client, n int) error {
+	_, err := client.UpdateItem(ctx, &ddb.UpdateItemInput{
+		TableName:                 aws.String(benchmarkgo.Table()),
+		Key:                       map[string]types.AttributeValue{keyAttr: &types.AttributeValueMemberS{Value: maxCounterKey}},
+		UpdateExpression:          aws.String("SET v = :n"),
+		ConditionExpression:       aws.String("attribute_not_exists(v) OR v < :n"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":n": &types.AttributeValueMemberN{Value: strconv.Itoa(n)}},
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return nil
+	}
+	return err
+}
+
+// GetMaxPatientCounter returns the highest patient ordinal written, or -1.
+func GetMaxPatientCounter(ctx context.Context, client *ddb.Client) (int, error) {
+	out, err := client.GetItem(ctx, &ddb.GetItemInput{
+		TableName:      aws.String(benchmarkgo.Table()),
+		Key:            map[string]types.AttributeValue{keyAttr: &types.AttributeValueMemberS{Value: maxCounterKey}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return -1, err
+	}
+	v, ok := out.Item["v"].(*types.AttributeValueMemberN)
+	if !ok {
+		return -1, nil
+	}
+	return strconv.Atoi(v.Value)
+}
+
+// ordinal parses the numeric suffix of "MRN-NNNNNNNNNN" / "patient-NNNNNNNNNN"; -1 if malformed.
+func ordinal(id, prefix string) int {
+	s, ok := strings.CutPrefix(id, prefix)
+	if !ok {
+		return -1
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// QueryByPrimaryKey is a GetItem on the MRN (eventually consistent, the DynamoDB default).
+func QueryByPrimaryKey(ctx context.Context, client *ddb.Client, mrn string) (int, error) {
+	out, err := client.GetItem(ctx, &ddb.GetItemInput{
+		TableName:              aws.String(benchmarkgo.Table()),
+		Key:                    map[string]types.AttributeValue{keyAttr: &types.AttributeValueMemberS{Value: mrn}},
+		ProjectionExpression:   aws.String(keyAttr),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if out.ConsumedCapacity != nil {
+		addConsumed(&consumedRCU, []types.ConsumedCapacity{*out.ConsumedCapacity})
+	}
+	if out.Item == nil {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// QueryResultSet emulates an MRN range scan with BatchGetItem of the limit MRNs ending at mrn (descending
+// ordinals), head items only. Bytes are the summed string attribute lengths.
+func QueryResultSet(ctx context.Context, client *ddb.Client, mrn string, limit int) (int, int64, error) {
+	end := ordinal(mrn, mrnPrefix)
+	if end < 0 {
+		return 0, 0, fmt.Errorf("dynamodb: malformed MRN %q", mrn)
+	}
+	table := benchmarkgo.Table()
+	var keys []map[string]types.AttributeValue
+	for o := end; o >= 0 && len(keys) < limit; o-- {
+		keys = append(keys, map[string]types.AttributeValue{keyAttr: &types.AttributeValueMemberS{Value: mrnPrefix + fmt.Sprintf("%010d", o)}})
+	}
+	var n int
+	var bytes int64
+	for off := 0; off < len(keys); off += batchGetMax {
+		pending := keys[off:min(off+batchGetMax, len(keys))]
+		for attempt := 0; len(pending) > 0; attempt++ {
+			out, err := client.BatchGetItem(ctx, &ddb.BatchGetItemInput{
+				RequestItems:           map[string]types.KeysAndAttributes{table: {Keys: pending}},
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			})
+			if err != nil {
+				return n, bytes, err
+			}
+			addConsumed(&consumedRCU, out.ConsumedCapacity)
+			for _, it := range out.Responses[table] {
+				n++
+				for _, v := range it {
+					if s, ok := v.(*types.AttributeValueMemberS); ok {
+						bytes += int64(len(s.Value))
+					}
+				}
+			}
+			pending = out.UnprocessedKeys[table].Keys
+			if len(pending) > 0 {
+				if attempt >= maxRetries {
+					return n, bytes, errUnprocessed
+				}
+				time.Sleep(retryBackoff(attempt))
+			}
+		}
+	}
+	return n, bytes, nil
+}
+
+// queryPatient runs a Query on the patient_id GSI (keys-only projection) with the given Select.
+func queryPatient(ctx context.Context, client *ddb.Client, patientID string, sel types.Select) (int, error) {
+	var count int
+	var start map[string]types.AttributeValue
+	for {
+		out, err := client.Query(ctx, &ddb.QueryInput{
+			TableName:                 aws.String(benchmarkgo.Table()),
+			IndexName:                 aws.String(patientIndex),
+			KeyConditionExpression:    aws.String("patient_id = :p"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":p": &types.AttributeValueMemberS{Value: patientID}},
+			Select:                    sel,
+			ExclusiveStartKey:         start,
+			ReturnConsumedCapacity:    types.ReturnConsumedCapacityTotal,
+		})
+		if err != nil {
+			return count, err
+		}
+		if out.ConsumedCapacity != nil {
+			addConsumed(&consumedRCU, []types.ConsumedCapacity{*out.ConsumedCapacity})
+		}
+		count += int(out.Count)
+		if len(out.LastEvaluatedKey) == 0 {
+			return count, nil
+		}
+		start = out.LastEvaluatedKey
+	}
+}
+
+// QueryByPatientID fetches the patient's rows through the patient_id GSI.
+func QueryByPatientID(ctx context.Context, client *ddb.Client, patientID string) (int, error) {
+	return queryPatient(ctx, client, patientID, types.SelectAllProjectedAttributes)
+}
+
+// AggregateByPatientID counts the patient's rows through the patient_id GSI (Select COUNT); DynamoDB has no
+// server-side max/sum, so only the count is computed.
+func AggregateByPatientID(ctx context.Context, client *ddb.Client, patientID string) (int, error) {
+	return queryPatient(ctx, client, patientID, types.SelectCount)
+}
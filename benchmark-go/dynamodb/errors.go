@@ -0,0 +1,44 @@
+package dynamodb
+
+import (
+	"errors"
+
+	"github.com/aws/smithy-go"
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// errUnprocessed marks a BatchWriteItem/BatchGetItem that left items unprocessed (throttled partitions); it is
+// counted as a retry like an explicit throttling error.
+var errUnprocessed = errors.New("dynamodb: unprocessed items returned (partition throttled)")
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// classifyError maps API errors to "dynamodb:<ErrorCode>", e.g. dynamodb:ProvisionedThroughputExceededException.
+func classifyError(err error) (string, bool) {
+	if errors.Is(err, errUnprocessed) {
+		return "dynamodb:UnprocessedItems", true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return "dynamodb:" + apiErr.ErrorCode(), true
+	}
+	return "", false
+}
+
+// isThrottle reports errors worth retrying after a backoff: throttling by capacity or request rate.
+func isThrottle(err error) bool {
+	if errors.Is(err, errUnprocessed) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ProvisionedThroughputExceededException", "ThrottlingException", "RequestLimitExceeded", "InternalServerError":
+		return true
+	}
+	return false
+}
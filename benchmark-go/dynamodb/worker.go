@@ -0,0 +1,248 @@
+// Package dynamodb runs the benchmark against Amazon DynamoDB (or DynamoDB Local): BatchWriteItem in 25-item
+// chunks for inserts, GetItem on the MRN for lookups, with throttling retries counted in the error stats.
+// Records exceed the 400 KB item limit, so SOURCE is split across chunk items next to each head item.
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	ddb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// Billing modes for --dynamodb-billing.
+const (
+	BillingOnDemand    = "on_demand"
+	BillingProvisioned = "provisioned"
+)
+
+// ValidateBilling checks a --dynamodb-billing value.
+func ValidateBilling(mode string) error {
+	switch mode {
+	case BillingOnDemand, BillingProvisioned:
+		return nil
+	}
+	return fmt.Errorf("unknown billing mode %q (want %s or %s)", mode, BillingOnDemand, BillingProvisioned)
+}
+
+// Options selects the capacity mode used when the table is created.
+type Options struct {
+	Billing       string
+	ReadCapacity  int64 // RCU for the table and the patient_id GSI (provisioned only)
+	WriteCapacity int64 // WCU for the table and the patient_id GSI (provisioned only)
+}
+
+// Backend implements benchmarkgo.InsertBackend; the SDK client pools HTTP connections, so the client is the conn.
+type Backend struct {
+	client *ddb.Client
+}
+
+// GetConn returns the shared client.
+func (b *Backend) GetConn() interface{} {
+	return b.client
+}
+
+// ReleaseConn is a no-op; the SDK manages its pool.
+func (b *Backend) ReleaseConn(c interface{}) {}
+
+// InsertBatch writes rows via BatchWriteItem; Statements counts BatchWriteItem calls, retries included.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	client, ok := conn.(*ddb.Client)
+	if !ok {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // pgbouncer routing hint; unused for DynamoDB
+	n, calls, err := InsertBatch(context.Background(), client, rows)
+	return benchmarkgo.InsertResult{Rows: n, Statements: calls}, err
+}
+
+// Context handles setup/teardown and query workers for DynamoDB.
+type Context struct {
+	Options Options
+	client  *ddb.Client
+	start   time.Time
+}
+
+// Setup builds the client (AWS_REGION and credentials from the default chain; DYNAMODB_ENDPOINT for DynamoDB
+// Local), creates the table if missing and warns when the target rate exceeds provisioned write capacity.
+// The SDK's own retryer is disabled so every throttle is retried, and counted, by the backend.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if c.client != nil {
+		log.Fatal("dynamodb Setup already called")
+	}
+	ctx := context.Background()
+	conns := max(1, numWorkers) * 2
+	httpClient := awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+		t.MaxIdleConnsPerHost = conns
+	})
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRetryer(func() aws.Retryer { return aws.NopRetryer{} }),
+		config.WithHTTPClient(httpClient),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	c.client = ddb.NewFromConfig(cfg, func(o *ddb.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+	where := "region " + cfg.Region
+	if endpoint != "" {
+		where = endpoint
+	}
+	log.Printf("Using DynamoDB at %s, table %s (%s)", where, benchmarkgo.Table(), c.Options.Billing)
+	if err := c.createTable(ctx); err != nil {
+		c.client = nil
+		return nil, err
+	}
+	c.logCapacityDemand(targetRPS)
+	c.start = time.Now()
+	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
+	return &Backend{client: c.client}, nil
+}
+
+// createTable creates the table keyed by MRN with a keys-only patient_id GSI, and waits until it is ACTIVE.
+func (c *Context) createTable(ctx context.Context) error {
+	table := benchmarkgo.Table()
+	in := &ddb.CreateTableInput{
+		TableName: aws.String(table),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(keyAttr), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String(patientAttr), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String(keyAttr), KeyType: types.KeyTypeHash}},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{{
+			IndexName:  aws.String(patientIndex),
+			KeySchema:  []types.KeySchemaElement{{AttributeName: aws.String(patientAttr), KeyType: types.KeyTypeHash}},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+		}},
+		BillingMode: types.BillingModePayPerRequest,
+	}
+	if c.Options.Billing == BillingProvisioned {
+		tp := &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(c.Options.ReadCapacity),
+			WriteCapacityUnits: aws.Int64(c.Options.WriteCapacity),
+		}
+		in.BillingMode = types.BillingModeProvisioned
+		in.ProvisionedThroughput = tp
+		in.GlobalSecondaryIndexes[0].ProvisionedThroughput = tp
+	}
+	_, err := c.client.CreateTable(ctx, in)
+	var inUse *types.ResourceInUseException
+	if err != nil && !errors.As(err, &inUse) {
+		return err
+	}
+	if err == nil {
+		log.Printf("Creating table %s ...", table)
+	}
+	return ddb.NewTableExistsWaiter(c.client).Wait(ctx, &ddb.DescribeTableInput{TableName: aws.String(table)}, 5*time.Minute)
+}
+
+// logCapacityDemand estimates write units per record (1 WCU per KB of each item) and compares the target rate's
+// demand with provisioned capacity.
+func (c *Context) logCapacityDemand(targetRPS int) {
+	jsonMsg, err := benchmarkgo.GenerateOnePatient(0, true).ToJSON()
+	if err != nil {
+		return
+	}
+	_, items, err := buildItems(jsonMsg, time.Now())
+	if err != nil {
+		return
+	}
+	var wcu int64
+	for _, it := range items {
+		var size int64
+		for k, v := range it {
+			size += int64(len(k))
+			if s, ok := v.(*types.AttributeValueMemberS); ok {
+				size += int64(len(s.Value))
+			}
+		}
+		wcu += (size + 1023) / 1024
+	}
+	demand := wcu * int64(targetRPS)
+	log.Printf("DynamoDB write demand: ~%d WCU per record (%d items) x %d rows/sec = ~%d WCU/sec", wcu, len(items), targetRPS, demand)
+	if c.Options.Billing == BillingProvisioned && demand > c.Options.WriteCapacity {
+		log.Printf("WARNING: target write demand ~%d WCU/sec exceeds provisioned %d WCU; expect throttling (counted as insert_retry errors)",
+			demand, c.Options.WriteCapacity)
+	}
+}
+
+// Teardown logs consumed capacity over the run.
+func (c *Context) Teardown() {
+	if c.client == nil {
+		return
+	}
+	elapsed := time.Since(c.start).Seconds()
+	wcu, rcu := float64(consumedWCU.Load())/100, float64(consumedRCU.Load())/100
+	if elapsed > 0 {
+		log.Printf("DynamoDB consumed capacity: %.0f WCU (%.1f/sec), %.0f RCU (%.1f/sec)", wcu, wcu/elapsed, rcu, rcu/elapsed)
+	}
+	c.client = nil
+}
+
+// GetMaxPatientCounter returns the max patient ordinal written, or -1.
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	return GetMaxPatientCounter(context.Background(), c.client)
+}
+
+// querier binds the shared client to benchmarkgo.Querier.
+type querier struct {
+	client *ddb.Client
+}
+
+func (q querier) QueryByPrimaryKey(ctx context.Context, mrn string) (int, error) {
+	return QueryByPrimaryKey(ctx, q.client, mrn)
+}
+
+func (q querier) QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error) {
+	return QueryResultSet(ctx, q.client, mrn, limit)
+}
+
+func (q querier) QueryByPatientID(ctx context.Context, patientID string) (int, error) {
+	return QueryByPatientID(ctx, q.client, patientID)
+}
+
+func (q querier) AggregateByPatientID(ctx context.Context, patientID string) (int, error) {
+	return AggregateByPatientID(ctx, q.client, patientID)
+}
+
+// RunQueryWorker consumes from queryQueue, runs the configured queries per MRN, reports via benchmarkgo.AddQuery.
+// workerIndex is the 0-based index of this query worker.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex // reserved for logging/tracing
+	runner := benchmarkgo.NewQueryRunner(opts)
+	q := querier{c.client}
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
+			if time.Now().Before(deadline) {
+				time.Sleep(time.Until(deadline))
+			}
+		}
+		count, failed, latency := runner.Run(context.Background(), q, job)
+		benchmarkgo.AddQuery(int64(count), latency.Microseconds(), int64(failed))
+	}
+}
@@ -0,0 +1,123 @@
+package benchmarkgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// Error operations passed to AddError.
+const (
+	ErrOpInsert      = "insert"
+	ErrOpInsertRetry = "insert_retry" // transient insert error that was retried (not a failed batch)
+	ErrOpQuery       = "query"
+	ErrOpConflict    = "conflict" // conflicting-writer scenario transaction error
+)
+
+// ErrorClassifier maps a backend error to its native code (e.g. "postgres:40P01", "clickhouse:252").
+// ok is false when the classifier does not recognize the error.
+type ErrorClassifier func(err error) (code string, ok bool)
+
+var (
+	classifierMu sync.RWMutex
+	classifiers  []ErrorClassifier
+)
+
+// RegisterErrorClassifier adds a backend classifier (backends call this from init).
+func RegisterErrorClassifier(c ErrorClassifier) {
+	classifierMu.Lock()
+	classifiers = append(classifiers, c)
+	classifierMu.Unlock()
+}
+
+// ClassifyError returns the native code for err from the registered backend classifiers,
+// falling back to a generic transport class (timeout, conn_reset, conn_refused, eof, canceled) or "other".
+func ClassifyError(err error) string {
+	classifierMu.RLock()
+	for _, c := range classifiers {
+		if code, ok := c(err); ok {
+			classifierMu.RUnlock()
+			return code
+		}
+	}
+	classifierMu.RUnlock()
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return "conn_reset"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "conn_refused"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "eof"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	}
+	return "other"
+}
+
+// IsConnectionError reports whether err is a transport failure (reset, refused, EOF, network error) rather than
+// an error the server returned, i.e. whether the connection it came from may be dead.
+func IsConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+// ErrorCount is the number of errors seen for one (operation, code), with the first message as a sample.
+type ErrorCount struct {
+	Op     string `json:"op"`
+	Code   string `json:"code"`
+	Count  int64  `json:"count"`
+	Sample string `json:"sample"`
+}
+
+type errorKey struct{ op, code string }
+
+// errorStats counts errors per (op, code); guarded by errorMu.
+var (
+	errorMu    sync.Mutex
+	errorStats = make(map[errorKey]*ErrorCount)
+)
+
+// AddError classifies err and counts it under op (ErrOpInsert, ErrOpQuery). No-op if err is nil.
+func AddError(op string, err error) {
+	if err == nil {
+		return
+	}
+	code := ClassifyError(err)
+	k := errorKey{op, code}
+	errorMu.Lock()
+	e := errorStats[k]
+	if e == nil {
+		e = &ErrorCount{Op: op, Code: code, Sample: err.Error()}
+		errorStats[k] = e
+	}
+	e.Count++
+	errorMu.Unlock()
+	noteReproError(op, code, err)
+}
+
+// loadErrors copies the per-code error counts, most frequent first.
+func loadErrors() []ErrorCount {
+	errorMu.Lock()
+	defer errorMu.Unlock()
+	out := make([]ErrorCount, 0, len(errorStats))
+	for _, e := range errorStats {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Op+out[i].Code < out[j].Op+out[j].Code
+	})
+	return out
+}
@@ -0,0 +1,239 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// failoverSampleInterval is the resolution of the error window and recovery measurements.
+	failoverSampleInterval = time.Second
+	// failoverRecoveredFrac is the fraction of the target rate that counts as recovered.
+	failoverRecoveredFrac = 0.9
+	// failoverRecoveredSamples is how many consecutive error-free samples at that rate count as recovered.
+	failoverRecoveredSamples = 3
+)
+
+// KeyCounter is implemented by WorkerCtx backends that can count the distinct MRNs of generated patients
+// (patient_id 'patient-N') in the table, used to reconcile acknowledged inserts against what survived.
+type KeyCounter interface {
+	CountKeys(ctx context.Context) (int64, error)
+}
+
+// FailoverReconciliation compares acknowledged original inserts with the MRNs found after the run.
+type FailoverReconciliation struct {
+	KeysBefore int64  `json:"keys_before"`
+	Acked      int64  `json:"acked"`    // original rows acknowledged during the run
+	Expected   int64  `json:"expected"` // keys_before + acked
+	Found      int64  `json:"found"`
+	Missing    int64  `json:"missing"` // acknowledged but gone: data loss
+	Unacked    int64  `json:"unacked"` // present without an acknowledgment (batch failed client-side but committed)
+	Error      string `json:"error,omitempty"`
+}
+
+// FailoverReport is the outcome of the failover resilience scenario.
+type FailoverReport struct {
+	Trigger          string                  `json:"trigger"`     // "hook" or "external" (first error)
+	TriggerSec       float64                 `json:"trigger_sec"` // seconds since run start; -1 if never triggered
+	Hook             string                  `json:"hook,omitempty"`
+	HookError        string                  `json:"hook_error,omitempty"`
+	ErrorWindowStart float64                 `json:"error_window_start_sec"` // -1 if no errors after the trigger
+	ErrorWindowSec   float64                 `json:"error_window_sec"`
+	Errors           int64                   `json:"errors"`       // insert+query errors after the trigger
+	RecoverySec      float64                 `json:"recovery_sec"` // trigger → sustained target rate; -1 if never
+	Reconciliation   *FailoverReconciliation `json:"reconciliation,omitempty"`
+}
+
+// failoverSample is one monitor tick: cumulative inserted rows and errors, and the target rate at that time.
+type failoverSample struct {
+	at     float64
+	rows   float64
+	errors int64
+	target int64
+}
+
+// errorTotal returns the number of insert and query errors counted so far.
+func errorTotal() int64 {
+	var n int64
+	for _, e := range loadErrors() {
+		if e.Op == ErrOpInsert || e.Op == ErrOpQuery {
+			n += e.Count
+		}
+	}
+	return n
+}
+
+// startFailover counts the keys present before the load when the backend supports reconciliation.
+func (r *LoadRunner) startFailover() {
+	cfg := &r.Config
+	r.failover = &FailoverReport{Trigger: "external", TriggerSec: -1, Hook: cfg.FailoverHook}
+	if cfg.FailoverHook != "" {
+		r.failover.Trigger = "hook"
+	}
+	kc, ok := r.WorkerCtx.(KeyCounter)
+	if !ok {
+		log.Printf("Failover: %s backend cannot count keys, skipping post-run reconciliation", cfg.Database)
+		return
+	}
+	rec := &FailoverReconciliation{}
+	n, err := kc.CountKeys(context.Background())
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	rec.KeysBefore = n
+	r.failover.Reconciliation = rec
+	log.Printf("Failover: %d patient keys in table before the run", n)
+}
+
+// runFailover samples throughput and errors until ctx is done, running the hook (if any) FailoverAtSec into the run.
+// Without a hook the failover is triggered externally and its start is taken as the first error.
+func (r *LoadRunner) runFailover(ctx context.Context) {
+	cfg := &r.Config
+	hookAt := r.runStart.Add(time.Duration(cfg.FailoverAtSec * float64(time.Second)))
+	hookDone := make(chan struct{})
+	if cfg.FailoverHook != "" {
+		go func() {
+			defer close(hookDone)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Until(hookAt)):
+			}
+			r.failover.TriggerSec = time.Since(r.runStart).Seconds()
+			r.events.add(r.runStart, "failover", "hook started: "+cfg.FailoverHook)
+			out, err := exec.CommandContext(ctx, "sh", "-c", cfg.FailoverHook).CombinedOutput()
+			detail := "hook finished"
+			if err != nil {
+				r.failover.HookError = err.Error()
+				detail = "hook failed: " + err.Error()
+			}
+			if s := strings.TrimSpace(string(out)); s != "" {
+				log.Printf("Failover hook output: %s", s)
+			}
+			r.events.add(r.runStart, "failover", detail)
+		}()
+	} else {
+		close(hookDone)
+	}
+	var samples []failoverSample
+	ticker := time.NewTicker(failoverSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			<-hookDone
+			r.failover.analyze(samples)
+			return
+		case <-ticker.C:
+			samples = append(samples, failoverSample{
+				at:     time.Since(r.runStart).Seconds(),
+				rows:   loadSnapshot().Inserted.Total,
+				errors: errorTotal(),
+				target: targetRPS.Load(),
+			})
+		}
+	}
+}
+
+// analyze derives the error window and recovery time from the monitor samples.
+func (rep *FailoverReport) analyze(samples []failoverSample) {
+	rep.ErrorWindowStart, rep.RecoverySec = -1, -1
+	var lastErr float64 = -1
+	for i := 1; i < len(samples); i++ {
+		prev, s := samples[i-1], samples[i]
+		if s.errors == prev.errors {
+			continue
+		}
+		if rep.TriggerSec < 0 && rep.Trigger == "external" {
+			rep.TriggerSec = prev.at
+		}
+		if rep.TriggerSec < 0 || s.at < rep.TriggerSec {
+			continue
+		}
+		if rep.ErrorWindowStart < 0 {
+			rep.ErrorWindowStart = prev.at
+		}
+		rep.Errors += s.errors - prev.errors
+		lastErr = s.at
+	}
+	if rep.TriggerSec < 0 {
+		return
+	}
+	if rep.ErrorWindowStart >= 0 {
+		rep.ErrorWindowSec = lastErr - rep.ErrorWindowStart
+	}
+	// Recovered at the first sample after the trigger (and the last error) that starts a run of
+	// failoverRecoveredSamples error-free intervals at or above the target rate.
+	good := 0
+	for i := 1; i < len(samples); i++ {
+		prev, s := samples[i-1], samples[i]
+		if prev.at < rep.TriggerSec || prev.at < lastErr {
+			continue
+		}
+		rate := (s.rows - prev.rows) / (s.at - prev.at)
+		if s.errors == prev.errors && rate >= failoverRecoveredFrac*float64(s.target) {
+			good++
+		} else {
+			good = 0
+		}
+		if good == failoverRecoveredSamples {
+			rep.RecoverySec = samples[i-failoverRecoveredSamples].at - rep.TriggerSec
+			return
+		}
+	}
+}
+
+// reconcileFailover counts the keys after the load and compares them with acknowledged originals.
+func (r *LoadRunner) reconcileFailover(snapshot Snapshot) {
+	rec := r.failover.Reconciliation
+	if rec == nil || rec.Error != "" {
+		return
+	}
+	kc := r.WorkerCtx.(KeyCounter)
+	found, err := kc.CountKeys(context.Background())
+	if err != nil {
+		rec.Error = err.Error()
+		return
+	}
+	rec.Acked = int64(snapshot.Inserted.Originals)
+	rec.Expected = rec.KeysBefore + rec.Acked
+	rec.Found = found
+	rec.Missing = max(0, rec.Expected-found)
+	rec.Unacked = max(0, found-rec.Expected)
+}
+
+// logFailover logs the failover scenario outcome (only when it ran).
+func logFailover(rep *FailoverReport) {
+	if rep == nil {
+		return
+	}
+	if rep.TriggerSec < 0 {
+		log.Printf("Failover (%s): not triggered during the run", rep.Trigger)
+	} else {
+		log.Printf("Failover (%s) at %.1fs:", rep.Trigger, rep.TriggerSec)
+		if rep.HookError != "" {
+			log.Printf("  hook error: %s", rep.HookError)
+		}
+		if rep.ErrorWindowStart >= 0 {
+			log.Printf("  error window: %.1fs from %.1fs (%d errors)", rep.ErrorWindowSec, rep.ErrorWindowStart, rep.Errors)
+		} else {
+			log.Printf("  error window: none")
+		}
+		if rep.RecoverySec >= 0 {
+			log.Printf("  recovery to %.0f%% of target rate: %.1fs after trigger", failoverRecoveredFrac*100, rep.RecoverySec)
+		} else {
+			log.Printf("  recovery to %.0f%% of target rate: not reached", failoverRecoveredFrac*100)
+		}
+	}
+	if rec := rep.Reconciliation; rec != nil {
+		if rec.Error != "" {
+			log.Printf("  reconciliation failed: %s", rec.Error)
+			return
+		}
+		log.Printf("  reconciliation: expected %d keys (%d before + %d acked), found %d | missing %d | unacked %d",
+			rec.Expected, rec.KeysBefore, rec.Acked, rec.Found, rec.Missing, rec.Unacked)
+	}
+}
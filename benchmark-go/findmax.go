@@ -0,0 +1,102 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Max-throughput search: hold a trial rate for StepSec, double it while trials pass, then bisect between the
+// last passing and first failing rate. A trial passes when it sustains the rate (loadStepSustainedPct) with
+// insert p99 within the SLO. The run stops once the bracket is narrower than findMaxPrecisionPct.
+
+// findMaxPrecisionPct ends the search once the failing rate is within this share above the passing one.
+const findMaxPrecisionPct = 5
+
+// FindMaxOptions configures the search; Config.TargetRPS is the first trial rate and DurationSec caps the search.
+type FindMaxOptions struct {
+	Enabled  bool
+	P99SLOMs float64 // insert batch p99 a trial may not exceed
+	StepSec  float64 // how long each trial rate is held
+}
+
+// FindMaxReport is the outcome of the search.
+type FindMaxReport struct {
+	P99SLOMs  float64         `json:"p99_slo_ms"`
+	StepSec   float64         `json:"step_sec"`
+	Trials    []LoadStepStats `json:"trials"`
+	MaxRPS    int             `json:"max_rps"`    // highest passing trial rate; 0 if none passed
+	FailedRPS int             `json:"failed_rps"` // lowest failing trial rate; 0 if none failed
+	Converged bool            `json:"converged"`  // false when the run's duration ended the search first
+}
+
+// passes reports whether a trial met its rate within the SLO.
+func (o FindMaxOptions) passes(st LoadStepStats) bool {
+	return st.Sustained && st.Insert != nil && st.Insert.P99Ms <= o.P99SLOMs
+}
+
+// runFindMax searches for the highest sustainable rate and stops the run when the search converges.
+func (r *LoadRunner) runFindMax(ctx context.Context) {
+	opts := r.Config.FindMax
+	rep := &FindMaxReport{P99SLOMs: opts.P99SLOMs, StepSec: opts.StepSec}
+	defer func() { r.findMax = rep }()
+	rps := r.Config.TargetRPS
+	for {
+		r.events.add(r.runStart, "find_max", fmt.Sprintf("trial %d: %d rows/sec", len(rep.Trials)+1, rps))
+		st, ok := r.measureStep(ctx, len(rep.Trials)+1, LoadStep{RPS: rps, DurationSec: opts.StepSec})
+		if !ok {
+			return // a cut-short trial decides nothing
+		}
+		rep.Trials = append(rep.Trials, st)
+		if opts.passes(st) {
+			rep.MaxRPS = rps
+		} else {
+			rep.FailedRPS = rps
+		}
+		log.Printf("Find max: %d rows/sec %s (actual %.1f rows/sec, insert p99 %s)", rps, passLabel(opts.passes(st)), st.RowsPerSec, p99Label(st.Insert))
+		switch {
+		case rep.FailedRPS == 0:
+			rps *= 2
+		case rep.MaxRPS == 0 && rps > 1:
+			rps /= 2
+		default:
+			rps = (rep.MaxRPS + rep.FailedRPS) / 2
+		}
+		if rep.FailedRPS > 0 && (rep.FailedRPS-rep.MaxRPS)*100 <= rep.MaxRPS*findMaxPrecisionPct || rps == rep.MaxRPS || rps < 1 {
+			rep.Converged = true
+			r.cancelRun()
+			return
+		}
+	}
+}
+
+func passLabel(pass bool) string {
+	if pass {
+		return "passed"
+	}
+	return "failed"
+}
+
+func p99Label(p *LatencyPercentiles) string {
+	if p == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f ms", p.P99Ms)
+}
+
+// logFindMax logs the trials and the highest rate that met the SLO.
+func logFindMax(rep *FindMaxReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Max-throughput search (insert p99 SLO %g ms, %gs per trial, %d trials):", rep.P99SLOMs, rep.StepSec, len(rep.Trials))
+	logStepTable(rep.Trials)
+	switch {
+	case rep.MaxRPS == 0:
+		log.Printf("  %sNo trial rate met the SLO%s", _colorYellow, _colorReset)
+	case !rep.Converged:
+		log.Printf("  %sMax sustainable rate: at least %d rows/sec (search cut short by --duration)%s", _colorYellow, rep.MaxRPS, _colorReset)
+	default:
+		log.Printf("  Max sustainable rate: %d rows/sec (%d rows/sec failed)", rep.MaxRPS, rep.FailedRPS)
+	}
+}
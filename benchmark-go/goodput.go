@@ -0,0 +1,129 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Goodput separates useful work from raw throughput. Throughput counts every row a backend acknowledged,
+// duplicates included; goodput counts only acknowledged original records (new MRNs), the rows a reader could
+// later find. Retried and failed batches show up as attempted rows that never became goodput, so runs with
+// different delivery semantics (upsert, append-only, at-least-once Kafka) compare on what they delivered.
+
+// GoodputInterval is throughput and goodput over one progress interval.
+type GoodputInterval struct {
+	AtSec         float64 `json:"at_sec"`
+	AttemptedRPS  float64 `json:"attempted_rps"`
+	ThroughputRPS float64 `json:"throughput_rps"`
+	GoodputRPS    float64 `json:"goodput_rps"`
+}
+
+// GoodputReport compares raw throughput with goodput for the run.
+type GoodputReport struct {
+	AttemptedRows  int64   `json:"attempted_rows"`  // rows handed to the backend, failed batches included
+	ThroughputRows int64   `json:"throughput_rows"` // rows acknowledged, duplicates included
+	GoodputRows    int64   `json:"goodput_rows"`    // acknowledged original records
+	ThroughputRPS  float64 `json:"throughput_rps"`
+	GoodputRPS     float64 `json:"goodput_rps"`
+	Efficiency     float64 `json:"efficiency"` // goodput_rows / attempted_rows
+	// Durable is false when acknowledgments do not wait for durability (--durability off), so goodput is
+	// acknowledged rather than confirmed durable.
+	Durable bool `json:"durable"`
+	// VerifiedRows are the new MRNs counted in the table after the run (keys after - keys before), when
+	// verification ran; nil otherwise.
+	VerifiedRows *int64            `json:"verified_rows,omitempty"`
+	VerifiedRPS  float64           `json:"verified_rps,omitempty"`
+	VerifyError  string            `json:"verify_error,omitempty"`
+	Intervals    []GoodputInterval `json:"intervals,omitempty"`
+}
+
+var (
+	goodputMu        sync.Mutex
+	goodputIntervals []GoodputInterval
+)
+
+// recordGoodputInterval appends one progress interval's rates (called by the Reporter).
+func recordGoodputInterval(iv GoodputInterval) {
+	goodputMu.Lock()
+	goodputIntervals = append(goodputIntervals, iv)
+	goodputMu.Unlock()
+}
+
+func loadGoodputIntervals() []GoodputInterval {
+	goodputMu.Lock()
+	defer goodputMu.Unlock()
+	return append([]GoodputInterval(nil), goodputIntervals...)
+}
+
+// startGoodput counts the generated patients' MRNs before the load, for verifying goodput afterwards.
+func (r *LoadRunner) startGoodput() {
+	r.goodputKeysBefore = -1
+	kc, ok := r.WorkerCtx.(KeyCounter)
+	if !ok {
+		log.Printf("Goodput verification: %s cannot count keys; reporting acknowledged goodput only", r.Config.Database)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	n, err := kc.CountKeys(ctx)
+	if err != nil {
+		log.Printf("Goodput verification: counting keys before load: %v", err)
+		return
+	}
+	r.goodputKeysBefore = n
+}
+
+// finishGoodput builds the goodput report, verifying it against the table when startGoodput counted keys.
+func (r *LoadRunner) finishGoodput(snapshot Snapshot) *GoodputReport {
+	elapsed := r.runEnd.Sub(r.runStart).Seconds()
+	rep := &GoodputReport{
+		AttemptedRows:  int64(snapshot.Inserted.Attempted),
+		ThroughputRows: int64(snapshot.Inserted.Total),
+		GoodputRows:    int64(snapshot.Inserted.Originals),
+		Durable:        r.Config.Durability != DurabilityOff,
+		Intervals:      loadGoodputIntervals(),
+	}
+	if elapsed > 0 {
+		rep.ThroughputRPS = float64(rep.ThroughputRows) / elapsed
+		rep.GoodputRPS = float64(rep.GoodputRows) / elapsed
+	}
+	if rep.AttemptedRows > 0 {
+		rep.Efficiency = float64(rep.GoodputRows) / float64(rep.AttemptedRows)
+	}
+	if !r.Config.VerifyGoodput || r.goodputKeysBefore < 0 {
+		return rep
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	found, err := r.WorkerCtx.(KeyCounter).CountKeys(ctx)
+	if err != nil {
+		rep.VerifyError = err.Error()
+		return rep
+	}
+	verified := max(0, found-r.goodputKeysBefore)
+	rep.VerifiedRows = &verified
+	if elapsed > 0 {
+		rep.VerifiedRPS = float64(verified) / elapsed
+	}
+	return rep
+}
+
+// logGoodput logs throughput next to goodput, and the verified count when verification ran.
+func logGoodput(rep *GoodputReport) {
+	if rep == nil || rep.AttemptedRows == 0 {
+		return
+	}
+	log.Printf("Goodput: %.1f rows/sec (%d original records) vs throughput %.1f rows/sec (%d rows acknowledged, %d attempted) | efficiency %.1f%%",
+		rep.GoodputRPS, rep.GoodputRows, rep.ThroughputRPS, rep.ThroughputRows, rep.AttemptedRows, rep.Efficiency*100)
+	if !rep.Durable {
+		log.Printf("  durability off: acknowledged, not confirmed durable")
+	}
+	if rep.VerifyError != "" {
+		log.Printf("  verification failed: %s", rep.VerifyError)
+	} else if rep.VerifiedRows != nil {
+		log.Printf("  verified: %d new MRNs in the table (%.1f rows/sec, %+d vs acknowledged goodput)",
+			*rep.VerifiedRows, rep.VerifiedRPS, *rep.VerifiedRows-rep.GoodputRows)
+	}
+}
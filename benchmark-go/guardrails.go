@@ -0,0 +1,100 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// guardrailInterval is how often the table is checked against the guardrail limits.
+const guardrailInterval = 15 * time.Second
+
+// RowCounter is implemented by WorkerCtx backends that can count the table's rows.
+type RowCounter interface {
+	CountRows(ctx context.Context) (int64, error)
+}
+
+// GuardrailOptions limits how large the target table may grow; the run stops once a limit is exceeded.
+type GuardrailOptions struct {
+	MaxTotalRows int64   // 0 = unlimited
+	MaxStorageGB float64 // 0 = unlimited; on-disk size as reported by the backend's StorageReporter
+}
+
+// Enabled reports whether any limit is set.
+func (o GuardrailOptions) Enabled() bool {
+	return o.MaxTotalRows > 0 || o.MaxStorageGB > 0
+}
+
+// GuardrailTrip records the guardrail that stopped the run.
+type GuardrailTrip struct {
+	Limit string  `json:"limit"` // "max_total_rows" or "max_storage_gb"
+	Max   float64 `json:"max"`
+	Value float64 `json:"value"`
+	AtSec float64 `json:"at_sec"`
+}
+
+// runGuardrails checks the table every guardrailInterval and cancels the run when a limit is exceeded.
+// Limits the backend cannot measure are skipped with a warning.
+func (r *LoadRunner) runGuardrails(ctx context.Context) {
+	opts := r.Config.Guardrails
+	rc, canCount := r.WorkerCtx.(RowCounter)
+	sr, canSize := r.WorkerCtx.(StorageReporter)
+	if opts.MaxTotalRows > 0 && !canCount {
+		log.Printf("Guardrails: %s backend cannot count rows, --max-total-rows is not enforced", r.Config.Database)
+	}
+	if opts.MaxStorageGB > 0 && !canSize {
+		log.Printf("Guardrails: %s backend cannot measure storage, --max-storage-gb is not enforced", r.Config.Database)
+	}
+	checkRows := opts.MaxTotalRows > 0 && canCount
+	checkSize := opts.MaxStorageGB > 0 && canSize
+	if !checkRows && !checkSize {
+		return
+	}
+	ticker := time.NewTicker(guardrailInterval)
+	defer ticker.Stop()
+	for {
+		if trip := r.checkGuardrails(ctx, rc, sr, checkRows, checkSize); trip != nil {
+			r.guardrail = trip
+			r.events.add(r.runStart, "guardrail", fmt.Sprintf("%s exceeded (%.0f > %g); stopping the run", trip.Limit, trip.Value, trip.Max))
+			r.cancelRun()
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *LoadRunner) checkGuardrails(ctx context.Context, rc RowCounter, sr StorageReporter, checkRows, checkSize bool) *GuardrailTrip {
+	opts := r.Config.Guardrails
+	if checkRows {
+		n, err := rc.CountRows(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Guardrails: count rows: %v", err)
+		}
+		if err == nil && n > opts.MaxTotalRows {
+			return &GuardrailTrip{Limit: "max_total_rows", Max: float64(opts.MaxTotalRows), Value: float64(n), AtSec: time.Since(r.runStart).Seconds()}
+		}
+	}
+	if checkSize {
+		b, err := sr.TableBytes(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Guardrails: table size: %v", err)
+		}
+		if gb := float64(b) / (1 << 30); err == nil && gb > opts.MaxStorageGB {
+			return &GuardrailTrip{Limit: "max_storage_gb", Max: opts.MaxStorageGB, Value: gb, AtSec: time.Since(r.runStart).Seconds()}
+		}
+	}
+	return nil
+}
+
+// logGuardrail logs the guardrail that stopped the run, if any.
+func logGuardrail(trip *GuardrailTrip) {
+	if trip == nil {
+		return
+	}
+	log.Printf("Run stopped by guardrail at %.1fs: %s %.2f exceeded limit %g", trip.AtSec, trip.Limit, trip.Value, trip.Max)
+}
@@ -0,0 +1,215 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// hintSampleInterval is the window for correlating insert latency with backend state (e.g. ClickHouse parts).
+	hintSampleInterval = 5 * time.Second
+	// hintPartsThreshold is the active parts per partition above which ClickHouse starts to delay inserts
+	// (parts_to_delay_insert defaults to 150 in newer releases and 300 before; 300 is where it reliably bites).
+	hintPartsThreshold = 300
+)
+
+// PartsReporter is implemented by WorkerCtx backends whose write path degrades with the number of active
+// parts (ClickHouse); MaxActiveParts returns the highest active part count of any partition on any replica.
+type PartsReporter interface {
+	MaxActiveParts(ctx context.Context) (int64, error)
+}
+
+// Wait counters behind the hints: time the pipeline spends blocked rather than working.
+var (
+	producerBlockedMicros   atomic.Int64 // producers waiting on the full producer queue
+	routerLimiterMicros     atomic.Int64 // router waiting on the rate limiter
+	routerQueueMicros       atomic.Int64 // router waiting on full worker queues
+	queryQueueBlockedMicros atomic.Int64 // insert workers waiting on the full query queue
+	insertPoolWaitMicros    atomic.Int64 // insert workers waiting for a connection
+	queryPoolWaitMicros     atomic.Int64 // query workers waiting for a connection
+	queryPoolWaits          atomic.Int64
+	insertWindowMaxMicros   atomic.Int64 // slowest insert batch in the current hint window
+)
+
+// AddQueryPoolWait records time a query worker waited to acquire a connection (backends with explicit pools).
+func AddQueryPoolWait(d time.Duration) {
+	queryPoolWaitMicros.Add(d.Microseconds())
+	queryPoolWaits.Add(1)
+}
+
+// noteInsertLatency raises the current window's slowest insert batch.
+func noteInsertLatency(d time.Duration) {
+	us := d.Microseconds()
+	for {
+		cur := insertWindowMaxMicros.Load()
+		if us <= cur || insertWindowMaxMicros.CompareAndSwap(cur, us) {
+			return
+		}
+	}
+}
+
+// hintWindow is one sample of the slowest insert and the backend's part count.
+type hintWindow struct {
+	maxInsertMs float64
+	parts       int64 // -1 when not measured
+}
+
+// runHintSampler records a hintWindow every hintSampleInterval until ctx ends.
+func (r *LoadRunner) runHintSampler(ctx context.Context) {
+	pr, hasParts := r.WorkerCtx.(PartsReporter)
+	ticker := time.NewTicker(hintSampleInterval)
+	defer ticker.Stop()
+	insertWindowMaxMicros.Store(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		w := hintWindow{maxInsertMs: float64(insertWindowMaxMicros.Swap(0)) / 1000, parts: -1}
+		if hasParts {
+			if n, err := pr.MaxActiveParts(ctx); err == nil {
+				w.parts = n
+			}
+		}
+		r.hintWindows = append(r.hintWindows, w)
+	}
+}
+
+// bottleneckHints derives heuristic diagnostics from the run's wait counters, stage utilization, errors and
+// backend samples, each phrased with a next step.
+func (r *LoadRunner) bottleneckHints(snapshot Snapshot, elapsed float64) []string {
+	if elapsed <= 0 {
+		return nil
+	}
+	var hints []string
+	add := func(format string, args ...interface{}) {
+		hints = append(hints, fmt.Sprintf(format, args...))
+	}
+	share := func(micros int64, goroutines int) float64 {
+		if goroutines <= 0 {
+			return 0
+		}
+		return float64(micros) / 1e6 / (elapsed * float64(goroutines)) * 100
+	}
+	cfg := &r.Config
+	workers := len(r.insertWorkers)
+
+	if cfg.TargetRPS > 0 {
+		done := snapshot.Inserted.Total
+		if cfg.Mode == ModeQueryOnly {
+			done = snapshot.Queries.Count // the target is a query rate
+		}
+		reached := done / elapsed / float64(cfg.TargetRPS) * 100
+		if reached < 90 {
+			busiest, util := "", 0.0
+			for _, s := range r.Pipeline.Stats() {
+				if s.Workers == 0 || s.Name == StagePace {
+					continue
+				}
+				if u := s.BusySec / (elapsed * float64(s.Workers)) * 100; u > util {
+					busiest, util = s.Name, u
+				}
+			}
+			advice := map[string]string{
+				StageGenerate: "add --producers",
+				StageInsert:   "the database or its connections are the limit: add --workers, raise --batch-size, or scale the database",
+				StageQuery:    "query workers are the limit: lower --queries-per-record or scale reads",
+			}[busiest]
+			if advice == "" {
+				advice = "check the pipeline stage table"
+			}
+			add("reached %.0f%% of the target rate; the busiest stage was %s (%.0f%% utilized): %s", reached, busiest, util, advice)
+		} else if p := share(routerLimiterMicros.Load(), 1); p > 50 {
+			add("the rate limiter held batches back %.0f%% of the time: the target rate, not the system, capped throughput; raise --target-rps to find the limit", p)
+		}
+	}
+	if p := share(producerBlockedMicros.Load(), len(r.producers)); p > 20 {
+		add("producers were blocked by queue backpressure %.0f%% of the time: generation is not the limit, inserts are", p)
+	}
+	if p := share(routerQueueMicros.Load(), 1); p > 20 {
+		add("the router waited on full insert worker queues %.0f%% of the time: insert workers are saturated; add --workers or raise --batch-size", p)
+	}
+	if p := share(queryQueueBlockedMicros.Load(), workers); p > 10 {
+		add("insert workers waited on the full query queue %.0f%% of the time: query workers throttle inserts; lower --queries-per-record or raise --query-queue-size", p)
+	}
+	if insert := findStage(r.Pipeline.Stats(), StageInsert); insert != nil && insert.BusySec > 0 {
+		wait := float64(insertPoolWaitMicros.Load()) / 1e6
+		if pct := wait / (wait + insert.BusySec) * 100; pct > 25 {
+			add("connection pool acquire wait was %.0f%% of insert time: the pool is smaller than the workers using it", pct)
+		}
+	}
+	if n := queryPoolWaits.Load(); n > 0 && snapshot.Queries.Count > 0 {
+		waitMs := float64(queryPoolWaitMicros.Load()) / 1000 / float64(n)
+		queryMs := snapshot.Queries.TotalLatencySec / snapshot.Queries.Count * 1000
+		if waitMs > queryMs {
+			add("pool acquire wait dominated query latency (avg %.2f ms waiting vs %.2f ms per query): raise the query pool size or lower query concurrency", waitMs, queryMs)
+		}
+	}
+	r.partsHint(add)
+	if batches := findStage(r.Pipeline.Stats(), StageInsert); batches != nil && batches.Items > 0 {
+		for _, e := range snapshot.Errors {
+			if e.Op != ErrOpInsert && e.Op != ErrOpInsertRetry {
+				continue
+			}
+			if pct := float64(e.Count) / float64(batches.Items) * 100; pct > 1 {
+				add("%s errors %s hit %.1f%% of insert batches (%d): see the error table; throughput above is after failures", e.Op, e.Code, pct, e.Count)
+			}
+			break
+		}
+	}
+	if cpu := processCPUSeconds() - r.cpuBefore; cpu > 0 {
+		procs := usableCPUs()
+		if pct := cpu / (elapsed * procs) * 100; pct > clientCPUBoundPct {
+			add("the load generator used %.0f%% of its %.0f CPUs: results may be client-bound; run on a larger host or split with --runner-count", pct, procs)
+		}
+	}
+	return hints
+}
+
+// partsHint compares the slowest inserts in windows above hintPartsThreshold active parts with the others.
+func (r *LoadRunner) partsHint(add func(string, ...interface{})) {
+	var hiSum, loSum float64
+	var hi, lo int
+	for _, w := range r.hintWindows {
+		switch {
+		case w.parts < 0 || w.maxInsertMs == 0:
+		case w.parts > hintPartsThreshold:
+			hiSum += w.maxInsertMs
+			hi++
+		default:
+			loSum += w.maxInsertMs
+			lo++
+		}
+	}
+	switch {
+	case hi > 0 && lo > 0 && hiSum/float64(hi) >= 1.5*loSum/float64(lo):
+		add("worst insert latency was %.1f ms in %d windows with >%d active parts vs %.1f ms otherwise: merges lag inserts; raise --batch-size or use async inserts",
+			hiSum/float64(hi), hi, hintPartsThreshold, loSum/float64(lo))
+	case hi > 0 && lo == 0:
+		add("active parts stayed above %d for the whole run: merges cannot keep up; raise --batch-size or use async inserts", hintPartsThreshold)
+	}
+}
+
+func findStage(stages []StageStats, name string) *StageStats {
+	for i := range stages {
+		if stages[i].Name == name {
+			return &stages[i]
+		}
+	}
+	return nil
+}
+
+// logHints logs the bottleneck hints (only when any fired).
+func logHints(hints []string) {
+	if len(hints) == 0 {
+		return
+	}
+	log.Printf("Bottleneck hints:")
+	for _, h := range hints {
+		log.Printf("  - %s", h)
+	}
+}
@@ -0,0 +1,167 @@
+// Package httpsink is a sink pseudo-backend: it POSTs each batch as NDJSON (one patient JSON message per line)
+// to an HTTP ingestion endpoint, benchmarking an ingestion service end to end with the same producer load shape
+// as the direct-database runs. Inserted rows are rows of accepted (2xx) requests; "insert latency" is the
+// request's round trip.
+package httpsink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// DefaultTimeout bounds one POST when Options.Timeout is unset.
+const DefaultTimeout = 30 * time.Second
+
+// maxErrorBody caps how much of a failed response's body is kept in the error.
+const maxErrorBody = 512
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// StatusError is a non-2xx response from the endpoint.
+type StatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("httpsink: HTTP %d: %s", e.Status, e.Body)
+}
+
+// classifyError maps non-2xx responses to "http:<status>" (e.g. http:429 when the service sheds load).
+func classifyError(err error) (string, bool) {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return "http:" + strconv.Itoa(se.Status), true
+	}
+	return "", false
+}
+
+// Options configures the endpoint.
+type Options struct {
+	URL     string        // endpoint receiving the POSTs; HTTPSINK_URL when empty
+	Timeout time.Duration // per-request timeout (0 = DefaultTimeout)
+}
+
+// Backend implements benchmarkgo.InsertBackend with one shared http.Client (safe for concurrent use).
+type Backend struct {
+	client *http.Client
+	url    string
+	auth   string
+}
+
+// GetConn returns the shared client.
+func (b *Backend) GetConn() interface{} {
+	return b.client
+}
+
+// ReleaseConn is a no-op; the client's transport pools connections.
+func (b *Backend) ReleaseConn(c interface{}) {}
+
+// InsertBatch POSTs the rows as one NDJSON body. Statements is the one request.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	client, ok := conn.(*http.Client)
+	if !ok {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // pgbouncer routing hint; unused for HTTP
+	var body bytes.Buffer
+	for _, r := range rows {
+		body.WriteString(r.JSONMessage)
+		body.WriteByte('\n')
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, b.url, &body)
+	if err != nil {
+		return benchmarkgo.InsertResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if b.auth != "" {
+		req.Header.Set("Authorization", b.auth)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return benchmarkgo.InsertResult{Statements: 1}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return benchmarkgo.InsertResult{Statements: 1}, &StatusError{Status: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	}
+	io.Copy(io.Discard, resp.Body) // drain so the connection is reused
+	return benchmarkgo.InsertResult{Rows: len(rows), Statements: 1}, nil
+}
+
+// Context builds the client. An ingestion endpoint has no lookups, so query workers are not supported.
+type Context struct {
+	Options Options
+	client  *http.Client
+}
+
+// Setup builds a client keeping up to numWorkers idle connections to the endpoint (URL from Options or
+// HTTPSINK_URL; HTTPSINK_AUTHORIZATION, if set, is sent as the Authorization header).
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if c.client != nil {
+		log.Fatal("httpsink Setup already called")
+	}
+	if queriesPerRecord > 0 {
+		return nil, errors.New("httpsink is a sink without lookups; run with --queries-per-record 0")
+	}
+	url := c.Options.URL
+	if url == "" {
+		url = os.Getenv("HTTPSINK_URL")
+	}
+	if url == "" {
+		return nil, errors.New("httpsink: no endpoint; set --httpsink-url or HTTPSINK_URL")
+	}
+	timeout := c.Options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = numWorkers
+	transport.MaxIdleConnsPerHost = numWorkers
+	c.client = &http.Client{Transport: transport, Timeout: timeout}
+	log.Printf("POSTing NDJSON batches to %s (%d connections, timeout %s)", url, numWorkers, timeout)
+	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
+	return &Backend{client: c.client, url: url, auth: os.Getenv("HTTPSINK_AUTHORIZATION")}, nil
+}
+
+// Teardown closes idle connections.
+func (c *Context) Teardown() {
+	if c.client != nil {
+		c.client.CloseIdleConnections()
+		c.client = nil
+	}
+}
+
+// GetMaxPatientCounter returns -1: the sink cannot be read back, so reruns start again at ordinal 0.
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	return -1, nil
+}
+
+// RunQueryWorker drains queryQueue; Setup rejects query workloads, so no jobs arrive.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex
+	_ = opts
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+	}
+}
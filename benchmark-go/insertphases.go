@@ -0,0 +1,69 @@
+package benchmarkgo
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// InsertPhases splits one InsertBatch call: Decode is parsing each row's JSON message, Bind is building the
+// statement or appending column values, and Call is the network round trips and server work through commit.
+type InsertPhases struct {
+	Decode time.Duration
+	Bind   time.Duration
+	Call   time.Duration
+}
+
+// InsertPhaseBreakdown is the average per-batch time of each phase over the instrumented successful batches.
+type InsertPhaseBreakdown struct {
+	Batches  int64   `json:"batches"`
+	DecodeMs float64 `json:"decode_ms"`
+	BindMs   float64 `json:"bind_ms"`
+	CallMs   float64 `json:"call_ms"`
+}
+
+var (
+	insertPhaseBatches atomic.Int64
+	insertDecodeMicros atomic.Int64
+	insertBindMicros   atomic.Int64
+	insertCallMicros   atomic.Int64
+)
+
+// addInsertPhases records one instrumented batch; zero phases (uninstrumented backends) are skipped.
+func addInsertPhases(p InsertPhases) {
+	if p == (InsertPhases{}) {
+		return
+	}
+	insertPhaseBatches.Add(1)
+	insertDecodeMicros.Add(p.Decode.Microseconds())
+	insertBindMicros.Add(p.Bind.Microseconds())
+	insertCallMicros.Add(p.Call.Microseconds())
+}
+
+// loadInsertPhases averages the phases per batch; nil unless the backend instrumented its inserts.
+func loadInsertPhases() *InsertPhaseBreakdown {
+	n := insertPhaseBatches.Load()
+	if n == 0 {
+		return nil
+	}
+	avg := func(micros int64) float64 { return float64(micros) / 1000 / float64(n) }
+	return &InsertPhaseBreakdown{
+		Batches:  n,
+		DecodeMs: avg(insertDecodeMicros.Load()),
+		BindMs:   avg(insertBindMicros.Load()),
+		CallMs:   avg(insertCallMicros.Load()),
+	}
+}
+
+// logInsertPhases logs the per-batch decode/bind/call split with each phase's share.
+func logInsertPhases(b *InsertPhaseBreakdown) {
+	if b == nil {
+		return
+	}
+	total := b.DecodeMs + b.BindMs + b.CallMs
+	if total <= 0 {
+		return
+	}
+	log.Printf("Insert time breakdown (avg per batch over %d batches): JSON decode %.2f ms (%.0f%%) | bind/append %.2f ms (%.0f%%) | network/commit %.2f ms (%.0f%%)",
+		b.Batches, b.DecodeMs, b.DecodeMs/total*100, b.BindMs, b.BindMs/total*100, b.CallMs, b.CallMs/total*100)
+}
@@ -0,0 +1,97 @@
+package benchmarkgo
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// InsertResult is what a backend reports for one InsertBatch call. Zero Bytes/ServerTime mean the driver
+// did not say.
+type InsertResult struct {
+	Rows       int             // rows written
+	Statements int             // statements or requests issued
+	Bytes      int64           // bytes the server reports written (0 = unknown; message bytes are counted instead)
+	ServerTime time.Duration   // server-side duration of the insert (0 = unknown)
+	Warnings   []InsertWarning // non-fatal server conditions, e.g. ClickHouse delaying the insert for too many parts
+	Phases     InsertPhases    // client-side time breakdown (zero = the backend does not instrument its insert path)
+}
+
+// InsertWarning is one server warning on a successful insert. Code is stable ("clickhouse:delayed_insert")
+// so warnings aggregate across batches; Message carries the details of the first occurrence.
+type InsertWarning struct {
+	Code    string
+	Message string
+}
+
+// WarningCount is the number of batches that reported one warning code, with the first message as a sample.
+type WarningCount struct {
+	Code   string `json:"code"`
+	Count  int64  `json:"count"`
+	Sample string `json:"sample"`
+}
+
+var (
+	insertServerMicros  atomic.Int64 // server-reported insert time
+	insertServerBatches atomic.Int64 // batches that reported a server time
+
+	warningMu    sync.Mutex
+	warningStats = make(map[string]*WarningCount)
+)
+
+// addInsertResult records the server-side details of a successful insert.
+func addInsertResult(res InsertResult) {
+	addInsertPhases(res.Phases)
+	if res.ServerTime > 0 {
+		insertServerMicros.Add(res.ServerTime.Microseconds())
+		insertServerBatches.Add(1)
+	}
+	if len(res.Warnings) == 0 {
+		return
+	}
+	warningMu.Lock()
+	for _, w := range res.Warnings {
+		c := warningStats[w.Code]
+		if c == nil {
+			c = &WarningCount{Code: w.Code, Sample: w.Message}
+			warningStats[w.Code] = c
+		}
+		c.Count++
+	}
+	warningMu.Unlock()
+}
+
+// loadWarnings copies the per-code warning counts, most frequent first.
+func loadWarnings() []WarningCount {
+	warningMu.Lock()
+	defer warningMu.Unlock()
+	out := make([]WarningCount, 0, len(warningStats))
+	for _, w := range warningStats {
+		out = append(out, *w)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Code < out[j].Code
+	})
+	return out
+}
+
+// logWarnings logs insert warning counts per code (only when the server reported any).
+func logWarnings(warnings []WarningCount) {
+	if len(warnings) == 0 {
+		return
+	}
+	log.Printf("Insert warnings by code:")
+	log.Printf("  %-32s %10s  %s", "code", "batches", "first message")
+	for _, w := range warnings {
+		sample := w.Sample
+		if len(sample) > 120 {
+			sample = sample[:120] + "..."
+		}
+		log.Printf("  %-32s %10d  %s", w.Code, w.Count, sample)
+	}
+}
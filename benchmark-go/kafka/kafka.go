@@ -0,0 +1,255 @@
+// Package kafka is a sink pseudo-backend: it publishes patient JSON to a topic keyed by MRN through the usual
+// producer/insert-worker machinery, benchmarking the leg where HL7 lands in Kafka before any database.
+// Inserted rows are produced messages; "insert latency" is produce-until-acknowledged latency.
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const (
+	defaultBrokers = "kafka:9092"
+	// maxMessageBytes covers a record with its ~2 MiB SOURCE; set on the topic and as the writer's batch limit.
+	maxMessageBytes = 8 << 20
+)
+
+// Compression codecs for --kafka-compression.
+var compressions = map[string]kafkago.Compression{
+	"none":   0,
+	"gzip":   kafkago.Gzip,
+	"snappy": kafkago.Snappy,
+	"lz4":    kafkago.Lz4,
+	"zstd":   kafkago.Zstd,
+}
+
+// ValidateCompression checks a --kafka-compression value.
+func ValidateCompression(codec string) error {
+	if _, ok := compressions[codec]; !ok {
+		return fmt.Errorf("unknown compression %q (want none, gzip, snappy, lz4 or zstd)", codec)
+	}
+	return nil
+}
+
+// Options configures the topic and producer.
+type Options struct {
+	Partitions  int    // partitions when the topic is created
+	Compression string // producer compression codec, "none" by default
+	Durability  string // benchmarkgo durability level, applied as acks
+}
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// classifyError maps broker error codes to "kafka:<code>" (e.g. kafka:10 for MESSAGE_TOO_LARGE).
+func classifyError(err error) (string, bool) {
+	var we kafkago.WriteErrors
+	if errors.As(err, &we) {
+		for _, e := range we {
+			if e != nil {
+				return classifyError(e)
+			}
+		}
+	}
+	var ke kafkago.Error
+	if errors.As(err, &ke) {
+		return "kafka:" + strconv.Itoa(int(ke)), true
+	}
+	var tooLarge kafkago.MessageTooLargeError
+	if errors.As(err, &tooLarge) {
+		return "kafka:message_too_large", true
+	}
+	return "", false
+}
+
+// RequiredAcks maps a benchmarkgo durability level to producer acks: off → 0, local → leader only, otherwise all
+// in-sync replicas.
+func RequiredAcks(level string) kafkago.RequiredAcks {
+	switch level {
+	case benchmarkgo.DurabilityOff:
+		return kafkago.RequireNone
+	case benchmarkgo.DurabilityLocal:
+		return kafkago.RequireOne
+	}
+	return kafkago.RequireAll
+}
+
+// Backend implements benchmarkgo.InsertBackend with one shared Writer (safe for concurrent use).
+type Backend struct {
+	writer *kafkago.Writer
+}
+
+// GetConn returns the shared writer.
+func (b *Backend) GetConn() interface{} {
+	return b.writer
+}
+
+// ReleaseConn is a no-op; the writer manages its broker connections.
+func (b *Backend) ReleaseConn(c interface{}) {}
+
+// InsertBatch produces one message per row (key MRN, value the JSON message) and waits for the acks.
+// Statements is the one produce call.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	w, ok := conn.(*kafkago.Writer)
+	if !ok {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // pgbouncer routing hint; unused for Kafka
+	msgs := make([]kafkago.Message, len(rows))
+	for i, r := range rows {
+		var m struct {
+			MRN string `json:"MEDICAL_RECORD_NUMBER"`
+		}
+		if err := json.Unmarshal([]byte(r.JSONMessage), &m); err != nil {
+			return benchmarkgo.InsertResult{}, err
+		}
+		msgs[i] = kafkago.Message{
+			Key:     []byte(m.MRN),
+			Value:   []byte(r.JSONMessage),
+			Headers: []kafkago.Header{{Key: "message_type", Value: []byte(r.MessageType)}},
+		}
+	}
+	if err := w.WriteMessages(context.Background(), msgs...); err != nil {
+		return benchmarkgo.InsertResult{Statements: 1}, err
+	}
+	return benchmarkgo.InsertResult{Rows: len(rows), Statements: 1}, nil
+}
+
+// Context creates the topic and writer. Kafka has no lookups, so query workers are not supported.
+type Context struct {
+	Options Options
+	writer  *kafkago.Writer
+	topic   string
+}
+
+// Setup creates the topic if missing (KAFKA_BROKERS, comma-separated; topic KAFKA_TOPIC or the table name)
+// and a writer batching up to numWorkers × batch rows per partition request.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if c.writer != nil {
+		log.Fatal("kafka Setup already called")
+	}
+	if queriesPerRecord > 0 {
+		return nil, errors.New("kafka is a sink without lookups; run with --queries-per-record 0")
+	}
+	brokers := strings.Split(os.Getenv("KAFKA_BROKERS"), ",")
+	if brokers[0] == "" {
+		brokers = strings.Split(defaultBrokers, ",")
+	}
+	c.topic = os.Getenv("KAFKA_TOPIC")
+	if c.topic == "" {
+		c.topic = benchmarkgo.Table()
+	}
+	if c.Options.Partitions <= 0 {
+		c.Options.Partitions = 1
+	}
+	if c.Options.Compression == "" {
+		c.Options.Compression = "none"
+	}
+	log.Printf("Using Kafka at %s, topic %s (%d partitions, compression %s)",
+		strings.Join(brokers, ","), c.topic, c.Options.Partitions, c.Options.Compression)
+	if err := createTopic(brokers[0], c.topic, c.Options.Partitions); err != nil {
+		return nil, err
+	}
+	c.writer = &kafkago.Writer{
+		Addr:         kafkago.TCP(brokers...),
+		Topic:        c.topic,
+		Balancer:     &kafkago.Hash{}, // same MRN → same partition, as our pipeline keys by patient
+		RequiredAcks: RequiredAcks(c.Options.Durability),
+		Compression:  compressions[c.Options.Compression],
+		BatchSize:    1 << 16,
+		BatchBytes:   maxMessageBytes,
+		BatchTimeout: time.Millisecond, // workers hand over whole batches; don't hold them back
+	}
+	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
+	return &Backend{writer: c.writer}, nil
+}
+
+// createTopic creates topic through the controller unless it already exists.
+func createTopic(broker, topic string, partitions int) error {
+	conn, err := kafkago.Dial("tcp", broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	controller, err := conn.Controller()
+	if err != nil {
+		return err
+	}
+	cc, err := kafkago.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	err = cc.CreateTopics(kafkago.TopicConfig{
+		Topic:             topic,
+		NumPartitions:     partitions,
+		ReplicationFactor: -1, // broker default
+		ConfigEntries:     []kafkago.ConfigEntry{{ConfigName: "max.message.bytes", ConfigValue: strconv.Itoa(maxMessageBytes)}},
+	})
+	if errors.Is(err, kafkago.TopicAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// DurabilitySetting reports the producer acks used (implements benchmarkgo.DurabilityReporter).
+func (c *Context) DurabilitySetting() string {
+	switch RequiredAcks(c.Options.Durability) {
+	case kafkago.RequireNone:
+		return "acks=0"
+	case kafkago.RequireOne:
+		return "acks=1"
+	}
+	return "acks=all"
+}
+
+// Teardown flushes and closes the writer and logs its produce statistics.
+func (c *Context) Teardown() {
+	if c.writer == nil {
+		return
+	}
+	st := c.writer.Stats()
+	if err := c.writer.Close(); err != nil {
+		log.Printf("kafka writer close: %v", err)
+	}
+	c.writer = nil
+	if st.Messages == 0 {
+		return
+	}
+	log.Printf("Kafka produce: %d messages, %s in %d requests | write avg %.2f ms, max %.2f ms | %d retries, %d errors",
+		st.Messages, benchmarkgo.FormatBytes(st.Bytes), st.Writes,
+		float64(st.WriteTime.Avg)/float64(time.Millisecond), float64(st.WriteTime.Max)/float64(time.Millisecond),
+		st.Retries, st.Errors)
+}
+
+// GetMaxPatientCounter returns -1: a topic is an append-only log, so reruns start again at ordinal 0.
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	return -1, nil
+}
+
+// RunQueryWorker drains queryQueue; Setup rejects query workloads, so no jobs arrive.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex
+	_ = opts
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+	}
+}
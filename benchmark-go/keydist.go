@@ -0,0 +1,95 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Key distributions: which loaded patient a mixed-workload or query-only read looks up.
+const (
+	KeyDistUniform = "uniform" // every loaded patient equally likely (default)
+	KeyDistZipfian = "zipfian" // a few hot patients take most reads, scattered over the key space
+	KeyDistLatest  = "latest"  // zipfian over recency: the most recently loaded patients are the hottest
+)
+
+// defaultKeySkew is the zipfian exponent when none is given.
+const defaultKeySkew = 1.2
+
+// KeyDist selects the patients reads look up.
+type KeyDist struct {
+	Kind string  // KeyDistUniform, KeyDistZipfian or KeyDistLatest ("" = uniform)
+	S    float64 // zipfian exponent, > 1; higher concentrates reads on fewer patients
+}
+
+// ParseKeyDist parses "uniform", "zipfian[:s=SKEW]" or "latest[:s=SKEW]", e.g. "zipfian:s=1.2". The skew
+// defaults to 1.2.
+func ParseKeyDist(s string) (KeyDist, error) {
+	kind, params, _ := strings.Cut(strings.TrimSpace(s), ":")
+	d := KeyDist{Kind: kind}
+	switch kind {
+	case KeyDistUniform:
+		if params != "" {
+			return d, fmt.Errorf("%s takes no parameters", KeyDistUniform)
+		}
+		return d, nil
+	case KeyDistZipfian, KeyDistLatest:
+		d.S = defaultKeySkew
+	default:
+		return d, fmt.Errorf("unknown key distribution %q (want %s, %s or %s)", kind, KeyDistUniform, KeyDistZipfian, KeyDistLatest)
+	}
+	if params != "" {
+		k, v, ok := strings.Cut(params, "=")
+		f, err := strconv.ParseFloat(v, 64)
+		if !ok || k != "s" || err != nil {
+			return d, fmt.Errorf("%q: want s=SKEW", params)
+		}
+		d.S = f
+	}
+	if d.S <= 1 {
+		return d, fmt.Errorf("s must be > 1")
+	}
+	return d, nil
+}
+
+// String renders d in the form ParseKeyDist accepts.
+func (d KeyDist) String() string {
+	if d.Kind == "" || d.Kind == KeyDistUniform {
+		return KeyDistUniform
+	}
+	return fmt.Sprintf("%s:s=%g", d.Kind, d.S)
+}
+
+// zipfRanks draws zipfian popularity ranks for one reader. rand.NewZipf precomputes its constants, so the
+// sampler is built once for at least n ranks (doubling as n grows) rather than per draw, and a draw at or past n
+// is redrawn: a zipfian cut off at n is exactly the zipfian over [0, n). The zero value is ready to use; reset it
+// when the rng changes.
+type zipfRanks struct {
+	zipf *rand.Zipf
+	size uint64 // ranks zipf covers, [0, size)
+}
+
+// rank draws a popularity rank in [0, n) with exponent s: 0 is the hottest key.
+func (z *zipfRanks) rank(rng *rand.Rand, s float64, n int64) int64 {
+	if n <= 1 {
+		return 0
+	}
+	if z.zipf == nil || z.size < uint64(n) {
+		z.size = max(uint64(n), 2*z.size)
+		z.zipf = rand.NewZipf(rng, s, 1, z.size-1)
+	}
+	for {
+		if k := z.zipf.Uint64(); k < uint64(n) {
+			return int64(k)
+		}
+	}
+}
+
+// scatterRank maps a zipfian rank to a key index in [0, n), so hot keys are spread over the table instead of
+// being its oldest rows.
+func scatterRank(rank, n int64) int64 {
+	x := uint64(rank) * 0x9e3779b97f4a7c15
+	x ^= x >> 31
+	return int64(x % uint64(n))
+}
@@ -0,0 +1,171 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Latency distributions: averages hide the tail, so insert (per batch round trip) and query (per individual
+// query) latencies also go into HDR histograms, cumulative for the run and reset every progress interval.
+const (
+	latencyLowestMicros  = 1
+	latencyHighestMicros = int64(time.Hour / time.Microsecond) // longer values are clamped
+	latencySigFigs       = 3
+)
+
+// LatencyPercentiles summarizes one latency distribution in milliseconds.
+type LatencyPercentiles struct {
+	Count  int64   `json:"count"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	P999Ms float64 `json:"p99_9_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// String renders the percentiles as "p50 1.20 | p90 ... | max 9.80 ms".
+func (p *LatencyPercentiles) String() string {
+	if p == nil || p.Count == 0 {
+		return "no samples"
+	}
+	return fmt.Sprintf("p50 %.2f | p90 %.2f | p95 %.2f | p99 %.2f | p99.9 %.2f | max %.2f ms",
+		p.P50Ms, p.P90Ms, p.P95Ms, p.P99Ms, p.P999Ms, p.MaxMs)
+}
+
+// latencyRecorder holds a run-long, a per-interval, a per-load-step, a per-phase and a burst/non-burst histogram
+// of one operation type.
+type latencyRecorder struct {
+	mu       sync.Mutex
+	run      *hdrhistogram.Histogram
+	interval *hdrhistogram.Histogram
+	step     *hdrhistogram.Histogram
+	phases   [numPhases]*hdrhistogram.Histogram
+	windows  [2]*hdrhistogram.Histogram // [0] outside bursts, [1] during them
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	l := &latencyRecorder{
+		run:      newLatencyHistogram(),
+		interval: newLatencyHistogram(),
+		step:     newLatencyHistogram(),
+	}
+	for p := range l.phases {
+		l.phases[p] = newLatencyHistogram()
+	}
+	for w := range l.windows {
+		l.windows[w] = newLatencyHistogram()
+	}
+	return l
+}
+
+func newLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(latencyLowestMicros, latencyHighestMicros, latencySigFigs)
+}
+
+var (
+	insertLatencies = newLatencyRecorder() // successful InsertBatch calls
+	queryLatencies  = newLatencyRecorder() // individual queries
+	// endToEndLatencies holds per-record intended start → commit times, queue waits included (see Router.intended).
+	endToEndLatencies = newLatencyRecorder()
+)
+
+func (l *latencyRecorder) record(d time.Duration) {
+	l.recordN(d, 1)
+}
+
+// recordN records n samples of d.
+func (l *latencyRecorder) recordN(d time.Duration, n int64) {
+	v := min(max(d.Microseconds(), latencyLowestMicros), latencyHighestMicros)
+	l.mu.Lock()
+	l.run.RecordValues(v, n)
+	l.interval.RecordValues(v, n)
+	l.step.RecordValues(v, n)
+	l.windows[burstWindow()].RecordValues(v, n)
+	l.phases[currentPhase.Load()].RecordValues(v, n)
+	l.mu.Unlock()
+}
+
+// recordEndToEnd records n records committed now that were due at intended; zero intended (batches not routed
+// through a Router) is ignored.
+func recordEndToEnd(intended time.Time, n int) {
+	if intended.IsZero() || n <= 0 {
+		return
+	}
+	endToEndLatencies.recordN(time.Since(intended), int64(n))
+}
+
+// total summarizes the run so far; nil before the first sample.
+func (l *latencyRecorder) total() *LatencyPercentiles {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return percentilesOf(l.run)
+}
+
+// phase summarizes the samples recorded during phase p; nil when there were none.
+func (l *latencyRecorder) phase(p int) *LatencyPercentiles {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return percentilesOf(l.phases[p])
+}
+
+// resetRun drops the run-long samples so far (an excluded warmup); interval and phase histograms keep theirs.
+func (l *latencyRecorder) resetRun() {
+	l.mu.Lock()
+	l.run.Reset()
+	l.mu.Unlock()
+}
+
+// window summarizes the samples recorded during bursts (burst) or outside them; nil when there were none.
+func (l *latencyRecorder) window(burst bool) *LatencyPercentiles {
+	w := 0
+	if burst {
+		w = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return percentilesOf(l.windows[w])
+}
+
+// takeInterval summarizes the samples since the previous call and starts a new interval.
+func (l *latencyRecorder) takeInterval() *LatencyPercentiles {
+	return l.take(l.interval)
+}
+
+// takeStep summarizes the samples since the previous call and starts a new load step.
+func (l *latencyRecorder) takeStep() *LatencyPercentiles {
+	return l.take(l.step)
+}
+
+func (l *latencyRecorder) take(h *hdrhistogram.Histogram) *LatencyPercentiles {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := percentilesOf(h)
+	h.Reset()
+	return p
+}
+
+func percentilesOf(h *hdrhistogram.Histogram) *LatencyPercentiles {
+	if h.TotalCount() == 0 {
+		return nil
+	}
+	ms := func(v int64) float64 { return float64(v) / 1000 }
+	return &LatencyPercentiles{
+		Count:  h.TotalCount(),
+		P50Ms:  ms(h.ValueAtQuantile(50)),
+		P90Ms:  ms(h.ValueAtQuantile(90)),
+		P95Ms:  ms(h.ValueAtQuantile(95)),
+		P99Ms:  ms(h.ValueAtQuantile(99)),
+		P999Ms: ms(h.ValueAtQuantile(99.9)),
+		MaxMs:  ms(h.Max()),
+	}
+}
+
+// AddQueryLatency records one individual query's latency for the percentiles. QueryRunner records its own
+// queries; workers that run lookups outside it call this next to AddQuery.
+func AddQueryLatency(d time.Duration) {
+	queryLatencies.record(d)
+}
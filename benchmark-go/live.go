@@ -0,0 +1,243 @@
+package benchmarkgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// liveConfigPollInterval is how often the watched live config file is checked for changes.
+const liveConfigPollInterval = time.Second
+
+// LiveSettings is a change to the running workload, read from the watched live config file (JSON).
+// Omitted fields keep their current value.
+type LiveSettings struct {
+	TargetRPS        *int               `json:"target_rps,omitempty"`
+	QueriesPerRecord *int               `json:"queries_per_record,omitempty"` // read/write mix: lookups per inserted record
+	QueryWeights     map[string]float64 `json:"query_weights,omitempty"`      // query type → relative weight
+}
+
+// weightedQueryType is one entry of the cumulative weight table used to pick a query type.
+type weightedQueryType struct {
+	queryType string
+	cumWeight float64
+}
+
+// LiveWorkload holds the workload knobs query workers read on every record; the runner updates them mid-run.
+type LiveWorkload struct {
+	queriesPerRecord atomic.Int64
+	weights          atomic.Pointer[[]weightedQueryType]
+}
+
+// NewLiveWorkload creates a LiveWorkload with the starting queries per record and no query-type weights.
+func NewLiveWorkload(queriesPerRecord int) *LiveWorkload {
+	lw := &LiveWorkload{}
+	lw.queriesPerRecord.Store(int64(queriesPerRecord))
+	return lw
+}
+
+// QueriesPerRecord returns the current lookups per inserted record.
+func (lw *LiveWorkload) QueriesPerRecord() int {
+	return int(lw.queriesPerRecord.Load())
+}
+
+// HasQueryWeights reports whether query-type weights are currently set.
+func (lw *LiveWorkload) HasQueryWeights() bool {
+	table := lw.weights.Load()
+	return table != nil && len(*table) > 0
+}
+
+// PickQueryType returns a query type drawn by the current weights, or "" when no weights are set.
+func (lw *LiveWorkload) PickQueryType() string {
+	table := lw.weights.Load()
+	if table == nil || len(*table) == 0 {
+		return ""
+	}
+	t := *table
+	x := rand.Float64() * t[len(t)-1].cumWeight
+	for _, w := range t {
+		if x < w.cumWeight {
+			return w.queryType
+		}
+	}
+	return t[len(t)-1].queryType
+}
+
+// SetQueryWeights replaces the query-type weights; an empty map clears them (back to the configured query type).
+func (lw *LiveWorkload) SetQueryWeights(weights map[string]float64) error {
+	table, err := weightTable(weights)
+	if err != nil {
+		return err
+	}
+	lw.weights.Store(&table)
+	return nil
+}
+
+// weightTable validates weights and builds their cumulative table.
+func weightTable(weights map[string]float64) ([]weightedQueryType, error) {
+	types := make([]string, 0, len(weights))
+	for qt, w := range weights {
+		if !IsQueryType(qt) {
+			return nil, fmt.Errorf("unknown query type %q", qt)
+		}
+		if w < 0 {
+			return nil, fmt.Errorf("negative weight for %q", qt)
+		}
+		types = append(types, qt)
+	}
+	sort.Strings(types)
+	table := make([]weightedQueryType, 0, len(types))
+	var cum float64
+	for _, qt := range types {
+		if weights[qt] == 0 {
+			continue
+		}
+		cum += weights[qt]
+		table = append(table, weightedQueryType{queryType: qt, cumWeight: cum})
+	}
+	return table, nil
+}
+
+// Event is a timestamped entry in the run timeline (settings changes, annotations).
+type Event struct {
+	AtSec  float64   `json:"at_sec"` // seconds since run start
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail"`
+}
+
+// eventLog collects timeline events from concurrent goroutines.
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (l *eventLog) add(runStart time.Time, kind, detail string) {
+	now := time.Now()
+	l.mu.Lock()
+	l.events = append(l.events, Event{AtSec: now.Sub(runStart).Seconds(), Time: now, Kind: kind, Detail: detail})
+	l.mu.Unlock()
+	log.Printf("Event [%s] %s", kind, detail)
+}
+
+func (l *eventLog) list() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Event(nil), l.events...)
+}
+
+// ApplyLiveSettings applies a workload change to the running load and records it in the timeline. Every field is
+// checked before any is applied, so a rejected change leaves the run as it was.
+func (r *LoadRunner) ApplyLiveSettings(s LiveSettings, source string) error {
+	var table []weightedQueryType
+	if s.QueryWeights != nil {
+		var err error
+		if table, err = weightTable(s.QueryWeights); err != nil {
+			return err
+		}
+	}
+	if s.TargetRPS != nil {
+		if *s.TargetRPS <= 0 {
+			return fmt.Errorf("target_rps must be > 0")
+		}
+		if r.Config.Mode == ModeClosed {
+			return fmt.Errorf("target_rps cannot be set in closed-loop mode")
+		}
+		if c := r.rateController(); c != "" {
+			return fmt.Errorf("target_rps cannot be set while %s controls the rate", c)
+		}
+	}
+	if s.QueriesPerRecord != nil {
+		if *s.QueriesPerRecord < 0 {
+			return fmt.Errorf("queries_per_record must be >= 0")
+		}
+		if r.Config.QueriesPerRecord == 0 && *s.QueriesPerRecord > 0 {
+			return fmt.Errorf("queries_per_record cannot be raised from 0: query workers were not started")
+		}
+	}
+
+	var changes []string
+	if s.QueryWeights != nil {
+		r.live.weights.Store(&table)
+		changes = append(changes, fmt.Sprintf("query_weights=%v", s.QueryWeights))
+	}
+	if s.TargetRPS != nil {
+		r.rateLimiter.SetLimit(rate.Limit(*s.TargetRPS))
+		targetRPS.Store(int64(*s.TargetRPS))
+		changes = append(changes, fmt.Sprintf("target_rps=%d", *s.TargetRPS))
+	}
+	if s.QueriesPerRecord != nil {
+		r.live.queriesPerRecord.Store(int64(*s.QueriesPerRecord))
+		changes = append(changes, fmt.Sprintf("queries_per_record=%d", *s.QueriesPerRecord))
+	}
+	if len(changes) > 0 {
+		r.events.add(r.runStart, "settings", source+": "+strings.Join(changes, " "))
+	}
+	return nil
+}
+
+// rateController names the option that sets the target rate during the run (ramp, pattern, load steps or find-max),
+// or returns "" when the rate is only changed by live settings.
+func (r *LoadRunner) rateController() string {
+	switch {
+	case r.Config.Ramp.Enabled():
+		return "the ramp"
+	case r.Config.Pattern.Shape != "":
+		return "the load pattern"
+	case len(r.Config.LoadSteps) > 0:
+		return "the load steps"
+	case r.Config.FindMax.Enabled:
+		return "find-max"
+	}
+	return ""
+}
+
+// watchLiveConfig polls path and applies its LiveSettings whenever the file's modification time changes.
+func (r *LoadRunner) watchLiveConfig(ctx context.Context, path string) {
+	var lastMod time.Time
+	if st, err := os.Stat(path); err == nil {
+		lastMod = st.ModTime()
+		r.loadLiveConfig(path)
+	}
+	ticker := time.NewTicker(liveConfigPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			st, err := os.Stat(path)
+			if err != nil || !st.ModTime().After(lastMod) {
+				continue
+			}
+			lastMod = st.ModTime()
+			r.loadLiveConfig(path)
+		}
+	}
+}
+
+func (r *LoadRunner) loadLiveConfig(path string) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Live config %s: %v", path, err)
+		return
+	}
+	var s LiveSettings
+	if err := json.Unmarshal(b, &s); err != nil {
+		log.Printf("Live config %s: %v", path, err)
+		return
+	}
+	if err := r.ApplyLiveSettings(s, "live config "+path); err != nil {
+		log.Printf("Live config %s: %v", path, err)
+	}
+}
@@ -0,0 +1,163 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Stepped load: successive target rates held for a fixed time each, with throughput and latency reported per
+// step, to find the highest rate the database sustains in a single run.
+
+// loadStepSustainedPct is the share of a step's target that committed throughput must reach to count as sustained.
+const loadStepSustainedPct = 95
+
+// LoadStep is one rate held for DurationSec.
+type LoadStep struct {
+	RPS         int
+	DurationSec float64
+}
+
+// ParseLoadSteps parses "RPS:SECONDS[,RPS:SECONDS...]", e.g. "1000:60,2000:60,4000:60".
+func ParseLoadSteps(s string) ([]LoadStep, error) {
+	var steps []LoadStep
+	for _, part := range strings.Split(s, ",") {
+		rps, sec, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("load step %q: want RPS:SECONDS", part)
+		}
+		n, err := strconv.Atoi(rps)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("load step %q: rate must be a positive integer", part)
+		}
+		d, err := strconv.ParseFloat(sec, 64)
+		if err != nil || !(d > 0) { // !(d > 0) also rejects NaN
+			return nil, fmt.Errorf("load step %q: seconds must be > 0", part)
+		}
+		steps = append(steps, LoadStep{RPS: n, DurationSec: d})
+	}
+	return steps, nil
+}
+
+// LoadStepStats is one step's throughput and latency.
+type LoadStepStats struct {
+	Step          int                 `json:"step"`
+	TargetRPS     int                 `json:"target_rps"`
+	StartSec      float64             `json:"start_sec"` // since run start
+	DurationSec   float64             `json:"duration_sec"`
+	Rows          int64               `json:"rows"`
+	RowsPerSec    float64             `json:"rows_per_sec"`
+	Queries       int64               `json:"queries"`
+	QueriesPerSec float64             `json:"queries_per_sec"`
+	QueriesFailed int64               `json:"queries_failed"`
+	Sustained     bool                `json:"sustained"`                // rows_per_sec reached loadStepSustainedPct of the target
+	Insert        *LatencyPercentiles `json:"insert_latency,omitempty"` // per batch
+	EndToEnd      *LatencyPercentiles `json:"end_to_end_latency,omitempty"`
+	Query         *LatencyPercentiles `json:"query_latency,omitempty"`
+}
+
+// LoadStepReport lists the steps run and the highest sustained rate.
+type LoadStepReport struct {
+	Steps           []LoadStepStats `json:"steps"`
+	MaxSustainedRPS int             `json:"max_sustained_rps"` // 0 if no step was sustained
+}
+
+// runLoadSteps sets each step's rate in turn and measures it, until the steps run out or ctx ends.
+func (r *LoadRunner) runLoadSteps(ctx context.Context) {
+	rep := &LoadStepReport{}
+	defer func() { r.loadSteps = rep }()
+	for i, step := range r.Config.LoadSteps {
+		if i > 0 {
+			r.events.add(r.runStart, "load_step", fmt.Sprintf("step %d: %d rows/sec for %gs", i+1, step.RPS, step.DurationSec))
+		}
+		st, ok := r.measureStep(ctx, i+1, step)
+		if st.Sustained {
+			rep.MaxSustainedRPS = max(rep.MaxSustainedRPS, step.RPS)
+		}
+		rep.Steps = append(rep.Steps, st)
+		if !ok {
+			return
+		}
+	}
+}
+
+// measureStep holds step's rate and measures it; ok is false when ctx ended first (the stats then cover the
+// part of the step that ran).
+func (r *LoadRunner) measureStep(ctx context.Context, index int, step LoadStep) (st LoadStepStats, ok bool) {
+	r.rateLimiter.SetLimit(rate.Limit(step.RPS))
+	targetRPS.Store(int64(step.RPS))
+	for _, l := range []*latencyRecorder{insertLatencies, queryLatencies, endToEndLatencies} {
+		l.takeStep()
+	}
+	start := time.Now()
+	rows, queries, failed := insertTotal.Load(), queryCount.Load(), queryFailed.Load()
+	timer := time.NewTimer(time.Duration(step.DurationSec * float64(time.Second)))
+	ok = true
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		ok = false
+	case <-timer.C:
+	}
+	dur := time.Since(start).Seconds()
+	st = LoadStepStats{
+		Step:          index,
+		TargetRPS:     step.RPS,
+		StartSec:      start.Sub(r.runStart).Seconds(),
+		DurationSec:   dur,
+		Rows:          insertTotal.Load() - rows,
+		Queries:       queryCount.Load() - queries,
+		QueriesFailed: queryFailed.Load() - failed,
+		Insert:        insertLatencies.takeStep(),
+		EndToEnd:      endToEndLatencies.takeStep(),
+		Query:         queryLatencies.takeStep(),
+	}
+	if dur > 0 {
+		st.RowsPerSec = float64(st.Rows) / dur
+		st.QueriesPerSec = float64(st.Queries) / dur
+	}
+	st.Sustained = st.RowsPerSec >= float64(step.RPS)*loadStepSustainedPct/100
+	return st, ok
+}
+
+// logLoadSteps logs each step's achieved rate and latency and the highest sustained rate.
+func logLoadSteps(rep *LoadStepReport) {
+	if rep == nil || len(rep.Steps) == 0 {
+		return
+	}
+	log.Printf("Load steps (sustained = at least %d%% of the target committed):", loadStepSustainedPct)
+	logStepTable(rep.Steps)
+	if rep.MaxSustainedRPS > 0 {
+		log.Printf("  Max sustained rate: %d rows/sec", rep.MaxSustainedRPS)
+	} else {
+		log.Printf("  %sNo step sustained its target rate%s", _colorYellow, _colorReset)
+	}
+}
+
+// logStepTable logs one row per step: target and achieved rate and latency percentiles.
+func logStepTable(steps []LoadStepStats) {
+	log.Printf("  step  target rows/s  actual rows/s  queries/s  sustained  insert p50/p99 ms  e2e p99 ms  query p50/p99 ms")
+	p50p99 := func(p *LatencyPercentiles) string {
+		if p == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f/%.2f", p.P50Ms, p.P99Ms)
+	}
+	for _, s := range steps {
+		e2e := "-"
+		if s.EndToEnd != nil {
+			e2e = fmt.Sprintf("%.2f", s.EndToEnd.P99Ms)
+		}
+		sustained := "no"
+		if s.Sustained {
+			sustained = "yes"
+		}
+		log.Printf("  %4d %14d %14.1f %10.1f %10s %18s %11s %17s",
+			s.Step, s.TargetRPS, s.RowsPerSec, s.QueriesPerSec, sustained, p50p99(s.Insert), e2e, p50p99(s.Query))
+	}
+}
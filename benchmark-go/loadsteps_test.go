@@ -0,0 +1,40 @@
+package benchmarkgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLoadSteps(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []LoadStep
+		wantErr bool
+	}{
+		{in: "1000:60", want: []LoadStep{{RPS: 1000, DurationSec: 60}}},
+		{in: "1000:60,2000:30.5, 4000:1", want: []LoadStep{{1000, 60}, {2000, 30.5}, {4000, 1}}},
+		{in: "", wantErr: true},
+		{in: "1000", wantErr: true},
+		{in: "1000:60,", wantErr: true},
+		{in: ":60", wantErr: true},
+		{in: "1000:", wantErr: true},
+		{in: "1000:60s", wantErr: true}, // plain seconds only
+		{in: "1k:60", wantErr: true},
+		{in: "1000.5:60", wantErr: true},
+		{in: "0:60", wantErr: true},
+		{in: "-1000:60", wantErr: true},
+		{in: "1000:0", wantErr: true},
+		{in: "1000:-60", wantErr: true},
+		{in: "1000:NaN", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLoadSteps(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLoadSteps(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLoadSteps(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// estimatedRecordBytes approximates one queued Record: the JSON message without SOURCE, which is referenced by pool index.
+const estimatedRecordBytes = 2 * 1024
+
+// estimatedExpandedRecordBytes approximates one record in a batch being inserted, with its SOURCE payload spliced in.
+const estimatedExpandedRecordBytes = payloadSize + estimatedRecordBytes
+
+// CgroupMemoryLimit returns the container memory limit in bytes from cgroup v2 memory.max (or v1 limit_in_bytes), or 0 if unlimited/unknown.
+func CgroupMemoryLimit() int64 {
+	for _, path := range []string{"/sys/fs/cgroup/memory.max", "/sys/fs/cgroup/memory/memory.limit_in_bytes"} {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		s := strings.TrimSpace(string(b))
+		if s == "max" {
+			return 0
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		// cgroup v1 reports "unlimited" as a huge page-aligned number.
+		if err != nil || v <= 0 || v >= 1<<60 {
+			return 0
+		}
+		return v
+	}
+	return 0
+}
+
+// ParseByteSize parses sizes like "512MiB", "4GiB", "2G", "1500000" into bytes.
+func ParseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	units := []struct {
+		suffix string
+		mult   float64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+		{"B", 1},
+	}
+	mult := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(v * mult), nil
+}
+
+// FormatBytes renders n as a human-readable MiB/GiB string.
+func FormatBytes(n int64) string {
+	if n >= 1<<30 {
+		return fmt.Sprintf("%.2f GiB", float64(n)/(1<<30))
+	}
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
+
+// MemoryLimit returns the current Go soft memory limit, or 0 if none is set.
+func MemoryLimit() int64 {
+	limit := debug.SetMemoryLimit(-1)
+	if limit == math.MaxInt64 {
+		return 0
+	}
+	return limit
+}
+
+// queueByteBudget estimates memory held by records sitting in the producer and worker queues plus one expanded batch in flight per worker.
+func queueByteBudget(producerQueueCap, workers, workerQueue, batchSize int) int64 {
+	queued := int64(producerQueueCap+workers*workerQueue) * int64(batchSize) * estimatedRecordBytes
+	inFlight := int64(workers) * int64(batchSize) * estimatedExpandedRecordBytes
+	return queued + inFlight
+}
@@ -0,0 +1,186 @@
+package benchmarkgo
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metric kinds.
+const (
+	MetricCounter = "counter"
+	MetricGauge   = "gauge"
+)
+
+// Metric describes one exported Prometheus series. The /metrics endpoint and the Grafana dashboard generator
+// both iterate Metrics, so dashboards always query the names and labels the runner actually exposes.
+type Metric struct {
+	Name   string   // full Prometheus name
+	Help   string   // HELP text, also the panel description
+	Kind   string   // MetricCounter or MetricGauge
+	Labels []string // label names, in the order collect emits values
+	Group  string   // dashboard the panel belongs to
+	Unit   string   // Grafana unit of the panel (rate of a counter, or the gauge value)
+	// PerUnitOf names a counter this one is divided by for an extra "average" panel,
+	// e.g. insert latency seconds per inserted row.
+	PerUnitOf string
+	collect   func(emit func(value float64, labelValues ...string))
+}
+
+// Dashboard groups.
+const (
+	groupInserts = "Inserts"
+	groupQueries = "Queries"
+	groupErrors  = "Errors"
+	groupClient  = "Client"
+)
+
+// Metrics is the registry of everything the runner exports.
+var Metrics = []Metric{
+	{
+		Name: "loadrunner_inserted_rows_total", Help: "Rows inserted, by generator intent (original or duplicate).",
+		Kind: MetricCounter, Labels: []string{"kind"}, Group: groupInserts, Unit: "rows/s",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(insertOriginals.Load()), "original")
+			emit(float64(insertDuplicates.Load()), "duplicate")
+		},
+	},
+	{
+		Name: "loadrunner_insert_latency_seconds_total", Help: "Sum of insert statement latency.",
+		Kind: MetricCounter, Group: groupInserts, Unit: "s", PerUnitOf: "loadrunner_inserted_rows_total",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(insertLatencyMicros.Load()) / 1e6)
+		},
+	},
+	{
+		Name: "loadrunner_insert_statements_total", Help: "Insert statements executed.",
+		Kind: MetricCounter, Group: groupInserts, Unit: "ops",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(insertStatements.Load()))
+		},
+	},
+	{
+		Name: "loadrunner_inserted_bytes_total", Help: "Message bytes sent in successful inserts.",
+		Kind: MetricCounter, Group: groupInserts, Unit: "Bps",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(insertBytes.Load()))
+		},
+	},
+	{
+		Name: "loadrunner_insert_batches_started_total", Help: "Batches handed from the router to insert workers.",
+		Kind: MetricCounter, Group: groupInserts, Unit: "ops",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(insertStarted.Load()))
+		},
+	},
+	{
+		Name: "loadrunner_upsert_outcome_rows_total", Help: "Rows the database reports as newly inserted or updated on conflict.",
+		Kind: MetricCounter, Labels: []string{"outcome"}, Group: groupInserts, Unit: "rows/s",
+		collect: func(emit func(float64, ...string)) {
+			if !upsertReported.Load() {
+				return
+			}
+			emit(float64(upsertInserted.Load()), "inserted")
+			emit(float64(upsertUpdated.Load()), "updated")
+		},
+	},
+	{
+		Name: "loadrunner_target_rows_per_second", Help: "Current target insert rate (changes with live settings).",
+		Kind: MetricGauge, Group: groupInserts, Unit: "rows/s",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(targetRPS.Load()))
+		},
+	},
+	{
+		Name: "loadrunner_queries_total", Help: "Queries executed.",
+		Kind: MetricCounter, Group: groupQueries, Unit: "reqps",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(queryCount.Load()))
+		},
+	},
+	{
+		Name: "loadrunner_query_failures_total", Help: "Queries that errored or returned no rows.",
+		Kind: MetricCounter, Group: groupQueries, Unit: "reqps",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(queryFailed.Load()))
+		},
+	},
+	{
+		Name: "loadrunner_query_latency_seconds_total", Help: "Sum of query latency.",
+		Kind: MetricCounter, Group: groupQueries, Unit: "s", PerUnitOf: "loadrunner_queries_total",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(queryLatencyMicros.Load()) / 1e6)
+		},
+	},
+	{
+		Name: "loadrunner_errors_total", Help: "Backend errors by operation and native code (SQLSTATE, ClickHouse exception, ...).",
+		Kind: MetricCounter, Labels: []string{"op", "code"}, Group: groupErrors, Unit: "ops",
+		collect: func(emit func(float64, ...string)) {
+			for _, e := range loadErrors() {
+				emit(float64(e.Count), e.Op, e.Code)
+			}
+		},
+	},
+	{
+		Name: "loadrunner_client_cpu_seconds_total", Help: "CPU time used by the load generator process (user + system).",
+		Kind: MetricCounter, Group: groupClient, Unit: "s",
+		collect: func(emit func(float64, ...string)) {
+			emit(processCPUSeconds())
+		},
+	},
+	{
+		Name: "loadrunner_client_resident_bytes", Help: "Resident set size of the load generator process.",
+		Kind: MetricGauge, Group: groupClient, Unit: "bytes",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(processRSSBytes()))
+		},
+	},
+	{
+		Name: "loadrunner_client_goroutines", Help: "Goroutines in the load generator process.",
+		Kind: MetricGauge, Group: groupClient, Unit: "short",
+		collect: func(emit func(float64, ...string)) {
+			emit(float64(runtime.NumGoroutine()))
+		},
+	},
+}
+
+// registryCollector exposes Metrics through the Prometheus client.
+type registryCollector struct {
+	descs []*prometheus.Desc
+}
+
+func newRegistryCollector() *registryCollector {
+	c := &registryCollector{descs: make([]*prometheus.Desc, len(Metrics))}
+	for i, m := range Metrics {
+		c.descs[i] = prometheus.NewDesc(m.Name, m.Help, m.Labels, nil)
+	}
+	return c
+}
+
+func (c *registryCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *registryCollector) Collect(ch chan<- prometheus.Metric) {
+	for i, m := range Metrics {
+		vt := prometheus.GaugeValue
+		if m.Kind == MetricCounter {
+			vt = prometheus.CounterValue
+		}
+		desc := c.descs[i]
+		m.collect(func(v float64, labelValues ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, vt, v, labelValues...)
+		})
+	}
+}
+
+// MetricsHandler returns an http.Handler serving Metrics (plus Go runtime metrics) in Prometheus text format.
+func MetricsHandler() http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(newRegistryCollector(), prometheus.NewGoCollector())
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
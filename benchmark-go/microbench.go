@@ -0,0 +1,120 @@
+package benchmarkgo
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Microbench is one serialization-layer benchmark. Setup builds inputs for batchSize rows and returns the
+// operation to time; one op processes the whole batch, as an insert worker does.
+type Microbench struct {
+	Name  string
+	Setup func(batchSize int) func()
+}
+
+// MicrobenchResult is the throughput and allocation profile of one Microbench.
+type MicrobenchResult struct {
+	Name        string
+	Ops         int64
+	NsPerOp     float64
+	NsPerRow    float64
+	AllocsPerOp float64
+	BytesPerOp  float64
+}
+
+var (
+	microbenchMu sync.Mutex
+	microbenches []Microbench
+)
+
+// RegisterMicrobench adds a benchmark to the microbench subcommand (backends call this from init).
+func RegisterMicrobench(m Microbench) {
+	microbenchMu.Lock()
+	microbenches = append(microbenches, m)
+	microbenchMu.Unlock()
+}
+
+// Microbenches returns the registered benchmarks sorted by name.
+func Microbenches() []Microbench {
+	microbenchMu.Lock()
+	defer microbenchMu.Unlock()
+	out := append([]Microbench(nil), microbenches...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// MicrobenchRows returns n original records with their payloads expanded, as insert workers pass them to InsertBatch.
+func MicrobenchRows(n int) []RowForDB {
+	rows := make([]RowForDB, n)
+	for i := range rows {
+		p := GenerateOnePatient(i, true)
+		jsonMsg, _ := p.ToJSONRef()
+		rows[i] = RowForDB{p.PatientID, patientMessageType, ExpandPayload(jsonMsg, p.PayloadIndex)}
+	}
+	return rows
+}
+
+// RunMicrobench repeats m's op for at least d (after one warm-up op) and reports ns and heap allocations per op.
+func RunMicrobench(m Microbench, batchSize int, d time.Duration) MicrobenchResult {
+	op := m.Setup(batchSize)
+	op()
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	var ops int64
+	start := time.Now()
+	for ops == 0 || time.Since(start) < d {
+		op()
+		ops++
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	res := MicrobenchResult{
+		Name:        m.Name,
+		Ops:         ops,
+		NsPerOp:     float64(elapsed.Nanoseconds()) / float64(ops),
+		AllocsPerOp: float64(after.Mallocs-before.Mallocs) / float64(ops),
+		BytesPerOp:  float64(after.TotalAlloc-before.TotalAlloc) / float64(ops),
+	}
+	if batchSize > 0 {
+		res.NsPerRow = res.NsPerOp / float64(batchSize)
+	}
+	return res
+}
+
+func init() {
+	RegisterMicrobench(Microbench{Name: "generate/GenerateOnePatient+ToJSONRef", Setup: func(batchSize int) func() {
+		return func() {
+			for i := 0; i < batchSize; i++ {
+				p := GenerateOnePatient(i, true)
+				_, _ = p.ToJSONRef()
+			}
+		}
+	}})
+	RegisterMicrobench(Microbench{Name: "json/ToJSON (full payload)", Setup: func(batchSize int) func() {
+		patients := make([]PatientRecord, batchSize)
+		for i := range patients {
+			patients[i] = GenerateOnePatient(i, true)
+		}
+		return func() {
+			for _, p := range patients {
+				_, _ = p.ToJSON()
+			}
+		}
+	}})
+	RegisterMicrobench(Microbench{Name: "payload/ExpandPayload", Setup: func(batchSize int) func() {
+		records := make([]Record, batchSize)
+		for i := range records {
+			p := GenerateOnePatient(i, true)
+			jsonMsg, _ := p.ToJSONRef()
+			records[i] = Record{JSONMessage: jsonMsg, PayloadIndex: p.PayloadIndex}
+		}
+		return func() {
+			for _, r := range records {
+				_ = ExpandPayload(r.JSONMessage, r.PayloadIndex)
+			}
+		}
+	}})
+}
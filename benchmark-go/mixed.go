@@ -0,0 +1,207 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Workloads: where query jobs come from.
+const (
+	WorkloadWriteTriggered = "write-triggered" // each inserted record is looked up QueriesPerRecord times (default)
+	WorkloadMixed          = "mixed"           // reads run at their own rate against any loaded MRN, for an R/W mix
+)
+
+// ValidateWorkload returns an error if workload is unknown or readRatio does not suit it.
+func ValidateWorkload(workload string, readRatio float64) error {
+	switch workload {
+	case WorkloadWriteTriggered:
+		return nil
+	case WorkloadMixed:
+		if readRatio <= 0 || readRatio >= 1 {
+			return fmt.Errorf("read ratio must be between 0 and 1 (exclusive) for %s, got %g", WorkloadMixed, readRatio)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown workload %q (want %s or %s)", workload, WorkloadWriteTriggered, WorkloadMixed)
+}
+
+// maxLoadedKeys bounds the reservoir of committed patient IDs that mixed-workload reads draw from.
+const maxLoadedKeys = 100000
+
+// loadedKeys is a uniform sample of the patients committed this run plus the ordinals already in the table
+// (0..preexisting-1); guarded by mu. Under KeyDistLatest it keeps the most recent patients instead, in a ring
+// whose next slot is next.
+type loadedKeys struct {
+	mu          sync.Mutex
+	rng         *rand.Rand
+	dist        KeyDist
+	ranks       zipfRanks // zipfian sampler over rng, reused across reads
+	preexisting int64
+	seen        int64
+	patientIDs  []string
+	next        int
+}
+
+var (
+	mixedWorkload atomic.Bool // insert workers feed loaded while set
+	loaded        loadedKeys
+)
+
+// startLoadedKeys resets the sample; ordinals below preexisting are assumed to be in the table already.
+func startLoadedKeys(preexisting int, seed int64, dist KeyDist) {
+	loaded.mu.Lock()
+	loaded.rng = rand.New(rand.NewSource(seed))
+	loaded.dist = dist
+	loaded.ranks = zipfRanks{}
+	loaded.preexisting = int64(max(preexisting, 0))
+	loaded.seen = 0
+	loaded.patientIDs = nil
+	loaded.next = 0
+	loaded.mu.Unlock()
+	mixedWorkload.Store(true)
+}
+
+// noteLoaded adds the originals of a committed batch to the sample (reservoir sampling past maxLoadedKeys).
+func noteLoaded(batch []*Record) {
+	if !mixedWorkload.Load() {
+		return
+	}
+	loaded.mu.Lock()
+	defer loaded.mu.Unlock()
+	for _, rec := range batch {
+		if rec == nil || !rec.IsOriginal {
+			continue
+		}
+		loaded.seen++
+		if len(loaded.patientIDs) < maxLoadedKeys {
+			loaded.patientIDs = append(loaded.patientIDs, rec.PatientID)
+			loaded.next = len(loaded.patientIDs) % maxLoadedKeys
+		} else if loaded.dist.Kind == KeyDistLatest {
+			loaded.patientIDs[loaded.next] = rec.PatientID
+			loaded.next = (loaded.next + 1) % maxLoadedKeys
+		} else if i := loaded.rng.Int63n(loaded.seen); i < maxLoadedKeys {
+			loaded.patientIDs[i] = rec.PatientID
+		}
+	}
+}
+
+// KeySampler is implemented by WorkerCtx backends that can list up to limit distinct patient IDs of generated
+// patients in the table. Reads of existing data draw from that sample instead of the ordinal range, which has
+// gaps where duplicates consumed indexes.
+type KeySampler interface {
+	SampleKeys(ctx context.Context, limit int) ([]string, error)
+}
+
+// startReadKeys resets the sample for reads of existing data and returns how many patients it starts with: a
+// sample from the table when the backend is a KeySampler, else ordinals 0..maxCounter. A table sample has no
+// insert order, so KeyDistLatest treats its patients as equally old.
+func (r *LoadRunner) startReadKeys(maxCounter int, seed int64) int {
+	dist := r.Config.KeyDist
+	ks, ok := r.WorkerCtx.(KeySampler)
+	if !ok {
+		startLoadedKeys(maxCounter+1, seed, dist)
+		return maxCounter + 1
+	}
+	startLoadedKeys(0, seed, dist)
+	if maxCounter < 0 {
+		return 0
+	}
+	ids, err := ks.SampleKeys(context.Background(), maxLoadedKeys)
+	if err != nil {
+		log.Printf("Warning: sampling loaded patients failed, reading ordinals 0..%d instead: %v", maxCounter, err)
+		startLoadedKeys(maxCounter+1, seed, dist)
+		return maxCounter + 1
+	}
+	loaded.mu.Lock()
+	loaded.patientIDs = ids
+	loaded.seen = int64(len(ids))
+	loaded.next = len(ids) % maxLoadedKeys
+	loaded.mu.Unlock()
+	return len(ids)
+}
+
+// pickLoaded returns a query job for a loaded patient drawn from the key distribution; nil before anything is
+// loaded.
+func pickLoaded() *QueryJob {
+	loaded.mu.Lock()
+	defer loaded.mu.Unlock()
+	total := loaded.preexisting + loaded.seen
+	if total == 0 {
+		return nil
+	}
+	held := int64(len(loaded.patientIDs))
+	n := loaded.preexisting + held
+	var pid string
+	switch loaded.dist.Kind {
+	case KeyDistZipfian:
+		pid = loaded.key(scatterRank(loaded.ranks.rank(loaded.rng, loaded.dist.S, n), n))
+	case KeyDistLatest:
+		// Rank 0 is the newest patient: back through the ring, then down from the highest preexisting ordinal.
+		if rank := loaded.ranks.rank(loaded.rng, loaded.dist.S, n); rank < held {
+			pid = loaded.patientIDs[(int64(loaded.next)-1-rank+held)%held]
+		} else {
+			pid = loaded.key(loaded.preexisting - 1 - (rank - held))
+		}
+	default:
+		if i := loaded.rng.Int63n(total); i < loaded.preexisting {
+			pid = loaded.key(i)
+		} else {
+			pid = loaded.patientIDs[loaded.rng.Intn(len(loaded.patientIDs))]
+		}
+	}
+	return &QueryJob{MRN: mrnForPatientID(pid), PatientID: pid}
+}
+
+// key is the patient at index i: the preexisting ordinals, then the held patients.
+func (k *loadedKeys) key(i int64) string {
+	if i < k.preexisting {
+		return "patient-" + formatOrdinal(int(i))
+	}
+	return k.patientIDs[i-k.preexisting]
+}
+
+// readRate is the mixed workload's read rate: reads/(reads+writes) = readRatio at the target write rate.
+func readRate(targetRPS int, readRatio float64) float64 {
+	return float64(targetRPS) * readRatio / (1 - readRatio)
+}
+
+// runReads enqueues one lookup of a loaded patient per read at readsPerSec(target) until ctx ends, where target
+// follows live and stepped changes to the target rate.
+func (r *LoadRunner) runReads(ctx context.Context, readsPerSec func(target int) float64) {
+	limiter := rate.NewLimiter(rate.Limit(readsPerSec(r.Config.TargetRPS)), 1)
+	for {
+		if err := limiter.Wait(ctx); err != nil {
+			return
+		}
+		limiter.SetLimit(rate.Limit(readsPerSec(int(targetRPS.Load()))))
+		job := pickLoaded()
+		if job == nil {
+			if !sleepCtx(ctx, 10*time.Millisecond) {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case r.queryQueue <- job:
+		}
+	}
+}
+
+// logMixed logs the achieved read share next to the configured one.
+func logMixed(readRatio float64, snapshot Snapshot, elapsed float64) {
+	reads, writes := snapshot.Queries.Count, snapshot.Inserted.Total
+	if reads+writes == 0 || elapsed <= 0 {
+		return
+	}
+	log.Printf("Mixed workload: %.1f reads/sec, %.1f writes/sec | read share %.1f%% (target %.1f%%)",
+		reads/elapsed, writes/elapsed, reads/(reads+writes)*100, readRatio*100)
+}
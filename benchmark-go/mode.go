@@ -0,0 +1,36 @@
+package benchmarkgo
+
+import "fmt"
+
+// Run modes: which sides of the load run.
+const (
+	ModeInsertQuery = "insert-query" // inserts at the target rate plus the Workload's queries (default)
+	ModeQueryOnly   = "query-only"   // no inserts: lookups of the MRNs already in the table at TargetRPS queries/sec
+	ModeInsertOnly  = "insert-only"  // no queries: no query queue or workers, and no MRN extraction after inserts
+	ModeClosed      = "closed"       // closed loop: Concurrency batches in flight, inserted as fast as the database allows
+)
+
+// closedConcurrency is the batches in flight of a ModeClosed run; 0 for the rate-driven modes.
+func (c *Config) closedConcurrency() int {
+	if c.Mode != ModeClosed {
+		return 0
+	}
+	return c.Workers
+}
+
+// targetLabel describes what drives the insert rate, for the summary.
+func (c *Config) targetLabel() string {
+	if n := c.closedConcurrency(); n > 0 {
+		return fmt.Sprintf("closed loop, %d in flight", n)
+	}
+	return fmt.Sprintf("target %d", c.TargetRPS)
+}
+
+// ValidateMode returns an error if mode is not a known run mode.
+func ValidateMode(mode string) error {
+	switch mode {
+	case ModeInsertQuery, ModeQueryOnly, ModeInsertOnly, ModeClosed:
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q (want %s, %s, %s or %s)", mode, ModeInsertQuery, ModeQueryOnly, ModeInsertOnly, ModeClosed)
+}
@@ -3,11 +3,13 @@ package benchmarkgo
 import "time"
 
 // Record is (patient_id, message_type, json_message, is_original).
+// PayloadIndex references the shared payload pool for SOURCE (see ExpandPayload); -1 when JSONMessage is complete.
 type Record struct {
-	PatientID   string
-	MessageType string
-	JSONMessage string
-	IsOriginal  bool
+	PatientID    string
+	MessageType  string
+	JSONMessage  string
+	IsOriginal   bool
+	PayloadIndex int
 }
 
 // InsertPair is a single queue unit: originals first, then duplicates. The same worker processes both back-to-back on one connection so originals commit before duplicates.
@@ -16,12 +18,14 @@ type InsertPair struct {
 	Originals  []*Record
 	Duplicates []*Record
 	QueryHint  string
+	Intended   time.Time // when the router's constant-rate schedule had the batch due; set by Router
 }
 
 // QueryJob is sent to query workers; nil pointer means QUERY_SENTINEL (stop).
 type QueryJob struct {
 	MRN        string
-	InsertTime time.Time
+	PatientID  string
+	InsertTime time.Time // zero for reads not tied to a fresh insert (WorkloadMixed)
 }
 
 // InsertionSentinel: pass nil *Record to signal end of insertion stream.
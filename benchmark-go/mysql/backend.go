@@ -0,0 +1,72 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+)
+
+// Storage engines for --mysql-engine.
+const (
+	EngineInnoDB      = "innodb"      // row store, clustered on medical_record_number; duplicates upsert
+	EngineColumnStore = "columnstore" // MariaDB ColumnStore: no keys or indexes, so duplicates append
+)
+
+// ValidateEngine checks a --mysql-engine value.
+func ValidateEngine(engine string) error {
+	switch engine {
+	case EngineInnoDB, EngineColumnStore:
+		return nil
+	}
+	return fmt.Errorf("unknown engine %q (want %s or %s)", engine, EngineInnoDB, EngineColumnStore)
+}
+
+// Options selects the table's storage engine.
+type Options struct {
+	Engine string
+}
+
+// schema returns the DDL for the engine. ColumnStore supports neither primary keys nor secondary indexes;
+// lookups scan using its per-extent min/max metadata.
+func schema(opts Options) func(table string) []string {
+	return func(table string) []string {
+		if opts.Engine == EngineColumnStore {
+			return []string{"CREATE TABLE IF NOT EXISTS " + table + " (" + ColumnsDDL() + ") ENGINE=ColumnStore"}
+		}
+		return []string{
+			"CREATE TABLE IF NOT EXISTS " + table + " (" + ColumnsDDL() +
+				", PRIMARY KEY (medical_record_number), KEY idx_hl7_patient_id (patient_id(32))) ENGINE=InnoDB",
+		}
+	}
+}
+
+// Context is a sqldb context that creates the database before connecting to it.
+type Context struct {
+	*sqldb.Context
+	conn Conn
+}
+
+// Setup creates the database if missing, then opens the pool and creates the table.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if err := CreateDatabase(context.Background(), c.conn); err != nil {
+		return nil, err
+	}
+	return c.Context.Setup(numWorkers, targetRPS, queriesPerRecord)
+}
+
+// NewContext returns a worker context for MySQL or MariaDB at MYSQL_HOST:MYSQL_PORT (default mysql:3306,
+// user root, database db_benchmark). ColumnStore requires MariaDB with the ColumnStore engine installed.
+func NewContext(opts Options) *Context {
+	if opts.Engine == "" {
+		opts.Engine = EngineInnoDB
+	}
+	conn := ConnFromEnv("MYSQL", Conn{Host: "mysql", Port: 3306, User: "root", Database: "db_benchmark"})
+	d := Dialect("mysql ("+opts.Engine+")", schema(opts))
+	if opts.Engine == EngineColumnStore {
+		d.Upsert = sqldb.AppendInsert
+		d.AppendOnly = true
+	}
+	return &Context{Context: &sqldb.Context{Dialect: d, DSN: conn.DSN()}, conn: conn}
+}
@@ -0,0 +1,129 @@
+// Package mysql holds what the MySQL-protocol backends (TiDB, SingleStore, MariaDB) share on top of sqldb:
+// connection settings from the environment, the base dialect and MySQL error-number classification.
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+	driver "github.com/go-sql-driver/mysql"
+)
+
+// Server error numbers shared by the MySQL family.
+const (
+	ErLockWaitTimeout = 1205
+	ErLockDeadlock    = 1213
+)
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// classifyError maps server errors to "mysql:<number>", e.g. mysql:1213 (deadlock) or mysql:9007 (TiDB write conflict).
+func classifyError(err error) (string, bool) {
+	var myErr *driver.MySQLError
+	if errors.As(err, &myErr) {
+		return "mysql:" + strconv.Itoa(int(myErr.Number)), true
+	}
+	return "", false
+}
+
+// ErrorNumber returns the server error number of err, or 0 if it is not a server error.
+func ErrorNumber(err error) uint16 {
+	var myErr *driver.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number
+	}
+	return 0
+}
+
+// Conn holds connection settings for a MySQL-protocol server.
+type Conn struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+}
+
+// ConnFromEnv reads <prefix>_HOST, _PORT, _USER, _PASSWORD and _DATABASE, falling back to def for unset values.
+func ConnFromEnv(prefix string, def Conn) Conn {
+	c := def
+	if v := os.Getenv(prefix + "_HOST"); v != "" {
+		c.Host = v
+	}
+	if v := os.Getenv(prefix + "_PORT"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil {
+			c.Port = p
+		}
+	}
+	if v, ok := os.LookupEnv(prefix + "_USER"); ok {
+		c.User = v
+	}
+	if v, ok := os.LookupEnv(prefix + "_PASSWORD"); ok {
+		c.Password = v
+	}
+	if v := os.Getenv(prefix + "_DATABASE"); v != "" {
+		c.Database = v
+	}
+	return c
+}
+
+// DSN renders the go-sql-driver DSN. Timestamps are sent and parsed as UTC; maxAllowedPacket=0 adopts the
+// server's limit, which must fit a full batch (each record carries a ~2 MiB SOURCE).
+func (c Conn) DSN() string {
+	cfg := driver.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = c.Host + ":" + strconv.Itoa(c.Port)
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.DBName = c.Database
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	cfg.MaxAllowedPacket = 0
+	cfg.Timeout = 10 * time.Second
+	return cfg.FormatDSN()
+}
+
+// CreateDatabase connects without a default database and creates c.Database if missing, for servers that
+// (unlike TiDB's "test") ship without a usable default.
+func CreateDatabase(ctx context.Context, c Conn) error {
+	admin := c
+	admin.Database = ""
+	db, err := sql.Open("mysql", admin.DSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.ExecContext(ctx, "CREATE DATABASE IF NOT EXISTS "+c.Database)
+	return err
+}
+
+// Dialect returns the MySQL-family base dialect; schema is the table DDL for the given (prefixed) table name.
+func Dialect(name string, schema func(table string) []string) *sqldb.Dialect {
+	return &sqldb.Dialect{
+		Name:           name,
+		Driver:         "mysql",
+		Placeholder:    sqldb.QuestionPlaceholder,
+		Schema:         schema,
+		Upsert:         sqldb.OnDuplicateKeyUpsert,
+		PatientOrdinal: "CAST(SUBSTRING(patient_id, 9) AS SIGNED)",
+		PayloadLength:  "LENGTH",
+		Retryable: func(err error) bool {
+			n := ErrorNumber(err)
+			return n == ErLockDeadlock || n == ErLockWaitTimeout
+		},
+		MaxRetries: 5,
+	}
+}
+
+// ColumnsDDL is sqldb.ColumnsDDL with MySQL types: LONGTEXT payload columns, DATETIME(3) timestamps, VARCHAR key.
+func ColumnsDDL() string {
+	return sqldb.ColumnsDDL("LONGTEXT", "DATETIME(3)", "VARCHAR(64)")
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -13,16 +15,26 @@ const (
 
 var payloadPool []string
 
+// payloadSums are the sourceSums of payloadPool, so generating a record hashes a few short columns for its
+// CHECKSUM instead of its 2 MiB SOURCE.
+var payloadSums []string
+
 func init() {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// Fixed seed: every process builds the same pool, so seeded runs send identical payloads to each target.
+	rng := rand.New(rand.NewSource(1))
 	payloadPool = make([]string, payloadPoolSize)
 	for i := 0; i < payloadPoolSize; i++ {
 		b := make([]byte, payloadSize)
 		for j := range b {
-			b[j] = letters[rand.Intn(len(letters))]
+			b[j] = letters[rng.Intn(len(letters))]
 		}
 		payloadPool[i] = string(b)
 	}
+	payloadSums = make([]string, payloadPoolSize)
+	for i, p := range payloadPool {
+		payloadSums[i] = sourceSum(p)
+	}
 }
 
 // PatientRecord is a single patient record for load.
@@ -57,6 +69,7 @@ type PatientRecord struct {
 	FHIREthnicityDisplay     string      `json:"FHIR_ETHNICITY_DISPLAY"`
 	SexAtBirth               string      `json:"SEX_AT_BIRTH"`
 	IsPregnant               string      `json:"IS_PREGNANT"`
+	PayloadIndex             int         `json:"-"` // index of Source in the shared payload pool
 }
 
 var firstNames = []string{"John", "Jane", "Bob", "Alice", "Charlie", "Diana", "Eve", "Frank", "Grace", "Henry"}
@@ -66,7 +79,11 @@ var genders = []string{"male", "female", "other"}
 // GenerateOnePatient creates a single patient record for the given ordinal.
 // isOriginal marks whether this is the first record for this patient (true) or a duplicate (false).
 func GenerateOnePatient(ordinal int, isOriginal bool) PatientRecord {
-	baseSource := payloadPool[rand.Intn(len(payloadPool))]
+	return generatePatient(ordinal, isOriginal, rand.Intn(len(payloadPool)))
+}
+
+// generatePatient builds the record for ordinal with SOURCE taken from payloadPool[payloadIndex].
+func generatePatient(ordinal int, isOriginal bool, payloadIndex int) PatientRecord {
 	ord := formatOrdinal(ordinal)
 	mrn := "MRN-" + ord
 	pid := "patient-" + ord
@@ -78,18 +95,21 @@ func GenerateOnePatient(ordinal int, isOriginal bool) PatientRecord {
 	if ordinal%4 == 0 {
 		nameSuffix = "Jr"
 	}
+	lastName, firstName, dateOfBirth := demographics(ordinal)
 	return PatientRecord{
+		Checksum:                 recordChecksum(pid, mrn, lastName, firstName, dateOfBirth, payloadSums[payloadIndex]),
 		IsOriginal:               isOriginal,
 		FHIRID:                   pid,
 		RXPatientID:              "rx-" + pid,
-		Source:                   baseSource,
+		Source:                   payloadPool[payloadIndex],
+		PayloadIndex:             payloadIndex,
 		PatientID:                pid,
 		MedicalRecordNumber:      mrn,
 		NamePrefix:               namePrefix,
-		LastName:                 lastNames[ordinal%len(lastNames)],
-		FirstName:                firstNames[ordinal%len(firstNames)],
+		LastName:                 lastName,
+		FirstName:                firstName,
 		NameSuffix:               nameSuffix,
-		DateOfBirth:              formatDateOfBirth(1980+(ordinal%40), (ordinal%12)+1, (ordinal%28)+1),
+		DateOfBirth:              dateOfBirth,
 		GenderAdministrative:     genders[ordinal%3],
 		FHIRGenderAdministrative: genders[ordinal%3],
 		GenderIdentity:           capitalize(genders[ordinal%3]),
@@ -117,16 +137,39 @@ func GenerateBulkPatients(start, total int, duplicateRatio float64) []PatientRec
 		patients = append(patients, GenerateOnePatient(start+i, true))
 	}
 	nDuplicates := total - nUnique
-	baseSource := payloadPool[rand.Intn(len(payloadPool))]
+	baseIdx := rand.Intn(len(payloadPool))
 	for j := 0; j < nDuplicates; j++ {
 		dup := patients[j%nUnique]
 		dup.IsOriginal = false
-		dup.Source = baseSource
+		dup.Source = payloadPool[baseIdx]
+		dup.PayloadIndex = baseIdx
+		dup.Checksum = recordChecksum(dup.PatientID, dup.MedicalRecordNumber, dup.LastName, dup.FirstName, dup.DateOfBirth, payloadSums[baseIdx])
 		patients = append(patients, dup)
 	}
 	return patients
 }
 
+// demographics returns the last name, first name and date of birth generated for ordinal; many ordinals share them.
+func demographics(ordinal int) (lastName, firstName, dateOfBirth string) {
+	return lastNames[ordinal%len(lastNames)], firstNames[ordinal%len(firstNames)],
+		formatDateOfBirth(1980+(ordinal%40), (ordinal%12)+1, (ordinal%28)+1)
+}
+
+// demographicsForPatientID returns demographics for a generated patient ID; ok is false for other IDs.
+func demographicsForPatientID(patientID string) (lastName, firstName, dateOfBirth string, ok bool) {
+	ordinal, err := strconv.Atoi(strings.TrimPrefix(patientID, "patient-"))
+	if err != nil || !strings.HasPrefix(patientID, "patient-") {
+		return "", "", "", false
+	}
+	lastName, firstName, dateOfBirth = demographics(ordinal)
+	return lastName, firstName, dateOfBirth, true
+}
+
+// mrnForPatientID returns the MEDICAL_RECORD_NUMBER generatePatient pairs with patientID.
+func mrnForPatientID(patientID string) string {
+	return "MRN-" + strings.TrimPrefix(patientID, "patient-")
+}
+
 func formatOrdinal(n int) string {
 	if n < 0 {
 		n = 0
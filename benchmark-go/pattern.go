@@ -0,0 +1,90 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Load pattern shapes: each period the target rate moves between Min×TargetRPS and TargetRPS.
+const (
+	PatternSine     = "sine"     // smooth cycle, starting halfway up and rising
+	PatternSquare   = "square"   // first half of each period at the target, second half at the minimum
+	PatternSawtooth = "sawtooth" // linear climb from the minimum to the target, then an instant drop
+)
+
+// LoadPattern modulates the target rate periodically, e.g. to mimic daily admission cycles.
+type LoadPattern struct {
+	Shape     string  // PatternSine, PatternSquare or PatternSawtooth ("" = constant rate)
+	PeriodSec float64 // length of one cycle
+	Min       float64 // lowest rate as a fraction of TargetRPS, in [0, 1)
+}
+
+// ParseLoadPattern parses "SHAPE[:period=SECONDS][,min=FRACTION]", e.g. "sine:period=300,min=0.2". The period
+// defaults to 300s and the minimum to 0.
+func ParseLoadPattern(s string) (LoadPattern, error) {
+	shape, params, _ := strings.Cut(strings.TrimSpace(s), ":")
+	p := LoadPattern{Shape: shape, PeriodSec: 300}
+	switch shape {
+	case PatternSine, PatternSquare, PatternSawtooth:
+	default:
+		return p, fmt.Errorf("unknown shape %q (want %s, %s or %s)", shape, PatternSine, PatternSquare, PatternSawtooth)
+	}
+	for _, kv := range strings.Split(params, ",") {
+		if kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		f, err := strconv.ParseFloat(v, 64)
+		if !ok || err != nil {
+			return p, fmt.Errorf("%q: want key=number", kv)
+		}
+		switch k {
+		case "period":
+			p.PeriodSec = f
+		case "min":
+			p.Min = f
+		default:
+			return p, fmt.Errorf("unknown parameter %q (want period or min)", k)
+		}
+	}
+	if !(p.PeriodSec > 0) { // also rejects NaN
+		return p, fmt.Errorf("period must be > 0")
+	}
+	if !(p.Min >= 0 && p.Min < 1) {
+		return p, fmt.Errorf("min must be in [0, 1)")
+	}
+	return p, nil
+}
+
+// String renders p in the form ParseLoadPattern accepts.
+func (p LoadPattern) String() string {
+	return fmt.Sprintf("%s:period=%g,min=%g", p.Shape, p.PeriodSec, p.Min)
+}
+
+// loadPattern is a LoadPattern anchored to the run's start.
+type loadPattern struct {
+	LoadPattern
+	start time.Time
+}
+
+// factor is the share of the target rate at t, between Min and 1.
+func (p *loadPattern) factor(t time.Time) float64 {
+	phase := math.Mod(t.Sub(p.start).Seconds()/p.PeriodSec, 1)
+	var level float64 // 0 at the minimum, 1 at the target
+	switch p.Shape {
+	case PatternSine:
+		level = (1 + math.Sin(2*math.Pi*phase)) / 2
+	case PatternSquare:
+		if phase < 0.5 {
+			level = 1
+		}
+	case PatternSawtooth:
+		level = phase
+	default:
+		return 1
+	}
+	return p.Min + (1-p.Min)*level
+}
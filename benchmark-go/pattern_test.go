@@ -0,0 +1,47 @@
+package benchmarkgo
+
+import "testing"
+
+func TestParseLoadPattern(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    LoadPattern
+		wantErr bool
+	}{
+		{in: "sine", want: LoadPattern{Shape: PatternSine, PeriodSec: 300}},
+		{in: " square ", want: LoadPattern{Shape: PatternSquare, PeriodSec: 300}},
+		{in: "sawtooth:period=60", want: LoadPattern{Shape: PatternSawtooth, PeriodSec: 60}},
+		{in: "sine:period=300,min=0.2", want: LoadPattern{Shape: PatternSine, PeriodSec: 300, Min: 0.2}},
+		{in: "sine:min=0", want: LoadPattern{Shape: PatternSine, PeriodSec: 300}},
+		{in: "", wantErr: true},
+		{in: "triangle", wantErr: true},
+		{in: "Sine", wantErr: true},
+		{in: "sine:period", wantErr: true},
+		{in: "sine:period=5m", wantErr: true}, // plain seconds only
+		{in: "sine:period=0", wantErr: true},
+		{in: "sine:period=-60", wantErr: true},
+		{in: "sine:period=NaN", wantErr: true},
+		{in: "sine:min=-0.1", wantErr: true},
+		{in: "sine:min=1", wantErr: true},
+		{in: "sine:min=NaN", wantErr: true},
+		{in: "sine:amplitude=2", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLoadPattern(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLoadPattern(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseLoadPattern(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPatternStringRoundTrip(t *testing.T) {
+	p := LoadPattern{Shape: PatternSquare, PeriodSec: 90, Min: 0.25}
+	got, err := ParseLoadPattern(p.String())
+	if err != nil || got != p {
+		t.Errorf("ParseLoadPattern(%q) = %+v, %v; want %+v", p.String(), got, err, p)
+	}
+}
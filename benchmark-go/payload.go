@@ -0,0 +1,34 @@
+package benchmarkgo
+
+import (
+	"strings"
+)
+
+// emptySource is the SOURCE member of a queued message; ExpandPayload splices the pooled payload into it.
+const emptySource = `"SOURCE":""`
+
+// ToJSONRef returns the record as JSON with an empty SOURCE, so queued records carry payloadPool[p.PayloadIndex]
+// by index (a few bytes) instead of a multi-megabyte copy. ExpandPayload restores the full message at insert time.
+func (p PatientRecord) ToJSONRef() (string, error) {
+	p.Source = ""
+	return p.ToJSON()
+}
+
+// ExpandPayload returns jsonMsg with payloadPool[idx] spliced into its empty SOURCE. idx < 0 means jsonMsg already
+// holds its payload and is returned unchanged. Pool payloads are alphanumeric, so no JSON escaping is needed.
+func ExpandPayload(jsonMsg string, idx int) string {
+	if idx < 0 || idx >= len(payloadPool) {
+		return jsonMsg
+	}
+	at := strings.Index(jsonMsg, emptySource)
+	if at < 0 {
+		return jsonMsg
+	}
+	payload := payloadPool[idx]
+	var b strings.Builder
+	b.Grow(len(jsonMsg) + len(payload))
+	b.WriteString(jsonMsg[:at+len(emptySource)-1])
+	b.WriteString(payload)
+	b.WriteString(jsonMsg[at+len(emptySource)-1:])
+	return b.String()
+}
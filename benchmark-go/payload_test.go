@@ -0,0 +1,34 @@
+package benchmarkgo
+
+import "testing"
+
+func TestExpandPayload(t *testing.T) {
+	rec := generatePatient(42, true, 7)
+	full, err := rec.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := rec.ToJSONRef()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		msg  string
+		idx  int
+		want string
+	}{
+		{name: "pooled payload", msg: ref, idx: rec.PayloadIndex, want: full},
+		{name: "first pool entry", msg: `{"SOURCE":"","X":1}`, idx: 0, want: `{"SOURCE":"` + payloadPool[0] + `","X":1}`},
+		{name: "last pool entry", msg: `{"SOURCE":""}`, idx: payloadPoolSize - 1, want: `{"SOURCE":"` + payloadPool[payloadPoolSize-1] + `"}`},
+		{name: "full message", msg: full, idx: -1, want: full},
+		{name: "index past the pool", msg: ref, idx: payloadPoolSize, want: ref},
+		{name: "no empty SOURCE", msg: `{"SOURCE":"inline"}`, idx: 0, want: `{"SOURCE":"inline"}`},
+		{name: "empty message", msg: "", idx: 0, want: ""},
+	}
+	for _, tt := range tests {
+		if got := ExpandPayload(tt.msg, tt.idx); got != tt.want {
+			t.Errorf("%s: ExpandPayload(%.40q, %d) returned %d bytes that differ from the %d wanted", tt.name, tt.msg, tt.idx, len(got), len(tt.want))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package benchmarkgo
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Run phases: warmup (the first phaseWarmup of load, or Config.WarmupSec), steady (until generation stops at the deadline) and
+// drain (queued batches and queries finishing). Rows, queries and latency histograms are split per phase so
+// the summary can show steady-state numbers apart from the ramp and the tail.
+const (
+	PhaseWarmup = iota
+	PhaseSteady
+	PhaseDrain
+	numPhases
+)
+
+var phaseNames = [numPhases]string{"warmup", "steady", "drain"}
+
+// phaseWarmup is the warmup length for a run of duration: a tenth of it, at most 10s.
+func phaseWarmup(duration time.Duration) time.Duration {
+	return min(duration/10, 10*time.Second)
+}
+
+// phaseMark is the state when a phase began.
+type phaseMark struct {
+	entered bool
+	at      time.Time
+	rows    int64
+	queries int64
+}
+
+var (
+	phaseMu      sync.Mutex
+	phaseMarks   [numPhases]phaseMark
+	currentPhase atomic.Int32 // index into phaseNames; latency samples are recorded under it
+)
+
+// enterPhase moves the run into phase p; phases only move forward, so a late warmup timer cannot undo drain.
+func enterPhase(p int) {
+	phaseMu.Lock()
+	defer phaseMu.Unlock()
+	if phaseMarks[p].entered || (p > 0 && int(currentPhase.Load()) > p) {
+		return
+	}
+	phaseMarks[p] = phaseMark{entered: true, at: time.Now(), rows: insertTotal.Load(), queries: queryCount.Load()}
+	currentPhase.Store(int32(p))
+}
+
+// PhaseStats is one phase's throughput and latency.
+type PhaseStats struct {
+	Name          string              `json:"name"`
+	StartSec      float64             `json:"start_sec"` // since run start
+	DurationSec   float64             `json:"duration_sec"`
+	Rows          int64               `json:"rows"`
+	RowsPerSec    float64             `json:"rows_per_sec"`
+	Queries       int64               `json:"queries"`
+	QueriesPerSec float64             `json:"queries_per_sec"`
+	Insert        *LatencyPercentiles `json:"insert_latency,omitempty"` // per batch
+	Query         *LatencyPercentiles `json:"query_latency,omitempty"`
+}
+
+// loadPhases summarizes the phases entered, ending the last at end.
+func loadPhases(runStart, end time.Time) []PhaseStats {
+	phaseMu.Lock()
+	marks := phaseMarks
+	phaseMu.Unlock()
+	var out []PhaseStats
+	for p := 0; p < numPhases; p++ {
+		m := marks[p]
+		if !m.entered {
+			continue
+		}
+		until := phaseMark{at: end, rows: insertTotal.Load(), queries: queryCount.Load()}
+		for next := p + 1; next < numPhases; next++ {
+			if marks[next].entered {
+				until = marks[next]
+				break
+			}
+		}
+		dur := until.at.Sub(m.at).Seconds()
+		ps := PhaseStats{
+			Name:        phaseNames[p],
+			StartSec:    m.at.Sub(runStart).Seconds(),
+			DurationSec: dur,
+			Rows:        until.rows - m.rows,
+			Queries:     until.queries - m.queries,
+			Insert:      insertLatencies.phase(p),
+			Query:       queryLatencies.phase(p),
+		}
+		if dur > 0 {
+			ps.RowsPerSec = float64(ps.Rows) / dur
+			ps.QueriesPerSec = float64(ps.Queries) / dur
+		}
+		out = append(out, ps)
+	}
+	return out
+}
@@ -0,0 +1,195 @@
+package benchmarkgo
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pipeline stages in flow order: records are generated, paced by the rate limiter, turned into insert rows
+// (batch), passed through any RowStages, inserted, enqueued as query jobs, and queried.
+const (
+	StageGenerate = "generate"
+	StagePace     = "pace"
+	StageBatch    = "batch"
+	StageInsert   = "insert"
+	StageEnqueue  = "enqueue"
+	StageQuery    = "query"
+)
+
+// RowStage transforms a batch's insert rows between the batch and insert stages (e.g. compressing or
+// encrypting SOURCE). Process is called from all insert workers concurrently.
+type RowStage interface {
+	Name() string
+	Process(rows []RowForDB) ([]RowForDB, error)
+}
+
+// StageStats is one stage's activity over the run. Items are batches (query jobs for the query stage), BusySec
+// is time spent in the stage summed over its goroutines, so BusySec / (elapsed × Workers) is its utilization.
+type StageStats struct {
+	Name    string  `json:"name"`
+	Workers int     `json:"workers"`
+	Items   int64   `json:"items"`
+	Rows    int64   `json:"rows"`
+	BusySec float64 `json:"busy_sec"`
+	Failed  int64   `json:"failed"`
+}
+
+type stageMetrics struct {
+	workers    atomic.Int64
+	items      atomic.Int64
+	rows       atomic.Int64
+	busyMicros atomic.Int64
+	failed     atomic.Int64
+}
+
+// Pipeline wires the stages of a run: it starts each stage's goroutines, holds the pluggable RowStages, and
+// keeps per-stage metrics. Stages hand work to each other over the runner's queues.
+type Pipeline struct {
+	mu        sync.Mutex
+	metrics   map[string]*stageMetrics
+	rowStages []RowStage
+}
+
+// NewPipeline returns a Pipeline with no RowStages.
+func NewPipeline() *Pipeline {
+	return &Pipeline{metrics: make(map[string]*stageMetrics)}
+}
+
+// Use appends a RowStage; RowStages run after the batch stage in the order added. Call before Run.
+func (p *Pipeline) Use(s RowStage) {
+	p.mu.Lock()
+	p.rowStages = append(p.rowStages, s)
+	p.mu.Unlock()
+}
+
+func (p *Pipeline) stage(name string) *stageMetrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, ok := p.metrics[name]
+	if !ok {
+		m = &stageMetrics{}
+		p.metrics[name] = m
+	}
+	return m
+}
+
+// StageGroup is the set of goroutines running one stage.
+type StageGroup struct {
+	wg sync.WaitGroup
+}
+
+// Wait blocks until every goroutine of the stage has returned.
+func (g *StageGroup) Wait() {
+	g.wg.Wait()
+}
+
+// Start runs fn(0..n-1) on n goroutines as stage name. Stages that run inline on another stage's goroutines
+// (batch, RowStages, verify run on the insert workers) are accounted to those goroutines via alsoOn.
+func (p *Pipeline) Start(name string, n int, fn func(i int), alsoOn ...string) *StageGroup {
+	p.stage(name).workers.Add(int64(n))
+	for _, s := range alsoOn {
+		p.stage(s).workers.Add(int64(n))
+	}
+	g := &StageGroup{}
+	g.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer g.wg.Done()
+			fn(i)
+		}(i)
+	}
+	return g
+}
+
+// Record adds one item of rows that spent d in stage name, failed of which failed. Safe on a nil Pipeline.
+func (p *Pipeline) Record(name string, rows int, d time.Duration, failed int) {
+	if p == nil {
+		return
+	}
+	m := p.stage(name)
+	m.items.Add(1)
+	m.rows.Add(int64(rows))
+	m.busyMicros.Add(d.Microseconds())
+	m.failed.Add(int64(failed))
+}
+
+// processRows runs rows through the RowStages in order, recording each; the first error aborts the batch.
+func (p *Pipeline) processRows(rows []RowForDB) ([]RowForDB, error) {
+	if p == nil {
+		return rows, nil
+	}
+	p.mu.Lock()
+	stages := p.rowStages
+	p.mu.Unlock()
+	for _, s := range stages {
+		t0 := time.Now()
+		out, err := s.Process(rows)
+		failed := 0
+		if err != nil {
+			failed = len(rows)
+		}
+		p.Record(s.Name(), len(rows), time.Since(t0), failed)
+		if err != nil {
+			return nil, err
+		}
+		rows = out
+	}
+	return rows, nil
+}
+
+func (p *Pipeline) rowStageNames() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, len(p.rowStages))
+	for i, s := range p.rowStages {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// order returns the stage names in flow order, RowStages after batch.
+func (p *Pipeline) order() []string {
+	names := append([]string{StageGenerate, StagePace, StageBatch}, p.rowStageNames()...)
+	return append(names, StageInsert, StageEnqueue, StageQuery, StageDecode)
+}
+
+// Stats returns the stages that ran, in flow order.
+func (p *Pipeline) Stats() []StageStats {
+	var out []StageStats
+	for _, name := range p.order() {
+		p.mu.Lock()
+		m, ok := p.metrics[name]
+		p.mu.Unlock()
+		if !ok || m.items.Load() == 0 {
+			continue
+		}
+		out = append(out, StageStats{
+			Name:    name,
+			Workers: int(m.workers.Load()),
+			Items:   m.items.Load(),
+			Rows:    m.rows.Load(),
+			BusySec: float64(m.busyMicros.Load()) / 1e6,
+			Failed:  m.failed.Load(),
+		})
+	}
+	return out
+}
+
+// logStages logs per-stage throughput and utilization; the most utilized stage is the likely bottleneck.
+func logStages(stages []StageStats, elapsedSec float64) {
+	if len(stages) == 0 {
+		return
+	}
+	log.Printf("Pipeline stages:")
+	log.Printf("  %-10s %7s %10s %12s %10s %8s %10s", "stage", "workers", "items", "rows", "avg_ms", "util", "failed")
+	for _, s := range stages {
+		avgMs := s.BusySec / float64(s.Items) * 1000
+		util := 0.0
+		if elapsedSec > 0 && s.Workers > 0 {
+			util = s.BusySec / (elapsedSec * float64(s.Workers)) * 100
+		}
+		log.Printf("  %-10s %7d %10d %12d %10.2f %7.1f%% %10d", s.Name, s.Workers, s.Items, s.Rows, avgMs, util, s.Failed)
+	}
+}
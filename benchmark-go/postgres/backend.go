@@ -4,19 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"net"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	benchmarkgo "github.com/db-benchmarking/benchmark-go"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // BuildInsertStatement returns the INSERT upsert SQL and args for the given rows (for use with Exec or Batch.Queue).
-// placeholderStart is the first placeholder number (default 1).
-func BuildInsertStatement(rows []benchmarkgo.RowForDB, placeholderStart int) (sql string, args []interface{}, err error) {
+// placeholderStart is the first placeholder number (default 1). schema selects the ON CONFLICT target.
+func BuildInsertStatement(rows []benchmarkgo.RowForDB, placeholderStart int, schema SchemaOptions) (sql string, args []interface{}, err error) {
+	return buildInsertStatement(rows, placeholderStart, schema, nil)
+}
+
+// buildInsertStatement is BuildInsertStatement that adds the time spent decoding the rows' JSON to decode (if not nil).
+func buildInsertStatement(rows []benchmarkgo.RowForDB, placeholderStart int, schema SchemaOptions, decode *time.Duration) (sql string, args []interface{}, err error) {
 	if len(rows) == 0 {
 		return "", nil, nil
 	}
@@ -26,7 +35,7 @@ func BuildInsertStatement(rows []benchmarkgo.RowForDB, placeholderStart int) (sq
 	now := time.Now().UTC()
 	updateCols := make([]string, 0, len(hl7Columns)-1)
 	for _, c := range hl7Columns {
-		if c != "medical_record_number" {
+		if c != "medical_record_number" && !(schema.Timescale && c == "created_at") {
 			updateCols = append(updateCols, c)
 		}
 	}
@@ -51,7 +60,11 @@ func BuildInsertStatement(rows []benchmarkgo.RowForDB, placeholderStart int) (sq
 		if i > 0 {
 			placeholders += ", "
 		}
+		t0 := time.Now()
 		row, err := rowFromJSON(rows[i].JSONMessage, now)
+		if decode != nil {
+			*decode += time.Since(t0)
+		}
 		if err != nil {
 			return "", nil, err
 		}
@@ -67,17 +80,20 @@ func BuildInsertStatement(rows []benchmarkgo.RowForDB, placeholderStart int) (sq
 		ph += ")"
 		placeholders += ph
 	}
-	sql = "INSERT INTO hl7_messages (" + cols + ") VALUES " + placeholders +
-		" ON CONFLICT (medical_record_number) DO UPDATE SET " + setClause
+	// RETURNING (xmax = 0) is true for freshly inserted rows and false for conflict-updated ones,
+	// so the database outcome can be reconciled against the generator's original/duplicate intent.
+	sql = "INSERT INTO " + benchmarkgo.Table() + " (" + cols + ") VALUES " + placeholders +
+		" ON CONFLICT (" + schema.conflictTarget() + ") DO UPDATE SET " + setClause +
+		" RETURNING (xmax = 0) AS inserted"
 	return sql, args, nil
 }
 
 // BuildPgbouncerHintInsertStatement prepends the producer-prepared queryHint string to the INSERT.
-func BuildPgbouncerHintInsertStatement(rows []benchmarkgo.RowForDB, queryHint string) (sql string, args []interface{}, err error) {
+func BuildPgbouncerHintInsertStatement(rows []benchmarkgo.RowForDB, queryHint string, schema SchemaOptions) (sql string, args []interface{}, err error) {
 	if len(rows) == 0 {
 		return "", nil, nil
 	}
-	insertSQL, insertArgs, err := BuildInsertStatement(rows, 1)
+	insertSQL, insertArgs, err := BuildInsertStatement(rows, 1, schema)
 	if err != nil {
 		return "", nil, err
 	}
@@ -101,8 +117,11 @@ const (
 	// so that shards are placed one per worker (even distribution). Must match worker count.
 	citusShardCount = 4
 
-	createTableSQL = `
-CREATE TABLE IF NOT EXISTS hl7_messages (
+	// timescaleChunkInterval is the hypertable chunk width on created_at.
+	timescaleChunkInterval = "1 hour"
+
+	// hl7ColumnsDDL is the column list shared by the hash-partitioned table and the Timescale hypertable.
+	hl7ColumnsDDL = `
     fhir_id TEXT,
     rx_patient_id TEXT,
     source TEXT,
@@ -131,12 +150,66 @@ CREATE TABLE IF NOT EXISTS hl7_messages (
     ethnicity_display TEXT,
     fhir_ethnicity_display TEXT,
     sex_at_birth TEXT,
-    is_pregnant TEXT,
+    is_pregnant TEXT,`
+
+	createTableSQL = `
+CREATE TABLE IF NOT EXISTS %s (` + hl7ColumnsDDL + `
     PRIMARY KEY (medical_record_number)
 ) PARTITION BY HASH (medical_record_number);
 `
+
+	// createGreenplumSQL: segments hash rows by the DISTRIBUTED BY column, which must be part of every unique key.
+	createGreenplumSQL = `
+CREATE TABLE IF NOT EXISTS %s (` + hl7ColumnsDDL + `
+    PRIMARY KEY (medical_record_number)
+) DISTRIBUTED BY (medical_record_number);
+`
+
+	// createHypertableSQL: hypertable unique keys must include the time column, so the key is (mrn, created_at).
+	createHypertableSQL = `
+CREATE TABLE IF NOT EXISTS %s (` + hl7ColumnsDDL + `
+    PRIMARY KEY (medical_record_number, created_at)
+);
+`
+)
+
+// SchemaOptions selects the hl7_messages layout created by InitSchema and targeted by inserts and lookups.
+type SchemaOptions struct {
+	// Timescale creates hl7_messages as a TimescaleDB hypertable on created_at with key (medical_record_number, created_at)
+	// instead of the hash-partitioned table. Duplicates then land as new time-series rows rather than updating in place.
+	Timescale bool
+	// Distribution shards the table across nodes: DistributionCitus (create_distributed_table; must succeed) or
+	// DistributionGreenplum (DISTRIBUTED BY). "" keeps the single-node layout, distributing only if Citus is detected.
+	Distribution string
+	// NameIndex adds idx_hl7_name_dob on (last_name, first_name, date_of_birth) for name-dob lookups.
+	NameIndex bool
+	// PageIndex adds idx_hl7_name_order on (last_name, first_name, patient_id), the pagination queries' sort order.
+	PageIndex bool
+}
+
+// Distribution modes for SchemaOptions.Distribution.
+const (
+	DistributionCitus     = "citus"
+	DistributionGreenplum = "greenplum"
 )
 
+// ValidateDistribution checks a --postgres-distribution value.
+func ValidateDistribution(d string) error {
+	switch d {
+	case "", DistributionCitus, DistributionGreenplum:
+		return nil
+	}
+	return fmt.Errorf("unknown distribution %q (want %s or %s)", d, DistributionCitus, DistributionGreenplum)
+}
+
+// conflictTarget returns the ON CONFLICT column list matching the table's primary key.
+func (o SchemaOptions) conflictTarget() string {
+	if o.Timescale {
+		return "medical_record_number, created_at"
+	}
+	return "medical_record_number"
+}
+
 var hl7Columns = []string{
 	"fhir_id", "rx_patient_id", "source", "cdc", "created_at", "created_by",
 	"updated_at", "updated_by", "load_date", "checksum", "patient_id",
@@ -149,24 +222,49 @@ var hl7Columns = []string{
 
 // CreatePool creates a pgx connection pool using the default database (postgres).
 func CreatePool(ctx context.Context, host string, port int, size int) (*pgxpool.Pool, error) {
-	return CreatePoolWithDB(ctx, host, port, size, benchmarkgo.DBName)
+	return CreatePoolWithDB(ctx, host, port, size, benchmarkgo.DBName())
 }
 
 // CreatePoolWithDB creates a pgx connection pool for the given database name (e.g. postgres1, postgres2 for PgBouncer).
+// The benchmarkgo.Pool max idle and max lifetime settings apply; connections beyond max idle close after poolIdleTime.
 func CreatePoolWithDB(ctx context.Context, host string, port int, size int, database string) (*pgxpool.Pool, error) {
+	return createPool(ctx, host, port, size, database, "")
+}
+
+// createPool is CreatePoolWithDB with synchronous_commit set on every connection the pool opens (when non-empty), so
+// connections reopened after --pool-max-lifetime or --pool-max-idle keep the run's durability.
+func createPool(ctx context.Context, host string, port int, size int, database, syncCommit string) (*pgxpool.Pool, error) {
 	if database == "" {
-		database = benchmarkgo.DBName
+		database = benchmarkgo.DBName()
+	}
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(benchmarkgo.DBUser(), benchmarkgo.DBPassword()),
+		Host:   net.JoinHostPort(host, fmtPort(port)),
+		Path:   "/" + database,
 	}
-	connStr := "postgres://" + benchmarkgo.User + ":" + benchmarkgo.Password + "@" + host + ":" + fmtPort(port) + "/" + database
-	cfg, err := pgxpool.ParseConfig(connStr)
+	cfg, err := pgxpool.ParseConfig(u.String())
 	if err != nil {
 		return nil, err
 	}
+	if syncCommit != "" {
+		cfg.ConnConfig.RuntimeParams["synchronous_commit"] = syncCommit
+	}
+	opts := benchmarkgo.Pool()
 	cfg.MaxConns = int32(size)
-	cfg.MinConns = int32(size)
+	cfg.MinConns = int32(opts.Idle(size))
+	if opts.MaxIdle > 0 {
+		cfg.MaxConnIdleTime = poolIdleTime
+	}
+	if opts.MaxLifetime > 0 {
+		cfg.MaxConnLifetime = opts.MaxLifetime
+	}
 	return pgxpool.NewWithConfig(ctx, cfg)
 }
 
+// poolIdleTime is how long a connection beyond --pool-max-idle stays open unused.
+const poolIdleTime = 10 * time.Second
+
 func fmtPort(p int) string {
 	if p <= 0 {
 		return "5432"
@@ -174,20 +272,28 @@ func fmtPort(p int) string {
 	return strconv.Itoa(p)
 }
 
-// SetSessionSyncCommit sets synchronous_commit = off for the connection (faster writes).
-func SetSessionSyncCommit(ctx context.Context, conn *pgxpool.Conn) error {
-	_, err := conn.Exec(ctx, "SET synchronous_commit = off")
+// SynchronousCommit maps a benchmarkgo durability level to a synchronous_commit value (default off, for faster writes).
+func SynchronousCommit(level string) string {
+	if level == benchmarkgo.DurabilityDefault {
+		return benchmarkgo.DurabilityOff
+	}
+	return level
+}
+
+// SetSessionSyncCommit sets synchronous_commit for the connection.
+func SetSessionSyncCommit(ctx context.Context, conn *pgxpool.Conn, value string) error {
+	_, err := conn.Exec(ctx, "SET synchronous_commit = "+value)
 	return err
 }
 
-// PrewarmPool acquires and releases each connection and sets sync_commit off.
-func PrewarmPool(ctx context.Context, pool *pgxpool.Pool, size int) error {
+// PrewarmPool acquires and releases each connection and sets synchronous_commit to syncCommit.
+func PrewarmPool(ctx context.Context, pool *pgxpool.Pool, size int, syncCommit string) error {
 	for i := 0; i < size; i++ {
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
 			return err
 		}
-		if err := SetSessionSyncCommit(ctx, conn); err != nil {
+		if err := SetSessionSyncCommit(ctx, conn, syncCommit); err != nil {
 			conn.Release()
 			return err
 		}
@@ -197,42 +303,112 @@ func PrewarmPool(ctx context.Context, pool *pgxpool.Pool, size int) error {
 	return nil
 }
 
-// InitSchema creates hl7_messages hash-partitioned table if not exists (modulus 8).
-// When running on a Citus coordinator, distributes the table by medical_record_number (auto-detected).
-func InitSchema(ctx context.Context, pool *pgxpool.Pool) error {
-	if _, err := pool.Exec(ctx, createTableSQL); err != nil {
+// createIndexes creates the secondary indexes: idx_hl7_patient_id, plus idx_hl7_name_dob and idx_hl7_name_order
+// when schema asks for them.
+func createIndexes(ctx context.Context, pool *pgxpool.Pool, schema SchemaOptions) error {
+	if _, err := pool.Exec(ctx, "CREATE INDEX IF NOT EXISTS "+benchmarkgo.Prefixed("idx_hl7_patient_id")+" ON "+benchmarkgo.Table()+"(patient_id)"); err != nil {
+		return err
+	}
+	optional := []struct {
+		on      bool
+		name    string
+		columns string
+	}{
+		{schema.NameIndex, "idx_hl7_name_dob", "last_name, first_name, date_of_birth"},
+		{schema.PageIndex, "idx_hl7_name_order", "last_name, first_name, patient_id"},
+	}
+	for _, idx := range optional {
+		if !idx.on {
+			continue
+		}
+		if _, err := pool.Exec(ctx, "CREATE INDEX IF NOT EXISTS "+benchmarkgo.Prefixed(idx.name)+" ON "+benchmarkgo.Table()+"("+idx.columns+")"); err != nil {
+			return err
+		}
+		log.Printf("Index %s on (%s) ready", benchmarkgo.Prefixed(idx.name), idx.columns)
+	}
+	return nil
+}
+
+// initHypertable creates hl7_messages as a TimescaleDB hypertable chunked on created_at.
+func initHypertable(ctx context.Context, pool *pgxpool.Pool, schema SchemaOptions) error {
+	if _, err := pool.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS timescaledb"); err != nil {
+		return err
+	}
+	if _, err := pool.Exec(ctx, fmt.Sprintf(createHypertableSQL, benchmarkgo.Table())); err != nil {
+		return err
+	}
+	if _, err := pool.Exec(ctx, "SELECT create_hypertable('"+benchmarkgo.Table()+"', 'created_at', chunk_time_interval => INTERVAL '"+
+		timescaleChunkInterval+"', if_not_exists => TRUE)"); err != nil {
+		return err
+	}
+	if err := createIndexes(ctx, pool, schema); err != nil {
+		return err
+	}
+	log.Printf("Table %s created as TimescaleDB hypertable on created_at (chunk %s, key medical_record_number, created_at)", benchmarkgo.Table(), timescaleChunkInterval)
+	return nil
+}
+
+// initGreenplum creates hl7_messages distributed across Greenplum segments by medical_record_number.
+func initGreenplum(ctx context.Context, pool *pgxpool.Pool, schema SchemaOptions) error {
+	if _, err := pool.Exec(ctx, fmt.Sprintf(createGreenplumSQL, benchmarkgo.Table())); err != nil {
+		return err
+	}
+	if err := createIndexes(ctx, pool, schema); err != nil {
+		return err
+	}
+	log.Printf("Table %s created on Greenplum, DISTRIBUTED BY (medical_record_number)", benchmarkgo.Table())
+	return nil
+}
+
+// InitSchema creates hl7_messages hash-partitioned table if not exists (modulus 8), or a hypertable when schema.Timescale,
+// or a segment-distributed table on Greenplum. When running on a Citus coordinator, distributes the table by
+// medical_record_number (auto-detected; required when schema.Distribution is DistributionCitus).
+func InitSchema(ctx context.Context, pool *pgxpool.Pool, schema SchemaOptions) error {
+	if schema.Timescale {
+		return initHypertable(ctx, pool, schema)
+	}
+	if schema.Distribution == DistributionGreenplum {
+		return initGreenplum(ctx, pool, schema)
+	}
+	if _, err := pool.Exec(ctx, fmt.Sprintf(createTableSQL, benchmarkgo.Table())); err != nil {
 		return err
 	}
 	for i := 0; i < hashPartitionModulus; i++ {
-		partSQL := "CREATE TABLE IF NOT EXISTS hl7_messages_" + strconv.Itoa(i) +
-			" PARTITION OF hl7_messages FOR VALUES WITH (MODULUS " + strconv.Itoa(hashPartitionModulus) + ", REMAINDER " + strconv.Itoa(i) + ")"
+		partSQL := "CREATE TABLE IF NOT EXISTS " + benchmarkgo.Table() + "_" + strconv.Itoa(i) +
+			" PARTITION OF " + benchmarkgo.Table() + " FOR VALUES WITH (MODULUS " + strconv.Itoa(hashPartitionModulus) + ", REMAINDER " + strconv.Itoa(i) + ")"
 		if _, err := pool.Exec(ctx, partSQL); err != nil {
 			return err
 		}
 	}
-	if _, err := pool.Exec(ctx, "CREATE INDEX IF NOT EXISTS idx_hl7_patient_id ON hl7_messages(patient_id)"); err != nil {
+	if err := createIndexes(ctx, pool, schema); err != nil {
 		return err
 	}
-	log.Printf("Table hl7_messages created with hash partitioning (modulus %d)", hashPartitionModulus)
+	log.Printf("Table %s created with hash partitioning (modulus %d)", benchmarkgo.Table(), hashPartitionModulus)
 	// Citus: if extension is present, distribute by medical_record_number with explicit shard_count
 	// so that shards are evenly distributed (one shard per worker when citusShardCount == worker count).
 	// Hash partition modulus 8 is local to each shard; row placement is hash(mrn) -> shard.
 	var hasCitus int
 	errExt := pool.QueryRow(ctx, "SELECT 1 FROM pg_extension WHERE extname = 'citus'").Scan(&hasCitus)
+	if schema.Distribution == DistributionCitus && hasCitus != 1 {
+		return fmt.Errorf("--postgres-distribution citus: citus extension is not installed")
+	}
 	if errExt == nil && hasCitus == 1 {
 		var alreadyDist int
-		errDist := pool.QueryRow(ctx, "SELECT 1 FROM citus_tables WHERE tablename = 'hl7_messages'").Scan(&alreadyDist)
+		errDist := pool.QueryRow(ctx, "SELECT 1 FROM citus_tables WHERE tablename = $1", benchmarkgo.Table()).Scan(&alreadyDist)
 		if errDist != nil {
-			_, errDist = pool.Exec(ctx, "SELECT create_distributed_table('hl7_messages', 'medical_record_number', shard_count => $1)", citusShardCount)
+			_, errDist = pool.Exec(ctx, "SELECT create_distributed_table($1, 'medical_record_number', shard_count => $2)", benchmarkgo.Table(), citusShardCount)
 			if errDist != nil {
 				var pgErr *pgconn.PgError
+				if schema.Distribution == DistributionCitus {
+					return fmt.Errorf("citus create_distributed_table: %w", errDist)
+				}
 				if errors.As(errDist, &pgErr) && pgErr.Code == "42883" {
 					// undefined_function — shouldn't happen if extension exists
 				} else {
 					log.Printf("Citus create_distributed_table: %v", errDist)
 				}
 			} else {
-				log.Printf("Citus: distributed hl7_messages by medical_record_number (shard_count=%d)", citusShardCount)
+				log.Printf("Citus: distributed %s by medical_record_number (shard_count=%d)", benchmarkgo.Table(), citusShardCount)
 			}
 		}
 	}
@@ -271,37 +447,535 @@ func rowFromJSON(jsonStr string, now time.Time) ([]interface{}, error) {
 	}, nil
 }
 
+// CopyRows bulk-loads rows with COPY, for preloading; there is no upsert, so the patients must be new.
+func CopyRows(ctx context.Context, pool *pgxpool.Pool, rows []benchmarkgo.RowForDB) (int64, error) {
+	now := time.Now()
+	return pool.CopyFrom(ctx, pgx.Identifier{benchmarkgo.Table()}, hl7Columns, pgx.CopyFromSlice(len(rows), func(i int) ([]interface{}, error) {
+		return rowFromJSON(rows[i].JSONMessage, now)
+	}))
+}
+
 // InsertBatch upserts rows into hl7_messages (ON CONFLICT DO UPDATE).
-func InsertBatch(ctx context.Context, conn *pgxpool.Conn, rows []benchmarkgo.RowForDB) (int, error) {
+func InsertBatch(ctx context.Context, conn *pgxpool.Conn, rows []benchmarkgo.RowForDB, schema SchemaOptions) (int, error) {
 	if len(rows) == 0 {
 		return 0, nil
 	}
-	sql, args, err := BuildInsertStatement(rows, 1)
+	sql, args, err := BuildInsertStatement(rows, 1, schema)
 	if err != nil {
 		return 0, err
 	}
-	_, err = conn.Exec(ctx, sql, args...)
+	return ExecUpsert(ctx, conn, sql, args)
+}
+
+// ExecUpsert runs an upsert built by BuildInsertStatement, records the inserted-vs-updated outcome
+// via benchmarkgo.AddUpsertOutcome, and returns the number of rows written.
+func ExecUpsert(ctx context.Context, conn *pgxpool.Conn, sql string, args []interface{}) (int, error) {
+	rows, err := conn.Query(ctx, sql, args...)
 	if err != nil {
 		return 0, err
 	}
-	return len(rows), nil
+	defer rows.Close()
+	var inserted, updated int
+	for rows.Next() {
+		var isInsert bool
+		if err := rows.Scan(&isInsert); err != nil {
+			return 0, err
+		}
+		if isInsert {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	benchmarkgo.AddUpsertOutcome(int64(inserted), int64(updated))
+	return inserted + updated, nil
 }
 
 // QueryByPrimaryKey returns rows for the given medical_record_number.
-func QueryByPrimaryKey(ctx context.Context, conn *pgxpool.Conn, mrn string) (int, error) {
+// On a hypertable an MRN has one row per version, so it counts the latest version only.
+func QueryByPrimaryKey(ctx context.Context, conn *pgxpool.Conn, mrn string, schema SchemaOptions) (int, error) {
+	sql := "SELECT COUNT(*) FROM " + benchmarkgo.Table() + " WHERE medical_record_number = $1"
+	if schema.Timescale {
+		sql = "SELECT COUNT(*) FROM (SELECT 1 FROM " + benchmarkgo.Table() + " WHERE medical_record_number = $1 ORDER BY created_at DESC LIMIT 1) latest"
+	}
+	var n int
+	err := conn.QueryRow(ctx, sql, mrn).Scan(&n)
+	return n, err
+}
+
+// FetchSource returns SOURCE of the latest row for mrn (implements benchmarkgo.SourceFetcher via querier).
+func FetchSource(ctx context.Context, conn *pgxpool.Conn, mrn string) (string, error) {
+	var source *string
+	err := conn.QueryRow(ctx, "SELECT source FROM "+benchmarkgo.Table()+
+		" WHERE medical_record_number = $1 ORDER BY created_at DESC LIMIT 1", mrn).Scan(&source)
+	if err != nil || source == nil {
+		return "", err
+	}
+	return *source, nil
+}
+
+// QueryByPrimaryKeyTimed is QueryByPrimaryKey that also returns the server-side duration, measured in the same
+// statement as clock_timestamp() - statement_timestamp(): parse, plan and execution on the backend, excluding
+// the network round trip and client-side queueing.
+func QueryByPrimaryKeyTimed(ctx context.Context, conn *pgxpool.Conn, mrn string, schema SchemaOptions) (int, time.Duration, error) {
+	sql := "SELECT COUNT(*), EXTRACT(EPOCH FROM clock_timestamp() - statement_timestamp()) FROM " + benchmarkgo.Table() + " WHERE medical_record_number = $1"
+	if schema.Timescale {
+		sql = "SELECT COUNT(*), EXTRACT(EPOCH FROM clock_timestamp() - statement_timestamp()) FROM (SELECT 1 FROM " + benchmarkgo.Table() +
+			" WHERE medical_record_number = $1 ORDER BY created_at DESC LIMIT 1) latest"
+	}
+	var n int
+	var serverSec float64
+	err := conn.QueryRow(ctx, sql, mrn).Scan(&n, &serverSec)
+	return n, time.Duration(serverSec * float64(time.Second)), err
+}
+
+// QueryResultSet fetches full rows for the MRN range ending at mrn (descending, LIMIT limit).
+// Returns rows and wire bytes received, so latency can be related to result-set size.
+func QueryResultSet(ctx context.Context, conn *pgxpool.Conn, mrn string, limit int) (int, int64, error) {
+	rows, err := conn.Query(ctx,
+		"SELECT * FROM "+benchmarkgo.Table()+" WHERE medical_record_number <= $1 ORDER BY medical_record_number DESC LIMIT $2", mrn, limit)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	return countRawBytes(rows)
+}
+
+// countRawBytes drains rows, returning how many there were and the wire bytes of their values.
+func countRawBytes(rows pgx.Rows) (int, int64, error) {
+	var n int
+	var bytes int64
+	for rows.Next() {
+		n++
+		for _, v := range rows.RawValues() {
+			bytes += int64(len(v))
+		}
+	}
+	return n, bytes, rows.Err()
+}
+
+// hl7ColumnsNoSource is the select list for row fetches without the SOURCE payload.
+var hl7ColumnsNoSource = func() string {
+	cols := make([]string, 0, len(hl7Columns))
+	for _, c := range hl7Columns {
+		if c != "source" {
+			cols = append(cols, c)
+		}
+	}
+	return strings.Join(cols, ", ")
+}()
+
+// FetchByPrimaryKey fetches the latest row for mrn, with or without source, and returns rows and wire bytes
+// received.
+func FetchByPrimaryKey(ctx context.Context, conn *pgxpool.Conn, mrn string, withPayload bool) (int, int64, error) {
+	cols := "*"
+	if !withPayload {
+		cols = hl7ColumnsNoSource
+	}
+	rows, err := conn.Query(ctx, "SELECT "+cols+" FROM "+benchmarkgo.Table()+
+		" WHERE medical_record_number = $1 ORDER BY created_at DESC LIMIT 1", mrn)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	return countRawBytes(rows)
+}
+
+// FetchVerifyColumns reads the columns --verify compares from the latest row for mrn; nil when there is none.
+func FetchVerifyColumns(ctx context.Context, conn *pgxpool.Conn, mrn string) (*benchmarkgo.VerifyColumns, error) {
+	rows, err := conn.Query(ctx, "SELECT COALESCE(patient_id, ''), COALESCE(last_name, ''), COALESCE(first_name, ''),"+
+		" COALESCE(date_of_birth, ''), COALESCE(checksum, '') FROM "+benchmarkgo.Table()+
+		" WHERE medical_record_number = $1 ORDER BY created_at DESC LIMIT 1", mrn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var c benchmarkgo.VerifyColumns
+	if err := rows.Scan(&c.PatientID, &c.LastName, &c.FirstName, &c.DateOfBirth, &c.Checksum); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FetchChecksumRow reads the columns CHECKSUM covers, CHECKSUM and SOURCE from the latest row for mrn; nil when
+// there is none.
+func FetchChecksumRow(ctx context.Context, conn *pgxpool.Conn, mrn string) (*benchmarkgo.ChecksumRow, error) {
+	rows, err := conn.Query(ctx, "SELECT COALESCE(patient_id, ''), medical_record_number, COALESCE(last_name, ''), COALESCE(first_name, ''),"+
+		" COALESCE(date_of_birth, ''), COALESCE(checksum, ''), COALESCE(source, '') FROM "+benchmarkgo.Table()+
+		" WHERE medical_record_number = $1 ORDER BY created_at DESC LIMIT 1", mrn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var c benchmarkgo.ChecksumRow
+	if err := rows.Scan(&c.PatientID, &c.MRN, &c.LastName, &c.FirstName, &c.DateOfBirth, &c.Checksum, &c.Source); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// QueryByPatientID fetches full rows for patient_id via idx_hl7_patient_id and returns how many were read.
+func QueryByPatientID(ctx context.Context, conn *pgxpool.Conn, patientID string) (int, error) {
+	rows, err := conn.Query(ctx, "SELECT * FROM "+benchmarkgo.Table()+" WHERE patient_id = $1", patientID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// QueryPatientRange fetches full rows for patient_id created between from and to and returns how many were read.
+func QueryPatientRange(ctx context.Context, conn *pgxpool.Conn, patientID string, from, to time.Time) (int, error) {
+	rows, err := conn.Query(ctx, "SELECT * FROM "+benchmarkgo.Table()+" WHERE patient_id = $1 AND created_at BETWEEN $2 AND $3", patientID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// QueryByNameDOB fetches full rows matching last name, first name and date of birth (idx_hl7_name_dob when
+// created) and returns how many were read.
+func QueryByNameDOB(ctx context.Context, conn *pgxpool.Conn, lastName, firstName, dateOfBirth string) (int, error) {
+	rows, err := conn.Query(ctx, "SELECT * FROM "+benchmarkgo.Table()+" WHERE last_name = $1 AND first_name = $2 AND date_of_birth = $3",
+		lastName, firstName, dateOfBirth)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
 	var n int
-	err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM hl7_messages WHERE medical_record_number = $1", mrn).Scan(&n)
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// pageOrder is the patient list's sort order for the pagination queries.
+const pageOrder = " ORDER BY last_name, first_name, patient_id"
+
+// QueryPageOffset fetches limit rows of the name-ordered patient list from offset on and returns how many were read.
+func QueryPageOffset(ctx context.Context, conn *pgxpool.Conn, offset, limit int) (int, error) {
+	rows, err := conn.Query(ctx, "SELECT * FROM "+benchmarkgo.Table()+pageOrder+" LIMIT $1 OFFSET $2", limit, offset)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// QueryPageAfter fetches the limit rows of the name-ordered patient list after the given key and returns how many
+// were read.
+func QueryPageAfter(ctx context.Context, conn *pgxpool.Conn, lastName, firstName, patientID string, limit int) (int, error) {
+	rows, err := conn.Query(ctx, "SELECT * FROM "+benchmarkgo.Table()+" WHERE (last_name, first_name, patient_id) > ($1, $2, $3)"+
+		pageOrder+" LIMIT $4", lastName, firstName, patientID, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// AggregateByPatientID summarizes the patient's rows (count, latest update, payload size) and returns the count.
+func AggregateByPatientID(ctx context.Context, conn *pgxpool.Conn, patientID string) (int, error) {
+	var n int
+	var lastUpdated *time.Time
+	var payloadBytes *int64
+	err := conn.QueryRow(ctx,
+		"SELECT COUNT(*), MAX(updated_at), SUM(octet_length(source)) FROM "+benchmarkgo.Table()+" WHERE patient_id = $1", patientID,
+	).Scan(&n, &lastUpdated, &payloadBytes)
 	return n, err
 }
 
+// CountRows returns the number of rows in hl7_messages.
+func CountRows(ctx context.Context, pool *pgxpool.Pool) (int64, error) {
+	var n int64
+	err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+benchmarkgo.Table()).Scan(&n)
+	return n, err
+}
+
+// TableBytes returns total on-disk size of hl7_messages summed over its partitions or hypertable chunks (heap + indexes + TOAST).
+func TableBytes(ctx context.Context, pool *pgxpool.Pool, schema SchemaOptions) (int64, error) {
+	sql := "SELECT COALESCE(SUM(pg_total_relation_size(relid)), 0) FROM pg_partition_tree('" + benchmarkgo.Table() + "')"
+	if schema.Timescale {
+		sql = "SELECT COALESCE(hypertable_size('" + benchmarkgo.Table() + "'), 0)"
+	}
+	var n int64
+	err := pool.QueryRow(ctx, sql).Scan(&n)
+	return n, err
+}
+
+// DescribeSchema reconstructs hl7_messages' DDL from the catalog: columns, partition key and index definitions.
+func DescribeSchema(ctx context.Context, pool *pgxpool.Pool) (string, error) {
+	var create string
+	err := pool.QueryRow(ctx, `SELECT 'CREATE TABLE ' || c.relname || ' (' ||
+		string_agg(quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod) ||
+			CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END, ', ' ORDER BY a.attnum) || ')' ||
+		COALESCE(' PARTITION BY ' || pg_get_partkeydef(c.oid), '') || ';'
+		FROM pg_class c JOIN pg_attribute a ON a.attrelid = c.oid
+		WHERE c.oid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		GROUP BY c.oid, c.relname`, benchmarkgo.Table()).Scan(&create)
+	if err != nil {
+		return "", err
+	}
+	stmts := []string{create}
+	rows, err := pool.Query(ctx, "SELECT indexdef FROM pg_indexes WHERE tablename = $1 ORDER BY indexname", benchmarkgo.Table())
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var def string
+		if err := rows.Scan(&def); err != nil {
+			return "", err
+		}
+		stmts = append(stmts, def+";")
+	}
+	return strings.Join(stmts, "\n"), rows.Err()
+}
+
+// CacheCounters returns cumulative shared-buffer hits and reads (misses) of the current database.
+func CacheCounters(ctx context.Context, pool *pgxpool.Pool) (int64, int64, error) {
+	var hits, reads int64
+	err := pool.QueryRow(ctx,
+		"SELECT blks_hit, blks_read FROM pg_stat_database WHERE datname = current_database()").Scan(&hits, &reads)
+	return hits, reads, err
+}
+
+// ServerMetrics samples the numeric columns of pg_stat_database (current database), pg_stat_bgwriter and, on
+// Postgres 17+ where the checkpoint counters moved there, pg_stat_checkpointer. Rows are read as JSON so the
+// column set of the server's version is taken as is.
+func ServerMetrics(ctx context.Context, pool *pgxpool.Pool) (map[string]float64, error) {
+	views := []struct {
+		name, query string
+		optional    bool
+	}{
+		{"pg_stat_database", "SELECT to_jsonb(d) FROM pg_stat_database d WHERE datname = current_database()", false},
+		{"pg_stat_bgwriter", "SELECT to_jsonb(b) FROM pg_stat_bgwriter b", false},
+		{"pg_stat_checkpointer", "SELECT to_jsonb(c) FROM pg_stat_checkpointer c", true},
+	}
+	out := make(map[string]float64)
+	for _, v := range views {
+		var row map[string]interface{}
+		if err := pool.QueryRow(ctx, v.query).Scan(&row); err != nil {
+			if v.optional {
+				continue
+			}
+			return nil, fmt.Errorf("%s: %w", v.name, err)
+		}
+		for col, val := range row {
+			if f, ok := val.(float64); ok {
+				out[v.name+"."+col] = f
+			}
+		}
+	}
+	return out, nil
+}
+
+// ServerVersion returns version() of the server.
+func ServerVersion(ctx context.Context, pool *pgxpool.Pool) (string, error) {
+	var v string
+	err := pool.QueryRow(ctx, "SELECT version()").Scan(&v)
+	return v, err
+}
+
+// DeleteOlderThan deletes rows with created_at < cutoff, then VACUUMs so the space is reusable.
+// The hash partitioning is by MRN, so there is no time partition to drop; on a hypertable whole chunks are dropped instead.
+func DeleteOlderThan(ctx context.Context, pool *pgxpool.Pool, cutoff time.Time, schema SchemaOptions) (benchmarkgo.RetentionResult, error) {
+	res := benchmarkgo.RetentionResult{Method: "DELETE+VACUUM", BytesBefore: -1, BytesAfter: -1}
+	var err error
+	if res.RowsBefore, err = CountRows(ctx, pool); err != nil {
+		return res, err
+	}
+	if b, err := TableBytes(ctx, pool, schema); err == nil {
+		res.BytesBefore = b
+	}
+	if schema.Timescale {
+		res.Method = "drop_chunks"
+		t0 := time.Now()
+		if _, err := pool.Exec(ctx, "SELECT drop_chunks('"+benchmarkgo.Table()+"', older_than => $1::timestamptz)", cutoff); err != nil {
+			return res, err
+		}
+		res.DeleteSec = time.Since(t0).Seconds()
+		if res.RowsAfter, err = CountRows(ctx, pool); err != nil {
+			return res, err
+		}
+		if b, err := TableBytes(ctx, pool, schema); err == nil {
+			res.BytesAfter = b
+		}
+		return res, nil
+	}
+	t0 := time.Now()
+	if _, err := pool.Exec(ctx, "DELETE FROM "+benchmarkgo.Table()+" WHERE created_at < $1", cutoff); err != nil {
+		return res, err
+	}
+	res.DeleteSec = time.Since(t0).Seconds()
+	t1 := time.Now()
+	if _, err := pool.Exec(ctx, "VACUUM "+benchmarkgo.Table()); err != nil {
+		return res, err
+	}
+	res.ReclaimSec = time.Since(t1).Seconds()
+	if res.RowsAfter, err = CountRows(ctx, pool); err != nil {
+		return res, err
+	}
+	if b, err := TableBytes(ctx, pool, schema); err == nil {
+		res.BytesAfter = b
+	}
+	return res, nil
+}
+
+// CreatePatientCounter creates the single-row counter table ReservePatientRange leases from. Runners started together
+// race on CREATE TABLE IF NOT EXISTS, which Postgres can fail with a unique violation on pg_type (or a duplicate
+// table); either means another runner created it, so both count as success.
+func CreatePatientCounter(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx, "CREATE TABLE IF NOT EXISTS "+benchmarkgo.Prefixed("hl7_patient_counter")+" (id INT PRIMARY KEY, next_ordinal BIGINT NOT NULL)")
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "23505" || pgErr.Code == "42P07") {
+		return nil
+	}
+	return err
+}
+
+// ReservePatientRange leases n ordinals from a single-row counter table shared by all runners on this database
+// (created by CreatePatientCounter in Setup). The upsert takes a row lock, so concurrent reservations are
+// serialized and never overlap.
+func ReservePatientRange(ctx context.Context, pool *pgxpool.Pool, floor, n int) (int, error) {
+	counter := benchmarkgo.Prefixed("hl7_patient_counter")
+	var first int64
+	err := pool.QueryRow(ctx,
+		"INSERT INTO "+counter+" AS c (id, next_ordinal) VALUES (1, $1::bigint + $2::bigint) "+
+			"ON CONFLICT (id) DO UPDATE SET next_ordinal = GREATEST(c.next_ordinal, $1::bigint) + $2::bigint "+
+			"RETURNING next_ordinal - $2::bigint",
+		floor, n,
+	).Scan(&first)
+	if err != nil {
+		return 0, err
+	}
+	return int(first), nil
+}
+
 // GetMaxPatientCounter returns max patient ordinal from PATIENT_ID 'patient-NNNNNNNNNN', or -1.
 func GetMaxPatientCounter(ctx context.Context, conn *pgxpool.Conn) (int, error) {
 	var v int64
 	err := conn.QueryRow(ctx,
-		"SELECT COALESCE(MAX(CAST(SUBSTRING(patient_id FROM 10) AS BIGINT)), -1) FROM hl7_messages WHERE patient_id IS NOT NULL AND patient_id ~ '^patient-[0-9]+$'",
+		"SELECT COALESCE(MAX(CAST(SUBSTRING(patient_id FROM 10) AS BIGINT)), -1) FROM "+benchmarkgo.Table()+" WHERE patient_id IS NOT NULL AND patient_id ~ '^patient-[0-9]+$'",
 	).Scan(&v)
 	if err != nil {
 		return -1, err
 	}
 	return int(v), nil
 }
+
+// SaveSnapshot replaces snapshot name with a plain copy of hl7_messages (CREATE TABLE AS; no indexes, since the
+// snapshot is only ever read back in full).
+func SaveSnapshot(ctx context.Context, pool *pgxpool.Pool, name string) (benchmarkgo.SnapshotResult, error) {
+	res := benchmarkgo.SnapshotResult{Method: "CREATE TABLE AS", Rows: -1}
+	snap := benchmarkgo.SnapshotTable(name)
+	if _, err := pool.Exec(ctx, "DROP TABLE IF EXISTS "+snap); err != nil {
+		return res, err
+	}
+	tag, err := pool.Exec(ctx, "CREATE TABLE "+snap+" AS TABLE "+benchmarkgo.Table())
+	if err != nil {
+		return res, err
+	}
+	res.Rows = tag.RowsAffected()
+	return res, nil
+}
+
+// RestoreSnapshot replaces the contents of hl7_messages with snapshot name in one transaction
+// (TRUNCATE + INSERT ... SELECT), then ANALYZEs so the query planner sees the restored data.
+func RestoreSnapshot(ctx context.Context, pool *pgxpool.Pool, name string) (benchmarkgo.SnapshotResult, error) {
+	res := benchmarkgo.SnapshotResult{Method: "TRUNCATE+INSERT SELECT", Rows: -1}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "TRUNCATE "+benchmarkgo.Table()); err != nil {
+		return res, err
+	}
+	tag, err := tx.Exec(ctx, "INSERT INTO "+benchmarkgo.Table()+" SELECT * FROM "+benchmarkgo.SnapshotTable(name))
+	if err != nil {
+		return res, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return res, err
+	}
+	res.Rows = tag.RowsAffected()
+	_, err = pool.Exec(ctx, "ANALYZE "+benchmarkgo.Table())
+	return res, err
+}
+
+// CountKeys returns the number of distinct MRNs of generated patients, for failover reconciliation
+// (distinct because a Timescale hypertable keeps one row per (MRN, created_at)).
+func CountKeys(ctx context.Context, pool *pgxpool.Pool) (int64, error) {
+	var n int64
+	err := pool.QueryRow(ctx, "SELECT COUNT(DISTINCT medical_record_number) FROM "+benchmarkgo.Table()+
+		" WHERE patient_id LIKE 'patient-%'").Scan(&n)
+	return n, err
+}
+
+// SampleKeys returns up to limit distinct patient IDs of generated patients, chosen at random.
+func SampleKeys(ctx context.Context, pool *pgxpool.Pool, limit int) ([]string, error) {
+	rows, err := pool.Query(ctx, "SELECT patient_id FROM (SELECT DISTINCT patient_id FROM "+benchmarkgo.Table()+
+		" WHERE patient_id LIKE 'patient-%') k ORDER BY random() LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// ShardRows returns the row count of every shard: Citus shards (counted on the workers via run_command_on_shards)
+// or Greenplum segments (grouped by gp_segment_id). Returns nil for a single-node layout.
+func ShardRows(ctx context.Context, pool *pgxpool.Pool, schema SchemaOptions) ([]benchmarkgo.ShardRows, error) {
+	var sql string
+	switch schema.Distribution {
+	case DistributionCitus:
+		sql = "SELECT r.shardid::text, s.nodename || ':' || s.nodeport, r.result::bigint " +
+			"FROM run_command_on_shards('" + benchmarkgo.Table() + "', 'SELECT count(*) FROM %s') r " +
+			"JOIN citus_shards s USING (shardid) WHERE r.success ORDER BY r.shardid"
+	case DistributionGreenplum:
+		sql = "SELECT 'seg' || gp_segment_id, 'segment ' || gp_segment_id, count(*) FROM " + benchmarkgo.Table() +
+			" GROUP BY gp_segment_id ORDER BY gp_segment_id"
+	default:
+		return nil, nil
+	}
+	rows, err := pool.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []benchmarkgo.ShardRows{} // non-nil: distributed, even if still empty
+	for rows.Next() {
+		var s benchmarkgo.ShardRows
+		if err := rows.Scan(&s.Shard, &s.Node, &s.Rows); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxConflictRetries bounds retries of one conflict-writer transaction before it is counted as aborted.
+const maxConflictRetries = 20
+
+// conflictCreatedAt is the fixed created_at of conflict rows, so the Timescale key (MRN, created_at) collides too.
+var conflictCreatedAt = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var isoLevels = map[string]pgx.TxIsoLevel{
+	benchmarkgo.IsolationReadCommitted:  pgx.ReadCommitted,
+	benchmarkgo.IsolationRepeatableRead: pgx.RepeatableRead,
+	benchmarkgo.IsolationSerializable:   pgx.Serializable,
+}
+
+// conflictKind maps SQLSTATE 40P01 (deadlock_detected) and 40001 (serialization_failure) to a retryable kind.
+func conflictKind(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40P01":
+			return benchmarkgo.ConflictDeadlock
+		case "40001":
+			return benchmarkgo.ConflictSerialization
+		}
+	}
+	return ""
+}
+
+// conflictMRNs returns the shared MRN set; the "conflict-" patient_id keeps it out of the patient counter.
+func conflictMRNs(n int) []string {
+	mrns := make([]string, n)
+	for i := range mrns {
+		mrns[i] = fmt.Sprintf("MRN-CONFLICT-%06d", i)
+	}
+	return mrns
+}
+
+// RunConflictWriters runs opts.Writers goroutines on a dedicated pool (so they do not steal insert-worker
+// connections) until ctx is done. Each transaction reads the MRN set, then upserts it in a fresh random order,
+// so concurrent writers lock rows in different orders: deadlocks under any level, serialization failures under
+// repeatable_read/serializable.
+func RunConflictWriters(ctx context.Context, base *pgxpool.Pool, schema SchemaOptions, opts benchmarkgo.ConflictOptions, rec *benchmarkgo.ConflictRecorder) error {
+	cfg := base.Config().Copy()
+	cfg.MaxConns = int32(opts.Writers)
+	cfg.MinConns = 0
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	mrns := conflictMRNs(opts.Keys)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Writers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			conflictWriter(ctx, pool, schema, isoLevels[opts.Isolation], mrns, rand.New(rand.NewSource(seed)), rec)
+		}(time.Now().UnixNano() + int64(w))
+	}
+	wg.Wait()
+	return nil
+}
+
+func conflictWriter(ctx context.Context, pool *pgxpool.Pool, schema SchemaOptions, level pgx.TxIsoLevel, mrns []string, rng *rand.Rand, rec *benchmarkgo.ConflictRecorder) {
+	ordered := make([]string, len(mrns))
+	for ctx.Err() == nil {
+		for i, j := range rng.Perm(len(mrns)) {
+			ordered[i] = mrns[j]
+		}
+		start := time.Now()
+		for attempt := 1; ; attempt++ {
+			err := conflictTx(ctx, pool, schema, level, ordered)
+			if err == nil {
+				rec.Commit(attempt, time.Since(start))
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			kind := conflictKind(err)
+			if kind == "" || attempt > maxConflictRetries {
+				rec.Abort(err)
+				break
+			}
+			rec.Retry(kind, err)
+			time.Sleep(time.Duration(rng.Intn(1000*attempt)) * time.Microsecond)
+		}
+	}
+}
+
+// conflictTx reads the MRN set, then upserts it row by row in the given order (INSERT ... SELECT ... ORDER BY
+// ordinality takes the row locks in that order) within one transaction at level.
+func conflictTx(ctx context.Context, pool *pgxpool.Pool, schema SchemaOptions, level pgx.TxIsoLevel, mrns []string) error {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: level})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+	var existing int64
+	if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM "+benchmarkgo.Table()+" WHERE medical_record_number = ANY($1)", mrns).Scan(&existing); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO "+benchmarkgo.Table()+" (medical_record_number, patient_id, created_at, updated_at, updated_by) "+
+		"SELECT m, 'conflict-' || i, $2, now(), 'conflict-writer' FROM unnest($1::text[]) WITH ORDINALITY AS u(m, i) ORDER BY i "+
+		"ON CONFLICT ("+schema.conflictTarget()+") DO UPDATE SET updated_at = excluded.updated_at, updated_by = excluded.updated_by",
+		mrns, conflictCreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
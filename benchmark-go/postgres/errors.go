@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// classifyError maps server errors to "postgres:<SQLSTATE>", e.g. postgres:40P01 (deadlock_detected).
+func classifyError(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return "postgres:" + pgErr.Code, true
+	}
+	return "", false
+}
@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+func init() {
+	benchmarkgo.RegisterMicrobench(benchmarkgo.Microbench{Name: "postgres/rowFromJSON", Setup: func(batchSize int) func() {
+		rows := benchmarkgo.MicrobenchRows(batchSize)
+		return func() {
+			now := time.Now()
+			for _, r := range rows {
+				_, _ = rowFromJSON(r.JSONMessage, now)
+			}
+		}
+	}})
+	benchmarkgo.RegisterMicrobench(benchmarkgo.Microbench{Name: "postgres/BuildInsertStatement", Setup: func(batchSize int) func() {
+		rows := benchmarkgo.MicrobenchRows(batchSize)
+		return func() {
+			_, _, _ = BuildInsertStatement(rows, 1, SchemaOptions{})
+		}
+	}})
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"log"
+	"net"
 	"os"
 	"strconv"
 	"time"
@@ -13,6 +14,7 @@ import (
 
 const defaultHost = "localhost"
 const defaultPort = 5432
+
 // When PgbouncerEnabled, connect to pgbouncer (not Postgres directly).
 const defaultPgbouncerHost = "pgbouncer"
 const defaultPgbouncerPort = 6432
@@ -25,6 +27,7 @@ const pgbouncerDB2 = "postgres2"
 type Backend struct {
 	pool          *pgxpool.Pool
 	pgbouncerMode bool
+	schema        SchemaOptions
 }
 
 // GetConn acquires a connection from the pool.
@@ -44,39 +47,52 @@ func (b *Backend) ReleaseConn(c interface{}) {
 	}
 }
 
-// InsertBatch inserts rows using the given connection (must be *pgxpool.Conn). Returns (rowsInserted, statementCount, error).
+// InsertBatch inserts rows using the given connection (must be *pgxpool.Conn) in one statement, timing JSON decode,
+// statement building and the round trip separately.
 // When pgbouncerMode is true, queryHint (prepared by the producer) is prepended to the INSERT.
-func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (int, int, error) {
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
 	c, ok := conn.(*pgxpool.Conn)
-	if !ok {
-		return 0, 0, nil
+	if !ok || len(rows) == 0 {
+		return benchmarkgo.InsertResult{}, nil
 	}
 	ctx := context.Background()
-	if b.pgbouncerMode && len(rows) > 0 && queryHint != "" {
-		sql, args, err := BuildPgbouncerHintInsertStatement(rows, queryHint)
-		if err != nil {
-			return 0, 0, err
-		}
-		if _, err := c.Exec(ctx, sql, args...); err != nil {
-			return 0, 0, err
-		}
-		if db := databaseFromQueryHint(queryHint); db != "" {
-			benchmarkgo.AddInsertToDB(db, int64(len(rows)))
-		}
-		return len(rows), 1, nil
+	var phases benchmarkgo.InsertPhases
+	t0 := time.Now()
+	sql, args, err := buildInsertStatement(rows, 1, b.schema, &phases.Decode)
+	if err != nil {
+		return benchmarkgo.InsertResult{}, err
 	}
-	n, err := InsertBatch(ctx, c, rows)
+	hinted := b.pgbouncerMode && queryHint != ""
+	if hinted {
+		sql = queryHint + sql
+	}
+	phases.Bind = time.Since(t0) - phases.Decode
+	t0 = time.Now()
+	n, err := ExecUpsert(ctx, c, sql, args)
+	phases.Call = time.Since(t0)
 	if err != nil {
-		return n, 0, err
+		return benchmarkgo.InsertResult{Rows: n}, err
+	}
+	if hinted {
+		if db := databaseFromQueryHint(queryHint); db != "" {
+			benchmarkgo.AddInsertToDB(db, int64(n))
+		}
 	}
-	return n, 1, nil
+	return benchmarkgo.InsertResult{Rows: n, Statements: 1, Phases: phases}, nil
 }
 
 // Context handles setup/teardown and query workers for PostgreSQL.
 type Context struct {
-	insertPool        *pgxpool.Pool
-	selectPool        *pgxpool.Pool
-	PgbouncerEnabled  bool
+	insertPool       *pgxpool.Pool
+	selectPool       *pgxpool.Pool
+	PgbouncerEnabled bool
+	Durability       string // benchmarkgo durability level, applied as synchronous_commit
+	Schema           SchemaOptions
+	// ReplicaHost (host or host:port), when set, points the select pool at a streaming read replica; query
+	// workers then poll each MRN there from insert completion until visible (up to ReplicaTimeout) and record
+	// the read-after-write lag via benchmarkgo.AddVisibilityLag.
+	ReplicaHost    string
+	ReplicaTimeout time.Duration
 }
 
 // Setup creates insert pool and optionally a separate select pool. When PgbouncerEnabled, uses one pool (postgres1) and query hint with INSERT.
@@ -109,24 +125,32 @@ func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchm
 			}
 		}
 	}
+	if h := benchmarkgo.DBHost(); h != "" {
+		host = h
+	}
+	if p := benchmarkgo.DBPort(); p > 0 {
+		port = p
+	}
 	ctx := context.Background()
+	insertSize, selectSize := benchmarkgo.Pool().Sizes(numWorkers, queriesPerRecord > 0)
 	if c.PgbouncerEnabled {
 		log.Printf("Creating PostgreSQL connection pool at %s:%d (pgbouncer: postgres1, query hint + INSERT flip-flop postgres1/postgres2, %d insert)",
-			host, port, numWorkers)
-		insertPool, err := CreatePoolWithDB(ctx, host, port, numWorkers, pgbouncerDB1)
+			host, port, insertSize)
+		insertPool, err := createPool(ctx, host, port, insertSize, pgbouncerDB1, SynchronousCommit(c.Durability))
 		if err != nil {
 			return nil, err
 		}
 		c.insertPool = insertPool
-		if err := PrewarmPool(ctx, insertPool, numWorkers); err != nil {
+		if err := PrewarmPool(ctx, insertPool, insertSize, SynchronousCommit(c.Durability)); err != nil {
 			insertPool.Close()
 			return nil, err
 		}
-		c.selectPool, _ = CreatePoolWithDB(ctx, host, port, numWorkers, pgbouncerDB1)
+		selectSize, _ = benchmarkgo.Pool().Sizes(numWorkers, true) // the pgbouncer select pool always opens
+		c.selectPool, _ = createPool(ctx, host, port, selectSize, pgbouncerDB1, SynchronousCommit(c.Durability))
 		if c.selectPool != nil {
-			_ = PrewarmPool(ctx, c.selectPool, numWorkers)
+			_ = PrewarmPool(ctx, c.selectPool, selectSize, SynchronousCommit(c.Durability))
 		}
-		if err := InitSchema(ctx, insertPool); err != nil {
+		if err := initSchemaAndCounter(ctx, insertPool, c.Schema); err != nil {
 			insertPool.Close()
 			if c.selectPool != nil {
 				c.selectPool.Close()
@@ -134,37 +158,44 @@ func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchm
 			return nil, err
 		}
 		log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
-		be := &Backend{pool: insertPool, pgbouncerMode: true}
+		be := &Backend{pool: insertPool, pgbouncerMode: true, schema: c.Schema}
 		return be, nil
 	}
 	log.Printf("Creating PostgreSQL connection pool(s) at %s:%d (%d insert connections)",
-		host, port, numWorkers)
+		host, port, insertSize)
 	if queriesPerRecord > 0 {
-		log.Printf("  + %d select connections for query workers", numWorkers)
+		log.Printf("  + %d select connections for query workers", selectSize)
 	}
-	insertPool, err := CreatePool(ctx, host, port, numWorkers)
+	insertPool, err := createPool(ctx, host, port, insertSize, "", SynchronousCommit(c.Durability))
 	if err != nil {
 		return nil, err
 	}
 	c.insertPool = insertPool
-	if err := PrewarmPool(ctx, insertPool, numWorkers); err != nil {
+	if err := PrewarmPool(ctx, insertPool, insertSize, SynchronousCommit(c.Durability)); err != nil {
 		insertPool.Close()
 		return nil, err
 	}
 	if queriesPerRecord > 0 {
-		selectPool, err := CreatePool(ctx, host, port, numWorkers)
+		selectHost, selectPort := benchmarkgo.QueryAddr(host, port)
+		if c.ReplicaHost != "" {
+			selectHost, selectPort = replicaAddr(c.ReplicaHost, port)
+			log.Printf("  select connections use read replica %s:%d", selectHost, selectPort)
+		} else if selectHost != host || selectPort != port {
+			log.Printf("  select connections use query host %s:%d", selectHost, selectPort)
+		}
+		selectPool, err := createPool(ctx, selectHost, selectPort, selectSize, "", SynchronousCommit(c.Durability))
 		if err != nil {
 			insertPool.Close()
 			return nil, err
 		}
 		c.selectPool = selectPool
-		if err := PrewarmPool(ctx, selectPool, numWorkers); err != nil {
+		if err := PrewarmPool(ctx, selectPool, selectSize, SynchronousCommit(c.Durability)); err != nil {
 			insertPool.Close()
 			selectPool.Close()
 			return nil, err
 		}
 	}
-	if err := InitSchema(ctx, insertPool); err != nil {
+	if err := initSchemaAndCounter(ctx, insertPool, c.Schema); err != nil {
 		insertPool.Close()
 		if c.selectPool != nil {
 			c.selectPool.Close()
@@ -172,7 +203,21 @@ func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchm
 		return nil, err
 	}
 	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
-	return &Backend{pool: insertPool}, nil
+	return &Backend{pool: insertPool, schema: c.Schema}, nil
+}
+
+// initSchemaAndCounter creates the table and the patient counter table (--patient-counter=reserve) once, at Setup,
+// rather than on every reservation.
+func initSchemaAndCounter(ctx context.Context, pool *pgxpool.Pool, schema SchemaOptions) error {
+	if err := InitSchema(ctx, pool, schema); err != nil {
+		return err
+	}
+	return CreatePatientCounter(ctx, pool)
+}
+
+// DurabilitySetting reports the synchronous_commit value used for inserts (implements benchmarkgo.DurabilityReporter).
+func (c *Context) DurabilitySetting() string {
+	return "synchronous_commit=" + SynchronousCommit(c.Durability)
 }
 
 // Teardown closes all pools.
@@ -201,43 +246,219 @@ func (c *Context) GetMaxPatientCounter() (int, error) {
 	return GetMaxPatientCounter(context.Background(), conn)
 }
 
-// RunQueryWorker consumes from queryQueue, runs queries_per_record lookups per MRN, reports via benchmarkgo.AddQuery.
+// DeleteOlderThan runs the retention step on the insert pool (implements benchmarkgo.RetentionBackend).
+func (c *Context) DeleteOlderThan(ctx context.Context, cutoff time.Time) (benchmarkgo.RetentionResult, error) {
+	return DeleteOlderThan(ctx, c.insertPool, cutoff, c.Schema)
+}
+
+// SaveSnapshot copies the table into snapshot name on the insert pool (implements benchmarkgo.SnapshotBackend).
+func (c *Context) SaveSnapshot(ctx context.Context, name string) (benchmarkgo.SnapshotResult, error) {
+	return SaveSnapshot(ctx, c.insertPool, name)
+}
+
+// RestoreSnapshot replaces the table's contents with snapshot name on the insert pool.
+func (c *Context) RestoreSnapshot(ctx context.Context, name string) (benchmarkgo.SnapshotResult, error) {
+	return RestoreSnapshot(ctx, c.insertPool, name)
+}
+
+// RunConflictWriters runs the conflicting-writer scenario (implements benchmarkgo.ConflictBackend).
+func (c *Context) RunConflictWriters(ctx context.Context, opts benchmarkgo.ConflictOptions, rec *benchmarkgo.ConflictRecorder) error {
+	return RunConflictWriters(ctx, c.insertPool, c.Schema, opts, rec)
+}
+
+// CountKeys counts generated patients' MRNs on the insert pool (implements benchmarkgo.KeyCounter).
+func (c *Context) CountKeys(ctx context.Context) (int64, error) {
+	return CountKeys(ctx, c.insertPool)
+}
+
+// BulkLoad copies rows in on the insert pool (implements benchmarkgo.BulkLoader).
+func (c *Context) BulkLoad(ctx context.Context, rows []benchmarkgo.RowForDB) (int64, error) {
+	return CopyRows(ctx, c.insertPool, rows)
+}
+
+// SampleKeys samples generated patient IDs on the insert pool (implements benchmarkgo.KeySampler).
+func (c *Context) SampleKeys(ctx context.Context, limit int) ([]string, error) {
+	return SampleKeys(ctx, c.insertPool, limit)
+}
+
+// CountRows counts the table's rows on the insert pool (implements benchmarkgo.RowCounter).
+func (c *Context) CountRows(ctx context.Context) (int64, error) {
+	return CountRows(ctx, c.insertPool)
+}
+
+// DescribeSchema renders the table's DDL from the catalog on the insert pool (implements benchmarkgo.SchemaDescriber).
+func (c *Context) DescribeSchema(ctx context.Context) (string, error) {
+	return DescribeSchema(ctx, c.insertPool)
+}
+
+// CacheCounters reads shared-buffer hit/read counters on the insert pool (implements benchmarkgo.CacheReporter).
+func (c *Context) CacheCounters(ctx context.Context) (int64, int64, error) {
+	return CacheCounters(ctx, c.insertPool)
+}
+
+// ServerMetrics samples pg_stat_* views on the insert pool (implements benchmarkgo.ServerMetricsReporter).
+func (c *Context) ServerMetrics(ctx context.Context) (map[string]float64, error) {
+	return ServerMetrics(ctx, c.insertPool)
+}
+
+// ServerVersion reports the server version on the insert pool (implements benchmarkgo.VersionReporter).
+func (c *Context) ServerVersion(ctx context.Context) (string, error) {
+	return ServerVersion(ctx, c.insertPool)
+}
+
+// TableBytes measures the table's on-disk size on the insert pool (implements benchmarkgo.StorageReporter).
+func (c *Context) TableBytes(ctx context.Context) (int64, error) {
+	return TableBytes(ctx, c.insertPool, c.Schema)
+}
+
+// ShardRows reports per-shard row counts on the insert pool (implements benchmarkgo.ShardReporter).
+func (c *Context) ShardRows(ctx context.Context) ([]benchmarkgo.ShardRows, error) {
+	return ShardRows(ctx, c.insertPool, c.Schema)
+}
+
+// ReservePatientRange leases ordinals from the shared counter table (implements benchmarkgo.CounterReserver).
+func (c *Context) ReservePatientRange(ctx context.Context, floor, n int) (int, error) {
+	return ReservePatientRange(ctx, c.insertPool, floor, n)
+}
+
+// CountMRN counts rows with the MRN on a select connection (read-after-write checks of a dual-write run).
+func (c *Context) CountMRN(ctx context.Context, mrn string) (int, error) {
+	pool := c.selectPool
+	if pool == nil {
+		pool = c.insertPool
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+	return QueryByPrimaryKey(ctx, conn, mrn, c.Schema)
+}
+
+// replicaAddr splits a host or host:port replica address, defaulting the port to the primary's.
+func replicaAddr(addr string, defaultPort int) (string, int) {
+	host, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, defaultPort
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return host, defaultPort
+	}
+	return host, port
+}
+
+// replicaPollInterval spaces the replica lookups of a record that is not visible there yet.
+const replicaPollInterval = 5 * time.Millisecond
+
+// waitReplicaVisible polls mrn on the replica connection until it is returned or the deadline passes, and
+// records the lag from insertTime. Failed lookups are counted as query errors and retried.
+func (c *Context) waitReplicaVisible(ctx context.Context, conn *pgxpool.Conn, mrn string, insertTime time.Time) {
+	deadline := insertTime.Add(c.ReplicaTimeout)
+	for {
+		n, err := QueryByPrimaryKey(ctx, conn, mrn, c.Schema)
+		if err != nil {
+			benchmarkgo.AddError(benchmarkgo.ErrOpQuery, err)
+		} else if n >= 1 {
+			benchmarkgo.AddVisibilityLag(time.Since(insertTime), true)
+			return
+		}
+		if time.Now().After(deadline) {
+			benchmarkgo.AddVisibilityLag(0, false)
+			return
+		}
+		time.Sleep(replicaPollInterval)
+	}
+}
+
+// querier binds an acquired select connection to benchmarkgo.Querier.
+type querier struct {
+	conn   *pgxpool.Conn
+	schema SchemaOptions
+}
+
+func (q querier) QueryByPrimaryKey(ctx context.Context, mrn string) (int, error) {
+	return QueryByPrimaryKey(ctx, q.conn, mrn, q.schema)
+}
+
+func (q querier) QueryByPrimaryKeyTimed(ctx context.Context, mrn string) (int, time.Duration, error) {
+	return QueryByPrimaryKeyTimed(ctx, q.conn, mrn, q.schema)
+}
+
+func (q querier) FetchByPrimaryKey(ctx context.Context, mrn string, withPayload bool) (int, int64, error) {
+	return FetchByPrimaryKey(ctx, q.conn, mrn, withPayload)
+}
+
+func (q querier) FetchVerifyColumns(ctx context.Context, mrn string) (*benchmarkgo.VerifyColumns, error) {
+	return FetchVerifyColumns(ctx, q.conn, mrn)
+}
+
+func (q querier) FetchChecksumRow(ctx context.Context, mrn string) (*benchmarkgo.ChecksumRow, error) {
+	return FetchChecksumRow(ctx, q.conn, mrn)
+}
+
+func (q querier) FetchSource(ctx context.Context, mrn string) (string, error) {
+	return FetchSource(ctx, q.conn, mrn)
+}
+
+func (q querier) QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error) {
+	return QueryResultSet(ctx, q.conn, mrn, limit)
+}
+
+func (q querier) QueryByPatientID(ctx context.Context, patientID string) (int, error) {
+	return QueryByPatientID(ctx, q.conn, patientID)
+}
+
+func (q querier) AggregateByPatientID(ctx context.Context, patientID string) (int, error) {
+	return AggregateByPatientID(ctx, q.conn, patientID)
+}
+
+func (q querier) QueryByNameDOB(ctx context.Context, lastName, firstName, dateOfBirth string) (int, error) {
+	return QueryByNameDOB(ctx, q.conn, lastName, firstName, dateOfBirth)
+}
+
+func (q querier) QueryPageOffset(ctx context.Context, offset, limit int) (int, error) {
+	return QueryPageOffset(ctx, q.conn, offset, limit)
+}
+
+func (q querier) QueryPageAfter(ctx context.Context, lastName, firstName, patientID string, limit int) (int, error) {
+	return QueryPageAfter(ctx, q.conn, lastName, firstName, patientID, limit)
+}
+
+func (q querier) QueryPatientRange(ctx context.Context, patientID string, from, to time.Time) (int, error) {
+	return QueryPatientRange(ctx, q.conn, patientID, from, to)
+}
+
+// RunQueryWorker consumes from queryQueue, runs the configured queries per MRN, reports via benchmarkgo.AddQuery.
 // workerIndex is the 0-based index of this query worker.
 func (c *Context) RunQueryWorker(
 	workerIndex int,
 	queryQueue <-chan *benchmarkgo.QueryJob,
-	queriesPerRecord int,
-	queryDelaySec float64,
-	ignoreSelectErrors bool,
+	opts benchmarkgo.QueryOptions,
 ) {
 	_ = workerIndex // reserved for logging/tracing
+	runner := benchmarkgo.NewQueryRunner(opts)
 	for job := range queryQueue {
 		if job == nil {
 			return
 		}
-		if queryDelaySec > 0 {
-			deadline := job.InsertTime.Add(time.Duration(queryDelaySec * float64(time.Second)))
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
 			if time.Now().Before(deadline) {
 				time.Sleep(time.Until(deadline))
 			}
 		}
+		t0 := time.Now()
 		conn, err := c.selectPool.Acquire(context.Background())
+		benchmarkgo.AddQueryPoolWait(time.Since(t0))
 		if err != nil {
 			continue
 		}
-		t0 := time.Now()
-		var failed int
-		for i := 0; i < queriesPerRecord; i++ {
-			n, _ := QueryByPrimaryKey(context.Background(), conn, job.MRN)
-			if n != 1 {
-				failed++
-				if !ignoreSelectErrors {
-					log.Printf("Query by primary key returned %d rows for MEDICAL_RECORD_NUMBER=%s (expected 1)", n, job.MRN)
-				}
-			}
+		if c.ReplicaHost != "" && !job.InsertTime.IsZero() {
+			c.waitReplicaVisible(context.Background(), conn, job.MRN, job.InsertTime)
 		}
-		latencyMicros := time.Since(t0).Microseconds()
+		count, failed, latency := runner.Run(context.Background(), querier{conn, c.Schema}, job)
 		conn.Release()
-		benchmarkgo.AddQuery(int64(queriesPerRecord), latencyMicros, int64(failed))
+		benchmarkgo.AddQuery(int64(count), latency.Microseconds(), int64(failed))
 	}
 }
@@ -0,0 +1,129 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Preload: before the measured run, bulk-load fresh patients above the highest ordinal in the table, so queries
+// are measured against a realistically sized table rather than an empty one. Preloaded rows are not part of the
+// run's stats, and the run clock starts once the preload is done.
+
+// BulkLoader is implemented by WorkerCtx backends with a faster path than InsertBatch for loading many rows at
+// once (COPY for Postgres, large native batches for ClickHouse). Others preload through InsertBatch.
+type BulkLoader interface {
+	BulkLoad(ctx context.Context, rows []RowForDB) (int64, error)
+}
+
+const (
+	preloadBulkRows   = 50000 // rows per BulkLoad call
+	preloadInsertRows = 1000  // rows per InsertBatch call, within the backends' bind-parameter limits
+	preloadLogEvery   = 10 * time.Second
+)
+
+// PreloadReport is the bulk load that ran before the measured run.
+type PreloadReport struct {
+	Rows       int64   `json:"rows"`
+	Sec        float64 `json:"sec"`
+	RowsPerSec float64 `json:"rows_per_sec"`
+	Method     string  `json:"method"` // "bulk" (BulkLoader) or "insert" (InsertBatch)
+}
+
+// preloadRows builds n original patients from ordinal start on, as insert workers pass them to InsertBatch.
+func preloadRows(start, n int) []RowForDB {
+	rows := make([]RowForDB, n)
+	for i := range rows {
+		p := GenerateOnePatient(start+i, true)
+		jsonMsg, _ := p.ToJSONRef()
+		rows[i] = RowForDB{p.PatientID, patientMessageType, ExpandPayload(jsonMsg, p.PayloadIndex)}
+	}
+	return rows
+}
+
+// runPreload loads Config.PreloadRows patients from ordinal start on, one chunk at a time on each of Workers
+// goroutines. A failed chunk is fatal, since the run would otherwise measure a smaller table than asked for.
+func (r *LoadRunner) runPreload(ctx context.Context, start int) {
+	total := int64(r.Config.PreloadRows)
+	bl, bulk := r.WorkerCtx.(BulkLoader)
+	chunk, method := int64(preloadInsertRows), "insert"
+	if bulk {
+		chunk, method = preloadBulkRows, "bulk"
+	}
+	log.Printf("Preload: %d rows (patients %d-%d) via %s ...", total, start, int64(start)+total-1, method)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	t0 := time.Now()
+	var next, done atomic.Int64
+	var errOnce sync.Once
+	var loadErr error
+	var wg sync.WaitGroup
+	for range max(r.Config.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				from := next.Add(chunk) - chunk
+				if from >= total {
+					return
+				}
+				rows := preloadRows(start+int(from), int(min(chunk, total-from)))
+				var n int64
+				var err error
+				if bulk {
+					n, err = bl.BulkLoad(ctx, rows)
+				} else {
+					conn := r.backend.GetConn()
+					var res InsertResult
+					res, err = r.backend.InsertBatch(conn, rows, "")
+					r.backend.ReleaseConn(conn)
+					n = int64(res.Rows)
+				}
+				if err != nil {
+					errOnce.Do(func() {
+						loadErr = err
+						cancel()
+					})
+					return
+				}
+				done.Add(n)
+			}
+		}()
+	}
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	ticker := time.NewTicker(preloadLogEvery)
+	defer ticker.Stop()
+	for waiting := true; waiting; {
+		select {
+		case <-ticker.C:
+			n := done.Load()
+			log.Printf("Preload: %d / %d rows (%.0f%%, %.0f rows/sec)", n, total, float64(n)/float64(total)*100, float64(n)/time.Since(t0).Seconds())
+		case <-finished:
+			waiting = false
+		}
+	}
+	if loadErr != nil {
+		log.Fatalf("Preload: %v", loadErr)
+	}
+	if ctx.Err() != nil {
+		log.Fatalf("Preload: interrupted after %d rows", done.Load())
+	}
+	sec := time.Since(t0).Seconds()
+	r.preload = &PreloadReport{Rows: done.Load(), Sec: sec, RowsPerSec: float64(done.Load()) / sec, Method: method}
+	log.Printf("Preload: %d rows in %.1fs (%.0f rows/sec via %s)", r.preload.Rows, sec, r.preload.RowsPerSec, method)
+}
+
+// logPreload logs the preload ahead of the run's stats (only when one ran).
+func logPreload(rep *PreloadReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Preload before the run (excluded from these stats): %d rows in %.1fs (%.0f rows/sec via %s)",
+		rep.Rows, rep.Sec, rep.RowsPerSec, rep.Method)
+}
@@ -6,70 +6,98 @@ import (
 	"math/rand"
 	"strings"
 	"sync/atomic"
+	"time"
 )
 
 const patientMessageType = "PATIENT"
 
 // Producer holds state for one producer goroutine and produces batches of records.
-// Patient ordinals are derived from NextBatchIndex (batch index) so batches are deterministic; no nextID contention.
+// Patient ordinals are derived from NextBatchIndex (batch index) through Patients, so batches are deterministic; no nextID contention.
 type Producer struct {
-	Index            int
-	BatchSize        int
-	PatientStartBase int
-	NextBatchIndex   *atomic.Int64 // shared; batch index → TargetDB and patient ordinal range
-	DuplicateRatio   float64
-	ProducerQueue    chan<- *InsertPair
-	RecvCh           <-chan struct{}
-	SendCh           chan<- struct{}
+	Index          int
+	BatchSize      int
+	Patients       *PatientAllocator // shared; logical patient index → unique ordinal
+	NextBatchIndex *atomic.Int64     // shared; batch index → TargetDB and patient ordinal range
+	RowsLeft       *atomic.Int64     // shared; rows still to generate under Config.TotalRows (nil = no limit)
+	DuplicateRatio float64
+	DuplicateLag   int   // duplicates re-send the original from about this many rows earlier (0 = any earlier original)
+	Seed           int64 // with the batch index, seeds each batch's duplicate and payload choices
+	ProducerQueue  chan<- *InsertPair
+	RecvCh         <-chan struct{}
+	SendCh         chan<- struct{}
+	Pipeline       *Pipeline
 }
 
 // NewProducer builds a Producer. Pairs are built on each send using batch index for patient ordinals.
 func NewProducer(
 	index int,
 	batchSize int,
-	patientStartBase int,
+	patients *PatientAllocator,
 	nextBatchIndex *atomic.Int64,
+	rowsLeft *atomic.Int64,
 	duplicateRatio float64,
+	duplicateLag int,
+	seed int64,
 	producerQueue chan<- *InsertPair,
 	recvCh <-chan struct{},
 	sendCh chan<- struct{},
+	pipeline *Pipeline,
 ) *Producer {
 	return &Producer{
-		Index:            index,
-		BatchSize:        batchSize,
-		PatientStartBase: patientStartBase,
-		NextBatchIndex:   nextBatchIndex,
-		DuplicateRatio:   duplicateRatio,
-		ProducerQueue:    producerQueue,
-		RecvCh:           recvCh,
-		SendCh:           sendCh,
+		Index:          index,
+		BatchSize:      batchSize,
+		Patients:       patients,
+		NextBatchIndex: nextBatchIndex,
+		RowsLeft:       rowsLeft,
+		DuplicateRatio: duplicateRatio,
+		DuplicateLag:   duplicateLag,
+		Seed:           seed,
+		ProducerQueue:  producerQueue,
+		RecvCh:         recvCh,
+		SendCh:         sendCh,
+		Pipeline:       pipeline,
 	}
 }
 
-// buildInsertPair builds one InsertPair for the given batch index. Patient ordinals are deterministic:
-// originals at patientStartBase + batchIndex*batchSize + i; duplicates random in [patientStartBase, patientStartBase + batchIndex*batchSize).
-// Batch 0 has no duplicate range so all originals.
-func buildInsertPair(batchSize int, patientStartBase int, batchIndex int64, duplicateRatio float64) *InsertPair {
+// buildInsertPair builds one InsertPair for the given batch index. Logical patient indexes are deterministic:
+// originals at batchIndex*batchSize + i; duplicates random in [0, batchIndex*batchSize). patients maps them to ordinals.
+// Batch 0 has no duplicate range so all originals. With lag > 0 a duplicate instead re-sends the original from
+// between lag/2 and 3*lag/2 indexes back (in an earlier batch), and is an original while there is none that old.
+// Random choices come from a source seeded by seed and batchIndex, so runs with the same seed generate the same
+// stream regardless of which producer builds which batch. Duplicates keep one row per patient: an upsert statement
+// cannot touch the same MRN twice (Postgres ON CONFLICT, DynamoDB BatchWriteItem). It fails when an ordinal cannot
+// be allocated.
+func buildInsertPair(batchSize int, patients *PatientAllocator, batchIndex int64, duplicateRatio float64, lag int, seed int64) (*InsertPair, error) {
+	rng := rand.New(rand.NewSource(seed + batchIndex))
 	batch := make([]*Record, 0, batchSize)
-	base := patientStartBase + int(batchIndex)*batchSize
-	dupEnd := base // exclusive upper bound for duplicate ordinals (batch 0: no duplicates)
+	base := int(batchIndex) * batchSize
+	dupEnd := base // exclusive upper bound for duplicate indexes (batch 0: no duplicates)
 	for i := 0; i < batchSize; i++ {
-		var ordinal int
 		var isOriginal bool
-		if rand.Float64() < duplicateRatio && dupEnd > patientStartBase {
-			ordinal = patientStartBase + rand.Intn(dupEnd-patientStartBase)
-			isOriginal = false
-		} else {
-			ordinal = base + i
-			isOriginal = true
+		dup := -1
+		if rng.Float64() < duplicateRatio && dupEnd > 0 {
+			if lag > 0 {
+				dup = min(base+i-lag/2-rng.Intn(lag+1), dupEnd-1)
+			} else {
+				dup = rng.Intn(dupEnd)
+			}
+		}
+		k := dup
+		if dup < 0 {
+			k, isOriginal = base+i, true
+		}
+		ordinal, err := patients.Ordinal(k)
+		if err != nil {
+			return nil, err
 		}
-		p := GenerateOnePatient(ordinal, isOriginal)
-		jsonMsg, _ := p.ToJSON()
+		p := generatePatient(ordinal, isOriginal, rng.Intn(len(payloadPool)))
+		jsonMsg, _ := p.ToJSONRef()
 		batch = append(batch, &Record{
-			PatientID:   p.PatientID,
-			MessageType: patientMessageType,
-			JSONMessage: jsonMsg,
-			IsOriginal:  p.IsOriginal,
+			PatientID:    p.PatientID,
+			MessageType:  patientMessageType,
+			JSONMessage:  jsonMsg,
+			IsOriginal:   p.IsOriginal,
+			PayloadIndex: p.PayloadIndex,
 		})
 	}
 	var originals []*Record
@@ -84,14 +112,23 @@ func buildInsertPair(batchSize int, patientStartBase int, batchIndex int64, dupl
 		if r == nil || r.IsOriginal {
 			continue
 		}
-		key := r.PatientID + "\x00" + r.MessageType + "\x00" + r.JSONMessage
-		if _, ok := seen[key]; ok {
+		if _, ok := seen[r.PatientID]; ok {
 			continue
 		}
-		seen[key] = struct{}{}
+		seen[r.PatientID] = struct{}{}
 		duplicates = append(duplicates, r)
 	}
-	return &InsertPair{Originals: originals, Duplicates: duplicates}
+	return &InsertPair{Originals: originals, Duplicates: duplicates}, nil
+}
+
+// truncate keeps the first n rows of the pair, originals before duplicates, so the last batch of a
+// Config.TotalRows run lands exactly on the target.
+func (p *InsertPair) truncate(n int) {
+	if n < len(p.Originals) {
+		p.Originals, p.Duplicates = p.Originals[:n], nil
+		return
+	}
+	p.Duplicates = p.Duplicates[:n-len(p.Originals)]
 }
 
 // buildQueryHint builds the single query hint string to prepend to the INSERT (two separate comments: pgbouncer.database, pgbouncer.patient_ids).
@@ -116,11 +153,13 @@ func buildQueryHint(batchIndex int64, originals []*Record) string {
 	return prefix
 }
 
-// Run produces batches and enqueues them until ctx is cancelled.
-// Each batch is built from the current batch index (patient ordinals = patientStartBase + batchIndex*batchSize + i).
-func (p *Producer) Run(ctx context.Context) {
+// Run produces batches and enqueues them until ctx is cancelled or RowsLeft runs out.
+// Each batch is built from the current batch index (logical patient indexes = batchIndex*batchSize + i).
+// A batch that cannot be built (no patient ordinals) ends the producer with the error; it keeps the generation
+// token, so the other producers wait until the caller cancels ctx.
+func (p *Producer) Run(ctx context.Context) error {
 	if p.BatchSize <= 0 {
-		return
+		return nil
 	}
 	for {
 		select {
@@ -130,16 +169,29 @@ func (p *Producer) Run(ctx context.Context) {
 				p.SendCh <- struct{}{}
 			default:
 			}
-			return
+			return nil
 		case <-p.RecvCh:
 		}
-		if ctx.Err() != nil {
+		// The token serializes generation, so RowsLeft cannot change between this check and the Add below.
+		if ctx.Err() != nil || (p.RowsLeft != nil && p.RowsLeft.Load() <= 0) {
 			p.SendCh <- struct{}{}
-			return
+			return nil
 		}
+		t0 := time.Now()
 		idx := p.NextBatchIndex.Add(1) - 1
-		pair := buildInsertPair(p.BatchSize, p.PatientStartBase, idx, p.DuplicateRatio)
+		pair, err := buildInsertPair(p.BatchSize, p.Patients, idx, p.DuplicateRatio, p.DuplicateLag, p.Seed)
+		if err != nil {
+			return err
+		}
+		if p.RowsLeft != nil {
+			n := len(pair.Originals) + len(pair.Duplicates)
+			if left := p.RowsLeft.Add(int64(-n)); left < 0 {
+				pair.truncate(n + int(left))
+			}
+		}
 		pair.QueryHint = buildQueryHint(idx, pair.Originals)
+		p.Pipeline.Record(StageGenerate, len(pair.Originals)+len(pair.Duplicates), time.Since(t0), 0)
+		t1 := time.Now()
 		select {
 		case <-ctx.Done():
 			select {
@@ -147,8 +199,9 @@ func (p *Producer) Run(ctx context.Context) {
 				p.SendCh <- struct{}{}
 			default:
 			}
-			return
+			return nil
 		case p.ProducerQueue <- pair:
+			producerBlockedMicros.Add(time.Since(t1).Microseconds())
 			p.SendCh <- struct{}{}
 		}
 	}
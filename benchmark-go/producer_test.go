@@ -0,0 +1,134 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBuildInsertPairDuplicates(t *testing.T) {
+	const batchSize = 100
+	tests := []struct {
+		name  string
+		ratio float64
+		lag   int
+	}{
+		{name: "no duplicates", ratio: 0},
+		{name: "any earlier original", ratio: 0.25},
+		{name: "all duplicates", ratio: 1},
+		{name: "lag under a batch", ratio: 0.5, lag: 10},
+		{name: "lag of half a batch", ratio: 0.5, lag: batchSize / 2},
+		{name: "lag of one batch", ratio: 0.5, lag: batchSize},
+		{name: "lag of two batches", ratio: 0.5, lag: 2 * batchSize},
+		{name: "lag of ten batches", ratio: 0.5, lag: 10 * batchSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patients := &PatientAllocator{mode: PatientCounterMax, stride: 1}
+			for idx := int64(0); idx < 30; idx++ {
+				pair, err := buildInsertPair(batchSize, patients, idx, tt.ratio, tt.lag, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := len(pair.Originals) + len(pair.Duplicates); n > batchSize {
+					t.Fatalf("batch %d: %d rows, want at most %d", idx, n, batchSize)
+				}
+				if idx == 0 && len(pair.Duplicates) > 0 {
+					t.Errorf("batch 0: %d duplicates, want none (no earlier originals)", len(pair.Duplicates))
+				}
+				mrns := make(map[string]bool)
+				for _, r := range pair.Originals {
+					if mrns[mrnForPatientID(r.PatientID)] {
+						t.Errorf("batch %d: original %s repeated", idx, r.PatientID)
+					}
+					mrns[mrnForPatientID(r.PatientID)] = true
+				}
+				for _, r := range pair.Duplicates {
+					mrn := mrnForPatientID(r.PatientID)
+					if mrns[mrn] {
+						t.Errorf("batch %d: duplicate %s repeats an MRN already in the batch", idx, mrn)
+					}
+					mrns[mrn] = true
+					if ord := patientOrdinal(t, r.PatientID); ord >= int(idx)*batchSize {
+						t.Errorf("batch %d: duplicate of ordinal %d, want one from an earlier batch", idx, ord)
+					}
+				}
+			}
+		})
+	}
+}
+
+// patientOrdinal is the ordinal generatePatient encoded in patientID.
+func patientOrdinal(t *testing.T, patientID string) int {
+	t.Helper()
+	var ord int
+	if _, err := fmt.Sscanf(patientID, "patient-%d", &ord); err != nil {
+		t.Fatalf("patient ID %q: %v", patientID, err)
+	}
+	return ord
+}
+
+func TestValidateDuplicateLag(t *testing.T) {
+	tests := []struct {
+		lagSec  float64
+		rps     int
+		wantErr bool
+	}{
+		{lagSec: 0, rps: 1000},
+		{lagSec: 0.2, rps: 1000},
+		{lagSec: 60, rps: 1000},
+		{lagSec: 0.5, rps: 100, wantErr: true},
+		{lagSec: 0.19, rps: 1000, wantErr: true},
+		{lagSec: -1, rps: 1000, wantErr: true},
+	}
+	for _, tt := range tests {
+		c := DefaultConfig("sqlite") // 100-row batches
+		c.DuplicateLagSec, c.TargetRPS = tt.lagSec, tt.rps
+		if err := c.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("DuplicateLagSec %g at %d rows/sec: error = %v, want error %v", tt.lagSec, tt.rps, err, tt.wantErr)
+		}
+	}
+}
+
+func TestInsertPairTruncate(t *testing.T) {
+	tests := []struct {
+		originals, duplicates int
+		n                     int
+		wantOrig, wantDup     int
+	}{
+		{originals: 75, duplicates: 25, n: 0, wantOrig: 0, wantDup: 0},
+		{originals: 75, duplicates: 25, n: 1, wantOrig: 1, wantDup: 0},
+		{originals: 75, duplicates: 25, n: 74, wantOrig: 74, wantDup: 0},
+		{originals: 75, duplicates: 25, n: 75, wantOrig: 75, wantDup: 0},
+		{originals: 75, duplicates: 25, n: 76, wantOrig: 75, wantDup: 1},
+		{originals: 75, duplicates: 25, n: 99, wantOrig: 75, wantDup: 24},
+		{originals: 75, duplicates: 25, n: 100, wantOrig: 75, wantDup: 25},
+		{originals: 100, duplicates: 0, n: 40, wantOrig: 40, wantDup: 0},
+		{originals: 100, duplicates: 0, n: 100, wantOrig: 100, wantDup: 0},
+		{originals: 0, duplicates: 30, n: 10, wantOrig: 0, wantDup: 10},
+	}
+	for _, tt := range tests {
+		p := &InsertPair{}
+		for i := 0; i < tt.originals; i++ {
+			p.Originals = append(p.Originals, &Record{PatientID: fmt.Sprintf("o%d", i), IsOriginal: true})
+		}
+		for i := 0; i < tt.duplicates; i++ {
+			p.Duplicates = append(p.Duplicates, &Record{PatientID: fmt.Sprintf("d%d", i)})
+		}
+		p.truncate(tt.n)
+		if len(p.Originals) != tt.wantOrig || len(p.Duplicates) != tt.wantDup {
+			t.Errorf("%d originals + %d duplicates truncated to %d: kept %d + %d, want %d + %d",
+				tt.originals, tt.duplicates, tt.n, len(p.Originals), len(p.Duplicates), tt.wantOrig, tt.wantDup)
+			continue
+		}
+		for i, r := range p.Originals {
+			if r.PatientID != fmt.Sprintf("o%d", i) {
+				t.Errorf("truncate(%d): original %d is %s, want the first rows kept in order", tt.n, i, r.PatientID)
+			}
+		}
+		for i, r := range p.Duplicates {
+			if r.PatientID != fmt.Sprintf("d%d", i) {
+				t.Errorf("truncate(%d): duplicate %d is %s, want the first rows kept in order", tt.n, i, r.PatientID)
+			}
+		}
+	}
+}
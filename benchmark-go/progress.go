@@ -3,6 +3,8 @@ package benchmarkgo
 import (
 	"fmt"
 	"log"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -17,11 +19,17 @@ var (
 	insertLatencyMicros atomic.Int64
 	insertStatements    atomic.Int64
 	insertStarted       atomic.Int64
+	insertAttempted     atomic.Int64 // rows handed to InsertBatch, failed batches included
+	insertBytes         atomic.Int64 // bytes written by successful inserts (server-reported, else message bytes)
 	insertPostgres1     atomic.Int64 // rows inserted via pgbouncer.database=postgres1
 	insertPostgres2     atomic.Int64 // rows inserted via pgbouncer.database=postgres2
+	upsertInserted      atomic.Int64 // rows the database reports as newly inserted (backends that can tell)
+	upsertUpdated       atomic.Int64 // rows the database reports as conflict-updated
+	upsertReported      atomic.Bool  // set once any backend reports an upsert outcome
 	queryCount          atomic.Int64
 	queryLatencyMicros  atomic.Int64
 	queryFailed         atomic.Int64
+	targetRPS           atomic.Int64 // current target insert rate (initial config, then live changes)
 )
 
 func init() {
@@ -35,6 +43,17 @@ func AddInsert(total, originals, duplicates, latencyMicros, statements int64) {
 	insertDuplicates.Add(duplicates)
 	insertLatencyMicros.Add(latencyMicros)
 	insertStatements.Add(statements)
+	noteDutyInsert(latencyMicros)
+}
+
+// AddInsertBytes records bytes written by a successful insert.
+func AddInsertBytes(n int64) {
+	insertBytes.Add(n)
+}
+
+// AddInsertAttempted records rows handed to the backend, whether or not the insert succeeds.
+func AddInsertAttempted(rows int64) {
+	insertAttempted.Add(rows)
 }
 
 // AddInsertStarted records one batch handed to a worker (incoming).
@@ -52,11 +71,64 @@ func AddInsertToDB(db string, count int64) {
 	}
 }
 
+// AddUpsertOutcome records the database-reported outcome of an upsert: rows newly inserted vs updated on conflict.
+func AddUpsertOutcome(inserted, updated int64) {
+	upsertInserted.Add(inserted)
+	upsertUpdated.Add(updated)
+	upsertReported.Store(true)
+}
+
 // AddQuery records a query batch. Latency is in microseconds.
 func AddQuery(count, latencyMicros, failed int64) {
 	queryCount.Add(count)
 	queryLatencyMicros.Add(latencyMicros)
 	queryFailed.Add(failed)
+	noteDutyQuery(count, latencyMicros)
+}
+
+// resultSetStats aggregates result-set queries per requested LIMIT; guarded by resultSetMu.
+var (
+	resultSetMu    sync.Mutex
+	resultSetStats = make(map[int]*ResultSetBucket)
+)
+
+// AddResultSetQuery records one result-set query: requested limit, rows and bytes actually returned, latency in microseconds.
+func AddResultSetQuery(limit int, rows, bytes, latencyMicros int64) {
+	resultSetMu.Lock()
+	b := resultSetStats[limit]
+	if b == nil {
+		b = &ResultSetBucket{Limit: limit}
+		resultSetStats[limit] = b
+	}
+	b.Count++
+	b.Rows += rows
+	b.Bytes += bytes
+	b.TotalLatencySec += float64(latencyMicros) / 1e6
+	resultSetMu.Unlock()
+}
+
+// Session stats: per-step counts/latency and whole-session wall time (think time included).
+var (
+	sessionStepCount         [numSessionSteps]atomic.Int64
+	sessionStepLatencyMicros [numSessionSteps]atomic.Int64
+	sessionStepFailed        [numSessionSteps]atomic.Int64
+	sessionCount             atomic.Int64
+	sessionWallMicros        atomic.Int64
+)
+
+// AddSessionStep records one session query step (SessionStepLookup..SessionStepAggregate). Latency is in microseconds.
+func AddSessionStep(step int, latencyMicros int64, failed bool) {
+	sessionStepCount[step].Add(1)
+	sessionStepLatencyMicros[step].Add(latencyMicros)
+	if failed {
+		sessionStepFailed[step].Add(1)
+	}
+}
+
+// AddSession records one completed session with its wall time in microseconds.
+func AddSession(wallMicros int64) {
+	sessionCount.Add(1)
+	sessionWallMicros.Add(wallMicros)
 }
 
 // padRight returns s padded with spaces on the right to width w.
@@ -86,53 +158,150 @@ const (
 
 // Snapshot is the final aggregated state, sent on resultCh when doneCh is closed.
 type Snapshot struct {
-	Inserted InsertedStats
-	Queries  QueryStats
+	Inserted    InsertedStats
+	Queries     QueryStats
+	ResultSets  []ResultSetBucket   // sorted by Limit; empty unless result-set queries ran
+	Sessions    *SessionStats       // nil unless session queries ran
+	Errors      []ErrorCount        // per (op, native code), most frequent first
+	Warnings    []WarningCount      // insert warnings per code, most frequent first
+	Attribution *LatencyAttribution // nil unless latency sampling ran against a ServerTimedQuerier
+	DualWrite   *DualWriteReport    // nil unless a dual-write backend ran
+	Visibility  *VisibilityReport   // nil unless a backend polled for visibility lag
 }
 
 // InsertedStats holds aggregated insert stats.
 type InsertedStats struct {
-	Total                 float64
-	Originals             float64
-	Duplicates            float64
-	TotalInsertLatencySec float64
-	InsertStatements      float64
-	Postgres1             float64 // rows inserted via pgbouncer.database=postgres1
-	Postgres2             float64 // rows inserted via pgbouncer.database=postgres2
+	Total                 float64 `json:"total"`
+	Attempted             float64 `json:"attempted"` // rows handed to the backend, failed batches included
+	Originals             float64 `json:"originals"`
+	Duplicates            float64 `json:"duplicates"`
+	TotalInsertLatencySec float64 `json:"total_insert_latency_sec"`
+	InsertStatements      float64 `json:"insert_statements"`
+	Bytes                 float64 `json:"bytes"`                // bytes written by successful inserts: server-reported, else message bytes sent
+	ServerSec             float64 `json:"server_sec"`           // server-reported insert time, summed
+	ServerTimedBatches    float64 `json:"server_timed_batches"` // batches that reported a server time
+	Postgres1             float64 `json:"postgres1"`            // rows inserted via pgbouncer.database=postgres1
+	Postgres2             float64 `json:"postgres2"`            // rows inserted via pgbouncer.database=postgres2
+	// DBInserted/DBUpdated are the database-reported upsert outcome; nil when the backend cannot tell (e.g. ClickHouse appends).
+	DBInserted *float64 `json:"db_inserted,omitempty"`
+	DBUpdated  *float64 `json:"db_updated,omitempty"`
+	// Latency is the distribution of successful batch round trips (service time); nil before the first insert.
+	Latency *LatencyPercentiles `json:"latency,omitempty"`
+	// EndToEnd is the per-record distribution from the intended start on the rate schedule to commit, which
+	// includes queueing behind a saturated backend; nil before the first insert.
+	EndToEnd *LatencyPercentiles `json:"end_to_end_latency,omitempty"`
 }
 
 // QueryStats holds aggregated query stats.
 type QueryStats struct {
-	Count           float64
-	TotalLatencySec float64
-	FailedCount     float64
+	Count           float64 `json:"count"`
+	TotalLatencySec float64 `json:"total_latency_sec"`
+	FailedCount     float64 `json:"failed_count"`
+	// Latency is the distribution of individual query latencies; nil before the first query.
+	Latency *LatencyPercentiles `json:"latency,omitempty"`
+}
+
+// ResultSetBucket holds aggregated result-set query stats for one requested LIMIT.
+type ResultSetBucket struct {
+	Limit           int     `json:"limit"`
+	Count           int64   `json:"count"`
+	Rows            int64   `json:"rows"`
+	Bytes           int64   `json:"bytes"`
+	TotalLatencySec float64 `json:"total_latency_sec"`
+}
+
+// SessionStats holds aggregated per-patient session stats.
+type SessionStats struct {
+	Count        int64              `json:"count"`
+	TotalWallSec float64            `json:"total_wall_sec"` // includes think time
+	Steps        []SessionStepStats `json:"steps"`
+}
+
+// SessionStepStats holds aggregated stats for one session step.
+type SessionStepStats struct {
+	Name            string  `json:"name"`
+	Count           int64   `json:"count"`
+	Failed          int64   `json:"failed"`
+	TotalLatencySec float64 `json:"total_latency_sec"`
+}
+
+func loadSessions() *SessionStats {
+	n := sessionCount.Load()
+	if n == 0 {
+		return nil
+	}
+	st := &SessionStats{Count: n, TotalWallSec: float64(sessionWallMicros.Load()) / 1e6}
+	for i := 0; i < numSessionSteps; i++ {
+		st.Steps = append(st.Steps, SessionStepStats{
+			Name:            sessionStepNames[i],
+			Count:           sessionStepCount[i].Load(),
+			Failed:          sessionStepFailed[i].Load(),
+			TotalLatencySec: float64(sessionStepLatencyMicros[i].Load()) / 1e6,
+		})
+	}
+	return st
+}
+
+// loadResultSets copies the per-limit result-set buckets, sorted by limit.
+func loadResultSets() []ResultSetBucket {
+	resultSetMu.Lock()
+	defer resultSetMu.Unlock()
+	out := make([]ResultSetBucket, 0, len(resultSetStats))
+	for _, b := range resultSetStats {
+		out = append(out, *b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Limit < out[j].Limit })
+	return out
 }
 
 // loadSnapshot reads current atomic counters into a Snapshot (latency from micros to sec).
 func loadSnapshot() Snapshot {
 	insLat := insertLatencyMicros.Load()
 	qLat := queryLatencyMicros.Load()
-	return Snapshot{
+	snap := Snapshot{
 		Inserted: InsertedStats{
 			Total:                 float64(insertTotal.Load()),
+			Attempted:             float64(insertAttempted.Load()),
 			Originals:             float64(insertOriginals.Load()),
-			Duplicates:             float64(insertDuplicates.Load()),
+			Duplicates:            float64(insertDuplicates.Load()),
 			TotalInsertLatencySec: float64(insLat) / 1e6,
 			InsertStatements:      float64(insertStatements.Load()),
+			Bytes:                 float64(insertBytes.Load()),
+			ServerSec:             float64(insertServerMicros.Load()) / 1e6,
+			ServerTimedBatches:    float64(insertServerBatches.Load()),
 			Postgres1:             float64(insertPostgres1.Load()),
 			Postgres2:             float64(insertPostgres2.Load()),
+			Latency:               insertLatencies.total(),
+			EndToEnd:              endToEndLatencies.total(),
 		},
 		Queries: QueryStats{
 			Count:           float64(queryCount.Load()),
 			TotalLatencySec: float64(qLat) / 1e6,
 			FailedCount:     float64(queryFailed.Load()),
+			Latency:         queryLatencies.total(),
 		},
+		ResultSets:  loadResultSets(),
+		Sessions:    loadSessions(),
+		Errors:      loadErrors(),
+		Warnings:    loadWarnings(),
+		Attribution: loadAttribution(),
+		DualWrite:   loadDualWrite(),
+		Visibility:  loadVisibility(),
 	}
+	if upsertReported.Load() {
+		dbInserted := float64(upsertInserted.Load())
+		dbUpdated := float64(upsertUpdated.Load())
+		snap.Inserted.DBInserted = &dbInserted
+		snap.Inserted.DBUpdated = &dbUpdated
+	}
+	return snap
 }
 
 // Reporter holds state for the progress reporting goroutine and logs insert/query progress every interval.
 type Reporter struct {
 	Interval          time.Duration
+	NoQueries         bool // omit the query lines when no query workers run
+	start             time.Time
 	prevInserted      InsertedStats
 	prevInsertStarted int64
 	prevPostgres1     int64
@@ -140,6 +309,17 @@ type Reporter struct {
 	prevQueries       float64
 	prevQueryLatency  float64
 	prevFailed        float64
+	timeline          *timelineCSV // appends each interval when set by WriteCSV
+}
+
+// WriteCSV appends every interval's stats to a CSV file at path (created or truncated now).
+func (r *Reporter) WriteCSV(path string) error {
+	t, err := openTimelineCSV(path)
+	if err != nil {
+		return err
+	}
+	r.timeline = t
+	return nil
 }
 
 // NewReporter creates a Reporter with the given log interval. If interval <= 0, defaultInterval is used.
@@ -147,7 +327,7 @@ func NewReporter(interval time.Duration) *Reporter {
 	if interval <= 0 {
 		interval = defaultInterval
 	}
-	return &Reporter{Interval: interval}
+	return &Reporter{Interval: interval, start: time.Now()}
 }
 
 // Run runs in the calling goroutine and logs insert/query progress every r.Interval.
@@ -155,10 +335,16 @@ func NewReporter(interval time.Duration) *Reporter {
 func (r *Reporter) Run(doneCh <-chan struct{}, resultCh chan<- Snapshot) {
 	ticker := time.NewTicker(r.Interval)
 	defer ticker.Stop()
+	startClientSampling()
 
 	for {
 		select {
 		case <-doneCh:
+			if r.timeline != nil {
+				if err := r.timeline.close(); err != nil {
+					log.Printf("Output CSV: %v", err)
+				}
+			}
 			resultCh <- loadSnapshot()
 			close(resultCh)
 			return
@@ -181,10 +367,20 @@ func (r *Reporter) Run(doneCh <-chan struct{}, resultCh chan<- Snapshot) {
 
 			intervalTotal := int(total - r.prevInserted.Total)
 			intervalOriginals := int(originals - r.prevInserted.Originals)
+			intervalAttempted := int(snap.Inserted.Attempted - r.prevInserted.Attempted)
 			intervalDuplicates := int(duplicates - r.prevInserted.Duplicates)
 			intervalLatency := totalInsertLatency - r.prevInserted.TotalInsertLatencySec
 			intervalStatements := int(insertStatements - r.prevInserted.InsertStatements)
-			r.prevInserted = InsertedStats{total, originals, duplicates, totalInsertLatency, insertStatements, float64(curPostgres1), float64(curPostgres2)}
+			r.prevInserted = InsertedStats{
+				Total:                 total,
+				Attempted:             snap.Inserted.Attempted,
+				Originals:             originals,
+				Duplicates:            duplicates,
+				TotalInsertLatencySec: totalInsertLatency,
+				InsertStatements:      insertStatements,
+				Postgres1:             float64(curPostgres1),
+				Postgres2:             float64(curPostgres2),
+			}
 
 			intervalAvgInsertMs := 0.0
 			if intervalTotal > 0 {
@@ -215,7 +411,11 @@ func (r *Reporter) Run(doneCh <-chan struct{}, resultCh chan<- Snapshot) {
 			}
 
 			colW := 12
-			log.Printf("%s---%s", _colorDim, _colorReset)
+			if warmupExcluded.Load() && currentPhase.Load() == PhaseWarmup {
+				log.Printf("%s--- warmup (excluded from summary)%s", _colorDim, _colorReset)
+			} else {
+				log.Printf("%s---%s", _colorDim, _colorReset)
+			}
 			log.Println(_colorYellow + "  Insert   " + padLeft("incoming", colW) + padLeft("completed", colW) + " " +
 				padLeft("int_tot", colW) + padLeft("int_orig", colW) + padLeft("int_dup", colW) + padLeft("int_avg_ms", colW) + " " +
 				padLeft("cum_tot", colW) + padLeft("cum_orig", colW) + padLeft("cum_dup", colW) + padLeft("cum_avg_ms", colW) + _colorReset)
@@ -235,15 +435,67 @@ func (r *Reporter) Run(doneCh <-chan struct{}, resultCh chan<- Snapshot) {
 				_colorCyan, colW, int(curPostgres1), _colorReset,
 				_colorCyan, colW, intervalPostgres2, _colorReset,
 				_colorCyan, colW, int(curPostgres2), _colorReset)
-			log.Println(_colorYellow + "  Query    " + padLeft("int_queries", colW) + padLeft("int_failed", colW) + padLeft("int_avg_ms", colW) + " " +
-				padLeft("cum_queries", colW) + padLeft("cum_failed", colW) + padLeft("cum_avg_ms", colW) + _colorReset)
-			log.Printf("           %s%*d%s%s%*d%s%s%*.*f%s %s%*.0f%s%s%*.0f%s%s%*.*f%s",
-				_colorCyan, colW, intervalQ, _colorReset,
-				_colorCyan, colW, intervalFailed, _colorReset,
-				_colorCyan, colW, 2, intervalAvgMs, _colorReset,
-				_colorCyan, colW, q, _colorReset,
-				_colorCyan, colW, failed, _colorReset,
-				_colorCyan, colW, 2, avgLatencyMs, _colorReset)
+			intervalSec := r.Interval.Seconds()
+			recordGoodputInterval(GoodputInterval{
+				AtSec:         time.Since(r.start).Seconds(),
+				AttemptedRPS:  float64(intervalAttempted) / intervalSec,
+				ThroughputRPS: float64(intervalTotal) / intervalSec,
+				GoodputRPS:    float64(intervalOriginals) / intervalSec,
+			})
+			goodputPct := 0.0
+			if intervalAttempted > 0 {
+				goodputPct = float64(intervalOriginals) / float64(intervalAttempted) * 100
+			}
+			log.Printf("  Rate     attempted %s%.1f%s rows/s | throughput %s%.1f%s rows/s | goodput %s%.1f%s rows/s (%.0f%% of attempted)",
+				_colorCyan, float64(intervalAttempted)/intervalSec, _colorReset,
+				_colorCyan, float64(intervalTotal)/intervalSec, _colorReset,
+				_colorCyan, float64(intervalOriginals)/intervalSec, _colorReset, goodputPct)
+			if !r.NoQueries {
+				log.Println(_colorYellow + "  Query    " + padLeft("int_queries", colW) + padLeft("int_failed", colW) + padLeft("int_avg_ms", colW) + " " +
+					padLeft("cum_queries", colW) + padLeft("cum_failed", colW) + padLeft("cum_avg_ms", colW) + _colorReset)
+				log.Printf("           %s%*d%s%s%*d%s%s%*.*f%s %s%*.0f%s%s%*.0f%s%s%*.*f%s",
+					_colorCyan, colW, intervalQ, _colorReset,
+					_colorCyan, colW, intervalFailed, _colorReset,
+					_colorCyan, colW, 2, intervalAvgMs, _colorReset,
+					_colorCyan, colW, q, _colorReset,
+					_colorCyan, colW, failed, _colorReset,
+					_colorCyan, colW, 2, avgLatencyMs, _colorReset)
+			}
+			intervalInsertPercentiles := insertLatencies.takeInterval()
+			intervalQueryPercentiles := queryLatencies.takeInterval()
+			log.Printf("  Latency  insert/batch int %s | cum %s", intervalInsertPercentiles, snap.Inserted.Latency)
+			log.Printf("           insert/e2e   int %s | cum %s", endToEndLatencies.takeInterval(), snap.Inserted.EndToEnd)
+			if !r.NoQueries {
+				log.Printf("           query        int %s | cum %s", intervalQueryPercentiles, snap.Queries.Latency)
+			}
+			client := sampleClient()
+			log.Printf("  Client   %s", client)
+			if r.timeline != nil {
+				now := time.Now()
+				err := r.timeline.add(timelineRow{
+					atSec:         now.Sub(r.start).Seconds(),
+					unixTime:      float64(now.UnixMilli()) / 1000,
+					rows:          intervalTotal,
+					originals:     intervalOriginals,
+					duplicates:    intervalDuplicates,
+					statements:    intervalStatements,
+					rowsPerSec:    float64(intervalTotal) / intervalSec,
+					insertAvgMs:   intervalAvgInsertMs,
+					insertLatency: intervalInsertPercentiles,
+					queries:       intervalQ,
+					failed:        intervalFailed,
+					queriesPerSec: float64(intervalQ) / intervalSec,
+					queryAvgMs:    intervalAvgMs,
+					queryLatency:  intervalQueryPercentiles,
+					cumRows:       total,
+					cumQueries:    q,
+					clientCPUPct:  client.CPUPct,
+					clientRSS:     client.RSSBytes,
+				})
+				if err != nil {
+					log.Printf("Output CSV: %v", err)
+				}
+			}
 		}
 	}
 }
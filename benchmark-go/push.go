@@ -0,0 +1,188 @@
+package benchmarkgo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Push formats.
+const (
+	PushPushgateway = "pushgateway"  // PUT the metric families to a Pushgateway grouped by job, instance and database
+	PushRemoteWrite = "remote-write" // POST samples in the Prometheus remote-write protocol (Prometheus, Mimir, Cortex)
+)
+
+// pushTimeout bounds each push so a slow receiver cannot stall the run.
+const pushTimeout = 10 * time.Second
+
+// PushOptions pushes Metrics every progress interval and once at the end, for short-lived runs (e.g. Kubernetes
+// Jobs) that finish before a scraper can catch them.
+type PushOptions struct {
+	URL    string // Pushgateway base URL or remote-write endpoint ("" = disabled)
+	Format string // PushPushgateway (default) or PushRemoteWrite
+	Job    string // job label (default "loadrunner"); instance is the hostname, database the target
+}
+
+// ValidatePushFormat returns an error if format is not a known push format.
+func ValidatePushFormat(format string) error {
+	switch format {
+	case PushPushgateway, PushRemoteWrite:
+		return nil
+	}
+	return fmt.Errorf("unknown push format %q (want %s or %s)", format, PushPushgateway, PushRemoteWrite)
+}
+
+// metricsPusher sends the metric registry to opts.URL.
+type metricsPusher struct {
+	opts     PushOptions
+	instance string
+	database string
+	reg      *prometheus.Registry
+	client   *http.Client
+	failures int
+}
+
+func newMetricsPusher(opts PushOptions, database string) *metricsPusher {
+	if opts.Format == "" {
+		opts.Format = PushPushgateway
+	}
+	if opts.Job == "" {
+		opts.Job = "loadrunner"
+	}
+	instance, _ := os.Hostname()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(newRegistryCollector(), prometheus.NewGoCollector())
+	return &metricsPusher{opts: opts, instance: instance, database: database, reg: reg, client: &http.Client{Timeout: pushTimeout}}
+}
+
+// push sends the current values; errors are logged (the first few) and counted, never fatal.
+func (p *metricsPusher) push(ctx context.Context) {
+	var err error
+	if p.opts.Format == PushRemoteWrite {
+		err = p.remoteWrite(ctx)
+	} else {
+		err = push.New(p.opts.URL, p.opts.Job).Grouping("instance", p.instance).Grouping("database", p.database).Gatherer(p.reg).
+			Client(p.client).PushContext(ctx)
+	}
+	if err != nil {
+		p.failures++
+		if p.failures <= 3 {
+			log.Printf("Metrics push to %s: %v", p.opts.URL, err)
+		}
+	}
+}
+
+// remoteWrite encodes counters and gauges as a snappy-compressed remote-write WriteRequest and POSTs it.
+func (p *metricsPusher) remoteWrite(ctx context.Context) error {
+	families, err := p.reg.Gather()
+	if err != nil {
+		return err
+	}
+	body := s2.EncodeSnappy(nil, encodeWriteRequest(families, map[string]string{"job": p.opts.Job, "instance": p.instance, "database": p.database}, time.Now()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// encodeWriteRequest builds a prometheus.WriteRequest protobuf with one sample at ts per counter, gauge or
+// untyped series (summaries and histograms are skipped). Labels are sorted by name, as the protocol requires.
+func encodeWriteRequest(families []*dto.MetricFamily, extra map[string]string, ts time.Time) []byte {
+	type label struct{ name, value string }
+	var out []byte
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			var v float64
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				v = m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				v = m.GetGauge().GetValue()
+			case dto.MetricType_UNTYPED:
+				v = m.GetUntyped().GetValue()
+			default:
+				continue
+			}
+			labels := []label{{"__name__", mf.GetName()}}
+			for k, val := range extra {
+				labels = append(labels, label{k, val})
+			}
+			for _, l := range m.GetLabel() {
+				labels = append(labels, label{l.GetName(), l.GetValue()})
+			}
+			sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+			var series []byte
+			for _, l := range labels {
+				var lb []byte
+				lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+				lb = protowire.AppendString(lb, l.name)
+				lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+				lb = protowire.AppendString(lb, l.value)
+				series = protowire.AppendTag(series, 1, protowire.BytesType)
+				series = protowire.AppendBytes(series, lb)
+			}
+			var sample []byte
+			sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(v))
+			sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, uint64(ts.UnixMilli()))
+			series = protowire.AppendTag(series, 2, protowire.BytesType)
+			series = protowire.AppendBytes(series, sample)
+			out = protowire.AppendTag(out, 1, protowire.BytesType)
+			out = protowire.AppendBytes(out, series)
+		}
+	}
+	return out
+}
+
+// runPush pushes every progress interval until ctx is done; the final push happens after the drain (finishPush).
+func (r *LoadRunner) runPush(ctx context.Context) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pushCtx, cancel := context.WithTimeout(ctx, pushTimeout)
+			r.pusher.push(pushCtx)
+			cancel()
+		}
+	}
+}
+
+// finishPush sends the end-of-run values and reports failed pushes.
+func (r *LoadRunner) finishPush() {
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	r.pusher.push(ctx)
+	if r.pusher.failures > 0 {
+		log.Printf("Metrics push: %d pushes to %s failed", r.pusher.failures, r.pusher.opts.URL)
+	}
+}
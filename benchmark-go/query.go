@@ -0,0 +1,510 @@
+package benchmarkgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Query types selectable via Config.QueryType.
+const (
+	QueryTypePK         = "pk"          // COUNT(*) by medical_record_number
+	QueryTypeResultSet  = "resultset"   // full rows for an MRN range, LIMIT N
+	QueryTypeSession    = "session"     // per-patient lookup → patient_id fetch → aggregate, with think time
+	QueryTypeRange      = "range"       // the patient's full rows over a created_at window (RangeQuerier backends)
+	QueryTypePatientID  = "patient-id"  // the patient's full rows by patient_id (secondary index)
+	QueryTypeNameDOB    = "name-dob"    // rows matching the patient's last name, first name and date of birth (DemographicQuerier backends)
+	QueryTypePageOffset = "page-offset" // one page of the name-ordered patient list by LIMIT/OFFSET at a random depth (PageQuerier backends)
+	QueryTypePageKeyset = "page-keyset" // the page of the name-ordered patient list after the patient, by keyset (PageQuerier backends)
+	QueryTypeChecksum   = "checksum"    // the row with SOURCE, its CHECKSUM recomputed and compared (ChecksumFetcher backends)
+)
+
+// What pk lookups select, via Config.PKSelect.
+const (
+	PKSelectCount   = "count"   // COUNT(*) by MRN (default)
+	PKSelectColumns = "columns" // fetch and scan the row's columns except SOURCE
+	PKSelectPayload = "payload" // fetch and scan the full row including the SOURCE payload, so transfer cost is measured
+)
+
+// ValidatePKSelect returns an error if s is not a PKSelect value.
+func ValidatePKSelect(s string) error {
+	switch s {
+	case PKSelectCount, PKSelectColumns, PKSelectPayload:
+		return nil
+	}
+	return fmt.Errorf("unknown pk select %q (want %s, %s or %s)", s, PKSelectCount, PKSelectColumns, PKSelectPayload)
+}
+
+// IsQueryType reports whether qt is a known query type.
+func IsQueryType(qt string) bool {
+	switch qt {
+	case QueryTypePK, QueryTypeResultSet, QueryTypeSession, QueryTypeRange, QueryTypePatientID, QueryTypeNameDOB,
+		QueryTypePageOffset, QueryTypePageKeyset, QueryTypeChecksum:
+		return true
+	}
+	return false
+}
+
+// QueryWeights maps query types to relative weights for a query mix.
+type QueryWeights map[string]float64
+
+// ParseQueryWeights parses a query mix such as "pk,range" (equal weights) or "pk:1,range:4".
+func ParseQueryWeights(s string) (QueryWeights, error) {
+	weights := make(QueryWeights)
+	for _, part := range strings.Split(s, ",") {
+		qt, w, hasWeight := strings.Cut(strings.TrimSpace(part), ":")
+		if !IsQueryType(qt) {
+			return nil, fmt.Errorf("unknown query type %q", qt)
+		}
+		if _, dup := weights[qt]; dup {
+			return nil, fmt.Errorf("query type %q listed twice", qt)
+		}
+		weights[qt] = 1
+		if hasWeight {
+			f, err := strconv.ParseFloat(w, 64)
+			if err != nil || f <= 0 {
+				return nil, fmt.Errorf("%q: weight must be a number > 0", part)
+			}
+			weights[qt] = f
+		}
+	}
+	return weights, nil
+}
+
+// String renders w in the form ParseQueryWeights accepts, types sorted.
+func (w QueryWeights) String() string {
+	types := make([]string, 0, len(w))
+	for qt := range w {
+		types = append(types, qt)
+	}
+	sort.Strings(types)
+	for i, qt := range types {
+		types[i] = qt + ":" + strconv.FormatFloat(w[qt], 'g', -1, 64)
+	}
+	return strings.Join(types, ",")
+}
+
+// RangeQuerier is implemented by Queriers that can run QueryTypeRange.
+type RangeQuerier interface {
+	// QueryPatientRange fetches the full rows for patientID with created_at between from and to, returning how many.
+	QueryPatientRange(ctx context.Context, patientID string, from, to time.Time) (int, error)
+}
+
+// DemographicQuerier is implemented by Queriers that can run QueryTypeNameDOB.
+type DemographicQuerier interface {
+	// QueryByNameDOB fetches the full rows matching last name, first name and date of birth, returning how many.
+	QueryByNameDOB(ctx context.Context, lastName, firstName, dateOfBirth string) (int, error)
+}
+
+// RowFetcher is implemented by Queriers that can fetch the row for an MRN instead of counting it, for
+// PKSelectColumns and PKSelectPayload.
+type RowFetcher interface {
+	// FetchByPrimaryKey fetches the (latest) row for mrn, with or without SOURCE, returning rows and bytes read.
+	FetchByPrimaryKey(ctx context.Context, mrn string, withPayload bool) (int, int64, error)
+}
+
+// PageQuerier is implemented by Queriers that can page through the patient list ordered by (last_name,
+// first_name, patient_id), for QueryTypePageOffset and QueryTypePageKeyset.
+type PageQuerier interface {
+	// QueryPageOffset fetches limit rows of the list from offset on, returning how many.
+	QueryPageOffset(ctx context.Context, offset, limit int) (int, error)
+	// QueryPageAfter fetches the limit rows of the list that follow the given key, returning how many.
+	QueryPageAfter(ctx context.Context, lastName, firstName, patientID string, limit int) (int, error)
+}
+
+var (
+	errRangeUnsupported    = errors.New("range queries are not supported by this backend")
+	errNameDOBUnsupported  = errors.New("name-dob queries are not supported by this backend")
+	errPageUnsupported     = errors.New("pagination queries are not supported by this backend")
+	errFetchUnsupported    = errors.New("row-fetching pk queries are not supported by this backend")
+	errVerifyUnsupported   = errors.New("verified pk queries are not supported by this backend")
+	errChecksumUnsupported = errors.New("checksum queries are not supported by this backend")
+)
+
+// QueryOptions is what each query worker needs per dequeued record (derived from Config).
+type QueryOptions struct {
+	QueriesPerRecord   int
+	QueryDelaySec      float64
+	IgnoreSelectErrors bool
+	QueryType          string
+	PKSelect           string // PKSelectCount ("" too), PKSelectColumns or PKSelectPayload
+	Verify             bool   // pk lookups compare columns with the generated record (ColumnFetcher backends)
+	ResultSetSizes     []int
+	SessionThinkSec    float64
+	RangeWindowSec     float64           // range queries cover created_at from this long ago until now
+	PageSize           int               // rows per page for pagination queries
+	PageDepth          int               // page-offset queries fetch a page in [0, PageDepth)
+	ThinkSec           float64           // pause between the QueriesPerRecord lookups of one record
+	ThinkJitterSec     float64           // ThinkSec varies uniformly by up to this much either way
+	Live               *LiveWorkload     // mid-run overrides of QueriesPerRecord and query-type weights; may be nil
+	LatencySampleRate  float64           // fraction of pk lookups timed server-side when the Querier is a ServerTimedQuerier
+	Pipeline           *Pipeline         // records the query stage; may be nil
+	Payload            PayloadTransforms // when set, pk lookups on a SourceFetcher read SOURCE back and decode it
+}
+
+// ResultSetSize returns the LIMIT for the i-th result-set query (round-robin over ResultSetSizes, default 1).
+func (o QueryOptions) ResultSetSize(i int) int {
+	if len(o.ResultSetSizes) == 0 {
+		return 1
+	}
+	return o.ResultSetSizes[i%len(o.ResultSetSizes)]
+}
+
+func (cfg *Config) queryOptions(live *LiveWorkload, pipeline *Pipeline) QueryOptions {
+	queryType := cfg.QueryType
+	if queryType == "" {
+		queryType = QueryTypePK
+	}
+	return QueryOptions{
+		QueriesPerRecord:   cfg.QueriesPerRecord,
+		QueryDelaySec:      cfg.QueryDelaySec,
+		IgnoreSelectErrors: cfg.IgnoreSelectErrors,
+		QueryType:          queryType,
+		PKSelect:           cfg.PKSelect,
+		Verify:             cfg.Verify,
+		ResultSetSizes:     cfg.ResultSetSizes,
+		SessionThinkSec:    cfg.SessionThinkSec,
+		RangeWindowSec:     cfg.RangeWindowSec,
+		PageSize:           cfg.PageSize,
+		PageDepth:          cfg.PageDepth,
+		ThinkSec:           cfg.QueryThinkSec,
+		ThinkJitterSec:     cfg.QueryJitterSec,
+		Live:               live,
+		LatencySampleRate:  cfg.LatencySampleRate,
+		Pipeline:           pipeline,
+	}
+}
+
+// Querier runs the individual lookups on one acquired connection; each backend provides one.
+type Querier interface {
+	// QueryByPrimaryKey returns the row count for medical_record_number = mrn.
+	QueryByPrimaryKey(ctx context.Context, mrn string) (int, error)
+	// QueryResultSet returns rows and bytes for the MRN range ending at mrn, LIMIT limit.
+	QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error)
+	// QueryByPatientID fetches the full rows for patient_id (secondary-index path) and returns how many.
+	QueryByPatientID(ctx context.Context, patientID string) (int, error)
+	// AggregateByPatientID runs an aggregate over the patient's rows and returns the row count it saw.
+	AggregateByPatientID(ctx context.Context, patientID string) (int, error)
+}
+
+// QueryRunner runs the queries for each dequeued record on behalf of one query worker.
+type QueryRunner struct {
+	Opts         QueryOptions
+	resultSetSeq int
+}
+
+// NewQueryRunner creates a QueryRunner for one query worker.
+func NewQueryRunner(opts QueryOptions) *QueryRunner {
+	return &QueryRunner{Opts: opts}
+}
+
+// Run executes the queries for job against q. Returns queries executed, queries failed, and time spent
+// in queries (session think time excluded). With live query-type weights set, each query's type is drawn
+// from them; otherwise Opts.QueryType is used (a session runs once per record).
+func (qr *QueryRunner) Run(ctx context.Context, q Querier, job *QueryJob) (count int, failed int, latency time.Duration) {
+	defer func() { qr.Opts.Pipeline.Record(StageQuery, count, latency, failed) }()
+	opts := qr.Opts
+	queriesPerRecord := opts.QueriesPerRecord
+	var weighted bool
+	if opts.Live != nil {
+		queriesPerRecord = opts.Live.QueriesPerRecord()
+		weighted = opts.Live.HasQueryWeights()
+	}
+	if opts.QueryType == QueryTypeSession && !weighted {
+		if queriesPerRecord == 0 {
+			return 0, 0, 0
+		}
+		return qr.runSession(ctx, q, job)
+	}
+	for i := 0; i < queriesPerRecord; i++ {
+		if i > 0 && opts.ThinkSec > 0 && !sleepCtx(ctx, opts.thinkTime()) {
+			break
+		}
+		queryType := opts.QueryType
+		if weighted {
+			queryType = opts.Live.PickQueryType()
+		}
+		c, f, d := qr.runOne(ctx, q, job, queryType)
+		if queryType != QueryTypeSession {
+			queryLatencies.record(d)
+		}
+		count += c
+		failed += f
+		latency += d
+	}
+	return count, failed, latency
+}
+
+// thinkTime draws the pause before the next lookup of a record: ThinkSec ± up to ThinkJitterSec.
+func (o QueryOptions) thinkTime() time.Duration {
+	sec := o.ThinkSec + (rand.Float64()*2-1)*o.ThinkJitterSec
+	return time.Duration(max(sec, 0) * float64(time.Second))
+}
+
+// runOne runs a single query (or one session) of queryType for job.
+func (qr *QueryRunner) runOne(ctx context.Context, q Querier, job *QueryJob, queryType string) (count int, failed int, latency time.Duration) {
+	switch queryType {
+	case QueryTypeSession:
+		return qr.runSession(ctx, q, job)
+	case QueryTypeRange:
+		return qr.runRange(ctx, q, job)
+	case QueryTypePatientID, QueryTypeNameDOB:
+		return qr.runSecondary(ctx, q, job, queryType)
+	case QueryTypePageOffset, QueryTypePageKeyset:
+		return qr.runPage(ctx, q, job, queryType)
+	case QueryTypeChecksum:
+		return qr.runChecksum(ctx, q, job)
+	case QueryTypeResultSet:
+		limit := qr.Opts.ResultSetSize(qr.resultSetSeq)
+		qr.resultSetSeq++
+		t0 := time.Now()
+		n, bytes, err := q.QueryResultSet(ctx, job.MRN, limit)
+		latency = time.Since(t0)
+		AddResultSetQuery(limit, int64(n), bytes, latency.Microseconds())
+		AddError(ErrOpQuery, err)
+		if err != nil || n == 0 {
+			failed++
+			if !qr.Opts.IgnoreSelectErrors {
+				log.Printf("Result-set query returned %d rows for MEDICAL_RECORD_NUMBER<=%s LIMIT %d: %v", n, job.MRN, limit, err)
+			}
+		}
+		return 1, failed, latency
+	}
+	if qr.Opts.Verify {
+		return qr.runVerify(ctx, q, job)
+	}
+	if sf, ok := q.(SourceFetcher); ok && len(qr.Opts.Payload) > 0 {
+		return qr.runDecode(ctx, sf, job)
+	}
+	if qr.Opts.PKSelect == PKSelectColumns || qr.Opts.PKSelect == PKSelectPayload {
+		return qr.runFetch(ctx, q, job)
+	}
+	var n int
+	var err error
+	if tq, ok := q.(ServerTimedQuerier); ok && sampleLatency(qr.Opts.LatencySampleRate) {
+		var server time.Duration
+		t0 := time.Now()
+		n, server, err = tq.QueryByPrimaryKeyTimed(ctx, job.MRN)
+		latency = time.Since(t0)
+		if err == nil && server > 0 {
+			AddLatencySample(latency, server)
+		}
+	} else {
+		spanCtx, span := tracer.Start(ctx, "QueryByPrimaryKey", trace.WithAttributes(attribute.String("mrn", job.MRN)))
+		t0 := time.Now()
+		n, err = q.QueryByPrimaryKey(spanCtx, job.MRN)
+		latency = time.Since(t0)
+		span.SetAttributes(attribute.Int("db.rows", n))
+		endSpan(span, err)
+	}
+	AddError(ErrOpQuery, err)
+	if n != 1 {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("Query by primary key returned %d rows for MEDICAL_RECORD_NUMBER=%s (expected 1)", n, job.MRN)
+		}
+	}
+	return 1, failed, latency
+}
+
+// runFetch is the pk lookup with PKSelectColumns or PKSelectPayload: it fetches and scans the row rather than
+// counting it, so the latency includes transferring the result.
+func (qr *QueryRunner) runFetch(ctx context.Context, q Querier, job *QueryJob) (count int, failed int, latency time.Duration) {
+	var n int
+	err := errFetchUnsupported
+	if rf, ok := q.(RowFetcher); ok {
+		t0 := time.Now()
+		var bytes int64
+		n, bytes, err = rf.FetchByPrimaryKey(ctx, job.MRN, qr.Opts.PKSelect == PKSelectPayload)
+		latency = time.Since(t0)
+		pkFetchCount.Add(1)
+		pkFetchBytes.Add(bytes)
+	}
+	AddError(ErrOpQuery, err)
+	if err != nil || n != 1 {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("Row fetch by primary key returned %d rows for MEDICAL_RECORD_NUMBER=%s (expected 1): %v", n, job.MRN, err)
+		}
+	}
+	return 1, failed, latency
+}
+
+// pk row fetches (PKSelectColumns, PKSelectPayload) and the bytes they read, process-wide like the other query stats.
+var (
+	pkFetchCount atomic.Int64
+	pkFetchBytes atomic.Int64
+)
+
+// logPKFetch logs the average result size of pk row fetches (only when some ran).
+func logPKFetch(pkSelect string) {
+	n := pkFetchCount.Load()
+	if n == 0 {
+		return
+	}
+	log.Printf("PK row fetches (select %s): %d, avg %.1f KiB read per fetch", pkSelect, n, float64(pkFetchBytes.Load())/float64(n)/1024)
+}
+
+// runDecode is the pk lookup with payload transforms on: it fetches SOURCE and reverses the transforms,
+// failing the query when the row is missing or SOURCE does not decode. Decoding is timed as StageDecode.
+func (qr *QueryRunner) runDecode(ctx context.Context, sf SourceFetcher, job *QueryJob) (count int, failed int, latency time.Duration) {
+	t0 := time.Now()
+	source, err := sf.FetchSource(ctx, job.MRN)
+	latency = time.Since(t0)
+	if err == nil {
+		t1 := time.Now()
+		var decoded string
+		decoded, err = qr.Opts.Payload.Decode(source)
+		if err == nil && decoded == "" {
+			err = errors.New("decoded SOURCE is empty")
+		}
+		decodeFailed := 0
+		if err != nil {
+			decodeFailed = 1
+		}
+		qr.Opts.Pipeline.Record(StageDecode, 1, time.Since(t1), decodeFailed)
+	}
+	AddError(ErrOpQuery, err)
+	if err != nil {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("SOURCE fetch/decode failed for MEDICAL_RECORD_NUMBER=%s: %v", job.MRN, err)
+		}
+	}
+	return 1, failed, latency
+}
+
+// runRange fetches the patient's rows created within the last RangeWindowSec. The patient having no rows in the
+// window is not a failure: for reads of older data the window can legitimately be empty.
+func (qr *QueryRunner) runRange(ctx context.Context, q Querier, job *QueryJob) (count int, failed int, latency time.Duration) {
+	rq, ok := q.(RangeQuerier)
+	var n int
+	err := errRangeUnsupported
+	if ok {
+		to := time.Now()
+		from := to.Add(-time.Duration(qr.Opts.RangeWindowSec * float64(time.Second)))
+		t0 := time.Now()
+		n, err = rq.QueryPatientRange(ctx, job.PatientID, from, to)
+		latency = time.Since(t0)
+	}
+	AddError(ErrOpQuery, err)
+	if err != nil {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("Range query returned %d rows for PATIENT_ID=%s: %v", n, job.PatientID, err)
+		}
+	}
+	return 1, failed, latency
+}
+
+// runSecondary looks the patient up through a secondary index: by patient_id, or by name and date of birth (which
+// other patients may share). Finding none of the patient's rows is a failure.
+func (qr *QueryRunner) runSecondary(ctx context.Context, q Querier, job *QueryJob, queryType string) (count int, failed int, latency time.Duration) {
+	var n int
+	var err error
+	t0 := time.Now()
+	if queryType == QueryTypePatientID {
+		n, err = q.QueryByPatientID(ctx, job.PatientID)
+	} else if dq, ok := q.(DemographicQuerier); !ok {
+		err = errNameDOBUnsupported
+	} else if last, first, dob, ok := demographicsForPatientID(job.PatientID); !ok {
+		err = fmt.Errorf("no generated demographics for %s", job.PatientID)
+	} else {
+		n, err = dq.QueryByNameDOB(ctx, last, first, dob)
+	}
+	latency = time.Since(t0)
+	AddError(ErrOpQuery, err)
+	if err != nil || n < 1 {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("Query type %s returned %d rows for PATIENT_ID=%s: %v", queryType, n, job.PatientID, err)
+		}
+	}
+	return 1, failed, latency
+}
+
+// runPage fetches one page of the patient list: by OFFSET at a uniformly random depth below PageDepth, whose cost
+// grows with the depth, or by keyset from the job's patient on. A short or empty page past the end of the list is
+// not a failure.
+func (qr *QueryRunner) runPage(ctx context.Context, q Querier, job *QueryJob, queryType string) (count int, failed int, latency time.Duration) {
+	size := max(qr.Opts.PageSize, 1)
+	var n int
+	var err error
+	t0 := time.Now()
+	if pq, ok := q.(PageQuerier); !ok {
+		err = errPageUnsupported
+	} else if queryType == QueryTypePageOffset {
+		n, err = pq.QueryPageOffset(ctx, rand.Intn(max(qr.Opts.PageDepth, 1))*size, size)
+	} else if last, first, _, ok := demographicsForPatientID(job.PatientID); !ok {
+		err = fmt.Errorf("no generated demographics for %s", job.PatientID)
+	} else {
+		n, err = pq.QueryPageAfter(ctx, last, first, job.PatientID, size)
+	}
+	latency = time.Since(t0)
+	AddError(ErrOpQuery, err)
+	if err != nil {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("Query type %s returned %d rows for PATIENT_ID=%s: %v", queryType, n, job.PatientID, err)
+		}
+	}
+	return 1, failed, latency
+}
+
+// Session steps, in the order a clinician opening a chart triggers them.
+const (
+	SessionStepLookup = iota
+	SessionStepFetch
+	SessionStepAggregate
+	numSessionSteps
+)
+
+var sessionStepNames = [numSessionSteps]string{"lookup", "fetch", "aggregate"}
+
+// runSession runs one correlated session for the patient: MRN lookup, patient_id fetch, aggregate,
+// sleeping Opts.SessionThinkSec between steps.
+func (qr *QueryRunner) runSession(ctx context.Context, q Querier, job *QueryJob) (count int, failed int, latency time.Duration) {
+	think := time.Duration(qr.Opts.SessionThinkSec * float64(time.Second))
+	sessionStart := time.Now()
+	for step := 0; step < numSessionSteps; step++ {
+		if step > 0 && think > 0 {
+			time.Sleep(think)
+		}
+		t0 := time.Now()
+		var n int
+		var err error
+		switch step {
+		case SessionStepLookup:
+			n, err = q.QueryByPrimaryKey(ctx, job.MRN)
+		case SessionStepFetch:
+			n, err = q.QueryByPatientID(ctx, job.PatientID)
+		case SessionStepAggregate:
+			n, err = q.AggregateByPatientID(ctx, job.PatientID)
+		}
+		d := time.Since(t0)
+		queryLatencies.record(d)
+		latency += d
+		count++
+		AddError(ErrOpQuery, err)
+		stepFailed := err != nil || n < 1
+		if stepFailed {
+			failed++
+			if !qr.Opts.IgnoreSelectErrors {
+				log.Printf("Session %s step returned %d rows for MEDICAL_RECORD_NUMBER=%s: %v", sessionStepNames[step], n, job.MRN, err)
+			}
+		}
+		AddSessionStep(step, d.Microseconds(), stepFailed)
+	}
+	AddSession(time.Since(sessionStart).Microseconds())
+	return count, failed, latency
+}
@@ -0,0 +1,191 @@
+package questdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+)
+
+// maxRequestBytes caps one ILP/HTTP request; a batch of ~2 MiB rows is split across requests so it stays
+// below the server's receive buffer (line.http.max.recv.buffer.size).
+const maxRequestBytes = 64 << 20
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// ILPError is a rejected /write request: the HTTP status plus QuestDB's error code and message.
+type ILPError struct {
+	Status  int
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+}
+
+func (e *ILPError) Error() string {
+	return fmt.Sprintf("questdb /write: HTTP %d %s: %s (line %d)", e.Status, e.Code, e.Message, e.Line)
+}
+
+// classifyError maps ILP rejections to "questdb:<code>", e.g. questdb:invalid.
+func classifyError(err error) (string, bool) {
+	var ilpErr *ILPError
+	if errors.As(err, &ilpErr) {
+		code := ilpErr.Code
+		if code == "" {
+			code = "http_" + strconv.Itoa(ilpErr.Status)
+		}
+		return "questdb:" + code, true
+	}
+	return "", false
+}
+
+// ILPWriter sends rows to QuestDB's InfluxDB Line Protocol endpoint over HTTP (/write), which, unlike ILP/TCP,
+// acknowledges each request and reports errors.
+type ILPWriter struct {
+	URL    string // e.g. http://questdb:9000/write
+	Client *http.Client
+}
+
+// NewILPWriter returns a writer for the HTTP endpoint at host:port with a connection pool of size conns.
+func NewILPWriter(host string, port int, conns int) *ILPWriter {
+	return &ILPWriter{
+		URL: "http://" + host + ":" + strconv.Itoa(port) + "/write?precision=n",
+		Client: &http.Client{
+			Timeout:   2 * time.Minute,
+			Transport: &http.Transport{MaxIdleConnsPerHost: conns, MaxConnsPerHost: conns},
+		},
+	}
+}
+
+// escapeString writes s as an ILP string field value (quotes, backslashes and newlines escaped).
+func escapeString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	if !strings.ContainsAny(s, "\"\\\n") {
+		b.WriteString(s)
+	} else {
+		for i := 0; i < len(s); i++ {
+			switch s[i] {
+			case '"', '\\', '\n':
+				b.WriteByte('\\')
+			}
+			b.WriteByte(s[i])
+		}
+	}
+	b.WriteByte('"')
+}
+
+// appendLine renders one row: every non-null column as a field, updated_at as a timestamp field and
+// created_at as the designated timestamp.
+func appendLine(b *bytes.Buffer, table string, row []interface{}) {
+	b.WriteString(table)
+	b.WriteByte(' ')
+	var createdAt time.Time
+	first := true
+	for i, c := range sqldb.Columns {
+		v := row[i]
+		if v == nil {
+			continue
+		}
+		if c == "created_at" {
+			if t, ok := v.(time.Time); ok {
+				createdAt = t
+				continue
+			}
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		b.WriteString(c)
+		b.WriteByte('=')
+		switch x := v.(type) {
+		case time.Time:
+			b.WriteString(strconv.FormatInt(x.UnixMicro(), 10))
+			b.WriteByte('t')
+		case string:
+			escapeString(b, x)
+		default:
+			escapeString(b, fmt.Sprint(x))
+		}
+	}
+	if !createdAt.IsZero() {
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(createdAt.UnixNano(), 10))
+	}
+	b.WriteByte('\n')
+}
+
+// Write sends rows as ILP, splitting into requests of at most maxRequestBytes. Returns (rows, requests, error).
+func (w *ILPWriter) Write(ctx context.Context, rows []benchmarkgo.RowForDB) (int, int, error) {
+	now := time.Now().UTC()
+	table := benchmarkgo.Table()
+	var buf bytes.Buffer
+	var sent, requests, pending int
+	flush := func() error {
+		if pending == 0 {
+			return nil
+		}
+		requests++
+		if err := w.post(ctx, buf.Bytes()); err != nil {
+			return err
+		}
+		sent += pending
+		pending = 0
+		buf.Reset()
+		return nil
+	}
+	for _, r := range rows {
+		row, err := sqldb.RowFromJSON(r.JSONMessage, now)
+		if err != nil {
+			return sent, requests, err
+		}
+		mark := buf.Len()
+		appendLine(&buf, table, row)
+		if buf.Len() > maxRequestBytes && pending > 0 {
+			line := append([]byte(nil), buf.Bytes()[mark:]...)
+			buf.Truncate(mark)
+			if err := flush(); err != nil {
+				return sent, requests, err
+			}
+			buf.Write(line)
+		}
+		pending++
+	}
+	if err := flush(); err != nil {
+		return sent, requests, err
+	}
+	return sent, requests, nil
+}
+
+func (w *ILPWriter) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	ilpErr := &ILPError{Status: resp.StatusCode}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(msg, ilpErr) != nil {
+		ilpErr.Message = strings.TrimSpace(string(msg))
+	}
+	return ilpErr
+}
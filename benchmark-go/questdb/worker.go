@@ -0,0 +1,222 @@
+// Package questdb runs the benchmark against QuestDB: inserts go through the InfluxDB Line Protocol over HTTP
+// and reads through the PostgreSQL wire protocol, so both of QuestDB's very different paths are measured.
+package questdb
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultHost     = "questdb"
+	defaultHTTPPort = 9000
+	defaultPGPort   = 8812
+	defaultUser     = "admin"
+	defaultPassword = "quest"
+)
+
+// Backend implements benchmarkgo.InsertBackend over the ILP writer (whose HTTP client pools connections).
+type Backend struct {
+	writer *ILPWriter
+}
+
+// GetConn returns the shared writer.
+func (b *Backend) GetConn() interface{} {
+	return b.writer
+}
+
+// ReleaseConn is a no-op; the HTTP client manages its pool.
+func (b *Backend) ReleaseConn(c interface{}) {}
+
+// InsertBatch writes rows as ILP; statements are HTTP requests.
+// Rows are appended: a duplicate MRN becomes a newer row, and reads take the latest per MRN.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	w, ok := conn.(*ILPWriter)
+	if !ok {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // pgbouncer routing hint; unused for QuestDB
+	n, requests, err := w.Write(context.Background(), rows)
+	return benchmarkgo.InsertResult{Rows: n, Statements: requests}, err
+}
+
+// Context handles setup/teardown and query workers for QuestDB.
+type Context struct {
+	pgPool *pgxpool.Pool
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// createTableSQL is the WAL table partitioned by hour on the designated created_at timestamp.
+func createTableSQL() string {
+	var cols []string
+	for _, c := range sqldb.Columns {
+		switch c {
+		case "created_at", "updated_at":
+			cols = append(cols, c+" TIMESTAMP")
+		default:
+			cols = append(cols, c+" VARCHAR")
+		}
+	}
+	return "CREATE TABLE IF NOT EXISTS " + benchmarkgo.Table() + " (" + strings.Join(cols, ", ") +
+		") TIMESTAMP(created_at) PARTITION BY HOUR WAL"
+}
+
+// Setup creates the PG-wire pool (schema + reads) and the ILP writer for inserts.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if c.pgPool != nil {
+		log.Fatal("questdb Setup already called")
+	}
+	host := os.Getenv("QUESTDB_HOST")
+	if host == "" {
+		host = defaultHost
+	}
+	httpPort := envInt("QUESTDB_HTTP_PORT", defaultHTTPPort)
+	pgPort := envInt("QUESTDB_PG_PORT", defaultPGPort)
+	user, password := defaultUser, defaultPassword
+	if v := os.Getenv("QUESTDB_USER"); v != "" {
+		user = v
+	}
+	if v := os.Getenv("QUESTDB_PASSWORD"); v != "" {
+		password = v
+	}
+	ctx := context.Background()
+	cfg, err := pgxpool.ParseConfig("postgres://" + user + ":" + password + "@" + host + ":" + strconv.Itoa(pgPort) + "/qdb")
+	if err != nil {
+		return nil, err
+	}
+	size := max(1, numWorkers)
+	cfg.MaxConns = int32(size)
+	cfg.MinConns = int32(size)
+	log.Printf("Creating QuestDB PG-wire pool at %s:%d (%d connections) and ILP/HTTP writer at :%d (%d connections)",
+		host, pgPort, size, httpPort, numWorkers)
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pool.Exec(ctx, createTableSQL()); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	log.Printf("Table %s ready (QuestDB WAL, partitioned by hour on created_at)", benchmarkgo.Table())
+	c.pgPool = pool
+	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
+	return &Backend{writer: NewILPWriter(host, httpPort, numWorkers)}, nil
+}
+
+// Teardown closes the PG-wire pool.
+func (c *Context) Teardown() {
+	if c.pgPool != nil {
+		c.pgPool.Close()
+		c.pgPool = nil
+	}
+}
+
+// GetMaxPatientCounter returns the max patient ordinal in the table, or -1.
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	var v *int64
+	err := c.pgPool.QueryRow(context.Background(),
+		"SELECT max(cast(replace(patient_id, 'patient-', '') AS LONG)) FROM "+benchmarkgo.Table()+" WHERE patient_id LIKE 'patient-%'").Scan(&v)
+	if err != nil || v == nil {
+		return -1, err
+	}
+	return int(*v), nil
+}
+
+// querier binds an acquired PG-wire connection to benchmarkgo.Querier.
+type querier struct {
+	conn *pgxpool.Conn
+}
+
+// QueryByPrimaryKey counts the latest row for mrn (ILP appends, so duplicates are newer rows of the same MRN).
+func (q querier) QueryByPrimaryKey(ctx context.Context, mrn string) (int, error) {
+	var n int64
+	err := q.conn.QueryRow(ctx, "SELECT count() FROM (SELECT medical_record_number FROM "+benchmarkgo.Table()+
+		" WHERE medical_record_number = $1 LATEST ON created_at PARTITION BY medical_record_number)", mrn).Scan(&n)
+	return int(n), err
+}
+
+func (q querier) QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error) {
+	rows, err := q.conn.Query(ctx, "SELECT * FROM "+benchmarkgo.Table()+
+		" WHERE medical_record_number <= $1 ORDER BY medical_record_number DESC LIMIT $2", mrn, limit)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	var n int
+	var bytes int64
+	for rows.Next() {
+		n++
+		for _, v := range rows.RawValues() {
+			bytes += int64(len(v))
+		}
+	}
+	return n, bytes, rows.Err()
+}
+
+func (q querier) QueryByPatientID(ctx context.Context, patientID string) (int, error) {
+	rows, err := q.conn.Query(ctx, "SELECT * FROM "+benchmarkgo.Table()+" WHERE patient_id = $1", patientID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+func (q querier) AggregateByPatientID(ctx context.Context, patientID string) (int, error) {
+	var n int64
+	var lastUpdated *time.Time
+	var payloadBytes *int64
+	err := q.conn.QueryRow(ctx,
+		"SELECT count(), max(updated_at), sum(length(source)) FROM "+benchmarkgo.Table()+" WHERE patient_id = $1", patientID,
+	).Scan(&n, &lastUpdated, &payloadBytes)
+	return int(n), err
+}
+
+// RunQueryWorker consumes from queryQueue, runs the configured queries per MRN over PG-wire, reports via benchmarkgo.AddQuery.
+// workerIndex is the 0-based index of this query worker.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex // reserved for logging/tracing
+	runner := benchmarkgo.NewQueryRunner(opts)
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
+			if time.Now().Before(deadline) {
+				time.Sleep(time.Until(deadline))
+			}
+		}
+		conn, err := c.pgPool.Acquire(context.Background())
+		if err != nil {
+			continue
+		}
+		count, failed, latency := runner.Run(context.Background(), querier{conn}, job)
+		conn.Release()
+		benchmarkgo.AddQuery(int64(count), latency.Microseconds(), int64(failed))
+	}
+}
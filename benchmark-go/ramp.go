@@ -0,0 +1,95 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Ramped load: instead of stepping straight to TargetRPS, the router scales the rate linearly from 0 over the
+// ramp-up and back to 0 over the ramp-down, so the interval logs trace throughput and latency across the whole
+// range and show where the knee is.
+
+// rampFloorRPS keeps the limiter above zero at the ends of a ramp or a pattern's trough; a zero limit never refills.
+const rampFloorRPS = 1
+
+// rampStep is how often a waiting batch re-reads the ramped rate.
+const rampStep = 100 * time.Millisecond
+
+// RampOptions shapes the start and end of the load.
+type RampOptions struct {
+	UpSec   float64 // scale the target from 0 to TargetRPS over the first UpSec seconds (0 = start at the target)
+	DownSec float64 // scale it back to 0 over the last DownSec seconds of the duration (0 = stop at the target)
+}
+
+// Enabled reports whether either ramp is set.
+func (o RampOptions) Enabled() bool {
+	return o.UpSec > 0 || o.DownSec > 0
+}
+
+// ramp is a RampOptions anchored to the run's start and deadline.
+type ramp struct {
+	opts       RampOptions
+	start, end time.Time
+}
+
+// factor is the share of the target rate at t: rising from 0 to 1 over the ramp-up, 1 in between, and falling
+// back to 0 over the ramp-down.
+func (p *ramp) factor(t time.Time) float64 {
+	f := 1.0
+	if p.opts.UpSec > 0 {
+		f = min(f, t.Sub(p.start).Seconds()/p.opts.UpSec)
+	}
+	if p.opts.DownSec > 0 {
+		f = min(f, p.end.Sub(t).Seconds()/p.opts.DownSec)
+	}
+	return max(f, 0)
+}
+
+// rateFactor is the share of the target rate in force at t under the ramp and the load pattern.
+func (r *Router) rateFactor(t time.Time) float64 {
+	f := 1.0
+	if r.ramp != nil {
+		f *= r.ramp.factor(t)
+	}
+	if r.pattern != nil {
+		f *= r.pattern.factor(t)
+	}
+	return f
+}
+
+// shapedWait waits until rows may be sent at the ramped, patterned or bursting rate, re-applying it every
+// rampStep. A plain WaitN would charge the whole batch at the rate in force when it was reserved, which near the
+// bottom of a ramp-up or pattern trough is far slower than the rate a moment later.
+func (r *Router) shapedWait(ctx context.Context, rows int) error {
+	for {
+		now := time.Now()
+		limit := max(float64(targetRPS.Load())*r.rateFactor(now), rampFloorRPS)
+		if burstActive.Load() {
+			limit += float64(r.burstRPS)
+		}
+		r.RateLimiter.SetLimitAt(now, rate.Limit(limit))
+		res := r.RateLimiter.ReserveN(now, rows)
+		if !res.OK() {
+			return fmt.Errorf("rate: batch of %d rows exceeds the limiter's burst", rows)
+		}
+		wait := res.DelayFrom(now)
+		granted := wait <= rampStep
+		if !granted {
+			res.CancelAt(now)
+			wait = rampStep
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if granted {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+)
+
+// Reconciliation: with Config.Reconcile, the table's rows and generated patients' MRNs are counted before and after
+// the run and compared with the inserts the workers saw acknowledged, so a run whose inserts did not all land (or
+// landed twice) is flagged instead of being trusted from client-side counters alone.
+
+// FinalCounter is implemented by WorkerCtx backends whose table keeps duplicates as separate rows until a
+// background merge (ClickHouse ReplacingMergeTree): CountRowsFinal counts rows with the merge applied (FINAL).
+type FinalCounter interface {
+	CountRowsFinal(ctx context.Context) (int64, error)
+}
+
+// ReconcileReport compares what the workers inserted with what the table holds after the run.
+type ReconcileReport struct {
+	KeysBefore     int64  `json:"keys_before"`
+	AckedOriginals int64  `json:"acked_originals"` // original rows acknowledged during the run: each a new MRN
+	ExpectedKeys   int64  `json:"expected_keys"`   // keys_before + acked_originals
+	FoundKeys      int64  `json:"found_keys"`
+	MissingKeys    int64  `json:"missing_keys"` // acknowledged but not found
+	ExtraKeys      int64  `json:"extra_keys"`   // found without an acknowledgment (failed client-side but committed)
+	RowsBefore     int64  `json:"rows_before,omitempty"`
+	RowsAfter      int64  `json:"rows_after,omitempty"`
+	AckedRows      int64  `json:"acked_rows"`           // originals + duplicates acknowledged
+	RowsFinal      int64  `json:"rows_final,omitempty"` // rows after FINAL (FinalCounter backends)
+	Unmerged       int64  `json:"unmerged,omitempty"`   // rows_after - rows_final: duplicates not merged yet
+	Error          string `json:"error,omitempty"`
+}
+
+// startReconcile counts keys (and rows, where the backend can) before the load.
+func (r *LoadRunner) startReconcile() {
+	kc, ok := r.WorkerCtx.(KeyCounter)
+	if !ok {
+		log.Printf("Reconcile: %s backend cannot count keys, skipping post-run reconciliation", r.Config.Database)
+		return
+	}
+	ctx := context.Background()
+	rep := &ReconcileReport{}
+	r.reconcile = rep
+	var err error
+	if rep.KeysBefore, err = kc.CountKeys(ctx); err != nil {
+		rep.Error = err.Error()
+		return
+	}
+	if rc, ok := r.WorkerCtx.(RowCounter); ok {
+		if rep.RowsBefore, err = rc.CountRows(ctx); err != nil {
+			rep.Error = err.Error()
+			return
+		}
+	}
+	log.Printf("Reconcile: %d patient keys, %d rows in table before the run", rep.KeysBefore, rep.RowsBefore)
+}
+
+// finishReconcile counts keys and rows after the load and compares them with acknowledged inserts.
+func (r *LoadRunner) finishReconcile(snapshot Snapshot) {
+	rep := r.reconcile
+	if rep == nil || rep.Error != "" {
+		return
+	}
+	ctx := context.Background()
+	found, err := r.WorkerCtx.(KeyCounter).CountKeys(ctx)
+	if err != nil {
+		rep.Error = err.Error()
+		return
+	}
+	rep.AckedOriginals = int64(snapshot.Inserted.Originals)
+	rep.AckedRows = int64(snapshot.Inserted.Total)
+	rep.ExpectedKeys = rep.KeysBefore + rep.AckedOriginals
+	rep.FoundKeys = found
+	rep.MissingKeys = max(0, rep.ExpectedKeys-found)
+	rep.ExtraKeys = max(0, found-rep.ExpectedKeys)
+	if rc, ok := r.WorkerCtx.(RowCounter); ok {
+		if rep.RowsAfter, err = rc.CountRows(ctx); err != nil {
+			rep.Error = err.Error()
+			return
+		}
+	}
+	if fc, ok := r.WorkerCtx.(FinalCounter); ok {
+		if rep.RowsFinal, err = fc.CountRowsFinal(ctx); err != nil {
+			rep.Error = err.Error()
+			return
+		}
+		rep.Unmerged = rep.RowsAfter - rep.RowsFinal
+	}
+}
+
+// logReconcile logs the reconciliation (only when it ran).
+func logReconcile(rep *ReconcileReport) {
+	if rep == nil {
+		return
+	}
+	if rep.Error != "" {
+		log.Printf("Reconcile failed: %s", rep.Error)
+		return
+	}
+	log.Printf("Reconcile: expected %d keys (%d before + %d acked originals), found %d | missing %d | extra %d",
+		rep.ExpectedKeys, rep.KeysBefore, rep.AckedOriginals, rep.FoundKeys, rep.MissingKeys, rep.ExtraKeys)
+	if rep.RowsAfter > 0 {
+		log.Printf("  rows: %d before, %d after (+%d; %d acked incl. duplicates)",
+			rep.RowsBefore, rep.RowsAfter, rep.RowsAfter-rep.RowsBefore, rep.AckedRows)
+	}
+	if rep.RowsFinal > 0 {
+		log.Printf("  rows with FINAL: %d (%d duplicates not merged yet)", rep.RowsFinal, rep.Unmerged)
+	}
+	if rep.MissingKeys > 0 || rep.ExtraKeys > 0 {
+		log.Printf("Warning: reconciliation found %d missing and %d unacknowledged keys", rep.MissingKeys, rep.ExtraKeys)
+	}
+}
@@ -0,0 +1,42 @@
+package benchmarkgo
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// Reconnects: backends that pool their own connections (ClickHouse) health-check a connection after a transport
+// error and replace it when it is dead, e.g. after a server restart. Those replacements are counted here.
+
+// ReconnectStats counts dead pooled connections replaced during the run.
+type ReconnectStats struct {
+	Replaced int64 `json:"replaced"`
+	Failed   int64 `json:"failed"` // replacement could not reach the server yet; it redials on its next use
+}
+
+var reconnectsReplaced, reconnectsFailed atomic.Int64
+
+// AddReconnect counts a replaced connection; err is the replacement's first health check (nil when it answered).
+func AddReconnect(err error) {
+	reconnectsReplaced.Add(1)
+	if err != nil {
+		reconnectsFailed.Add(1)
+	}
+}
+
+// loadReconnects returns the reconnect counts, or nil when no connection was replaced.
+func loadReconnects() *ReconnectStats {
+	n := reconnectsReplaced.Load()
+	if n == 0 {
+		return nil
+	}
+	return &ReconnectStats{Replaced: n, Failed: reconnectsFailed.Load()}
+}
+
+// logReconnects logs the reconnect counts (only when connections were replaced).
+func logReconnects(s *ReconnectStats) {
+	if s == nil {
+		return
+	}
+	log.Printf("Reconnects: %d dead connections replaced (%d could not reach the server at first)", s.Replaced, s.Failed)
+}
@@ -0,0 +1,158 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	benchmarkgo "github.com/db-benchmarking/benchmark-go"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// keyPrefix namespaces hl7 payloads: hl7:<MEDICAL_RECORD_NUMBER> → JSON message.
+	keyPrefix = "hl7:"
+	// maxCounterKey holds the highest patient ordinal written, so reruns continue after existing data.
+	maxCounterKey = "hl7:max_patient_counter"
+	mrnPrefix     = "MRN-"
+	patientPrefix = "patient-"
+)
+
+// errNoSecondaryIndex is returned for lookups a key-value store cannot serve without a secondary index.
+var errNoSecondaryIndex = errors.New("redis: lookups by patient_id need a secondary index, which this backend does not maintain")
+
+// setMaxScript raises maxCounterKey to ARGV[1] if it is larger (atomic compare-and-set on the server).
+var setMaxScript = goredis.NewScript(`
+local cur = tonumber(redis.call('GET', KEYS[1]) or '-1')
+local v = tonumber(ARGV[1])
+if v > cur then redis.call('SET', KEYS[1], ARGV[1]) end
+return 0`)
+
+// patientCounterKey holds the next unleased patient ordinal for --patient-counter=reserve.
+const patientCounterKey = "hl7:next_patient_ordinal"
+
+// reserveScript raises KEYS[1] to at least ARGV[1] (floor), then leases ARGV[2] ordinals and returns the first.
+var reserveScript = goredis.NewScript(`
+local cur = tonumber(redis.call('GET', KEYS[1]) or '0')
+local floor = tonumber(ARGV[1])
+if cur < floor then cur = floor end
+redis.call('SET', KEYS[1], cur + tonumber(ARGV[2]))
+return cur`)
+
+// Key returns the Redis key for an MRN, namespaced by the table prefix.
+func Key(mrn string) string {
+	return benchmarkgo.TablePrefix() + keyPrefix + mrn
+}
+
+// mrnFromJSON reads MEDICAL_RECORD_NUMBER from the producer's JSON message.
+func mrnFromJSON(jsonStr string) (string, error) {
+	var m struct {
+		MRN string `json:"MEDICAL_RECORD_NUMBER"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &m); err != nil {
+		return "", err
+	}
+	if m.MRN == "" {
+		return "", errors.New("redis: MEDICAL_RECORD_NUMBER is empty")
+	}
+	return m.MRN, nil
+}
+
+// ordinal parses the numeric suffix of "MRN-NNNNNNNNNN" / "patient-NNNNNNNNNN"; -1 if malformed.
+func ordinal(id, prefix string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, prefix))
+	if err != nil || !strings.HasPrefix(id, prefix) {
+		return -1
+	}
+	return n
+}
+
+// InsertBatch writes rows with one pipelined MSET (last write wins, like the upsert) plus a max-counter update.
+func InsertBatch(ctx context.Context, client *goredis.Client, rows []benchmarkgo.RowForDB) (int, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	pairs := make([]interface{}, 0, len(rows)*2)
+	maxOrdinal := -1
+	for _, r := range rows {
+		mrn, err := mrnFromJSON(r.JSONMessage)
+		if err != nil {
+			return 0, err
+		}
+		pairs = append(pairs, Key(mrn), r.JSONMessage)
+		if o := ordinal(r.PatientID, patientPrefix); o > maxOrdinal {
+			maxOrdinal = o
+		}
+	}
+	pipe := client.Pipeline()
+	pipe.MSet(ctx, pairs...)
+	if maxOrdinal >= 0 {
+		setMaxScript.Run(ctx, pipe, []string{benchmarkgo.Prefixed(maxCounterKey)}, maxOrdinal)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// QueryByPrimaryKey GETs the payload for mrn and returns 1 if present, 0 if not.
+func QueryByPrimaryKey(ctx context.Context, client *goredis.Client, mrn string) (int, error) {
+	_, err := client.Get(ctx, Key(mrn)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// QueryResultSet emulates an MRN range scan with one MGET of the limit MRNs ending at mrn (descending ordinals).
+func QueryResultSet(ctx context.Context, client *goredis.Client, mrn string, limit int) (int, int64, error) {
+	end := ordinal(mrn, mrnPrefix)
+	if end < 0 {
+		return 0, 0, errors.New("redis: malformed MRN " + mrn)
+	}
+	keys := make([]string, 0, limit)
+	for o := end; o >= 0 && len(keys) < limit; o-- {
+		keys = append(keys, Key(mrnPrefix+formatOrdinal(o)))
+	}
+	vals, err := client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	var n int
+	var bytes int64
+	for _, v := range vals {
+		if s, ok := v.(string); ok {
+			n++
+			bytes += int64(len(s))
+		}
+	}
+	return n, bytes, nil
+}
+
+// ReservePatientRange atomically leases n ordinals (server-side script) and returns the first.
+func ReservePatientRange(ctx context.Context, client *goredis.Client, floor, n int) (int, error) {
+	return reserveScript.Run(ctx, client, []string{benchmarkgo.Prefixed(patientCounterKey)}, floor, n).Int()
+}
+
+// GetMaxPatientCounter returns the highest patient ordinal written, or -1.
+func GetMaxPatientCounter(ctx context.Context, client *goredis.Client) (int, error) {
+	v, err := client.Get(ctx, benchmarkgo.Prefixed(maxCounterKey)).Int()
+	if errors.Is(err, goredis.Nil) {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return v, nil
+}
+
+// formatOrdinal matches benchmarkgo's zero-padded MRN/patient ordinal format.
+func formatOrdinal(n int) string {
+	return fmt.Sprintf("%010d", n)
+}
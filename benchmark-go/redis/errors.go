@@ -0,0 +1,23 @@
+package redis
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/db-benchmarking/benchmark-go"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func init() {
+	benchmarkgo.RegisterErrorClassifier(classifyError)
+}
+
+// classifyError maps server replies to "redis:<PREFIX>", e.g. redis:OOM or redis:LOADING.
+func classifyError(err error) (string, bool) {
+	var rErr goredis.Error
+	if errors.As(err, &rErr) && !errors.Is(err, goredis.Nil) {
+		prefix, _, _ := strings.Cut(rErr.Error(), " ")
+		return "redis:" + prefix, true
+	}
+	return "", false
+}
@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const defaultHost = "redis"
+const defaultPort = 6379
+
+// Backend implements benchmarkgo.InsertBackend on a go-redis client (which pools its own connections).
+type Backend struct {
+	client *goredis.Client
+}
+
+// GetConn returns the shared client; go-redis checks out a pooled connection per command.
+func (b *Backend) GetConn() interface{} {
+	return b.client
+}
+
+// ReleaseConn is a no-op; the client manages its pool.
+func (b *Backend) ReleaseConn(c interface{}) {}
+
+// InsertBatch writes rows with a pipelined MSET as one statement.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	client, ok := conn.(*goredis.Client)
+	if !ok {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // unused for Redis
+	n, err := InsertBatch(context.Background(), client, rows)
+	if err != nil {
+		return benchmarkgo.InsertResult{Rows: n}, err
+	}
+	return benchmarkgo.InsertResult{Rows: n, Statements: 1}, nil
+}
+
+// Context holds the Redis client for setup/teardown and query workers.
+type Context struct {
+	client *goredis.Client
+}
+
+// Setup creates the client (pool sized for insert + query workers) and pings the server. There is no schema.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if c.client != nil {
+		log.Fatal("redis Setup already called")
+	}
+	host := os.Getenv("REDIS_HOST")
+	if host == "" {
+		host = defaultHost
+	}
+	port := defaultPort
+	if p := os.Getenv("REDIS_PORT"); p != "" {
+		if v, err := strconv.Atoi(p); err == nil {
+			port = v
+		}
+	}
+	poolSize := numWorkers
+	if queriesPerRecord > 0 {
+		poolSize = numWorkers * 2
+	}
+	log.Printf("Creating Redis client at %s:%d (pool %d connections)", host, port, poolSize)
+	client := goredis.NewClient(&goredis.Options{
+		Addr:         host + ":" + strconv.Itoa(port),
+		Password:     os.Getenv("REDIS_PASSWORD"),
+		PoolSize:     poolSize,
+		MinIdleConns: poolSize,
+		DialTimeout:  10 * time.Second,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	c.client = client
+	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
+	return &Backend{client: client}, nil
+}
+
+// Teardown closes the client.
+func (c *Context) Teardown() {
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// GetMaxPatientCounter returns the max patient ordinal recorded by previous inserts.
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	return GetMaxPatientCounter(context.Background(), c.client)
+}
+
+// ReservePatientRange leases ordinals from the shared counter key (implements benchmarkgo.CounterReserver).
+func (c *Context) ReservePatientRange(ctx context.Context, floor, n int) (int, error) {
+	return ReservePatientRange(ctx, c.client, floor, n)
+}
+
+// querier binds the client to benchmarkgo.Querier.
+type querier struct {
+	client *goredis.Client
+}
+
+func (q querier) QueryByPrimaryKey(ctx context.Context, mrn string) (int, error) {
+	return QueryByPrimaryKey(ctx, q.client, mrn)
+}
+
+func (q querier) QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error) {
+	return QueryResultSet(ctx, q.client, mrn, limit)
+}
+
+func (q querier) QueryByPatientID(ctx context.Context, patientID string) (int, error) {
+	return 0, errNoSecondaryIndex
+}
+
+func (q querier) AggregateByPatientID(ctx context.Context, patientID string) (int, error) {
+	return 0, errNoSecondaryIndex
+}
+
+// RunQueryWorker consumes from queryQueue and runs GET lookups, reports via benchmarkgo.AddQuery.
+// workerIndex is the 0-based index of this query worker.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex // reserved for logging/tracing
+	runner := benchmarkgo.NewQueryRunner(opts)
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
+			if time.Now().Before(deadline) {
+				time.Sleep(time.Until(deadline))
+			}
+		}
+		count, failed, latency := runner.Run(context.Background(), querier{c.client}, job)
+		benchmarkgo.AddQuery(int64(count), latency.Microseconds(), int64(failed))
+	}
+}
@@ -0,0 +1,188 @@
+package benchmarkgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reproSampleRows is how many rows of the failing batch a bundle keeps.
+const reproSampleRows = 3
+
+// SchemaDescriber is implemented by WorkerCtx backends that can render the table's DDL for repro bundles.
+type SchemaDescriber interface {
+	DescribeSchema(ctx context.Context) (string, error)
+}
+
+// VersionReporter is implemented by WorkerCtx backends that can report the server version.
+type VersionReporter interface {
+	ServerVersion(ctx context.Context) (string, error)
+}
+
+// ReproOptions enables repro bundles: one directory per (op, error code) once the code has occurred After
+// times, and one for a fatal setup error.
+type ReproOptions struct {
+	Dir   string // "" = disabled
+	After int    // occurrences of one (op, code) before its bundle is written (default 1)
+}
+
+// reproRecorder counts errors per (op, code) and writes each code's bundle once.
+type reproRecorder struct {
+	r        *LoadRunner
+	opts     ReproOptions
+	settings []byte // effective config and metadata, captured when the recorder starts
+	setUp    bool   // backend Setup succeeded, so its schema and version can be queried
+
+	mu        sync.Mutex
+	counts    map[errorKey]int
+	written   map[errorKey]bool
+	lastBatch []RowForDB
+}
+
+var activeRepro atomic.Pointer[reproRecorder]
+
+// startRepro activates bundle capture for the run.
+func (r *LoadRunner) startRepro() {
+	rec := r.newReproRecorder()
+	rec.setUp = true
+	activeRepro.Store(rec)
+	log.Printf("Repro bundles: %s (after %d occurrences of an error code)", rec.opts.Dir, rec.opts.After)
+}
+
+func (r *LoadRunner) newReproRecorder() *reproRecorder {
+	opts := r.Config.Repro
+	if opts.After <= 0 {
+		opts.After = 1
+	}
+	settings, _ := json.MarshalIndent(struct {
+		Config   Config                 `json:"config"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}{r.Config, r.metadata}, "", "  ")
+	return &reproRecorder{
+		r:        r,
+		opts:     opts,
+		settings: settings,
+		counts:   make(map[errorKey]int),
+		written:  make(map[errorKey]bool),
+	}
+}
+
+// stopRepro deactivates bundle capture.
+func stopRepro() {
+	activeRepro.Store(nil)
+}
+
+// reproBatch remembers the head of a batch whose insert failed, for the bundle of the error it caused.
+func reproBatch(rows []RowForDB) {
+	rec := activeRepro.Load()
+	if rec == nil {
+		return
+	}
+	sample := append([]RowForDB(nil), rows[:min(len(rows), reproSampleRows)]...)
+	rec.mu.Lock()
+	rec.lastBatch = sample
+	rec.mu.Unlock()
+}
+
+// noteReproError counts an error and writes its bundle when the count reaches ReproOptions.After.
+func noteReproError(op, code string, err error) {
+	rec := activeRepro.Load()
+	if rec == nil {
+		return
+	}
+	k := errorKey{op, code}
+	rec.mu.Lock()
+	rec.counts[k]++
+	due := rec.counts[k] >= rec.opts.After && !rec.written[k]
+	if due {
+		rec.written[k] = true
+	}
+	count := rec.counts[k]
+	var batch []RowForDB
+	if op == ErrOpInsert {
+		batch = rec.lastBatch
+	}
+	rec.mu.Unlock()
+	if due {
+		rec.write(op, code, err, count, batch)
+	}
+}
+
+// writeFatalRepro writes a bundle for an error that aborts the run (e.g. Setup failing).
+func (r *LoadRunner) writeFatalRepro(op string, err error) {
+	if r.Config.Repro.Dir == "" {
+		return
+	}
+	r.newReproRecorder().write(op, ClassifyError(err), err, 1, nil)
+}
+
+// write creates <Dir>/<time>-<op>-<code>/ with the error, batch sample, schema, settings and versions.
+func (rec *reproRecorder) write(op, code string, err error, count int, batch []RowForDB) {
+	now := time.Now().UTC()
+	name := now.Format("20060102T150405.000") + "-" + op + "-" + strings.NewReplacer(":", "_", "/", "_", " ", "_").Replace(code)
+	dir := filepath.Join(rec.opts.Dir, name)
+	if mkErr := os.MkdirAll(dir, 0o755); mkErr != nil {
+		log.Printf("Repro bundle %s: %v", dir, mkErr)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	files := map[string][]byte{
+		"error.txt": []byte(fmt.Sprintf("time: %s\ndatabase: %s\noperation: %s\ncode: %s\noccurrences: %d\nerror: %v\n",
+			now.Format(time.RFC3339Nano), rec.r.Config.Database, op, code, count, err)),
+		"settings.json": append(rec.settings, '\n'),
+		"versions.txt":  []byte(rec.versions(ctx)),
+	}
+	if len(batch) > 0 {
+		var b strings.Builder
+		for _, row := range batch {
+			b.WriteString(row.JSONMessage)
+			b.WriteByte('\n')
+		}
+		files["batch.ndjson"] = []byte(b.String())
+	}
+	if sd, ok := rec.r.WorkerCtx.(SchemaDescriber); ok && rec.setUp {
+		if ddl, dErr := sd.DescribeSchema(ctx); dErr == nil {
+			files["schema.sql"] = []byte(ddl + "\n")
+		} else {
+			files["schema.sql"] = []byte("-- describe schema: " + dErr.Error() + "\n")
+		}
+	}
+	for file, data := range files {
+		if wErr := os.WriteFile(filepath.Join(dir, file), data, 0o644); wErr != nil {
+			log.Printf("Repro bundle %s: %v", dir, wErr)
+			return
+		}
+	}
+	log.Printf("Repro bundle written to %s (%s %s, %d occurrences)", dir, op, code, count)
+}
+
+// versions lists the Go runtime, the server version when the backend reports it, and every module in the
+// build (database drivers included) with its version.
+func (rec *reproRecorder) versions(ctx context.Context) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if vr, ok := rec.r.WorkerCtx.(VersionReporter); ok && rec.setUp {
+		if v, err := vr.ServerVersion(ctx); err == nil {
+			fmt.Fprintf(&b, "server: %s\n", v)
+		} else {
+			fmt.Fprintf(&b, "server: unknown (%v)\n", err)
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		b.WriteString("modules:\n")
+		for _, dep := range info.Deps {
+			fmt.Fprintf(&b, "  %s %s\n", dep.Path, dep.Version)
+		}
+	}
+	return b.String()
+}
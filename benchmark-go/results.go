@@ -0,0 +1,105 @@
+package benchmarkgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Results is the machine-readable outcome of a run, written as JSON when Config.ResultsJSON is set.
+type Results struct {
+	Database    string                 `json:"database"`
+	StartedAt   time.Time              `json:"started_at"`
+	ElapsedSec  float64                `json:"elapsed_sec"`
+	WarmupSec   float64                `json:"warmup_sec,omitempty"` // excluded from the stats before elapsed_sec began
+	TargetRPS   int                    `json:"target_rps"`
+	Concurrency int                    `json:"concurrency,omitempty"` // closed-loop runs: batches in flight, no target rate
+	ActualRPS   float64                `json:"actual_rps"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	Inserted    InsertedStats          `json:"inserted"`
+	Batches     *BatchReport           `json:"insert_batches,omitempty"`
+	Queries     QueryStats             `json:"queries"`
+	Goodput     *GoodputReport         `json:"goodput,omitempty"`
+	DutyCycle   *DutyCycleReport       `json:"duty_cycle,omitempty"`
+	ResultSets  []ResultSetBucket      `json:"result_sets,omitempty"`
+	Sessions    *SessionStats          `json:"sessions,omitempty"`
+	Verify      *VerifyReport          `json:"verify,omitempty"`
+	Checksum    *ChecksumReport        `json:"checksum,omitempty"`
+	Reconnects  *ReconnectStats        `json:"reconnects,omitempty"`
+	Breaker     *BreakerReport         `json:"breaker,omitempty"`
+	Errors      []ErrorCount           `json:"errors,omitempty"`
+	Warnings    []WarningCount         `json:"warnings,omitempty"`
+	Attribution *LatencyAttribution    `json:"latency_attribution,omitempty"`
+	DualWrite   *DualWriteReport       `json:"dual_write,omitempty"`
+	Visibility  *VisibilityReport      `json:"visibility,omitempty"`
+	Server      *ServerMetricsReport   `json:"server_metrics,omitempty"`
+	Attainment  *AttainmentReport      `json:"target_attainment,omitempty"`
+	LoadSteps   *LoadStepReport        `json:"load_steps,omitempty"`
+	FindMax     *FindMaxReport         `json:"find_max,omitempty"`
+	Burst       *BurstReport           `json:"bursts,omitempty"`
+	Client      *ClientReport          `json:"client_resources,omitempty"`
+	InsertPhase *InsertPhaseBreakdown  `json:"insert_phases,omitempty"`
+	Retention   *RetentionReport       `json:"retention,omitempty"`
+	Snapshots   []SnapshotReport       `json:"snapshots,omitempty"`
+	Preload     *PreloadReport         `json:"preload,omitempty"`
+	Conflicts   *ConflictReport        `json:"conflicts,omitempty"`
+	Failover    *FailoverReport        `json:"failover,omitempty"`
+	Reconcile   *ReconcileReport       `json:"reconcile,omitempty"`
+	Dedup       *DedupReport           `json:"dedup,omitempty"`
+	Shards      *ShardDistribution     `json:"shards,omitempty"`
+	Stages      []StageStats           `json:"stages,omitempty"`
+	Transforms  []TransformStats       `json:"payload_transforms,omitempty"`
+	Cost        *CostReport            `json:"cost,omitempty"`
+	Guardrail   *GuardrailTrip         `json:"guardrail,omitempty"`
+	Hints       []string               `json:"bottleneck_hints,omitempty"`
+	Summary     *SummaryReport         `json:"summary,omitempty"`
+	Baseline    *BaselineReport        `json:"baseline,omitempty"`
+	Events      []Event                `json:"events,omitempty"` // timeline of mid-run changes and annotations
+}
+
+// SetMetadata records an effective setting (e.g. GOMAXPROCS, CPU affinity) to be included in the results.
+func (r *LoadRunner) SetMetadata(key string, value interface{}) {
+	if r.metadata == nil {
+		r.metadata = make(map[string]interface{})
+	}
+	r.metadata[key] = value
+}
+
+// WriteResults writes res as indented JSON to path.
+func WriteResults(path string, res *Results) error {
+	b, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// ReadResults reads a Results JSON file written by WriteResults.
+func ReadResults(path string) (*Results, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var res Results
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &res, nil
+}
+
+// formatMetadata renders metadata as "k1=v1 k2=v2" sorted by key.
+func formatMetadata(m map[string]interface{}) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", k, m[k])
+	}
+	return strings.Join(parts, " ")
+}
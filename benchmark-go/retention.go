@@ -0,0 +1,132 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RetentionResult is what a backend reports for one retention step.
+type RetentionResult struct {
+	Method      string  `json:"method"` // e.g. "DELETE+VACUUM", "ALTER DELETE (mutations_sync=2)"
+	RowsBefore  int64   `json:"rows_before"`
+	RowsAfter   int64   `json:"rows_after"`
+	BytesBefore int64   `json:"bytes_before"` // table storage before the step (-1 if unknown)
+	BytesAfter  int64   `json:"bytes_after"`  // table storage after reclamation (-1 if unknown)
+	DeleteSec   float64 `json:"delete_sec"`
+	ReclaimSec  float64 `json:"reclaim_sec"` // VACUUM / merge wait after the delete; 0 if not applicable
+	// Note flags a method that does not measure the intended retention path (e.g. a ClickHouse mutation instead of
+	// DROP PARTITION), so its numbers are not compared with runs that used it.
+	Note string `json:"note,omitempty"`
+}
+
+// RetentionBackend is implemented by WorkerCtx backends that can drop data older than a cutoff while ingestion continues.
+type RetentionBackend interface {
+	DeleteOlderThan(ctx context.Context, cutoff time.Time) (RetentionResult, error)
+}
+
+// RetentionReport is the retention step outcome plus the concurrent-ops impact measured around it.
+type RetentionReport struct {
+	Result   RetentionResult `json:"result"`
+	Cutoff   time.Time       `json:"cutoff"`
+	StartSec float64         `json:"start_sec"` // seconds since run start when the step began
+	Before   OpsWindow       `json:"before"`
+	During   OpsWindow       `json:"during"`
+	Err      error           `json:"-"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// OpsWindow is insert/query throughput and average latency over a window of the run.
+type OpsWindow struct {
+	Seconds     float64 `json:"seconds"`
+	InsertRPS   float64 `json:"insert_rps"`
+	InsertAvgMs float64 `json:"insert_avg_ms"`
+	QueryQPS    float64 `json:"query_qps"`
+	QueryAvgMs  float64 `json:"query_avg_ms"`
+}
+
+func windowBetween(a, b Snapshot, seconds float64) OpsWindow {
+	w := OpsWindow{Seconds: seconds}
+	rows := b.Inserted.Total - a.Inserted.Total
+	queries := b.Queries.Count - a.Queries.Count
+	if seconds > 0 {
+		w.InsertRPS = rows / seconds
+		w.QueryQPS = queries / seconds
+	}
+	if rows > 0 {
+		w.InsertAvgMs = (b.Inserted.TotalInsertLatencySec - a.Inserted.TotalInsertLatencySec) / rows * 1000
+	}
+	if queries > 0 {
+		w.QueryAvgMs = (b.Queries.TotalLatencySec - a.Queries.TotalLatencySec) / queries * 1000
+	}
+	return w
+}
+
+// runRetention waits until cfg.RetentionAtSec into the run, then deletes rows older than cfg.RetentionKeepSec
+// while producers and workers keep running. The report is stored on r.retention for the summary.
+func (r *LoadRunner) runRetention(ctx context.Context) {
+	cfg := &r.Config
+	rb, ok := r.WorkerCtx.(RetentionBackend)
+	if !ok {
+		log.Printf("Retention: %s backend does not support the retention step, skipping", cfg.Database)
+		return
+	}
+	timer := time.NewTimer(time.Until(r.runStart.Add(time.Duration(cfg.RetentionAtSec * float64(time.Second)))))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+	cutoff := time.Now().Add(-time.Duration(cfg.RetentionKeepSec * float64(time.Second))).UTC()
+	startSec := time.Since(r.runStart).Seconds()
+	log.Printf("Retention: dropping rows with created_at < %s (keep last %.0fs) while ingestion continues",
+		cutoff.Format(time.RFC3339), cfg.RetentionKeepSec)
+	before := loadSnapshot()
+	t0 := time.Now()
+	res, err := rb.DeleteOlderThan(context.Background(), cutoff)
+	elapsed := time.Since(t0).Seconds()
+	after := loadSnapshot()
+	rep := &RetentionReport{
+		Result:   res,
+		Cutoff:   cutoff,
+		StartSec: startSec,
+		Before:   windowBetween(Snapshot{}, before, startSec),
+		During:   windowBetween(before, after, elapsed),
+		Err:      err,
+	}
+	if err != nil {
+		rep.Error = err.Error()
+		log.Printf("Retention: %v", err)
+	} else {
+		log.Printf("Retention: %s removed %d rows in %.2fs (reclaim %.2fs)",
+			res.Method, res.RowsBefore-res.RowsAfter, res.DeleteSec, res.ReclaimSec)
+	}
+	r.retention = rep
+}
+
+// logRetention logs the retention step outcome and the insert/query impact while it ran.
+func logRetention(rep *RetentionReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Retention step at %.1fs (cutoff %s):", rep.StartSec, rep.Cutoff.Format(time.RFC3339))
+	if rep.Err != nil {
+		log.Printf("  failed: %v", rep.Err)
+		return
+	}
+	res := rep.Result
+	log.Printf("  %s: rows %d -> %d (%d deleted) | delete %.2fs, reclaim %.2fs",
+		res.Method, res.RowsBefore, res.RowsAfter, res.RowsBefore-res.RowsAfter, res.DeleteSec, res.ReclaimSec)
+	if res.Note != "" {
+		log.Printf("  NOT COMPARABLE: %s", res.Note)
+	}
+	if res.BytesBefore >= 0 && res.BytesAfter >= 0 {
+		log.Printf("  storage: %.1f MiB -> %.1f MiB (%.1f MiB reclaimed)",
+			float64(res.BytesBefore)/(1<<20), float64(res.BytesAfter)/(1<<20), float64(res.BytesBefore-res.BytesAfter)/(1<<20))
+	}
+	log.Printf("  before step: insert %.1f rows/sec avg %.2f ms/row | query %.1f q/sec avg %.2f ms",
+		rep.Before.InsertRPS, rep.Before.InsertAvgMs, rep.Before.QueryQPS, rep.Before.QueryAvgMs)
+	log.Printf("  during step: insert %.1f rows/sec avg %.2f ms/row | query %.1f q/sec avg %.2f ms (%.1fs)",
+		rep.During.InsertRPS, rep.During.InsertAvgMs, rep.During.QueryQPS, rep.During.QueryAvgMs, rep.During.Seconds)
+}
@@ -2,6 +2,7 @@ package benchmarkgo
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sync"
 	"sync/atomic"
@@ -19,6 +20,7 @@ const (
 type Config struct {
 	Database           string
 	DurationSec        float64
+	TotalRows          int // stop once this many rows are generated (0 = run for DurationSec); DurationSec still caps it
 	BatchSize          int
 	Workers            int
 	TargetRPS          int
@@ -27,7 +29,169 @@ type Config struct {
 	ProducerThreads    int
 	IgnoreSelectErrors bool
 	DuplicateRatio     float64
+	DuplicateLagSec    float64 // duplicates re-send originals from about this long ago at TargetRPS (0 = any earlier original)
 	PgbouncerEnabled   bool
+	QueryType          string           // QueryTypePK (default), QueryTypeResultSet, QueryTypeSession or QueryTypeRange
+	QueryWeights       QueryWeights     // when set, each query's type is drawn by these weights instead of QueryType
+	PKSelect           string           // what pk lookups select: PKSelectCount (default), PKSelectColumns or PKSelectPayload
+	Verify             bool             // pk lookups compare names, DOB and checksum with the generated record
+	ResultSetSizes     []int            // LIMIT values cycled through by QueryTypeResultSet
+	SessionThinkSec    float64          // pause between the queries of a QueryTypeSession session
+	RangeWindowSec     float64          // QueryTypeRange covers created_at from this long ago until now
+	PageSize           int              // rows per page for QueryTypePageOffset and QueryTypePageKeyset
+	PageDepth          int              // QueryTypePageOffset fetches a uniformly random page below this one
+	QueryThinkSec      float64          // pause between the QueriesPerRecord lookups of one record (0 = back to back)
+	QueryJitterSec     float64          // QueryThinkSec varies uniformly by up to this much either way
+	RetentionAtSec     float64          // run the retention step this many seconds into the run (0 = disabled)
+	RetentionKeepSec   float64          // retention step deletes rows with created_at older than now minus this
+	ResultsJSON        string           // write Results as JSON to this path at the end of the run ("" = disabled)
+	OutputCSV          string           // append each progress interval's stats to this CSV file ("" = disabled)
+	ReportMarkdown     string           // write the end-of-run summary as Markdown to this path ("" = disabled)
+	Baseline           BaselineOptions  // fail the run on regressions against a stored results file (disabled when Path is "")
+	Durability         string           // DurabilityOff..DurabilityRemoteApply, or DurabilityDefault
+	LiveConfigPath     string           // watched JSON file of LiveSettings applied mid-run ("" = disabled)
+	AnnotationsPath    string           // tailed file whose appended lines become timeline annotations ("" = disabled)
+	ControlAddr        string           // serve the HTTP control API (stats, settings, pause/resume, stop) here ("" = disabled)
+	ControlToken       string           // bearer token the control API's POST routes require ("" = loopback addresses only)
+	InsertQueueSize    int              // producer queue capacity in batches (0 = derived from workers/producers)
+	QueryQueueSize     int              // query queue capacity in records (0 = derived from batch size/workers/target RPS)
+	PatientCounter     string           // PatientCounterMax (default), PatientCounterReserve or PatientCounterStatic
+	RunnerID           int              // this runner's index for PatientCounterStatic
+	RunnerCount        int              // number of concurrent runners for PatientCounterStatic
+	LatencySampleRate  float64          // fraction of pk lookups attributed to server vs network time (0 = off)
+	SnapshotRestore    string           // replace the table's contents with this snapshot before the load ("" = disabled)
+	SnapshotSave       string           // copy the table's state into this snapshot after the load ("" = disabled)
+	PreloadRows        int              // bulk-load this many fresh patients before the measured run (0 = disabled)
+	ConflictWriters    int              // writers upserting a shared MRN set to measure lock contention (0 = disabled)
+	ConflictKeys       int              // size of the shared MRN set
+	ConflictIsolation  string           // IsolationReadCommitted (default), IsolationRepeatableRead or IsolationSerializable
+	FailoverWatch      bool             // measure error window, recovery time and data loss around a failover
+	FailoverHook       string           // shell command that triggers the failover ("" = triggered externally)
+	FailoverAtSec      float64          // run FailoverHook this many seconds into the run
+	Reconcile          bool             // count keys and rows before and after the run and compare with acked inserts
+	DedupWatch         bool             // after the run, measure how long background merges take to collapse duplicates
+	DedupPollSec       float64          // DedupWatch polls count() vs count() FINAL this often
+	DedupWaitSec       float64          // DedupWatch waits at most this long for convergence before OPTIMIZE FINAL
+	PayloadTransforms  string           // comma-separated SOURCE transforms applied before insert, e.g. "zstd,aes-gcm" ("" = none)
+	Cost               CostOptions      // prices for the per-run cost estimate (disabled when all zero)
+	Guardrails         GuardrailOptions // stop the run when the table exceeds these limits (disabled when all zero)
+	Breaker            BreakerOptions   // pause insert workers and shed records after failed or slow batches (disabled when both triggers are zero)
+	Repro              ReproOptions     // write failure repro bundles (disabled when Dir is "")
+	VerifyGoodput      bool             // count generated MRNs before and after the load to verify goodput
+	DutyCycle          DutyCycleOptions // alternate active and idle phases (disabled when either is zero)
+	Tracing            TracingOptions   // export OpenTelemetry spans (disabled when Endpoint is "")
+	Seed               int64            // seeds the generated stream so runs can replay it (0 = random, recorded in metadata)
+	ServerMetricsSec   float64          // sample the backend's ServerMetricsReporter this often during the load (0 = disabled)
+	Push               PushOptions      // push Metrics to a Pushgateway or remote-write endpoint (disabled when URL is "")
+	WarmupSec          float64          // run this many seconds of load before the summary's stats start (0 = include everything)
+	Ramp               RampOptions      // scale the target rate up from 0 and back down (disabled when both are zero)
+	LoadSteps          []LoadStep       // successive rates, each held for its duration; TargetRPS is the first (nil = constant)
+	FindMax            FindMaxOptions   // search for the highest rate within an insert p99 SLO, starting at TargetRPS
+	Arrival            string           // ArrivalFixed (default), ArrivalUniform or ArrivalPoisson gaps between batches
+	Pattern            LoadPattern      // modulate the target rate periodically (disabled when Shape is "")
+	Burst              BurstOptions     // superimpose periodic bursts on the target rate (disabled when RPS is 0)
+	Mode               string           // ModeInsertQuery (default), ModeQueryOnly, ModeInsertOnly or ModeClosed
+	Concurrency        int              // ModeClosed: batches in flight, one per insert worker (overrides Workers)
+	Workload           string           // WorkloadWriteTriggered (default) or WorkloadMixed
+	ReadRatio          float64          // WorkloadMixed: reads / (reads + writes), e.g. 0.8
+	KeyDist            KeyDist          // which loaded patients WorkloadMixed and ModeQueryOnly reads pick (zero = uniform)
+}
+
+// DefaultConfig returns loadrunner's default settings (its flag defaults) for database; fields it leaves zero are
+// features that are off by default.
+func DefaultConfig(database string) Config {
+	return Config{
+		Database:         database,
+		DurationSec:      60,
+		BatchSize:        100,
+		Workers:          5,
+		TargetRPS:        1000,
+		QueriesPerRecord: 10,
+		ProducerThreads:  2,
+		DuplicateRatio:   0.25,
+		QueryType:        QueryTypePK,
+		PKSelect:         PKSelectCount,
+		RangeWindowSec:   3600,
+		PageSize:         50,
+		PageDepth:        100,
+		ResultSetSizes:   []int{1, 10, 100},
+		ConflictKeys:     100,
+		DedupPollSec:     5,
+		DedupWaitSec:     300,
+		Breaker:          BreakerOptions{SlowBatches: 5, PauseSec: 5},
+		Repro:            ReproOptions{After: 3},
+		PatientCounter:   PatientCounterMax,
+		RunnerCount:      1,
+		Tracing:          TracingOptions{SampleRate: 0.01},
+		Mode:             ModeInsertQuery,
+		Workload:         WorkloadWriteTriggered,
+		ReadRatio:        0.8,
+	}
+}
+
+// Validate checks the settings a run cannot start with. Empty mode, workload, query type and patient counter
+// strings mean their defaults.
+func (c *Config) Validate() error {
+	closed := c.Mode == ModeClosed
+	switch {
+	case c.Database == "":
+		return fmt.Errorf("Database is required")
+	case c.DurationSec <= 0:
+		return fmt.Errorf("DurationSec must be > 0")
+	case c.BatchSize < 1:
+		return fmt.Errorf("BatchSize must be >= 1")
+	case c.Workers < 1 && !(closed && c.Concurrency > 0):
+		return fmt.Errorf("Workers must be >= 1")
+	case c.ProducerThreads < 2:
+		return fmt.Errorf("ProducerThreads must be >= 2")
+	case c.TargetRPS < 1 && !closed:
+		return fmt.Errorf("TargetRPS must be >= 1 (only ModeClosed runs without a target rate)")
+	case c.QueriesPerRecord < 0:
+		return fmt.Errorf("QueriesPerRecord must be >= 0")
+	case c.DuplicateRatio < 0 || c.DuplicateRatio > 1:
+		return fmt.Errorf("DuplicateRatio must be between 0 and 1")
+	case c.DuplicateLagSec < 0 || c.TotalRows < 0 || c.PreloadRows < 0:
+		return fmt.Errorf("DuplicateLagSec, TotalRows and PreloadRows must be >= 0")
+	case c.DuplicateLagSec > 0 && c.DuplicateLagSec*float64(c.TargetRPS) < float64(2*c.BatchSize):
+		// A shorter lag reaches back less than a batch, so most duplicates pile onto the last earlier original.
+		return fmt.Errorf("DuplicateLagSec must span at least two batches (%d rows) at TargetRPS, got %d rows",
+			2*c.BatchSize, int(c.DuplicateLagSec*float64(c.TargetRPS)))
+	case c.InsertQueueSize < 0 || c.QueryQueueSize < 0:
+		return fmt.Errorf("InsertQueueSize and QueryQueueSize must be >= 0")
+	case c.QueryType != "" && !IsQueryType(c.QueryType):
+		return fmt.Errorf("unknown QueryType %q", c.QueryType)
+	case c.QueryType == QueryTypeRange && c.RangeWindowSec <= 0:
+		return fmt.Errorf("RangeWindowSec must be > 0 for range queries")
+	case (c.QueryType == QueryTypePageOffset || c.QueryType == QueryTypePageKeyset) && (c.PageSize < 1 || c.PageDepth < 1):
+		return fmt.Errorf("PageSize and PageDepth must be >= 1 for page queries")
+	case c.QueryType == QueryTypeResultSet && len(c.ResultSetSizes) == 0:
+		return fmt.Errorf("ResultSetSizes is required for result-set queries")
+	case c.Breaker.Enabled() && (c.Breaker.SlowBatches < 1 || c.Breaker.PauseSec <= 0):
+		return fmt.Errorf("Breaker needs SlowBatches >= 1 and PauseSec > 0")
+	case c.DedupWatch && (c.DedupPollSec <= 0 || c.DedupWaitSec < 0):
+		return fmt.Errorf("DedupPollSec must be > 0 and DedupWaitSec >= 0")
+	}
+	if c.Mode != "" {
+		if err := ValidateMode(c.Mode); err != nil {
+			return err
+		}
+	}
+	if c.Workload != "" {
+		if err := ValidateWorkload(c.Workload, c.ReadRatio); err != nil {
+			return err
+		}
+	}
+	if c.PatientCounter != "" {
+		if err := ValidatePatientCounter(c.PatientCounter, c.RunnerID, c.RunnerCount); err != nil {
+			return err
+		}
+	}
+	if c.PKSelect != "" {
+		if err := ValidatePKSelect(c.PKSelect); err != nil {
+			return err
+		}
+	}
+	return ValidatePayloadTransforms(c.PayloadTransforms)
 }
 
 // WorkerCtx is the interface for postgres/clickhouse (Setup, Teardown, GetMaxPatientCounter, RunQueryWorker).
@@ -35,26 +199,60 @@ type WorkerCtx interface {
 	Setup(numWorkers, targetRPS int, queriesPerRecord int) (InsertBackend, error)
 	Teardown()
 	GetMaxPatientCounter() (int, error)
-	RunQueryWorker(workerIndex int, queryQueue <-chan *QueryJob, queriesPerRecord int, queryDelaySec float64, ignoreSelectErrors bool)
+	RunQueryWorker(workerIndex int, queryQueue <-chan *QueryJob, opts QueryOptions)
 }
 
 // Router distributes from producer queue to worker queues with rate limiting. Round-robin to workers; pair.TargetDB is already set by Producer.
 type Router struct {
-	ProducerQueue  <-chan *InsertPair
-	WorkerQueues   []chan *InsertPair
-	RateLimiter    *rate.Limiter
-	nextIndex      int
+	ProducerQueue <-chan *InsertPair
+	WorkerQueues  []chan *InsertPair
+	RateLimiter   *rate.Limiter
+	Pipeline      *Pipeline // records the pace stage: time each batch waits on the limiter and worker queues
+	nextIndex     int
+	gate          *dutyGate    // holds batches back during duty-cycle idle phases; nil = always active
+	schedule      time.Time    // next batch's intended start on the constant-rate schedule
+	ramp          *ramp        // scales the rate during ramp-up and ramp-down; nil = constant target
+	pattern       *loadPattern // modulates the rate periodically; nil = constant target
+	arrivals      *arrivals    // draws random gaps between batches; nil = fixed (token bucket)
+	burstRPS      int          // rows/sec added while burstActive
 }
 
 // NewRouter creates a Router. workerQueues are the per-worker queues to distribute to.
-func NewRouter(producerQueue <-chan *InsertPair, workerQueues []chan *InsertPair, rateLimiter *rate.Limiter) *Router {
+func NewRouter(producerQueue <-chan *InsertPair, workerQueues []chan *InsertPair, rateLimiter *rate.Limiter, pipeline *Pipeline) *Router {
 	return &Router{
 		ProducerQueue: producerQueue,
 		WorkerQueues:  workerQueues,
 		RateLimiter:   rateLimiter,
+		Pipeline:      pipeline,
 	}
 }
 
+// intended returns when a batch of rows is due on the rate schedule and advances the schedule: evenly spaced at
+// the current rate, or drawn gaps with a random arrival process (which then sends the batch when due). This is
+// the wrk2-style intended start that end-to-end latency is measured from: when the backend stalls, batches queue
+// up behind it and their latency includes that wait instead of being omitted (coordinated omission). Unpaced
+// runs have no schedule and use now.
+func (r *Router) intended(now time.Time, rows int) time.Time {
+	if r.RateLimiter == nil || r.RateLimiter.Limit() == rate.Inf || r.RateLimiter.Limit() <= 0 {
+		return now
+	}
+	// A ramp's rate changes under every batch, so the schedule restarts once the rate holds at the target.
+	if r.ramp != nil && r.ramp.factor(now) < 1 {
+		r.schedule = time.Time{}
+		return now
+	}
+	if r.schedule.IsZero() {
+		r.schedule = now
+	}
+	due := r.schedule
+	gap := time.Duration(float64(rows) / float64(r.RateLimiter.Limit()) * float64(time.Second))
+	if r.arrivals != nil {
+		gap = r.arrivals.gap(gap)
+	}
+	r.schedule = r.schedule.Add(gap)
+	return due
+}
+
 // Run drains the producer queue, rate-limits, and sends to worker queues round-robin. Closes all worker queues when done.
 // If ctx is cancelled (e.g. Ctrl+C), rate-limited wait is interrupted and the loop exits.
 func (r *Router) Run(ctx context.Context) {
@@ -72,15 +270,38 @@ func (r *Router) Run(ctx context.Context) {
 				}
 				return
 			}
+			t0 := time.Now()
+			if r.gate != nil {
+				if err := r.gate.wait(ctx); err != nil {
+					for i := range r.WorkerQueues {
+						close(r.WorkerQueues[i])
+					}
+					return
+				}
+				// Idle duty-cycle phases are intended pauses, so the schedule resumes after them.
+				if !r.schedule.IsZero() {
+					r.schedule = r.schedule.Add(time.Since(t0))
+				}
+			}
 			totalRows := len(pair.Originals) + len(pair.Duplicates)
+			pair.Intended = r.intended(time.Now(), totalRows)
 			if totalRows > 0 && r.RateLimiter != nil {
-				if err := r.RateLimiter.WaitN(ctx, totalRows); err != nil {
+				wait := r.RateLimiter.WaitN
+				switch {
+				case r.ramp != nil || r.pattern != nil || r.burstRPS > 0:
+					wait = r.shapedWait
+				case r.arrivals != nil && r.RateLimiter.Limit() > 0 && r.RateLimiter.Limit() != rate.Inf:
+					wait = func(ctx context.Context, _ int) error { return waitArrival(ctx, pair.Intended) }
+				}
+				if err := wait(ctx, totalRows); err != nil {
 					for i := range r.WorkerQueues {
 						close(r.WorkerQueues[i])
 					}
 					return
 				}
 			}
+			t1 := time.Now()
+			routerLimiterMicros.Add(t1.Sub(t0).Microseconds())
 			idx := r.nextIndex % len(r.WorkerQueues)
 			r.nextIndex = (r.nextIndex + 1) % len(r.WorkerQueues)
 			select {
@@ -90,33 +311,79 @@ func (r *Router) Run(ctx context.Context) {
 				}
 				return
 			case r.WorkerQueues[idx] <- pair:
+				routerQueueMicros.Add(time.Since(t1).Microseconds())
 				AddInsertStarted(1)
+				noteEnqueued(totalRows)
+				r.Pipeline.Record(StagePace, totalRows, time.Since(t0), 0)
 			}
 		}
 	}
 }
 
 // LoadRunner holds config, backend context, and runtime state for a load run.
+// Pipeline wires the run's stages; add RowStages to it before Run.
 type LoadRunner struct {
 	Config    Config
 	WorkerCtx WorkerCtx
+	Pipeline  *Pipeline
 
 	// Runtime state (set by Run)
-	runStart         time.Time
-	producerQueue    chan *InsertPair
-	queryQueue       chan *QueryJob
-	workerQueues     []chan *InsertPair
-	doneCh           chan struct{}
-	resultCh         chan Snapshot
-	runCtx           context.Context
-	cancelRun        context.CancelFunc
-	patientStart     int
-	nextBatchIndex   atomic.Int64 // shared by producers; batch index → pair.TargetDB and patient ordinals
-	backend          InsertBackend
-	triggers         []chan struct{}
-	producers        []*Producer
-	insertWorkers    []*InsertWorker
-	progressReporter *Reporter
+	runStart          time.Time
+	producerQueue     chan *InsertPair
+	queryQueue        chan *QueryJob
+	workerQueues      []chan *InsertPair
+	doneCh            chan struct{}
+	resultCh          chan Snapshot
+	runCtx            context.Context
+	cancelRun         context.CancelFunc
+	patients          *PatientAllocator
+	abortOnce         sync.Once
+	runErr            error        // why the run was stopped early by a failure (see abort)
+	started           bool         // Setup succeeded; see Started
+	nextBatchIndex    atomic.Int64 // shared by producers; batch index → pair.TargetDB and patient ordinals
+	rowsLeft          atomic.Int64 // shared by producers under Config.TotalRows
+	backend           InsertBackend
+	triggers          []chan struct{}
+	producers         []*Producer
+	insertWorkers     []*InsertWorker
+	progressReporter  *Reporter
+	retention         *RetentionReport
+	snapshots         []SnapshotReport
+	preload           *PreloadReport
+	cpuBefore         float64 // process CPU seconds spent before the run clock started (restore, preload)
+	conflicts         *ConflictReport
+	failover          *FailoverReport
+	reconcile         *ReconcileReport
+	dedup             *DedupReport
+	shardsBefore      []ShardRows
+	shards            *ShardDistribution
+	transforms        PayloadTransforms
+	cost              CostOptions
+	costReport        *CostReport
+	guardrail         *GuardrailTrip
+	goodput           *GoodputReport
+	duty              *DutyCycleReport
+	dutyGate          *dutyGate  // also the control API's pause switch
+	controlMu         sync.Mutex // serializes control API pause/resume
+	hintWindows       []hintWindow
+	hints             []string
+	summary           *SummaryReport
+	baseline          *BaselineReport
+	serverMetrics     *ServerMetricsReport
+	results           *Results // set by logSummary at the end of Run
+	pusher            *metricsPusher
+	loadSteps         *LoadStepReport
+	findMax           *FindMaxReport
+	bursts            atomic.Int64 // bursts started
+	warmupMu          sync.Mutex
+	warmupBase        *Snapshot // counters at the end of an excluded warmup; nil until then
+	statsStart        time.Time // start of the summary's stats: runStart, or the end of an excluded warmup
+	goodputKeysBefore int64
+	runEnd            time.Time
+	metadata          map[string]interface{}
+	rateLimiter       *rate.Limiter
+	live              *LiveWorkload
+	events            eventLog
 }
 
 // NewLoadRunner builds a LoadRunner from config and worker context. Call Run() to execute the load.
@@ -124,22 +391,75 @@ func NewLoadRunner(cfg Config, ctx WorkerCtx) *LoadRunner {
 	return &LoadRunner{
 		Config:    cfg,
 		WorkerCtx: ctx,
+		Pipeline:  NewPipeline(),
 	}
 }
 
 // Run executes the full load: sets up channels and state, starts router, producers, and workers, then waits and logs summary.
-// If ctx is cancelled (e.g. Ctrl+C), producers stop and the run shuts down gracefully.
-func (r *LoadRunner) Run(ctx context.Context) {
+// If ctx is cancelled (e.g. Ctrl+C), producers stop and the run shuts down gracefully. It returns an error when the
+// run cannot start (setup, patient counter, output files) or is stopped by a failure mid-run (a patient range that
+// cannot be reserved); Results holds the outcome of a run that started, including one stopped that way.
+func (r *LoadRunner) Run(ctx context.Context) error {
 	cfg := &r.Config
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	mixed := cfg.Workload == WorkloadMixed
+	queryOnly := cfg.Mode == ModeQueryOnly
+	insertQueries := cfg.QueriesPerRecord
+	if mixed || queryOnly {
+		cfg.QueriesPerRecord, insertQueries = 1, 0 // each read job is one lookup; inserts enqueue none
+	}
+	if cfg.Mode == ModeInsertOnly {
+		cfg.QueriesPerRecord, insertQueries = 0, 0
+	}
+	closed := cfg.Mode == ModeClosed
+	if closed && cfg.Concurrency > 0 {
+		cfg.Workers = cfg.Concurrency
+	}
+	runQueryWorkers := cfg.QueriesPerRecord > 0
 	workers := cfg.Workers
 	producerThreads := cfg.ProducerThreads
+	workerQueue := workerQueueCap
+	if closed {
+		workerQueue = 1 // in flight is what the workers hold; a deep backlog would only lengthen the drain
+	}
 
 	r.runStart = time.Now()
-	producerQueueCap := max3(256, workers*workerQueueCap*2, producerThreads*32)
+	producerQueueCap := max3(256, workers*workerQueue*2, producerThreads*32)
+	producerQueueSource := "auto"
+	if cfg.InsertQueueSize > 0 {
+		producerQueueCap, producerQueueSource = cfg.InsertQueueSize, "override"
+	}
 	queryQueueMax := max3(workers*4, cfg.BatchSize*workers*4, cfg.TargetRPS*4)
+	queryQueueSource := "auto"
+	if cfg.QueryQueueSize > 0 {
+		queryQueueMax, queryQueueSource = cfg.QueryQueueSize, "override"
+	}
+	if runQueryWorkers {
+		log.Printf("Queues: insert (producer) %d batches [%s], per-worker %d batches x %d workers, query %d records [%s]",
+			producerQueueCap, producerQueueSource, workerQueue, workers, queryQueueMax, queryQueueSource)
+		r.SetMetadata("query_queue_size", queryQueueMax)
+		r.SetMetadata("query_queue_size_source", queryQueueSource)
+	} else {
+		log.Printf("Queues: insert (producer) %d batches [%s], per-worker %d batches x %d workers, no query queue",
+			producerQueueCap, producerQueueSource, workerQueue, workers)
+	}
+	r.SetMetadata("insert_queue_size", producerQueueCap)
+	r.SetMetadata("insert_queue_size_source", producerQueueSource)
+	r.SetMetadata("worker_queue_size", workerQueue)
+
+	if limit := MemoryLimit(); limit > 0 {
+		if budget := queueByteBudget(producerQueueCap, workers, workerQueue, cfg.BatchSize); budget > limit {
+			log.Printf("WARNING: full insert queues could hold ~%s of records (producer queue %d, %d workers x %d, batch size %d) which exceeds the memory limit %s; reduce --batch-size or --workers",
+				FormatBytes(budget), producerQueueCap, workers, workerQueue, cfg.BatchSize, FormatBytes(limit))
+		}
+	}
 
 	r.producerQueue = make(chan *InsertPair, producerQueueCap)
-	r.queryQueue = make(chan *QueryJob, queryQueueMax)
+	if runQueryWorkers {
+		r.queryQueue = make(chan *QueryJob, queryQueueMax)
+	}
 	r.doneCh = make(chan struct{})
 	r.resultCh = make(chan Snapshot, 1)
 	r.runCtx, r.cancelRun = context.WithTimeout(ctx, time.Duration(cfg.DurationSec*float64(time.Second)))
@@ -147,50 +467,384 @@ func (r *LoadRunner) Run(ctx context.Context) {
 
 	r.workerQueues = make([]chan *InsertPair, workers)
 	for i := 0; i < workers; i++ {
-		r.workerQueues[i] = make(chan *InsertPair, workerQueueCap)
+		r.workerQueues[i] = make(chan *InsertPair, workerQueue)
 	}
 
 	log.Printf("Connecting to %s (workers=%d, producers=%d, batch_size=%d, duration=%.1fs, target_rps=%d, queries_per_record=%d, query_delay=%.0fms, duplicate_ratio=%.2f)",
 		cfg.Database, workers, producerThreads, cfg.BatchSize, cfg.DurationSec, cfg.TargetRPS, cfg.QueriesPerRecord, cfg.QueryDelaySec*1000, cfg.DuplicateRatio)
+	switch cfg.QueryType {
+	case QueryTypeResultSet:
+		log.Printf("Query type %s with result-set sizes %v", cfg.QueryType, cfg.ResultSetSizes)
+	case QueryTypeSession:
+		log.Printf("Query type %s (lookup, fetch, aggregate per patient) with %.0fms think time", cfg.QueryType, cfg.SessionThinkSec*1000)
+	case QueryTypeRange:
+		log.Printf("Query type %s (a patient's rows over the last %gs of created_at)", cfg.QueryType, cfg.RangeWindowSec)
+	case QueryTypePageOffset, QueryTypePageKeyset:
+		log.Printf("Query type %s with %d-row pages (offset pages up to %d deep)", cfg.QueryType, cfg.PageSize, cfg.PageDepth)
+	}
+	if cfg.QueryThinkSec > 0 && cfg.QueriesPerRecord > 1 {
+		log.Printf("Query think time: %.0fms ± %.0fms between a record's lookups", cfg.QueryThinkSec*1000, cfg.QueryJitterSec*1000)
+		r.SetMetadata("query_think_ms", cfg.QueryThinkSec*1000)
+		r.SetMetadata("query_think_jitter_ms", cfg.QueryJitterSec*1000)
+	}
 
-	rateLimiter := rate.NewLimiter(rate.Limit(cfg.TargetRPS), cfg.BatchSize)
+	r.rateLimiter = rate.NewLimiter(rate.Limit(cfg.TargetRPS), cfg.BatchSize)
+	if closed {
+		r.rateLimiter.SetLimit(rate.Inf) // workers pull batches as fast as they commit them
+		log.Printf("Closed loop: %d batches in flight (one per insert worker), no target rate", workers)
+		r.SetMetadata("concurrency", workers)
+	}
+	targetRPS.Store(int64(cfg.TargetRPS))
+	r.live = NewLiveWorkload(cfg.QueriesPerRecord)
+	if len(cfg.QueryWeights) > 0 {
+		if err := r.live.SetQueryWeights(cfg.QueryWeights); err != nil {
+			return fmt.Errorf("query types: %w", err)
+		}
+		log.Printf("Query mix: %s", cfg.QueryWeights)
+		r.SetMetadata("query_types", cfg.QueryWeights.String())
+	}
+	if cfg.PKSelect != "" && cfg.PKSelect != PKSelectCount {
+		log.Printf("PK lookups fetch and scan the row (select %s) instead of counting it", cfg.PKSelect)
+		r.SetMetadata("pk_select", cfg.PKSelect)
+	}
+	if cfg.Verify {
+		log.Printf("Verify: pk lookups compare patient_id, names, date of birth and checksum with the generated record")
+		r.SetMetadata("verify", true)
+	}
+	if cfg.Breaker.Enabled() {
+		b := cfg.Breaker
+		log.Printf("Circuit breaker: open for %.1fs after %d failed or %d slow (>%gms) consecutive batches (0 = trigger off)",
+			b.PauseSec, b.Failures, b.SlowBatches, b.SlowSec*1000)
+		r.SetMetadata("breaker_failures", b.Failures)
+		r.SetMetadata("breaker_slow_ms", b.SlowSec*1000)
+		r.SetMetadata("breaker_slow_batches", b.SlowBatches)
+		r.SetMetadata("breaker_pause_sec", b.PauseSec)
+	}
 
 	var err error
 	r.backend, err = r.WorkerCtx.Setup(workers, cfg.TargetRPS, cfg.QueriesPerRecord)
 	if err != nil {
-		log.Fatalf("Setup: %v", err)
+		r.writeFatalRepro("setup", err)
+		return fmt.Errorf("setup: %w", err)
 	}
+	r.started = true
 	defer r.WorkerCtx.Teardown()
+	if dr, ok := r.WorkerCtx.(DurabilityReporter); ok {
+		level := cfg.Durability
+		if level == DurabilityDefault {
+			level = "default"
+		}
+		r.SetMetadata("durability", level)
+		r.SetMetadata("durability_setting", dr.DurabilitySetting())
+		log.Printf("Durability %s: %s", level, dr.DurabilitySetting())
+	}
+	if cfg.SnapshotRestore != "" {
+		r.runSnapshot("restore", cfg.SnapshotRestore)
+		r.SetMetadata("snapshot_restore", cfg.SnapshotRestore)
+	}
+	if cfg.PreloadRows > 0 {
+		maxBefore, _ := r.WorkerCtx.GetMaxPatientCounter()
+		r.runPreload(ctx, maxBefore+1)
+		r.SetMetadata("preload_rows", cfg.PreloadRows)
+	}
+	if cfg.SnapshotRestore != "" || cfg.PreloadRows > 0 {
+		// Restoring and preloading can take minutes; start the run clock and its deadline after them.
+		r.cancelRun()
+		r.runStart = time.Now()
+		r.runCtx, r.cancelRun = context.WithTimeout(ctx, time.Duration(cfg.DurationSec*float64(time.Second)))
+		defer r.cancelRun()
+		r.cpuBefore = processCPUSeconds()
+	}
+
+	if cfg.FailoverWatch || cfg.FailoverHook != "" {
+		r.startFailover()
+	}
+	if cfg.Reconcile {
+		r.startReconcile()
+	}
+	r.startShards()
+	if cfg.Cost.Enabled() {
+		r.cost = cfg.Cost
+		r.startCost()
+	}
+
+	if cfg.VerifyGoodput {
+		r.startGoodput()
+	}
 
 	maxCounter, _ := r.WorkerCtx.GetMaxPatientCounter()
-	r.patientStart = max(0, maxCounter+1)
-	log.Printf("Producers using batch-index-derived patient ordinals starting at %d (max in DB: %d)", r.patientStart, maxCounter)
+	if cfg.PatientCounter == "" {
+		cfg.PatientCounter = PatientCounterMax
+	}
+	r.patients, err = newPatientAllocator(cfg, r.WorkerCtx, maxCounter)
+	if err != nil {
+		return fmt.Errorf("patient counter: %w", err)
+	}
+	log.Printf("Producers using batch-index-derived patient ordinals, %s (max in DB: %d)", r.patients.Describe(), maxCounter)
+	r.SetMetadata("patient_counter", cfg.PatientCounter)
+	if cfg.PatientCounter == PatientCounterStatic {
+		r.SetMetadata("runner_id", cfg.RunnerID)
+		r.SetMetadata("runner_count", cfg.RunnerCount)
+	}
+
+	if cfg.Tracing.Endpoint != "" {
+		shutdown, err := startTracing(cfg.Tracing, cfg.Database)
+		if err != nil {
+			return fmt.Errorf("OpenTelemetry: %w", err)
+		}
+		defer shutdown()
+		r.SetMetadata("otel_endpoint", cfg.Tracing.Endpoint)
+		r.SetMetadata("otel_sample_rate", cfg.Tracing.SampleRate)
+	}
+
+	if cfg.Repro.Dir != "" {
+		r.startRepro()
+		defer stopRepro()
+	}
+
+	r.transforms, err = NewPayloadTransforms(cfg.PayloadTransforms)
+	if err != nil {
+		return fmt.Errorf("payload transforms: %w", err)
+	}
 
 	r.progressReporter = NewReporter(progressInterval)
+	r.progressReporter.NoQueries = !runQueryWorkers
+	if cfg.OutputCSV != "" {
+		if err := r.progressReporter.WriteCSV(cfg.OutputCSV); err != nil {
+			return fmt.Errorf("output CSV: %w", err)
+		}
+		log.Printf("Appending interval stats to %s every %s", cfg.OutputCSV, progressInterval)
+	}
 	go r.progressReporter.Run(r.doneCh, r.resultCh)
 
-	router := NewRouter(r.producerQueue, r.workerQueues, rateLimiter)
-	go router.Run(r.runCtx)
+	for _, t := range r.transforms {
+		r.Pipeline.Use(t)
+	}
+	if len(r.transforms) > 0 {
+		r.SetMetadata("payload_transforms", cfg.PayloadTransforms)
+		log.Printf("Payload transforms on SOURCE before insert: %s (queries fetch and decode SOURCE where supported)", cfg.PayloadTransforms)
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r.SetMetadata("seed", seed)
+	if cfg.Mode != "" && cfg.Mode != ModeInsertQuery {
+		r.SetMetadata("mode", cfg.Mode)
+	}
+	var rowsLeft *atomic.Int64
+	if cfg.TotalRows > 0 {
+		r.rowsLeft.Store(int64(cfg.TotalRows))
+		rowsLeft = &r.rowsLeft
+		log.Printf("Row target: stopping after %d rows", cfg.TotalRows)
+		r.SetMetadata("total_rows", cfg.TotalRows)
+	}
+	duplicateLag := int(cfg.DuplicateLagSec * float64(cfg.TargetRPS))
+	if duplicateLag > 0 {
+		log.Printf("Duplicate lag: duplicates re-send originals from about %gs (%d rows at %d rows/sec) earlier", cfg.DuplicateLagSec, duplicateLag, cfg.TargetRPS)
+		r.SetMetadata("duplicate_lag_sec", cfg.DuplicateLagSec)
+	}
+	if (mixed || queryOnly) && cfg.KeyDist.Kind != "" {
+		r.SetMetadata("key_dist", cfg.KeyDist.String())
+	}
+	router := NewRouter(r.producerQueue, r.workerQueues, r.rateLimiter, r.Pipeline)
+	var readWg sync.WaitGroup
+	if mixed {
+		r.startReadKeys(maxCounter, seed)
+		log.Printf("Mixed workload: %.0f reads/sec against loaded MRNs (%s) alongside %d writes/sec (read ratio %g)",
+			readRate(cfg.TargetRPS, cfg.ReadRatio), cfg.KeyDist, cfg.TargetRPS, cfg.ReadRatio)
+		r.SetMetadata("workload", cfg.Workload)
+		r.SetMetadata("read_ratio", cfg.ReadRatio)
+		readWg.Add(1)
+		go func() {
+			defer readWg.Done()
+			r.runReads(r.runCtx, func(target int) float64 { return readRate(target, cfg.ReadRatio) })
+		}()
+	}
+	if queryOnly {
+		keys := r.startReadKeys(maxCounter, seed)
+		if keys == 0 {
+			close(r.doneCh)
+			return fmt.Errorf("query-only: no generated patients in %s; load the table first", Table())
+		}
+		log.Printf("Query-only: %d queries/sec against %d MRNs already loaded (%s, no inserts)", cfg.TargetRPS, keys, cfg.KeyDist)
+		readWg.Add(1)
+		go func() {
+			defer readWg.Done()
+			r.runReads(r.runCtx, func(target int) float64 { return float64(target) })
+		}()
+	}
+	if cfg.Arrival != "" && cfg.Arrival != ArrivalFixed {
+		router.arrivals = newArrivals(cfg.Arrival, seed)
+		r.SetMetadata("arrival", cfg.Arrival)
+	}
+	if cfg.DutyCycle.Enabled() {
+		r.dutyGate = newDutyGate()
+		r.duty = &DutyCycleReport{OnSec: cfg.DutyCycle.OnSec, IdleSec: cfg.DutyCycle.IdleSec}
+		router.gate = r.dutyGate
+		r.SetMetadata("duty_on_sec", cfg.DutyCycle.OnSec)
+		r.SetMetadata("duty_idle_sec", cfg.DutyCycle.IdleSec)
+	}
+	if cfg.ControlAddr != "" {
+		if r.dutyGate == nil {
+			r.dutyGate = newDutyGate()
+			router.gate = r.dutyGate
+		}
+		if err := r.serveControl(r.runCtx, cfg.ControlAddr); err != nil {
+			close(r.doneCh)
+			return fmt.Errorf("control API: %w", err)
+		}
+	}
+	if cfg.Ramp.Enabled() {
+		deadline, _ := r.runCtx.Deadline()
+		router.ramp = &ramp{opts: cfg.Ramp, start: r.runStart, end: deadline}
+		log.Printf("Ramping the target rate 0 → %d rows/sec over %gs and back to 0 over the last %gs", cfg.TargetRPS, cfg.Ramp.UpSec, cfg.Ramp.DownSec)
+		r.SetMetadata("ramp_up_sec", cfg.Ramp.UpSec)
+		r.SetMetadata("ramp_down_sec", cfg.Ramp.DownSec)
+	}
+	if cfg.Pattern.Shape != "" {
+		router.pattern = &loadPattern{LoadPattern: cfg.Pattern, start: r.runStart}
+		log.Printf("Load pattern %s: target rate between %.0f and %d rows/sec over %gs periods", cfg.Pattern, cfg.Pattern.Min*float64(cfg.TargetRPS), cfg.TargetRPS, cfg.Pattern.PeriodSec)
+		r.SetMetadata("load_pattern", cfg.Pattern.String())
+	}
+	if cfg.Burst.Enabled() {
+		router.burstRPS = cfg.Burst.RPS
+		r.SetMetadata("burst_rps", cfg.Burst.RPS)
+		r.SetMetadata("burst_duration_sec", cfg.Burst.DurationSec)
+		r.SetMetadata("burst_every_sec", cfg.Burst.EverySec)
+	}
+	startAttainment(time.Now())
+	r.Pipeline.Start(StagePace, 1, func(int) { router.Run(r.runCtx) })
 
-	var insertExitWg sync.WaitGroup
-	insertExitWg.Add(workers)
 	r.insertWorkers = make([]*InsertWorker, workers)
 	for i := 0; i < workers; i++ {
-		r.insertWorkers[i] = NewInsertWorker(i, r.backend, r.workerQueues[i], r.queryQueue, cfg.QueriesPerRecord, &insertExitWg)
-		go r.insertWorkers[i].Run()
+		r.insertWorkers[i] = NewInsertWorker(i, r.backend, r.workerQueues[i], r.queryQueue, insertQueries, r.Pipeline)
+		r.insertWorkers[i].breaker = newBreaker(cfg.Breaker)
 	}
+	inlineStages := append([]string{StageBatch, StageEnqueue}, r.Pipeline.rowStageNames()...)
+	insertStage := r.Pipeline.Start(StageInsert, workers, func(i int) { r.insertWorkers[i].Run() }, inlineStages...)
 
-	var queryWorkersWg sync.WaitGroup
-	runQueryWorkers := cfg.QueriesPerRecord > 0
+	var queryStage *StageGroup
+	queryOpts := cfg.queryOptions(r.live, r.Pipeline)
+	queryOpts.Payload = r.transforms
 	if runQueryWorkers {
-		for i := 0; i < workers; i++ {
-			queryWorkersWg.Add(1)
-			workerIndex := i
-			go func() {
-				defer queryWorkersWg.Done()
-				r.WorkerCtx.RunQueryWorker(workerIndex, r.queryQueue, cfg.QueriesPerRecord, cfg.QueryDelaySec, cfg.IgnoreSelectErrors)
-			}()
+		queryStage = r.Pipeline.Start(StageQuery, workers, func(i int) {
+			r.WorkerCtx.RunQueryWorker(i, r.queryQueue, queryOpts)
+		})
+	}
+
+	if cfg.LiveConfigPath != "" {
+		go r.watchLiveConfig(r.runCtx, cfg.LiveConfigPath)
+	}
+	if cfg.AnnotationsPath != "" {
+		go r.watchAnnotations(r.runCtx, cfg.AnnotationsPath)
+	}
+
+	var sideWg sync.WaitGroup // retention step and conflict writers, which run alongside the load
+	if !queryOnly && !closed {
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runAttainment(r.runCtx)
+		}()
+	}
+	if cfg.RetentionAtSec > 0 {
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runRetention(r.runCtx)
+		}()
+	}
+
+	if cfg.ConflictWriters > 0 {
+		if cfg.ConflictIsolation == "" {
+			cfg.ConflictIsolation = IsolationReadCommitted
 		}
+		r.SetMetadata("conflict_isolation", cfg.ConflictIsolation)
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runConflicts(r.runCtx)
+		}()
+	}
+
+	if cfg.Guardrails.Enabled() {
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runGuardrails(r.runCtx)
+		}()
+	}
+
+	if _, ok := r.WorkerCtx.(PartsReporter); ok {
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runHintSampler(r.runCtx)
+		}()
+	}
+
+	if r.duty != nil {
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runDutyCycle(r.runCtx)
+		}()
+	}
+
+	if r.failover != nil {
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runFailover(r.runCtx)
+		}()
+	}
+
+	if len(cfg.LoadSteps) > 0 {
+		log.Printf("Running %d load steps, starting at %d rows/sec for %gs", len(cfg.LoadSteps), cfg.LoadSteps[0].RPS, cfg.LoadSteps[0].DurationSec)
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runLoadSteps(r.runCtx)
+		}()
+	}
+
+	if cfg.Burst.Enabled() {
+		log.Printf("Bursting +%d rows/sec for %gs every %gs", cfg.Burst.RPS, cfg.Burst.DurationSec, cfg.Burst.EverySec)
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runBursts(r.runCtx)
+		}()
+	}
+
+	if cfg.FindMax.Enabled {
+		log.Printf("Searching for the max rate with insert p99 <= %g ms, starting at %d rows/sec, %gs per trial (capped at %gs)",
+			cfg.FindMax.P99SLOMs, cfg.TargetRPS, cfg.FindMax.StepSec, cfg.DurationSec)
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runFindMax(r.runCtx)
+		}()
+	}
+
+	if cfg.Push.URL != "" {
+		r.pusher = newMetricsPusher(cfg.Push, cfg.Database)
+		log.Printf("Pushing metrics every %s to %s (%s)", progressInterval, cfg.Push.URL, r.pusher.opts.Format)
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runPush(r.runCtx)
+		}()
+	}
+
+	if cfg.ServerMetricsSec > 0 {
+		sideWg.Add(1)
+		go func() {
+			defer sideWg.Done()
+			r.runServerMetrics(r.runCtx)
+		}()
 	}
 
 	r.triggers = make([]chan struct{}, producerThreads)
@@ -200,45 +854,108 @@ func (r *LoadRunner) Run(ctx context.Context) {
 	r.triggers[0] <- struct{}{}
 
 	r.producers = make([]*Producer, producerThreads)
-	var producerWg sync.WaitGroup
 	for i := 0; i < producerThreads; i++ {
 		r.producers[i] = NewProducer(
 			i,
 			cfg.BatchSize,
-			r.patientStart,
+			r.patients,
 			&r.nextBatchIndex,
+			rowsLeft,
 			cfg.DuplicateRatio,
+			duplicateLag,
+			seed,
 			r.producerQueue,
 			r.triggers[i],
 			r.triggers[(i+1)%producerThreads],
+			r.Pipeline,
 		)
-		producerWg.Add(1)
-		go func(p *Producer) {
-			defer producerWg.Done()
-			p.Run(r.runCtx)
-		}(r.producers[i])
+	}
+	r.statsStart = r.runStart
+	warmupExcluded.Store(cfg.WarmupSec > 0)
+	enterPhase(PhaseWarmup)
+	warmupTimer := time.AfterFunc(r.warmupLength(), r.endWarmup)
+	var generateStage *StageGroup
+	if !queryOnly {
+		generateStage = r.Pipeline.Start(StageGenerate, producerThreads, func(i int) {
+			if err := r.producers[i].Run(r.runCtx); err != nil {
+				r.abort(err)
+			}
+		})
 	}
 
-	producerWg.Wait()
+	// Drain in flow order: generation stops at the deadline, the router closes the worker queues once the
+	// producer queue is drained, and query workers stop on one nil job each after the last insert.
+	if queryOnly {
+		<-r.runCtx.Done() // reads run until the deadline
+	} else {
+		generateStage.Wait()
+	}
+	warmupTimer.Stop()
+	enterPhase(PhaseDrain)
 	close(r.producerQueue)
-	insertExitWg.Wait()
+	insertStage.Wait()
+	r.cancelRun() // the row target can end the inserts before the deadline; stop reads and side goroutines too
 
+	readWg.Wait()
 	if runQueryWorkers {
 		for i := 0; i < workers; i++ {
 			r.queryQueue <- nil
 		}
-		queryWorkersWg.Wait()
+		queryStage.Wait()
 	}
+	sideWg.Wait()
 	close(r.doneCh)
 
 	snapshot := <-r.resultCh
-	r.logSummary(snapshot)
+	r.runEnd = time.Now()
+	if r.pusher != nil {
+		r.finishPush()
+	}
+	if r.failover != nil {
+		r.reconcileFailover(snapshot)
+	}
+	r.finishReconcile(snapshot)
+	if cfg.DedupWatch {
+		r.runDedupWatch(ctx)
+	}
+	r.finishShards()
+	r.goodput = r.finishGoodput(snapshot)
+	r.hints = r.bottleneckHints(snapshot, r.runEnd.Sub(r.runStart).Seconds())
+	r.summary = buildSummary(r.runStart, r.runEnd, loadGoodputIntervals())
+	if cfg.Cost.Enabled() {
+		r.costReport = r.finishCost(snapshot)
+	}
+	if cfg.SnapshotSave != "" {
+		r.runSnapshot("save", cfg.SnapshotSave)
+	}
+	r.logSummary(r.sinceWarmup(snapshot))
+	return r.runErr
+}
+
+// abort stops the run because of err, recording it in the timeline; the run drains and reports as if the duration
+// had passed, and Run then returns err. Only the first failure is kept.
+func (r *LoadRunner) abort(err error) {
+	r.abortOnce.Do(func() {
+		r.runErr = err
+		r.events.add(r.runStart, "error", err.Error()+"; stopping the run")
+		r.cancelRun()
+	})
+}
+
+// Started reports whether Run got past the backend's Setup. From there on the run (restore, preload, load) moves
+// the process-wide counters, whether or not it then fails.
+func (r *LoadRunner) Started() bool {
+	return r.started
+}
+
+// Results returns the finished run's results, or nil before Run has completed.
+func (r *LoadRunner) Results() *Results {
+	return r.results
 }
 
 func (r *LoadRunner) logSummary(snapshot Snapshot) {
 	cfg := &r.Config
-	runEnd := time.Now()
-	elapsed := runEnd.Sub(r.runStart).Seconds()
+	elapsed := r.runEnd.Sub(r.statsStart).Seconds()
 	totalInserted := int(snapshot.Inserted.Total)
 	originals := int(snapshot.Inserted.Originals)
 	duplicates := int(snapshot.Inserted.Duplicates)
@@ -261,18 +978,43 @@ func (r *LoadRunner) logSummary(snapshot Snapshot) {
 		avgQueryMs = totalQueryLatency / float64(queriesFinal) * 1000
 	}
 
-	log.Printf("Run finished: %d rows inserted (%d original, %d duplicate) in %.2fs (%.1f rows/sec, target %d)",
-		totalInserted, originals, duplicates, elapsed, actualRPS, cfg.TargetRPS)
+	log.Printf("Run finished: %d rows inserted (%d original, %d duplicate) in %.2fs (%.1f rows/sec, %s)",
+		totalInserted, originals, duplicates, elapsed, actualRPS, cfg.targetLabel())
+	if cfg.TotalRows > 0 && int(totalInserted) < cfg.TotalRows {
+		log.Printf("Row target not reached: %d of %d rows inserted before the run ended", totalInserted, cfg.TotalRows)
+	}
 	log.Printf("Database: %s", cfg.Database)
+	if cfg.WarmupSec > 0 {
+		log.Printf("Warmup: the first %gs of load are excluded from these stats (interval logs tagged warmup)", cfg.WarmupSec)
+	}
 	log.Printf("Duration: %.2fs | Workers: %d | Rows inserted: %d (%d original, %d duplicate) | Insert statements: %d",
 		elapsed, cfg.Workers, totalInserted, originals, duplicates, insertStatements)
+	logUpsertReconciliation(snapshot.Inserted)
 	postgres1 := int(snapshot.Inserted.Postgres1)
 	postgres2 := int(snapshot.Inserted.Postgres2)
 	log.Printf("postgres1: %d | postgres2: %d", postgres1, postgres2)
-	log.Printf("Actual insert rate: %.1f rows/sec (target %d)", actualRPS, cfg.TargetRPS)
+	log.Printf("Actual insert rate: %.1f rows/sec (%s)", actualRPS, cfg.targetLabel())
+	if len(r.metadata) > 0 {
+		log.Printf("Runtime: %s", formatMetadata(r.metadata))
+	}
 	if totalInserted > 0 {
 		log.Printf("Insert latency: avg %.2f ms/row", avgInsertMs)
 	}
+	if p := snapshot.Inserted.Latency; p != nil {
+		log.Printf("Insert batch latency (%d batches): %s", p.Count, p)
+	}
+	if p := snapshot.Inserted.EndToEnd; p != nil {
+		log.Printf("Insert end-to-end latency (%d records, intended start → commit): %s", p.Count, p)
+	}
+	batches := loadBatchReport(cfg.BatchSize)
+	attained := loadAttainment()
+	client := loadClient()
+	logBatches(batches)
+	insertPhases := loadInsertPhases()
+	logInsertPhases(insertPhases)
+	if b := snapshot.Inserted.ServerTimedBatches; b > 0 {
+		log.Printf("Insert server time: avg %.2f ms over %d batches that reported it", snapshot.Inserted.ServerSec/b*1000, int(b))
+	}
 	if queriesFinal > 0 {
 		actualQueryRPS := 0.0
 		if elapsed > 0 {
@@ -280,6 +1022,195 @@ func (r *LoadRunner) logSummary(snapshot Snapshot) {
 		}
 		log.Printf("Actual query rate: %.1f queries/sec", actualQueryRPS)
 		log.Printf("Queries: %d executed, %d failed | Query latency: avg %.2f ms per SELECT", queriesFinal, queriesFailed, avgQueryMs)
+		if p := snapshot.Queries.Latency; p != nil {
+			log.Printf("Query latency: %s", p)
+		}
+	}
+	if cfg.Workload == WorkloadMixed {
+		logMixed(cfg.ReadRatio, snapshot, elapsed)
+	}
+	logGoodput(r.goodput)
+	logDutyCycle(r.duty)
+	logResultSetCurve(snapshot.ResultSets)
+	logPKFetch(cfg.PKSelect)
+	verify := loadVerify()
+	logVerify(verify)
+	checksum := loadChecksum()
+	logChecksum(checksum)
+	reconnects := loadReconnects()
+	logReconnects(reconnects)
+	breaker := loadBreaker(cfg.Breaker)
+	logBreaker(breaker)
+	logSessions(snapshot.Sessions)
+	logErrors(snapshot.Errors)
+	logWarnings(snapshot.Warnings)
+	logAttribution(snapshot.Attribution)
+	logDualWrite(snapshot.DualWrite)
+	logVisibility(snapshot.Visibility)
+	logServerMetrics(r.serverMetrics)
+	logAttainment(attained)
+	logLoadSteps(r.loadSteps)
+	logFindMax(r.findMax)
+	burst := r.loadBurst()
+	logBurst(burst)
+	logClient(client)
+	logRetention(r.retention)
+	logSnapshots(r.snapshots)
+	logPreload(r.preload)
+	logConflicts(r.conflicts)
+	logFailover(r.failover)
+	logReconcile(r.reconcile)
+	logDedup(r.dedup)
+	logShards(r.shards)
+	logStages(r.Pipeline.Stats(), elapsed)
+	logTransforms(r.transforms.Stats())
+	logCost(r.costReport)
+	logGuardrail(r.guardrail)
+	logHints(r.hints)
+	logSummaryReport(r.summary)
+	events := r.events.list()
+	if len(events) > 0 {
+		log.Printf("Timeline events:")
+		for _, e := range events {
+			log.Printf("  %8.1fs [%s] %s", e.AtSec, e.Kind, e.Detail)
+		}
+	}
+
+	res := &Results{
+		Database:    cfg.Database,
+		StartedAt:   r.runStart,
+		ElapsedSec:  elapsed,
+		WarmupSec:   cfg.WarmupSec,
+		TargetRPS:   cfg.TargetRPS,
+		Concurrency: cfg.closedConcurrency(),
+		ActualRPS:   actualRPS,
+		Metadata:    r.metadata,
+		Inserted:    snapshot.Inserted,
+		Batches:     batches,
+		Queries:     snapshot.Queries,
+		Goodput:     r.goodput,
+		DutyCycle:   r.duty,
+		ResultSets:  snapshot.ResultSets,
+		Sessions:    snapshot.Sessions,
+		Verify:      verify,
+		Checksum:    checksum,
+		Reconnects:  reconnects,
+		Breaker:     breaker,
+		Errors:      snapshot.Errors,
+		Warnings:    snapshot.Warnings,
+		Attribution: snapshot.Attribution,
+		DualWrite:   snapshot.DualWrite,
+		Visibility:  snapshot.Visibility,
+		Server:      r.serverMetrics,
+		Attainment:  attained,
+		LoadSteps:   r.loadSteps,
+		FindMax:     r.findMax,
+		Burst:       burst,
+		Client:      client,
+		InsertPhase: insertPhases,
+		Retention:   r.retention,
+		Snapshots:   r.snapshots,
+		Preload:     r.preload,
+		Conflicts:   r.conflicts,
+		Failover:    r.failover,
+		Reconcile:   r.reconcile,
+		Dedup:       r.dedup,
+		Shards:      r.shards,
+		Stages:      r.Pipeline.Stats(),
+		Transforms:  r.transforms.Stats(),
+		Cost:        r.costReport,
+		Guardrail:   r.guardrail,
+		Hints:       r.hints,
+		Summary:     r.summary,
+		Events:      events,
+	}
+	r.results = res
+	if cfg.Baseline.Path != "" {
+		r.baseline = checkBaseline(cfg.Baseline, res)
+		res.Baseline = r.baseline
+		logBaseline(r.baseline)
+	}
+	if cfg.ResultsJSON != "" {
+		if err := WriteResults(cfg.ResultsJSON, res); err != nil {
+			log.Printf("Write results %s: %v", cfg.ResultsJSON, err)
+		} else {
+			log.Printf("Results written to %s", cfg.ResultsJSON)
+		}
+	}
+	if cfg.ReportMarkdown != "" {
+		if err := WriteMarkdownReport(cfg.ReportMarkdown, res); err != nil {
+			log.Printf("Write report %s: %v", cfg.ReportMarkdown, err)
+		} else {
+			log.Printf("Markdown report written to %s", cfg.ReportMarkdown)
+		}
+	}
+}
+
+// logUpsertReconciliation compares the database-reported upsert outcome with the generator's intent
+// (originals should insert, duplicates should update) and logs any drift.
+func logUpsertReconciliation(ins InsertedStats) {
+	if ins.DBInserted == nil || ins.DBUpdated == nil {
+		return
+	}
+	dbInserted, dbUpdated := int(*ins.DBInserted), int(*ins.DBUpdated)
+	log.Printf("Database outcome: %d inserted, %d updated on conflict (generator intent: %d original, %d duplicate)",
+		dbInserted, dbUpdated, int(ins.Originals), int(ins.Duplicates))
+	if drift := dbInserted - int(ins.Originals); drift != 0 {
+		log.Printf("Upsert drift: %+d rows inserted vs originals (%+d updated vs duplicates); duplicates that overtook their original insert, or originals that hit existing MRNs",
+			drift, dbUpdated-int(ins.Duplicates))
+	}
+}
+
+// logSessions logs per-step latency and average session wall time (only when session queries ran).
+func logSessions(st *SessionStats) {
+	if st == nil {
+		return
+	}
+	log.Printf("Sessions: %d completed | avg wall %.2f ms (think time included)", st.Count, st.TotalWallSec/float64(st.Count)*1000)
+	for _, step := range st.Steps {
+		avgMs := 0.0
+		if step.Count > 0 {
+			avgMs = step.TotalLatencySec / float64(step.Count) * 1000
+		}
+		log.Printf("  %-10s %10d queries %8d failed | avg %.2f ms", step.Name, step.Count, step.Failed, avgMs)
+	}
+}
+
+// logErrors logs error counts per operation and native code (only when errors occurred).
+func logErrors(errs []ErrorCount) {
+	if len(errs) == 0 {
+		return
+	}
+	log.Printf("Errors by code:")
+	log.Printf("  %-7s %-32s %10s  %s", "op", "code", "count", "first message")
+	for _, e := range errs {
+		sample := e.Sample
+		if len(sample) > 120 {
+			sample = sample[:120] + "..."
+		}
+		log.Printf("  %-7s %-32s %10d  %s", e.Op, e.Code, e.Count, sample)
+	}
+}
+
+// logResultSetCurve logs latency vs rows-returned per requested LIMIT (only when result-set queries ran).
+func logResultSetCurve(buckets []ResultSetBucket) {
+	if len(buckets) == 0 {
+		return
+	}
+	log.Printf("Result-set queries (latency vs rows returned):")
+	log.Printf("  %8s %10s %10s %12s %10s %12s", "limit", "queries", "avg_rows", "avg_kb", "avg_ms", "ms_per_row")
+	for _, b := range buckets {
+		if b.Count == 0 {
+			continue
+		}
+		avgRows := float64(b.Rows) / float64(b.Count)
+		avgKB := float64(b.Bytes) / float64(b.Count) / 1024
+		avgMs := b.TotalLatencySec / float64(b.Count) * 1000
+		msPerRow := 0.0
+		if b.Rows > 0 {
+			msPerRow = b.TotalLatencySec / float64(b.Rows) * 1000
+		}
+		log.Printf("  %8d %10d %10.1f %12.1f %10.2f %12.3f", b.Limit, b.Count, avgRows, avgKB, avgMs, msPerRow)
 	}
 }
 
@@ -0,0 +1,119 @@
+package benchmarkgo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenarios run ordered phases back to back against the same table, e.g. a bulk load, then a mixed read/write
+// period, then query-only. Each phase is a separate run (stats are process-wide) whose settings override the
+// command line, and the phases' Results are compared side by side at the end.
+
+// ScenarioPhase is one phase; zero fields keep the command-line value.
+type ScenarioPhase struct {
+	Name             string            `yaml:"name"`     // label in logs and results file names (default phase-N)
+	Duration         string            `yaml:"duration"` // Go duration or plain seconds, e.g. 10m (required)
+	RowsPerSecond    int               `yaml:"rows_per_second"`
+	BatchSize        int               `yaml:"batch_size"`
+	Workers          int               `yaml:"workers"`
+	Mode             string            `yaml:"mode"`
+	Concurrency      int               `yaml:"concurrency"`
+	Workload         string            `yaml:"workload"`
+	ReadRatio        float64           `yaml:"read_ratio"`
+	QueriesPerRecord *int              `yaml:"queries_per_record"` // a pointer so 0 can turn queries off
+	QueryType        string            `yaml:"query_type"`
+	KeyDist          string            `yaml:"key_dist"`
+	Flags            map[string]string `yaml:"flags"` // any other flag by name, e.g. {duplicate-ratio: "0.1"}
+}
+
+// Scenario is an ordered list of phases.
+type Scenario struct {
+	Name   string          `yaml:"name"`
+	Phases []ScenarioPhase `yaml:"phases"`
+}
+
+// LoadScenario reads and validates a YAML scenario file; unknown keys are errors so typos do not go unnoticed.
+func LoadScenario(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	var sc Scenario
+	if err := dec.Decode(&sc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(sc.Phases) == 0 {
+		return nil, fmt.Errorf("%s: no phases", path)
+	}
+	var names []string
+	for i := range sc.Phases {
+		p := &sc.Phases[i]
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("phase-%d", i+1)
+		}
+		if strings.ContainsAny(p.Name, "/\\ ") || slices.Contains(names, p.Name) {
+			return nil, fmt.Errorf("%s: phase name %q must be unique and contain no spaces or slashes", path, p.Name)
+		}
+		names = append(names, p.Name)
+		if sec, err := parseSeconds(p.Duration); err != nil || sec <= 0 {
+			return nil, fmt.Errorf("%s: phase %s: duration must be > 0 (e.g. 10m or 600), got %q", path, p.Name, p.Duration)
+		}
+	}
+	return &sc, nil
+}
+
+// Names returns the phase names in order.
+func (sc *Scenario) Names() []string {
+	names := make([]string, len(sc.Phases))
+	for i, p := range sc.Phases {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Args renders the phase's settings as command-line flags, to be appended after the base arguments.
+func (p ScenarioPhase) Args() []string {
+	sec, _ := parseSeconds(p.Duration)
+	args := []string{"--duration=" + strconv.FormatFloat(sec, 'f', -1, 64)}
+	addInt := func(name string, v int) {
+		if v != 0 {
+			args = append(args, fmt.Sprintf("--%s=%d", name, v))
+		}
+	}
+	addString := func(name, v string) {
+		if v != "" {
+			args = append(args, "--"+name+"="+v)
+		}
+	}
+	addInt("rows-per-second", p.RowsPerSecond)
+	addInt("batch-size", p.BatchSize)
+	addInt("workers", p.Workers)
+	addString("mode", p.Mode)
+	addInt("concurrency", p.Concurrency)
+	addString("workload", p.Workload)
+	if p.ReadRatio != 0 {
+		args = append(args, "--read-ratio="+strconv.FormatFloat(p.ReadRatio, 'f', -1, 64))
+	}
+	if p.QueriesPerRecord != nil {
+		args = append(args, fmt.Sprintf("--queries-per-record=%d", *p.QueriesPerRecord))
+	}
+	addString("query-type", p.QueryType)
+	addString("key-dist", p.KeyDist)
+	keys := make([]string, 0, len(p.Flags))
+	for k := range p.Flags {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		args = append(args, "--"+strings.TrimLeft(k, "-")+"="+p.Flags[k])
+	}
+	return args
+}
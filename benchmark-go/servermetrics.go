@@ -0,0 +1,88 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+)
+
+// ServerMetricsReporter is implemented by WorkerCtx backends that expose internal server statistics
+// (Postgres pg_stat_database/pg_stat_bgwriter, ClickHouse system.metrics/asynchronous_metrics, merges, parts).
+// Values are the server's own numbers keyed by "source.name": counters stay cumulative, gauges are point-in-time.
+type ServerMetricsReporter interface {
+	ServerMetrics(ctx context.Context) (map[string]float64, error)
+}
+
+// ServerMetricsSample is one snapshot of the backend's statistics, AtSec seconds into the run.
+type ServerMetricsSample struct {
+	AtSec  float64            `json:"at_sec"`
+	Values map[string]float64 `json:"values"`
+}
+
+// ServerMetricsReport is the series of server-side samples taken alongside the load, for correlating client
+// throughput with server behavior such as checkpoints and merges.
+type ServerMetricsReport struct {
+	IntervalSec float64               `json:"interval_sec"`
+	Samples     []ServerMetricsSample `json:"samples"`
+	Failed      int                   `json:"failed,omitempty"` // samples that could not be taken
+}
+
+// runServerMetrics samples the backend every interval from the start of the load, plus once when it ends.
+func (r *LoadRunner) runServerMetrics(ctx context.Context) {
+	smr, ok := r.WorkerCtx.(ServerMetricsReporter)
+	if !ok {
+		log.Printf("Server metrics: %s backend does not expose server statistics, --server-metrics-interval ignored", r.Config.Database)
+		return
+	}
+	interval := time.Duration(r.Config.ServerMetricsSec * float64(time.Second))
+	rep := &ServerMetricsReport{IntervalSec: r.Config.ServerMetricsSec}
+	sample := func(ctx context.Context) {
+		at := time.Since(r.runStart).Seconds()
+		values, err := smr.ServerMetrics(ctx)
+		if err != nil {
+			rep.Failed++
+			log.Printf("Server metrics: %v", err)
+			return
+		}
+		rep.Samples = append(rep.Samples, ServerMetricsSample{AtSec: at, Values: values})
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	sample(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			finalCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			sample(finalCtx)
+			cancel()
+			r.serverMetrics = rep
+			return
+		case <-ticker.C:
+			sample(ctx)
+		}
+	}
+}
+
+// logServerMetrics logs how the sampled counters moved over the run (last - first sample); the full series
+// is in the results JSON.
+func logServerMetrics(rep *ServerMetricsReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Server metrics: %d samples every %gs (%d failed); full series in the results JSON", len(rep.Samples), rep.IntervalSec, rep.Failed)
+	if len(rep.Samples) < 2 {
+		return
+	}
+	first, last := rep.Samples[0].Values, rep.Samples[len(rep.Samples)-1].Values
+	names := make([]string, 0, len(last))
+	for name := range last {
+		if _, ok := first[name]; ok && last[name] != first[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Printf("  %-48s %16.0f → %16.0f  (%+.0f)", name, first[name], last[name], last[name]-first[name])
+	}
+}
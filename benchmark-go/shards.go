@@ -0,0 +1,93 @@
+package benchmarkgo
+
+import (
+	"context"
+	"log"
+)
+
+// ShardRows is the row count of one shard (Citus shard, Greenplum segment) of the benchmark table.
+type ShardRows struct {
+	Shard string
+	Node  string
+	Rows  int64
+}
+
+// ShardReporter is implemented by WorkerCtx backends that can count rows per shard. ShardRows returns nil
+// when the table is not distributed.
+type ShardReporter interface {
+	ShardRows(ctx context.Context) ([]ShardRows, error)
+}
+
+// ShardInserts is how many rows one shard gained during the run.
+type ShardInserts struct {
+	Shard      string  `json:"shard"`
+	Node       string  `json:"node"`
+	RowsBefore int64   `json:"rows_before"`
+	RowsAfter  int64   `json:"rows_after"`
+	Inserted   int64   `json:"inserted"`
+	Share      float64 `json:"share"` // fraction of all rows inserted during the run
+}
+
+// ShardDistribution is the per-shard insert distribution, with Skew = busiest shard / mean (1.0 = perfectly even).
+type ShardDistribution struct {
+	Shards []ShardInserts `json:"shards"`
+	Skew   float64        `json:"skew"`
+}
+
+// startShards records per-shard row counts before the load (nil when the backend is not distributed).
+func (r *LoadRunner) startShards() {
+	sr, ok := r.WorkerCtx.(ShardReporter)
+	if !ok {
+		return
+	}
+	before, err := sr.ShardRows(context.Background())
+	if err != nil {
+		log.Printf("Shard distribution: %v", err)
+		return
+	}
+	r.shardsBefore = before
+}
+
+// finishShards compares per-shard row counts after the load with those recorded by startShards.
+func (r *LoadRunner) finishShards() {
+	if r.shardsBefore == nil {
+		return
+	}
+	after, err := r.WorkerCtx.(ShardReporter).ShardRows(context.Background())
+	if err != nil {
+		log.Printf("Shard distribution: %v", err)
+		return
+	}
+	before := make(map[string]int64, len(r.shardsBefore))
+	for _, s := range r.shardsBefore {
+		before[s.Shard] = s.Rows
+	}
+	dist := &ShardDistribution{}
+	var total, busiest int64
+	for _, s := range after {
+		in := ShardInserts{Shard: s.Shard, Node: s.Node, RowsBefore: before[s.Shard], RowsAfter: s.Rows}
+		in.Inserted = in.RowsAfter - in.RowsBefore
+		total += in.Inserted
+		busiest = max(busiest, in.Inserted)
+		dist.Shards = append(dist.Shards, in)
+	}
+	if total > 0 {
+		for i := range dist.Shards {
+			dist.Shards[i].Share = float64(dist.Shards[i].Inserted) / float64(total)
+		}
+		dist.Skew = float64(busiest) / (float64(total) / float64(len(dist.Shards)))
+	}
+	r.shards = dist
+}
+
+// logShards logs rows inserted per shard (only for distributed tables).
+func logShards(dist *ShardDistribution) {
+	if dist == nil {
+		return
+	}
+	log.Printf("Shard insert distribution (%d shards, skew %.2f = busiest/mean):", len(dist.Shards), dist.Skew)
+	log.Printf("  %-12s %-28s %12s %12s %7s", "shard", "node", "inserted", "rows_after", "share")
+	for _, s := range dist.Shards {
+		log.Printf("  %-12s %-28s %12d %12d %6.1f%%", s.Shard, s.Node, s.Inserted, s.RowsAfter, s.Share*100)
+	}
+}
@@ -0,0 +1,81 @@
+// Package singlestore runs the benchmark against SingleStore (formerly MemSQL) over the MySQL protocol,
+// with the table created as a columnstore or an in-memory rowstore.
+package singlestore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/mysql"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+)
+
+// Table types for --singlestore-table-type.
+const (
+	TableColumnstore = "columnstore" // disk-backed, SORT KEY on created_at (SingleStore's default table type)
+	TableRowstore    = "rowstore"    // fully in memory; every ~2 MiB payload counts against leaf memory
+)
+
+// ValidateTableType checks a --singlestore-table-type value.
+func ValidateTableType(t string) error {
+	switch t {
+	case TableColumnstore, TableRowstore:
+		return nil
+	}
+	return fmt.Errorf("unknown table type %q (want %s or %s)", t, TableColumnstore, TableRowstore)
+}
+
+// Options selects the SingleStore table layout.
+type Options struct {
+	TableType string
+}
+
+// columnsDDL is the MySQL column list with patient_id as VARCHAR, since SingleStore cannot index TEXT columns.
+func columnsDDL() string {
+	return strings.Replace(mysql.ColumnsDDL(), ", patient_id LONGTEXT", ", patient_id VARCHAR(64)", 1)
+}
+
+// schema returns the DDL for opts. Both layouts shard by medical_record_number so the unique key that
+// ON DUPLICATE KEY UPDATE relies on is enforced within one partition.
+func schema(opts Options) func(table string) []string {
+	return func(table string) []string {
+		if opts.TableType == TableRowstore {
+			return []string{
+				"CREATE ROWSTORE TABLE IF NOT EXISTS " + table + " (" + columnsDDL() +
+					", PRIMARY KEY (medical_record_number), SHARD KEY (medical_record_number), KEY idx_hl7_patient_id (patient_id))",
+			}
+		}
+		return []string{
+			"CREATE TABLE IF NOT EXISTS " + table + " (" + columnsDDL() +
+				", SORT KEY (created_at), SHARD KEY (medical_record_number), UNIQUE KEY (medical_record_number) USING HASH" +
+				", KEY idx_hl7_patient_id (patient_id) USING HASH)",
+		}
+	}
+}
+
+// Context is a sqldb context that creates the database before connecting to it.
+type Context struct {
+	*sqldb.Context
+	conn mysql.Conn
+}
+
+// Setup creates the database if missing, then opens the pool and creates the table.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if err := mysql.CreateDatabase(context.Background(), c.conn); err != nil {
+		return nil, err
+	}
+	return c.Context.Setup(numWorkers, targetRPS, queriesPerRecord)
+}
+
+// NewContext returns a worker context for SingleStore at SINGLESTORE_HOST:SINGLESTORE_PORT
+// (default singlestore:3306, user root, database db_benchmark).
+func NewContext(opts Options) *Context {
+	if opts.TableType == "" {
+		opts.TableType = TableColumnstore
+	}
+	conn := mysql.ConnFromEnv("SINGLESTORE", mysql.Conn{Host: "singlestore", Port: 3306, User: "root", Database: "db_benchmark"})
+	d := mysql.Dialect("singlestore ("+opts.TableType+")", schema(opts))
+	return &Context{Context: &sqldb.Context{Dialect: d, DSN: conn.DSN()}, conn: conn}
+}
@@ -0,0 +1,87 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+)
+
+// SnapshotResult is what a backend reports for one snapshot save or restore.
+type SnapshotResult struct {
+	Method string `json:"method"` // e.g. "CREATE TABLE AS", "REPLACE PARTITION"
+	Rows   int64  `json:"rows"`   // rows in the benchmark table afterwards (-1 if unknown)
+}
+
+// SnapshotBackend is implemented by WorkerCtx backends that can copy the benchmark table's state into a named
+// snapshot and later replace the table's contents with it, so query experiments run against identical data
+// without re-ingesting it.
+type SnapshotBackend interface {
+	SaveSnapshot(ctx context.Context, name string) (SnapshotResult, error)
+	RestoreSnapshot(ctx context.Context, name string) (SnapshotResult, error)
+}
+
+// SnapshotReport is one snapshot step of the run: a restore before the load starts or a save after it ends.
+type SnapshotReport struct {
+	Op     string         `json:"op"` // "restore" or "save"
+	Name   string         `json:"name"`
+	Result SnapshotResult `json:"result"`
+	Sec    float64        `json:"sec"`
+	Error  string         `json:"error,omitempty"`
+}
+
+var snapshotNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// ValidateSnapshotName checks that name can be spliced into a table name.
+func ValidateSnapshotName(name string) error {
+	if name != "" && !snapshotNameRe.MatchString(name) {
+		return fmt.Errorf("snapshot name %q must match %s", name, snapshotNameRe)
+	}
+	return nil
+}
+
+// SnapshotTable returns the (prefixed) table holding snapshot name, e.g. "hl7_messages_snap_bulk".
+func SnapshotTable(name string) string {
+	return Prefixed(baseTable + "_snap_" + name)
+}
+
+// runSnapshot saves or restores snapshot name and records the step for the summary. A failed restore is fatal,
+// since the run would otherwise measure different data than intended.
+func (r *LoadRunner) runSnapshot(op, name string) {
+	sb, ok := r.WorkerCtx.(SnapshotBackend)
+	if !ok {
+		log.Fatalf("Snapshot: %s backend does not support snapshot save/restore", r.Config.Database)
+	}
+	log.Printf("Snapshot: %s %s (%s) ...", op, name, SnapshotTable(name))
+	t0 := time.Now()
+	var res SnapshotResult
+	var err error
+	if op == "restore" {
+		res, err = sb.RestoreSnapshot(context.Background(), name)
+	} else {
+		res, err = sb.SaveSnapshot(context.Background(), name)
+	}
+	rep := SnapshotReport{Op: op, Name: name, Result: res, Sec: time.Since(t0).Seconds()}
+	if err != nil {
+		if op == "restore" {
+			log.Fatalf("Snapshot: restore %s: %v", name, err)
+		}
+		rep.Error = err.Error()
+		log.Printf("Snapshot: save %s: %v", name, err)
+	} else {
+		log.Printf("Snapshot: %s %s via %s in %.2fs (%d rows in table)", op, name, res.Method, rep.Sec, res.Rows)
+	}
+	r.snapshots = append(r.snapshots, rep)
+}
+
+// logSnapshots logs the snapshot steps of the run (only when any ran).
+func logSnapshots(reps []SnapshotReport) {
+	for _, rep := range reps {
+		if rep.Error != "" {
+			log.Printf("Snapshot %s %s failed after %.2fs: %s", rep.Op, rep.Name, rep.Sec, rep.Error)
+			continue
+		}
+		log.Printf("Snapshot %s %s: %s in %.2fs, %d rows", rep.Op, rep.Name, rep.Result.Method, rep.Sec, rep.Result.Rows)
+	}
+}
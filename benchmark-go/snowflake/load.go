@@ -0,0 +1,201 @@
+//go:build snowflake
+
+package snowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+	sf "github.com/snowflakedb/gosnowflake"
+)
+
+// fileFormat is the staged files' format: gzip CSV with quoted fields, where an unquoted empty field is NULL.
+const fileFormat = "TYPE = CSV COMPRESSION = GZIP FIELD_OPTIONALLY_ENCLOSED_BY = '\"' EMPTY_FIELD_AS_NULL = TRUE"
+
+// fileSeq numbers staged files; the run's start time keeps names unique across runs (COPY skips files it has
+// already loaded by name).
+var (
+	fileSeq    atomic.Int64
+	filePrefix = "batch-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+)
+
+// Backend implements benchmarkgo.InsertBackend by staging each batch as one file and copying it into the table.
+type Backend struct {
+	db    *sql.DB
+	loads *loadStats
+}
+
+// GetConn returns the shared *sql.DB; database/sql checks out a pooled connection per statement.
+func (b *Backend) GetConn() interface{} {
+	return b.db
+}
+
+// ReleaseConn is a no-op; database/sql manages its pool.
+func (b *Backend) ReleaseConn(c interface{}) {}
+
+// InsertBatch writes rows as a gzip CSV file, PUTs it to the stage and COPYs it into the table. Bytes is the
+// compressed file size and ServerTime the COPY duration.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	db, ok := conn.(*sql.DB)
+	if !ok || len(rows) == 0 {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // pgbouncer routing hint; unused outside Postgres
+	file, err := EncodeCSV(rows)
+	if err != nil {
+		return benchmarkgo.InsertResult{}, err
+	}
+	name := fmt.Sprintf("%s-%d.csv.gz", filePrefix, fileSeq.Add(1))
+	ctx := context.Background()
+	putStart := time.Now()
+	if err := Put(ctx, db, name, file); err != nil {
+		return benchmarkgo.InsertResult{}, err
+	}
+	putDur := time.Since(putStart)
+	copyStart := time.Now()
+	loaded, err := Copy(ctx, db, name)
+	copyDur := time.Since(copyStart)
+	if err != nil {
+		return benchmarkgo.InsertResult{Rows: loaded}, err
+	}
+	b.loads.add(len(file), putDur, copyDur)
+	return benchmarkgo.InsertResult{Rows: loaded, Statements: 2, Bytes: int64(len(file)), ServerTime: copyDur}, nil
+}
+
+// EncodeCSV renders rows as gzip-compressed CSV in sqldb.Columns order.
+func EncodeCSV(rows []benchmarkgo.RowForDB) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	w := csv.NewWriter(zw)
+	now := time.Now().UTC()
+	record := make([]string, len(sqldb.Columns))
+	for _, r := range rows {
+		values, err := sqldb.RowFromJSON(r.JSONMessage, now)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			switch v := v.(type) {
+			case nil:
+				record[i] = ""
+			case time.Time:
+				record[i] = v.Format("2006-01-02 15:04:05.000")
+			case string:
+				record[i] = v
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Put uploads file to the internal stage as name, streamed from memory (no local temp file).
+func Put(ctx context.Context, db *sql.DB, name string, file []byte) error {
+	ctx = sf.WithFileStream(ctx, bytes.NewReader(file))
+	_, err := db.ExecContext(ctx, "PUT 'file://"+name+"' @"+stageName()+
+		" AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP OVERWRITE = TRUE")
+	return err
+}
+
+// Copy loads the staged file name into the table (aborting on the first bad row) and removes it from the stage.
+// Returns the rows loaded.
+func Copy(ctx context.Context, db *sql.DB, name string) (int, error) {
+	rows, err := db.QueryContext(ctx, "COPY INTO "+benchmarkgo.Table()+" ("+strings.Join(sqldb.Columns, ", ")+")"+
+		" FROM @"+stageName()+" FILES = ('"+name+"') FILE_FORMAT = ("+fileFormat+") ON_ERROR = ABORT_STATEMENT PURGE = TRUE")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	loadedCol := -1
+	for i, c := range cols {
+		if strings.EqualFold(c, "rows_loaded") {
+			loadedCol = i
+		}
+	}
+	var loaded int
+	values := make([]sql.NullString, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return loaded, err
+		}
+		if loadedCol >= 0 {
+			n, _ := strconv.Atoi(values[loadedCol].String)
+			loaded += n
+		}
+	}
+	return loaded, rows.Err()
+}
+
+// loadStats collects per-file PUT and COPY latencies.
+type loadStats struct {
+	mu    sync.Mutex
+	bytes int64
+	put   []time.Duration
+	copy  []time.Duration
+}
+
+func (s *loadStats) add(fileBytes int, putDur, copyDur time.Duration) {
+	s.mu.Lock()
+	s.bytes += int64(fileBytes)
+	s.put = append(s.put, putDur)
+	s.copy = append(s.copy, copyDur)
+	s.mu.Unlock()
+}
+
+// log prints file count, staged size and PUT/COPY latency percentiles.
+func (s *loadStats) log() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.put) == 0 {
+		return
+	}
+	log.Printf("Snowflake file loads: %d files, %.1f MiB staged (avg %.1f KiB/file)",
+		len(s.put), float64(s.bytes)/(1<<20), float64(s.bytes)/float64(len(s.put))/1024)
+	log.Printf("  PUT  %s", latencySummary(s.put))
+	log.Printf("  COPY %s", latencySummary(s.copy))
+}
+
+// latencySummary formats avg, p50, p99 and max of ds in ms (sorts ds).
+func latencySummary(ds []time.Duration) string {
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	var sum time.Duration
+	for _, d := range ds {
+		sum += d
+	}
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	pct := func(p float64) time.Duration { return ds[int(p*float64(len(ds)-1))] }
+	return fmt.Sprintf("avg %.1f ms, p50 %.1f ms, p99 %.1f ms, max %.1f ms",
+		ms(sum/time.Duration(len(ds))), ms(pct(0.5)), ms(pct(0.99)), ms(ds[len(ds)-1]))
+}
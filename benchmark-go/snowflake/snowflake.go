@@ -0,0 +1,85 @@
+//go:build snowflake
+
+// Package snowflake runs the benchmark against Snowflake. Inserts are not multi-row INSERTs (micro-inserts are
+// not how Snowflake is loaded): each batch is written as a gzip CSV file, PUT to an internal stage and loaded
+// with COPY INTO, and per-file PUT/COPY latency is reported at teardown. Queries go through sqldb. Build with
+// -tags snowflake.
+package snowflake
+
+import (
+	"os"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+	sf "github.com/snowflakedb/gosnowflake"
+)
+
+// stageName returns the (prefixed) internal stage batches are PUT to.
+func stageName() string {
+	return benchmarkgo.Prefixed("hl7_messages_stage")
+}
+
+// Dialect is Snowflake's SQL flavour. Tables have no unique keys or indexes and COPY only appends, so duplicates
+// add rows; micro-partition pruning on medical_record_number serves the lookups.
+var Dialect = &sqldb.Dialect{
+	Name:        "snowflake",
+	Driver:      "snowflake",
+	Placeholder: sqldb.QuestionPlaceholder,
+	Schema: func(table string) []string {
+		return []string{
+			"CREATE TABLE IF NOT EXISTS " + table + " (" + sqldb.ColumnsDDL("VARCHAR", "TIMESTAMP_NTZ", "VARCHAR") + ")",
+			"CREATE STAGE IF NOT EXISTS " + stageName() + " FILE_FORMAT = (" + fileFormat + ")",
+		}
+	},
+	Upsert:         sqldb.AppendInsert,
+	AppendOnly:     true,
+	PatientOrdinal: "TRY_TO_NUMBER(SUBSTR(patient_id, 9))",
+	PayloadLength:  "LENGTH", // characters; SOURCE is ASCII
+}
+
+// Context is a sqldb context whose inserts stage and COPY files instead of running INSERTs.
+type Context struct {
+	*sqldb.Context
+	loads *loadStats
+}
+
+// Setup opens the pool and creates the table and stage, then returns the stage-and-copy insert backend.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if _, err := c.Context.Setup(numWorkers, targetRPS, queriesPerRecord); err != nil {
+		return nil, err
+	}
+	return &Backend{db: c.DB(), loads: c.loads}, nil
+}
+
+// Teardown logs the per-file load latencies and closes the pool.
+func (c *Context) Teardown() {
+	c.loads.log()
+	c.Context.Teardown()
+}
+
+// NewContext returns a worker context for the account in SNOWFLAKE_ACCOUNT, authenticating as SNOWFLAKE_USER /
+// SNOWFLAKE_PASSWORD on SNOWFLAKE_WAREHOUSE, in SNOWFLAKE_DATABASE (default DB_BENCHMARK) and SNOWFLAKE_SCHEMA
+// (default PUBLIC); SNOWFLAKE_ROLE is optional. The database and schema must exist.
+func NewContext() (*Context, error) {
+	cfg := &sf.Config{
+		Account:   os.Getenv("SNOWFLAKE_ACCOUNT"),
+		User:      os.Getenv("SNOWFLAKE_USER"),
+		Password:  os.Getenv("SNOWFLAKE_PASSWORD"),
+		Warehouse: os.Getenv("SNOWFLAKE_WAREHOUSE"),
+		Database:  envOr("SNOWFLAKE_DATABASE", "DB_BENCHMARK"),
+		Schema:    envOr("SNOWFLAKE_SCHEMA", "PUBLIC"),
+		Role:      os.Getenv("SNOWFLAKE_ROLE"),
+	}
+	dsn, err := sf.DSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Context{Context: &sqldb.Context{Dialect: Dialect, DSN: dsn}, loads: &loadStats{}}, nil
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,419 @@
+// Package sqldb runs the benchmark against any database/sql driver. A Dialect supplies the driver name,
+// placeholder style, DDL and upsert syntax; the embedded and MySQL-family backends are thin dialects on top.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// Columns is the hl7_messages column list, in insert order.
+var Columns = []string{
+	"fhir_id", "rx_patient_id", "source", "cdc", "created_at", "created_by",
+	"updated_at", "updated_by", "load_date", "checksum", "patient_id",
+	"medical_record_number", "name_prefix", "last_name", "first_name", "name_suffix",
+	"date_of_birth", "gender_administrative", "fhir_gender_administrative",
+	"gender_identity", "fhir_gender_identity", "marital_status", "fhir_marital_status",
+	"race_display", "fhir_race_display", "ethnicity_display", "fhir_ethnicity_display",
+	"sex_at_birth", "is_pregnant",
+}
+
+// Dialect describes the SQL differences between database/sql backends.
+type Dialect struct {
+	Name   string // --database value, used in logs
+	Driver string // database/sql driver name passed to sql.Open
+	// Placeholder returns the n-th (1-based) bind parameter, e.g. "?" or "$1".
+	Placeholder func(n int) string
+	// Schema returns the statements that create the (prefixed) table and its patient_id index if missing.
+	Schema func(table string) []string
+	// Upsert returns the clause appended after VALUES so duplicates overwrite the row keyed by medical_record_number.
+	Upsert func(updateCols []string) string
+	// PatientOrdinal is the SQL expression extracting the ordinal from patient_id ('patient-NNNNNNNNNN').
+	PatientOrdinal string
+	// PayloadLength is the SQL function returning the byte length of a TEXT value (e.g. octet_length, LENGTH).
+	PayloadLength string
+	// Retryable reports insert errors worth retrying (write conflicts, region/leader changes); nil = never retry.
+	Retryable func(err error) bool
+	// MaxRetries bounds retries of one batch when Retryable matches.
+	MaxRetries int
+	// AppendOnly marks tables without a unique key on medical_record_number (Upsert is AppendInsert):
+	// duplicates add rows, so a primary-key lookup counts the MRN as found once rather than counting rows.
+	AppendOnly bool
+}
+
+// retryBackoff is the sleep before retry attempt (0-based): 10ms doubling, capped at 1s, with up to 50% jitter.
+func retryBackoff(attempt int) time.Duration {
+	d := 10 * time.Millisecond << min(attempt, 7)
+	if d > time.Second {
+		d = time.Second
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// QuestionPlaceholder is the "?" bind style (MySQL, SQLite, DuckDB).
+func QuestionPlaceholder(int) string { return "?" }
+
+// ColumnsDDL renders the column definitions with the given text and timestamp types.
+func ColumnsDDL(textType, timeType, keyType string) string {
+	defs := make([]string, len(Columns))
+	for i, c := range Columns {
+		switch c {
+		case "created_at", "updated_at":
+			defs[i] = c + " " + timeType + " NOT NULL"
+		case "medical_record_number":
+			defs[i] = c + " " + keyType + " NOT NULL"
+		default:
+			defs[i] = c + " " + textType
+		}
+	}
+	return strings.Join(defs, ", ")
+}
+
+// OnConflictUpsert is the ON CONFLICT (medical_record_number) DO UPDATE form (SQLite, DuckDB, Postgres wire).
+func OnConflictUpsert(updateCols []string) string {
+	set := make([]string, len(updateCols))
+	for i, c := range updateCols {
+		set[i] = c + " = excluded." + c
+	}
+	return " ON CONFLICT (medical_record_number) DO UPDATE SET " + strings.Join(set, ", ")
+}
+
+// AppendInsert adds no clause, for engines without unique keys; duplicates become additional rows.
+func AppendInsert(updateCols []string) string {
+	return ""
+}
+
+// OnDuplicateKeyUpsert is the MySQL-family ON DUPLICATE KEY UPDATE form.
+func OnDuplicateKeyUpsert(updateCols []string) string {
+	set := make([]string, len(updateCols))
+	for i, c := range updateCols {
+		set[i] = c + " = VALUES(" + c + ")"
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+}
+
+// RowFromJSON maps a producer JSON message to Columns values. now is used for created_at/updated_at.
+func RowFromJSON(jsonStr string, now time.Time) ([]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &m); err != nil {
+		return nil, err
+	}
+	row := make([]interface{}, len(Columns))
+	for i, c := range Columns {
+		v := m[strings.ToUpper(c)]
+		if v == nil && (c == "created_at" || c == "updated_at") {
+			v = now
+		}
+		row[i] = v
+	}
+	return row, nil
+}
+
+// BuildInsertStatement returns the multi-row upsert SQL and args for rows.
+func BuildInsertStatement(d *Dialect, rows []benchmarkgo.RowForDB) (string, []interface{}, error) {
+	now := time.Now().UTC()
+	updateCols := make([]string, 0, len(Columns)-1)
+	for _, c := range Columns {
+		if c != "medical_record_number" {
+			updateCols = append(updateCols, c)
+		}
+	}
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + benchmarkgo.Table() + " (" + strings.Join(Columns, ", ") + ") VALUES ")
+	args := make([]interface{}, 0, len(rows)*len(Columns))
+	n := 1
+	for i, r := range rows {
+		row, err := RowFromJSON(r.JSONMessage, now)
+		if err != nil {
+			return "", nil, err
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j := range Columns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(d.Placeholder(n))
+			n++
+		}
+		b.WriteByte(')')
+		args = append(args, row...)
+	}
+	b.WriteString(d.Upsert(updateCols))
+	return b.String(), args, nil
+}
+
+// InsertBatch upserts rows in one statement, retrying errors the dialect marks Retryable (each retry is counted
+// under benchmarkgo.ErrOpInsertRetry).
+func InsertBatch(ctx context.Context, db *sql.DB, d *Dialect, rows []benchmarkgo.RowForDB) (int, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	stmt, args, err := BuildInsertStatement(d, rows)
+	if err != nil {
+		return 0, err
+	}
+	for attempt := 0; ; attempt++ {
+		_, err = db.ExecContext(ctx, stmt, args...)
+		if err == nil {
+			return len(rows), nil
+		}
+		if d.Retryable == nil || !d.Retryable(err) || attempt >= d.MaxRetries {
+			return 0, err
+		}
+		benchmarkgo.AddError(benchmarkgo.ErrOpInsertRetry, err)
+		time.Sleep(retryBackoff(attempt))
+	}
+}
+
+// InitSchema runs the dialect's DDL.
+func InitSchema(ctx context.Context, db *sql.DB, d *Dialect) error {
+	for _, stmt := range d.Schema(benchmarkgo.Table()) {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryByPrimaryKey returns COUNT(*) for medical_record_number = mrn.
+func QueryByPrimaryKey(ctx context.Context, db *sql.DB, d *Dialect, mrn string) (int, error) {
+	query := "SELECT COUNT(*) FROM " + benchmarkgo.Table() + " WHERE medical_record_number = " + d.Placeholder(1)
+	if d.AppendOnly {
+		query = "SELECT COUNT(*) FROM (SELECT 1 FROM " + benchmarkgo.Table() + " WHERE medical_record_number = " + d.Placeholder(1) + " LIMIT 1) latest"
+	}
+	var n int
+	err := db.QueryRowContext(ctx, query, mrn).Scan(&n)
+	return n, err
+}
+
+// columnsNoSource is the select list for row fetches without the source payload.
+var columnsNoSource = func() string {
+	cols := make([]string, 0, len(Columns))
+	for _, c := range Columns {
+		if c != "source" {
+			cols = append(cols, c)
+		}
+	}
+	return strings.Join(cols, ", ")
+}()
+
+// FetchByPrimaryKey fetches the latest row for mrn, with or without source, and returns rows and bytes read.
+func FetchByPrimaryKey(ctx context.Context, db *sql.DB, d *Dialect, mrn string, withPayload bool) (int, int64, error) {
+	cols := "*"
+	if !withPayload {
+		cols = columnsNoSource
+	}
+	rows, err := db.QueryContext(ctx, "SELECT "+cols+" FROM "+benchmarkgo.Table()+" WHERE medical_record_number = "+d.Placeholder(1)+
+		" ORDER BY created_at DESC LIMIT 1", mrn)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	return scanCount(rows)
+}
+
+// FetchVerifyColumns reads the columns --verify compares from the latest row for mrn; nil when there is none.
+func FetchVerifyColumns(ctx context.Context, db *sql.DB, d *Dialect, mrn string) (*benchmarkgo.VerifyColumns, error) {
+	rows, err := db.QueryContext(ctx, "SELECT COALESCE(patient_id, ''), COALESCE(last_name, ''), COALESCE(first_name, ''),"+
+		" COALESCE(date_of_birth, ''), COALESCE(checksum, '') FROM "+benchmarkgo.Table()+
+		" WHERE medical_record_number = "+d.Placeholder(1)+" ORDER BY created_at DESC LIMIT 1", mrn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var c benchmarkgo.VerifyColumns
+	if err := rows.Scan(&c.PatientID, &c.LastName, &c.FirstName, &c.DateOfBirth, &c.Checksum); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FetchChecksumRow reads the columns CHECKSUM covers, CHECKSUM and SOURCE from the latest row for mrn; nil when
+// there is none.
+func FetchChecksumRow(ctx context.Context, db *sql.DB, d *Dialect, mrn string) (*benchmarkgo.ChecksumRow, error) {
+	rows, err := db.QueryContext(ctx, "SELECT COALESCE(patient_id, ''), medical_record_number, COALESCE(last_name, ''), COALESCE(first_name, ''),"+
+		" COALESCE(date_of_birth, ''), COALESCE(checksum, ''), COALESCE(source, '') FROM "+benchmarkgo.Table()+
+		" WHERE medical_record_number = "+d.Placeholder(1)+" ORDER BY created_at DESC LIMIT 1", mrn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var c benchmarkgo.ChecksumRow
+	if err := rows.Scan(&c.PatientID, &c.MRN, &c.LastName, &c.FirstName, &c.DateOfBirth, &c.Checksum, &c.Source); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// QueryResultSet fetches full rows for the MRN range ending at mrn (descending, LIMIT limit) and returns rows and bytes read.
+func QueryResultSet(ctx context.Context, db *sql.DB, d *Dialect, mrn string, limit int) (int, int64, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT * FROM "+benchmarkgo.Table()+" WHERE medical_record_number <= "+d.Placeholder(1)+
+			" ORDER BY medical_record_number DESC LIMIT "+d.Placeholder(2), mrn, limit)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	return scanCount(rows)
+}
+
+// QueryByPatientID fetches full rows for patient_id and returns how many were read.
+func QueryByPatientID(ctx context.Context, db *sql.DB, d *Dialect, patientID string) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+benchmarkgo.Table()+" WHERE patient_id = "+d.Placeholder(1), patientID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n, _, err := scanCount(rows)
+	return n, err
+}
+
+// QueryPatientRange fetches full rows for patient_id created between from and to and returns how many were read.
+func QueryPatientRange(ctx context.Context, db *sql.DB, d *Dialect, patientID string, from, to time.Time) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+benchmarkgo.Table()+" WHERE patient_id = "+d.Placeholder(1)+
+		" AND created_at BETWEEN "+d.Placeholder(2)+" AND "+d.Placeholder(3), patientID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n, _, err := scanCount(rows)
+	return n, err
+}
+
+// pageOrder is the patient list's sort order for the pagination queries.
+const pageOrder = " ORDER BY last_name, first_name, patient_id"
+
+// QueryPageOffset fetches limit rows of the name-ordered patient list from offset on and returns how many were read.
+func QueryPageOffset(ctx context.Context, db *sql.DB, d *Dialect, offset, limit int) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+benchmarkgo.Table()+pageOrder+" LIMIT "+d.Placeholder(1)+" OFFSET "+d.Placeholder(2), limit, offset)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n, _, err := scanCount(rows)
+	return n, err
+}
+
+// QueryPageAfter fetches the limit rows of the name-ordered patient list after the given key and returns how many
+// were read.
+func QueryPageAfter(ctx context.Context, db *sql.DB, d *Dialect, lastName, firstName, patientID string, limit int) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+benchmarkgo.Table()+" WHERE (last_name, first_name, patient_id) > ("+
+		d.Placeholder(1)+", "+d.Placeholder(2)+", "+d.Placeholder(3)+")"+pageOrder+" LIMIT "+d.Placeholder(4),
+		lastName, firstName, patientID, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n, _, err := scanCount(rows)
+	return n, err
+}
+
+// QueryByNameDOB fetches full rows matching last name, first name and date of birth and returns how many were read.
+func QueryByNameDOB(ctx context.Context, db *sql.DB, d *Dialect, lastName, firstName, dateOfBirth string) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+benchmarkgo.Table()+" WHERE last_name = "+d.Placeholder(1)+
+		" AND first_name = "+d.Placeholder(2)+" AND date_of_birth = "+d.Placeholder(3), lastName, firstName, dateOfBirth)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n, _, err := scanCount(rows)
+	return n, err
+}
+
+// AggregateByPatientID summarizes the patient's rows (count, latest update, payload size) and returns the count.
+func AggregateByPatientID(ctx context.Context, db *sql.DB, d *Dialect, patientID string) (int, error) {
+	var n int
+	var lastUpdated, payloadBytes interface{}
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*), MAX(updated_at), SUM("+d.PayloadLength+"(source)) FROM "+benchmarkgo.Table()+
+			" WHERE patient_id = "+d.Placeholder(1), patientID,
+	).Scan(&n, &lastUpdated, &payloadBytes)
+	return n, err
+}
+
+// GetMaxPatientCounter returns the max patient ordinal in the table, or -1.
+func GetMaxPatientCounter(ctx context.Context, db *sql.DB, d *Dialect) (int, error) {
+	var v sql.NullInt64
+	err := db.QueryRowContext(ctx,
+		"SELECT MAX("+d.PatientOrdinal+") FROM "+benchmarkgo.Table()+" WHERE patient_id LIKE 'patient-%'").Scan(&v)
+	if err != nil || !v.Valid {
+		return -1, err
+	}
+	return int(v.Int64), nil
+}
+
+// CountRows returns the number of rows in the table.
+func CountRows(ctx context.Context, db *sql.DB) (int64, error) {
+	var n int64
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+benchmarkgo.Table()).Scan(&n)
+	return n, err
+}
+
+// CountKeys returns the number of distinct MRNs of generated patients, for reconciliation.
+func CountKeys(ctx context.Context, db *sql.DB) (int64, error) {
+	var n int64
+	err := db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT medical_record_number) FROM "+benchmarkgo.Table()+
+		" WHERE patient_id LIKE 'patient-%'").Scan(&n)
+	return n, err
+}
+
+// SampleKeys returns up to limit distinct patient IDs of generated patients (the first found, since the dialects
+// disagree on a random function).
+func SampleKeys(ctx context.Context, db *sql.DB, limit int) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT patient_id FROM "+benchmarkgo.Table()+
+		" WHERE patient_id LIKE 'patient-%' LIMIT "+strconv.Itoa(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// scanCount drains rows, returning the row count and total bytes of the raw column values.
+func scanCount(rows *sql.Rows) (int, int64, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, 0, err
+	}
+	raw := make([]sql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range raw {
+		dest[i] = &raw[i]
+	}
+	var n int
+	var bytes int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, bytes, err
+		}
+		n++
+		for _, v := range raw {
+			bytes += int64(len(v))
+		}
+	}
+	return n, bytes, rows.Err()
+}
@@ -0,0 +1,17 @@
+package sqldb
+
+import (
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// microbenchDialect is a MySQL-style dialect for timing statement building without a driver.
+var microbenchDialect = &Dialect{Name: "microbench", Placeholder: QuestionPlaceholder, Upsert: OnDuplicateKeyUpsert}
+
+func init() {
+	benchmarkgo.RegisterMicrobench(benchmarkgo.Microbench{Name: "sqldb/BuildInsertStatement", Setup: func(batchSize int) func() {
+		rows := benchmarkgo.MicrobenchRows(batchSize)
+		return func() {
+			_, _, _ = BuildInsertStatement(microbenchDialect, rows)
+		}
+	}})
+}
@@ -0,0 +1,194 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// Backend implements benchmarkgo.InsertBackend on a *sql.DB (which pools its own connections).
+type Backend struct {
+	db      *sql.DB
+	dialect *Dialect
+}
+
+// GetConn returns the shared *sql.DB; database/sql checks out a pooled connection per statement.
+func (b *Backend) GetConn() interface{} {
+	return b.db
+}
+
+// ReleaseConn is a no-op; database/sql manages its pool.
+func (b *Backend) ReleaseConn(c interface{}) {}
+
+// InsertBatch upserts rows in one statement.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	db, ok := conn.(*sql.DB)
+	if !ok {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // pgbouncer routing hint; unused outside Postgres
+	n, err := InsertBatch(context.Background(), db, b.dialect, rows)
+	if err != nil {
+		return benchmarkgo.InsertResult{Rows: n}, err
+	}
+	return benchmarkgo.InsertResult{Rows: n, Statements: 1}, nil
+}
+
+// Context handles setup/teardown and query workers for a database/sql backend.
+type Context struct {
+	Dialect *Dialect
+	DSN     string // driver-specific data source name
+	// MaxConns caps open connections (0 = the benchmarkgo.Pool insert + query sizes). Embedded engines with a single
+	// writer set 1.
+	MaxConns int
+	db       *sql.DB
+}
+
+// Setup opens the database, sizes the pool for insert + query workers and creates the schema.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if c.db != nil {
+		log.Fatalf("%s Setup already called", c.Dialect.Name)
+	}
+	db, err := sql.Open(c.Dialect.Driver, c.DSN)
+	if err != nil {
+		return nil, err
+	}
+	conns := c.MaxConns
+	if conns <= 0 {
+		insert, query := benchmarkgo.Pool().Sizes(numWorkers, queriesPerRecord > 0)
+		conns = insert + query
+	}
+	db.SetMaxOpenConns(conns)
+	db.SetMaxIdleConns(benchmarkgo.Pool().Idle(conns))
+	db.SetConnMaxLifetime(benchmarkgo.Pool().MaxLifetime)
+	log.Printf("Opening %s (%d connections)", c.Dialect.Name, conns)
+	ctx := context.Background()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := InitSchema(ctx, db, c.Dialect); err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Printf("Table %s ready (%s)", benchmarkgo.Table(), c.Dialect.Name)
+	c.db = db
+	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
+	return &Backend{db: db, dialect: c.Dialect}, nil
+}
+
+// Teardown closes the database.
+func (c *Context) Teardown() {
+	if c.db != nil {
+		c.db.Close()
+		c.db = nil
+	}
+}
+
+// GetMaxPatientCounter returns the max patient ordinal in the table.
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	return GetMaxPatientCounter(context.Background(), c.db, c.Dialect)
+}
+
+// SampleKeys samples generated patient IDs (implements benchmarkgo.KeySampler).
+func (c *Context) SampleKeys(ctx context.Context, limit int) ([]string, error) {
+	return SampleKeys(ctx, c.db, limit)
+}
+
+// CountKeys counts generated patients' MRNs (implements benchmarkgo.KeyCounter).
+func (c *Context) CountKeys(ctx context.Context) (int64, error) {
+	return CountKeys(ctx, c.db)
+}
+
+// CountRows counts the table's rows (implements benchmarkgo.RowCounter).
+func (c *Context) CountRows(ctx context.Context) (int64, error) {
+	return CountRows(ctx, c.db)
+}
+
+// DescribeSchema returns the dialect's CREATE statements for the table (implements benchmarkgo.SchemaDescriber).
+func (c *Context) DescribeSchema(ctx context.Context) (string, error) {
+	return strings.Join(c.Dialect.Schema(benchmarkgo.Table()), ";\n") + ";", nil
+}
+
+// DB returns the open database (nil before Setup), for dialect packages that add capabilities.
+func (c *Context) DB() *sql.DB {
+	return c.db
+}
+
+// querier binds the database to benchmarkgo.Querier.
+type querier struct {
+	db      *sql.DB
+	dialect *Dialect
+}
+
+func (q querier) QueryByPrimaryKey(ctx context.Context, mrn string) (int, error) {
+	return QueryByPrimaryKey(ctx, q.db, q.dialect, mrn)
+}
+
+func (q querier) FetchByPrimaryKey(ctx context.Context, mrn string, withPayload bool) (int, int64, error) {
+	return FetchByPrimaryKey(ctx, q.db, q.dialect, mrn, withPayload)
+}
+
+func (q querier) FetchVerifyColumns(ctx context.Context, mrn string) (*benchmarkgo.VerifyColumns, error) {
+	return FetchVerifyColumns(ctx, q.db, q.dialect, mrn)
+}
+
+func (q querier) FetchChecksumRow(ctx context.Context, mrn string) (*benchmarkgo.ChecksumRow, error) {
+	return FetchChecksumRow(ctx, q.db, q.dialect, mrn)
+}
+
+func (q querier) QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error) {
+	return QueryResultSet(ctx, q.db, q.dialect, mrn, limit)
+}
+
+func (q querier) QueryByPatientID(ctx context.Context, patientID string) (int, error) {
+	return QueryByPatientID(ctx, q.db, q.dialect, patientID)
+}
+
+func (q querier) AggregateByPatientID(ctx context.Context, patientID string) (int, error) {
+	return AggregateByPatientID(ctx, q.db, q.dialect, patientID)
+}
+
+func (q querier) QueryPageOffset(ctx context.Context, offset, limit int) (int, error) {
+	return QueryPageOffset(ctx, q.db, q.dialect, offset, limit)
+}
+
+func (q querier) QueryPageAfter(ctx context.Context, lastName, firstName, patientID string, limit int) (int, error) {
+	return QueryPageAfter(ctx, q.db, q.dialect, lastName, firstName, patientID, limit)
+}
+
+func (q querier) QueryByNameDOB(ctx context.Context, lastName, firstName, dateOfBirth string) (int, error) {
+	return QueryByNameDOB(ctx, q.db, q.dialect, lastName, firstName, dateOfBirth)
+}
+
+func (q querier) QueryPatientRange(ctx context.Context, patientID string, from, to time.Time) (int, error) {
+	return QueryPatientRange(ctx, q.db, q.dialect, patientID, from, to)
+}
+
+// RunQueryWorker consumes from queryQueue, runs the configured queries per MRN, reports via benchmarkgo.AddQuery.
+// workerIndex is the 0-based index of this query worker.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex // reserved for logging/tracing
+	runner := benchmarkgo.NewQueryRunner(opts)
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
+			if time.Now().Before(deadline) {
+				time.Sleep(time.Until(deadline))
+			}
+		}
+		count, failed, latency := runner.Run(context.Background(), querier{c.db, c.Dialect}, job)
+		benchmarkgo.AddQuery(int64(count), latency.Microseconds(), int64(failed))
+	}
+}
@@ -0,0 +1,69 @@
+// Package sqlite runs the benchmark against an embedded SQLite database file in WAL mode, so the workload logic
+// can be sanity-checked locally with no external database. Uses the cgo driver github.com/mattn/go-sqlite3.
+package sqlite
+
+import (
+	"os"
+	"sync"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const defaultPath = "benchmark.sqlite"
+
+// Dialect is SQLite's SQL flavour. One batch is one multi-row INSERT ... ON CONFLICT statement, i.e. one
+// transaction; keep batch_size x 29 columns under SQLITE_MAX_VARIABLE_NUMBER (32766).
+var Dialect = &sqldb.Dialect{
+	Name:        "sqlite",
+	Driver:      "sqlite3",
+	Placeholder: sqldb.QuestionPlaceholder,
+	Schema: func(table string) []string {
+		return []string{
+			"CREATE TABLE IF NOT EXISTS " + table + " (" + sqldb.ColumnsDDL("TEXT", "TIMESTAMP", "TEXT") +
+				", PRIMARY KEY (medical_record_number))",
+			"CREATE INDEX IF NOT EXISTS " + benchmarkgo.Prefixed("idx_hl7_patient_id") + " ON " + table + " (patient_id)",
+		}
+	},
+	Upsert:         sqldb.OnConflictUpsert,
+	PatientOrdinal: "CAST(substr(patient_id, 9) AS INTEGER)",
+	PayloadLength:  "length",
+}
+
+// Context opens the database in WAL mode (readers never block the writer) and serializes inserts in-process,
+// since SQLite allows a single writer; otherwise concurrent insert workers would just spin on SQLITE_BUSY.
+type Context struct {
+	*sqldb.Context
+}
+
+// Setup opens the database and wraps the backend so only one insert worker writes at a time.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	be, err := c.Context.Setup(numWorkers, targetRPS, queriesPerRecord)
+	if err != nil {
+		return nil, err
+	}
+	return &singleWriter{InsertBackend: be}, nil
+}
+
+// singleWriter holds a lock around InsertBatch; insert latency therefore includes time queued for the writer.
+type singleWriter struct {
+	benchmarkgo.InsertBackend
+	mu sync.Mutex
+}
+
+func (b *singleWriter) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.InsertBackend.InsertBatch(conn, rows, queryHint)
+}
+
+// NewContext returns a worker context for the database file at SQLITE_PATH (default benchmark.sqlite).
+func NewContext() *Context {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = defaultPath
+	}
+	dsn := "file:" + path + "?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=10000"
+	return &Context{Context: &sqldb.Context{Dialect: Dialect, DSN: dsn}}
+}
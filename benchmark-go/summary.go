@@ -0,0 +1,179 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// sparklineWidth caps the sparkline's length; longer runs average adjacent intervals into one character.
+const sparklineWidth = 60
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// SummaryReport is the structured end-of-run summary: per-phase throughput and latency, and the run's
+// throughput over time as a sparkline of the progress intervals.
+type SummaryReport struct {
+	Phases       []PhaseStats `json:"phases"`
+	Sparkline    string       `json:"sparkline,omitempty"`
+	SparkMinRPS  float64      `json:"sparkline_min_rps,omitempty"`
+	SparkMaxRPS  float64      `json:"sparkline_max_rps,omitempty"`
+	SparkStepSec float64      `json:"sparkline_step_sec,omitempty"` // seconds of run per character
+}
+
+// buildSummary assembles the summary from the phase marks and the progress intervals.
+func buildSummary(runStart, runEnd time.Time, intervals []GoodputInterval) *SummaryReport {
+	s := &SummaryReport{Phases: loadPhases(runStart, runEnd)}
+	if len(intervals) == 0 {
+		return s
+	}
+	rates := make([]float64, len(intervals))
+	for i, iv := range intervals {
+		rates[i] = iv.ThroughputRPS
+	}
+	step := intervals[0].AtSec
+	if len(intervals) > 1 {
+		step = intervals[1].AtSec - intervals[0].AtSec
+	}
+	per := (len(rates) + sparklineWidth - 1) / sparklineWidth
+	var buckets []float64
+	for i := 0; i < len(rates); i += per {
+		j := min(i+per, len(rates))
+		sum := 0.0
+		for _, v := range rates[i:j] {
+			sum += v
+		}
+		buckets = append(buckets, sum/float64(j-i))
+	}
+	s.Sparkline, s.SparkMinRPS, s.SparkMaxRPS = sparkline(buckets)
+	s.SparkStepSec = step * float64(per)
+	return s
+}
+
+// sparkline renders values as block characters scaled between their min and max.
+func sparkline(values []float64) (line string, lo, hi float64) {
+	lo, hi = values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := len(sparkTicks) - 1
+		if hi > lo {
+			i = int((v - lo) / (hi - lo) * float64(len(sparkTicks)-1))
+		}
+		b.WriteRune(sparkTicks[i])
+	}
+	return b.String(), lo, hi
+}
+
+// formatMs renders a percentile in ms, or "-" without samples.
+func formatMs(p *LatencyPercentiles, pick func(*LatencyPercentiles) float64) string {
+	if p == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", pick(p))
+}
+
+func p50(p *LatencyPercentiles) float64 { return p.P50Ms }
+func p99(p *LatencyPercentiles) float64 { return p.P99Ms }
+
+// phaseTable renders the phases as rows of cells under phaseHeader.
+var phaseHeader = []string{"phase", "start s", "secs", "rows", "rows/s", "insert p50 ms", "insert p99 ms", "queries/s", "query p50 ms", "query p99 ms"}
+
+func phaseRows(phases []PhaseStats) [][]string {
+	rows := make([][]string, len(phases))
+	for i, p := range phases {
+		rows[i] = []string{
+			p.Name, fmt.Sprintf("%.1f", p.StartSec), fmt.Sprintf("%.1f", p.DurationSec),
+			fmt.Sprintf("%d", p.Rows), fmt.Sprintf("%.1f", p.RowsPerSec),
+			formatMs(p.Insert, p50), formatMs(p.Insert, p99),
+			fmt.Sprintf("%.1f", p.QueriesPerSec), formatMs(p.Query, p50), formatMs(p.Query, p99),
+		}
+	}
+	return rows
+}
+
+// logSummaryReport logs the phase table and the throughput sparkline.
+func logSummaryReport(s *SummaryReport) {
+	if s == nil || len(s.Phases) == 0 {
+		return
+	}
+	widths := make([]int, len(phaseHeader))
+	rows := phaseRows(s.Phases)
+	for i, h := range phaseHeader {
+		widths[i] = len(h)
+		for _, r := range rows {
+			widths[i] = max(widths[i], len(r[i]))
+		}
+	}
+	line := func(cells []string) string {
+		parts := make([]string, len(cells))
+		for i, c := range cells {
+			if i == 0 {
+				parts[i] = padRight(c, widths[i])
+			} else {
+				parts[i] = padLeft(c, widths[i])
+			}
+		}
+		return strings.Join(parts, "  ")
+	}
+	log.Printf("Phases:")
+	log.Printf("  %s", line(phaseHeader))
+	for _, r := range rows {
+		log.Printf("  %s", line(r))
+	}
+	if s.Sparkline != "" {
+		log.Printf("Throughput: %s  (%.0f-%.0f rows/s, %.0fs per char)", s.Sparkline, s.SparkMinRPS, s.SparkMaxRPS, s.SparkStepSec)
+	}
+}
+
+// WriteMarkdownReport renders res as a Markdown report: headline numbers, phases, throughput sparkline,
+// errors and bottleneck hints.
+func WriteMarkdownReport(path string, res *Results) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Benchmark report: %s\n\n", res.Database)
+	fmt.Fprintf(&b, "Started %s, ran %.1fs.\n\n", res.StartedAt.Format(time.RFC3339), res.ElapsedSec)
+	b.WriteString("| metric | value |\n|---|---:|\n")
+	if res.Concurrency > 0 {
+		fmt.Fprintf(&b, "| closed-loop batches in flight | %d |\n", res.Concurrency)
+	} else {
+		fmt.Fprintf(&b, "| target rows/s | %d |\n", res.TargetRPS)
+	}
+	fmt.Fprintf(&b, "| actual rows/s | %.1f |\n", res.ActualRPS)
+	fmt.Fprintf(&b, "| rows inserted | %d |\n", int64(res.Inserted.Total))
+	if p := res.Inserted.Latency; p != nil {
+		fmt.Fprintf(&b, "| insert batch p50 / p99 / max ms | %.2f / %.2f / %.2f |\n", p.P50Ms, p.P99Ms, p.MaxMs)
+	}
+	fmt.Fprintf(&b, "| queries | %d (%d failed) |\n", int64(res.Queries.Count), int64(res.Queries.FailedCount))
+	if p := res.Queries.Latency; p != nil {
+		fmt.Fprintf(&b, "| query p50 / p99 / max ms | %.2f / %.2f / %.2f |\n", p.P50Ms, p.P99Ms, p.MaxMs)
+	}
+	if s := res.Summary; s != nil && len(s.Phases) > 0 {
+		b.WriteString("\n## Phases\n\n")
+		b.WriteString("| " + strings.Join(phaseHeader, " | ") + " |\n")
+		b.WriteString("|---" + strings.Repeat("|---:", len(phaseHeader)-1) + "|\n")
+		for _, r := range phaseRows(s.Phases) {
+			b.WriteString("| " + strings.Join(r, " | ") + " |\n")
+		}
+		if s.Sparkline != "" {
+			fmt.Fprintf(&b, "\n## Throughput over time\n\n```\n%s\n```\n\n%.0f-%.0f rows/s, %.0fs per character.\n",
+				s.Sparkline, s.SparkMinRPS, s.SparkMaxRPS, s.SparkStepSec)
+		}
+	}
+	if len(res.Errors) > 0 {
+		b.WriteString("\n## Errors\n\n| op | code | count |\n|---|---|---:|\n")
+		for _, e := range res.Errors {
+			fmt.Fprintf(&b, "| %s | %s | %d |\n", e.Op, e.Code, e.Count)
+		}
+	}
+	if len(res.Hints) > 0 {
+		b.WriteString("\n## Bottleneck hints\n\n")
+		for _, h := range res.Hints {
+			fmt.Fprintf(&b, "- %s\n", h)
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
@@ -0,0 +1,107 @@
+package benchmarkgo
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Multi-target runs drive several databases at once with the same seeded stream, one process per target
+// (stats are process-wide), and compare the per-target Results side by side. Running the targets concurrently
+// removes the drift (cache state, noisy neighbours, time of day) between back-to-back runs.
+
+// comparisonRow is one metric of the side-by-side table; value returns "" when a target has no data for it.
+type comparisonRow struct {
+	label string
+	value func(res *Results) string
+}
+
+var comparisonRows = []comparisonRow{
+	{"Elapsed (s)", func(res *Results) string { return fmt.Sprintf("%.1f", res.ElapsedSec) }},
+	{"Rows inserted", func(res *Results) string { return fmt.Sprintf("%d", int64(res.Inserted.Total)) }},
+	{"Insert rate (rows/s)", func(res *Results) string { return fmt.Sprintf("%.1f", res.ActualRPS) }},
+	{"Goodput (rows/s)", func(res *Results) string {
+		if res.Goodput == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.1f", res.Goodput.GoodputRPS)
+	}},
+	{"Insert latency (ms/row)", func(res *Results) string {
+		if res.Inserted.Total == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3f", res.Inserted.TotalInsertLatencySec/res.Inserted.Total*1000)
+	}},
+	{"Insert p99 (ms/batch)", func(res *Results) string {
+		if res.Inserted.Latency == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", res.Inserted.Latency.P99Ms)
+	}},
+	{"Insert statements", func(res *Results) string { return fmt.Sprintf("%d", int64(res.Inserted.InsertStatements)) }},
+	{"Queries", func(res *Results) string { return fmt.Sprintf("%d", int64(res.Queries.Count)) }},
+	{"Query rate (queries/s)", func(res *Results) string {
+		if res.ElapsedSec == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.1f", res.Queries.Count/res.ElapsedSec)
+	}},
+	{"Query latency (ms)", func(res *Results) string {
+		if res.Queries.Count == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", res.Queries.TotalLatencySec/res.Queries.Count*1000)
+	}},
+	{"Query p99 (ms)", func(res *Results) string {
+		if res.Queries.Latency == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", res.Queries.Latency.P99Ms)
+	}},
+	{"Failed queries", func(res *Results) string { return fmt.Sprintf("%d", int64(res.Queries.FailedCount)) }},
+	{"Errors", func(res *Results) string {
+		var n int64
+		for _, e := range res.Errors {
+			n += e.Count
+		}
+		return fmt.Sprintf("%d", n)
+	}},
+}
+
+// LogTargetComparison prints the targets' results side by side. names and results are parallel; a nil result
+// (the target's run failed) shows as "failed".
+func LogTargetComparison(names []string, results []*Results) {
+	logComparison("Target comparison", names, results)
+}
+
+// LogPhaseComparison prints a scenario's per-phase results side by side, like LogTargetComparison.
+func LogPhaseComparison(names []string, results []*Results) {
+	logComparison("Phase comparison", names, results)
+}
+
+func logComparison(title string, names []string, results []*Results) {
+	width := 14
+	for _, name := range names {
+		width = max(width, len(name)+2)
+	}
+	header := fmt.Sprintf("%-26s", "Metric")
+	for _, name := range names {
+		header += fmt.Sprintf("%*s", width, name)
+	}
+	log.Printf("%s:", title)
+	log.Printf("  %s", header)
+	log.Printf("  %s", strings.Repeat("-", len(header)))
+	for _, row := range comparisonRows {
+		line := fmt.Sprintf("%-26s", row.label)
+		for _, res := range results {
+			v := "failed"
+			if res != nil {
+				if v = row.value(res); v == "" {
+					v = "-"
+				}
+			}
+			line += fmt.Sprintf("%*s", width, v)
+		}
+		log.Printf("  %s", line)
+	}
+}
@@ -0,0 +1,62 @@
+// Package tidb runs the benchmark against TiDB over the MySQL protocol, optionally with AUTO_RANDOM surrogate keys.
+package tidb
+
+import (
+	"github.com/db-benchmarking/benchmark-go/mysql"
+	"github.com/db-benchmarking/benchmark-go/sqldb"
+)
+
+// TiDB error numbers that are safe to retry: the statement is rolled back and re-running it can succeed.
+const (
+	erWriteConflict        = 9007 // optimistic write conflict
+	erTxnRetryable         = 8022 // transaction commit failed, retryable
+	erWriteConflictInLatch = 8002
+	erInfoSchemaChanged    = 8028 // schema changed during the transaction (e.g. concurrent DDL)
+	erRegionUnavailable    = 9005
+	erTiKVServerBusy       = 9003
+	erTiKVServerTimeout    = 9002
+	erPDServerTimeout      = 9001
+)
+
+var retryableErrors = map[uint16]bool{
+	erWriteConflict:         true,
+	erTxnRetryable:          true,
+	erWriteConflictInLatch:  true,
+	erInfoSchemaChanged:     true,
+	erRegionUnavailable:     true,
+	erTiKVServerBusy:        true,
+	erTiKVServerTimeout:     true,
+	erPDServerTimeout:       true,
+	mysql.ErLockDeadlock:    true,
+	mysql.ErLockWaitTimeout: true,
+}
+
+// Options selects the TiDB table layout.
+type Options struct {
+	// AutoRandom keys rows by a BIGINT AUTO_RANDOM surrogate (scattering inserts across regions instead of
+	// hot-spotting the MRN-ordered tail) with a unique index on medical_record_number for the upsert.
+	AutoRandom bool
+}
+
+// schema returns the DDL for opts: a clustered MRN primary key, or an AUTO_RANDOM id plus unique MRN index.
+func schema(opts Options) func(table string) []string {
+	return func(table string) []string {
+		key := "PRIMARY KEY (medical_record_number) CLUSTERED"
+		if opts.AutoRandom {
+			key = "id BIGINT AUTO_RANDOM, PRIMARY KEY (id) CLUSTERED, UNIQUE KEY uk_mrn (medical_record_number)"
+		}
+		return []string{
+			"CREATE TABLE IF NOT EXISTS " + table + " (" + mysql.ColumnsDDL() + ", " + key +
+				", KEY idx_hl7_patient_id (patient_id(32)))",
+		}
+	}
+}
+
+// NewContext returns a worker context for TiDB at TIDB_HOST:TIDB_PORT (default tidb:4000, user root, database test).
+func NewContext(opts Options) *sqldb.Context {
+	conn := mysql.ConnFromEnv("TIDB", mysql.Conn{Host: "tidb", Port: 4000, User: "root", Database: "test"})
+	d := mysql.Dialect("tidb", schema(opts))
+	d.Retryable = func(err error) bool { return retryableErrors[mysql.ErrorNumber(err)] }
+	d.MaxRetries = 10
+	return &sqldb.Context{Dialect: d, DSN: conn.DSN()}
+}
@@ -0,0 +1,86 @@
+package benchmarkgo
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+)
+
+// timelineColumns is the header of the --output-csv time series; one row per progress interval.
+var timelineColumns = []string{
+	"at_sec", "unix_time",
+	"inserted_rows", "inserted_originals", "inserted_duplicates", "insert_statements", "insert_rows_per_sec",
+	"insert_avg_ms_per_row", "insert_batch_p50_ms", "insert_batch_p99_ms", "insert_batch_max_ms",
+	"queries", "queries_failed", "queries_per_sec", "query_avg_ms", "query_p50_ms", "query_p99_ms", "query_max_ms",
+	"cum_inserted_rows", "cum_queries",
+	"client_cpu_pct", "client_rss_bytes",
+}
+
+// timelineRow is one progress interval's values for the CSV.
+type timelineRow struct {
+	atSec, unixTime                         float64
+	rows, originals, duplicates, statements int
+	rowsPerSec, insertAvgMs                 float64
+	insertLatency                           *LatencyPercentiles
+	queries, failed                         int
+	queriesPerSec, queryAvgMs               float64
+	queryLatency                            *LatencyPercentiles
+	cumRows, cumQueries                     float64
+	clientCPUPct                            float64
+	clientRSS                               int64
+}
+
+// timelineCSV appends interval rows to a CSV file, flushing each so the file can be plotted mid-run.
+type timelineCSV struct {
+	f *os.File
+	w *csv.Writer
+}
+
+// openTimelineCSV creates (truncates) path and writes the header.
+func openTimelineCSV(path string) (*timelineCSV, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &timelineCSV{f: f, w: csv.NewWriter(f)}
+	if err := t.write(timelineColumns); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *timelineCSV) write(record []string) error {
+	if err := t.w.Write(record); err != nil {
+		return err
+	}
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// add appends one interval; empty latency cells mean no samples in the interval.
+func (t *timelineCSV) add(r timelineRow) error {
+	f := func(v float64, prec int) string { return strconv.FormatFloat(v, 'f', prec, 64) }
+	pct := func(p *LatencyPercentiles) []string {
+		if p == nil {
+			return []string{"", "", ""}
+		}
+		return []string{f(p.P50Ms, 3), f(p.P99Ms, 3), f(p.MaxMs, 3)}
+	}
+	rec := []string{
+		f(r.atSec, 1), f(r.unixTime, 3),
+		strconv.Itoa(r.rows), strconv.Itoa(r.originals), strconv.Itoa(r.duplicates), strconv.Itoa(r.statements),
+		f(r.rowsPerSec, 1), f(r.insertAvgMs, 3),
+	}
+	rec = append(rec, pct(r.insertLatency)...)
+	rec = append(rec, strconv.Itoa(r.queries), strconv.Itoa(r.failed), f(r.queriesPerSec, 1), f(r.queryAvgMs, 3))
+	rec = append(rec, pct(r.queryLatency)...)
+	rec = append(rec, f(r.cumRows, 0), f(r.cumQueries, 0))
+	rec = append(rec, f(r.clientCPUPct, 1), strconv.FormatInt(r.clientRSS, 10))
+	return t.write(rec)
+}
+
+func (t *timelineCSV) close() error {
+	t.w.Flush()
+	return t.f.Close()
+}
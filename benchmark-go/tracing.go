@@ -0,0 +1,78 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Tracing exports OpenTelemetry spans for worker flushes (with their connection checkout and InsertBatch
+// children) and primary-key lookups over OTLP/HTTP, so a slow batch's waterfall shows whether time went to
+// client-side queueing (pool checkout) or the database call.
+
+// TracingOptions configures span export (disabled when Endpoint is "").
+type TracingOptions struct {
+	Endpoint   string  // OTLP/HTTP collector URL, e.g. http://otel-collector:4318
+	SampleRate float64 // fraction of root spans (flushes, lookups) sampled
+}
+
+// tracerName is the instrumentation scope of the benchmark's spans.
+const tracerName = "github.com/db-benchmarking/benchmark-go"
+
+// tracer is a no-op until startTracing installs an exporting provider.
+var tracer trace.Tracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// startTracing installs an OTLP-exporting tracer for database and returns a shutdown that flushes pending spans.
+func startTracing(opts TracingOptions, database string) (func(), error) {
+	exp, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(opts.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", "db-benchmark"),
+		attribute.String("benchmark.database", database),
+	))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRate))),
+	)
+	tracer = tp.Tracer(tracerName)
+	log.Printf("Exporting OpenTelemetry spans to %s (sample rate %g)", opts.Endpoint, opts.SampleRate)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			log.Printf("OpenTelemetry shutdown: %v", err)
+		}
+	}, nil
+}
+
+// ValidateTracing checks the tracing options.
+func ValidateTracing(opts TracingOptions) error {
+	if opts.SampleRate < 0 || opts.SampleRate > 1 {
+		return fmt.Errorf("sample rate %g must be within 0-1", opts.SampleRate)
+	}
+	return nil
+}
+
+// endSpan records err on span (if any) and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,249 @@
+package benchmarkgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Payload transforms selectable via Config.PayloadTransforms (comma-separated, applied in order).
+const (
+	TransformGzip   = "gzip"
+	TransformZstd   = "zstd"
+	TransformAESGCM = "aes-gcm"
+)
+
+// StageDecode is the pipeline stage that reverses the payload transforms on SOURCE read back by queries.
+const StageDecode = "decode"
+
+// sourceMember opens the SOURCE member of a JSON message. Payloads and transformed payloads ("<name>:" + base64)
+// never contain quotes or backslashes, so the member ends at the next quote.
+const sourceMember = `"SOURCE":"`
+
+// SourceFetcher is implemented by Queriers that can read SOURCE back, so queries validate transformed payloads.
+type SourceFetcher interface {
+	FetchSource(ctx context.Context, mrn string) (string, error)
+}
+
+// PayloadTransform is a RowStage that rewrites SOURCE as "<name>:" + base64(transformed bytes) and can reverse it.
+type PayloadTransform struct {
+	name     string
+	encode   func([]byte) ([]byte, error)
+	decode   func([]byte) ([]byte, error)
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+}
+
+// Name implements RowStage.
+func (t *PayloadTransform) Name() string {
+	return t.name
+}
+
+// Process implements RowStage: transforms each row's SOURCE in place.
+func (t *PayloadTransform) Process(rows []RowForDB) ([]RowForDB, error) {
+	for i := range rows {
+		msg := rows[i].JSONMessage
+		start, end, ok := sourceBounds(msg)
+		if !ok {
+			continue
+		}
+		out, err := t.encode([]byte(msg[start:end]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		enc := t.name + ":" + base64.StdEncoding.EncodeToString(out)
+		t.bytesIn.Add(int64(end - start))
+		t.bytesOut.Add(int64(len(enc)))
+		rows[i].JSONMessage = msg[:start] + enc + msg[end:]
+	}
+	return rows, nil
+}
+
+// sourceBounds returns the byte range of SOURCE's value in msg.
+func sourceBounds(msg string) (int, int, bool) {
+	at := strings.Index(msg, sourceMember)
+	if at < 0 {
+		return 0, 0, false
+	}
+	start := at + len(sourceMember)
+	n := strings.IndexByte(msg[start:], '"')
+	if n < 0 {
+		return 0, 0, false
+	}
+	return start, start + n, true
+}
+
+// PayloadTransforms is the ordered chain configured for a run.
+type PayloadTransforms []*PayloadTransform
+
+// ValidatePayloadTransforms checks a comma-separated transform list without building the transforms.
+func ValidatePayloadTransforms(spec string) error {
+	if spec == "" {
+		return nil
+	}
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(name) {
+		case TransformGzip, TransformZstd, TransformAESGCM:
+		default:
+			return fmt.Errorf("unknown payload transform %q (want gzip, zstd or aes-gcm)", name)
+		}
+	}
+	return nil
+}
+
+// NewPayloadTransforms parses a comma-separated list of transform names. The AES-GCM key is PAYLOAD_KEY
+// (64 hex chars); without it a random key is generated, which makes the rows unreadable after the run.
+func NewPayloadTransforms(spec string) (PayloadTransforms, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	var out PayloadTransforms
+	for _, name := range strings.Split(spec, ",") {
+		t := &PayloadTransform{name: strings.TrimSpace(name)}
+		switch t.name {
+		case TransformGzip:
+			t.encode, t.decode = gzipEncode, gzipDecode
+		case TransformZstd:
+			enc, err := zstd.NewWriter(nil)
+			if err != nil {
+				return nil, err
+			}
+			dec, err := zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+			t.encode = func(b []byte) ([]byte, error) { return enc.EncodeAll(b, nil), nil }
+			t.decode = func(b []byte) ([]byte, error) { return dec.DecodeAll(b, nil) }
+		case TransformAESGCM:
+			aead, err := payloadAEAD()
+			if err != nil {
+				return nil, err
+			}
+			t.encode = func(b []byte) ([]byte, error) {
+				nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(b)+aead.Overhead())
+				if _, err := rand.Read(nonce); err != nil {
+					return nil, err
+				}
+				return aead.Seal(nonce, nonce, b, nil), nil
+			}
+			t.decode = func(b []byte) ([]byte, error) {
+				if len(b) < aead.NonceSize() {
+					return nil, errors.New("ciphertext shorter than nonce")
+				}
+				return aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+			}
+		default:
+			return nil, fmt.Errorf("unknown payload transform %q (want gzip, zstd or aes-gcm)", t.name)
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func payloadAEAD() (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	if k := os.Getenv("PAYLOAD_KEY"); k != "" {
+		b, err := hex.DecodeString(k)
+		if err != nil || len(b) != 32 {
+			return nil, errors.New("PAYLOAD_KEY must be 64 hex characters (AES-256)")
+		}
+		key = b
+	} else {
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		log.Printf("WARNING: PAYLOAD_KEY not set; encrypting SOURCE with a random per-run key (rows are unreadable after this run)")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func gzipEncode(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gzipDecode(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// Decode reverses the chain on a SOURCE value read back from the database, checking each layer's name.
+func (ts PayloadTransforms) Decode(source string) (string, error) {
+	for i := len(ts) - 1; i >= 0; i-- {
+		t := ts[i]
+		enc, ok := strings.CutPrefix(source, t.name+":")
+		if !ok {
+			return "", fmt.Errorf("SOURCE is not %s-encoded", t.name)
+		}
+		raw, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", t.name, err)
+		}
+		out, err := t.decode(raw)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", t.name, err)
+		}
+		source = string(out)
+	}
+	return source, nil
+}
+
+// TransformStats is one transform's effect on SOURCE size over the run.
+type TransformStats struct {
+	Name     string  `json:"name"`
+	BytesIn  int64   `json:"bytes_in"`
+	BytesOut int64   `json:"bytes_out"`
+	Ratio    float64 `json:"ratio"` // BytesOut / BytesIn
+}
+
+// Stats returns per-transform size totals (nil when no transforms ran).
+func (ts PayloadTransforms) Stats() []TransformStats {
+	var out []TransformStats
+	for _, t := range ts {
+		in, o := t.bytesIn.Load(), t.bytesOut.Load()
+		if in == 0 {
+			continue
+		}
+		out = append(out, TransformStats{Name: t.name, BytesIn: in, BytesOut: o, Ratio: float64(o) / float64(in)})
+	}
+	return out
+}
+
+// logTransforms logs the size impact of each transform; CPU cost is in the pipeline stage table.
+func logTransforms(stats []TransformStats) {
+	if len(stats) == 0 {
+		return
+	}
+	log.Printf("Payload transforms (SOURCE size; stored as base64):")
+	for _, s := range stats {
+		log.Printf("  %-8s %12s -> %12s (%.1f%%)", s.Name, FormatBytes(s.BytesIn), FormatBytes(s.BytesOut), s.Ratio*100)
+	}
+}
@@ -0,0 +1,194 @@
+package benchmarkgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verification: with Config.Verify, pk lookups fetch a few mapped columns and compare them with the record the
+// generator built for the MRN, so a column mapping bug shows up as mismatches instead of passing as "1 row".
+
+// VerifyColumns are the column values a verified lookup compares; NULL reads as "".
+type VerifyColumns struct {
+	PatientID   string
+	LastName    string
+	FirstName   string
+	DateOfBirth string
+	Checksum    string
+}
+
+// ColumnFetcher is implemented by Queriers that can read VerifyColumns back; nil means no row for mrn.
+type ColumnFetcher interface {
+	FetchVerifyColumns(ctx context.Context, mrn string) (*VerifyColumns, error)
+}
+
+// maxVerifySamples bounds the mismatches kept verbatim for the report.
+const maxVerifySamples = 10
+
+// VerifyReport summarizes the verified lookups.
+type VerifyReport struct {
+	Checked    int64            `json:"checked"`
+	Missing    int64            `json:"missing"`    // no row for the MRN
+	Mismatched int64            `json:"mismatched"` // row found but at least one column differs
+	ByColumn   map[string]int64 `json:"by_column,omitempty"`
+	Samples    []string         `json:"samples,omitempty"` // first mismatches, "MRN column: got X, want Y"
+}
+
+// verifyStats accumulates verified lookups from all query workers, process-wide like the other query stats.
+var verifyStats struct {
+	mu  sync.Mutex
+	rep VerifyReport
+}
+
+// expectedColumns is what the generator wrote for mrn, except Checksum, which depends on the record's SOURCE; ok
+// is false for MRNs it did not generate.
+func expectedColumns(mrn string) (VerifyColumns, bool) {
+	ordinal, err := strconv.Atoi(strings.TrimPrefix(mrn, "MRN-"))
+	if err != nil || !strings.HasPrefix(mrn, "MRN-") {
+		return VerifyColumns{}, false
+	}
+	p := generatePatient(ordinal, true, 0)
+	return VerifyColumns{
+		PatientID:   p.PatientID,
+		LastName:    p.LastName,
+		FirstName:   p.FirstName,
+		DateOfBirth: p.DateOfBirth,
+	}, true
+}
+
+// generatedChecksum reports whether checksum is one the generator writes for want's patient, with any pooled SOURCE.
+func generatedChecksum(checksum string, want VerifyColumns) bool {
+	mrn := mrnForPatientID(want.PatientID)
+	for _, sum := range payloadSums {
+		if checksum == recordChecksum(want.PatientID, mrn, want.LastName, want.FirstName, want.DateOfBirth, sum) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffColumns returns the differing columns of got against want as name → "got X, want Y"; the checksum must be
+// one the generator writes for the patient.
+func diffColumns(got, want VerifyColumns) map[string]string {
+	diffs := make(map[string]string)
+	check := func(name, g, w string) {
+		if g != w {
+			diffs[name] = fmt.Sprintf("got %q, want %q", g, w)
+		}
+	}
+	check("patient_id", got.PatientID, want.PatientID)
+	check("last_name", got.LastName, want.LastName)
+	check("first_name", got.FirstName, want.FirstName)
+	check("date_of_birth", got.DateOfBirth, want.DateOfBirth)
+	if !generatedChecksum(got.Checksum, want) {
+		diffs["checksum"] = fmt.Sprintf("got %q, not a generated checksum for the record", got.Checksum)
+	}
+	return diffs
+}
+
+// recordVerify adds one verified lookup: got is nil when the row was missing.
+func recordVerify(mrn string, got *VerifyColumns, diffs map[string]string) {
+	verifyStats.mu.Lock()
+	defer verifyStats.mu.Unlock()
+	rep := &verifyStats.rep
+	rep.Checked++
+	if got == nil {
+		rep.Missing++
+		return
+	}
+	if len(diffs) == 0 {
+		return
+	}
+	rep.Mismatched++
+	if rep.ByColumn == nil {
+		rep.ByColumn = make(map[string]int64)
+	}
+	cols := make([]string, 0, len(diffs))
+	for col := range diffs {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for _, col := range cols {
+		rep.ByColumn[col]++
+		if len(rep.Samples) < maxVerifySamples {
+			rep.Samples = append(rep.Samples, mrn+" "+col+": "+diffs[col])
+		}
+	}
+}
+
+// runVerify is the pk lookup with Config.Verify: it fetches VerifyColumns and fails the query when the row is
+// missing or differs from the generated record.
+func (qr *QueryRunner) runVerify(ctx context.Context, q Querier, job *QueryJob) (count int, failed int, latency time.Duration) {
+	var got *VerifyColumns
+	err := errVerifyUnsupported
+	if cf, ok := q.(ColumnFetcher); ok {
+		t0 := time.Now()
+		got, err = cf.FetchVerifyColumns(ctx, job.MRN)
+		latency = time.Since(t0)
+	}
+	AddError(ErrOpQuery, err)
+	if err != nil {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("Verify fetch failed for MEDICAL_RECORD_NUMBER=%s: %v", job.MRN, err)
+		}
+		return 1, failed, latency
+	}
+	want, ok := expectedColumns(job.MRN)
+	if !ok {
+		return 1, failed, latency // not a generated MRN: nothing to compare against
+	}
+	var diffs map[string]string
+	if got != nil {
+		diffs = diffColumns(*got, want)
+	}
+	recordVerify(job.MRN, got, diffs)
+	if got == nil || len(diffs) > 0 {
+		failed++
+		if !qr.Opts.IgnoreSelectErrors {
+			log.Printf("Verify mismatch for MEDICAL_RECORD_NUMBER=%s: row found %v, differences %v", job.MRN, got != nil, diffs)
+		}
+	}
+	return 1, failed, latency
+}
+
+// loadVerify returns the verification report, or nil when no lookup was verified.
+func loadVerify() *VerifyReport {
+	verifyStats.mu.Lock()
+	defer verifyStats.mu.Unlock()
+	if verifyStats.rep.Checked == 0 {
+		return nil
+	}
+	rep := verifyStats.rep
+	rep.ByColumn = make(map[string]int64, len(verifyStats.rep.ByColumn))
+	for k, v := range verifyStats.rep.ByColumn {
+		rep.ByColumn[k] = v
+	}
+	rep.Samples = append([]string(nil), verifyStats.rep.Samples...)
+	return &rep
+}
+
+// logVerify logs the verification results (only when lookups were verified).
+func logVerify(rep *VerifyReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Verify: %d lookups checked, %d missing, %d mismatched", rep.Checked, rep.Missing, rep.Mismatched)
+	cols := make([]string, 0, len(rep.ByColumn))
+	for col := range rep.ByColumn {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for _, col := range cols {
+		log.Printf("  %-14s %d mismatches", col, rep.ByColumn[col])
+	}
+	for _, s := range rep.Samples {
+		log.Printf("  e.g. %s", s)
+	}
+}
@@ -0,0 +1,57 @@
+package benchmarkgo
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Visibility lag: an acknowledged insert is not necessarily readable yet (ClickHouse replicas fetch parts
+// asynchronously and the Distributed table may route the read elsewhere; a Postgres streaming replica replays
+// WAL behind the primary). Backends that measure it poll each queried MRN from insert completion until it is
+// first returned, and report the delta here.
+
+// VisibilityReport is the insert-completion → first-successful-read lag distribution.
+type VisibilityReport struct {
+	Visible int64               `json:"visible"` // records found before the deadline
+	Missing int64               `json:"missing"` // records still not found at the deadline
+	Lag     *LatencyPercentiles `json:"lag,omitempty"`
+}
+
+var (
+	visibilityLatencies = newLatencyRecorder()
+	visibilityMissing   atomic.Int64
+)
+
+// AddVisibilityLag records one polled record: lag is the time from insert completion until the record was
+// first visible; ok is false when it was still not visible at the deadline.
+func AddVisibilityLag(lag time.Duration, ok bool) {
+	if !ok {
+		visibilityMissing.Add(1)
+		return
+	}
+	visibilityLatencies.record(lag)
+}
+
+// loadVisibility summarizes the visibility samples; nil unless a backend polled for visibility.
+func loadVisibility() *VisibilityReport {
+	lag := visibilityLatencies.total()
+	missing := visibilityMissing.Load()
+	if lag == nil && missing == 0 {
+		return nil
+	}
+	rep := &VisibilityReport{Missing: missing, Lag: lag}
+	if lag != nil {
+		rep.Visible = lag.Count
+	}
+	return rep
+}
+
+// logVisibility logs the visibility-lag percentiles and how many records never became visible.
+func logVisibility(rep *VisibilityReport) {
+	if rep == nil {
+		return
+	}
+	log.Printf("Visibility lag (insert ack → first read): %d visible, %d missing at deadline | %s",
+		rep.Visible, rep.Missing, rep.Lag)
+}
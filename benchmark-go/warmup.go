@@ -0,0 +1,72 @@
+package benchmarkgo
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Excluded warmup (Config.WarmupSec): the first seconds of load run normally but the summary starts from the end
+// of the warmup phase, so ClickHouse merges and Postgres cache warm-up do not skew it. Interval logs still show
+// the warmup, tagged as such. Error, warning, session and result-set breakdowns still cover the whole run.
+
+// warmupExcluded is set while an excluded warmup is configured; the progress log tags its intervals.
+var warmupExcluded atomic.Bool
+
+// warmupLength is the warmup phase length: Config.WarmupSec when set, else phaseWarmup of the run duration.
+func (r *LoadRunner) warmupLength() time.Duration {
+	if r.Config.WarmupSec > 0 {
+		return time.Duration(r.Config.WarmupSec * float64(time.Second))
+	}
+	return phaseWarmup(time.Duration(r.Config.DurationSec * float64(time.Second)))
+}
+
+// endWarmup enters the steady phase; with an excluded warmup it also records the counters to subtract from the
+// final snapshot and restarts the run-long latency histograms.
+func (r *LoadRunner) endWarmup() {
+	enterPhase(PhaseSteady)
+	if r.Config.WarmupSec <= 0 {
+		return
+	}
+	base := loadSnapshot()
+	for _, l := range []*latencyRecorder{insertLatencies, queryLatencies, endToEndLatencies} {
+		l.resetRun()
+	}
+	r.warmupMu.Lock()
+	r.warmupBase = &base
+	r.statsStart = time.Now()
+	r.warmupMu.Unlock()
+	log.Printf("Warmup finished after %gs (%d rows, %d queries): excluded from the summary", r.Config.WarmupSec, int(base.Inserted.Total), int(base.Queries.Count))
+}
+
+// sinceWarmup returns s with the warmup's insert and query counters subtracted; s is unchanged when the run
+// ended before the warmup did.
+func (r *LoadRunner) sinceWarmup(s Snapshot) Snapshot {
+	r.warmupMu.Lock()
+	base := r.warmupBase
+	r.warmupMu.Unlock()
+	if base == nil {
+		return s
+	}
+	in, b := &s.Inserted, base.Inserted
+	in.Total -= b.Total
+	in.Attempted -= b.Attempted
+	in.Originals -= b.Originals
+	in.Duplicates -= b.Duplicates
+	in.TotalInsertLatencySec -= b.TotalInsertLatencySec
+	in.InsertStatements -= b.InsertStatements
+	in.Bytes -= b.Bytes
+	in.ServerSec -= b.ServerSec
+	in.ServerTimedBatches -= b.ServerTimedBatches
+	in.Postgres1 -= b.Postgres1
+	in.Postgres2 -= b.Postgres2
+	if in.DBInserted != nil && b.DBInserted != nil {
+		dbInserted, dbUpdated := *in.DBInserted-*b.DBInserted, *in.DBUpdated-*b.DBUpdated
+		in.DBInserted, in.DBUpdated = &dbInserted, &dbUpdated
+	}
+	q, bq := &s.Queries, base.Queries
+	q.Count -= bq.Count
+	q.TotalLatencySec -= bq.TotalLatencySec
+	q.FailedCount -= bq.FailedCount
+	return s
+}
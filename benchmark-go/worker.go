@@ -1,10 +1,12 @@
 package benchmarkgo
 
 import (
-	"encoding/json"
+	"context"
 	"log"
-	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RowForDB is (patient_id, message_type, json_message) for insert.
@@ -18,18 +20,20 @@ type RowForDB struct {
 type InsertBackend interface {
 	GetConn() interface{}
 	ReleaseConn(interface{})
-	// InsertBatch returns (rowsInserted, statementCount, error). queryHint is the prepared hint string set by the producer, prepended to the INSERT.
-	InsertBatch(conn interface{}, rows []RowForDB, queryHint string) (int, int, error)
+	// InsertBatch returns what the database reported for the batch. queryHint is the prepared hint string set by the producer, prepended to the INSERT.
+	InsertBatch(conn interface{}, rows []RowForDB, queryHint string) (InsertResult, error)
 }
 
 // InsertWorker holds state for one insert worker goroutine. Index identifies this worker (0-based).
+// Each batch passes through the batch, RowStage, insert and enqueue stages of Pipeline on this goroutine.
 type InsertWorker struct {
 	Index            int
 	Backend          InsertBackend
 	WorkerQueue      <-chan *InsertPair
 	QueryQueue       chan *QueryJob
 	QueriesPerRecord int
-	ExitWg           *sync.WaitGroup
+	Pipeline         *Pipeline
+	breaker          *breaker // nil unless Config.Breaker is enabled
 }
 
 // NewInsertWorker builds an InsertWorker with the given index and config.
@@ -39,7 +43,7 @@ func NewInsertWorker(
 	workerQueue <-chan *InsertPair,
 	queryQueue chan *QueryJob,
 	queriesPerRecord int,
-	exitWg *sync.WaitGroup,
+	pipeline *Pipeline,
 ) *InsertWorker {
 	return &InsertWorker{
 		Index:            index,
@@ -47,15 +51,12 @@ func NewInsertWorker(
 		WorkerQueue:      workerQueue,
 		QueryQueue:       queryQueue,
 		QueriesPerRecord: queriesPerRecord,
-		ExitWg:           exitWg,
+		Pipeline:         pipeline,
 	}
 }
 
 // Run consumes pairs from the worker queue and inserts until the queue is closed.
 func (w *InsertWorker) Run() {
-	if w.ExitWg != nil {
-		defer w.ExitWg.Done()
-	}
 	for pair := range w.WorkerQueue {
 		w.flushPair(pair)
 	}
@@ -68,16 +69,32 @@ func (w *InsertWorker) flushPair(pair *InsertPair) {
 	if len(pair.Originals)+len(pair.Duplicates) == 0 {
 		return
 	}
+	if !w.breaker.allow() {
+		shed(len(pair.Originals) + len(pair.Duplicates))
+		return
+	}
 
 	var totalRows, totalOriginals, totalDuplicates, totalStatements int
 	var totalLatencySec float64
+	ctx, span := tracer.Start(context.Background(), "worker.flush", trace.WithAttributes(
+		attribute.Int("worker.index", w.Index),
+		attribute.Int("batch.originals", len(pair.Originals)),
+		attribute.Int("batch.duplicates", len(pair.Duplicates)),
+	))
+	defer span.End()
 
 	// Use a separate connection per batch when we have both originals and duplicates,
 	// so we never send two hint + INSERT on the same connection back-to-back (optional; hint works in transaction).
+	kind := BatchWhole
+	if len(pair.Duplicates) > 0 {
+		kind = BatchOriginals
+	}
 	if len(pair.Originals) > 0 {
-		conn := w.Backend.GetConn()
-		n, nOrig, nDup, stmts, lat := w.insertBatch(conn, pair.Originals, pair.QueryHint)
+		conn := w.getConn(ctx)
+		n, nOrig, nDup, stmts, lat := w.insertBatch(ctx, conn, pair.Originals, pair.QueryHint, kind)
 		w.Backend.ReleaseConn(conn)
+		w.breaker.record(w.Index, nOrig+nDup > 0, time.Duration(lat*float64(time.Second)))
+		recordEndToEnd(pair.Intended, nOrig+nDup)
 		totalRows += n
 		totalOriginals += nOrig
 		totalDuplicates += nDup
@@ -85,9 +102,11 @@ func (w *InsertWorker) flushPair(pair *InsertPair) {
 		totalLatencySec += lat
 	}
 	if len(pair.Duplicates) > 0 {
-		conn := w.Backend.GetConn()
-		n, nOrig, nDup, stmts, lat := w.insertBatch(conn, pair.Duplicates, pair.QueryHint)
+		conn := w.getConn(ctx)
+		n, nOrig, nDup, stmts, lat := w.insertBatch(ctx, conn, pair.Duplicates, pair.QueryHint, BatchDuplicates)
 		w.Backend.ReleaseConn(conn)
+		w.breaker.record(w.Index, nOrig+nDup > 0, time.Duration(lat*float64(time.Second)))
+		recordEndToEnd(pair.Intended, nOrig+nDup)
 		totalRows += n
 		totalOriginals += nOrig
 		totalDuplicates += nDup
@@ -101,54 +120,92 @@ func (w *InsertWorker) flushPair(pair *InsertPair) {
 		stmts64 = 1
 	}
 	AddInsert(int64(totalRows), int64(totalOriginals), int64(totalDuplicates), latencyMicros, stmts64)
+	noteCommitted(totalOriginals + totalDuplicates)
+}
+
+// getConn acquires a backend connection, recording the wait.
+func (w *InsertWorker) getConn(ctx context.Context) interface{} {
+	_, span := tracer.Start(ctx, "GetConn")
+	t0 := time.Now()
+	conn := w.Backend.GetConn()
+	insertPoolWaitMicros.Add(time.Since(t0).Microseconds())
+	span.End()
+	return conn
 }
 
-func (w *InsertWorker) insertBatch(conn interface{}, batch []*Record, queryHint string) (n int, nOriginals int, nDuplicates int, statements int, latencySec float64) {
+// insertBatch runs one batch through the batch stage (payload expansion), the RowStages, the insert stage
+// and the enqueue stage (query jobs for the inserted records). kind is recorded with the statement's size.
+func (w *InsertWorker) insertBatch(ctx context.Context, conn interface{}, batch []*Record, queryHint string, kind string) (n int, nOriginals int, nDuplicates int, statements int, latencySec float64) {
+	t0 := time.Now()
 	rows := make([]RowForDB, len(batch))
 	for i, r := range batch {
-		rows[i] = RowForDB{r.PatientID, r.MessageType, r.JSONMessage}
+		rows[i] = RowForDB{r.PatientID, r.MessageType, ExpandPayload(r.JSONMessage, r.PayloadIndex)}
 	}
-	t0 := time.Now()
-	var err error
-	n, statements, err = w.Backend.InsertBatch(conn, rows, queryHint)
-	latencySec = time.Since(t0).Seconds()
+	w.Pipeline.Record(StageBatch, len(rows), time.Since(t0), 0)
+	rows, err := w.Pipeline.processRows(rows)
+	if err != nil {
+		log.Printf("Row stage error: %v", err)
+		AddError(ErrOpInsert, err)
+		return 0, 0, 0, 0, 0
+	}
+	AddInsertAttempted(int64(len(rows)))
+	_, span := tracer.Start(ctx, "InsertBatch", trace.WithAttributes(
+		attribute.Int("batch.rows", len(rows)),
+		attribute.String("batch.kind", kind),
+	))
+	t0 = time.Now()
+	res, err := w.Backend.InsertBatch(conn, rows, queryHint)
+	latency := time.Since(t0)
+	span.SetAttributes(attribute.Int("db.statements", res.Statements))
+	endSpan(span, err)
+	noteInsertLatency(latency)
+	recordBatch(kind, len(rows), latency, err == nil)
+	n, statements, latencySec = res.Rows, res.Statements, latency.Seconds()
 	if err != nil {
+		w.Pipeline.Record(StageInsert, len(rows), latency, len(rows))
+		reproBatch(rows)
 		log.Printf("InsertBatch error: %v", err)
+		AddError(ErrOpInsert, err)
 		return n, 0, 0, statements, latencySec
 	}
+	w.Pipeline.Record(StageInsert, len(rows), latency, 0)
+	insertLatencies.record(latency)
+	bytes := res.Bytes
+	if bytes == 0 {
+		for _, r := range rows {
+			bytes += int64(len(r.JSONMessage))
+		}
+	}
+	AddInsertBytes(bytes)
+	addInsertResult(res)
 	for _, r := range batch {
 		if r.IsOriginal {
 			nOriginals++
 		}
 	}
 	nDuplicates = len(batch) - nOriginals
+	noteLoaded(batch)
 	if w.QueriesPerRecord > 0 {
 		insertTime := time.Now()
-		for _, mrn := range mrnsFromBatch(batch) {
-			w.QueryQueue <- &QueryJob{MRN: mrn, InsertTime: insertTime}
+		jobs := queryJobsFromBatch(batch, insertTime)
+		w.Pipeline.Record(StageEnqueue, len(batch), time.Since(insertTime), 0)
+		t1 := time.Now()
+		for _, job := range jobs {
+			w.QueryQueue <- job
 		}
+		queryQueueBlockedMicros.Add(time.Since(t1).Microseconds())
 	}
 	return n, nOriginals, nDuplicates, statements, latencySec
 }
 
-func mrnsFromBatch(batch []*Record) []string {
-	var mrns []string
+// queryJobsFromBatch builds one QueryJob per record, with the MEDICAL_RECORD_NUMBER generatePatient pairs with its
+// patient ID (no need to decode the JSON message).
+func queryJobsFromBatch(batch []*Record, insertTime time.Time) []*QueryJob {
+	jobs := make([]*QueryJob, 0, len(batch))
 	for _, rec := range batch {
-		if rec == nil {
-			continue
-		}
-		var m map[string]interface{}
-		if err := json.Unmarshal([]byte(rec.JSONMessage), &m); err != nil {
-			log.Printf("query queue: could not get MEDICAL_RECORD_NUMBER from record, skipping: %v", err)
-			continue
-		}
-		v, _ := m["MEDICAL_RECORD_NUMBER"]
-		s, _ := v.(string)
-		if s != "" {
-			mrns = append(mrns, s)
-		} else {
-			log.Printf("query queue: MEDICAL_RECORD_NUMBER is empty, skipping")
+		if rec != nil {
+			jobs = append(jobs, &QueryJob{MRN: mrnForPatientID(rec.PatientID), PatientID: rec.PatientID, InsertTime: insertTime})
 		}
 	}
-	return mrns
+	return jobs
 }
@@ -0,0 +1,292 @@
+// Package yugabyte runs the benchmark against YugabyteDB YSQL. It reuses the postgres package's pgx statements
+// and spreads connections across tservers: one pool per tserver with round-robin checkout (load balancing), or a
+// single multi-host pool that fails over in order. Query connections can read from followers.
+package yugabyte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultHosts    = "yugabyte:5433"
+	defaultUser     = "yugabyte"
+	defaultPassword = "yugabyte"
+	defaultDatabase = "yugabyte"
+
+	// maxInsertRetries bounds retries of a batch that hit a serialization failure (concurrent upserts of one MRN).
+	maxInsertRetries             = 5
+	sqlstateSerializationFailure = "40001"
+)
+
+// Topology selects how connections are spread across tservers.
+type Topology struct {
+	// LoadBalance opens one pool per tserver and checks connections out round-robin; otherwise one pool
+	// connects to the first reachable host in the list.
+	LoadBalance bool
+	// ReadFromFollowers sets yb_read_from_followers on query connections (which must be read-only),
+	// so lookups may be served by the nearest replica up to FollowerStalenessMs behind the leader.
+	ReadFromFollowers   bool
+	FollowerStalenessMs int
+}
+
+// pools is a set of pools checked out round-robin.
+type pools struct {
+	list []*pgxpool.Pool
+	next atomic.Uint64
+}
+
+func (p *pools) acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	i := p.next.Add(1) - 1
+	return p.list[i%uint64(len(p.list))].Acquire(ctx)
+}
+
+func (p *pools) close() {
+	for _, pool := range p.list {
+		pool.Close()
+	}
+	p.list = nil
+}
+
+// Backend implements benchmarkgo.InsertBackend over the insert pools.
+type Backend struct {
+	pools *pools
+}
+
+// GetConn acquires a connection from the next pool.
+func (b *Backend) GetConn() interface{} {
+	conn, err := b.pools.acquire(context.Background())
+	if err != nil {
+		log.Printf("yugabyte Acquire: %v", err)
+		return nil
+	}
+	return conn
+}
+
+// ReleaseConn returns the connection to its pool.
+func (b *Backend) ReleaseConn(c interface{}) {
+	if conn, ok := c.(*pgxpool.Conn); ok {
+		conn.Release()
+	}
+}
+
+// InsertBatch upserts rows, retrying serialization failures.
+func (b *Backend) InsertBatch(conn interface{}, rows []benchmarkgo.RowForDB, queryHint string) (benchmarkgo.InsertResult, error) {
+	c, ok := conn.(*pgxpool.Conn)
+	if !ok {
+		return benchmarkgo.InsertResult{}, nil
+	}
+	_ = queryHint // pgbouncer routing hint; unused for Yugabyte
+	ctx := context.Background()
+	for attempt := 0; ; attempt++ {
+		n, err := postgres.InsertBatch(ctx, c, rows, postgres.SchemaOptions{})
+		if err == nil {
+			return benchmarkgo.InsertResult{Rows: n, Statements: 1}, nil
+		}
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != sqlstateSerializationFailure || attempt >= maxInsertRetries {
+			return benchmarkgo.InsertResult{Rows: n}, err
+		}
+		benchmarkgo.AddError(benchmarkgo.ErrOpInsertRetry, err)
+		time.Sleep(time.Duration(10<<attempt) * time.Millisecond)
+	}
+}
+
+// Context handles setup/teardown and query workers for YugabyteDB.
+type Context struct {
+	Topology Topology
+	insert   *pools
+	query    *pools
+}
+
+// hosts returns YUGABYTE_HOSTS (comma-separated host:port tservers) or the default.
+func hosts() []string {
+	v := os.Getenv("YUGABYTE_HOSTS")
+	if v == "" {
+		v = defaultHosts
+	}
+	var out []string
+	for _, h := range strings.Split(v, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func env(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// createPool opens a pool of size connections to the given host list (several hosts = ordered failover).
+// followerReads makes every connection a read-only follower-read session.
+func (c *Context) createPool(ctx context.Context, hostList []string, size int, followerReads bool) (*pgxpool.Pool, error) {
+	connStr := "postgres://" + env("YUGABYTE_USER", defaultUser) + ":" + env("YUGABYTE_PASSWORD", defaultPassword) +
+		"@" + strings.Join(hostList, ",") + "/" + env("YUGABYTE_DATABASE", defaultDatabase)
+	cfg, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxConns = int32(size)
+	cfg.MinConns = int32(size)
+	if followerReads {
+		staleness := c.Topology.FollowerStalenessMs
+		cfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, fmt.Sprintf(
+				"SET yb_read_from_followers = true; SET yb_follower_read_staleness_ms = %d; SET default_transaction_read_only = true", staleness))
+			return err
+		}
+	}
+	return pgxpool.NewWithConfig(ctx, cfg)
+}
+
+// createPools opens size connections in total, one pool per tserver when load balancing.
+func (c *Context) createPools(ctx context.Context, size int, followerReads bool) (*pools, error) {
+	hostList := hosts()
+	p := &pools{}
+	if !c.Topology.LoadBalance || len(hostList) == 1 {
+		pool, err := c.createPool(ctx, hostList, size, followerReads)
+		if err != nil {
+			return nil, err
+		}
+		p.list = append(p.list, pool)
+		return p, nil
+	}
+	per := (size + len(hostList) - 1) / len(hostList)
+	for _, h := range hostList {
+		pool, err := c.createPool(ctx, []string{h}, per, followerReads)
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		p.list = append(p.list, pool)
+	}
+	return p, nil
+}
+
+// Setup creates insert (and query) pools across the tservers and initializes the schema.
+func (c *Context) Setup(numWorkers, targetRPS int, queriesPerRecord int) (benchmarkgo.InsertBackend, error) {
+	if c.insert != nil {
+		log.Fatal("yugabyte Setup already called")
+	}
+	ctx := context.Background()
+	mode := "failover"
+	if c.Topology.LoadBalance {
+		mode = "load-balanced"
+	}
+	log.Printf("Creating YugabyteDB pools across %v (%s, %d insert connections)", hosts(), mode, numWorkers)
+	insert, err := c.createPools(ctx, numWorkers, false)
+	if err != nil {
+		return nil, err
+	}
+	c.insert = insert
+	if queriesPerRecord > 0 {
+		if c.Topology.ReadFromFollowers {
+			log.Printf("  + %d select connections reading from followers (staleness %d ms)", numWorkers, c.Topology.FollowerStalenessMs)
+		} else {
+			log.Printf("  + %d select connections for query workers", numWorkers)
+		}
+		c.query, err = c.createPools(ctx, numWorkers, c.Topology.ReadFromFollowers)
+		if err != nil {
+			c.Teardown()
+			return nil, err
+		}
+	}
+	if err := postgres.InitSchema(ctx, insert.list[0], postgres.SchemaOptions{}); err != nil {
+		c.Teardown()
+		return nil, err
+	}
+	log.Printf("Starting insertions (target %d rows/sec) ...", targetRPS)
+	return &Backend{pools: insert}, nil
+}
+
+// Teardown closes all pools.
+func (c *Context) Teardown() {
+	if c.query != nil {
+		c.query.close()
+		c.query = nil
+	}
+	if c.insert != nil {
+		c.insert.close()
+		c.insert = nil
+	}
+}
+
+// GetMaxPatientCounter returns the max patient ordinal in the DB (read from an insert connection, i.e. the leader).
+func (c *Context) GetMaxPatientCounter() (int, error) {
+	conn, err := c.insert.acquire(context.Background())
+	if err != nil {
+		return -1, err
+	}
+	defer conn.Release()
+	return postgres.GetMaxPatientCounter(context.Background(), conn)
+}
+
+// querier binds an acquired select connection to benchmarkgo.Querier.
+type querier struct {
+	conn *pgxpool.Conn
+}
+
+func (q querier) QueryByPrimaryKey(ctx context.Context, mrn string) (int, error) {
+	return postgres.QueryByPrimaryKey(ctx, q.conn, mrn, postgres.SchemaOptions{})
+}
+
+func (q querier) QueryByPrimaryKeyTimed(ctx context.Context, mrn string) (int, time.Duration, error) {
+	return postgres.QueryByPrimaryKeyTimed(ctx, q.conn, mrn, postgres.SchemaOptions{})
+}
+
+func (q querier) QueryResultSet(ctx context.Context, mrn string, limit int) (int, int64, error) {
+	return postgres.QueryResultSet(ctx, q.conn, mrn, limit)
+}
+
+func (q querier) QueryByPatientID(ctx context.Context, patientID string) (int, error) {
+	return postgres.QueryByPatientID(ctx, q.conn, patientID)
+}
+
+func (q querier) AggregateByPatientID(ctx context.Context, patientID string) (int, error) {
+	return postgres.AggregateByPatientID(ctx, q.conn, patientID)
+}
+
+// RunQueryWorker consumes from queryQueue, runs the configured queries per MRN, reports via benchmarkgo.AddQuery.
+// workerIndex is the 0-based index of this query worker.
+func (c *Context) RunQueryWorker(
+	workerIndex int,
+	queryQueue <-chan *benchmarkgo.QueryJob,
+	opts benchmarkgo.QueryOptions,
+) {
+	_ = workerIndex // reserved for logging/tracing
+	runner := benchmarkgo.NewQueryRunner(opts)
+	for job := range queryQueue {
+		if job == nil {
+			return
+		}
+		if opts.QueryDelaySec > 0 {
+			deadline := job.InsertTime.Add(time.Duration(opts.QueryDelaySec * float64(time.Second)))
+			if time.Now().Before(deadline) {
+				time.Sleep(time.Until(deadline))
+			}
+		}
+		conn, err := c.query.acquire(context.Background())
+		if err != nil {
+			continue
+		}
+		count, failed, latency := runner.Run(context.Background(), querier{conn}, job)
+		conn.Release()
+		benchmarkgo.AddQuery(int64(count), latency.Microseconds(), int64(failed))
+	}
+}
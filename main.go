@@ -4,16 +4,39 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"flag"
+	"fmt"
 	"io"
 	"log"
+	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/db-benchmarking/benchmark-go"
 	"github.com/db-benchmarking/benchmark-go/clickhouse"
+	"github.com/db-benchmarking/benchmark-go/druid"
+	"github.com/db-benchmarking/benchmark-go/dualwrite"
+	"github.com/db-benchmarking/benchmark-go/dynamodb"
+	"github.com/db-benchmarking/benchmark-go/httpsink"
+	"github.com/db-benchmarking/benchmark-go/kafka"
+	"github.com/db-benchmarking/benchmark-go/mysql"
 	"github.com/db-benchmarking/benchmark-go/postgres"
+	"github.com/db-benchmarking/benchmark-go/questdb"
+	"github.com/db-benchmarking/benchmark-go/redis"
+	"github.com/db-benchmarking/benchmark-go/singlestore"
+	"github.com/db-benchmarking/benchmark-go/sqlite"
+	"github.com/db-benchmarking/benchmark-go/tidb"
+	"github.com/db-benchmarking/benchmark-go/yugabyte"
 )
 
 // millisWriter prefixes each log line with timestamp in milliseconds (2006/01/02 15:04:05.000).
@@ -27,26 +50,335 @@ func (m *millisWriter) Write(p []byte) (n int, err error) {
 	return m.w.Write(p)
 }
 
+// totalRowsMaxSec is the run's time limit under --total-rows without an explicit --duration: a week, i.e. none
+// in practice.
+const totalRowsMaxSec = 7 * 24 * 3600
+
+// controlTokenEnv names the environment variable holding the control API's bearer token (Config.ControlToken).
+const controlTokenEnv = "LOADRUNNER_CONTROL_TOKEN"
+
+// optionalBackends are backends compiled in with build tags (see main_<name>.go), keyed by --database value.
+var optionalBackends = map[string]func() benchmarkgo.WorkerCtx{}
+
+// databaseNames lists the --database values available in this build.
+func databaseNames() []string {
+	names := []string{"postgres", "clickhouse", "redis", "tidb", "yugabyte", "questdb", "druid", "singlestore", "sqlite", "dynamodb", "kafka", "mysql", "dualwrite", "httpsink"}
+	builtin := len(names)
+	for name := range optionalBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names[builtin:])
+	return names
+}
+
 func main() {
 	log.SetFlags(0)
 	log.SetOutput(&millisWriter{w: os.Stdout})
 
-	database := flag.String("database", "", "postgres or clickhouse (required)")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "dashboards":
+			runDashboards(os.Args[2:])
+			return
+		case "microbench":
+			runMicrobench(os.Args[2:])
+			return
+		case "compare":
+			runCompare(os.Args[2:])
+			return
+		case "agent":
+			runAgent(os.Args[2:])
+			return
+		case "coordinate":
+			runCoordinate(os.Args[2:])
+			return
+		}
+	}
+
+	def := benchmarkgo.DefaultConfig("") // flag defaults
+	database := flag.String("database", "", strings.Join(databaseNames(), ", ")+" (required); a comma-separated list (e.g. postgres,clickhouse) runs the same stream against each target concurrently and compares them")
+	postgresReplicaHost := flag.String("postgres-replica-host", "", "Route query workers to this streaming read replica (host or host:port) and report the read-after-write lag histogram (postgres only, not with --pgbouncer-enabled)")
+	postgresReplicaTimeout := flag.Float64("postgres-replica-timeout", 10, "Seconds query workers poll --postgres-replica-host for a written MRN before counting it missing")
 	pgbouncerEnabled := flag.Bool("pgbouncer-enabled", false, "Use PgBouncer with postgres1/postgres2 aliases and pipeline mode for inserts (postgres only)")
-	duration := flag.Float64("duration", 60, "Run duration in seconds")
-	batchSize := flag.Int("batch-size", 100, "Rows per batch (producers enqueue full batches)")
-	workers := flag.Int("workers", 5, "Number of worker goroutines")
-	rowsPerSecond := flag.Int("rows-per-second", 1000, "Target insert rate (rows/sec)")
-	producers := flag.Int("producers", 2, "Number of producer goroutines (minimum 2)")
-	queriesPerRecord := flag.Int("queries-per-record", 10, "Primary-key queries per inserted record")
+	postgresDistribution := flag.String("postgres-distribution", "", "Shard hl7_messages across nodes: citus (create_distributed_table) or greenplum (DISTRIBUTED BY); reports per-shard insert distribution (postgres only; empty = auto-detect Citus)")
+	postgresTimescale := flag.Bool("postgres-timescale", false, "Create hl7_messages as a TimescaleDB hypertable on created_at with key (medical_record_number, created_at) (postgres only)")
+	duration := flag.Float64("duration", def.DurationSec, "Run duration in seconds")
+	totalRows := flag.Int("total-rows", 0, "Stop after generating exactly this many rows instead of at the end of --duration, e.g. to load a fixed-size dataset for later query-only runs; an explicit --duration still caps the run (0 = disabled)")
+	loadStepsFlag := flag.String("load-steps", "", "Stepped load for capacity search: RPS:SECONDS steps run in turn, e.g. 1000:60,2000:60,4000:60, reporting per-step throughput and latency (overrides --rows-per-second and --duration)")
+	findMax := flag.Bool("find-max", false, "Search for the highest rate whose insert p99 stays within --find-max-p99-ms: start at --rows-per-second, double each trial until one fails, then bisect; the run ends when the search converges (--duration caps it)")
+	findMaxP99 := flag.Float64("find-max-p99-ms", 100, "Insert batch p99 SLO in ms for --find-max")
+	findMaxStep := flag.Float64("find-max-step-sec", 30, "Seconds each --find-max trial rate is held")
+	arrival := flag.String("arrival", benchmarkgo.ArrivalFixed, "Arrival process for batches at the target rate: fixed (token bucket), uniform (gaps uniform in 0-2x the mean) or poisson (exponential gaps, bursty like a real feed)")
+	patternFlag := flag.String("pattern", "", "Modulate the target rate periodically between min×--rows-per-second and --rows-per-second: sine, square or sawtooth with optional period (seconds, default 300) and min (fraction, default 0), e.g. sine:period=300,min=0.2")
+	burstFlag := flag.String("burst", "", "Superimpose bursts on the target rate and report burst vs non-burst latency: rate=ROWS_PER_SEC,duration=D,every=D, e.g. rate=10000,duration=5s,every=60s")
+	mode := flag.String("mode", def.Mode, "What runs: insert-query (inserts plus --workload queries), insert-only (no query queue, workers or MRN extraction; --queries-per-record is ignored), closed (no target rate: --concurrency batches in flight, inserted as fast as the database allows, for peak throughput) or query-only (no inserts; point lookups of the MRNs already in the table at --rows-per-second queries/sec, with --query-type choosing the query)")
+	scenarioPath := flag.String("scenario", "", "Run the ordered phases of this YAML scenario file back to back (e.g. bulk load, then mixed, then query-only), each overriding the other flags, and compare the phases (results: one file per phase, <name>-<phase>.json)")
+	concurrency := flag.Int("concurrency", 64, "With --mode closed: batches in flight, one per insert worker (replaces --workers and --rows-per-second)")
+	workload := flag.String("workload", def.Workload, "Where queries come from: write-triggered (--queries-per-record lookups of each fresh insert) or mixed (query workers look up random loaded MRNs at their own rate, set by --read-ratio)")
+	keyDistFlag := flag.String("key-dist", benchmarkgo.KeyDistUniform, "Which loaded MRNs reads pick with --workload mixed or --mode query-only: uniform, zipfian[:s=SKEW] (hot patients scattered over the table) or latest[:s=SKEW] (the newest patients hottest); SKEW > 1, default 1.2")
+	readRatio := flag.Float64("read-ratio", def.ReadRatio, "With --workload mixed: reads / (reads + writes), e.g. 0.8 for an 80/20 read/write mix")
+	rampUpSec := flag.Float64("ramp-up-sec", 0, "Scale the target rate linearly from 0 to --rows-per-second over the first N seconds (0 = start at the target)")
+	rampDownSec := flag.Float64("ramp-down-sec", 0, "Scale the target rate linearly back to 0 over the last N seconds of --duration (0 = stop at the target)")
+	warmupSec := flag.Float64("warmup-sec", 0, "Run the first N seconds of --duration as warmup: inserts and queries execute and show in interval logs (tagged warmup) but are excluded from the summary stats (0 = include everything)")
+	batchSize := flag.Int("batch-size", def.BatchSize, "Rows per batch (producers enqueue full batches)")
+	workers := flag.Int("workers", def.Workers, "Number of worker goroutines")
+	rowsPerSecond := flag.Int("rows-per-second", def.TargetRPS, "Target insert rate (rows/sec)")
+	producers := flag.Int("producers", def.ProducerThreads, "Number of producer goroutines (minimum 2)")
+	queriesPerRecord := flag.Int("queries-per-record", def.QueriesPerRecord, "Primary-key queries per inserted record")
 	queryDelay := flag.Float64("query-delay", 0, "Fixed delay in ms before querying each record (0 = no delay)")
 	ignoreSelectErrors := flag.Bool("ignore-select-errors", false, "Do not log when primary-key query returns != 1 row (avoids console slowdown)")
-	duplicateRatio := flag.Float64("duplicate-ratio", 0.25, "Ratio of duplicate records (0-1)")
+	duplicateRatio := flag.Float64("duplicate-ratio", def.DuplicateRatio, "Ratio of duplicate records (0-1)")
+	duplicateLag := flag.Float64("duplicate-lag-sec", 0, "Duplicates re-send the original from about this many seconds earlier at --rows-per-second (between half and 1.5x), shuffled into later batches like real HL7 re-sends (0 = a random earlier original; otherwise at least two batches of rows)")
+	queryType := flag.String("query-type", def.QueryType, "Query type: pk (COUNT by MRN), resultset (full rows for an MRN range, LIMIT N), session (per-patient lookup, patient_id fetch, aggregate), range (a patient's rows over a created_at window), patient-id (rows by patient_id via its secondary index), name-dob (rows by last name, first name and date of birth; postgres adds an index for it), page-offset or page-keyset (a page of the name-ordered patient list by LIMIT/OFFSET or keyset; postgres adds an index for the order), checksum (the row with SOURCE, its CHECKSUM recomputed and compared to catch corruption); range, name-dob, page-* and checksum need postgres, clickhouse or a SQL backend")
+	querySelect := flag.String("query-select", def.PKSelect, "What pk queries select: count (COUNT(*) by MRN), columns (fetch and scan the row without SOURCE), or payload (the full row with its 2 MiB SOURCE, so result transfer is measured); columns and payload need postgres, clickhouse or a SQL backend")
+	verify := flag.Bool("verify", false, "pk queries fetch patient_id, last/first name, date of birth and checksum and compare them with the generated record; missing rows and mismatches fail the query and are reported (postgres, clickhouse or a SQL backend)")
+	pageSize := flag.Int("page-size", def.PageSize, "Rows per page for --query-type page-offset and page-keyset")
+	pageDepth := flag.Int("page-depth", def.PageDepth, "--query-type page-offset fetches a uniformly random page below this page number")
+	queryTypes := flag.String("query-types", "", "Query mix drawn per query instead of --query-type, as TYPE[:WEIGHT] pairs, e.g. pk,range (equal) or pk:1,range:4")
+	rangeWindow := flag.Float64("range-window-sec", def.RangeWindowSec, "Range queries fetch the patient's rows with created_at within this many seconds before now")
+	resultSetSizes := flag.String("result-set-sizes", "1,10,100", "Comma-separated LIMIT values cycled through by --query-type resultset")
+	sessionThink := flag.Float64("session-think-ms", 500, "Think time in ms between the queries of a --query-type session session")
+	queryThink := flag.Float64("query-think-time-ms", 0, "Think time in ms between a record's --queries-per-record lookups, modeling page refreshes instead of a tight loop (0 = back to back)")
+	queryJitter := flag.Float64("query-think-jitter-ms", 0, "Vary --query-think-time-ms uniformly by up to this many ms either way")
+	retentionAt := flag.Float64("retention-at", 0, "Seconds into the run to delete rows older than --retention-keep while ingestion continues (0 = disabled)")
+	retentionKeep := flag.Float64("retention-keep", 30, "Retention step keeps rows whose created_at is within this many seconds")
+	preloadRows := flag.Int("preload-rows", 0, "Before the measured run, bulk-load this many fresh patients (COPY on postgres, large native batches on clickhouse, big INSERT batches elsewhere) so queries hit a realistically sized table; excluded from the stats (0 = disabled)")
+	snapshotSave := flag.String("snapshot-save", "", "After the run, copy the table's state into this named snapshot, e.g. after a bulk-load phase (postgres, clickhouse)")
+	snapshotRestore := flag.String("snapshot-restore", "", "Before the run, replace the table's contents with this named snapshot so each experiment phase starts from identical data (postgres, clickhouse)")
+	gomaxprocs := flag.Int("gomaxprocs", 0, "Set GOMAXPROCS (0 = Go default)")
+	cpus := flag.String("cpus", "", "Pin the process to this CPU list, e.g. 0-3,6 (Linux only; empty = no pinning)")
+	gogc := flag.Int("gogc", 0, "Set GOGC percent (0 = Go default / GOGC env, negative = GC off)")
+	gomemlimit := flag.String("gomemlimit", "auto", "Go soft memory limit, e.g. 6GiB; auto = 90% of the cgroup memory limit unless GOMEMLIMIT is set; off = none")
+	clickhouseNameIndex := flag.Bool("clickhouse-name-index", false, "Add a bloom_filter skipping index on (LAST_NAME, FIRST_NAME, DATE_OF_BIRTH) for --query-type name-dob (clickhouse only)")
+	clickhouseTopology := flag.String("clickhouse-topology", clickhouse.TopologyCluster, "ClickHouse layout: cluster (ReplicatedReplacingMergeTree + Distributed, DDL ON CLUSTER) or single (one ReplacingMergeTree, e.g. laptop or ClickHouse Cloud) (clickhouse only)")
+	clickhouseProtocol := flag.String("clickhouse-protocol", clickhouse.ProtocolNative, "ClickHouse wire protocol: native (TCP, port 9000) or http (HTTP interface, port 8123) to quantify protocol overhead (clickhouse only)")
+	clickhousePartitionMinutes := flag.Int("clickhouse-partition-minutes", 0, "Partition the table by CREATED_AT into ranges this many minutes wide so the --retention-at step drops partitions (DROP PARTITION) instead of running a DELETE mutation (0 = unpartitioned; not with --snapshot-*; clickhouse only)")
+	clickhouseVisibility := flag.Float64("clickhouse-visibility-timeout", 0, "Poll each queried MRN with SELECT ... FINAL from insert completion until visible, up to this many seconds, and report the visibility-lag histogram (0 = disabled; needs --queries-per-record > 0; clickhouse only)")
+	conflictWriters := flag.Int("conflict-writers", 0, "Run this many extra writers upserting the same MRN set in random order to measure deadlocks/serialization failures (0 = disabled; postgres only)")
+	conflictKeys := flag.Int("conflict-keys", def.ConflictKeys, "Size of the MRN set shared by --conflict-writers")
+	conflictIsolation := flag.String("conflict-isolation", benchmarkgo.IsolationReadCommitted, "Isolation level of --conflict-writers transactions: read_committed, repeatable_read, serializable")
+	failoverWatch := flag.Bool("failover-watch", false, "Measure error window, recovery time to target rate and post-run data loss around an externally triggered failover (postgres, clickhouse)")
+	failoverHook := flag.String("failover-hook", "", "Shell command run --failover-at seconds into the run to trigger a failover (e.g. patronictl switchover, docker restart); implies --failover-watch")
+	failoverAt := flag.Float64("failover-at", 30, "Seconds into the run to execute --failover-hook")
+	dedupWatch := flag.Bool("dedup-watch", false, "After the run, poll count() vs count() FINAL until background merges have collapsed the duplicates (ReplacingMergeTree convergence time), then time OPTIMIZE TABLE ... FINAL (clickhouse)")
+	dedupPoll := flag.Float64("dedup-poll-sec", def.DedupPollSec, "--dedup-watch polls the row counts this often")
+	dedupWait := flag.Float64("dedup-wait-sec", def.DedupWaitSec, "--dedup-watch waits at most this long for background merges before running OPTIMIZE FINAL")
+	reconcile := flag.Bool("reconcile", false, "After the run, count the table's rows and generated MRNs and compare them with the inserts acknowledged during it, reporting missing or unacknowledged keys; ClickHouse also counts rows with FINAL (postgres, clickhouse or a SQL backend)")
+	costPerGBMonth := flag.Float64("cost-per-gb-month", 0, "Storage price in $/GB-month for the cost estimate (0 = omit storage)")
+	costPerVCPUHour := flag.Float64("cost-per-vcpu-hour", 0, "Compute price in $/vCPU-hour for the cost estimate (0 = omit compute)")
+	costVCPUs := flag.Float64("cost-vcpus", 0, "Database vCPUs billed for the run's duration in the cost estimate")
+	breakerFailures := flag.Int("breaker-failures", 0, "Circuit breaker: an insert worker stops sending after this many consecutive failed batches and sheds the records it dequeues for --breaker-pause-sec (0 = off)")
+	breakerSlowMs := flag.Float64("breaker-latency-ms", 0, "Circuit breaker: a batch slower than this counts as slow; --breaker-slow-batches consecutive slow batches open the breaker (0 = off)")
+	breakerSlowBatches := flag.Int("breaker-slow-batches", def.Breaker.SlowBatches, "Consecutive batches over --breaker-latency-ms that open the circuit breaker")
+	breakerPause := flag.Float64("breaker-pause-sec", def.Breaker.PauseSec, "How long an open circuit breaker sheds records before probing the database with one batch")
+	maxTotalRows := flag.Int64("max-total-rows", 0, "Stop the run once the table holds more than this many rows, checked every 15s (0 = unlimited)")
+	maxStorageGB := flag.Float64("max-storage-gb", 0, "Stop the run once the table's on-disk size exceeds this many GiB, checked every 15s (0 = unlimited; postgres/clickhouse)")
+	reproDir := flag.String("repro-dir", "", "Directory for failure repro bundles (error, batch sample, schema DDL, settings, driver/server versions); written on setup failure and per error code after --repro-after occurrences")
+	dutyOn := flag.Float64("duty-on", 0, "Duty cycle: seconds of load per active phase, alternating with --duty-idle seconds of no traffic to measure cold starts (0 = continuous)")
+	dutyIdle := flag.Float64("duty-idle", 0, "Duty cycle: seconds idle between active phases")
+	verifyGoodput := flag.Bool("verify-goodput", false, "Count generated MRNs before and after the load to verify goodput (acknowledged original records) against the table (postgres, clickhouse)")
+	dualWriteTimeout := flag.Float64("dual-write-timeout", dualwrite.DefaultVisibilityTimeout.Seconds(), "Seconds query workers poll Postgres and ClickHouse for a written record before counting it missing (dualwrite only)")
+	reproAfter := flag.Int("repro-after", def.Repro.After, "Occurrences of one error code before its repro bundle is written")
+	payloadTransform := flag.String("payload-transform", "", "Comma-separated client-side SOURCE transforms applied before insert, in order: gzip, zstd, aes-gcm (key from PAYLOAD_KEY, 64 hex chars); pk queries read SOURCE back and decode it (postgres/clickhouse)")
+	durability := flag.String("durability", "", "Insert acknowledgment level: off, local, on, remote_apply (Postgres synchronous_commit; ClickHouse insert_quorum 0/0/auto/majority; Kafka acks 0/1/all/all); empty = backend default")
+	liveConfig := flag.String("live-config", "", "JSON file watched during the run; changes to target_rps, queries_per_record, query_weights are applied live")
+	controlAddr := flag.String("control-addr", "", "Serve an HTTP control API on this address (e.g. localhost:7080): GET /stats, POST /settings (live config JSON), /pause, /resume, /stop; POSTs need the bearer token in $"+controlTokenEnv+" when set, else only loopback addresses are allowed; empty = disabled")
+	annotationsFile := flag.String("annotations-file", "", "File tailed during the run; each line appended (e.g. echo 'failover started' >> f) is recorded as a timeline annotation")
+	insertQueueSize := flag.Int("insert-queue-size", 0, "Producer (insert) queue capacity in batches (0 = auto: max(256, workers*20, producers*32))")
+	queryQueueSize := flag.Int("query-queue-size", 0, "Query queue capacity in records (0 = auto: max(workers*4, batch_size*workers*4, rows_per_second*4))")
+	mysqlEngine := flag.String("mysql-engine", mysql.EngineInnoDB, "Table storage engine: innodb, or columnstore for MariaDB ColumnStore (no keys; duplicates append) (mysql only)")
+	kafkaPartitions := flag.Int("kafka-partitions", 12, "Partitions when creating the topic (kafka only)")
+	kafkaCompression := flag.String("kafka-compression", "none", "Producer compression: none, gzip, snappy, lz4, zstd (kafka only)")
+	httpsinkURL := flag.String("httpsink-url", "", "Ingestion endpoint receiving each batch as an NDJSON POST; empty = HTTPSINK_URL (httpsink only)")
+	httpsinkTimeout := flag.Float64("httpsink-timeout", httpsink.DefaultTimeout.Seconds(), "Per-request timeout in seconds (httpsink only)")
+	dynamodbBilling := flag.String("dynamodb-billing", dynamodb.BillingOnDemand, "DynamoDB capacity mode when creating the table: on_demand or provisioned (dynamodb only)")
+	dynamodbRCU := flag.Int64("dynamodb-rcu", 1000, "Provisioned read capacity units for the table and its patient_id index (--dynamodb-billing provisioned)")
+	dynamodbWCU := flag.Int64("dynamodb-wcu", 1000, "Provisioned write capacity units for the table and its patient_id index (--dynamodb-billing provisioned)")
+	singlestoreTableType := flag.String("singlestore-table-type", singlestore.TableColumnstore, "SingleStore table type: columnstore or rowstore (in-memory) (singlestore only)")
+	tidbAutoRandom := flag.Bool("tidb-auto-random", false, "Key hl7_messages by a BIGINT AUTO_RANDOM id with a unique index on medical_record_number (tidb only)")
+	ybLoadBalance := flag.Bool("yb-load-balance", true, "Spread connections over every tserver in YUGABYTE_HOSTS with round-robin checkout; false = ordered failover (yugabyte only)")
+	ybFollowerReads := flag.Bool("yb-read-from-followers", false, "Serve query-worker reads from follower replicas via yb_read_from_followers (yugabyte only)")
+	ybFollowerStaleness := flag.Int("yb-follower-staleness-ms", 30000, "Max staleness of follower reads in ms (yb_follower_read_staleness_ms; yugabyte only)")
+	dbHost := flag.String("db-host", "", "Database host for postgres/clickhouse (overrides POSTGRES_HOST/CLICKHOUSE_HOST and the pgbouncer host)")
+	dbPort := flag.Int("db-port", 0, "Database port for postgres/clickhouse (overrides POSTGRES_PORT/CLICKHOUSE_PORT; 0 = backend default)")
+	dbName := flag.String("db-name", "", "Database name for postgres/clickhouse (overrides POSTGRES_DB/CLICKHOUSE_DB; default postgres)")
+	dbUser := flag.String("db-user", "", "Database user for postgres/clickhouse (overrides POSTGRES_USER/CLICKHOUSE_USER; default default)")
+	dbPasswordEnv := flag.String("db-password-env", "", "Read the database password from this environment variable (overrides POSTGRES_PASSWORD/CLICKHOUSE_PASSWORD)")
+	dbPasswordFile := flag.String("db-password-file", "", "Read the database password from this file, e.g. a mounted secret (trailing newline trimmed)")
+	queryHost := flag.String("query-host", "", "Host (or host:port) for query workers' connections, e.g. a read replica, while inserts use --db-host (postgres/clickhouse; overrides POSTGRES_QUERY_HOST/CLICKHOUSE_QUERY_HOST)")
+	clickhouseCluster := flag.String("clickhouse-cluster", "", "ClickHouse cluster for ON CLUSTER DDL and the Distributed table (overrides CLICKHOUSE_CLUSTER; default dev-cluster)")
+	poolSize := flag.Int("pool-size", 0, "Connections per pool for insert and for query workers (postgres, clickhouse, database/sql backends; 0 = one per worker)")
+	insertPoolSize := flag.Int("insert-pool-size", 0, "Insert pool connections (overrides --pool-size for inserts)")
+	queryPoolSize := flag.Int("query-pool-size", 0, "Query pool connections (overrides --pool-size for query workers)")
+	poolMaxIdle := flag.Int("pool-max-idle", 0, "Connections kept open while idle, per pool (postgres, database/sql backends; 0 = all)")
+	poolMaxLifetime := flag.Duration("pool-max-lifetime", 0, "Close and reopen pooled connections older than this, e.g. 5m (0 = never)")
+	tablePrefix := flag.String("table-prefix", "", "Prefix for the benchmark table and its indexes/partitions (e.g. run2_ → run2_hl7_messages) so concurrent runs can share one database")
+	patientCounter := flag.String("patient-counter", def.PatientCounter, "Patient ordinal allocation: max (start after max in DB; single runner), reserve (lease blocks from a DB counter; postgres/redis), static (interleave by --runner-id/--runner-count)")
+	runnerID := flag.Int("runner-id", 0, "This runner's index for --patient-counter=static (0-based)")
+	runnerCount := flag.Int("runner-count", def.RunnerCount, "Number of concurrent runners for --patient-counter=static")
+	latencySampleRate := flag.Float64("latency-sample-rate", 0, "Fraction of pk lookups (0-1) timed server-side to split latency into server vs network/queueing (postgres, clickhouse, yugabyte)")
+	pushURL := flag.String("push-url", "", "Push metrics every interval and at the end to this Pushgateway base URL or remote-write endpoint, for Jobs that finish before a scrape; empty = disabled")
+	pushFormat := flag.String("push-format", benchmarkgo.PushPushgateway, "Format for --push-url: pushgateway or remote-write")
+	pushJob := flag.String("push-job", "loadrunner", "job label of pushed metrics (instance is the hostname)")
+	pprofAddr := flag.String("pprof-addr", "", "Serve net/http/pprof (/debug/pprof/) and expvar (/debug/vars) on this address (e.g. :6060) to profile the benchmark during the run; empty = disabled")
+	metricsAddr := flag.String("metrics-addr", "", "Serve Prometheus metrics on this address (e.g. :9100) at /metrics; empty = disabled")
+	resultsJSON := flag.String("results-json", "", "Write run results (stats + effective settings) as JSON to this file (multi-target: one file per target, <name>-<target>.json)")
+	outputCSV := flag.String("output-csv", "", "Append each progress interval's inserted rows, queries and latencies as CSV rows to this file, e.g. timeline.csv (multi-target: one file per target, <name>-<target>.csv)")
+	otelEndpoint := flag.String("otel-endpoint", "", "Export OpenTelemetry spans for worker flushes, InsertBatch and QueryByPrimaryKey to this OTLP/HTTP collector URL, e.g. http://otel-collector:4318 (empty = tracing off)")
+	otelSampleRate := flag.Float64("otel-sample-rate", def.Tracing.SampleRate, "Fraction of flushes and lookups traced (0-1) with --otel-endpoint")
+	reportMD := flag.String("report-md", "", "Write the end-of-run summary (headline numbers, warmup/steady/drain phases, throughput sparkline, errors, hints) as Markdown to this file")
+	baseline := flag.String("baseline", "", "Results JSON of a baseline run; the process exits non-zero if throughput drops or insert/query p99 rises by more than --fail-on-regression")
+	failOnRegression := flag.String("fail-on-regression", "10%", "Regression threshold for --baseline, e.g. 10%")
+	serverMetricsInterval := flag.Float64("server-metrics-interval", 0, "Sample server statistics (pg_stat_database/pg_stat_bgwriter; ClickHouse system.metrics/events/asynchronous_metrics, merges, parts) every this many seconds into the results JSON (0 = disabled; postgres, clickhouse)")
+	seed := flag.Int64("seed", 0, "Seed for the generated stream (duplicate and payload choices); the same seed replays the same stream (0 = random, recorded in results)")
 	flag.Parse()
 
-	if *database != "postgres" && *database != "clickhouse" {
-		flag.Usage()
-		log.Fatal("--database must be postgres or clickhouse")
+	targets := strings.Split(*database, ",")
+	for i, t := range targets {
+		if !slices.Contains(databaseNames(), t) {
+			flag.Usage()
+			log.Fatalf("--database must be one or more of: %s", strings.Join(databaseNames(), ", "))
+		}
+		if slices.Contains(targets[:i], t) {
+			log.Fatalf("--database lists %s twice", t)
+		}
+	}
+	if len(targets) > 1 && *metricsAddr != "" {
+		log.Fatal("--metrics-addr cannot be used with multiple --database targets (each target would bind it)")
+	}
+	if err := benchmarkgo.ValidatePushFormat(*pushFormat); err != nil {
+		log.Fatalf("--push-format: %v", err)
+	}
+	if len(targets) > 1 && *controlAddr != "" {
+		log.Fatal("--control-addr cannot be used with multiple --database targets (each target would bind it)")
+	}
+	if (len(targets) > 1 || slices.Contains(targets, "dualwrite")) && (*dbHost != "" || *dbPort != 0) {
+		log.Fatal("--db-host and --db-port name one server; with several targets or dualwrite set POSTGRES_HOST/CLICKHOUSE_HOST etc. instead")
+	}
+	if (len(targets) > 1 || slices.Contains(targets, "dualwrite")) && *queryHost != "" {
+		log.Fatal("--query-host names one server; with several targets or dualwrite set POSTGRES_QUERY_HOST/CLICKHOUSE_QUERY_HOST instead")
+	}
+	if *dbPasswordEnv != "" && *dbPasswordFile != "" {
+		log.Fatal("--db-password-env and --db-password-file are mutually exclusive")
+	}
+	if len(targets) > 1 && *pprofAddr != "" {
+		log.Fatal("--pprof-addr cannot be used with multiple --database targets (each target would bind it)")
+	}
+	var loadSteps []benchmarkgo.LoadStep
+	if *loadStepsFlag != "" {
+		var err error
+		if loadSteps, err = benchmarkgo.ParseLoadSteps(*loadStepsFlag); err != nil {
+			log.Fatalf("--load-steps: %v", err)
+		}
+		if *rampUpSec > 0 || *rampDownSec > 0 {
+			log.Fatal("--load-steps cannot be combined with --ramp-up-sec/--ramp-down-sec")
+		}
+		*rowsPerSecond = loadSteps[0].RPS
+		*duration = 0
+		for _, s := range loadSteps {
+			*duration += s.DurationSec
+		}
+	}
+	if err := benchmarkgo.ValidateArrival(*arrival); err != nil {
+		log.Fatalf("--arrival: %v", err)
+	}
+	if *arrival != benchmarkgo.ArrivalFixed && (*rampUpSec > 0 || *rampDownSec > 0) {
+		log.Fatal("--arrival uniform/poisson cannot be combined with --ramp-up-sec/--ramp-down-sec")
+	}
+	var pattern benchmarkgo.LoadPattern
+	if *patternFlag != "" {
+		var err error
+		if pattern, err = benchmarkgo.ParseLoadPattern(*patternFlag); err != nil {
+			log.Fatalf("--pattern: %v", err)
+		}
+		if *arrival != benchmarkgo.ArrivalFixed || *findMax {
+			log.Fatal("--pattern cannot be combined with --arrival uniform/poisson or --find-max")
+		}
+	}
+	var burst benchmarkgo.BurstOptions
+	if *burstFlag != "" {
+		var err error
+		if burst, err = benchmarkgo.ParseBurst(*burstFlag); err != nil {
+			log.Fatalf("--burst: %v", err)
+		}
+		if *arrival != benchmarkgo.ArrivalFixed {
+			log.Fatal("--burst cannot be combined with --arrival uniform/poisson")
+		}
+	}
+	if err := benchmarkgo.ValidateWorkload(*workload, *readRatio); err != nil {
+		log.Fatalf("--workload: %v", err)
+	}
+	if err := benchmarkgo.ValidateMode(*mode); err != nil {
+		log.Fatalf("--mode: %v", err)
+	}
+	if *mode == benchmarkgo.ModeQueryOnly && (*workload != benchmarkgo.WorkloadWriteTriggered || *findMax || *burstFlag != "" || *patternFlag != "" ||
+		*rampUpSec > 0 || *rampDownSec > 0 || *arrival != benchmarkgo.ArrivalFixed) {
+		log.Fatal("--mode query-only paces queries at --rows-per-second; it cannot be combined with --workload mixed, --find-max or insert rate shaping (--burst, --pattern, --ramp-*, --arrival)")
+	}
+	if *mode == benchmarkgo.ModeClosed {
+		if *concurrency < 1 {
+			log.Fatal("--concurrency must be >= 1")
+		}
+		if *workload != benchmarkgo.WorkloadWriteTriggered || len(loadSteps) > 0 || *findMax || *burstFlag != "" || *patternFlag != "" ||
+			*rampUpSec > 0 || *rampDownSec > 0 || *arrival != benchmarkgo.ArrivalFixed {
+			log.Fatal("--mode closed has no target rate; it cannot be combined with --workload mixed, --load-steps, --find-max or rate shaping (--burst, --pattern, --ramp-*, --arrival)")
+		}
+		*workers, *rowsPerSecond = *concurrency, 0
+	}
+	if *mode == benchmarkgo.ModeInsertOnly {
+		if *workload != benchmarkgo.WorkloadWriteTriggered {
+			log.Fatal("--mode insert-only runs no queries; it cannot be combined with --workload mixed")
+		}
+		*queriesPerRecord = 0
+	}
+	keyDist, err := benchmarkgo.ParseKeyDist(*keyDistFlag)
+	if err != nil {
+		log.Fatalf("--key-dist: %v", err)
+	}
+	if keyDist.Kind != benchmarkgo.KeyDistUniform && *workload != benchmarkgo.WorkloadMixed && *mode != benchmarkgo.ModeQueryOnly {
+		log.Fatal("--key-dist picks among loaded MRNs; use it with --workload mixed or --mode query-only (write-triggered queries look up each fresh insert)")
+	}
+	if *preloadRows < 0 {
+		log.Fatal("--preload-rows must be >= 0")
+	}
+	if *preloadRows > 0 && (*payloadTransform != "" || *patientCounter != benchmarkgo.PatientCounterMax || slices.Contains(targets, "dualwrite")) {
+		log.Fatal("--preload-rows loads plain rows above the max ordinal in the table; it cannot be combined with --payload-transform, --patient-counter reserve/static or dualwrite")
+	}
+	if *duplicateRatio < 0 || *duplicateRatio > 1 {
+		log.Fatal("--duplicate-ratio must be between 0 and 1")
+	}
+	if *duplicateLag < 0 {
+		log.Fatal("--duplicate-lag-sec must be >= 0")
+	}
+	if *duplicateLag > 0 && *mode == benchmarkgo.ModeClosed {
+		log.Fatal("--duplicate-lag-sec is measured at --rows-per-second; --mode closed has no target rate")
+	}
+	if *totalRows < 0 {
+		log.Fatal("--total-rows must be >= 0")
+	}
+	if *totalRows > 0 {
+		if *mode == benchmarkgo.ModeQueryOnly || len(loadSteps) > 0 || *findMax || *rampUpSec > 0 || *rampDownSec > 0 {
+			log.Fatal("--total-rows cannot be combined with --mode query-only, --load-steps, --find-max or --ramp-up-sec/--ramp-down-sec")
+		}
+		durationSet := false
+		flag.Visit(func(f *flag.Flag) { durationSet = durationSet || f.Name == "duration" })
+		if !durationSet {
+			*duration = totalRowsMaxSec
+		}
+	}
+	if *workload == benchmarkgo.WorkloadMixed && slices.Contains(targets, "dualwrite") {
+		log.Fatal("--workload mixed is not supported with dualwrite (its queries measure visibility of fresh writes)")
+	}
+	if *findMax {
+		if len(loadSteps) > 0 || *rampUpSec > 0 || *rampDownSec > 0 {
+			log.Fatal("--find-max cannot be combined with --load-steps or --ramp-up-sec/--ramp-down-sec")
+		}
+		if *findMaxP99 <= 0 || *findMaxStep <= 0 {
+			log.Fatal("--find-max-p99-ms and --find-max-step-sec must be > 0")
+		}
+		if *rowsPerSecond < 1 {
+			log.Fatal("--find-max needs --rows-per-second >= 1 as the first trial rate")
+		}
 	}
 	if *workers < 1 {
 		log.Fatal("--workers must be >= 1")
@@ -54,19 +386,289 @@ func main() {
 	if *producers < 2 {
 		log.Fatal("--producers must be >= 2")
 	}
+	if (*dutyOn > 0) != (*dutyIdle > 0) || *dutyOn < 0 || *dutyIdle < 0 {
+		log.Fatal("--duty-on and --duty-idle must both be > 0 (or both 0)")
+	}
+	if *reproAfter < 1 {
+		log.Fatal("--repro-after must be >= 1")
+	}
+	if *insertQueueSize < 0 || *queryQueueSize < 0 {
+		log.Fatal("--insert-queue-size and --query-queue-size must be >= 0")
+	}
+	switch *queryType {
+	case benchmarkgo.QueryTypePK, benchmarkgo.QueryTypeResultSet, benchmarkgo.QueryTypeSession, benchmarkgo.QueryTypeRange,
+		benchmarkgo.QueryTypePatientID, benchmarkgo.QueryTypeNameDOB, benchmarkgo.QueryTypePageOffset, benchmarkgo.QueryTypePageKeyset,
+		benchmarkgo.QueryTypeChecksum:
+	default:
+		log.Fatal("--query-type must be pk, resultset, session, range, patient-id, name-dob, page-offset, page-keyset, or checksum")
+	}
+	var queryWeights benchmarkgo.QueryWeights
+	if *queryTypes != "" {
+		var err error
+		if queryWeights, err = benchmarkgo.ParseQueryWeights(*queryTypes); err != nil {
+			log.Fatalf("--query-types: %v", err)
+		}
+	}
+	if *breakerFailures < 0 || *breakerSlowMs < 0 || *breakerSlowBatches < 1 || *breakerPause <= 0 {
+		log.Fatal("--breaker-failures and --breaker-latency-ms must be >= 0, --breaker-slow-batches >= 1 and --breaker-pause-sec > 0")
+	}
+	if *dedupWatch && (*dedupPoll <= 0 || *dedupWait < 0) {
+		log.Fatal("--dedup-poll-sec must be > 0 and --dedup-wait-sec >= 0")
+	}
+	if *rangeWindow <= 0 {
+		log.Fatal("--range-window-sec must be > 0")
+	}
+	if err := benchmarkgo.ValidatePKSelect(*querySelect); err != nil {
+		log.Fatalf("--query-select: %v", err)
+	}
+	if *verify && (*querySelect != benchmarkgo.PKSelectCount || *payloadTransform != "") {
+		log.Fatal("--verify replaces the pk query; it cannot be combined with --query-select columns/payload or --payload-transform")
+	}
+	if *pageSize < 1 || *pageDepth < 1 {
+		log.Fatal("--page-size and --page-depth must be >= 1")
+	}
+	usesQuery := func(qt string) bool { return *queryType == qt || queryWeights[qt] > 0 }
+	nameQueries := usesQuery(benchmarkgo.QueryTypeNameDOB)
+	pageQueries := usesQuery(benchmarkgo.QueryTypePageOffset) || usesQuery(benchmarkgo.QueryTypePageKeyset)
+	if *queryThink < 0 || *queryJitter < 0 || *queryJitter > *queryThink {
+		log.Fatal("--query-think-time-ms and --query-think-jitter-ms must be >= 0, with the jitter no more than the think time")
+	}
+	if err := postgres.ValidateDistribution(*postgresDistribution); err != nil {
+		log.Fatalf("--postgres-distribution: %v", err)
+	}
+	if *postgresDistribution != "" && *postgresTimescale {
+		log.Fatal("--postgres-distribution and --postgres-timescale are mutually exclusive")
+	}
+	if *costPerVCPUHour > 0 && *costVCPUs <= 0 {
+		log.Fatal("--cost-per-vcpu-hour requires --cost-vcpus (the database's vCPU count)")
+	}
+	if err := benchmarkgo.ValidatePayloadTransforms(*payloadTransform); err != nil {
+		log.Fatalf("--payload-transform: %v", err)
+	}
+	if err := mysql.ValidateEngine(*mysqlEngine); err != nil {
+		log.Fatalf("--mysql-engine: %v", err)
+	}
+	if err := kafka.ValidateCompression(*kafkaCompression); err != nil {
+		log.Fatalf("--kafka-compression: %v", err)
+	}
+	if err := dynamodb.ValidateBilling(*dynamodbBilling); err != nil {
+		log.Fatalf("--dynamodb-billing: %v", err)
+	}
+	if err := singlestore.ValidateTableType(*singlestoreTableType); err != nil {
+		log.Fatalf("--singlestore-table-type: %v", err)
+	}
+	if err := clickhouse.ValidateTopology(*clickhouseTopology); err != nil {
+		log.Fatalf("--clickhouse-topology: %v", err)
+	}
+	if err := clickhouse.ValidateProtocol(*clickhouseProtocol); err != nil {
+		log.Fatalf("--clickhouse-protocol: %v", err)
+	}
+	if *postgresReplicaHost != "" {
+		if *pgbouncerEnabled {
+			log.Fatal("--postgres-replica-host cannot be combined with --pgbouncer-enabled")
+		}
+		if *postgresReplicaTimeout <= 0 {
+			log.Fatal("--postgres-replica-timeout must be > 0")
+		}
+		if *queryHost != "" {
+			log.Fatal("--query-host and --postgres-replica-host both set the query workers' host; use one")
+		}
+	}
+	if *queryHost != "" && *pgbouncerEnabled {
+		log.Fatal("--query-host cannot be combined with --pgbouncer-enabled (query workers share the pgbouncer pool)")
+	}
+	if *warmupSec < 0 || *warmupSec >= *duration {
+		log.Fatal("--warmup-sec must be >= 0 and less than --duration")
+	}
+	if *rampUpSec < 0 || *rampDownSec < 0 || *rampUpSec+*rampDownSec > *duration {
+		log.Fatal("--ramp-up-sec and --ramp-down-sec must be >= 0 and together at most --duration")
+	}
+	if *serverMetricsInterval < 0 {
+		log.Fatal("--server-metrics-interval must be >= 0")
+	}
+	if *clickhouseVisibility < 0 {
+		log.Fatal("--clickhouse-visibility-timeout must be >= 0")
+	}
+	if *clickhousePartitionMinutes < 0 {
+		log.Fatal("--clickhouse-partition-minutes must be >= 0")
+	}
+	if *clickhousePartitionMinutes > 0 && (*snapshotSave != "" || *snapshotRestore != "") {
+		log.Fatal("--clickhouse-partition-minutes cannot be combined with --snapshot-save/--snapshot-restore (snapshots copy the single unpartitioned partition)")
+	}
+	if err := benchmarkgo.ValidateIsolation(*conflictIsolation); err != nil {
+		log.Fatalf("--conflict-isolation: %v", err)
+	}
+	if *conflictWriters < 0 || *conflictKeys < 1 {
+		log.Fatal("--conflict-writers must be >= 0 and --conflict-keys >= 1")
+	}
+	if err := benchmarkgo.ValidateTracing(benchmarkgo.TracingOptions{Endpoint: *otelEndpoint, SampleRate: *otelSampleRate}); err != nil {
+		log.Fatalf("--otel-sample-rate: %v", err)
+	}
+	if err := benchmarkgo.ValidateDurability(*durability); err != nil {
+		log.Fatalf("--durability: %v", err)
+	}
+	if err := benchmarkgo.ValidatePatientCounter(*patientCounter, *runnerID, *runnerCount); err != nil {
+		log.Fatalf("--patient-counter: %v", err)
+	}
+	if err := benchmarkgo.SetTablePrefix(*tablePrefix); err != nil {
+		log.Fatalf("--table-prefix: %v", err)
+	}
+	conn, err := connectionSettings(*database, benchmarkgo.Connection{
+		Host: *dbHost, Port: *dbPort, Database: *dbName, User: *dbUser, Cluster: *clickhouseCluster, QueryHost: *queryHost,
+	}, *dbPasswordEnv, *dbPasswordFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := benchmarkgo.SetConnection(conn); err != nil {
+		log.Fatalf("Connection settings: %v", err)
+	}
+	poolOpts := benchmarkgo.PoolOptions{InsertSize: *poolSize, QuerySize: *poolSize, MaxIdle: *poolMaxIdle, MaxLifetime: *poolMaxLifetime}
+	if *insertPoolSize != 0 {
+		poolOpts.InsertSize = *insertPoolSize
+	}
+	if *queryPoolSize != 0 {
+		poolOpts.QuerySize = *queryPoolSize
+	}
+	if err := benchmarkgo.SetPoolOptions(poolOpts); err != nil {
+		log.Fatalf("--pool-*: %v", err)
+	}
+	if err := benchmarkgo.ValidateSnapshotName(*snapshotSave); err != nil {
+		log.Fatalf("--snapshot-save: %v", err)
+	}
+	if err := benchmarkgo.ValidateSnapshotName(*snapshotRestore); err != nil {
+		log.Fatalf("--snapshot-restore: %v", err)
+	}
+	regressionPct, err := benchmarkgo.ParsePercent(*failOnRegression)
+	if err != nil {
+		log.Fatalf("--fail-on-regression: %v", err)
+	}
+	if *baseline != "" {
+		if _, err := benchmarkgo.ReadResults(*baseline); err != nil {
+			log.Fatalf("--baseline: %v", err)
+		}
+		if len(targets) > 1 {
+			log.Fatal("--baseline cannot be used with multiple --database targets")
+		}
+	}
+	sizes, err := parseIntList(*resultSetSizes)
+	if err != nil || len(sizes) == 0 {
+		log.Fatalf("--result-set-sizes must be a comma-separated list of positive integers: %v", err)
+	}
+	if *scenarioPath != "" {
+		if len(targets) > 1 {
+			log.Fatal("--scenario cannot be used with multiple --database targets")
+		}
+		sc, err := benchmarkgo.LoadScenario(*scenarioPath)
+		if err != nil {
+			log.Fatalf("--scenario: %v", err)
+		}
+		checkScenarioFlags(sc)
+		runScenario(sc, *seed, *resultsJSON, *outputCSV)
+		return
+	}
+	if len(targets) > 1 {
+		runTargets(targets, *seed, *resultsJSON, *outputCSV)
+		return
+	}
+
+	if *cpus != "" {
+		cpuList, err := benchmarkgo.ParseCPUList(*cpus)
+		if err != nil {
+			log.Fatalf("--cpus: %v", err)
+		}
+		if err := benchmarkgo.SetCPUAffinity(cpuList); err != nil {
+			log.Fatalf("--cpus: %v", err)
+		}
+	}
+	if *gomaxprocs > 0 {
+		runtime.GOMAXPROCS(*gomaxprocs)
+	}
+	if *gogc != 0 {
+		debug.SetGCPercent(*gogc)
+	}
+	if err := applyMemoryLimit(*gomemlimit); err != nil {
+		log.Fatalf("--gomemlimit: %v", err)
+	}
 
 	queryDelaySec := *queryDelay / 1000
 
 	var workerCtx benchmarkgo.WorkerCtx
-	if *database == "postgres" {
-		workerCtx = &postgres.Context{PgbouncerEnabled: *pgbouncerEnabled}
-	} else {
-		workerCtx = &clickhouse.Context{}
+	newPostgres := func() *postgres.Context {
+		return &postgres.Context{
+			PgbouncerEnabled: *pgbouncerEnabled,
+			Durability:       *durability,
+			Schema: postgres.SchemaOptions{
+				Timescale:    *postgresTimescale,
+				Distribution: *postgresDistribution,
+				NameIndex:    nameQueries,
+				PageIndex:    pageQueries,
+			},
+		}
+	}
+	newClickHouse := func() *clickhouse.Context {
+		return &clickhouse.Context{Durability: *durability, Protocol: *clickhouseProtocol, Topology: *clickhouseTopology, NameIndex: *clickhouseNameIndex, PartitionMinutes: *clickhousePartitionMinutes}
+	}
+	switch *database {
+	case "postgres":
+		pg := newPostgres()
+		pg.ReplicaHost = *postgresReplicaHost
+		pg.ReplicaTimeout = time.Duration(*postgresReplicaTimeout * float64(time.Second))
+		workerCtx = pg
+	case "clickhouse":
+		ch := newClickHouse()
+		ch.VisibilityTimeout = time.Duration(*clickhouseVisibility * float64(time.Second))
+		workerCtx = ch
+	case "dualwrite":
+		workerCtx = &dualwrite.Context{
+			Primary:           dualwrite.Target{Name: "postgres", Ctx: newPostgres()},
+			Secondary:         dualwrite.Target{Name: "clickhouse", Ctx: newClickHouse()},
+			VisibilityTimeout: time.Duration(*dualWriteTimeout * float64(time.Second)),
+		}
+	case "redis":
+		workerCtx = &redis.Context{}
+	case "tidb":
+		workerCtx = tidb.NewContext(tidb.Options{AutoRandom: *tidbAutoRandom})
+	case "yugabyte":
+		workerCtx = &yugabyte.Context{Topology: yugabyte.Topology{
+			LoadBalance:         *ybLoadBalance,
+			ReadFromFollowers:   *ybFollowerReads,
+			FollowerStalenessMs: *ybFollowerStaleness,
+		}}
+	case "questdb":
+		workerCtx = &questdb.Context{}
+	case "druid":
+		workerCtx = &druid.Context{}
+	case "dynamodb":
+		workerCtx = &dynamodb.Context{Options: dynamodb.Options{
+			Billing:       *dynamodbBilling,
+			ReadCapacity:  *dynamodbRCU,
+			WriteCapacity: *dynamodbWCU,
+		}}
+	case "kafka":
+		workerCtx = &kafka.Context{Options: kafka.Options{
+			Partitions:  *kafkaPartitions,
+			Compression: *kafkaCompression,
+			Durability:  *durability,
+		}}
+	case "httpsink":
+		workerCtx = &httpsink.Context{Options: httpsink.Options{
+			URL:     *httpsinkURL,
+			Timeout: time.Duration(*httpsinkTimeout * float64(time.Second)),
+		}}
+	case "mysql":
+		workerCtx = mysql.NewContext(mysql.Options{Engine: *mysqlEngine})
+	case "sqlite":
+		workerCtx = sqlite.NewContext()
+	case "singlestore":
+		workerCtx = singlestore.NewContext(singlestore.Options{TableType: *singlestoreTableType})
+	default:
+		workerCtx = optionalBackends[*database]()
 	}
 
 	cfg := benchmarkgo.Config{
 		Database:           *database,
 		DurationSec:        *duration,
+		TotalRows:          *totalRows,
 		BatchSize:          *batchSize,
 		Workers:            *workers,
 		TargetRPS:          *rowsPerSecond,
@@ -75,10 +677,357 @@ func main() {
 		ProducerThreads:    *producers,
 		IgnoreSelectErrors: *ignoreSelectErrors,
 		DuplicateRatio:     *duplicateRatio,
+		DuplicateLagSec:    *duplicateLag,
 		PgbouncerEnabled:   *pgbouncerEnabled,
+		QueryType:          *queryType,
+		QueryWeights:       queryWeights,
+		PKSelect:           *querySelect,
+		Verify:             *verify,
+		RangeWindowSec:     *rangeWindow,
+		PageSize:           *pageSize,
+		PageDepth:          *pageDepth,
+		ResultSetSizes:     sizes,
+		SessionThinkSec:    *sessionThink / 1000,
+		QueryThinkSec:      *queryThink / 1000,
+		QueryJitterSec:     *queryJitter / 1000,
+		RetentionAtSec:     *retentionAt,
+		RetentionKeepSec:   *retentionKeep,
+		SnapshotSave:       *snapshotSave,
+		SnapshotRestore:    *snapshotRestore,
+		PreloadRows:        *preloadRows,
+		ConflictWriters:    *conflictWriters,
+		ConflictKeys:       *conflictKeys,
+		ConflictIsolation:  *conflictIsolation,
+		FailoverWatch:      *failoverWatch,
+		FailoverHook:       *failoverHook,
+		FailoverAtSec:      *failoverAt,
+		Reconcile:          *reconcile,
+		DedupWatch:         *dedupWatch,
+		DedupPollSec:       *dedupPoll,
+		DedupWaitSec:       *dedupWait,
+		PayloadTransforms:  *payloadTransform,
+		Guardrails:         benchmarkgo.GuardrailOptions{MaxTotalRows: *maxTotalRows, MaxStorageGB: *maxStorageGB},
+		Breaker:            benchmarkgo.BreakerOptions{Failures: *breakerFailures, SlowSec: *breakerSlowMs / 1000, SlowBatches: *breakerSlowBatches, PauseSec: *breakerPause},
+		Repro:              benchmarkgo.ReproOptions{Dir: *reproDir, After: *reproAfter},
+		VerifyGoodput:      *verifyGoodput,
+		DutyCycle:          benchmarkgo.DutyCycleOptions{OnSec: *dutyOn, IdleSec: *dutyIdle},
+		Cost:               benchmarkgo.CostOptions{PerGBMonth: *costPerGBMonth, PerVCPUHour: *costPerVCPUHour, VCPUs: *costVCPUs},
+		ResultsJSON:        *resultsJSON,
+		OutputCSV:          *outputCSV,
+		ReportMarkdown:     *reportMD,
+		Baseline:           benchmarkgo.BaselineOptions{Path: *baseline, ThresholdPct: regressionPct},
+		Durability:         *durability,
+		LiveConfigPath:     *liveConfig,
+		AnnotationsPath:    *annotationsFile,
+		ControlAddr:        *controlAddr,
+		ControlToken:       os.Getenv(controlTokenEnv),
+		InsertQueueSize:    *insertQueueSize,
+		QueryQueueSize:     *queryQueueSize,
+		PatientCounter:     *patientCounter,
+		RunnerID:           *runnerID,
+		RunnerCount:        *runnerCount,
+		LatencySampleRate:  *latencySampleRate,
+		Tracing:            benchmarkgo.TracingOptions{Endpoint: *otelEndpoint, SampleRate: *otelSampleRate},
+		Seed:               *seed,
+		ServerMetricsSec:   *serverMetricsInterval,
+		WarmupSec:          *warmupSec,
+		Ramp:               benchmarkgo.RampOptions{UpSec: *rampUpSec, DownSec: *rampDownSec},
+		LoadSteps:          loadSteps,
+		Arrival:            *arrival,
+		Pattern:            pattern,
+		Burst:              burst,
+		Mode:               *mode,
+		Concurrency:        *concurrency,
+		Workload:           *workload,
+		ReadRatio:          *readRatio,
+		KeyDist:            keyDist,
+		FindMax:            benchmarkgo.FindMaxOptions{Enabled: *findMax, P99SLOMs: *findMaxP99, StepSec: *findMaxStep},
+		Push:               benchmarkgo.PushOptions{URL: *pushURL, Format: *pushFormat, Job: *pushJob},
 	}
 	r := benchmarkgo.NewLoadRunner(cfg, workerCtx)
+	recordRuntimeSettings(r)
+	r.SetMetadata("table_prefix", benchmarkgo.TablePrefix())
+	r.SetMetadata("table", benchmarkgo.Table())
+	if h := benchmarkgo.DBQueryHost(); h != "" {
+		r.SetMetadata("query_host", h)
+	}
+	if p := benchmarkgo.Pool(); p != (benchmarkgo.PoolOptions{}) {
+		r.SetMetadata("pool_insert_size", p.InsertSize)
+		r.SetMetadata("pool_query_size", p.QuerySize)
+		r.SetMetadata("pool_max_idle", p.MaxIdle)
+		r.SetMetadata("pool_max_lifetime_sec", p.MaxLifetime.Seconds())
+	}
+	if *database == "postgres" || *database == "dualwrite" {
+		r.SetMetadata("postgres_timescale", *postgresTimescale)
+		if *postgresDistribution != "" {
+			r.SetMetadata("postgres_distribution", *postgresDistribution)
+		}
+	}
+	if *database == "postgres" && *postgresReplicaHost != "" {
+		r.SetMetadata("postgres_replica_host", *postgresReplicaHost)
+		r.SetMetadata("postgres_replica_timeout_sec", *postgresReplicaTimeout)
+	}
+	if *database == "yugabyte" {
+		r.SetMetadata("yb_load_balance", *ybLoadBalance)
+		r.SetMetadata("yb_read_from_followers", *ybFollowerReads)
+	}
+	if *database == "tidb" {
+		r.SetMetadata("tidb_auto_random", *tidbAutoRandom)
+	}
+	if *database == "dualwrite" {
+		r.SetMetadata("dual_write_timeout_sec", *dualWriteTimeout)
+	}
+	if *database == "clickhouse" || *database == "dualwrite" {
+		r.SetMetadata("clickhouse_protocol", *clickhouseProtocol)
+		r.SetMetadata("clickhouse_topology", *clickhouseTopology)
+		r.SetMetadata("clickhouse_partition_minutes", *clickhousePartitionMinutes)
+	}
+	if *database == "clickhouse" && *clickhouseVisibility > 0 {
+		r.SetMetadata("clickhouse_visibility_timeout_sec", *clickhouseVisibility)
+	}
+	if *database == "mysql" {
+		r.SetMetadata("mysql_engine", *mysqlEngine)
+	}
+	if *database == "kafka" {
+		r.SetMetadata("kafka_partitions", *kafkaPartitions)
+		r.SetMetadata("kafka_compression", *kafkaCompression)
+	}
+	if *database == "dynamodb" {
+		r.SetMetadata("dynamodb_billing", *dynamodbBilling)
+		if *dynamodbBilling == dynamodb.BillingProvisioned {
+			r.SetMetadata("dynamodb_rcu", *dynamodbRCU)
+			r.SetMetadata("dynamodb_wcu", *dynamodbWCU)
+		}
+	}
+	if *database == "httpsink" && *httpsinkURL != "" {
+		r.SetMetadata("httpsink_url", *httpsinkURL)
+	}
+	if *database == "singlestore" {
+		r.SetMetadata("singlestore_table_type", *singlestoreTableType)
+	}
+	if *metricsAddr != "" {
+		serveMetrics(*metricsAddr)
+	}
+	if *pprofAddr != "" {
+		serveDebug(*pprofAddr)
+	}
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer stop()
-	r.Run(ctx)
+	err = r.Run(ctx)
+	stop()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if r.BaselineFailed() {
+		os.Exit(1)
+	}
+}
+
+// connectionSettings layers the connection settings: defaults, then the backend's POSTGRES_*/CLICKHOUSE_* environment
+// variables, then the --db-* flags (flags' non-zero fields) and the password from passwordEnv or passwordFile.
+func connectionSettings(database string, flags benchmarkgo.Connection, passwordEnv, passwordFile string) (benchmarkgo.Connection, error) {
+	c := benchmarkgo.DefaultConnection()
+	if prefix := map[string]string{"postgres": "POSTGRES", "clickhouse": "CLICKHOUSE"}[database]; prefix != "" {
+		var err error
+		if c, err = c.FromEnv(prefix); err != nil {
+			return c, err
+		}
+	}
+	if flags.Host != "" {
+		c.Host = flags.Host
+	}
+	if flags.Port < 0 {
+		return c, fmt.Errorf("--db-port must be >= 0")
+	} else if flags.Port > 0 {
+		c.Port = flags.Port
+	}
+	if flags.Database != "" {
+		c.Database = flags.Database
+	}
+	if flags.User != "" {
+		c.User = flags.User
+	}
+	if flags.Cluster != "" {
+		c.Cluster = flags.Cluster
+	}
+	if flags.QueryHost != "" {
+		c.QueryHost = flags.QueryHost
+	}
+	switch {
+	case passwordEnv != "":
+		v, ok := os.LookupEnv(passwordEnv)
+		if !ok {
+			return c, fmt.Errorf("--db-password-env: %s is not set", passwordEnv)
+		}
+		c.Password = v
+	case passwordFile != "":
+		b, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return c, fmt.Errorf("--db-password-file: %w", err)
+		}
+		c.Password = strings.TrimRight(string(b), "\r\n")
+	}
+	return c, nil
+}
+
+// parseIntList parses "1,10,100" into positive ints.
+func parseIntList(s string) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		if v <= 0 {
+			return nil, fmt.Errorf("%d is not positive", v)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// recordRuntimeSettings stores the effective CPU settings in the run results; container CPU limits
+// change harness capacity between runs, so they must be visible next to the numbers.
+func recordRuntimeSettings(r *benchmarkgo.LoadRunner) {
+	r.SetMetadata("gomaxprocs", runtime.GOMAXPROCS(0))
+	r.SetMetadata("num_cpu", runtime.NumCPU())
+	if cpuList, err := benchmarkgo.CPUAffinity(); err == nil {
+		r.SetMetadata("cpu_affinity", benchmarkgo.FormatCPUList(cpuList))
+	}
+	if limit := benchmarkgo.CgroupCPULimit(); limit > 0 {
+		r.SetMetadata("cgroup_cpu_limit", limit)
+	}
+	gcPercent := debug.SetGCPercent(-1)
+	debug.SetGCPercent(gcPercent)
+	r.SetMetadata("gogc", gcPercent)
+	if limit := benchmarkgo.MemoryLimit(); limit > 0 {
+		r.SetMetadata("gomemlimit", limit)
+	}
+	if limit := benchmarkgo.CgroupMemoryLimit(); limit > 0 {
+		r.SetMetadata("cgroup_memory_limit", limit)
+	}
+}
+
+// applyMemoryLimit sets the Go soft memory limit. "auto" derives it from the cgroup limit (leaving 10% headroom
+// for non-heap memory) unless GOMEMLIMIT is already set in the environment; "off" or "" leaves it unset.
+func applyMemoryLimit(value string) error {
+	switch value {
+	case "", "off":
+		return nil
+	case "auto":
+		if os.Getenv("GOMEMLIMIT") != "" {
+			return nil
+		}
+		if limit := benchmarkgo.CgroupMemoryLimit(); limit > 0 {
+			debug.SetMemoryLimit(limit / 10 * 9)
+			log.Printf("GOMEMLIMIT set to %s (90%% of cgroup limit %s)", benchmarkgo.FormatBytes(limit/10*9), benchmarkgo.FormatBytes(limit))
+		}
+		return nil
+	}
+	limit, err := benchmarkgo.ParseByteSize(value)
+	if err != nil {
+		return err
+	}
+	debug.SetMemoryLimit(limit)
+	return nil
+}
+
+// serveMetrics serves the Prometheus endpoint in the background for the life of the process.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", benchmarkgo.MetricsHandler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Metrics server on %s: %v", addr, err)
+		}
+	}()
+	log.Printf("Serving Prometheus metrics on %s/metrics", addr)
+}
+
+// serveDebug serves pprof profiles and expvar variables in the background for the life of the process.
+func serveDebug(addr string) {
+	expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Debug server on %s: %v", addr, err)
+		}
+	}()
+	log.Printf("Serving pprof on %s/debug/pprof/ and expvar on %s/debug/vars", addr, addr)
+}
+
+// runDashboards implements `dashboards --out dir/`: writes Grafana dashboards generated from the metric registry.
+func runDashboards(args []string) {
+	fs := flag.NewFlagSet("dashboards", flag.ExitOnError)
+	out := fs.String("out", "dashboards", "Directory to write Grafana dashboard JSON files into")
+	fs.Parse(args)
+	paths, err := benchmarkgo.WriteDashboards(*out)
+	if err != nil {
+		log.Fatalf("dashboards: %v", err)
+	}
+	for _, p := range paths {
+		log.Printf("Wrote %s", p)
+	}
+}
+
+// runCompare implements `compare [--threshold 10%] baseline.json candidate.json`: percentage deltas of two
+// runs' throughput and latency percentiles, with regressions beyond the threshold flagged.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	threshold := fs.String("threshold", "10%", "Flag metrics that got worse than the baseline by more than this percentage")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		log.Fatal("usage: compare [--threshold 10%] baseline.json candidate.json")
+	}
+	pct, err := benchmarkgo.ParsePercent(*threshold)
+	if err != nil {
+		log.Fatalf("compare --threshold: %v", err)
+	}
+	baseline, err := benchmarkgo.ReadResults(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("compare: %v", err)
+	}
+	candidate, err := benchmarkgo.ReadResults(fs.Arg(1))
+	if err != nil {
+		log.Fatalf("compare: %v", err)
+	}
+	metrics := benchmarkgo.CompareResults(baseline, candidate, pct)
+	benchmarkgo.LogComparison(fs.Arg(0)+" ["+baseline.Database+"]", fs.Arg(1)+" ["+candidate.Database+"]", metrics, pct)
+	if reg := benchmarkgo.Regressions(metrics); len(reg) > 0 {
+		log.Printf("%d regression(s): %s", len(reg), strings.Join(reg, ", "))
+	}
+}
+
+// runMicrobench times the serialization layer (JSON marshal, payload expansion, row mapping, statement building)
+// with no database, so regressions show up without database noise.
+func runMicrobench(args []string) {
+	fs := flag.NewFlagSet("microbench", flag.ExitOnError)
+	benchtime := fs.Duration("benchtime", 2*time.Second, "Minimum time to run each benchmark")
+	batchSize := fs.Int("batch-size", 100, "Rows per op (one op processes a whole batch)")
+	run := fs.String("run", "", "Only run benchmarks whose name matches this regexp")
+	fs.Parse(args)
+	re, err := regexp.Compile(*run)
+	if err != nil {
+		log.Fatalf("microbench --run: %v", err)
+	}
+	if *batchSize < 1 {
+		log.Fatal("microbench --batch-size must be >= 1")
+	}
+	fmt.Printf("%-40s %10s %14s %12s %12s %14s\n", "benchmark", "ops", "ns/op", "ns/row", "allocs/op", "B/op")
+	for _, m := range benchmarkgo.Microbenches() {
+		if !re.MatchString(m.Name) {
+			continue
+		}
+		res := benchmarkgo.RunMicrobench(m, *batchSize, *benchtime)
+		fmt.Printf("%-40s %10d %14.0f %12.0f %12.1f %14.0f\n", res.Name, res.Ops, res.NsPerOp, res.NsPerRow, res.AllocsPerOp, res.BytesPerOp)
+	}
 }
@@ -0,0 +1,12 @@
+//go:build bigquery
+
+package main
+
+import (
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/bigquery"
+)
+
+func init() {
+	optionalBackends["bigquery"] = func() benchmarkgo.WorkerCtx { return bigquery.NewContext() }
+}
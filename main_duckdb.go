@@ -0,0 +1,12 @@
+//go:build duckdb
+
+package main
+
+import (
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/duckdb"
+)
+
+func init() {
+	optionalBackends["duckdb"] = func() benchmarkgo.WorkerCtx { return duckdb.NewContext() }
+}
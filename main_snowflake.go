@@ -0,0 +1,20 @@
+//go:build snowflake
+
+package main
+
+import (
+	"log"
+
+	"github.com/db-benchmarking/benchmark-go"
+	"github.com/db-benchmarking/benchmark-go/snowflake"
+)
+
+func init() {
+	optionalBackends["snowflake"] = func() benchmarkgo.WorkerCtx {
+		c, err := snowflake.NewContext()
+		if err != nil {
+			log.Fatalf("snowflake: %v", err)
+		}
+		return c
+	}
+}
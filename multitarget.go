@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// runTargets runs the benchmark against each target concurrently, one child process per target with the same
+// arguments and seed (so every target receives the same generated stream), prefixes the children's output with
+// [target], then prints a side-by-side comparison of their results. Exits non-zero if any target failed.
+func runTargets(targets []string, seed int64, resultsJSON, outputCSV string) {
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("multi-target: %v", err)
+	}
+	dir := ""
+	if resultsJSON == "" {
+		if dir, err = os.MkdirTemp("", "loadrunner-targets-"); err != nil {
+			log.Fatalf("multi-target: %v", err)
+		}
+		defer os.RemoveAll(dir)
+	}
+	paths := make([]string, len(targets))
+	for i, t := range targets {
+		if dir != "" {
+			paths[i] = filepath.Join(dir, t+".json")
+		} else {
+			paths[i] = strings.TrimSuffix(resultsJSON, ".json") + "-" + t + ".json"
+		}
+	}
+	log.Printf("Multi-target run: %s (seed %d)", strings.Join(targets, ", "), seed)
+
+	// Ctrl-C reaches the children through the process group; catch it here so the parent outlives them and
+	// still prints the comparison, and forward it for non-terminal senders.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	var outMu sync.Mutex
+	cmds := make([]*exec.Cmd, len(targets))
+	var wg sync.WaitGroup
+	failed := make([]bool, len(targets))
+	for i, t := range targets {
+		args := append(append([]string(nil), os.Args[1:]...),
+			"--database="+t, fmt.Sprintf("--seed=%d", seed), "--results-json="+paths[i])
+		if outputCSV != "" {
+			args = append(args, "--output-csv="+strings.TrimSuffix(outputCSV, ".csv")+"-"+t+".csv")
+		}
+		cmd := exec.Command(exe, args...)
+		cmd.Stderr = os.Stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			log.Fatalf("multi-target %s: %v", t, err)
+		}
+		if err := cmd.Start(); err != nil {
+			log.Fatalf("multi-target %s: %v", t, err)
+		}
+		cmds[i] = cmd
+		wg.Add(1)
+		go func(i int, t string) {
+			defer wg.Done()
+			sc := bufio.NewScanner(stdout)
+			sc.Buffer(make([]byte, 64*1024), 1024*1024)
+			for sc.Scan() {
+				outMu.Lock()
+				fmt.Fprintf(os.Stdout, "[%s] %s\n", t, sc.Text())
+				outMu.Unlock()
+			}
+			if err := cmd.Wait(); err != nil {
+				log.Printf("Target %s: %v", t, err)
+				failed[i] = true
+			}
+		}(i, t)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for waiting := true; waiting; {
+		select {
+		case <-sig:
+			for _, cmd := range cmds {
+				cmd.Process.Signal(os.Interrupt)
+			}
+		case <-done:
+			waiting = false
+		}
+	}
+
+	results := make([]*benchmarkgo.Results, len(targets))
+	anyFailed := false
+	for i, t := range targets {
+		if failed[i] {
+			anyFailed = true
+			continue
+		}
+		res, err := benchmarkgo.ReadResults(paths[i])
+		if err != nil {
+			log.Printf("Target %s: %v", t, err)
+			anyFailed = true
+			continue
+		}
+		results[i] = res
+	}
+	benchmarkgo.LogTargetComparison(targets, results)
+	if anyFailed {
+		os.Exit(1)
+	}
+}
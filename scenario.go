@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/db-benchmarking/benchmark-go"
+)
+
+// checkScenarioFlags fails if a phase sets a flag the loadrunner does not have, before any phase runs.
+func checkScenarioFlags(sc *benchmarkgo.Scenario) {
+	for _, p := range sc.Phases {
+		for _, arg := range p.Args() {
+			name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if flag.Lookup(name) == nil {
+				log.Fatalf("--scenario: phase %s sets unknown flag --%s", p.Name, name)
+			}
+			if name == "scenario" || name == "database" {
+				log.Fatalf("--scenario: phase %s cannot set --%s", p.Name, name)
+			}
+		}
+	}
+}
+
+// runScenario runs the scenario's phases one after another, one child process per phase with the command-line
+// arguments plus the phase's overrides, prefixes the children's output with [phase], then prints the phases'
+// results side by side. A failed or interrupted phase ends the scenario; exits non-zero if any phase failed.
+func runScenario(sc *benchmarkgo.Scenario, seed int64, resultsJSON, outputCSV string) {
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("scenario: %v", err)
+	}
+	dir := ""
+	if resultsJSON == "" {
+		if dir, err = os.MkdirTemp("", "loadrunner-scenario-"); err != nil {
+			log.Fatalf("scenario: %v", err)
+		}
+		defer os.RemoveAll(dir)
+	}
+	names := sc.Names()
+	log.Printf("Scenario %s: %d phases (%s), seed %d", sc.Name, len(names), strings.Join(names, " → "), seed)
+
+	// As with multi-target runs, Ctrl-C reaches the child through the process group; the parent stops after it.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	results := make([]*benchmarkgo.Results, len(names))
+	failed := false
+	for i, p := range sc.Phases {
+		path := filepath.Join(dir, p.Name+".json")
+		if dir == "" {
+			path = strings.TrimSuffix(resultsJSON, ".json") + "-" + p.Name + ".json"
+		}
+		// Each phase gets its own seed so a later phase does not replay an earlier one's stream.
+		args := append(append([]string(nil), os.Args[1:]...),
+			"--scenario=", fmt.Sprintf("--seed=%d", seed+int64(i)), "--results-json="+path)
+		if outputCSV != "" {
+			args = append(args, "--output-csv="+strings.TrimSuffix(outputCSV, ".csv")+"-"+p.Name+".csv")
+		}
+		args = append(args, p.Args()...)
+		log.Printf("Phase %d/%d %s: %s", i+1, len(names), p.Name, strings.Join(p.Args(), " "))
+		interrupted, err := runPhase(exe, args, p.Name, sig)
+		if err != nil {
+			log.Printf("Phase %s: %v", p.Name, err)
+			failed = true
+			break
+		}
+		if results[i], err = benchmarkgo.ReadResults(path); err != nil {
+			log.Printf("Phase %s: %v", p.Name, err)
+			failed = true
+			break
+		}
+		if interrupted {
+			log.Printf("Scenario interrupted after phase %s", p.Name)
+			break
+		}
+	}
+	benchmarkgo.LogPhaseComparison(names, results)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runPhase runs one phase's child process, prefixing its output with [name]; interrupted reports whether Ctrl-C
+// arrived while it ran.
+func runPhase(exe string, args []string, name string, sig <-chan os.Signal) (interrupted bool, err error) {
+	cmd := exec.Command(exe, args...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return false, err
+	}
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		sc := bufio.NewScanner(stdout)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for sc.Scan() {
+			fmt.Fprintf(os.Stdout, "[%s] %s\n", name, sc.Text())
+		}
+		done <- cmd.Wait()
+	}()
+	for {
+		select {
+		case <-sig:
+			interrupted = true
+			cmd.Process.Signal(os.Interrupt)
+		case err := <-done:
+			return interrupted, err
+		}
+	}
+}